@@ -0,0 +1,85 @@
+// Package echoapi exposes the response contract for Echo handlers.
+// Output is byte-identical to the gin helpers in the response package.
+package echoapi
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Object sends a single object response.
+func Object(c echo.Context, obj any) error {
+	response.WriteObject(c.Response(), c.Request(), obj)
+	return nil
+}
+
+// Created sends a 201 Created response with the created object.
+func Created(c echo.Context, obj any) error {
+	response.WriteCreated(c.Response(), c.Request(), obj)
+	return nil
+}
+
+// NoContent sends a 204 No Content response.
+func NoContent(c echo.Context) error {
+	response.WriteNoContent(c.Response())
+	return nil
+}
+
+// Deleted sends a Stripe-style deletion confirmation.
+func Deleted(c echo.Context, objectType string, id string) error {
+	response.WriteDeleted(c.Response(), c.Request(), objectType, id)
+	return nil
+}
+
+// List sends a Stripe-style list response.
+func List[T any](c echo.Context, data []T, total int64, limit, offset int) error {
+	response.WriteList(c.Response(), c.Request(), data, total, limit, offset)
+	return nil
+}
+
+// Error sends an error response with the given status and error info.
+func Error(c echo.Context, status int, info response.ErrorInfo) error {
+	response.WriteError(c.Response(), c.Request(), status, info)
+	return nil
+}
+
+// BadRequest sends a 400 Bad Request error.
+func BadRequest(c echo.Context, message string) error {
+	return Error(c, http.StatusBadRequest, response.ErrorInfo{Type: response.ErrorTypeInvalidRequest, Message: message})
+}
+
+// NotFound sends a 404 Not Found error for an entity.
+func NotFound(c echo.Context, entity string) error {
+	return Error(c, http.StatusNotFound, response.ErrorInfo{Type: response.ErrorTypeNotFound, Message: entity + " not found"})
+}
+
+// InternalError sends a 500 Internal Server Error.
+func InternalError(c echo.Context, message string) error {
+	return Error(c, http.StatusInternalServerError, response.ErrorInfo{Type: response.ErrorTypeAPI, Message: message})
+}
+
+// HTTPErrorHandler renders errors returned from Echo handlers (including
+// echo.HTTPError from routing and binding) in the error envelope.
+//
+//	e.HTTPErrorHandler = echoapi.HTTPErrorHandler
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	message := "internal error"
+	if he, ok := err.(*echo.HTTPError); ok {
+		status = he.Code
+		if m, ok := he.Message.(string); ok {
+			message = m
+		} else {
+			message = http.StatusText(status)
+		}
+	}
+
+	_ = Error(c, status, response.ErrorInfo{Type: response.ErrorTypeForStatus(status), Message: message})
+}
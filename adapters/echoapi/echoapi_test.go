@@ -0,0 +1,53 @@
+package echoapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+
+	"github.com/doujins-org/ginapi/adapters/echoapi"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestListMatchesGin(t *testing.T) {
+	data := []map[string]string{{"object": "artist", "id": "art_1"}}
+
+	gin.SetMode(gin.TestMode)
+	ginRec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(ginRec)
+	c.Request = httptest.NewRequest("GET", "/artists?limit=1", nil)
+	response.ListResponse(c, data, 5, 1, 0)
+
+	e := echo.New()
+	e.GET("/artists", func(c echo.Context) error {
+		return echoapi.List(c, data, 5, 1, 0)
+	})
+	echoRec := httptest.NewRecorder()
+	e.ServeHTTP(echoRec, httptest.NewRequest("GET", "/artists?limit=1", nil))
+
+	if echoRec.Body.String() != ginRec.Body.String() {
+		t.Errorf("bodies differ:\n gin: %s\necho: %s", ginRec.Body.String(), echoRec.Body.String())
+	}
+	if echoRec.Header().Get("Content-Type") != ginRec.Header().Get("Content-Type") {
+		t.Errorf("content types differ")
+	}
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = echoapi.HTTPErrorHandler
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	want := `{"object":"error","error":{"type":"not_found","message":"Not Found"}}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
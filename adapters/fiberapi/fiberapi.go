@@ -0,0 +1,129 @@
+// Package fiberapi exposes the response contract for Fiber handlers.
+// Output is byte-identical to the gin helpers in the response package.
+//
+// Fiber runs on fasthttp, so each helper renders through the shared net/http
+// core into a buffer and copies status, headers, and body onto the Fiber context.
+package fiberapi
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Object sends a single object response.
+func Object(c *fiber.Ctx, obj any) error {
+	return write(c, func(w http.ResponseWriter, r *http.Request) { response.WriteObject(w, r, obj) })
+}
+
+// Created sends a 201 Created response with the created object.
+func Created(c *fiber.Ctx, obj any) error {
+	return write(c, func(w http.ResponseWriter, r *http.Request) { response.WriteCreated(w, r, obj) })
+}
+
+// NoContent sends a 204 No Content response.
+func NoContent(c *fiber.Ctx) error {
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// Deleted sends a Stripe-style deletion confirmation.
+func Deleted(c *fiber.Ctx, objectType string, id string) error {
+	return write(c, func(w http.ResponseWriter, r *http.Request) { response.WriteDeleted(w, r, objectType, id) })
+}
+
+// List sends a Stripe-style list response.
+func List[T any](c *fiber.Ctx, data []T, total int64, limit, offset int) error {
+	return write(c, func(w http.ResponseWriter, r *http.Request) {
+		response.WriteList(w, r, data, total, limit, offset)
+	})
+}
+
+// Error sends an error response with the given status and error info.
+func Error(c *fiber.Ctx, status int, info response.ErrorInfo) error {
+	return write(c, func(w http.ResponseWriter, r *http.Request) { response.WriteError(w, r, status, info) })
+}
+
+// BadRequest sends a 400 Bad Request error.
+func BadRequest(c *fiber.Ctx, message string) error {
+	return Error(c, http.StatusBadRequest, response.ErrorInfo{Type: response.ErrorTypeInvalidRequest, Message: message})
+}
+
+// NotFound sends a 404 Not Found error for an entity.
+func NotFound(c *fiber.Ctx, entity string) error {
+	return Error(c, http.StatusNotFound, response.ErrorInfo{Type: response.ErrorTypeNotFound, Message: entity + " not found"})
+}
+
+// InternalError sends a 500 Internal Server Error.
+func InternalError(c *fiber.Ctx, message string) error {
+	return Error(c, http.StatusInternalServerError, response.ErrorInfo{Type: response.ErrorTypeAPI, Message: message})
+}
+
+// ErrorHandler renders errors returned from Fiber handlers (including
+// *fiber.Error from routing) in the error envelope.
+//
+//	app := fiber.New(fiber.Config{ErrorHandler: fiberapi.ErrorHandler})
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	status := http.StatusInternalServerError
+	message := "internal error"
+	if fe, ok := err.(*fiber.Error); ok {
+		status = fe.Code
+		message = fe.Message
+	}
+	return Error(c, status, response.ErrorInfo{Type: response.ErrorTypeForStatus(status), Message: message})
+}
+
+// write renders fn into a buffer and copies the result onto c.
+func write(c *fiber.Ctx, fn func(w http.ResponseWriter, r *http.Request)) error {
+	rec := &recorder{header: http.Header{}, status: http.StatusOK}
+	fn(rec, request(c))
+
+	for key, values := range rec.header {
+		for i, v := range values {
+			if i == 0 {
+				c.Set(key, v)
+			} else {
+				c.Append(key, v)
+			}
+		}
+	}
+	c.Status(rec.status)
+	if rec.body.Len() == 0 {
+		return nil
+	}
+	return c.Send(rec.body.Bytes())
+}
+
+// request builds the subset of *http.Request the response core reads:
+// method (HEAD handling), URL (pagination links), headers, and context.
+func request(c *fiber.Ctx) *http.Request {
+	u, err := url.ParseRequestURI(c.OriginalURL())
+	if err != nil {
+		u = &url.URL{Path: c.Path()}
+	}
+
+	r := &http.Request{
+		Method: c.Method(),
+		URL:    u,
+		Header: http.Header{},
+		Host:   c.Hostname(),
+	}
+	for key, value := range c.GetReqHeaders() {
+		r.Header[key] = value
+	}
+	return r.WithContext(c.UserContext())
+}
+
+// recorder is a minimal in-memory http.ResponseWriter.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header         { return r.header }
+func (r *recorder) WriteHeader(status int)      { r.status = status }
+func (r *recorder) Write(b []byte) (int, error) { return r.body.Write(b) }
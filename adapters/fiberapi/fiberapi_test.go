@@ -0,0 +1,60 @@
+package fiberapi_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/doujins-org/ginapi/adapters/fiberapi"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestListMatchesGin(t *testing.T) {
+	data := []map[string]string{{"object": "artist", "id": "art_1"}}
+
+	gin.SetMode(gin.TestMode)
+	ginRec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(ginRec)
+	c.Request = httptest.NewRequest("GET", "/artists", nil)
+	response.ListResponse(c, data, 5, 1, 0)
+
+	app := fiber.New()
+	app.Get("/artists", func(c *fiber.Ctx) error {
+		return fiberapi.List(c, data, 5, 1, 0)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/artists", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+
+	if string(body) != ginRec.Body.String() {
+		t.Errorf("bodies differ:\n  gin: %s\nfiber: %s", ginRec.Body.String(), body)
+	}
+	if resp.Header.Get("Content-Type") != ginRec.Header().Get("Content-Type") {
+		t.Errorf("content types differ: %s vs %s", ginRec.Header().Get("Content-Type"), resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: fiberapi.ErrorHandler})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/missing", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", resp.StatusCode)
+	}
+	want := `{"object":"error","error":{"type":"not_found","message":"Cannot GET /missing"}}`
+	if string(body) != want {
+		t.Errorf("expected %s, got %s", want, body)
+	}
+}
@@ -0,0 +1,218 @@
+// Package admin builds admin API route groups with the same protections in
+// every service: an IP allowlist, a stricter principal check, an audit
+// entry per request, and responses that are never cached or indexed.
+//
+// Admin-only response fields use the response package's audiences: tag
+// them `audience:"admin"` and they appear only in responses to admin
+// routes, since Group grants the request the Audience.
+//
+//	type Gallery struct {
+//	    ID         string `json:"id"`
+//	    Title      string `json:"title"`
+//	    UploaderIP string `json:"uploader_ip" audience:"admin"`
+//	}
+//
+//	router.Use(auth.Authenticate(sessionAuth))
+//	adm := admin.Group(router, "/admin", admin.Config{AllowedIPs: []string{"10.0.0.0/8"}})
+//	adm.GET("/galleries/:id", getGallery) // the same handler as the public route
+package admin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Audience is the response audience granted to admin requests. It is
+// reserved, so the admin role alone doesn't grant it outside admin routes.
+const Audience = "admin"
+
+func init() {
+	response.ReserveAudience(Audience)
+}
+
+// ErrorCodeIPNotAllowed is sent with a 403 to admin requests from outside
+// Config.AllowedIPs.
+const ErrorCodeIPNotAllowed = "ip_not_allowed"
+
+// adminKey is the gin context key marking admin requests.
+const adminKey = "ginapi.admin"
+
+// Entry is the audit record of an admin request.
+type Entry struct {
+	Time        time.Time
+	PrincipalID string
+	Method      string
+	Route       string
+	Path        string
+	Query       string
+	Status      int
+	ClientIP    string
+	RequestID   string
+}
+
+// Config configures admin route groups.
+type Config struct {
+	// Roles grant admin access: principals with any of them are admins
+	// (defaults to "admin")
+	Roles []string
+	// Allow, if set, decides admin access instead of Roles, e.g. from an
+	// authz.Policy permission
+	Allow func(p auth.Principal) bool
+	// AllowedIPs are the addresses and CIDR ranges admin requests may come
+	// from, matched against c.ClientIP (which honors gin's trusted
+	// proxies). Empty allows any address.
+	AllowedIPs []string
+	// Audit receives an entry for every admin request that reached the
+	// handlers (defaults to logging with slog.Default() at info)
+	Audit func(ctx context.Context, e Entry)
+	// SkipReads stops auditing GET and HEAD requests
+	SkipReads bool
+}
+
+// Group returns a route group at relativePath with Middleware applied.
+func Group(r gin.IRouter, relativePath string, cfg Config) *gin.RouterGroup {
+	return r.Group(relativePath, Middleware(cfg))
+}
+
+// Middleware returns the admin middleware, for groups built by hand. In
+// order, it rejects clients outside AllowedIPs (403 ip_not_allowed),
+// anonymous requests (401 auth_required), and non-admins (403
+// insufficient_permission); marks the response no-store and noindex;
+// grants the admin Audience; and audits the request once handled. It
+// panics if an entry of AllowedIPs is not an address or CIDR range.
+func Middleware(cfg Config) gin.HandlerFunc {
+	if len(cfg.Roles) == 0 {
+		cfg.Roles = []string{"admin"}
+	}
+	if cfg.Allow == nil {
+		roles := cfg.Roles
+		cfg.Allow = func(p auth.Principal) bool {
+			return slices.ContainsFunc(p.Roles, func(role string) bool { return slices.Contains(roles, role) })
+		}
+	}
+	if cfg.Audit == nil {
+		cfg.Audit = logEntry
+	}
+	prefixes := parsePrefixes(cfg.AllowedIPs)
+
+	return func(c *gin.Context) {
+		if len(prefixes) > 0 && !allowedIP(prefixes, c.ClientIP()) {
+			response.ErrorWithInfo(c, http.StatusForbidden, response.ErrorInfo{
+				Type:    response.ErrorTypeForbidden,
+				Code:    ErrorCodeIPNotAllowed,
+				Message: "admin access is not allowed from this address",
+			})
+			c.Abort()
+			return
+		}
+		p, ok := auth.GetPrincipal(c)
+		if !ok || p.Type == auth.TypeGuest {
+			response.ErrorWithInfo(c, http.StatusUnauthorized, response.ErrorInfo{
+				Type:    response.ErrorTypeAuthentication,
+				Code:    response.ErrorCodeAuthRequired,
+				Message: "authentication required",
+			})
+			c.Abort()
+			return
+		}
+		if !cfg.Allow(p) {
+			response.ErrorWithInfo(c, http.StatusForbidden, response.ErrorInfo{
+				Type:    response.ErrorTypeForbidden,
+				Code:    response.ErrorCodeInsufficientPermission,
+				Message: "admin access required",
+			})
+			c.Abort()
+			return
+		}
+
+		response.NoStore(c)
+		c.Header("X-Robots-Tag", "noindex, nofollow")
+		response.SetAudiences(c, append(slices.Clone(response.Audiences(c)), Audience)...)
+		c.Set(adminKey, true)
+
+		start := time.Now()
+		c.Next()
+
+		if cfg.SkipReads && (c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead) {
+			return
+		}
+		cfg.Audit(c, Entry{
+			Time:        start,
+			PrincipalID: p.ID,
+			Method:      c.Request.Method,
+			Route:       c.FullPath(),
+			Path:        c.Request.URL.Path,
+			Query:       c.Request.URL.RawQuery,
+			Status:      c.Writer.Status(),
+			ClientIP:    c.ClientIP(),
+			RequestID:   c.Writer.Header().Get("X-Request-ID"),
+		})
+	}
+}
+
+// Is reports whether the request went through the admin middleware, for
+// handlers shared with public routes.
+func Is(c *gin.Context) bool {
+	return c.GetBool(adminKey)
+}
+
+// logEntry is the default Audit.
+func logEntry(ctx context.Context, e Entry) {
+	slog.LogAttrs(ctx, slog.LevelInfo, "admin request",
+		slog.String("principal_id", e.PrincipalID),
+		slog.String("method", e.Method),
+		slog.String("route", e.Route),
+		slog.String("path", e.Path),
+		slog.String("query", e.Query),
+		slog.Int("status", e.Status),
+		slog.String("client_ip", e.ClientIP),
+		slog.String("request_id", e.RequestID),
+	)
+}
+
+// parsePrefixes parses addresses and CIDR ranges.
+func parsePrefixes(ips []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(ips))
+	for _, s := range ips {
+		if strings.Contains(s, "/") {
+			prefix, err := netip.ParsePrefix(s)
+			if err != nil {
+				panic(fmt.Sprintf("admin: invalid AllowedIPs entry %q: %v", s, err))
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			panic(fmt.Sprintf("admin: invalid AllowedIPs entry %q: %v", s, err))
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes
+}
+
+// allowedIP reports whether ip falls in one of prefixes.
+func allowedIP(prefixes []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package admin_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/admin"
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/response"
+)
+
+type gallery struct {
+	Object     string `json:"object"`
+	ID         string `json:"id"`
+	UploaderIP string `json:"uploader_ip" audience:"admin"`
+}
+
+func newAdminRouter(cfg admin.Config, principal *auth.Principal) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if principal != nil {
+			auth.SetPrincipal(c, *principal)
+		}
+	})
+	getGallery := func(c *gin.Context) {
+		response.Object(c, gallery{Object: "gallery", ID: "gal_1", UploaderIP: "203.0.113.7"})
+	}
+	router.GET("/galleries/gal_1", getGallery)
+	adm := admin.Group(router, "/admin", cfg)
+	adm.GET("/galleries/gal_1", getGallery)
+	adm.DELETE("/galleries/gal_1", func(c *gin.Context) {
+		if !admin.Is(c) {
+			c.Status(http.StatusTeapot)
+			return
+		}
+		response.Deleted(c, "gallery", "gal_1")
+	})
+	return router
+}
+
+func TestAdminAccess(t *testing.T) {
+	adminUser := &auth.Principal{ID: "usr_1", Type: auth.TypeUser, Roles: []string{"admin"}}
+	member := &auth.Principal{ID: "usr_2", Type: auth.TypeUser, Roles: []string{"member"}}
+
+	tests := []struct {
+		name       string
+		cfg        admin.Config
+		principal  *auth.Principal
+		remoteAddr string
+		want       int
+		wantCode   string
+	}{
+		{"admin", admin.Config{}, adminUser, "192.0.2.1:1234", http.StatusOK, ""},
+		{"anonymous", admin.Config{}, nil, "192.0.2.1:1234", http.StatusUnauthorized, "auth_required"},
+		{"guest", admin.Config{}, &auth.Principal{ID: "guest_1", Type: auth.TypeGuest, Roles: []string{"admin"}}, "192.0.2.1:1234", http.StatusUnauthorized, "auth_required"},
+		{"not admin", admin.Config{}, member, "192.0.2.1:1234", http.StatusForbidden, "insufficient_permission"},
+		{"custom roles", admin.Config{Roles: []string{"member"}}, member, "192.0.2.1:1234", http.StatusOK, ""},
+		{"allow func", admin.Config{Allow: func(p auth.Principal) bool { return p.ID == "usr_2" }}, member, "192.0.2.1:1234", http.StatusOK, ""},
+		{"ip in range", admin.Config{AllowedIPs: []string{"10.0.0.0/8"}}, adminUser, "10.1.2.3:1234", http.StatusOK, ""},
+		{"exact ip", admin.Config{AllowedIPs: []string{"10.1.2.3"}}, adminUser, "10.1.2.3:1234", http.StatusOK, ""},
+		{"ip outside range", admin.Config{AllowedIPs: []string{"10.0.0.0/8"}}, adminUser, "192.0.2.1:1234", http.StatusForbidden, admin.ErrorCodeIPNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Audit = func(context.Context, admin.Entry) {}
+			router := newAdminRouter(tt.cfg, tt.principal)
+			req := httptest.NewRequest(http.MethodGet, "/admin/galleries/gal_1", nil)
+			req.RemoteAddr = tt.remoteAddr
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d %s", tt.want, w.Code, w.Body.String())
+			}
+			if tt.wantCode != "" && !strings.Contains(w.Body.String(), `"code":"`+tt.wantCode+`"`) {
+				t.Errorf("expected code %s, got %s", tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminResponses(t *testing.T) {
+	var entries []admin.Entry
+	principal := &auth.Principal{ID: "usr_1", Type: auth.TypeUser, Roles: []string{"admin"}}
+	router := newAdminRouter(admin.Config{
+		Audit:     func(_ context.Context, e admin.Entry) { entries = append(entries, e) },
+		SkipReads: true,
+	}, principal)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	public := serve(http.MethodGet, "/galleries/gal_1")
+	if strings.Contains(public.Body.String(), "uploader_ip") {
+		t.Errorf("expected admin fields stripped from the public route, got %s", public.Body.String())
+	}
+
+	w := serve(http.MethodGet, "/admin/galleries/gal_1")
+	if !strings.Contains(w.Body.String(), `"uploader_ip":"203.0.113.7"`) {
+		t.Errorf("expected admin fields on the admin route, got %s", w.Body.String())
+	}
+	if w.Header().Get("Cache-Control") != "no-store" || w.Header().Get("X-Robots-Tag") == "" {
+		t.Errorf("expected no-store and X-Robots-Tag, got %v", w.Header())
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected reads not to be audited, got %+v", entries)
+	}
+
+	if w := serve(http.MethodDelete, "/admin/galleries/gal_1?reason=spam"); w.Code != http.StatusOK {
+		t.Errorf("expected admin.Is in the handler, got %d", w.Code)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 audit entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.PrincipalID != "usr_1" || e.Method != http.MethodDelete || e.Route != "/admin/galleries/gal_1" || e.Query != "reason=spam" || e.Status != http.StatusOK {
+		t.Errorf("unexpected audit entry %+v", e)
+	}
+}
+
+func TestAdminInvalidIPs(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	admin.Middleware(admin.Config{AllowedIPs: []string{"10.0.0.0/33"}})
+}
@@ -0,0 +1,185 @@
+// Package apitest has test helpers that catch list endpoint bugs clients
+// would otherwise find in production. Offset pagination over a
+// non-deterministic sort (e.g. ORDER BY created_at with ties) returns rows
+// in a different order on each query, so items appear on two pages or on
+// none:
+//
+//	func TestListGalleriesOrder(t *testing.T) {
+//	    router := newTestRouter(t) // seeded with rows sharing created_at
+//	    apitest.AssertStableOrder(t, router, "/api/galleries?limit=20", 5)
+//	    apitest.AssertPagesConsistent(t, router, "/api/galleries", 7)
+//	}
+//
+// AssertResponseSchema checks responses against the types declared in
+// route metadata, so the contract and the handlers can't diverge.
+package apitest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// AssertStableOrder requests target (a GET path with query) runs times and
+// fails t if the list items come back in a different order. It accepts
+// list envelopes and bare arrays (PaginationHeaders mode).
+func AssertStableOrder(t testing.TB, h http.Handler, target string, runs int) {
+	t.Helper()
+	if runs < 2 {
+		runs = 2
+	}
+
+	var first page
+	for i := 0; i < runs; i++ {
+		p, err := fetchPage(h, target)
+		if err != nil {
+			t.Fatalf("apitest: GET %s: %v", target, err)
+		}
+		if i == 0 {
+			first = p
+			continue
+		}
+		if p.hash == first.hash {
+			continue
+		}
+		if pos := firstDifference(first.ids, p.ids); pos >= 0 {
+			t.Errorf("apitest: GET %s returned items in a different order on request %d: position %d is %s, was %s; add a unique tiebreaker to the sort",
+				target, i+1, pos, at(p.ids, pos), at(first.ids, pos))
+		} else {
+			t.Errorf("apitest: GET %s returned different items on request %d", target, i+1)
+		}
+		return
+	}
+}
+
+// AssertPagesConsistent walks target with ?limit=pageSize&offset=... until
+// has_more is false, and fails t if an item appears on two pages or the
+// number of items seen doesn't match total. Use a page size that doesn't
+// divide the row count, so page boundaries fall inside runs of tied sort
+// keys.
+func AssertPagesConsistent(t testing.TB, h http.Handler, target string, pageSize int) {
+	t.Helper()
+
+	u, err := url.Parse(target)
+	if err != nil {
+		t.Fatalf("apitest: invalid target %q: %v", target, err)
+	}
+
+	seen := map[string]int{}
+	seenCount := 0
+	var total int64 = -1
+	for offset, pageNum := 0, 1; ; pageNum++ {
+		q := u.Query()
+		q.Set("limit", strconv.Itoa(pageSize))
+		q.Set("offset", strconv.Itoa(offset))
+		u.RawQuery = q.Encode()
+
+		p, err := fetchPage(h, u.String())
+		if err != nil {
+			t.Fatalf("apitest: GET %s: %v", u, err)
+		}
+		if !p.envelope {
+			t.Fatalf("apitest: GET %s: AssertPagesConsistent needs a list envelope", u)
+		}
+		total = p.total
+
+		for _, id := range p.ids {
+			if prev, ok := seen[id]; ok {
+				t.Errorf("apitest: GET %s: item %s appears on pages %d and %d", target, id, prev, pageNum)
+			}
+			seen[id] = pageNum
+		}
+		seenCount += len(p.ids)
+
+		if !p.hasMore || len(p.ids) == 0 {
+			break
+		}
+		offset += len(p.ids)
+	}
+
+	if total >= 0 && int64(seenCount) != total {
+		t.Errorf("apitest: GET %s: saw %d items across pages, expected total %d", target, seenCount, total)
+	}
+}
+
+// page is one list response.
+type page struct {
+	ids      []string // item ids, or the item JSON when it has no id
+	hash     [sha256.Size]byte
+	envelope bool
+	total    int64
+	hasMore  bool
+}
+
+// fetchPage requests target from h and decodes the list in the response.
+func fetchPage(h http.Handler, target string) (page, error) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("Accept", "application/json")
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		return page{}, fmt.Errorf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	var p page
+	var items []json.RawMessage
+	body := bytes.TrimSpace(w.Body.Bytes())
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &items); err != nil {
+			return page{}, err
+		}
+	} else {
+		var list struct {
+			Data    []json.RawMessage `json:"data"`
+			Total   int64             `json:"total"`
+			HasMore bool              `json:"has_more"`
+		}
+		if err := json.Unmarshal(body, &list); err != nil {
+			return page{}, err
+		}
+		items, p.envelope, p.total, p.hasMore = list.Data, true, list.Total, list.HasMore
+	}
+
+	sum := sha256.New()
+	for _, item := range items {
+		sum.Write(item)
+		p.ids = append(p.ids, itemID(item))
+	}
+	copy(p.hash[:], sum.Sum(nil))
+	return p, nil
+}
+
+// itemID returns the item's "id" as text, or the item JSON if it has none.
+func itemID(item json.RawMessage) string {
+	var obj struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(item, &obj) == nil && len(obj.ID) > 0 {
+		return string(obj.ID)
+	}
+	return string(item)
+}
+
+// firstDifference returns the first position where a and b differ, or -1.
+func firstDifference(a, b []string) int {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		if at(a, i) != at(b, i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// at returns ids[i], or "nothing" past the end.
+func at(ids []string, i int) string {
+	if i < len(ids) {
+		return ids[i]
+	}
+	return "nothing"
+}
@@ -0,0 +1,125 @@
+package apitest_test
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/apitest"
+	"github.com/doujins-org/ginapi/pagination"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// recorder captures failures instead of failing the real test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// run calls fn with a recorder and returns the failures.
+func run(t *testing.T, fn func(tb testing.TB)) []string {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+	return r.errors
+}
+
+type gallery struct {
+	Object  string `json:"object"`
+	ID      int    `json:"id"`
+	Created int    `json:"created"`
+}
+
+// galleryRouter serves 10 galleries sorted by created, where every pair
+// shares a created value; stable adds id as a tiebreaker.
+func galleryRouter(stable bool) *gin.Engine {
+	galleries := make([]gallery, 10)
+	for i := range galleries {
+		galleries[i] = gallery{Object: "gallery", ID: i + 1, Created: i / 2}
+	}
+
+	router := gin.New()
+	router.GET("/galleries", func(c *gin.Context) {
+		p := pagination.BindDefault(c)
+		rows := append([]gallery(nil), galleries...)
+		rand.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+		sort.SliceStable(rows, func(i, j int) bool {
+			if rows[i].Created != rows[j].Created || !stable {
+				return rows[i].Created < rows[j].Created
+			}
+			return rows[i].ID < rows[j].ID
+		})
+
+		end := min(p.Offset+p.Limit, len(rows))
+		start := min(p.Offset, end)
+		response.ListResponse(c, rows[start:end], int64(len(rows)), p.Limit, p.Offset)
+	})
+	return router
+}
+
+func TestAssertStableOrder(t *testing.T) {
+	if errs := run(t, func(tb testing.TB) {
+		apitest.AssertStableOrder(tb, galleryRouter(true), "/galleries", 10)
+	}); len(errs) != 0 {
+		t.Errorf("expected stable order to pass, got %v", errs)
+	}
+
+	errs := run(t, func(tb testing.TB) {
+		apitest.AssertStableOrder(tb, galleryRouter(false), "/galleries", 20)
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0], "different order") {
+		t.Errorf("expected an ordering failure, got %v", errs)
+	}
+}
+
+func TestAssertPagesConsistent(t *testing.T) {
+	if errs := run(t, func(tb testing.TB) {
+		apitest.AssertPagesConsistent(tb, galleryRouter(true), "/galleries", 3)
+	}); len(errs) != 0 {
+		t.Errorf("expected consistent pages to pass, got %v", errs)
+	}
+
+	// Ties across page boundaries make items repeat or go missing; retry
+	// so the shuffle can't pass by luck
+	var errs []string
+	for i := 0; i < 20 && len(errs) == 0; i++ {
+		errs = run(t, func(tb testing.TB) {
+			apitest.AssertPagesConsistent(tb, galleryRouter(false), "/galleries", 3)
+		})
+	}
+	if len(errs) == 0 {
+		t.Error("expected inconsistent pages to fail")
+	}
+}
+
+func TestAssertStableOrderErrorStatus(t *testing.T) {
+	router := gin.New()
+	router.GET("/broken", func(c *gin.Context) { response.InternalError(c, "boom") })
+
+	errs := run(t, func(tb testing.TB) {
+		apitest.AssertStableOrder(tb, router, "/broken", 2)
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0], "status 500") {
+		t.Errorf("expected a status failure, got %v", errs)
+	}
+}
@@ -0,0 +1,46 @@
+package apitest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a clock.Clock for tests that only moves when told to, so rate
+// limit windows, cache TTLs, and expiries can be crossed without sleeping:
+//
+//	clk := apitest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	router.Use(middleware.RateLimit(middleware.RateLimitConfig{Rules: rules, Clock: clk}))
+//	// ... exhaust the limit
+//	clk.Advance(time.Minute) // the window resets
+//
+// It is safe for concurrent use.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a Clock stopped at start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now implements clock.Clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// Set moves the clock to t, which may be in the past.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
@@ -0,0 +1,31 @@
+package apitest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/apitest"
+	"github.com/doujins-org/ginapi/clock"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var clk clock.Clock = apitest.NewClock(start)
+	fake := clk.(*apitest.Clock)
+
+	tests := []struct {
+		name string
+		move func()
+		want time.Time
+	}{
+		{"stopped", func() {}, start},
+		{"advance", func() { fake.Advance(90 * time.Second) }, start.Add(90 * time.Second)},
+		{"set back", func() { fake.Set(start.Add(-time.Hour)) }, start.Add(-time.Hour)},
+	}
+	for _, tt := range tests {
+		tt.move()
+		if got := clk.Now(); !got.Equal(tt.want) {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+)
+
+// AssertResponseSchema serves req on engine and fails t if the response
+// doesn't match the Response type declared for its route with
+// ginapi.Handle, so the declared contract (which clients are generated
+// from) can't drift from what handlers actually send:
+//
+//	func TestGetGallerySchema(t *testing.T) {
+//	    router := newTestRouter(t)
+//	    apitest.AssertResponseSchema(t, router, httptest.NewRequest("GET", "/api/galleries/gal_1", nil))
+//	}
+//
+// It reports fields the type doesn't declare and values whose JSON type
+// doesn't match the Go type (e.g. a string where an integer is declared).
+// Missing fields are allowed, as redaction and field selection drop them.
+// For List routes every item is checked. The response must be a 2xx JSON
+// body; it is returned for further assertions.
+func AssertResponseSchema(t testing.TB, engine *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	desc := req.Method + " " + req.URL.Path
+
+	route, ok := findRoute(engine, req.Method, req.URL.Path)
+	if !ok {
+		t.Fatalf("apitest: %s: no route matches", desc)
+	}
+	if route.Response == nil {
+		t.Fatalf("apitest: %s: route %s declares no Response type", desc, route.Path)
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code < 200 || w.Code > 299 {
+		t.Fatalf("apitest: %s: status %d, expected a 2xx response to check: %s", desc, w.Code, w.Body.String())
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(w.Body.Bytes()))
+	dec.UseNumber()
+	var body any
+	if err := dec.Decode(&body); err != nil {
+		t.Fatalf("apitest: %s: invalid JSON response: %v", desc, err)
+	}
+
+	var problems []string
+	if route.List {
+		items, ok := listItems(body)
+		if !ok {
+			t.Fatalf("apitest: %s: expected a list response, got %s", desc, w.Body.String())
+		}
+		for i, item := range items {
+			checkSchema(route.Response, item, "/data/"+strconv.Itoa(i), &problems)
+		}
+	} else {
+		if obj, ok := body.(map[string]any); ok {
+			// Added by the response package, not the handler
+			delete(obj, "warnings")
+			delete(obj, "_debug")
+		}
+		checkSchema(route.Response, body, "", &problems)
+	}
+	for _, p := range problems {
+		t.Errorf("apitest: %s: %s", desc, p)
+	}
+	return w
+}
+
+// findRoute returns the route of engine serving method and path.
+func findRoute(engine *gin.Engine, method, path string) (ginapi.RouteInfo, bool) {
+	var wildcard *ginapi.RouteInfo
+	routes := ginapi.Routes(engine)
+	for i, r := range routes {
+		if r.Method != method {
+			continue
+		}
+		exact, ok := matchPattern(r.Path, path)
+		if !ok {
+			continue
+		}
+		if exact {
+			return r, true
+		}
+		if wildcard == nil {
+			wildcard = &routes[i]
+		}
+	}
+	if wildcard != nil {
+		return *wildcard, true
+	}
+	return ginapi.RouteInfo{}, false
+}
+
+// matchPattern reports whether path matches a gin route pattern, and
+// whether it did without parameters (static routes win in gin).
+func matchPattern(pattern, path string) (exact, ok bool) {
+	if pattern == path {
+		return true, true
+	}
+	ps := strings.Split(strings.Trim(pattern, "/"), "/")
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range ps {
+		if strings.HasPrefix(p, "*") {
+			return false, true
+		}
+		if i >= len(segs) {
+			return false, false
+		}
+		if strings.HasPrefix(p, ":") {
+			if segs[i] == "" {
+				return false, false
+			}
+			continue
+		}
+		if p != segs[i] {
+			return false, false
+		}
+	}
+	return false, len(ps) == len(segs)
+}
+
+// listItems returns the items of a list envelope or bare array.
+func listItems(body any) ([]any, bool) {
+	switch v := body.(type) {
+	case []any:
+		return v, true
+	case map[string]any:
+		if v["object"] == "list" {
+			items, ok := v["data"].([]any)
+			return items, ok || v["data"] == nil
+		}
+	}
+	return nil, false
+}
+
+var (
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// checkSchema compares v, decoded at pointer, with the JSON encoding of t,
+// collecting problems.
+func checkSchema(t reflect.Type, v any, pointer string, problems *[]string) {
+	at := pointer
+	if at == "" {
+		at = "/"
+	}
+	mismatch := func(want string) {
+		*problems = append(*problems, at+": expected "+want+", got "+jsonKind(v))
+	}
+
+	if v == nil {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			return
+		}
+		if t.Implements(textMarshaler) || reflect.PointerTo(t).Implements(textMarshaler) {
+			mismatch("string")
+		} else if !t.Implements(jsonMarshaler) && !reflect.PointerTo(t).Implements(jsonMarshaler) {
+			mismatch(kindOf(t))
+		}
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Text marshalers are strings even when they also marshal JSON
+	// themselves, like time.Time
+	if t.Implements(textMarshaler) || reflect.PointerTo(t).Implements(textMarshaler) {
+		if _, ok := v.(string); !ok {
+			mismatch("string")
+		}
+		return
+	}
+	if t.Implements(jsonMarshaler) || reflect.PointerTo(t).Implements(jsonMarshaler) {
+		return // custom encoding, any shape
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			mismatch("string")
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			mismatch("boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := v.(json.Number)
+		if _, err := n.Int64(); !ok || (err != nil && !isUint(n)) {
+			mismatch("integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := v.(json.Number); !ok {
+			mismatch("number")
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			if _, ok := v.(string); !ok {
+				mismatch("string") // []byte is base64
+			}
+			return
+		}
+		items, ok := v.([]any)
+		if !ok {
+			mismatch("array")
+			return
+		}
+		for i, item := range items {
+			checkSchema(t.Elem(), item, pointer+"/"+strconv.Itoa(i), problems)
+		}
+	case reflect.Map:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			mismatch("object")
+			return
+		}
+		for _, k := range sortedKeys(obj) {
+			checkSchema(t.Elem(), obj[k], pointer+"/"+escapePointer(k), problems)
+		}
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			mismatch("object")
+			return
+		}
+		fields := map[string]reflect.Type{}
+		structFields(t, fields)
+		for _, k := range sortedKeys(obj) {
+			ft, declared := fields[k]
+			if !declared {
+				*problems = append(*problems, pointer+"/"+escapePointer(k)+": field is not declared in "+t.String())
+				continue
+			}
+			checkSchema(ft, obj[k], pointer+"/"+escapePointer(k), problems)
+		}
+	}
+}
+
+// structFields collects the JSON fields of struct type t, following
+// encoding/json: embedded structs without a name are flattened, "-" is
+// skipped, and untagged fields use the Go name.
+func structFields(t reflect.Type, fields map[string]reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				structFields(ft, fields)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		ft := f.Type
+		if strings.Contains(","+opts+",", ",string,") {
+			ft = reflect.TypeOf("") // ,string encodes scalars as strings
+		}
+		if _, ok := fields[name]; !ok {
+			fields[name] = ft // shallower fields win
+		}
+	}
+}
+
+// kindOf names the JSON type of t for messages.
+func kindOf(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "integer"
+}
+
+// jsonKind names the JSON type of a decoded value for messages.
+func jsonKind(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	}
+	return "object"
+}
+
+// isUint reports whether n is an integer too large for int64.
+func isUint(n json.Number) bool {
+	_, err := strconv.ParseUint(n.String(), 10, 64)
+	return err == nil
+}
+
+// escapePointer escapes a key for use as a JSON Pointer token.
+func escapePointer(k string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(k)
+}
+
+// sortedKeys returns the keys of obj in order, for stable reports.
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,105 @@
+package apitest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/apitest"
+	"github.com/doujins-org/ginapi/response"
+)
+
+type schemaTag struct {
+	Slug string `json:"slug"`
+}
+
+type schemaBase struct {
+	Object string `json:"object"`
+	ID     string `json:"id"`
+}
+
+type schemaGallery struct {
+	schemaBase
+	Title    string            `json:"title"`
+	Pages    int               `json:"pages"`
+	Rating   float64           `json:"rating"`
+	Subtitle *string           `json:"subtitle"`
+	Tags     []schemaTag       `json:"tags"`
+	Meta     map[string]string `json:"meta,omitempty"`
+	Created  time.Time         `json:"created"`
+	Secret   string            `json:"-"`
+}
+
+func schemaRouter(bodies map[string]any) *gin.Engine {
+	router := gin.New()
+	api := router.Group("/schema")
+	for path, body := range bodies {
+		meta := ginapi.RouteMeta{Response: schemaGallery{}}
+		handler := func(c *gin.Context) { response.Object(c, body) }
+		if path == "/list" {
+			meta.List = true
+			handler = func(c *gin.Context) { response.ListResponse(c, body.([]any), 1, 20, 0) }
+		}
+		ginapi.Handle(api, http.MethodGet, path+"/:id", meta, handler)
+	}
+	ginapi.Handle(api, http.MethodGet, "/undeclared", ginapi.RouteMeta{}, func(c *gin.Context) {
+		response.Object(c, gin.H{})
+	})
+	return router
+}
+
+func TestAssertResponseSchema(t *testing.T) {
+	valid := gin.H{
+		"object": "gallery", "id": "gal_1", "title": "Summer", "pages": 24, "rating": 4.5,
+		"subtitle": nil, "tags": []gin.H{{"slug": "beach"}}, "meta": gin.H{"lang": "en"},
+		"created": "2024-01-02T03:04:05Z",
+	}
+	router := schemaRouter(map[string]any{
+		"/valid":     valid,
+		"/extra":     gin.H{"object": "gallery", "id": "gal_1", "internal_notes": "x", "tags": []gin.H{{"slug": "a", "weight": 2}}},
+		"/drift":     gin.H{"id": 1, "pages": "24", "rating": "high", "tags": gin.H{}, "meta": gin.H{"lang": 1}, "created": 5},
+		"/null":      gin.H{"title": nil},
+		"/fractions": gin.H{"pages": 2.5},
+		"/list":      []any{valid, gin.H{"id": "gal_2", "owner_email": "a@example.com"}},
+	})
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"/schema/valid/gal_1", nil},
+		{"/schema/extra/gal_1", []string{
+			"apitest: GET /schema/extra/gal_1: /internal_notes: field is not declared in apitest_test.schemaGallery",
+			"apitest: GET /schema/extra/gal_1: /tags/0/weight: field is not declared in apitest_test.schemaTag",
+		}},
+		{"/schema/drift/gal_1", []string{
+			"apitest: GET /schema/drift/gal_1: /created: expected string, got integer",
+			"apitest: GET /schema/drift/gal_1: /id: expected string, got integer",
+			"apitest: GET /schema/drift/gal_1: /meta/lang: expected string, got integer",
+			"apitest: GET /schema/drift/gal_1: /pages: expected integer, got string",
+			"apitest: GET /schema/drift/gal_1: /rating: expected number, got string",
+			"apitest: GET /schema/drift/gal_1: /tags: expected array, got object",
+		}},
+		{"/schema/null/gal_1", []string{"apitest: GET /schema/null/gal_1: /title: expected string, got null"}},
+		{"/schema/fractions/gal_1", []string{"apitest: GET /schema/fractions/gal_1: /pages: expected integer, got number"}},
+		{"/schema/list/gal_1", []string{"apitest: GET /schema/list/gal_1: /data/1/owner_email: field is not declared in apitest_test.schemaGallery"}},
+		{"/schema/undeclared", []string{"apitest: GET /schema/undeclared: route /schema/undeclared declares no Response type"}},
+		{"/schema/missing", []string{"apitest: GET /schema/missing: no route matches"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got := run(t, func(tb testing.TB) {
+				apitest.AssertResponseSchema(tb, router, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
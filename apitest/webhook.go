@@ -0,0 +1,32 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/webhook"
+)
+
+// WebhookRequest returns a POST to target delivering e exactly as our
+// services do: the JSON envelope, signed with secret in the
+// webhook.SignatureHeader. Consumers use it to check in their own CI that
+// their webhook handlers accept our deliveries:
+//
+//	e := webhook.NewEvent("gallery.updated", map[string]any{"object": "gallery", "id": "gal_1"})
+//	w := httptest.NewRecorder()
+//	handler.ServeHTTP(w, apitest.WebhookRequest(t, "/hooks/doujins", secret, e))
+func WebhookRequest(t testing.TB, target string, secret []byte, e webhook.Event) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(e)
+	if err != nil {
+		t.Fatalf("apitest: marshaling webhook event: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, body, time.Now()))
+	return req
+}
@@ -0,0 +1,30 @@
+package apitest_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/apitest"
+	"github.com/doujins-org/ginapi/webhook"
+)
+
+func TestWebhookRequest(t *testing.T) {
+	secret := []byte("whsec")
+	e := webhook.NewEvent("gallery.updated", map[string]any{"object": "gallery", "id": "gal_1"})
+	req := apitest.WebhookRequest(t, "/hooks/doujins", secret, e)
+
+	if req.Method != http.MethodPost || req.URL.Path != "/hooks/doujins" {
+		t.Errorf("expected POST /hooks/doujins, got %s %s", req.Method, req.URL.Path)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if err := webhook.Verify(body, req.Header.Get(webhook.SignatureHeader), time.Now(), 0, secret); err != nil {
+		t.Errorf("expected a valid signature, got %v", err)
+	}
+	var got webhook.Event
+	if err := json.Unmarshal(body, &got); err != nil || got.ID != e.ID || got.Type != e.Type {
+		t.Errorf("expected the event as the body, got %s (%v)", body, err)
+	}
+}
@@ -0,0 +1,192 @@
+// Package auth defines the Principal every authentication method
+// populates, so authorization helpers, error reports, and request
+// classification work the same whether a request was authenticated by JWT,
+// API key, session cookie, or client certificate.
+//
+// Each authentication method implements Authenticator; Authenticate tries
+// them in order and stores the result:
+//
+//	router.Use(auth.Authenticate(jwtAuth, apiKeyAuth, sessionAuth))
+//	admin := router.Group("/admin", auth.Require())
+//
+//	func getGallery(c *gin.Context) {
+//	    if p, ok := auth.GetPrincipal(c); ok && p.HasScope("galleries:read") {
+//	        ...
+//	    }
+//	}
+package auth
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Principal types.
+const (
+	TypeUser    = "user"
+	TypeAPIKey  = "api_key"
+	TypeService = "service"
+	TypeGuest   = "guest"
+)
+
+// Authentication methods.
+const (
+	MethodJWT     = "jwt"
+	MethodAPIKey  = "api_key"
+	MethodSession = "session"
+	MethodMTLS    = "mtls"
+)
+
+// Principal is the authenticated identity behind a request.
+type Principal struct {
+	ID       string            `json:"id"`
+	Type     string            `json:"type"`             // see Type* constants
+	Method   string            `json:"method,omitempty"` // see Method* constants
+	Scopes   []string          `json:"scopes,omitempty"`
+	Roles    []string          `json:"roles,omitempty"` // see authz.Policy
+	Tier     string            `json:"tier,omitempty"`  // plan or tier, e.g. "free", "premium"
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// HasAllScopes reports whether the principal was granted every scope.
+func (p Principal) HasAllScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !p.HasScope(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// Authenticator authenticates a request with one method. It returns false
+// if the request doesn't use the method (e.g. no Authorization header), and
+// an error if it does but the credentials are invalid. The error message is
+// sent to the client.
+type Authenticator interface {
+	Authenticate(c *gin.Context) (Principal, bool, error)
+}
+
+// AuthenticatorFunc adapts a function to the Authenticator interface.
+type AuthenticatorFunc func(c *gin.Context) (Principal, bool, error)
+
+// Authenticate calls f(c).
+func (f AuthenticatorFunc) Authenticate(c *gin.Context) (Principal, bool, error) {
+	return f(c)
+}
+
+// Authenticate returns middleware that tries each authenticator in order and
+// stores the first principal found. Requests matching no authenticator
+// continue anonymously (use Require to reject them); invalid credentials get
+// a 401 with code invalid_token.
+func Authenticate(authenticators ...Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, a := range authenticators {
+			p, ok, err := a.Authenticate(c)
+			if err != nil {
+				response.ErrorWithInfo(c, http.StatusUnauthorized, response.ErrorInfo{
+					Type:    response.ErrorTypeAuthentication,
+					Code:    response.ErrorCodeInvalidToken,
+					Message: err.Error(),
+				})
+				c.Abort()
+				return
+			}
+			if ok {
+				SetPrincipal(c, p)
+				break
+			}
+		}
+		c.Next()
+	}
+}
+
+// Require returns middleware that rejects requests without a principal with
+// a 401 (code auth_required). Guests count as unauthenticated.
+func Require() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p, ok := GetPrincipal(c); !ok || p.Type == TypeGuest {
+			response.ErrorWithInfo(c, http.StatusUnauthorized, response.ErrorInfo{
+				Type:    response.ErrorTypeAuthentication,
+				Code:    response.ErrorCodeAuthRequired,
+				Message: "authentication required",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// principalKey is the gin context key for the principal.
+const principalKey = "ginapi.principal"
+
+// SetPrincipal stores p as the request's principal, in the gin context and
+// the request context. Authentication middleware not using Authenticate
+// should call it once the request is authenticated.
+func SetPrincipal(c *gin.Context, p Principal) {
+	c.Set(principalKey, p)
+	if c.Request != nil {
+		c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), p))
+	}
+}
+
+// GetPrincipal returns the principal stored by SetPrincipal.
+// The second return value is false for anonymous requests.
+func GetPrincipal(c *gin.Context) (Principal, bool) {
+	if c == nil {
+		return Principal{}, false
+	}
+	if v, ok := c.Get(principalKey); ok {
+		if p, ok := v.(Principal); ok {
+			return p, true
+		}
+	}
+	if c.Request != nil {
+		return PrincipalFromContext(c.Request.Context())
+	}
+	return Principal{}, false
+}
+
+// principalContextKey is the request context key for the principal.
+type principalContextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p, with its roles and
+// scopes as the response audiences unless others are granted (see
+// response.WithPrincipalAudiences).
+// This is the net/http equivalent of SetPrincipal.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	audiences := append(slices.Clone(p.Roles), p.Scopes...)
+	ctx = response.WithPrincipalAudiences(ctx, audiences...)
+	return context.WithValue(ctx, principalContextKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, which may be a
+// request context or a *gin.Context. Use it to report principals with
+// errors:
+//
+//	response.SetPrincipalResolver(func(ctx context.Context) any {
+//	    if p, ok := auth.PrincipalFromContext(ctx); ok {
+//	        return p
+//	    }
+//	    return nil
+//	})
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	if ctx == nil {
+		return Principal{}, false
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		return GetPrincipal(c)
+	}
+	p, ok := ctx.Value(principalContextKey{}).(Principal)
+	return p, ok
+}
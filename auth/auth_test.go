@@ -0,0 +1,133 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// headerAuth authenticates requests carrying header, rejecting the value "bad".
+func headerAuth(header string, p auth.Principal) auth.Authenticator {
+	return auth.AuthenticatorFunc(func(c *gin.Context) (auth.Principal, bool, error) {
+		switch c.GetHeader(header) {
+		case "":
+			return auth.Principal{}, false, nil
+		case "bad":
+			return auth.Principal{}, false, errors.New("invalid credentials")
+		}
+		return p, true, nil
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	jwt := headerAuth("Authorization", auth.Principal{ID: "usr_1", Type: auth.TypeUser, Method: auth.MethodJWT})
+	apiKey := headerAuth("X-API-Key", auth.Principal{ID: "key_1", Type: auth.TypeAPIKey, Method: auth.MethodAPIKey})
+
+	router := gin.New()
+	router.Use(auth.Authenticate(jwt, apiKey))
+	router.GET("/test", func(c *gin.Context) {
+		p, ok := auth.GetPrincipal(c)
+		fromCtx, _ := auth.PrincipalFromContext(c.Request.Context())
+		if p.ID != fromCtx.ID {
+			t.Errorf("expected request context principal %q, got %q", p.ID, fromCtx.ID)
+		}
+		if !ok {
+			c.String(http.StatusOK, "anonymous")
+			return
+		}
+		c.String(http.StatusOK, p.Method+":"+p.ID)
+	})
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+	}{
+		{"anonymous", nil, http.StatusOK, "anonymous"},
+		{"jwt", map[string]string{"Authorization": "tok"}, http.StatusOK, "jwt:usr_1"},
+		{"api key", map[string]string{"X-API-Key": "key"}, http.StatusOK, "api_key:key_1"},
+		{"first match wins", map[string]string{"Authorization": "tok", "X-API-Key": "key"}, http.StatusOK, "jwt:usr_1"},
+		{"invalid", map[string]string{"X-API-Key": "bad"}, http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				var result response.Error
+				_ = json.Unmarshal(w.Body.Bytes(), &result)
+				if result.Error.Code != response.ErrorCodeInvalidToken || result.Error.Message != "invalid credentials" {
+					t.Errorf("expected invalid_token error, got %s", w.Body.String())
+				}
+				return
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequire(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		wantStatus int
+	}{
+		{"anonymous", nil, http.StatusUnauthorized},
+		{"guest", &auth.Principal{ID: "dev_1", Type: auth.TypeGuest}, http.StatusUnauthorized},
+		{"user", &auth.Principal{ID: "usr_1", Type: auth.TypeUser}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.principal != nil {
+					auth.SetPrincipal(c, *tt.principal)
+				}
+			})
+			router.GET("/test", auth.Require(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestPrincipalScopes(t *testing.T) {
+	p := auth.Principal{Scopes: []string{"galleries:read", "galleries:write"}}
+
+	if !p.HasScope("galleries:read") || p.HasScope("galleries:delete") {
+		t.Errorf("unexpected HasScope results for %v", p.Scopes)
+	}
+	if !p.HasAllScopes("galleries:read", "galleries:write") || p.HasAllScopes("galleries:read", "admin") {
+		t.Errorf("unexpected HasAllScopes results for %v", p.Scopes)
+	}
+}
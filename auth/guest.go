@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MethodDeviceToken is the Principal.Method of guests identified by Guest.
+const MethodDeviceToken = "device_token"
+
+// GuestConfig configures anonymous device identities.
+type GuestConfig struct {
+	// Secret signs device tokens (required). Changing it re-issues every token.
+	Secret []byte
+	// PreviousSecrets are still accepted, so Secret can be rotated without
+	// resetting every guest. Tokens signed with them are re-issued.
+	PreviousSecrets [][]byte
+	// Header carrying the token for clients without cookies (defaults to
+	// "X-Guest-Token"). Newly issued tokens are sent back in it.
+	Header string
+	// CookieName for browsers (defaults to "guest")
+	CookieName string
+	// MaxAge of the cookie in seconds (defaults to 1 year)
+	MaxAge int
+	// Domain of the cookie, e.g. ".example.com" to share across subdomains
+	Domain string
+	// Secure restricts the cookie to HTTPS
+	Secure bool
+	// SameSite policy (defaults to Lax)
+	SameSite http.SameSite
+}
+
+// Guest returns middleware giving unauthenticated requests a stable
+// anonymous identity: a random device ID signed with Secret, read from the
+// header or cookie and issued when missing or invalid. The request's
+// principal becomes {ID: "guest_<device id>", Type: TypeGuest}, so rate
+// limiting, favorites, and A/B bucketing can key on it before login.
+// Install it after Authenticate; authenticated requests are left alone.
+//
+//	router.Use(auth.Authenticate(jwtAuth))
+//	router.Use(auth.Guest(auth.GuestConfig{Secret: cfg.GuestSecret, Secure: true}))
+func Guest(cfg GuestConfig) gin.HandlerFunc {
+	if len(cfg.Secret) == 0 {
+		panic("auth: Guest requires a Secret")
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-Guest-Token"
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "guest"
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = 365 * 24 * 60 * 60
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+
+	return func(c *gin.Context) {
+		if _, ok := GetPrincipal(c); ok {
+			c.Next()
+			return
+		}
+
+		token := c.GetHeader(cfg.Header)
+		if token == "" {
+			token, _ = c.Cookie(cfg.CookieName)
+		}
+		deviceID, current := verifyDeviceToken(token, cfg)
+		if deviceID == "" {
+			deviceID = newDeviceID()
+		}
+		if !current {
+			token = signDeviceToken(deviceID, cfg.Secret)
+			c.Header(cfg.Header, token)
+			http.SetCookie(c.Writer, &http.Cookie{
+				Name:     cfg.CookieName,
+				Value:    token,
+				Path:     "/",
+				Domain:   cfg.Domain,
+				MaxAge:   cfg.MaxAge,
+				Secure:   cfg.Secure,
+				HttpOnly: true,
+				SameSite: cfg.SameSite,
+			})
+		}
+
+		SetPrincipal(c, Principal{ID: "guest_" + deviceID, Type: TypeGuest, Method: MethodDeviceToken})
+		c.Next()
+	}
+}
+
+// newDeviceID returns a random 128-bit device ID.
+func newDeviceID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// signDeviceToken returns "<device id>.<signature>".
+func signDeviceToken(deviceID string, secret []byte) string {
+	return deviceID + "." + deviceSignature(deviceID, secret)
+}
+
+func deviceSignature(deviceID string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(deviceID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyDeviceToken returns the device ID of a validly signed token, or ""
+// if it is invalid. current is false if the token must be re-issued
+// (invalid, or signed with a previous secret).
+func verifyDeviceToken(token string, cfg GuestConfig) (deviceID string, current bool) {
+	id, sig, ok := strings.Cut(token, ".")
+	if !ok || len(id) != 32 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", false
+	}
+	if hmac.Equal([]byte(sig), []byte(deviceSignature(id, cfg.Secret))) {
+		return id, true
+	}
+	for _, secret := range cfg.PreviousSecrets {
+		if hmac.Equal([]byte(sig), []byte(deviceSignature(id, secret))) {
+			return id, false
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,107 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+)
+
+func newGuestRouter(cfg auth.GuestConfig, authenticated bool) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if authenticated {
+			auth.SetPrincipal(c, auth.Principal{ID: "usr_1", Type: auth.TypeUser})
+		}
+	})
+	router.Use(auth.Guest(cfg))
+	router.GET("/test", func(c *gin.Context) {
+		p, _ := auth.GetPrincipal(c)
+		c.String(http.StatusOK, p.Type+":"+p.ID)
+	})
+	return router
+}
+
+func serveGuest(router *gin.Engine, header, cookie string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	if header != "" {
+		req.Header.Set("X-Guest-Token", header)
+	}
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: "guest", Value: cookie})
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGuestIssuesAndAcceptsTokens(t *testing.T) {
+	router := newGuestRouter(auth.GuestConfig{Secret: []byte("secret")}, false)
+
+	first := serveGuest(router, "", "")
+	token := first.Header().Get("X-Guest-Token")
+	if token == "" || !strings.Contains(first.Header().Get("Set-Cookie"), "guest="+token) {
+		t.Fatalf("expected a token in the header and cookie, got %v", first.Header())
+	}
+	if !strings.HasPrefix(first.Body.String(), "guest:guest_") {
+		t.Errorf("expected a guest principal, got %q", first.Body.String())
+	}
+
+	for name, w := range map[string]*httptest.ResponseRecorder{
+		"cookie": serveGuest(router, "", token),
+		"header": serveGuest(router, token, ""),
+	} {
+		if w.Body.String() != first.Body.String() {
+			t.Errorf("%s: expected the same guest %q, got %q", name, first.Body.String(), w.Body.String())
+		}
+		if w.Header().Get("Set-Cookie") != "" {
+			t.Errorf("%s: expected no reissue, got %q", name, w.Header().Get("Set-Cookie"))
+		}
+	}
+}
+
+func TestGuestRejectsForgedTokens(t *testing.T) {
+	router := newGuestRouter(auth.GuestConfig{Secret: []byte("secret")}, false)
+	other := newGuestRouter(auth.GuestConfig{Secret: []byte("other")}, false)
+	forged := serveGuest(other, "", "").Header().Get("X-Guest-Token")
+
+	for _, token := range []string{forged, "garbage", strings.Repeat("a", 32) + ".sig"} {
+		w := serveGuest(router, token, "")
+		if w.Header().Get("X-Guest-Token") == "" || w.Header().Get("X-Guest-Token") == token {
+			t.Errorf("expected token %q to be replaced", token)
+		}
+	}
+}
+
+func TestGuestSecretRotation(t *testing.T) {
+	old := newGuestRouter(auth.GuestConfig{Secret: []byte("old")}, false)
+	w := serveGuest(old, "", "")
+	token := w.Header().Get("X-Guest-Token")
+
+	rotated := newGuestRouter(auth.GuestConfig{Secret: []byte("new"), PreviousSecrets: [][]byte{[]byte("old")}}, false)
+	w2 := serveGuest(rotated, token, "")
+
+	if w2.Body.String() != w.Body.String() {
+		t.Errorf("expected the same guest %q, got %q", w.Body.String(), w2.Body.String())
+	}
+	reissued := w2.Header().Get("X-Guest-Token")
+	if reissued == "" || reissued == token {
+		t.Errorf("expected the token to be re-signed, got %q", reissued)
+	}
+}
+
+func TestGuestSkipsAuthenticated(t *testing.T) {
+	router := newGuestRouter(auth.GuestConfig{Secret: []byte("secret")}, true)
+	w := serveGuest(router, "", "")
+
+	if w.Body.String() != "user:usr_1" {
+		t.Errorf("expected the authenticated user, got %q", w.Body.String())
+	}
+	if w.Header().Get("X-Guest-Token") != "" {
+		t.Errorf("expected no guest token, got %q", w.Header().Get("X-Guest-Token"))
+	}
+}
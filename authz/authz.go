@@ -0,0 +1,109 @@
+// Package authz provides authorization checks on top of auth.Principal,
+// responding through the response package so denials look the same on
+// every route.
+package authz
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// ErrNotFound is returned by an OwnerLoader when the resource doesn't exist.
+var ErrNotFound = errors.New("authz: resource not found")
+
+var hideExistence atomic.Bool
+
+// SetHideExistence makes ownership denials respond 404 instead of 403, so
+// callers can't probe which IDs exist (e.g. private galleries). Call it once
+// at startup.
+func SetHideExistence(hide bool) {
+	hideExistence.Store(hide)
+}
+
+// RequireOwner checks that the request's principal owns a resource. If not,
+// it sends the error response, aborts, and returns false:
+//
+//   - No principal: 401
+//   - Another principal: 403, or 404 with SetHideExistence
+//
+// Usage:
+//
+//	gallery, err := store.Gallery(ctx, id)
+//	...
+//	if !authz.RequireOwner(c, gallery.OwnerID) {
+//	    return
+//	}
+func RequireOwner(c *gin.Context, resourceOwnerID string) bool {
+	p, ok := auth.GetPrincipal(c)
+	if !ok {
+		response.ErrorWithInfo(c, http.StatusUnauthorized, response.ErrorInfo{
+			Type:    response.ErrorTypeAuthentication,
+			Code:    response.ErrorCodeAuthRequired,
+			Message: "authentication required",
+		})
+		c.Abort()
+		return false
+	}
+	if p.ID == "" || p.ID != resourceOwnerID {
+		deny(c)
+		return false
+	}
+	return true
+}
+
+// OwnerLoader returns the owner ID of the resource a request addresses,
+// typically from a path parameter. Return ErrNotFound (or an error
+// wrapping it) if the resource doesn't exist.
+type OwnerLoader func(c *gin.Context) (ownerID string, err error)
+
+// RequireOwnerOf returns middleware running RequireOwner against the owner
+// returned by load. Missing resources get 404; other load errors get a 500
+// and are reported.
+//
+//	galleries.PATCH("/:id", authz.RequireOwnerOf(func(c *gin.Context) (string, error) {
+//	    return store.GalleryOwner(c, c.Param("id"))
+//	}), updateGallery)
+func RequireOwnerOf(load OwnerLoader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ownerID, err := load(c)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			notFound(c)
+			return
+		case err != nil:
+			response.InternalError(c, err.Error())
+			c.Abort()
+			return
+		}
+		if RequireOwner(c, ownerID) {
+			c.Next()
+		}
+	}
+}
+
+// deny rejects an authenticated request for a resource it may not access.
+func deny(c *gin.Context) {
+	if hideExistence.Load() {
+		notFound(c)
+		return
+	}
+	response.ErrorWithInfo(c, http.StatusForbidden, response.ErrorInfo{
+		Type:    response.ErrorTypeForbidden,
+		Code:    response.ErrorCodeInsufficientPermission,
+		Message: "you do not have access to this resource",
+	})
+	c.Abort()
+}
+
+// notFound responds 404 the same way for missing and hidden resources,
+// and the same way as response.DenyOrHide.
+func notFound(c *gin.Context) {
+	response.DenyOrHide(c, false, false)
+	c.Abort()
+}
@@ -0,0 +1,97 @@
+package authz_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/authz"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// owners maps gallery IDs to their owners.
+var owners = map[string]string{"g1": "usr_1", "g2": "usr_2"}
+
+func loadOwner(c *gin.Context) (string, error) {
+	switch id := c.Param("id"); id {
+	case "broken":
+		return "", errors.New("database unavailable")
+	default:
+		owner, ok := owners[id]
+		if !ok {
+			return "", fmt.Errorf("gallery %s: %w", id, authz.ErrNotFound)
+		}
+		return owner, nil
+	}
+}
+
+func newRouter(principalID string) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if principalID != "" {
+			auth.SetPrincipal(c, auth.Principal{ID: principalID, Type: auth.TypeUser})
+		}
+	})
+	router.GET("/galleries/:id", authz.RequireOwnerOf(loadOwner), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/inline/:id", func(c *gin.Context) {
+		if !authz.RequireOwner(c, owners[c.Param("id")]) {
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestRequireOwner(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  string
+		path       string
+		hide       bool
+		wantStatus int
+		wantCode   string
+	}{
+		{"owner", "usr_1", "/galleries/g1", false, http.StatusOK, ""},
+		{"other user", "usr_1", "/galleries/g2", false, http.StatusForbidden, response.ErrorCodeInsufficientPermission},
+		{"other user hidden", "usr_1", "/galleries/g2", true, http.StatusNotFound, response.ErrorCodeResourceNotFound},
+		{"anonymous", "", "/galleries/g1", false, http.StatusUnauthorized, response.ErrorCodeAuthRequired},
+		{"missing", "usr_1", "/galleries/g9", false, http.StatusNotFound, response.ErrorCodeResourceNotFound},
+		{"load error", "usr_1", "/galleries/broken", false, http.StatusInternalServerError, ""},
+		{"inline owner", "usr_2", "/inline/g2", false, http.StatusOK, ""},
+		{"inline other user", "usr_2", "/inline/g1", false, http.StatusForbidden, response.ErrorCodeInsufficientPermission},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authz.SetHideExistence(tt.hide)
+			defer authz.SetHideExistence(false)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			newRouter(tt.principal).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantCode != "" {
+				var result response.Error
+				_ = json.Unmarshal(w.Body.Bytes(), &result)
+				if result.Error.Code != tt.wantCode {
+					t.Errorf("expected code %q, got %q", tt.wantCode, result.Error.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package authz
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// AllPermissions granted to a role allows every permission.
+const AllPermissions = "*"
+
+// Policy maps roles to the permissions they grant. Routes declare the
+// permissions they need when registered with Handle; a principal may call a
+// route if its roles (auth.Principal.Roles) or its scopes cover every one.
+//
+//	policy := authz.NewPolicy()
+//	policy.Grant("admin", authz.AllPermissions)
+//	policy.Grant("editor", "galleries:read", "galleries:write")
+//
+//	policy.Handle(api, http.MethodPatch, "/galleries/:id", []string{"galleries:write"}, updateGallery)
+//	admin.GET("/policy", policy.MatrixHandler(router))
+type Policy struct {
+	mu    sync.RWMutex
+	roles map[string][]string
+}
+
+// NewPolicy returns a policy with no roles.
+func NewPolicy() *Policy {
+	return &Policy{roles: map[string][]string{}}
+}
+
+// Grant adds permissions to role, declaring it if needed.
+func (p *Policy) Grant(role string, permissions ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	perms := p.roles[role]
+	if perms == nil {
+		perms = []string{}
+	}
+	for _, perm := range permissions {
+		if !slices.Contains(perms, perm) {
+			perms = append(perms, perm)
+		}
+	}
+	p.roles[role] = perms
+}
+
+// Allowed reports whether principal has permission, through a role or
+// directly as a scope (e.g. an API key issued with that scope).
+func (p *Policy) Allowed(principal auth.Principal, permission string) bool {
+	if principal.HasScope(permission) {
+		return true
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, role := range principal.Roles {
+		if p.roleGrants(role, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// roleGrants reports whether role grants permission. Called with p.mu held.
+func (p *Policy) roleGrants(role, permission string) bool {
+	perms := p.roles[role]
+	return slices.Contains(perms, permission) || slices.Contains(perms, AllPermissions)
+}
+
+// Require returns middleware that allows only principals with every one of
+// permissions: anonymous requests get 401 and others 403.
+func (p *Policy) Require(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.GetPrincipal(c)
+		if !ok {
+			response.ErrorWithInfo(c, http.StatusUnauthorized, response.ErrorInfo{
+				Type:    response.ErrorTypeAuthentication,
+				Code:    response.ErrorCodeAuthRequired,
+				Message: "authentication required",
+			})
+			c.Abort()
+			return
+		}
+		for _, perm := range permissions {
+			if !p.Allowed(principal, perm) {
+				response.ErrorWithInfo(c, http.StatusForbidden, response.ErrorInfo{
+					Type:    response.ErrorTypeForbidden,
+					Code:    response.ErrorCodeInsufficientPermission,
+					Message: "missing permission " + perm,
+				})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// Handle registers a route requiring permissions, like ginapi.Handle with
+// Require in front of the handlers. The permissions are recorded as the
+// route's scopes, so they show in ginapi.Routes and the policy matrix.
+func (p *Policy) Handle(r gin.IRoutes, method, relativePath string, permissions []string, handlers ...gin.HandlerFunc) {
+	chain := append([]gin.HandlerFunc{p.Require(permissions...)}, handlers...)
+	ginapi.Handle(r, method, relativePath, ginapi.RouteMeta{Scopes: permissions}, chain...)
+}
+
+// Matrix is the effective policy, for audits.
+type Matrix struct {
+	Object string              `json:"object"` // Always "policy"
+	Roles  map[string][]string `json:"roles"`  // role -> granted permissions
+	Routes []RoutePolicy       `json:"routes"`
+}
+
+// RoutePolicy lists the roles allowed to call a route.
+type RoutePolicy struct {
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Permissions []string `json:"permissions"`
+	Roles       []string `json:"roles"` // roles granting every permission
+}
+
+// Matrix returns the roles and, for each route of engine declaring
+// permissions, the roles allowed to call it.
+func (p *Policy) Matrix(engine *gin.Engine) Matrix {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	m := Matrix{Object: "policy", Roles: make(map[string][]string, len(p.roles)), Routes: []RoutePolicy{}}
+	names := make([]string, 0, len(p.roles))
+	for role, perms := range p.roles {
+		m.Roles[role] = slices.Clone(perms)
+		names = append(names, role)
+	}
+	sort.Strings(names)
+
+	for _, route := range ginapi.Routes(engine) {
+		if len(route.Scopes) == 0 {
+			continue
+		}
+		rp := RoutePolicy{Method: route.Method, Path: route.Path, Permissions: route.Scopes, Roles: []string{}}
+		for _, role := range names {
+			if p.roleGrantsAll(role, route.Scopes) {
+				rp.Roles = append(rp.Roles, role)
+			}
+		}
+		m.Routes = append(m.Routes, rp)
+	}
+	return m
+}
+
+// roleGrantsAll reports whether role grants every permission. Called with p.mu held.
+func (p *Policy) roleGrantsAll(role string, permissions []string) bool {
+	for _, perm := range permissions {
+		if !p.roleGrants(role, perm) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatrixHandler returns a handler responding with Matrix(engine). Mount it
+// behind admin auth.
+func (p *Policy) MatrixHandler(engine *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response.Object(c, p.Matrix(engine))
+	}
+}
@@ -0,0 +1,102 @@
+package authz_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/authz"
+)
+
+func newPolicy() *authz.Policy {
+	policy := authz.NewPolicy()
+	policy.Grant("admin", authz.AllPermissions)
+	policy.Grant("editor", "galleries:read", "galleries:write")
+	policy.Grant("viewer", "galleries:read")
+	return policy
+}
+
+func TestPolicyRequire(t *testing.T) {
+	policy := newPolicy()
+
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		wantStatus int
+	}{
+		{"anonymous", nil, http.StatusUnauthorized},
+		{"viewer", &auth.Principal{ID: "usr_1", Roles: []string{"viewer"}}, http.StatusForbidden},
+		{"editor", &auth.Principal{ID: "usr_2", Roles: []string{"viewer", "editor"}}, http.StatusOK},
+		{"admin", &auth.Principal{ID: "usr_3", Roles: []string{"admin"}}, http.StatusOK},
+		{"scoped api key", &auth.Principal{ID: "key_1", Scopes: []string{"galleries:write"}}, http.StatusOK},
+		{"unknown role", &auth.Principal{ID: "usr_4", Roles: []string{"owner"}}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.principal != nil {
+					auth.SetPrincipal(c, *tt.principal)
+				}
+			})
+			policy.Handle(router, http.MethodPatch, "/rbac/galleries/:id", []string{"galleries:write"}, func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PATCH", "/rbac/galleries/g1", nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestPolicyMatrix(t *testing.T) {
+	policy := newPolicy()
+
+	router := gin.New()
+	admin := router.Group("/matrix")
+	policy.Handle(admin, http.MethodGet, "/galleries", []string{"galleries:read"}, func(c *gin.Context) {})
+	policy.Handle(admin, http.MethodDelete, "/galleries/:id", []string{"galleries:delete"}, func(c *gin.Context) {})
+	router.GET("/matrix/policy", policy.MatrixHandler(router))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/matrix/policy", nil)
+	router.ServeHTTP(w, req)
+
+	var matrix authz.Matrix
+	if err := json.Unmarshal(w.Body.Bytes(), &matrix); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if matrix.Object != "policy" || len(matrix.Roles) != 3 {
+		t.Errorf("unexpected matrix %s", w.Body.String())
+	}
+
+	want := map[string][]string{
+		"DELETE /matrix/galleries/:id": {"admin"},
+		"GET /matrix/galleries":        {"admin", "editor", "viewer"},
+	}
+	if len(matrix.Routes) != len(want) {
+		t.Fatalf("expected %d routes, got %d", len(want), len(matrix.Routes))
+	}
+	for _, route := range matrix.Routes {
+		roles := want[route.Method+" "+route.Path]
+		if len(roles) != len(route.Roles) {
+			t.Errorf("%s %s: expected roles %v, got %v", route.Method, route.Path, roles, route.Roles)
+			continue
+		}
+		for i := range roles {
+			if roles[i] != route.Roles[i] {
+				t.Errorf("%s %s: expected roles %v, got %v", route.Method, route.Path, roles, route.Roles)
+			}
+		}
+	}
+}
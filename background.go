@@ -0,0 +1,58 @@
+package ginapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Go runs fn in a new goroutine, detached from the request ctx belongs to,
+// for fire-and-forget work started by a handler:
+//
+//	ginapi.Go(c, func(ctx context.Context) {
+//	    if err := mailer.SendWelcome(ctx, user); err != nil {
+//	        slog.ErrorContext(ctx, "welcome mail failed", "error", err)
+//	    }
+//	})
+//
+// fn's context keeps ctx's values (the request ID captured by
+// requestctx.Middleware, the principal, the language) but isn't canceled
+// when the response is written; add a timeout inside fn if the work must
+// end. ctx may be a *gin.Context, which is never handed to fn, since gin
+// reuses it for the next request. A panic in fn is recovered and sent to
+// the Reporter set with response.SetReporter, with the request and route
+// it came from, instead of crashing the process.
+func Go(ctx context.Context, fn func(ctx context.Context)) {
+	var r *http.Request
+	var route string
+	if c, ok := ctx.(*gin.Context); ok {
+		r, route = c.Request, c.FullPath()
+		ctx = context.Background()
+		if r != nil {
+			ctx = r.Context()
+		}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			err, ok := v.(error)
+			if !ok {
+				err = fmt.Errorf("panic: %v", v)
+			}
+			response.ReportError(ctx, r, route, err)
+		}()
+		fn(ctx)
+	}()
+}
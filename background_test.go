@@ -0,0 +1,60 @@
+package ginapi_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/requestctx"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestGo(t *testing.T) {
+	reports := make(chan response.Report, 1)
+	response.SetReporter(response.ReporterFunc(func(ctx context.Context, r response.Report) {
+		reports <- r
+	}))
+	defer response.SetReporter(nil)
+
+	values := make(chan requestctx.Values, 1)
+	canceled := make(chan error, 1)
+	router := gin.New()
+	router.Use(requestctx.Middleware(), middleware.Language(middleware.LanguageConfig{Supported: []string{"en", "ja"}}))
+	router.GET("/galleries/:id", func(c *gin.Context) {
+		auth.SetPrincipal(c, auth.Principal{ID: "usr_1", Type: auth.TypeUser})
+		done := make(chan struct{})
+		ginapi.Go(c, func(ctx context.Context) {
+			<-done
+			values <- requestctx.From(ctx)
+			canceled <- ctx.Err()
+			panic("mailer exploded")
+		})
+		c.Status(http.StatusAccepted)
+		close(done)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/galleries/1?lang=ja", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	v := <-values
+	if v.RequestID != "req-1" || v.Language != "ja" || v.Principal.ID != "usr_1" {
+		t.Errorf("expected the request's values, got %+v", v)
+	}
+	if err := <-canceled; err != nil {
+		t.Errorf("expected the context to outlive the request, got %v", err)
+	}
+	r := <-reports
+	if r.Err.Error() != "panic: mailer exploded" {
+		t.Errorf("expected the panic to be reported, got %v", r.Err)
+	}
+	if r.Route != "/galleries/:id" || r.RequestID != "req-1" {
+		t.Errorf("expected the route and request ID, got %q and %q", r.Route, r.RequestID)
+	}
+}
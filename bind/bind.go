@@ -0,0 +1,108 @@
+// Package bind decodes request bodies in whichever encoding the client sent,
+// and path parameters.
+package bind
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// ErrUnsupportedMediaType is returned for a Content-Type Body can't decode.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// msgpackHandle decodes msgpack strings as Go strings and matches struct
+// fields by their json tags, like the JSON decoder.
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{WriteExt: true}
+	h.RawToString = true
+	return h
+}()
+
+// Body decodes the request body into v based on Content-Type (JSON when
+// absent, or MessagePack), then runs gin's struct validation (binding tags).
+// Validation failures are returned as a *SchemaError locating the first
+// failing field by its JSON path, e.g. "items[2].price.currency":
+//
+//	var req CreateOrderRequest
+//	if err := bind.Body(c, &req); err != nil {
+//	    var se *bind.SchemaError
+//	    switch {
+//	    case errors.Is(err, bind.ErrUnsupportedMediaType):
+//	        response.UnsupportedMediaType(c, err.Error())
+//	    case errors.As(err, &se):
+//	        response.ErrorWithInfo(c, http.StatusUnprocessableEntity, se.ErrorInfo())
+//	    default:
+//	        response.BadRequest(c, err.Error())
+//	    }
+//	    return
+//	}
+//
+// Messages for the required, email, url, and phone rules (see
+// RegisterValidators) are localized from the default i18n catalog's
+// "validation.<rule>" messages, with the Field and Param arguments.
+func Body(c *gin.Context, v any) error {
+	return fromRequest(c, c.Request, v)
+}
+
+// FromRequest is the net/http equivalent of Body. Messages are localized
+// in the language stored with middleware.WithLanguage.
+func FromRequest(r *http.Request, v any) error {
+	var ctx context.Context
+	if r != nil {
+		ctx = r.Context()
+	}
+	return fromRequest(ctx, r, v)
+}
+
+// fromRequest decodes and validates the body of r, localizing validation
+// messages for ctx.
+func fromRequest(ctx context.Context, r *http.Request, v any) error {
+	if r == nil || r.Body == nil || r.Body == http.NoBody {
+		return errors.New("request body is empty")
+	}
+
+	mediaType := "application/json"
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		parsed, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, ct)
+		}
+		mediaType = parsed
+	}
+
+	var err error
+	switch mediaType {
+	case "application/json":
+		err = json.NewDecoder(r.Body).Decode(v)
+	case response.MsgpackMediaType, response.MsgpackMediaTypeLegacy:
+		err = codec.NewDecoder(r.Body, msgpackHandle).Decode(v)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+
+	return Validate(ctx, v)
+}
+
+// Validate runs gin's struct validation (binding tags) on v, returning
+// failures as a *SchemaError like Body, with messages localized for the
+// language of ctx, which may be nil. Use it for values decoded elsewhere,
+// e.g. the rows of an import.
+func Validate(ctx context.Context, v any) error {
+	if binding.Validator == nil {
+		return nil
+	}
+	return validationError(ctx, binding.Validator.ValidateStruct(v), v)
+}
@@ -0,0 +1,178 @@
+package bind_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+
+	"github.com/doujins-org/ginapi/bind"
+)
+
+type createGallery struct {
+	Title string   `json:"title" binding:"required"`
+	Tags  []string `json:"tags"`
+	Pages int      `json:"pages"`
+}
+
+func msgpackBody(t *testing.T, v any) []byte {
+	t.Helper()
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, &codec.MsgpackHandle{WriteExt: true}).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestBody(t *testing.T) {
+	msgpack := msgpackBody(t, map[string]any{"title": "Title", "tags": []string{"a"}, "pages": 12})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		wantErr     bool
+	}{
+		{name: "json", contentType: "application/json; charset=utf-8", body: []byte(`{"title":"Title","tags":["a"],"pages":12}`)},
+		{name: "no content type is json", body: []byte(`{"title":"Title","tags":["a"],"pages":12}`)},
+		{name: "msgpack", contentType: "application/msgpack", body: msgpack},
+		{name: "legacy msgpack", contentType: "application/x-msgpack", body: msgpack},
+		{name: "validation", contentType: "application/json", body: []byte(`{"pages":1}`), wantErr: true},
+		{name: "malformed", contentType: "application/json", body: []byte(`{`), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/galleries", bytes.NewReader(tt.body))
+			if tt.contentType != "" {
+				c.Request.Header.Set("Content-Type", tt.contentType)
+			}
+
+			var req createGallery
+			err := bind.Body(c, &req)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if req.Title != "Title" || len(req.Tags) != 1 || req.Tags[0] != "a" || req.Pages != 12 {
+				t.Errorf("unexpected decoded value %+v", req)
+			}
+		})
+	}
+}
+
+func TestBodyUnsupportedMediaType(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/galleries", bytes.NewReader([]byte("title=x")))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var req createGallery
+	if err := bind.FromRequest(r, &req); !errors.Is(err, bind.ErrUnsupportedMediaType) {
+		t.Errorf("expected ErrUnsupportedMediaType, got %v", err)
+	}
+}
+
+type orderPrice struct {
+	Amount   int    `json:"amount" binding:"min=1"`
+	Currency string `json:"currency" binding:"oneof=EUR JPY"`
+}
+
+type orderItem struct {
+	SKU   string      `json:"sku" binding:"required"`
+	Price *orderPrice `json:"price" binding:"required"`
+}
+
+type orderMeta struct {
+	Channel string `json:"channel" binding:"required"`
+}
+
+type createOrder struct {
+	orderMeta
+	Items []orderItem          `json:"items" binding:"dive"`
+	Notes map[string]orderItem `binding:"dive"`
+}
+
+func TestBodyFieldPaths(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantField   string
+		wantPointer string
+		wantCode    string
+	}{
+		{
+			name:        "nested in slice",
+			body:        `{"channel":"web","items":[{"sku":"a","price":{"amount":1,"currency":"EUR"}},{"sku":"b","price":{"amount":1,"currency":"USD"}}]}`,
+			wantField:   "items[1].price.currency",
+			wantPointer: "/items/1/price/currency",
+			wantCode:    "invalid_param",
+		},
+		{
+			name:        "missing nested",
+			body:        `{"channel":"web","items":[{"price":{"amount":1,"currency":"EUR"}}]}`,
+			wantField:   "items[0].sku",
+			wantPointer: "/items/0/sku",
+			wantCode:    "missing_param",
+		},
+		{
+			name:        "embedded struct is flattened",
+			body:        `{"items":[]}`,
+			wantField:   "channel",
+			wantPointer: "/channel",
+			wantCode:    "missing_param",
+		},
+		{
+			name:        "untagged field and map key",
+			body:        `{"channel":"web","Notes":{"gift/wrap":{"sku":"a","price":{"amount":0,"currency":"EUR"}}}}`,
+			wantField:   "Notes.gift/wrap.price.amount",
+			wantPointer: "/Notes/gift~1wrap/price/amount",
+			wantCode:    "invalid_param",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewReader([]byte(tt.body)))
+			var req createOrder
+			err := bind.FromRequest(r, &req)
+
+			var se *bind.SchemaError
+			if !errors.As(err, &se) {
+				t.Fatalf("expected *SchemaError, got %v", err)
+			}
+			if se.Field != tt.wantField || se.Pointer != tt.wantPointer || se.Code != tt.wantCode {
+				t.Errorf("expected %s on %q (%q), got %s on %q (%q): %s", tt.wantCode, tt.wantField, tt.wantPointer, se.Code, se.Field, se.Pointer, se.Message)
+			}
+			if info := se.ErrorInfo(); info.Param != tt.wantField || info.Pointer != tt.wantPointer {
+				t.Errorf("expected ErrorInfo to carry the path, got %+v", info)
+			}
+			if errors.Unwrap(err) == nil {
+				t.Error("expected the validator error to be wrapped")
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := bind.Validate(nil, &createGallery{Title: "Summer"}); err != nil {
+		t.Errorf("expected valid, got %v", err)
+	}
+
+	err := bind.Validate(nil, &createGallery{})
+	var se *bind.SchemaError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected *SchemaError, got %v", err)
+	}
+	if se.Field != "title" || se.Code != "missing_param" {
+		t.Errorf("expected missing_param on title, got %s on %q", se.Code, se.Field)
+	}
+}
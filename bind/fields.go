@@ -0,0 +1,168 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/doujins-org/ginapi/i18n"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// validationError converts the first failure of gin's struct validation
+// into a SchemaError that names the field by its JSON path, so clients can
+// highlight it. Other errors are returned as is.
+func validationError(ctx context.Context, err error, v any) error {
+	return validationErrorAt(ctx, err, func(namespace string) fieldPath {
+		return jsonPath(reflect.TypeOf(v), namespace)
+	})
+}
+
+// validationErrorAt is validationError naming the field with path, which
+// maps a validator struct namespace.
+func validationErrorAt(ctx context.Context, err error, path func(namespace string) fieldPath) error {
+	var failures validator.ValidationErrors
+	if !errors.As(err, &failures) || len(failures) == 0 {
+		return err
+	}
+	fe := failures[0]
+	p := path(fe.StructNamespace())
+	code, message := failureMessage(ctx, fe, p.field)
+	return &SchemaError{Field: p.field, Pointer: p.pointer, Code: code, Message: message, err: err}
+}
+
+// failureMessage returns the error code and message for a validation
+// failure of field.
+func failureMessage(ctx context.Context, fe validator.FieldError, field string) (code, message string) {
+	if fe.Tag() == "required" {
+		return response.ErrorCodeMissingParam, ruleMessage(ctx, fe.Tag(), field, fe.Param())
+	}
+	if _, ok := ruleMessages[fe.Tag()]; ok {
+		return response.ErrorCodeInvalidParam, ruleMessage(ctx, fe.Tag(), field, fe.Param())
+	}
+	rule := fe.Tag()
+	if fe.Param() != "" {
+		rule += "=" + fe.Param()
+	}
+	return response.ErrorCodeInvalidParam, fmt.Sprintf("%s failed the %s rule", field, rule)
+}
+
+// ruleMessages are the English messages of the rules with localized
+// messages, for the field and the rule's param.
+var ruleMessages = map[string]func(field, param string) string{
+	"required": func(field, _ string) string { return field + " is required" },
+	"email":    func(field, _ string) string { return field + " must be a valid email address" },
+	"url": func(field, param string) string {
+		schemes := strings.Fields(param)
+		if len(schemes) == 0 {
+			schemes = defaultSchemes
+		}
+		return field + " must be a URL starting with " + strings.Join(schemes, ":// or ") + "://"
+	},
+	"phone": phoneMessage,
+	"e164":  phoneMessage,
+	// File checks of Multipart, for its accept and maxsize tags
+	"file_type": func(field, param string) string {
+		return field + " must be a file of type " + strings.ReplaceAll(param, ",", ", ")
+	},
+	"file_size": func(field, param string) string { return field + " must be at most " + param },
+}
+
+func phoneMessage(field, _ string) string {
+	return field + " must be a phone number in international format, e.g. +14155550123"
+}
+
+// ruleMessage returns the message for a failed rule in the request's
+// language, from the default i18n catalog's "validation.<rule>" message
+// with the Field and Param arguments, or in English if it has none.
+func ruleMessage(ctx context.Context, rule, field, param string) string {
+	key := "validation." + rule
+	if ctx != nil {
+		if msg := i18n.Default().Translate(i18n.Language(ctx), key, i18n.Args{"Field": field, "Param": param}); msg != key {
+			return msg
+		}
+	}
+	return ruleMessages[rule](field, param)
+}
+
+// jsonPath maps a validator struct namespace like
+// "CreateOrder.Items[2].Price.Currency" onto the JSON names of t's fields.
+// Unknown fields keep their Go names.
+func jsonPath(t reflect.Type, namespace string) fieldPath {
+	return tagPath(t, namespace, "json")
+}
+
+// tagPath is jsonPath naming fields by their tag, e.g. "form".
+func tagPath(t reflect.Type, namespace, tag string) fieldPath {
+	_, rest, _ := strings.Cut(namespace, ".") // drop the top-level type name
+	var p fieldPath
+	for rest != "" {
+		name := rest
+		if i := strings.IndexAny(rest, ".["); i >= 0 {
+			name, rest = rest[:i], rest[i:]
+		} else {
+			rest = ""
+		}
+
+		t = indirect(t)
+		if t != nil && t.Kind() == reflect.Struct {
+			if f, ok := t.FieldByName(name); ok {
+				t = f.Type
+				tagName, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+				if f.Anonymous && tagName == "" {
+					name = "" // embedded structs are flattened
+				} else if tagName != "" && tagName != "-" {
+					name = tagName
+				}
+			} else {
+				t = nil
+			}
+		} else {
+			t = nil
+		}
+		if name != "" {
+			p = p.key(name)
+		}
+
+		for strings.HasPrefix(rest, "[") {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return p
+			}
+			key := rest[1:end]
+			rest = rest[end+1:]
+			t = indirect(t)
+			switch {
+			case t != nil && t.Kind() == reflect.Map:
+				t = t.Elem()
+				p = p.key(key)
+			default:
+				if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+					t = t.Elem()
+				} else {
+					t = nil
+				}
+				if i, err := strconv.Atoi(key); err == nil {
+					p = p.index(i)
+				} else {
+					p = p.key(key)
+				}
+			}
+		}
+		rest = strings.TrimPrefix(rest, ".")
+	}
+	return p
+}
+
+// indirect returns the type t points to, or t.
+func indirect(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,76 @@
+package bind
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/doujins-org/ginapi/ids"
+	"github.com/doujins-org/ginapi/response"
+	"github.com/doujins-org/ginapi/slug"
+)
+
+// setQueryIDs sets fv, a string or a slice of strings with an id tag, from
+// n, parsing the items with the Formatter registered for prefix (see
+// ids.Register). Items that aren't valid IDs are a 400 invalid_param
+// naming the item, or invalid_id_checksum for a checksum mismatch.
+func setQueryIDs(fv reflect.Value, n *queryNode, prefix string, p fieldPath) error {
+	var items []string
+	switch {
+	case fv.Kind() == reflect.String:
+		if len(n.values) == 0 {
+			return nil
+		}
+		items = n.values[:1]
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+		if items = queryItems(n); len(items) == 0 {
+			return nil
+		}
+	default:
+		return fmt.Errorf("bind: id tag on %s for query param %s needs a string or a slice of strings", fv.Type(), p.field)
+	}
+
+	prefixed := make([]string, len(items))
+	for i, item := range items {
+		prefixed[i] = prefixedID(item, prefix)
+	}
+	parsed, invalid := ids.ParseMany(ids.Lookup(prefix).Normalize, prefixed)
+	if len(invalid) > 0 {
+		item := p
+		if fv.Kind() == reflect.Slice {
+			item = p.index(invalid[0].Index)
+		}
+		code := response.ErrorCodeInvalidParam
+		var pe *ids.ParseError
+		if errors.As(invalid[0], &pe) && pe.Code != "" {
+			code = pe.Code
+		}
+		return &SchemaError{
+			Field:   item.field,
+			Pointer: item.pointer,
+			Code:    code,
+			Message: fmt.Sprintf("%s must be a %s ID, got %q", item.field, prefix, items[invalid[0].Index]),
+			err:     invalid[0],
+		}
+	}
+
+	if fv.Kind() == reflect.String {
+		fv.SetString(parsed[0])
+		return nil
+	}
+	s := reflect.MakeSlice(fv.Type(), len(parsed), len(parsed))
+	for i, id := range parsed {
+		s.Index(i).SetString(id)
+	}
+	fv.Set(s)
+	return nil
+}
+
+// prefixedID returns the ID in value, a prefixed ID, a raw one, or a
+// slug.Join segment, with its prefix.
+func prefixedID(value, prefix string) string {
+	if _, id, ok := slug.Parse(value, prefix+"_"); ok {
+		return id
+	}
+	return prefix + "_" + value
+}
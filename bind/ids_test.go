@@ -0,0 +1,66 @@
+package bind_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/doujins-org/ginapi/bind"
+	"github.com/doujins-org/ginapi/ids"
+)
+
+func init() {
+	ids.Register(ids.Formatter{Prefix: "inv", Checksum: true})
+}
+
+type batchQuery struct {
+	IDs      []string `form:"ids" id:"gal" binding:"max=3"`
+	Owner    string   `form:"owner" id:"usr"`
+	Invoices []string `form:"invoices" id:"inv"`
+}
+
+func TestQueryIDs(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantIDs   []string
+		wantOwner string
+		wantField string
+		wantMsg   string
+		wantErr   error
+	}{
+		{query: "ids=gal_1,gal_2B&owner=9", wantIDs: []string{"gal_1", "gal_2B"}, wantOwner: "usr_9"},
+		{query: "ids[]=1&ids[]=gal_2", wantIDs: []string{"gal_1", "gal_2"}},
+		{query: "ids=gal_1,usr_2", wantField: "ids[1]", wantMsg: `ids[1] must be a gal ID, got "usr_2"`},
+		{query: "owner=gal_1", wantField: "owner", wantMsg: `owner must be a usr ID, got "gal_1"`},
+		{query: "ids=1,2,3,4", wantField: "ids", wantMsg: "ids failed the max=3 rule"},
+		{query: "ids=gal_0001", wantField: "ids[0]", wantMsg: `ids[0] must be a gal ID, got "gal_0001"`, wantErr: ids.ErrMalformed},
+		{query: "invoices=inv_3D7T,inv_3D8T", wantField: "invoices[1]", wantMsg: `invoices[1] must be a inv ID, got "inv_3D8T"`, wantErr: ids.ErrChecksum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var q batchQuery
+			err := bindQuery("/galleries?"+tt.query, &q)
+
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if !reflect.DeepEqual(q.IDs, tt.wantIDs) || q.Owner != tt.wantOwner {
+					t.Errorf("expected %v and %q, got %v and %q", tt.wantIDs, tt.wantOwner, q.IDs, q.Owner)
+				}
+				return
+			}
+			var se *bind.SchemaError
+			if !errors.As(err, &se) {
+				t.Fatalf("expected *SchemaError, got %v", err)
+			}
+			if se.Field != tt.wantField || se.Message != tt.wantMsg {
+				t.Errorf("expected %q: %q, got %q: %q", tt.wantField, tt.wantMsg, se.Field, se.Message)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
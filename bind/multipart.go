@@ -0,0 +1,285 @@
+package bind
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// Multipart binds a multipart/form-data request into the fields of the
+// struct v points to, by their form tags, then runs gin's struct
+// validation (binding tags), so endpoints mixing fields, JSON metadata,
+// and files get the same errors as Body:
+//
+//	var req struct {
+//	    Title    string                  `form:"title" binding:"required,max=200"`
+//	    Metadata GalleryMetadata         `form:"metadata" binding:"required"`
+//	    Cover    *multipart.FileHeader   `form:"cover" binding:"required" accept:"image/png,image/jpeg" maxsize:"5MB"`
+//	    Pages    []*multipart.FileHeader `form:"pages" accept:"image/*" maxsize:"20MB"`
+//	}
+//	if err := bind.Multipart(c, &req); err != nil {
+//	    ... // as for Body
+//	}
+//
+// String, integer, float, and bool fields, and slices of them, take the
+// part's values. A *multipart.FileHeader field takes the part's first
+// file, and a []*multipart.FileHeader all of them. Fields of any other
+// type, such as structs and maps, are decoded as JSON from a field or a
+// file part. A file field's accept tag lists the media types its files
+// may have ("image/*" matches any image), and maxsize the largest size,
+// in bytes or with a KB, MB, or GB suffix.
+//
+// Failures are a *SchemaError naming the part, or a nested field of a JSON
+// part ("metadata.language"); file type and size messages are localized
+// like the validation rules' from "validation.file_type" and
+// "validation.file_size". Other Content-Types are ErrUnsupportedMediaType.
+// Files beyond gin's MaxMultipartMemory are spooled to temporary files,
+// removed when the request ends.
+func Multipart(c *gin.Context, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind: Multipart needs a pointer to a struct, got %T", v)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(c.ContentType()); mediaType != "multipart/form-data" {
+		return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, c.ContentType())
+	}
+	form, err := c.MultipartForm()
+	if err != nil {
+		return fmt.Errorf("invalid multipart form: %w", err)
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		name := f.Tag.Get("form")
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		if err := setPart(c, rv.Field(i), f, name, form); err != nil {
+			return err
+		}
+	}
+
+	if binding.Validator == nil {
+		return nil
+	}
+	return multipartValidationError(c, binding.Validator.ValidateStruct(v), rt)
+}
+
+// setPart sets fv, field f of the form's struct, from part name.
+func setPart(ctx context.Context, fv reflect.Value, f reflect.StructField, name string, form *multipart.Form) error {
+	files := form.File[name]
+	values := form.Value[name]
+
+	switch {
+	case f.Type == fileHeaderType || f.Type == reflect.SliceOf(fileHeaderType):
+		if len(files) == 0 {
+			return nil
+		}
+		if err := checkFiles(ctx, f, name, files); err != nil {
+			return err
+		}
+		if f.Type == fileHeaderType {
+			fv.Set(reflect.ValueOf(files[0]))
+		} else {
+			fv.Set(reflect.ValueOf(files))
+		}
+		return nil
+
+	case isScalar(f.Type.Kind()):
+		if len(values) == 0 {
+			return nil
+		}
+		return setPartValue(fv, name, values[0])
+
+	case f.Type.Kind() == reflect.Slice && isScalar(f.Type.Elem().Kind()):
+		s := reflect.MakeSlice(f.Type, len(values), len(values))
+		for i, value := range values {
+			if err := setPartValue(s.Index(i), name, value); err != nil {
+				return err
+			}
+		}
+		if len(values) > 0 {
+			fv.Set(s)
+		}
+		return nil
+	}
+
+	// Anything else is JSON, sent as a field or as a file
+	var r io.Reader
+	switch {
+	case len(values) > 0:
+		r = strings.NewReader(values[0])
+	case len(files) > 0:
+		file, err := files[0].Open()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	default:
+		return nil
+	}
+	if err := json.NewDecoder(r).Decode(fv.Addr().Interface()); err != nil {
+		return &SchemaError{
+			Field:   name,
+			Pointer: "/" + escapePointer(name),
+			Code:    response.ErrorCodeInvalidParam,
+			Message: name + " must be valid JSON: " + err.Error(),
+			err:     err,
+		}
+	}
+	return nil
+}
+
+// isScalar reports whether setScalar parses values of kind k.
+func isScalar(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// setPartValue parses value, of part name, into fv.
+func setPartValue(fv reflect.Value, name, value string) error {
+	want, err := setScalar(fv, value)
+	if err == nil {
+		return nil
+	}
+	return &SchemaError{
+		Field:   name,
+		Pointer: "/" + escapePointer(name),
+		Code:    response.ErrorCodeInvalidParam,
+		Message: fmt.Sprintf("%s must be %s, got %q", name, want, value),
+		err:     err,
+	}
+}
+
+// checkFiles checks files, of part name, against f's accept and maxsize
+// tags.
+func checkFiles(ctx context.Context, f reflect.StructField, name string, files []*multipart.FileHeader) error {
+	accept := f.Tag.Get("accept")
+	maxSize := f.Tag.Get("maxsize")
+	limit, err := parseSize(maxSize)
+	if err != nil {
+		return fmt.Errorf("bind: invalid maxsize tag on %s: %w", f.Name, err)
+	}
+
+	for _, fh := range files {
+		if accept != "" && !acceptsType(accept, fh.Header.Get("Content-Type")) {
+			return &SchemaError{
+				Field:   name,
+				Pointer: "/" + escapePointer(name),
+				Code:    response.ErrorCodeInvalidParam,
+				Message: ruleMessage(ctx, "file_type", name, accept),
+			}
+		}
+		if limit > 0 && fh.Size > limit {
+			return &SchemaError{
+				Field:   name,
+				Pointer: "/" + escapePointer(name),
+				Code:    response.ErrorCodeInvalidParam,
+				Message: ruleMessage(ctx, "file_size", name, maxSize),
+			}
+		}
+	}
+	return nil
+}
+
+// acceptsType reports whether contentType matches one of the
+// comma-separated media types of accept, which may end in "/*".
+func acceptsType(accept, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range strings.Split(accept, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == mediaType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// sizeUnits are the suffixes of maxsize tags.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// parseSize parses a size like "512", "64KB", or "5MB"; "" is no limit.
+func parseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if n, ok := strings.CutSuffix(strings.ToUpper(s), u.suffix); ok {
+			s, unit = strings.TrimSpace(n), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("size must be a positive number of bytes, KB, MB, or GB")
+	}
+	return n * unit, nil
+}
+
+// multipartValidationError converts the first failure of gin's struct
+// validation into a SchemaError naming the part by its form tag, and
+// fields within a JSON part by their JSON names.
+func multipartValidationError(ctx context.Context, err error, rt reflect.Type) error {
+	var failures validator.ValidationErrors
+	if !errors.As(err, &failures) || len(failures) == 0 {
+		return err
+	}
+	fe := failures[0]
+	_, rest, _ := strings.Cut(fe.StructNamespace(), ".") // drop the top-level type name
+	fieldName := rest
+	if i := strings.IndexAny(rest, ".["); i >= 0 {
+		fieldName, rest = rest[:i], rest[i:]
+	} else {
+		rest = ""
+	}
+
+	p := jsonPath(rt, fe.StructNamespace())
+	if f, ok := rt.FieldByName(fieldName); ok && f.Tag.Get("form") != "" {
+		// Map the rest of the path within the part's type, by JSON names
+		part := reflect.StructOf([]reflect.StructField{{Name: "Part", Type: f.Type}})
+		sub := jsonPath(part, "_.Part"+rest)
+		name := f.Tag.Get("form")
+		p = fieldPath{
+			field:   name + strings.TrimPrefix(sub.field, "Part"),
+			pointer: "/" + escapePointer(name) + strings.TrimPrefix(sub.pointer, "/Part"),
+		}
+	}
+	code, message := failureMessage(ctx, fe, p.field)
+	return &SchemaError{Field: p.field, Pointer: p.pointer, Code: code, Message: message, err: err}
+}
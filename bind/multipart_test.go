@@ -0,0 +1,194 @@
+package bind_test
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/bind"
+	"github.com/doujins-org/ginapi/i18n"
+)
+
+type galleryMetadata struct {
+	Language string   `json:"language" binding:"required"`
+	Tags     []string `json:"tags"`
+}
+
+type uploadGallery struct {
+	Title    string                  `form:"title" binding:"required"`
+	Pages    int                     `form:"pages"`
+	Labels   []string                `form:"label"`
+	Metadata galleryMetadata         `form:"metadata"`
+	Cover    *multipart.FileHeader   `form:"cover" binding:"required" accept:"image/png,image/jpeg" maxsize:"1KB"`
+	Extras   []*multipart.FileHeader `form:"extra" accept:"image/*"`
+}
+
+// part is a field, or a file when contentType is set.
+type part struct {
+	name, contentType, body string
+}
+
+func multipartRequest(t *testing.T, parts ...part) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, p := range parts {
+		if p.contentType == "" {
+			w.WriteField(p.name, p.body)
+			continue
+		}
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", `form-data; name="`+p.name+`"; filename="`+p.name+`.bin"`)
+		h.Set("Content-Type", p.contentType)
+		pw, err := w.CreatePart(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pw.Write([]byte(p.body))
+	}
+	w.Close()
+	r := httptest.NewRequest(http.MethodPost, "/galleries", &body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	return r
+}
+
+func bindMultipart(r *http.Request, lang string, v any) error {
+	var err error
+	router := gin.New()
+	router.POST("/galleries", func(c *gin.Context) {
+		if lang != "" {
+			c.Set("language", lang)
+		}
+		err = bind.Multipart(c, v)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	return err
+}
+
+func TestMultipart(t *testing.T) {
+	r := multipartRequest(t,
+		part{name: "title", body: "Summer"},
+		part{name: "pages", body: "12"},
+		part{name: "label", body: "a"},
+		part{name: "label", body: "b"},
+		part{name: "metadata", contentType: "application/json", body: `{"language":"ja","tags":["beach"]}`},
+		part{name: "cover", contentType: "image/png", body: "png"},
+		part{name: "extra", contentType: "image/webp", body: "webp"},
+		part{name: "extra", contentType: "image/avif", body: "avif"},
+	)
+
+	var req uploadGallery
+	if err := bindMultipart(r, "", &req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if req.Title != "Summer" || req.Pages != 12 || strings.Join(req.Labels, ",") != "a,b" {
+		t.Errorf("expected the fields bound, got %+v", req)
+	}
+	if req.Metadata.Language != "ja" || len(req.Metadata.Tags) != 1 {
+		t.Errorf("expected the JSON part decoded, got %+v", req.Metadata)
+	}
+	if req.Cover == nil || req.Cover.Size != 3 || len(req.Extras) != 2 {
+		t.Errorf("expected the files bound, got %v and %d extras", req.Cover, len(req.Extras))
+	}
+}
+
+func TestMultipartErrors(t *testing.T) {
+	catalog := i18n.New("en")
+	if err := catalog.Add("ja", map[string]any{
+		"validation": map[string]any{"file_size": "{{.Field}}は{{.Param}}以下にしてください"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	previous := i18n.Default()
+	i18n.SetDefault(catalog)
+	t.Cleanup(func() { i18n.SetDefault(previous) })
+
+	cover := part{name: "cover", contentType: "image/jpeg", body: "jpeg"}
+	tests := []struct {
+		name        string
+		lang        string
+		parts       []part
+		wantField   string
+		wantPointer string
+		wantCode    string
+		wantMsg     string
+	}{
+		{
+			"missing field", "",
+			[]part{cover},
+			"title", "/title", "missing_param", "title is required",
+		},
+		{
+			"missing file", "",
+			[]part{{name: "title", body: "Summer"}, {name: "metadata", body: `{"language":"ja"}`}},
+			"cover", "/cover", "missing_param", "cover is required",
+		},
+		{
+			"unparsable field", "",
+			[]part{{name: "title", body: "Summer"}, {name: "pages", body: "many"}, cover},
+			"pages", "/pages", "invalid_param", `pages must be an integer, got "many"`,
+		},
+		{
+			"invalid JSON", "",
+			[]part{{name: "title", body: "Summer"}, {name: "metadata", body: `{"language":`}, cover},
+			"metadata", "/metadata", "invalid_param", "metadata must be valid JSON: unexpected EOF",
+		},
+		{
+			"nested JSON field", "",
+			[]part{{name: "title", body: "Summer"}, {name: "metadata", body: `{"tags":[]}`}, cover},
+			"metadata.language", "/metadata/language", "missing_param", "metadata.language is required",
+		},
+		{
+			"file type", "",
+			[]part{{name: "title", body: "Summer"}, {name: "cover", contentType: "image/gif", body: "gif"}},
+			"cover", "/cover", "invalid_param", "cover must be a file of type image/png, image/jpeg",
+		},
+		{
+			"wildcard file type", "",
+			[]part{{name: "title", body: "Summer"}, cover, {name: "extra", contentType: "video/mp4", body: "mp4"}},
+			"extra", "/extra", "invalid_param", "extra must be a file of type image/*",
+		},
+		{
+			"file size", "",
+			[]part{{name: "title", body: "Summer"}, {name: "cover", contentType: "image/png", body: strings.Repeat("x", 1025)}},
+			"cover", "/cover", "invalid_param", "cover must be at most 1KB",
+		},
+		{
+			"localized file size", "ja",
+			[]part{{name: "title", body: "Summer"}, {name: "cover", contentType: "image/png", body: strings.Repeat("x", 1025)}},
+			"cover", "/cover", "invalid_param", "coverは1KB以下にしてください",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req uploadGallery
+			err := bindMultipart(multipartRequest(t, tt.parts...), tt.lang, &req)
+
+			var se *bind.SchemaError
+			if !errors.As(err, &se) {
+				t.Fatalf("expected *SchemaError, got %v", err)
+			}
+			if se.Field != tt.wantField || se.Pointer != tt.wantPointer || se.Code != tt.wantCode || se.Message != tt.wantMsg {
+				t.Errorf("expected %s on %q (%q): %q, got %s on %q (%q): %q", tt.wantCode, tt.wantField, tt.wantPointer, tt.wantMsg, se.Code, se.Field, se.Pointer, se.Message)
+			}
+		})
+	}
+}
+
+func TestMultipartUnsupportedMediaType(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/galleries", strings.NewReader(`{"title":"Summer"}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	var req uploadGallery
+	if err := bindMultipart(r, "", &req); !errors.Is(err, bind.ErrUnsupportedMediaType) {
+		t.Errorf("expected ErrUnsupportedMediaType, got %v", err)
+	}
+}
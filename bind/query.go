@@ -0,0 +1,250 @@
+package bind
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Query binds the query string into the fields of the struct v points to,
+// by their form tags, then runs gin's struct validation (binding tags).
+// Bracketed params, as PHP and Rails clients send them, and plain or
+// comma-separated ones bind the same fields:
+//
+//	var q struct {
+//	    IDs    []int             `form:"ids"`
+//	    Filter map[string]string `form:"filter"`
+//	    Range  struct {
+//	        From int `form:"from"`
+//	        To   int `form:"to"`
+//	    } `form:"range"`
+//	}
+//	// ?ids=1&ids=2, ?ids[]=1&ids[]=2, ?ids[0]=1&ids[1]=2, and ?ids=1,2 all set IDs
+//	// ?filter[status]=active&filter[tier]=free sets Filter
+//	// ?range[from]=1&range[to]=9 sets Range
+//	if err := bind.Query(c, &q); err != nil {
+//	    ... // as for Body
+//	}
+//
+// Fields may be strings, integers, floats, or bools; slices of them, whose
+// values are also split on commas; maps from strings to those or to
+// slices; and structs, whose fields are bound from the bracketed keys by
+// their own form tags. Failures are a *SchemaError naming the param like
+// Body's ("filter.status", "ids[1]"), with messages localized the same way.
+//
+// A string or string slice field with an id tag holds prefixed IDs, parsed
+// as URI parses them, with the Formatter registered for the prefix; an
+// item that isn't a valid ID is a 400 invalid_param, or
+// invalid_id_checksum if its check character doesn't match.
+// A max binding caps how many a batch endpoint accepts:
+//
+//	var q struct {
+//	    IDs []string `form:"ids" id:"gal" binding:"required,max=100"`
+//	}
+//	// ?ids=gal_1,gal_2B sets IDs to gal_1 and gal_2B
+func Query(c *gin.Context, v any) error {
+	return bindQuery(c, c.Request.URL.Query(), v)
+}
+
+// FromQuery is the net/http equivalent of Query. Messages are localized in
+// the language stored with middleware.WithLanguage.
+func FromQuery(r *http.Request, v any) error {
+	return bindQuery(r.Context(), r.URL.Query(), v)
+}
+
+// bindQuery binds and validates values into v.
+func bindQuery(ctx context.Context, values url.Values, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind: Query needs a pointer to a struct, got %T", v)
+	}
+	if err := setQueryStruct(rv.Elem(), parseQuery(values), fieldPath{}); err != nil {
+		return err
+	}
+
+	if binding.Validator == nil {
+		return nil
+	}
+	err := binding.Validator.ValidateStruct(v)
+	return validationErrorAt(ctx, err, func(namespace string) fieldPath {
+		return tagPath(rv.Type(), namespace, "form")
+	})
+}
+
+// queryNode is a query param and the bracketed params below it: for
+// "filter[status]=active", the node "filter" has the child "status" with
+// the value "active".
+type queryNode struct {
+	values   []string
+	children map[string]*queryNode
+}
+
+// child returns the child named key, creating it.
+func (n *queryNode) child(key string) *queryNode {
+	if n.children == nil {
+		n.children = map[string]*queryNode{}
+	}
+	c, ok := n.children[key]
+	if !ok {
+		c = &queryNode{}
+		n.children[key] = c
+	}
+	return c
+}
+
+// parseQuery builds the tree of values' bracketed keys. A trailing "[]"
+// appends to its param; keys with unbalanced brackets are taken literally.
+func parseQuery(values url.Values) *queryNode {
+	root := &queryNode{}
+	for key, vals := range values {
+		n := root
+		for _, part := range splitQueryKey(key) {
+			n = n.child(part)
+		}
+		n.values = append(n.values, vals...)
+	}
+	return root
+}
+
+// splitQueryKey splits "a[b][c][]" into "a", "b", "c".
+func splitQueryKey(key string) []string {
+	key = strings.TrimSuffix(key, "[]")
+	i := strings.IndexByte(key, '[')
+	if i <= 0 || !strings.HasSuffix(key, "]") {
+		return []string{key}
+	}
+	parts := []string{key[:i]}
+	for rest := key[i:]; rest != ""; {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end < 0 {
+			return []string{key}
+		}
+		parts = append(parts, rest[1:end])
+		rest = rest[end+1:]
+	}
+	return parts
+}
+
+// setQueryStruct sets the fields of struct rv from the children of n.
+func setQueryStruct(rv reflect.Value, n *queryNode, p fieldPath) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("form"), ",")
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		child, ok := n.children[name]
+		if !ok {
+			continue
+		}
+		if prefix := f.Tag.Get("id"); prefix != "" {
+			if err := setQueryIDs(rv.Field(i), child, prefix, p.key(name)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := setQueryField(rv.Field(i), child, p.key(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setQueryField sets fv from n.
+func setQueryField(fv reflect.Value, n *queryNode, p fieldPath) error {
+	t := fv.Type()
+	switch {
+	case isScalar(t.Kind()):
+		if len(n.values) == 0 {
+			return nil
+		}
+		return setQueryValue(fv, n.values[0], p)
+
+	case t.Kind() == reflect.Slice && isScalar(t.Elem().Kind()):
+		items := queryItems(n)
+		if len(items) == 0 {
+			return nil
+		}
+		s := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			if err := setQueryValue(s.Index(i), item, p.index(i)); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+		return nil
+
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+		if len(n.children) == 0 {
+			return nil
+		}
+		if fv.IsNil() {
+			fv.Set(reflect.MakeMap(t))
+		}
+		for key, child := range n.children {
+			elem := reflect.New(t.Elem()).Elem()
+			if err := setQueryField(elem, child, p.key(key)); err != nil {
+				return err
+			}
+			fv.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+		}
+		return nil
+
+	case t.Kind() == reflect.Struct:
+		return setQueryStruct(fv, n, p)
+	}
+	return fmt.Errorf("bind: unsupported type %s for query param %s", t, p.field)
+}
+
+// queryItems returns the items of a list param: its values, split on
+// commas, then its indexed children ("ids[0]", "ids[1]") in index order.
+func queryItems(n *queryNode) []string {
+	var items []string
+	for _, v := range n.values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+
+	indexes := make([]int, 0, len(n.children))
+	for key := range n.children {
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		if vals := n.children[strconv.Itoa(i)].values; len(vals) > 0 {
+			items = append(items, vals[0])
+		}
+	}
+	return items
+}
+
+// setQueryValue parses value, of the param at p, into fv.
+func setQueryValue(fv reflect.Value, value string, p fieldPath) error {
+	want, err := setScalar(fv, value)
+	if err == nil {
+		return nil
+	}
+	return &SchemaError{
+		Field:   p.field,
+		Pointer: p.pointer,
+		Code:    response.ErrorCodeInvalidParam,
+		Message: fmt.Sprintf("%s must be %s, got %q", p.field, want, value),
+		err:     err,
+	}
+}
@@ -0,0 +1,113 @@
+package bind_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/bind"
+)
+
+type galleryQuery struct {
+	IDs    []int               `form:"ids"`
+	Tags   []string            `form:"tags"`
+	Filter map[string]string   `form:"filter"`
+	Facets map[string][]string `form:"facets"`
+	Range  struct {
+		From int `form:"from" binding:"min=0"`
+		To   int `form:"to"`
+	} `form:"range"`
+	Sort  string `form:"sort" binding:"omitempty,oneof=new popular"`
+	Limit int    `form:"limit"`
+}
+
+func bindQuery(target string, v any) error {
+	var err error
+	router := gin.New()
+	router.GET("/galleries", func(c *gin.Context) {
+		err = bind.Query(c, v)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	return err
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		check func(q galleryQuery) bool
+	}{
+		{"repeated", "ids=1&ids=2", func(q galleryQuery) bool { return reflect.DeepEqual(q.IDs, []int{1, 2}) }},
+		{"brackets", "ids[]=1&ids[]=2", func(q galleryQuery) bool { return reflect.DeepEqual(q.IDs, []int{1, 2}) }},
+		{"indexed", "ids[1]=2&ids[0]=1", func(q galleryQuery) bool { return reflect.DeepEqual(q.IDs, []int{1, 2}) }},
+		{"comma", "ids=1,2&tags=beach,+summer", func(q galleryQuery) bool {
+			return reflect.DeepEqual(q.IDs, []int{1, 2}) && reflect.DeepEqual(q.Tags, []string{"beach", "summer"})
+		}},
+		{"map", "filter[status]=active&filter[tier]=free", func(q galleryQuery) bool {
+			return reflect.DeepEqual(q.Filter, map[string]string{"status": "active", "tier": "free"})
+		}},
+		{"map of lists", "facets[tag][]=beach&facets[tag][]=summer&facets[lang]=en,ja", func(q galleryQuery) bool {
+			return reflect.DeepEqual(q.Facets, map[string][]string{"tag": {"beach", "summer"}, "lang": {"en", "ja"}})
+		}},
+		{"struct", "range[from]=1&range[to]=9&sort=new&limit=20", func(q galleryQuery) bool {
+			return q.Range.From == 1 && q.Range.To == 9 && q.Sort == "new" && q.Limit == 20
+		}},
+		{"unbalanced brackets", "filter[status=active", func(q galleryQuery) bool { return q.Filter == nil }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var q galleryQuery
+			if err := bindQuery("/galleries?"+tt.query, &q); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !tt.check(q) {
+				t.Errorf("unexpected binding %+v", q)
+			}
+		})
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		query       string
+		wantField   string
+		wantPointer string
+		wantMsg     string
+	}{
+		{"ids[]=1&ids[]=two", "ids[1]", "/ids/1", `ids[1] must be an integer, got "two"`},
+		{"limit=many", "limit", "/limit", `limit must be an integer, got "many"`},
+		{"range[from]=-1", "range.from", "/range/from", "range.from failed the min=0 rule"},
+		{"sort=old", "sort", "/sort", "sort failed the oneof=new popular rule"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var q galleryQuery
+			err := bindQuery("/galleries?"+tt.query, &q)
+
+			var se *bind.SchemaError
+			if !errors.As(err, &se) {
+				t.Fatalf("expected *SchemaError, got %v", err)
+			}
+			if se.Field != tt.wantField || se.Pointer != tt.wantPointer || se.Message != tt.wantMsg {
+				t.Errorf("expected %q (%q): %q, got %q (%q): %q", tt.wantField, tt.wantPointer, tt.wantMsg, se.Field, se.Pointer, se.Message)
+			}
+		})
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	var q galleryQuery
+	r := httptest.NewRequest(http.MethodGet, "/galleries?filter[status]=active&ids=3", nil)
+	if err := bind.FromQuery(r, &q); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if q.Filter["status"] != "active" || len(q.IDs) != 1 || q.IDs[0] != 3 {
+		t.Errorf("unexpected binding %+v", q)
+	}
+}
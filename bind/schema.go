@@ -0,0 +1,890 @@
+package bind
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"mime"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Schema is a compiled JSON Schema. Compile schemas once at startup with
+// CompileSchema or LoadSchemas; a Schema is safe for concurrent use.
+//
+// The supported keywords are type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, uniqueItems, minLength,
+// maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// multipleOf, allOf, anyOf, oneOf, not, and $ref to "#/$defs/..." (or
+// "#/definitions/..."). Annotations such as title, description, and format
+// are ignored; any other keyword is a compile error rather than being
+// silently skipped.
+type Schema struct {
+	root *schemaNode
+}
+
+// SchemaError is a request body that doesn't match its Schema.
+type SchemaError struct {
+	// Field is the path of the offending value, e.g. "tags[2]" or
+	// "items[2].price.currency"; empty for the body itself
+	Field string
+	// Pointer is the JSON Pointer (RFC 6901) of the offending value, e.g.
+	// "/items/2/price/currency"; empty for the body itself
+	Pointer string
+	// Code is response.ErrorCodeMissingParam for missing required
+	// properties, otherwise response.ErrorCodeInvalidParam
+	Code string
+	// Message describes the violation, e.g. "pages must be at least 1"
+	Message string
+
+	err error
+}
+
+func (e *SchemaError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the validator error a SchemaError from Body was built
+// from, if any.
+func (e *SchemaError) Unwrap() error {
+	return e.err
+}
+
+// ErrorInfo returns the error details to send for e, with the field path
+// as the param and its JSON Pointer as the pointer.
+func (e *SchemaError) ErrorInfo() response.ErrorInfo {
+	return response.ErrorInfo{
+		Type:    response.ErrorTypeInvalidRequest,
+		Code:    e.Code,
+		Message: e.Message,
+		Param:   e.Field,
+		Pointer: e.Pointer,
+	}
+}
+
+// CompileSchema compiles a JSON Schema document.
+func CompileSchema(data []byte) (*Schema, error) {
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("bind: invalid schema: %w", err)
+	}
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return nil, errors.New("bind: schema must be an object")
+	}
+
+	c := schemaCompiler{defs: map[string]*schemaNode{}}
+	for _, key := range []string{"$defs", "definitions"} {
+		defs, _ := m[key].(map[string]any)
+		for name := range defs {
+			c.defs["#/"+key+"/"+name] = &schemaNode{}
+		}
+	}
+	for _, key := range []string{"$defs", "definitions"} {
+		defs, _ := m[key].(map[string]any)
+		for name, def := range defs {
+			if err := c.compileInto(c.defs["#/"+key+"/"+name], def); err != nil {
+				return nil, fmt.Errorf("bind: schema %s/%s: %w", key, name, err)
+			}
+		}
+	}
+
+	root, err := c.compile(m)
+	if err != nil {
+		return nil, fmt.Errorf("bind: schema: %w", err)
+	}
+	return &Schema{root: root}, nil
+}
+
+// MustCompileSchema is like CompileSchema but panics on error.
+func MustCompileSchema(data []byte) *Schema {
+	s, err := CompileSchema(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Validate checks a decoded JSON value against the schema. It returns a
+// *SchemaError describing the first violation, or nil.
+func (s *Schema) Validate(v any) error {
+	if err := s.root.validate(fieldPath{}, v); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Schemas is a set of compiled schemas, keyed by file name without the
+// .json extension.
+type Schemas struct {
+	// MaxBytes caps the bodies Validate reads (defaults to
+	// DefaultMaxBodyBytes)
+	MaxBytes int64
+
+	schemas map[string]*Schema
+}
+
+// LoadSchemas compiles every .json file in dir of fsys, typically an
+// embed.FS, so that malformed schemas fail at startup:
+//
+//	//go:embed schemas/*.json
+//	var schemaFS embed.FS
+//
+//	schemas, err := bind.LoadSchemas(schemaFS, "schemas")
+//	...
+//	api.POST("/galleries", schemas.Validate("create_gallery"), createGallery)
+func LoadSchemas(fsys fs.FS, dir string) (*Schemas, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("bind: loading schemas: %w", err)
+	}
+
+	set := &Schemas{schemas: map[string]*Schema{}}
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".json" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("bind: loading schemas: %w", err)
+		}
+		s, err := CompileSchema(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		set.schemas[strings.TrimSuffix(e.Name(), ".json")] = s
+	}
+	return set, nil
+}
+
+// Get returns the named schema.
+func (s *Schemas) Get(name string) (*Schema, bool) {
+	schema, ok := s.schemas[name]
+	return schema, ok
+}
+
+// Validate returns ValidateBody for the named schema. It panics if the
+// schema doesn't exist, so a typo'd name fails at route registration.
+func (s *Schemas) Validate(name string) gin.HandlerFunc {
+	schema, ok := s.schemas[name]
+	if !ok {
+		panic("bind: unknown schema " + strconv.Quote(name))
+	}
+	return ValidateBody(schema, ValidateConfig{MaxBytes: s.MaxBytes})
+}
+
+// msgpackGenericHandle decodes msgpack maps as map[string]any so they can
+// be validated like JSON.
+var msgpackGenericHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{WriteExt: true}
+	h.RawToString = true
+	h.MapType = reflect.TypeOf(map[string]any(nil))
+	return h
+}()
+
+// DefaultMaxBodyBytes is the largest body ValidateBody reads by default.
+const DefaultMaxBodyBytes = 1 << 20
+
+// ErrorCodeBodyTooLarge is the code of the 413 ValidateBody sends for
+// bodies over its MaxBytes.
+const ErrorCodeBodyTooLarge = "body_too_large"
+
+// ValidateConfig configures ValidateBody.
+type ValidateConfig struct {
+	// MaxBytes caps the body read for validation (defaults to
+	// DefaultMaxBodyBytes)
+	MaxBytes int64
+}
+
+// ValidateBody returns middleware that validates the request body (JSON or
+// MessagePack) against schema before the handler runs. The body is restored
+// afterwards, so the handler binds it as usual with Body.
+//
+// Bodies over cfg.MaxBytes get a 413 with code body_too_large, as the whole
+// body is held in memory. Malformed bodies get a 400 with code
+// invalid_format. Schema violations get
+// a 422 whose param and pointer locate the offending field and whose code
+// is missing_param or invalid_param:
+//
+//	{"object": "error", "error": {"type": "invalid_request", "code": "invalid_param",
+//	    "message": "items[2].price.currency must be one of \"EUR\", \"JPY\"",
+//	    "param": "items[2].price.currency", "pointer": "/items/2/price/currency"}}
+//
+// Requests without a body are validated as null, so a schema with
+// "type": "object" rejects them.
+func ValidateBody(schema *Schema, cfg ValidateConfig) gin.HandlerFunc {
+	if schema == nil {
+		panic("bind: ValidateBody requires a schema")
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBodyBytes
+	}
+
+	return func(c *gin.Context) {
+		var body []byte
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			var err error
+			body, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, cfg.MaxBytes))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				response.ErrorWithInfo(c, http.StatusRequestEntityTooLarge, response.ErrorInfo{
+					Type:    response.ErrorTypeInvalidRequest,
+					Code:    ErrorCodeBodyTooLarge,
+					Message: fmt.Sprintf("request body exceeds the maximum size of %d bytes", cfg.MaxBytes),
+					Details: map[string]any{"max_size": cfg.MaxBytes},
+				})
+				c.Abort()
+				return
+			}
+			if err != nil {
+				response.BadRequestWithCode(c, response.ErrorCodeInvalidFormat, "failed to read request body")
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		v, err := decodeGeneric(c.Request.Header.Get("Content-Type"), body)
+		if errors.Is(err, ErrUnsupportedMediaType) {
+			response.UnsupportedMediaType(c, err.Error())
+			c.Abort()
+			return
+		}
+		if err != nil {
+			response.BadRequestWithCode(c, response.ErrorCodeInvalidFormat, err.Error())
+			c.Abort()
+			return
+		}
+
+		if err := schema.Validate(v); err != nil {
+			response.ErrorWithInfo(c, http.StatusUnprocessableEntity, err.(*SchemaError).ErrorInfo())
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// decodeGeneric decodes body into plain values based on Content-Type.
+func decodeGeneric(contentType string, body []byte) (any, error) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+
+	mediaType := "application/json"
+	if contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+		}
+		mediaType = parsed
+	}
+
+	var v any
+	switch mediaType {
+	case "application/json":
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		if dec.More() {
+			return nil, errors.New("invalid request body: unexpected data after JSON value")
+		}
+	case response.MsgpackMediaType, response.MsgpackMediaTypeLegacy:
+		if err := codec.NewDecoderBytes(body, msgpackGenericHandle).Decode(&v); err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+	}
+	return v, nil
+}
+
+// schemaNode is a compiled (sub)schema. A nil *schemaNode accepts anything.
+type schemaNode struct {
+	ref   *schemaNode
+	never bool // the false schema
+
+	types  []string
+	enum   []any
+	konst  *any
+	allOf  []*schemaNode
+	anyOf  []*schemaNode
+	oneOf  []*schemaNode
+	not    *schemaNode
+	hasNot bool
+
+	properties   map[string]*schemaNode
+	required     []string
+	noAdditional bool
+	additional   *schemaNode
+	items        *schemaNode
+	minItems     *int
+	maxItems     *int
+	uniqueItems  bool
+	minLength    *int
+	maxLength    *int
+	pattern      *regexp.Regexp
+	minimum      *float64
+	maximum      *float64
+	exclusiveMin *float64
+	exclusiveMax *float64
+	multipleOf   *float64
+}
+
+// annotationKeywords are accepted and ignored.
+var annotationKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "$defs": true, "definitions": true,
+	"title": true, "description": true, "default": true, "examples": true,
+	"format": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// schemaCompiler compiles schema documents, resolving local $refs.
+type schemaCompiler struct {
+	defs map[string]*schemaNode
+}
+
+func (c *schemaCompiler) compile(v any) (*schemaNode, error) {
+	n := &schemaNode{}
+	if err := c.compileInto(n, v); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (c *schemaCompiler) compileInto(n *schemaNode, v any) error {
+	switch v := v.(type) {
+	case bool:
+		n.never = !v
+		return nil
+	case map[string]any:
+		for key, val := range v {
+			if err := c.keyword(n, key, val); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errors.New("schema must be an object or boolean")
+}
+
+// keyword compiles one keyword of a schema object into n.
+func (c *schemaCompiler) keyword(n *schemaNode, key string, val any) error {
+	var err error
+	switch key {
+	case "$ref":
+		ref, _ := val.(string)
+		target, ok := c.defs[ref]
+		if !ok {
+			return fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		n.ref = target
+	case "type":
+		switch t := val.(type) {
+		case string:
+			n.types = []string{t}
+		case []any:
+			for _, item := range t {
+				s, ok := item.(string)
+				if !ok {
+					return errors.New("type must be a string or array of strings")
+				}
+				n.types = append(n.types, s)
+			}
+		default:
+			return errors.New("type must be a string or array of strings")
+		}
+		for _, t := range n.types {
+			switch t {
+			case "null", "boolean", "object", "array", "number", "integer", "string":
+			default:
+				return fmt.Errorf("unknown type %q", t)
+			}
+		}
+	case "enum":
+		list, ok := val.([]any)
+		if !ok {
+			return errors.New("enum must be an array")
+		}
+		n.enum = list
+	case "const":
+		n.konst = &val
+	case "allOf", "anyOf", "oneOf":
+		list, ok := val.([]any)
+		if !ok || len(list) == 0 {
+			return fmt.Errorf("%s must be a non-empty array", key)
+		}
+		nodes := make([]*schemaNode, len(list))
+		for i, item := range list {
+			if nodes[i], err = c.compile(item); err != nil {
+				return fmt.Errorf("%s[%d]: %w", key, i, err)
+			}
+		}
+		switch key {
+		case "allOf":
+			n.allOf = nodes
+		case "anyOf":
+			n.anyOf = nodes
+		default:
+			n.oneOf = nodes
+		}
+	case "not":
+		if n.not, err = c.compile(val); err != nil {
+			return fmt.Errorf("not: %w", err)
+		}
+		n.hasNot = true
+	case "properties":
+		props, ok := val.(map[string]any)
+		if !ok {
+			return errors.New("properties must be an object")
+		}
+		n.properties = make(map[string]*schemaNode, len(props))
+		for name, prop := range props {
+			if n.properties[name], err = c.compile(prop); err != nil {
+				return fmt.Errorf("properties.%s: %w", name, err)
+			}
+		}
+	case "required":
+		list, ok := val.([]any)
+		if !ok {
+			return errors.New("required must be an array of strings")
+		}
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return errors.New("required must be an array of strings")
+			}
+			n.required = append(n.required, s)
+		}
+	case "additionalProperties":
+		if b, ok := val.(bool); ok {
+			n.noAdditional = !b
+			break
+		}
+		if n.additional, err = c.compile(val); err != nil {
+			return fmt.Errorf("additionalProperties: %w", err)
+		}
+	case "items":
+		if n.items, err = c.compile(val); err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+	case "uniqueItems":
+		n.uniqueItems, _ = val.(bool)
+	case "pattern":
+		s, _ := val.(string)
+		if n.pattern, err = regexp.Compile(s); err != nil {
+			return fmt.Errorf("pattern: %w", err)
+		}
+	case "minItems", "maxItems", "minLength", "maxLength":
+		i, err := schemaInt(val)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		switch key {
+		case "minItems":
+			n.minItems = &i
+		case "maxItems":
+			n.maxItems = &i
+		case "minLength":
+			n.minLength = &i
+		default:
+			n.maxLength = &i
+		}
+	case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf":
+		f, ok := toFloat(val)
+		if !ok {
+			return fmt.Errorf("%s must be a number", key)
+		}
+		switch key {
+		case "minimum":
+			n.minimum = &f
+		case "maximum":
+			n.maximum = &f
+		case "exclusiveMinimum":
+			n.exclusiveMin = &f
+		case "exclusiveMaximum":
+			n.exclusiveMax = &f
+		default:
+			if f <= 0 {
+				return errors.New("multipleOf must be greater than 0")
+			}
+			n.multipleOf = &f
+		}
+	default:
+		if !annotationKeywords[key] {
+			return fmt.Errorf("unsupported keyword %q", key)
+		}
+	}
+	return nil
+}
+
+// schemaInt parses a non-negative integer keyword value.
+func schemaInt(v any) (int, error) {
+	f, ok := toFloat(v)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return 0, errors.New("must be a non-negative integer")
+	}
+	return int(f), nil
+}
+
+// validate checks v, at field path p, against n.
+func (n *schemaNode) validate(p fieldPath, v any) *SchemaError {
+	if n == nil {
+		return nil
+	}
+	if n.never {
+		return invalid(p, "%s is not allowed")
+	}
+	if n.ref != nil {
+		if err := n.ref.validate(p, v); err != nil {
+			return err
+		}
+	}
+
+	if len(n.types) > 0 && !matchesAnyType(n.types, v) {
+		return invalid(p, "%s must be %s", typeList(n.types))
+	}
+	if n.konst != nil && !jsonEqual(*n.konst, v) {
+		return invalid(p, "%s must be %s", jsonText(*n.konst))
+	}
+	if n.enum != nil {
+		found := false
+		for _, e := range n.enum {
+			if jsonEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			texts := make([]string, len(n.enum))
+			for i, e := range n.enum {
+				texts[i] = jsonText(e)
+			}
+			return invalid(p, "%s must be one of %s", strings.Join(texts, ", "))
+		}
+	}
+
+	switch v := v.(type) {
+	case string:
+		if err := n.validateString(p, v); err != nil {
+			return err
+		}
+	case []any:
+		if err := n.validateArray(p, v); err != nil {
+			return err
+		}
+	case map[string]any:
+		if err := n.validateObject(p, v); err != nil {
+			return err
+		}
+	default:
+		if f, ok := toFloat(v); ok {
+			if err := n.validateNumber(p, f); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, sub := range n.allOf {
+		if err := sub.validate(p, v); err != nil {
+			return err
+		}
+	}
+	if n.anyOf != nil {
+		var first *SchemaError
+		for _, sub := range n.anyOf {
+			err := sub.validate(p, v)
+			if err == nil {
+				first = nil
+				break
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		if first != nil {
+			return first
+		}
+	}
+	if n.oneOf != nil {
+		matched := 0
+		for _, sub := range n.oneOf {
+			if sub.validate(p, v) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return invalid(p, "%s must match exactly one allowed form")
+		}
+	}
+	if n.hasNot && n.not.validate(p, v) == nil {
+		return invalid(p, "%s is not allowed")
+	}
+	return nil
+}
+
+func (n *schemaNode) validateString(p fieldPath, s string) *SchemaError {
+	length := utf8.RuneCountInString(s)
+	if n.minLength != nil && length < *n.minLength {
+		return invalid(p, "%s must be at least %d characters", *n.minLength)
+	}
+	if n.maxLength != nil && length > *n.maxLength {
+		return invalid(p, "%s must be at most %d characters", *n.maxLength)
+	}
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		return invalid(p, "%s has an invalid format")
+	}
+	return nil
+}
+
+func (n *schemaNode) validateNumber(p fieldPath, f float64) *SchemaError {
+	if n.minimum != nil && f < *n.minimum {
+		return invalid(p, "%s must be at least %s", formatNumber(*n.minimum))
+	}
+	if n.maximum != nil && f > *n.maximum {
+		return invalid(p, "%s must be at most %s", formatNumber(*n.maximum))
+	}
+	if n.exclusiveMin != nil && f <= *n.exclusiveMin {
+		return invalid(p, "%s must be greater than %s", formatNumber(*n.exclusiveMin))
+	}
+	if n.exclusiveMax != nil && f >= *n.exclusiveMax {
+		return invalid(p, "%s must be less than %s", formatNumber(*n.exclusiveMax))
+	}
+	if n.multipleOf != nil {
+		q := f / *n.multipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			return invalid(p, "%s must be a multiple of %s", formatNumber(*n.multipleOf))
+		}
+	}
+	return nil
+}
+
+func (n *schemaNode) validateArray(p fieldPath, items []any) *SchemaError {
+	if n.minItems != nil && len(items) < *n.minItems {
+		return invalid(p, "%s must have at least %d items", *n.minItems)
+	}
+	if n.maxItems != nil && len(items) > *n.maxItems {
+		return invalid(p, "%s must have at most %d items", *n.maxItems)
+	}
+	if n.uniqueItems {
+		for i := range items {
+			for j := 0; j < i; j++ {
+				if jsonEqual(items[i], items[j]) {
+					return invalid(p, "%s must not contain duplicates")
+				}
+			}
+		}
+	}
+	for i, item := range items {
+		if err := n.items.validate(p.index(i), item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *schemaNode) validateObject(p fieldPath, obj map[string]any) *SchemaError {
+	for _, name := range n.required {
+		if _, ok := obj[name]; !ok {
+			field := p.key(name)
+			return &SchemaError{Field: field.field, Pointer: field.pointer, Code: response.ErrorCodeMissingParam, Message: field.field + " is required"}
+		}
+	}
+	for name, val := range obj {
+		field := p.key(name)
+		if prop, ok := n.properties[name]; ok {
+			if err := prop.validate(field, val); err != nil {
+				return err
+			}
+			continue
+		}
+		if n.noAdditional {
+			return invalid(field, "unknown field: %s")
+		}
+		if err := n.additional.validate(field, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invalid returns an invalid_param SchemaError for the value at p. The
+// field path is the first format argument; "request body" stands in for
+// the root.
+func invalid(p fieldPath, format string, args ...any) *SchemaError {
+	name := p.field
+	if name == "" {
+		name = "request body"
+	}
+	return &SchemaError{
+		Field:   p.field,
+		Pointer: p.pointer,
+		Code:    response.ErrorCodeInvalidParam,
+		Message: fmt.Sprintf(format, append([]any{name}, args...)...),
+	}
+}
+
+// fieldPath locates a value in a request body, both as a field path for
+// messages ("items[2].price") and as a JSON Pointer ("/items/2/price").
+type fieldPath struct {
+	field, pointer string
+}
+
+// key returns the path of property name of the object at p.
+func (p fieldPath) key(name string) fieldPath {
+	field := name
+	if p.field != "" {
+		field = p.field + "." + name
+	}
+	return fieldPath{field: field, pointer: p.pointer + "/" + escapePointer(name)}
+}
+
+// index returns the path of item i of the array at p.
+func (p fieldPath) index(i int) fieldPath {
+	return fieldPath{field: p.field + "[" + strconv.Itoa(i) + "]", pointer: p.pointer + "/" + strconv.Itoa(i)}
+}
+
+// escapePointer escapes a JSON Pointer reference token (RFC 6901).
+func escapePointer(token string) string {
+	if !strings.ContainsAny(token, "~/") {
+		return token
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// matchesAnyType reports whether v is one of the JSON types.
+func matchesAnyType(types []string, v any) bool {
+	for _, t := range types {
+		if matchesType(t, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(t string, v any) bool {
+	switch t {
+	case "null":
+		return v == nil
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := toFloat(v)
+		return ok
+	case "integer":
+		f, ok := toFloat(v)
+		return ok && f == math.Trunc(f) && !math.IsInf(f, 0)
+	}
+	return false
+}
+
+// typeList describes types for messages, e.g. "a string or null".
+func typeList(types []string) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		switch t {
+		case "null":
+			names[i] = "null"
+		case "object", "array", "integer":
+			names[i] = "an " + t
+		default:
+			names[i] = "a " + t
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+// toFloat converts a decoded JSON or MessagePack number to float64.
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// formatNumber formats a schema bound for messages.
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// jsonEqual reports whether two decoded values are equal as JSON.
+func jsonEqual(a, b any) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	switch a := a.(type) {
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, av := range a {
+			bv, ok := b[k]
+			if !ok || !jsonEqual(av, bv) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// jsonText renders a schema value for messages.
+func jsonText(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,216 @@
+package bind_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/bind"
+)
+
+const gallerySchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["title"],
+	"additionalProperties": false,
+	"properties": {
+		"title": {"type": "string", "minLength": 1, "maxLength": 20},
+		"tags": {"type": "array", "maxItems": 3, "uniqueItems": true, "items": {"$ref": "#/$defs/tag"}},
+		"pages": {"type": "integer", "minimum": 1},
+		"rating": {"enum": ["safe", "questionable", "explicit"]},
+		"author": {
+			"type": "object",
+			"required": ["name"],
+			"properties": {"name": {"type": "string"}}
+		}
+	},
+	"$defs": {
+		"tag": {"type": "string", "pattern": "^[a-z-]+$"}
+	}
+}`
+
+func TestSchemaValidate(t *testing.T) {
+	schema := bind.MustCompileSchema([]byte(gallerySchema))
+
+	tests := []struct {
+		body        string
+		wantField   string
+		wantPointer string
+		wantCode    string
+	}{
+		{`{"title":"Title","tags":["big-cat"],"pages":12,"rating":"safe"}`, "", "", ""},
+		{`{"title":"Title","pages":12.0}`, "", "", ""},
+		{`{}`, "title", "/title", "missing_param"},
+		{`null`, "", "", "invalid_param"},
+		{`{"title":""}`, "title", "/title", "invalid_param"},
+		{`{"title":"Title","pages":0}`, "pages", "/pages", "invalid_param"},
+		{`{"title":"Title","pages":1.5}`, "pages", "/pages", "invalid_param"},
+		{`{"title":"Title","tags":["ok","Not Ok"]}`, "tags[1]", "/tags/1", "invalid_param"},
+		{`{"title":"Title","tags":["a","a"]}`, "tags", "/tags", "invalid_param"},
+		{`{"title":"Title","rating":"nsfw"}`, "rating", "/rating", "invalid_param"},
+		{`{"title":"Title","author":{}}`, "author.name", "/author/name", "missing_param"},
+		{`{"title":"Title","titel":"typo"}`, "titel", "/titel", "invalid_param"},
+		{`{"title":"Title","a/b~":1}`, "a/b~", "/a~1b~0", "invalid_param"},
+	}
+
+	for _, tt := range tests {
+		var v any
+		dec := json.NewDecoder(bytes.NewReader([]byte(tt.body)))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		err := schema.Validate(v)
+		if tt.wantCode == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.body, err)
+			}
+			continue
+		}
+		se, ok := err.(*bind.SchemaError)
+		if !ok {
+			t.Errorf("%s: expected *SchemaError, got %v", tt.body, err)
+			continue
+		}
+		if se.Field != tt.wantField || se.Pointer != tt.wantPointer || se.Code != tt.wantCode {
+			t.Errorf("%s: expected %s on %q (%q), got %s on %q (%q): %s", tt.body, tt.wantCode, tt.wantField, tt.wantPointer, se.Code, se.Field, se.Pointer, se.Message)
+		}
+	}
+}
+
+func TestCompileSchemaErrors(t *testing.T) {
+	tests := []string{
+		`[]`,
+		`{"type": "text"}`,
+		`{"$ref": "#/$defs/missing"}`,
+		`{"pattern": "("}`,
+		`{"minLength": -1}`,
+		`{"patternProperties": {}}`,
+	}
+
+	for _, schema := range tests {
+		if _, err := bind.CompileSchema([]byte(schema)); err == nil {
+			t.Errorf("%s: expected compile error", schema)
+		}
+	}
+}
+
+func TestValidateBody(t *testing.T) {
+	schemas, err := bind.LoadSchemas(fstest.MapFS{
+		"schemas/create_gallery.json": {Data: []byte(gallerySchema)},
+		"schemas/README.md":           {Data: []byte("not a schema")},
+	}, "schemas")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.POST("/galleries", schemas.Validate("create_gallery"), func(c *gin.Context) {
+		var req createGallery
+		if err := bind.Body(c, &req); err != nil {
+			t.Errorf("body not restored: %v", err)
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	tests := []struct {
+		body       string
+		wantStatus int
+		wantCode   string
+		wantParam  string
+	}{
+		{`{"title":"Title","pages":3}`, http.StatusCreated, "", ""},
+		{`{"pages":3}`, http.StatusUnprocessableEntity, "missing_param", "title"},
+		{`{"title":"Title","pages":"3"}`, http.StatusUnprocessableEntity, "invalid_param", "pages"},
+		{`{"title":"Title","author":{}}`, http.StatusUnprocessableEntity, "missing_param", "author.name"},
+		{`{"title":`, http.StatusBadRequest, "invalid_format", ""},
+		{``, http.StatusUnprocessableEntity, "invalid_param", ""},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/galleries", bytes.NewReader([]byte(tt.body)))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.body, tt.wantStatus, w.Code)
+			continue
+		}
+		if tt.wantCode == "" {
+			continue
+		}
+		var body struct {
+			Error struct {
+				Code    string `json:"code"`
+				Param   string `json:"param"`
+				Pointer string `json:"pointer"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if body.Error.Code != tt.wantCode || body.Error.Param != tt.wantParam {
+			t.Errorf("%s: expected %s on %q, got %s on %q", tt.body, tt.wantCode, tt.wantParam, body.Error.Code, body.Error.Param)
+		}
+		if wantPointer := "/" + strings.ReplaceAll(tt.wantParam, ".", "/"); tt.wantParam != "" && body.Error.Pointer != wantPointer {
+			t.Errorf("%s: expected pointer %q, got %q", tt.body, wantPointer, body.Error.Pointer)
+		}
+	}
+}
+
+func TestValidateBodyMaxBytes(t *testing.T) {
+	schema, err := bind.CompileSchema([]byte(gallerySchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.POST("/galleries", bind.ValidateBody(schema, bind.ValidateConfig{MaxBytes: 32}), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"within the limit", `{"title":"Title","pages":3}`, http.StatusCreated},
+		{"over the limit", `{"title":"` + strings.Repeat("a", 64) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/galleries", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusRequestEntityTooLarge && !strings.Contains(w.Body.String(), bind.ErrorCodeBodyTooLarge) {
+				t.Errorf("expected code %s, got %s", bind.ErrorCodeBodyTooLarge, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSchemasValidateUnknown(t *testing.T) {
+	schemas, err := bind.LoadSchemas(fstest.MapFS{"s/a.json": {Data: []byte(`{}`)}}, "s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for unknown schema")
+		}
+	}()
+	schemas.Validate("b")
+}
@@ -0,0 +1,230 @@
+package bind
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"github.com/doujins-org/ginapi/ids"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// ParamError is a path parameter URI couldn't bind.
+type ParamError struct {
+	// Param is the name of the path parameter, e.g. "id"
+	Param string
+	// Status is 404 for IDs that can't exist, so a malformed ID looks like
+	// any other unknown one, and 400 for other invalid parameters
+	Status int
+	// Code is response.ErrorCodeMissingParam or ErrorCodeInvalidParam for
+	// 400s, and empty for 404s
+	Code string
+	// Message describes the problem, e.g. `id "usr_1" not found`
+	Message string
+
+	err error
+}
+
+func (e *ParamError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the parse or validation error e was built from, if any.
+func (e *ParamError) Unwrap() error {
+	return e.err
+}
+
+// ErrorInfo returns the error details to send for e, with the parameter as
+// the param.
+func (e *ParamError) ErrorInfo() response.ErrorInfo {
+	if e.Status == http.StatusNotFound {
+		return response.ErrorInfo{Type: response.ErrorTypeNotFound, Message: e.Message, Param: e.Param}
+	}
+	return response.ErrorInfo{
+		Type:    response.ErrorTypeInvalidRequest,
+		Code:    e.Code,
+		Message: e.Message,
+		Param:   e.Param,
+	}
+}
+
+// URI binds path parameters into the fields of the struct v points to, by
+// their uri tags, then runs gin's struct validation (binding tags):
+//
+//	var p struct {
+//	    GalleryID string `uri:"id" id:"gal" binding:"required"`
+//	    Page      int    `uri:"page"`
+//	}
+//	if err := bind.URI(c, &p); err != nil {
+//	    var pe *bind.ParamError
+//	    if errors.As(err, &pe) {
+//	        response.ErrorWithInfo(c, pe.Status, pe.ErrorInfo())
+//	    }
+//	    return
+//	}
+//
+// A field with an id tag holds an ID parsed with the ids.Formatter
+// registered for the prefix (see ids.Register): the parameter may be the
+// ID with its prefix ("gal_3f9a2c"), without it ("3f9a2c"), or a slug.Join
+// segment ("my-title-gal_3f9a2c"). A string field gets the prefixed form,
+// an unsigned integer field the key, and a uuid.UUID field the UUID. IDs
+// that are malformed or have another prefix are a 404, since no such
+// record can exist, and IDs whose check character doesn't match are a 400
+// invalid_id_checksum. Other fields may be strings, integers,
+// floats, or bools; values that don't parse, and validation failures, are
+// a 400 invalid_param (missing_param for required). Every binding failure is a *ParamError.
+func URI(c *gin.Context, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind: URI needs a pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		name := f.Tag.Get("uri")
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		value := c.Param(name)
+		if value == "" {
+			continue
+		}
+		if err := setParam(rv.Field(i), f, name, value); err != nil {
+			return err
+		}
+	}
+
+	if binding.Validator == nil {
+		return nil
+	}
+	err := binding.Validator.ValidateStruct(v)
+	var failures validator.ValidationErrors
+	if !errors.As(err, &failures) || len(failures) == 0 {
+		return err
+	}
+	fe := failures[0]
+	param := fe.Field()
+	if f, ok := rt.FieldByName(fe.StructField()); ok && f.Tag.Get("uri") != "" {
+		param = f.Tag.Get("uri")
+	}
+	code, message := failureMessage(c, fe, param)
+	return &ParamError{Param: param, Status: http.StatusBadRequest, Code: code, Message: message, err: err}
+}
+
+// setParam parses value into field f.
+func setParam(fv reflect.Value, f reflect.StructField, name, value string) error {
+	if prefix := f.Tag.Get("id"); prefix != "" {
+		return setParamID(fv, f, name, value, prefix)
+	}
+
+	want, err := setScalar(fv, value)
+	if errors.Is(err, errUnsupportedType) {
+		return fmt.Errorf("bind: unsupported type %s for path parameter %s", f.Type, name)
+	}
+	if err != nil {
+		return &ParamError{
+			Param:   name,
+			Status:  http.StatusBadRequest,
+			Code:    response.ErrorCodeInvalidParam,
+			Message: fmt.Sprintf("%s must be %s, got %q", name, want, value),
+			err:     err,
+		}
+	}
+	return nil
+}
+
+// errUnsupportedType is returned by setScalar for kinds it can't parse.
+var errUnsupportedType = errors.New("bind: unsupported type")
+
+// setScalar parses value into fv, a string, integer, float, or bool. If
+// value doesn't parse, it returns what it must be, e.g. "an integer".
+func setScalar(fv reflect.Value, value string) (want string, err error) {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return "an integer", err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return "a non-negative integer", err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return "a number", err
+		}
+		fv.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "true or false", err
+		}
+		fv.SetBool(b)
+	default:
+		return "", errUnsupportedType
+	}
+	return "", nil
+}
+
+// uuidType is the type of uuid.UUID fields.
+var uuidType = reflect.TypeOf(uuid.UUID{})
+
+// setParamID parses value, the ID in path parameter name, into field f
+// with the Formatter registered for prefix.
+func setParamID(fv reflect.Value, f reflect.StructField, name, value, prefix string) error {
+	formatter := ids.Lookup(prefix)
+	id := prefixedID(value, prefix)
+	var err error
+	switch {
+	case fv.Kind() == reflect.String:
+		var s string
+		if s, err = formatter.Normalize(id); err == nil {
+			fv.SetString(s)
+		}
+	case fv.Type() == uuidType:
+		var u uuid.UUID
+		if u, err = formatter.ParseUUID(id); err == nil {
+			fv.Set(reflect.ValueOf(u))
+		}
+	case fv.CanUint():
+		var n uint64
+		if n, err = formatter.Parse(id); err == nil && fv.OverflowUint(n) {
+			err = ids.ErrMalformed
+		}
+		if err == nil {
+			fv.SetUint(n)
+		}
+	default:
+		return fmt.Errorf("bind: id tag on %s for path parameter %s needs a string, an unsigned integer, or a uuid.UUID", f.Type, name)
+	}
+	if err == nil {
+		return nil
+	}
+
+	var pe *ids.ParseError
+	if errors.As(err, &pe) && pe.Status == http.StatusBadRequest {
+		return &ParamError{
+			Param:   name,
+			Status:  http.StatusBadRequest,
+			Code:    pe.Code,
+			Message: fmt.Sprintf("%s %q is mistyped: its check character doesn't match", name, value),
+			err:     err,
+		}
+	}
+	return &ParamError{Param: name, Status: http.StatusNotFound, Message: fmt.Sprintf("%s %q not found", name, value), err: err}
+}
@@ -0,0 +1,141 @@
+package bind_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/bind"
+	"github.com/doujins-org/ginapi/ids"
+	"github.com/doujins-org/ginapi/response"
+)
+
+type galleryPageParams struct {
+	GalleryID string `uri:"id" id:"gal" binding:"required"`
+	Page      int    `uri:"page" binding:"min=1"`
+}
+
+func TestURI(t *testing.T) {
+	var got galleryPageParams
+	router := gin.New()
+	router.GET("/galleries/:id/pages/:page", func(c *gin.Context) {
+		got = galleryPageParams{}
+		if err := bind.URI(c, &got); err != nil {
+			var pe *bind.ParamError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expected a *ParamError, got %v", err)
+			}
+			response.ErrorWithInfo(c, pe.Status, pe.ErrorInfo())
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantID     string
+		wantPage   int
+		wantCode   string
+		wantParam  string
+	}{
+		{"prefixed id", "/galleries/gal_3f9a2c/pages/2", http.StatusNoContent, "gal_3f9a2c", 2, "", ""},
+		{"raw id", "/galleries/3f9a2c/pages/2", http.StatusNoContent, "gal_3f9a2c", 2, "", ""},
+		{"slug segment", "/galleries/my-title-gal_3f9a2c/pages/2", http.StatusNoContent, "gal_3f9a2c", 2, "", ""},
+		{"other prefix", "/galleries/usr_3f9a2c/pages/2", http.StatusNotFound, "", 0, "", "id"},
+		{"malformed id", "/galleries/3f9a.2c/pages/2", http.StatusNotFound, "", 0, "", "id"},
+		{"non-canonical id", "/galleries/gal_03f9a2c/pages/2", http.StatusNotFound, "", 0, "", "id"},
+		{"page not a number", "/galleries/gal_3f9a2c/pages/two", http.StatusBadRequest, "", 0, response.ErrorCodeInvalidParam, "page"},
+		{"page fails validation", "/galleries/gal_3f9a2c/pages/0", http.StatusBadRequest, "", 0, response.ErrorCodeInvalidParam, "page"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusNoContent {
+				if got.GalleryID != tt.wantID || got.Page != tt.wantPage {
+					t.Errorf("expected %s page %d, got %+v", tt.wantID, tt.wantPage, got)
+				}
+				return
+			}
+			e, err := response.ParseError(w.Body.Bytes())
+			if err != nil {
+				t.Fatalf("expected an error body, got %s", w.Body.String())
+			}
+			if e.Error.Code != tt.wantCode || e.Error.Param != tt.wantParam {
+				t.Errorf("expected code %q param %q, got %+v", tt.wantCode, tt.wantParam, e.Error)
+			}
+		})
+	}
+}
+
+func TestURIRegisteredFormatter(t *testing.T) {
+	ids.Register(ids.Formatter{Prefix: "rcp", Checksum: true, MaxLength: 6})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantKey    uint64
+		wantCode   string
+	}{
+		{"/receipts/rcp_3D7T", http.StatusOK, 12345, ""},
+		{"/receipts/3D7T", http.StatusOK, 12345, ""},
+		{"/receipts/rcp_3D8T", http.StatusBadRequest, 0, response.ErrorCodeInvalidIDChecksum},
+		{"/receipts/rcp_3D7TTTT", http.StatusNotFound, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var p struct {
+				Key uint64 `uri:"id" id:"rcp"`
+			}
+			router := gin.New()
+			router.GET("/receipts/:id", func(c *gin.Context) {
+				if err := bind.URI(c, &p); err != nil {
+					var pe *bind.ParamError
+					if !errors.As(err, &pe) {
+						t.Fatalf("expected a *ParamError, got %v", err)
+					}
+					if tt.wantCode != "" && !errors.Is(err, ids.ErrChecksum) {
+						t.Errorf("expected ErrChecksum, got %v", err)
+					}
+					response.ErrorWithInfo(c, pe.Status, pe.ErrorInfo())
+					return
+				}
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if p.Key != tt.wantKey {
+				t.Errorf("expected key %d, got %d", tt.wantKey, p.Key)
+			}
+			if tt.wantCode != "" {
+				if e, _ := response.ParseError(w.Body.Bytes()); e.Error.Code != tt.wantCode {
+					t.Errorf("expected code %s, got %s", tt.wantCode, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestURINotStruct(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	var id string
+	if err := bind.URI(c, &id); err == nil {
+		t.Error("expected an error for a non-struct")
+	}
+}
@@ -0,0 +1,116 @@
+package bind_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/bind"
+	"github.com/doujins-org/ginapi/i18n"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestValidators(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(string) bool
+		value string
+		want  bool
+	}{
+		{"email", bind.IsEmail, "mika@example.com", true},
+		{"email with name", bind.IsEmail, "Mika <mika@example.com>", false},
+		{"email without domain dot", bind.IsEmail, "mika@localhost", false},
+		{"email without at", bind.IsEmail, "mika.example.com", false},
+		{"email with trailing dot", bind.IsEmail, "mika@example.com.", false},
+		{"url", func(s string) bool { return bind.IsURL(s) }, "https://example.com/a?b=c", true},
+		{"url http", func(s string) bool { return bind.IsURL(s) }, "http://example.com", true},
+		{"url javascript", func(s string) bool { return bind.IsURL(s) }, "javascript:alert(1)", false},
+		{"url relative", func(s string) bool { return bind.IsURL(s) }, "/galleries", false},
+		{"url with space", func(s string) bool { return bind.IsURL(s) }, "https://example.com/a b", false},
+		{"url scheme allowlist", func(s string) bool { return bind.IsURL(s, "https") }, "http://example.com", false},
+		{"url uppercase scheme", func(s string) bool { return bind.IsURL(s, "https") }, "HTTPS://example.com", true},
+		{"phone", bind.IsPhone, "+14155550123", true},
+		{"phone without plus", bind.IsPhone, "14155550123", false},
+		{"phone with spaces", bind.IsPhone, "+1 415 555 0123", false},
+		{"phone too long", bind.IsPhone, "+1234567890123456", false},
+		{"phone leading zero", bind.IsPhone, "+0123456", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check(tt.value); got != tt.want {
+				t.Errorf("expected %v for %q, got %v", tt.want, tt.value, got)
+			}
+		})
+	}
+}
+
+type createContact struct {
+	Email   string `json:"email" binding:"required,email"`
+	Website string `json:"website" binding:"omitempty,url=https"`
+	Phone   string `json:"phone" binding:"omitempty,phone"`
+}
+
+func TestBodyValidatorMessages(t *testing.T) {
+	if err := bind.RegisterValidators(); err != nil {
+		t.Fatal(err)
+	}
+	catalog := i18n.New("en")
+	if err := catalog.Add("ja", map[string]any{
+		"validation": map[string]any{"email": "{{.Field}}は有効なメールアドレスではありません"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	previous := i18n.Default()
+	i18n.SetDefault(catalog)
+	t.Cleanup(func() { i18n.SetDefault(previous) })
+
+	tests := []struct {
+		name      string
+		lang      string
+		body      string
+		wantCode  string
+		wantParam string
+		wantMsg   string
+	}{
+		{"missing", "en", `{}`, response.ErrorCodeMissingParam, "email", "email is required"},
+		{"email", "en", `{"email":"mika"}`, response.ErrorCodeInvalidParam, "email", "email must be a valid email address"},
+		{"localized", "ja", `{"email":"mika"}`, response.ErrorCodeInvalidParam, "email", "emailは有効なメールアドレスではありません"},
+		{"not localized", "ja", `{"email":"mika@example.com","website":"http://example.com"}`, response.ErrorCodeInvalidParam, "website", "website must be a URL starting with https://"},
+		{"phone", "en", `{"email":"mika@example.com","phone":"555-0123"}`, response.ErrorCodeInvalidParam, "phone", "phone must be a phone number in international format, e.g. +14155550123"},
+		{"valid", "en", `{"email":"mika@example.com","website":"https://example.com","phone":"+14155550123"}`, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			router := gin.New()
+			router.POST("/contacts", func(c *gin.Context) {
+				c.Set("language", tt.lang)
+				var req createContact
+				err = bind.Body(c, &req)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/contacts", strings.NewReader(tt.body))
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			var se *bind.SchemaError
+			if !errors.As(err, &se) {
+				t.Fatalf("expected a SchemaError, got %v", err)
+			}
+			info := se.ErrorInfo()
+			if info.Code != tt.wantCode || info.Param != tt.wantParam || info.Message != tt.wantMsg {
+				t.Errorf("expected %s %s %q, got %s %s %q", tt.wantCode, tt.wantParam, tt.wantMsg, info.Code, info.Param, info.Message)
+			}
+		})
+	}
+}
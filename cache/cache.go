@@ -0,0 +1,282 @@
+// Package cache wraps expensive handler queries with a read-through cache:
+// concurrent misses for a key share one fetch (singleflight), stale entries
+// are served while a background refresh runs, and "not found" results are
+// cached briefly so missing rows don't hit the database on every request.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/doujins-org/ginapi/clock"
+)
+
+// ErrNotFound marks a fetch result as "not found". Fetch functions return
+// an error wrapping it to have the miss cached for Config.NegativeTTL;
+// Do then returns ErrNotFound until the negative entry expires.
+var ErrNotFound = errors.New("cache: not found")
+
+// Config configures a Cache.
+type Config struct {
+	// Store holding the entries (defaults to a MemoryStore of 10,000
+	// entries)
+	Store Store
+	// StaleFor is how long an entry is still served after its ttl while a
+	// background refresh runs (defaults to 0: expired entries are refetched
+	// before returning)
+	StaleFor time.Duration
+	// NegativeTTL is how long ErrNotFound results are cached (defaults to
+	// 30s; negative disables negative caching)
+	NegativeTTL time.Duration
+	// FetchTimeout bounds the fetch of a miss (defaults to 10s). The fetch
+	// is shared by every caller waiting for the key, so it isn't canceled
+	// with the context of the caller that started it.
+	FetchTimeout time.Duration
+	// RefreshTimeout bounds background refreshes (defaults to 10s)
+	RefreshTimeout time.Duration
+	// Logger for store and refresh failures (defaults to slog.Default())
+	Logger *slog.Logger
+	// Clock decides freshness, and expiry in the default store (defaults
+	// to clock.System)
+	Clock clock.Clock
+}
+
+// defaultMaxEntries caps the default store.
+const defaultMaxEntries = 10000
+
+// Cache is a read-through cache over a Store.
+type Cache struct {
+	cfg   Config
+	group singleflight.Group
+}
+
+// New returns a Cache for cfg.
+func New(cfg Config) *Cache {
+	cfg.Clock = clock.Or(cfg.Clock)
+	if cfg.Store == nil {
+		cfg.Store = newMemoryStore(defaultMaxEntries, cfg.Clock)
+	}
+	if cfg.NegativeTTL == 0 {
+		cfg.NegativeTTL = 30 * time.Second
+	}
+	if cfg.FetchTimeout == 0 {
+		cfg.FetchTimeout = 10 * time.Second
+	}
+	if cfg.RefreshTimeout == 0 {
+		cfg.RefreshTimeout = 10 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Cache{cfg: cfg}
+}
+
+// Delete removes key, e.g. after the underlying row changes.
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	return c.cfg.Store.Delete(ctx, key)
+}
+
+// KeyFor returns the conventional key of an object's cache entry, e.g.
+// "gallery:gal_3D7T". Caching objects under it lets
+// response.InvalidateCache delete the entry when a write response carries
+// the object. The id is normalized with the IDNormalizer registered for
+// objectType, so every spelling of an ID shares one entry; IDs it rejects
+// are used as given.
+func KeyFor(objectType, id string) string {
+	idNormalizersMu.RLock()
+	n, ok := idNormalizers[objectType]
+	idNormalizersMu.RUnlock()
+	if ok {
+		if normalized, err := n.Normalize(id); err == nil {
+			id = normalized
+		}
+	}
+	return objectType + ":" + id
+}
+
+// IDNormalizer returns the canonical form of an ID. ids.Formatter and
+// ids.Migration implement it.
+type IDNormalizer interface {
+	Normalize(id string) (string, error)
+}
+
+var (
+	idNormalizersMu sync.RWMutex
+	idNormalizers   = map[string]IDNormalizer{}
+)
+
+// RegisterIDs makes KeyFor normalize the IDs of objectType with n, so an
+// ID read in another form, e.g. a hyphenated UUID or a legacy ID during an
+// ids.Migration, has the same key as the canonical ID of write responses:
+//
+//	cache.RegisterIDs("gallery", galleryIDs)
+//
+// Call it at startup.
+func RegisterIDs(objectType string, n IDNormalizer) {
+	idNormalizersMu.Lock()
+	idNormalizers[objectType] = n
+	idNormalizersMu.Unlock()
+}
+
+// Invalidate removes key from the default cache.
+func Invalidate(ctx context.Context, key string) error {
+	return defaultCache.Delete(ctx, key)
+}
+
+var defaultCache = New(Config{})
+
+// SetDefault sets the cache used by Do. Call it once at startup.
+func SetDefault(c *Cache) {
+	defaultCache = c
+}
+
+// Default returns the cache used by Do.
+func Default() *Cache {
+	return defaultCache
+}
+
+// Do returns the cached value for key from the default cache, calling fetch
+// on a miss and caching its result for ttl. Values are stored as JSON.
+//
+//	gallery, err := cache.Do(ctx, cache.KeyFor("gallery", id), 5*time.Minute, func(ctx context.Context) (Gallery, error) {
+//	    g, err := repo.Get(ctx, id)
+//	    if errors.Is(err, sql.ErrNoRows) {
+//	        return Gallery{}, cache.ErrNotFound
+//	    }
+//	    return g, err
+//	})
+//	if errors.Is(err, cache.ErrNotFound) {
+//	    response.NotFound(c, "gallery not found")
+//	    return
+//	}
+func Do[T any](ctx context.Context, key string, ttl time.Duration, fetch func(ctx context.Context) (T, error)) (T, error) {
+	return DoWith(ctx, defaultCache, key, ttl, fetch)
+}
+
+// DoWith is Do on a specific cache. If ctx is done before the shared fetch
+// returns, DoWith returns ctx.Err() while the fetch goes on for the other
+// callers.
+func DoWith[T any](ctx context.Context, c *Cache, key string, ttl time.Duration, fetch func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+
+	if e, ok := c.load(ctx, key); ok {
+		if v, ok := decode[T](e); ok {
+			if c.cfg.Clock.Now().After(e.Fresh) {
+				refresh(c, ctx, key, ttl, fetch)
+			}
+			if e.NotFound {
+				return zero, ErrNotFound
+			}
+			return v, nil
+		}
+	}
+
+	ch := c.group.DoChan(key, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.cfg.FetchTimeout)
+		defer cancel()
+		return fetchAndStore(c, ctx, key, ttl, fetch)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		result, _ := res.Val.(T)
+		return result, nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// entry is the stored form of a cached result.
+type entry struct {
+	Value    json.RawMessage `json:"v,omitempty"`
+	NotFound bool            `json:"nf,omitempty"`
+	Fresh    time.Time       `json:"fresh"` // served without a refresh until then
+}
+
+// decode returns the value of e; ok is false if it can't be decoded as T.
+func decode[T any](e entry) (T, bool) {
+	var v T
+	if e.NotFound {
+		return v, true
+	}
+	return v, json.Unmarshal(e.Value, &v) == nil
+}
+
+// load reads the entry for key. Store errors are logged and treated as
+// misses, as are stale entries when StaleFor is unset.
+func (c *Cache) load(ctx context.Context, key string) (entry, bool) {
+	b, ok, err := c.cfg.Store.Get(ctx, key)
+	if err != nil {
+		c.cfg.Logger.Warn("cache get failed", "key", key, "error", err)
+		return entry{}, false
+	}
+	if !ok {
+		return entry{}, false
+	}
+	var e entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return entry{}, false
+	}
+	if c.cfg.StaleFor <= 0 && c.cfg.Clock.Now().After(e.Fresh) {
+		return entry{}, false
+	}
+	return e, true
+}
+
+// fetchAndStore calls fetch and stores its result: values for ttl (plus
+// StaleFor), ErrNotFound for NegativeTTL. Other errors aren't cached.
+func fetchAndStore[T any](c *Cache, ctx context.Context, key string, ttl time.Duration, fetch func(ctx context.Context) (T, error)) (T, error) {
+	v, err := fetch(ctx)
+
+	var e entry
+	var storeTTL time.Duration
+	switch {
+	case errors.Is(err, ErrNotFound):
+		if c.cfg.NegativeTTL < 0 {
+			return v, ErrNotFound
+		}
+		e = entry{NotFound: true, Fresh: c.cfg.Clock.Now().Add(c.cfg.NegativeTTL)}
+		storeTTL = c.cfg.NegativeTTL
+		err = ErrNotFound
+	case err != nil:
+		return v, err
+	default:
+		b, marshalErr := json.Marshal(v)
+		if marshalErr != nil {
+			c.cfg.Logger.Warn("cache encode failed", "key", key, "error", marshalErr)
+			return v, nil
+		}
+		e = entry{Value: b, Fresh: c.cfg.Clock.Now().Add(ttl)}
+		storeTTL = ttl + max(c.cfg.StaleFor, 0)
+	}
+
+	if b, marshalErr := json.Marshal(e); marshalErr == nil {
+		if setErr := c.cfg.Store.Set(ctx, key, b, storeTTL); setErr != nil {
+			c.cfg.Logger.Warn("cache set failed", "key", key, "error", setErr)
+		}
+	}
+	return v, err
+}
+
+// refresh refetches key in the background, unless a fetch is already
+// running. The refresh isn't canceled with the request.
+func refresh[T any](c *Cache, ctx context.Context, key string, ttl time.Duration, fetch func(ctx context.Context) (T, error)) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.cfg.RefreshTimeout)
+	ch := c.group.DoChan(key, func() (any, error) {
+		return fetchAndStore(c, ctx, key, ttl, fetch)
+	})
+	go func() {
+		defer cancel()
+		if res := <-ch; res.Err != nil && !errors.Is(res.Err, ErrNotFound) {
+			c.cfg.Logger.Warn("cache refresh failed", "key", key, "error", res.Err)
+		}
+	}()
+}
@@ -0,0 +1,242 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/apitest"
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/ids"
+)
+
+type gallery struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+func newCache(cfg cache.Config) *cache.Cache {
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	return cache.New(cfg)
+}
+
+func TestKeyFor(t *testing.T) {
+	cache.RegisterIDs("gallery", ids.Migration{Formatter: ids.Formatter{Prefix: "gal"}, Numeric: true})
+	cache.RegisterIDs("user", ids.Formatter{Prefix: "usr"})
+	u := ids.NewUUID()
+
+	tests := []struct {
+		objectType string
+		id         string
+		want       string
+	}{
+		{"gallery", "gal_3D7", "gallery:gal_3D7"},
+		{"gallery", "12345", "gallery:gal_3D7"}, // legacy numeric ID
+		{"gallery", "gal_not-an-id", "gallery:gal_not-an-id"},
+		{"user", u.String(), "user:" + ids.FormatUUID("usr", u)},
+		{"tag", "12345", "tag:12345"}, // no normalizer
+	}
+
+	for _, tt := range tests {
+		if got := cache.KeyFor(tt.objectType, tt.id); got != tt.want {
+			t.Errorf("KeyFor(%q, %q): expected %q, got %q", tt.objectType, tt.id, tt.want, got)
+		}
+	}
+}
+
+func TestDoWith(t *testing.T) {
+	c := newCache(cache.Config{})
+	var calls atomic.Int32
+	fetch := func(ctx context.Context) (gallery, error) {
+		calls.Add(1)
+		return gallery{ID: "gal_1", Title: "Summer"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		got, err := cache.DoWith(context.Background(), c, "gallery:1", time.Minute, fetch)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Title != "Summer" {
+			t.Errorf("expected title 'Summer', got '%s'", got.Title)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 fetch, got %d", calls.Load())
+	}
+
+	_ = c.Delete(context.Background(), "gallery:1")
+	_, _ = cache.DoWith(context.Background(), c, "gallery:1", time.Minute, fetch)
+	if calls.Load() != 2 {
+		t.Errorf("expected a refetch after Delete, got %d fetches", calls.Load())
+	}
+}
+
+func TestDoWithSingleflight(t *testing.T) {
+	c := newCache(cache.Config{})
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := cache.DoWith(context.Background(), c, "answer", time.Minute, fetch); v != 42 || err != nil {
+				t.Errorf("expected 42, got %d %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 fetch, got %d", calls.Load())
+	}
+}
+
+func TestDoWithFirstCallerCanceled(t *testing.T) {
+	c := newCache(cache.Config{})
+	started, release := make(chan struct{}), make(chan struct{})
+	fetch := func(ctx context.Context) (int, error) {
+		close(started)
+		<-release
+		return 42, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := cache.DoWith(ctx, c, "answer", time.Minute, fetch)
+		first <- err
+	}()
+	<-started
+
+	second := make(chan int, 1)
+	go func() {
+		v, err := cache.DoWith(context.Background(), c, "answer", time.Minute, fetch)
+		if err != nil {
+			t.Errorf("expected the waiter not to fail, got %v", err)
+		}
+		second <- v
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the canceled caller to get context.Canceled, got %v", err)
+	}
+	close(release)
+	if v := <-second; v != 42 {
+		t.Errorf("expected 42, got %d", v)
+	}
+}
+
+func TestDoWithNegativeCaching(t *testing.T) {
+	c := newCache(cache.Config{NegativeTTL: time.Minute})
+	var calls atomic.Int32
+	fetch := func(ctx context.Context) (gallery, error) {
+		calls.Add(1)
+		return gallery{}, cache.ErrNotFound
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.DoWith(context.Background(), c, "gallery:missing", time.Minute, fetch); !errors.Is(err, cache.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 fetch, got %d", calls.Load())
+	}
+}
+
+func TestDoWithErrorsNotCached(t *testing.T) {
+	c := newCache(cache.Config{})
+	var calls atomic.Int32
+	fetch := func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		return 0, errors.New("db down")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.DoWith(context.Background(), c, "k", time.Minute, fetch); err == nil {
+			t.Error("expected an error")
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 fetches, got %d", calls.Load())
+	}
+}
+
+func TestDoWithStaleWhileRefresh(t *testing.T) {
+	c := newCache(cache.Config{StaleFor: time.Minute})
+	var version atomic.Int32
+	refreshed := make(chan struct{}, 1)
+	fetch := func(ctx context.Context) (int32, error) {
+		v := version.Add(1)
+		if v > 1 {
+			refreshed <- struct{}{}
+		}
+		return v, nil
+	}
+
+	ttl := 10 * time.Millisecond
+	if v, _ := cache.DoWith(context.Background(), c, "k", ttl, fetch); v != 1 {
+		t.Fatalf("expected 1, got %d", v)
+	}
+	time.Sleep(2 * ttl)
+
+	// Stale: served immediately while the refresh runs
+	if v, _ := cache.DoWith(context.Background(), c, "k", ttl, fetch); v != 1 {
+		t.Errorf("expected stale value 1, got %d", v)
+	}
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("expected a background refresh")
+	}
+	time.Sleep(10 * time.Millisecond) // let the refresh store its result
+	if v, _ := cache.DoWith(context.Background(), c, "k", time.Minute, fetch); v != 2 {
+		t.Errorf("expected refreshed value 2, got %d", v)
+	}
+}
+
+func TestDoWithExpired(t *testing.T) {
+	clk := apitest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	c := newCache(cache.Config{Clock: clk})
+	var version atomic.Int32
+	fetch := func(ctx context.Context) (int32, error) { return version.Add(1), nil }
+
+	_, _ = cache.DoWith(context.Background(), c, "k", time.Minute, fetch)
+	clk.Advance(59 * time.Second)
+	if v, _ := cache.DoWith(context.Background(), c, "k", time.Minute, fetch); v != 1 {
+		t.Errorf("expected cached value 1, got %d", v)
+	}
+	clk.Advance(2 * time.Second)
+	if v, _ := cache.DoWith(context.Background(), c, "k", time.Minute, fetch); v != 2 {
+		t.Errorf("expected refetched value 2, got %d", v)
+	}
+}
+
+func TestDo(t *testing.T) {
+	defer cache.SetDefault(cache.Default())
+	cache.SetDefault(newCache(cache.Config{}))
+
+	v, err := cache.Do(context.Background(), "greeting", time.Minute, func(ctx context.Context) (string, error) {
+		return "hello", nil
+	})
+	if err != nil || v != "hello" {
+		t.Errorf("expected hello, got %q %v", v, err)
+	}
+}
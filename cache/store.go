@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/doujins-org/ginapi/clock"
+)
+
+// Store is the byte-level storage behind a Cache. Implementations must be
+// safe for concurrent use; a shared store (e.g. Redis) lets every instance
+// of a service see the same entries.
+type Store interface {
+	// Get returns the value for key; ok is false if it is missing or expired.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set stores value for key, expiring after ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes key.
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStore is an in-process Store, for single-instance services and tests.
+type MemoryStore struct {
+	clock      clock.Clock
+	mu         sync.Mutex
+	entries    map[string]memoryEntry
+	maxEntries int
+	sets       int // Set calls since the last sweep
+	swept      int // entries left by the last sweep
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryStore returns a MemoryStore holding at most maxEntries entries
+// (0 for no limit). When full, expired entries are dropped first, then an
+// arbitrary one. Expired entries are also swept out as new ones are set, so
+// keys that are never read again, like misses for random IDs, don't pile up.
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return newMemoryStore(maxEntries, clock.System)
+}
+
+// newMemoryStore returns a MemoryStore whose entries expire by clk.
+func newMemoryStore(maxEntries int, clk clock.Clock) *MemoryStore {
+	return &MemoryStore{clock: clk, entries: map[string]memoryEntry{}, maxEntries: maxEntries}
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if s.clock.Now().After(e.expires) {
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// Set implements Store.
+func (s *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Sweeping once the entries may have doubled keeps the cost of a set
+	// constant on average
+	if s.sets++; s.sets > s.swept {
+		s.sweep()
+	}
+	if _, exists := s.entries[key]; !exists && s.maxEntries > 0 && len(s.entries) >= s.maxEntries {
+		s.evict()
+	}
+	s.entries[key] = memoryEntry{value: value, expires: s.clock.Now().Add(ttl)}
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet swept.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// sweep drops expired entries. Called with s.mu held.
+func (s *MemoryStore) sweep() {
+	now := s.clock.Now()
+	for k, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+	s.sets, s.swept = 0, len(s.entries)
+}
+
+// evict makes room for one entry. Called with s.mu held.
+func (s *MemoryStore) evict() {
+	s.sweep()
+	if len(s.entries) < s.maxEntries {
+		return
+	}
+	for k := range s.entries {
+		delete(s.entries, k)
+		return
+	}
+}
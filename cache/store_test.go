@@ -0,0 +1,56 @@
+package cache_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/cache"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := cache.NewMemoryStore(2)
+
+	_ = s.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = s.Set(ctx, "b", []byte("2"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok, _ := s.Get(ctx, "b"); ok {
+		t.Error("expected b to have expired")
+	}
+
+	// Full: the expired entry makes room
+	_ = s.Set(ctx, "b", []byte("2"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_ = s.Set(ctx, "c", []byte("3"), time.Minute)
+	if v, ok, _ := s.Get(ctx, "a"); !ok || string(v) != "1" {
+		t.Errorf("expected a to survive eviction, got %q %v", v, ok)
+	}
+	if v, ok, _ := s.Get(ctx, "c"); !ok || string(v) != "3" {
+		t.Errorf("expected c, got %q %v", v, ok)
+	}
+
+	_ = s.Delete(ctx, "a")
+	if _, ok, _ := s.Get(ctx, "a"); ok {
+		t.Error("expected a to be deleted")
+	}
+}
+
+func TestMemoryStoreSweepsExpired(t *testing.T) {
+	ctx := context.Background()
+	s := cache.NewMemoryStore(0)
+
+	for i := 0; i < 100; i++ {
+		_ = s.Set(ctx, "missing:"+strconv.Itoa(i), []byte("{}"), time.Millisecond)
+	}
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 100; i++ {
+		_ = s.Set(ctx, "gallery:"+strconv.Itoa(i), []byte("{}"), time.Minute)
+	}
+
+	if s.Len() != 100 {
+		t.Errorf("expected the expired entries to be swept, got %d entries", s.Len())
+	}
+}
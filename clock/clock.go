@@ -0,0 +1,31 @@
+// Package clock abstracts the current time, so time-dependent features
+// (rate limit windows, cache TTLs, challenge expiry, circuit breaker
+// cooldowns) can be tested deterministically. Configs take a Clock field;
+// nil means System. apitest.Clock is a controllable clock for tests.
+package clock
+
+import "time"
+
+// Clock tells the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Func adapts a function to the Clock interface.
+type Func func() time.Time
+
+// Now calls f().
+func (f Func) Now() time.Time {
+	return f()
+}
+
+// System is the wall clock, time.Now.
+var System Clock = Func(time.Now)
+
+// Or returns c, or System if c is nil, for applying config defaults.
+func Or(c Clock) Clock {
+	if c == nil {
+		return System
+	}
+	return c
+}
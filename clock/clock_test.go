@@ -0,0 +1,27 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/clock"
+)
+
+func TestOr(t *testing.T) {
+	fixed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name  string
+		clock clock.Clock
+		check func(time.Time) bool
+	}{
+		{"nil is the system clock", nil, func(now time.Time) bool { return time.Since(now).Abs() < time.Minute }},
+		{"set clock is kept", clock.Func(func() time.Time { return fixed }), func(now time.Time) bool { return now.Equal(fixed) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if now := clock.Or(tt.clock).Now(); !tt.check(now) {
+				t.Errorf("unexpected time %s", now)
+			}
+		})
+	}
+}
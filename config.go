@@ -0,0 +1,459 @@
+package ginapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+// EnvPrefix is prepended to every variable name read by LoadConfig.
+const EnvPrefix = "GINAPI_"
+
+// Config configures the middleware stack from environment variables. Each
+// field's env tag names its variable (after EnvPrefix) and default tag its
+// value when unset. Lists are comma-separated; durations use
+// time.ParseDuration syntax.
+type Config struct {
+	// Environment names the Profile: "development", "staging", or
+	// "production". Empty applies none.
+	Environment string `env:"ENVIRONMENT"`
+	Language    LanguageSettings
+	Cookie      CookieSettings
+	Concurrency ConcurrencySettings
+	RateLimit   RateLimitSettings
+	Timeout     TimeoutSettings
+	CORS        CORSSettings
+	Chaos       ChaosSettings
+	// AllowedHosts for middleware.AllowedHosts; empty disables the check
+	AllowedHosts []string `env:"ALLOWED_HOSTS"`
+}
+
+// LanguageSettings configures language detection.
+type LanguageSettings struct {
+	// Supported languages, lowercase
+	Supported []string `env:"LANGUAGES" default:"en"`
+	// Default language; must be in Supported
+	Default string `env:"DEFAULT_LANGUAGE" default:"en"`
+	// QueryParam overriding the detected language
+	QueryParam string `env:"LANGUAGE_QUERY_PARAM" default:"lang"`
+}
+
+// CookieSettings configures the cookies set by the middleware.
+type CookieSettings struct {
+	// LanguageName of the language preference cookie
+	LanguageName string `env:"LANGUAGE_COOKIE" default:"lang"`
+	// LanguageMaxAge of the language preference cookie
+	LanguageMaxAge time.Duration `env:"LANGUAGE_COOKIE_MAX_AGE" default:"8760h"`
+	// Domain, e.g. ".example.com" to share across subdomains
+	Domain string `env:"COOKIE_DOMAIN"`
+	// Secure restricts cookies to HTTPS
+	Secure bool `env:"COOKIE_SECURE" default:"true"`
+	// SameSite policy: "lax", "strict", or "none" (which requires Secure)
+	SameSite string `env:"COOKIE_SAMESITE" default:"lax"`
+	// HostPrefix gives cookies the __Host- prefix where possible; see
+	// middleware.CookiePolicy
+	HostPrefix bool `env:"COOKIE_HOST_PREFIX"`
+}
+
+// ConcurrencySettings configures middleware.ConcurrencyLimit.
+type ConcurrencySettings struct {
+	// MaxInFlight requests; 0 disables the limit
+	MaxInFlight int `env:"MAX_IN_FLIGHT"`
+	// MaxQueue of waiting requests (0 defaults to MaxInFlight)
+	MaxQueue int `env:"MAX_QUEUE"`
+	// QueueTimeout for waiting requests
+	QueueTimeout time.Duration `env:"QUEUE_TIMEOUT" default:"5s"`
+}
+
+// RateLimitSettings configures a per-IP middleware.RateLimit.
+type RateLimitSettings struct {
+	// Limit of requests per IP and Window; 0 disables the limit
+	Limit int `env:"RATE_LIMIT"`
+	// Window the Limit applies to
+	Window time.Duration `env:"RATE_LIMIT_WINDOW" default:"1m"`
+	// SoftLimit past which responses carry a warning; must be below Limit
+	SoftLimit int `env:"RATE_LIMIT_SOFT"`
+	// WarnOnly warns instead of blocking; see middleware.RateLimitConfig
+	WarnOnly bool `env:"RATE_LIMIT_WARN_ONLY"`
+}
+
+// TimeoutSettings configures middleware.Timeout.
+type TimeoutSettings struct {
+	// Request budget; 0 disables the timeout
+	Request time.Duration `env:"REQUEST_TIMEOUT"`
+}
+
+// CORSSettings configures cross-origin access, for the application's CORS
+// middleware and ws.Config.AllowedOrigins.
+type CORSSettings struct {
+	// AllowedOrigins, e.g. "https://doujins.com", or "*" for any
+	AllowedOrigins []string `env:"CORS_ORIGINS"`
+	// AllowCredentials lets browsers send cookies; not with "*"
+	AllowCredentials bool `env:"CORS_ALLOW_CREDENTIALS"`
+	// MaxAge browsers may cache a preflight response for
+	MaxAge time.Duration `env:"CORS_MAX_AGE" default:"12h"`
+}
+
+// ChaosSettings configures middleware.Chaos. Leave it disabled outside
+// staging.
+type ChaosSettings struct {
+	// Enabled turns fault injection on
+	Enabled bool `env:"CHAOS_ENABLED"`
+	// Key game-day requests send in the X-Chaos-Key header; required when
+	// Enabled
+	Key string `env:"CHAOS_KEY"`
+	// Rules in middleware.ParseChaosRules syntax, e.g.
+	// "/api/search* latency=2s; POST /api/galleries error=0.2"
+	Rules string `env:"CHAOS_RULES"`
+}
+
+// LoadConfig reads the Config from environment variables and validates it.
+// The error lists every invalid variable, not just the first.
+//
+//	cfg, err := ginapi.LoadConfig()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	router.Use(middleware.Language(cfg.LanguageConfig()))
+func LoadConfig() (Config, error) {
+	return LoadConfigFrom(os.LookupEnv)
+}
+
+// LoadConfigFrom is LoadConfig reading variables through lookup instead of
+// the process environment.
+func LoadConfigFrom(lookup func(name string) (string, bool)) (Config, error) {
+	var cfg Config
+	var errs []error
+	loadFields(reflect.ValueOf(&cfg).Elem(), lookup, &errs)
+	if len(errs) == 0 {
+		errs = cfg.validate()
+	}
+	if len(errs) > 0 {
+		return cfg, fmt.Errorf("ginapi: invalid config: %w", errors.Join(errs...))
+	}
+	return cfg, nil
+}
+
+// Validate checks the config for mistakes that would otherwise surface as
+// odd runtime behavior, e.g. a default language that isn't supported.
+func (cfg Config) Validate() error {
+	if errs := cfg.validate(); len(errs) > 0 {
+		return fmt.Errorf("ginapi: invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (cfg Config) validate() []error {
+	var errs []error
+	invalid := func(name, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s%s: %s", EnvPrefix, name, fmt.Sprintf(format, args...)))
+	}
+
+	if len(cfg.Language.Supported) == 0 {
+		invalid("LANGUAGES", "at least one language is required")
+	}
+	for _, lang := range cfg.Language.Supported {
+		if lang != strings.ToLower(lang) {
+			invalid("LANGUAGES", "%q must be lowercase", lang)
+		}
+	}
+	if !slices.Contains(cfg.Language.Supported, cfg.Language.Default) {
+		invalid("DEFAULT_LANGUAGE", "%q is not in LANGUAGES %v", cfg.Language.Default, cfg.Language.Supported)
+	}
+
+	switch strings.ToLower(cfg.Cookie.SameSite) {
+	case "lax", "strict":
+	case "none":
+		if !cfg.Cookie.Secure {
+			invalid("COOKIE_SAMESITE", `"none" requires COOKIE_SECURE=true`)
+		}
+	default:
+		invalid("COOKIE_SAMESITE", "%q must be lax, strict, or none", cfg.Cookie.SameSite)
+	}
+	if cfg.Cookie.LanguageMaxAge < 0 {
+		invalid("LANGUAGE_COOKIE_MAX_AGE", "must not be negative")
+	}
+
+	if cfg.Concurrency.MaxInFlight < 0 {
+		invalid("MAX_IN_FLIGHT", "must not be negative")
+	}
+	if cfg.Concurrency.MaxQueue < 0 {
+		invalid("MAX_QUEUE", "must not be negative")
+	}
+	if cfg.Concurrency.QueueTimeout < 0 {
+		invalid("QUEUE_TIMEOUT", "must not be negative")
+	}
+
+	if cfg.RateLimit.Limit < 0 {
+		invalid("RATE_LIMIT", "must not be negative")
+	}
+	if cfg.RateLimit.Limit > 0 {
+		if cfg.RateLimit.Window <= 0 {
+			invalid("RATE_LIMIT_WINDOW", "must be positive")
+		}
+		if cfg.RateLimit.SoftLimit < 0 || cfg.RateLimit.SoftLimit >= cfg.RateLimit.Limit {
+			invalid("RATE_LIMIT_SOFT", "must be between 0 and RATE_LIMIT %d", cfg.RateLimit.Limit)
+		}
+	}
+	if cfg.Timeout.Request < 0 {
+		invalid("REQUEST_TIMEOUT", "must not be negative")
+	}
+
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		if origin == "*" {
+			if cfg.CORS.AllowCredentials {
+				invalid("CORS_ORIGINS", `"*" is not allowed with CORS_ALLOW_CREDENTIALS=true`)
+			}
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			invalid("CORS_ORIGINS", "%q must be a scheme and host, e.g. https://doujins.com", origin)
+		}
+	}
+	if cfg.CORS.MaxAge < 0 {
+		invalid("CORS_MAX_AGE", "must not be negative")
+	}
+
+	profile, ok := ProfileNamed(cfg.Environment)
+	if cfg.Environment != "" && !ok {
+		invalid("ENVIRONMENT", "%q must be development, staging, or production", cfg.Environment)
+	}
+	if cfg.Chaos.Enabled && ok && !profile.AllowChaos {
+		invalid("CHAOS_ENABLED", "is not allowed in %s", profile.Name)
+	}
+	if cfg.Chaos.Enabled && cfg.Chaos.Key == "" {
+		invalid("CHAOS_KEY", "is required when CHAOS_ENABLED=true")
+	}
+	if _, err := middleware.ParseChaosRules(cfg.Chaos.Rules); err != nil {
+		invalid("CHAOS_RULES", "%v", err)
+	}
+	return errs
+}
+
+// Runtime is the middleware whose settings Config.Update changes at
+// runtime. Nil fields are skipped.
+type Runtime struct {
+	Language  *middleware.LanguageDetector
+	Hosts     *middleware.HostAllowlist
+	RateLimit *middleware.RateLimiter
+}
+
+// Update validates cfg and applies its language, allowed hosts, and rate
+// limit settings to the middleware of rt, for a config watcher. An invalid
+// config changes nothing.
+//
+//	rt := ginapi.Runtime{
+//	    Language:  middleware.NewLanguageDetector(cfg.LanguageConfig()),
+//	    RateLimit: middleware.NewRateLimiter(cfg.RateLimitConfig()),
+//	}
+//	router.Use(rt.Language.Middleware(), rt.RateLimit.Middleware())
+//
+//	watcher.OnChange(func(cfg ginapi.Config) {
+//	    if err := cfg.Update(rt); err != nil {
+//	        slog.Error("config not applied", "error", err)
+//	    }
+//	})
+func (cfg Config) Update(rt Runtime) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if rt.RateLimit != nil {
+		if err := rt.RateLimit.Update(cfg.RateLimitRules()); err != nil {
+			return err
+		}
+	}
+	if rt.Language != nil {
+		rt.Language.Update(cfg.LanguageConfig())
+	}
+	if rt.Hosts != nil {
+		rt.Hosts.Update(cfg.AllowedHosts)
+	}
+	return nil
+}
+
+// Profile returns the Profile named by Environment; ok is false when none
+// is set.
+func (cfg Config) Profile() (profile Profile, ok bool) {
+	return ProfileNamed(cfg.Environment)
+}
+
+// LanguageConfig returns the settings for middleware.Language.
+func (cfg Config) LanguageConfig() middleware.LanguageConfig {
+	return middleware.LanguageConfig{
+		Supported:  cfg.Language.Supported,
+		Default:    cfg.Language.Default,
+		QueryParam: cfg.Language.QueryParam,
+		CookieName: cfg.Cookie.LanguageName,
+	}
+}
+
+// LanguagePreferenceConfig returns the settings for middleware.SetLanguageHandler.
+func (cfg Config) LanguagePreferenceConfig() middleware.LanguagePreferenceConfig {
+	return middleware.LanguagePreferenceConfig{
+		Supported:  cfg.Language.Supported,
+		CookieName: cfg.Cookie.LanguageName,
+		MaxAge:     int(cfg.Cookie.LanguageMaxAge / time.Second),
+		Domain:     cfg.Cookie.Domain,
+		Secure:     cfg.Cookie.Secure,
+		SameSite:   sameSite(cfg.Cookie.SameSite),
+	}
+}
+
+// CookiePolicyConfig returns the settings for middleware.CookiePolicy.
+func (cfg Config) CookiePolicyConfig() middleware.CookiePolicyConfig {
+	return middleware.CookiePolicyConfig{
+		SameSite:   sameSite(cfg.Cookie.SameSite),
+		Secure:     cfg.Cookie.Secure,
+		HostPrefix: cfg.Cookie.HostPrefix,
+	}
+}
+
+// ConcurrencyLimitConfig returns the settings for middleware.ConcurrencyLimit.
+// Only install the middleware when MaxInFlight is positive.
+func (cfg Config) ConcurrencyLimitConfig() middleware.ConcurrencyLimitConfig {
+	return middleware.ConcurrencyLimitConfig{
+		MaxInFlight:  cfg.Concurrency.MaxInFlight,
+		MaxQueue:     cfg.Concurrency.MaxQueue,
+		QueueTimeout: cfg.Concurrency.QueueTimeout,
+	}
+}
+
+// RateLimitConfig returns the settings for middleware.RateLimit: one rule
+// per IP. Only install the middleware when Limit is positive.
+func (cfg Config) RateLimitConfig() middleware.RateLimitConfig {
+	return middleware.RateLimitConfig{
+		Rules:    cfg.RateLimitRules(),
+		WarnOnly: cfg.RateLimit.WarnOnly,
+	}
+}
+
+// RateLimitRules returns the rate limit rules of the settings, nil when
+// Limit is 0.
+func (cfg Config) RateLimitRules() []middleware.RateLimitRule {
+	if cfg.RateLimit.Limit <= 0 {
+		return nil
+	}
+	return []middleware.RateLimitRule{{
+		Name:       "ip",
+		Dimensions: []middleware.Dimension{middleware.ByIP()},
+		Limit:      cfg.RateLimit.Limit,
+		Window:     cfg.RateLimit.Window,
+		SoftLimit:  cfg.RateLimit.SoftLimit,
+	}}
+}
+
+// TimeoutConfig returns the settings for middleware.Timeout. Only install
+// the middleware when the Request timeout is positive.
+func (cfg Config) TimeoutConfig() middleware.TimeoutConfig {
+	return middleware.TimeoutConfig{Timeout: cfg.Timeout.Request}
+}
+
+// ChaosConfig returns the settings for middleware.Chaos. Install the
+// middleware unconditionally; it does nothing unless CHAOS_ENABLED is set
+// and the Profile, if any, allows chaos.
+func (cfg Config) ChaosConfig() middleware.ChaosConfig {
+	rules, _ := middleware.ParseChaosRules(cfg.Chaos.Rules) // checked by Validate
+	enabled := cfg.Chaos.Enabled
+	if profile, ok := cfg.Profile(); ok && !profile.AllowChaos {
+		enabled = false
+	}
+	return middleware.ChaosConfig{
+		Enabled: enabled,
+		Key:     cfg.Chaos.Key,
+		Rules:   rules,
+	}
+}
+
+func sameSite(s string) http.SameSite {
+	switch strings.ToLower(s) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// loadFields fills the tagged fields of struct v, recursing into nested
+// structs, and collects parse errors.
+func loadFields(v reflect.Value, lookup func(string) (string, bool), errs *[]error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		if f.Type.Kind() == reflect.Struct && f.Type != durationType {
+			loadFields(fv, lookup, errs)
+			continue
+		}
+		name, ok := f.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		raw, set := lookup(EnvPrefix + name)
+		if !set {
+			raw = f.Tag.Get("default")
+		}
+		if err := setField(fv, strings.TrimSpace(raw)); err != nil {
+			*errs = append(*errs, fmt.Errorf("%s%s: %w", EnvPrefix, name, err))
+		}
+	}
+}
+
+// setField parses raw into fv according to its type.
+func setField(fv reflect.Value, raw string) error {
+	if fv.Type() == durationType {
+		if raw == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		if raw == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		fv.SetBool(b)
+	case reflect.Int:
+		if raw == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		fv.SetInt(int64(n))
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		fv.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package ginapi_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+// env returns a lookup function over vars.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	cfg, err := ginapi.LoadConfigFrom(env(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cfg.Language.Supported) != 1 || cfg.Language.Supported[0] != "en" {
+		t.Errorf("expected languages [en], got %v", cfg.Language.Supported)
+	}
+	if cfg.Language.QueryParam != "lang" {
+		t.Errorf("expected query param 'lang', got '%s'", cfg.Language.QueryParam)
+	}
+	if !cfg.Cookie.Secure || cfg.Cookie.SameSite != "lax" {
+		t.Errorf("expected secure lax cookies, got %+v", cfg.Cookie)
+	}
+	if cfg.Cookie.LanguageMaxAge != 365*24*time.Hour {
+		t.Errorf("expected 1 year cookie, got %s", cfg.Cookie.LanguageMaxAge)
+	}
+	if cfg.Concurrency.MaxInFlight != 0 || cfg.Concurrency.QueueTimeout != 5*time.Second {
+		t.Errorf("unexpected concurrency defaults %+v", cfg.Concurrency)
+	}
+	if cfg.RateLimitRules() != nil || cfg.Timeout.Request != 0 {
+		t.Errorf("expected no rate limit or timeout, got %+v and %+v", cfg.RateLimit, cfg.Timeout)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := ginapi.LoadConfigFrom(env(map[string]string{
+		"GINAPI_LANGUAGES":          "en, ja ,ko",
+		"GINAPI_DEFAULT_LANGUAGE":   "ja",
+		"GINAPI_ALLOWED_HOSTS":      "doujins.com,*.doujins.com",
+		"GINAPI_COOKIE_DOMAIN":      ".doujins.com",
+		"GINAPI_COOKIE_SAMESITE":    "strict",
+		"GINAPI_COOKIE_HOST_PREFIX": "true",
+		"GINAPI_MAX_IN_FLIGHT":      "256",
+		"GINAPI_QUEUE_TIMEOUT":      "2s",
+		"GINAPI_RATE_LIMIT":         "600",
+		"GINAPI_RATE_LIMIT_SOFT":    "450",
+		"GINAPI_REQUEST_TIMEOUT":    "5s",
+		"GINAPI_CORS_ORIGINS":       "https://doujins.com, http://localhost:3000",
+		"GINAPI_CHAOS_ENABLED":      "true",
+		"GINAPI_CHAOS_KEY":          "game-day",
+		"GINAPI_CHAOS_RULES":        "/api/search* latency=2s",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lang := cfg.LanguageConfig()
+	if strings.Join(lang.Supported, ",") != "en,ja,ko" || lang.Default != "ja" || lang.CookieName != "lang" {
+		t.Errorf("unexpected language config %+v", lang)
+	}
+	pref := cfg.LanguagePreferenceConfig()
+	if pref.Domain != ".doujins.com" || pref.SameSite != http.SameSiteStrictMode || pref.MaxAge != 365*24*60*60 {
+		t.Errorf("unexpected preference config %+v", pref)
+	}
+	cookies := cfg.CookiePolicyConfig()
+	if cookies.SameSite != http.SameSiteStrictMode || !cookies.Secure || !cookies.HostPrefix {
+		t.Errorf("unexpected cookie policy config %+v", cookies)
+	}
+	if len(cfg.AllowedHosts) != 2 {
+		t.Errorf("expected 2 allowed hosts, got %v", cfg.AllowedHosts)
+	}
+	limit := cfg.ConcurrencyLimitConfig()
+	if limit.MaxInFlight != 256 || limit.QueueTimeout != 2*time.Second {
+		t.Errorf("unexpected concurrency config %+v", limit)
+	}
+	rate := cfg.RateLimitConfig()
+	if len(rate.Rules) != 1 || rate.Rules[0].Limit != 600 || rate.Rules[0].SoftLimit != 450 || rate.Rules[0].Window != time.Minute {
+		t.Errorf("unexpected rate limit config %+v", rate)
+	}
+	if timeout := cfg.TimeoutConfig(); timeout.Timeout != 5*time.Second {
+		t.Errorf("expected a 5s timeout, got %s", timeout.Timeout)
+	}
+	if len(cfg.CORS.AllowedOrigins) != 2 || cfg.CORS.MaxAge != 12*time.Hour {
+		t.Errorf("unexpected CORS settings %+v", cfg.CORS)
+	}
+	chaos := cfg.ChaosConfig()
+	if !chaos.Enabled || chaos.Key != "game-day" || len(chaos.Rules) != 1 || chaos.Rules[0].Latency != 2*time.Second {
+		t.Errorf("unexpected chaos config %+v", chaos)
+	}
+}
+
+func TestConfigUpdate(t *testing.T) {
+	cfg, err := ginapi.LoadConfigFrom(env(map[string]string{"GINAPI_RATE_LIMIT": "100"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rt := ginapi.Runtime{
+		Language:  middleware.NewLanguageDetector(cfg.LanguageConfig()),
+		RateLimit: middleware.NewRateLimiter(cfg.RateLimitConfig()),
+	}
+
+	cfg.RateLimit.Limit = 10
+	cfg.Language.Supported = []string{"en", "ja"}
+	if err := cfg.Update(rt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rules := rt.RateLimit.Rules(); len(rules) != 1 || rules[0].Limit != 10 {
+		t.Errorf("expected a limit of 10, got %+v", rules)
+	}
+
+	cfg.RateLimit.Limit = 50
+	cfg.Language.Default = "ko"
+	if err := cfg.Update(rt); err == nil {
+		t.Fatal("expected an invalid config to be rejected")
+	}
+	if rules := rt.RateLimit.Rules(); rules[0].Limit != 10 {
+		t.Errorf("expected the limit to stay 10, got %d", rules[0].Limit)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+		want []string
+	}{
+		{
+			name: "parse errors are all reported",
+			vars: map[string]string{"GINAPI_MAX_IN_FLIGHT": "lots", "GINAPI_QUEUE_TIMEOUT": "5", "GINAPI_COOKIE_SECURE": "maybe"},
+			want: []string{`GINAPI_MAX_IN_FLIGHT: invalid integer "lots"`, `GINAPI_QUEUE_TIMEOUT: invalid duration "5"`, `GINAPI_COOKIE_SECURE: invalid boolean "maybe"`},
+		},
+		{
+			name: "languages",
+			vars: map[string]string{"GINAPI_LANGUAGES": "EN,ja", "GINAPI_DEFAULT_LANGUAGE": "ko"},
+			want: []string{`GINAPI_LANGUAGES: "EN" must be lowercase`, `GINAPI_DEFAULT_LANGUAGE: "ko" is not in LANGUAGES [EN ja]`},
+		},
+		{
+			name: "empty languages",
+			vars: map[string]string{"GINAPI_LANGUAGES": ""},
+			want: []string{"GINAPI_LANGUAGES: at least one language is required"},
+		},
+		{
+			name: "samesite none needs secure",
+			vars: map[string]string{"GINAPI_COOKIE_SAMESITE": "none", "GINAPI_COOKIE_SECURE": "false"},
+			want: []string{`GINAPI_COOKIE_SAMESITE: "none" requires COOKIE_SECURE=true`},
+		},
+		{
+			name: "negative limits",
+			vars: map[string]string{"GINAPI_MAX_QUEUE": "-1"},
+			want: []string{"GINAPI_MAX_QUEUE: must not be negative"},
+		},
+		{
+			name: "rate limit",
+			vars: map[string]string{"GINAPI_RATE_LIMIT": "100", "GINAPI_RATE_LIMIT_SOFT": "100", "GINAPI_RATE_LIMIT_WINDOW": "0s"},
+			want: []string{"GINAPI_RATE_LIMIT_WINDOW: must be positive", "GINAPI_RATE_LIMIT_SOFT: must be between 0 and RATE_LIMIT 100"},
+		},
+		{
+			name: "timeout",
+			vars: map[string]string{"GINAPI_REQUEST_TIMEOUT": "-1s"},
+			want: []string{"GINAPI_REQUEST_TIMEOUT: must not be negative"},
+		},
+		{
+			name: "cors",
+			vars: map[string]string{"GINAPI_CORS_ORIGINS": "*,doujins.com,https://doujins.com/app", "GINAPI_CORS_ALLOW_CREDENTIALS": "true"},
+			want: []string{
+				`GINAPI_CORS_ORIGINS: "*" is not allowed with CORS_ALLOW_CREDENTIALS=true`,
+				`GINAPI_CORS_ORIGINS: "doujins.com" must be a scheme and host`,
+				`GINAPI_CORS_ORIGINS: "https://doujins.com/app" must be a scheme and host`,
+			},
+		},
+		{
+			name: "environment",
+			vars: map[string]string{"GINAPI_ENVIRONMENT": "prod"},
+			want: []string{`GINAPI_ENVIRONMENT: "prod" must be development, staging, or production`},
+		},
+		{
+			name: "chaos in production",
+			vars: map[string]string{"GINAPI_ENVIRONMENT": "production", "GINAPI_CHAOS_ENABLED": "true", "GINAPI_CHAOS_KEY": "game-day"},
+			want: []string{"GINAPI_CHAOS_ENABLED: is not allowed in production"},
+		},
+		{
+			name: "chaos",
+			vars: map[string]string{"GINAPI_CHAOS_ENABLED": "true", "GINAPI_CHAOS_RULES": "/api error=2"},
+			want: []string{"GINAPI_CHAOS_KEY: is required when CHAOS_ENABLED=true", `GINAPI_CHAOS_RULES: chaos rule "/api error=2": error rate 2 must be between 0 and 1`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ginapi.LoadConfigFrom(env(tt.vars))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error to contain %q, got %v", want, err)
+				}
+			}
+		})
+	}
+}
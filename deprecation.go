@@ -0,0 +1,206 @@
+package ginapi
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/clock"
+	"github.com/doujins-org/ginapi/metrics"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// MetricDeprecatedRequests counts the requests to deprecated routes.
+const MetricDeprecatedRequests = "http.server.deprecated_requests"
+
+// OtherClients is the client the requests of clients past
+// DeprecationConfig.MaxClients are counted under.
+const OtherClients = "other"
+
+// DeprecationConfig configures a DeprecationTracker.
+type DeprecationConfig struct {
+	// Sink receives a MetricDeprecatedRequests count per request, tagged
+	// with the method, route, and principal type ("anonymous" without
+	// one). Optional.
+	Sink metrics.Sink
+	// Client identifies the caller (defaults to DeprecationClient)
+	Client func(c *gin.Context) string
+	// MaxClients tracked per route (defaults to 1000); further clients are
+	// counted as OtherClients
+	MaxClients int
+	// Clock defaults to clock.System
+	Clock clock.Clock
+}
+
+// DeprecatedRoute is the usage of a deprecated route since the process
+// started.
+type DeprecatedRoute struct {
+	Object   string    `json:"object"` // Always "deprecated_route"
+	Method   string    `json:"method"`
+	Path     string    `json:"path"`
+	Requests int64     `json:"requests"`
+	LastSeen time.Time `json:"last_seen"`
+	// Clients are the callers, most requests first
+	Clients []DeprecatedClient `json:"clients"`
+}
+
+// DeprecatedClient is one caller's usage of a deprecated route.
+type DeprecatedClient struct {
+	Client    string    `json:"client"`
+	Requests  int64     `json:"requests"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// NewDeprecationTracker returns a tracker recording who still calls the
+// routes marked Deprecated in their Meta, so the remaining integrators
+// can be contacted before removal:
+//
+//	deprecations := ginapi.NewDeprecationTracker(ginapi.DeprecationConfig{Sink: statsd})
+//	router.Use(deprecations.Middleware())
+//	admin.GET("/deprecations", deprecations.Handler())
+//
+// Counts are kept in memory per process; aggregate them across instances
+// with the metric, or by querying each instance.
+func NewDeprecationTracker(cfg DeprecationConfig) *DeprecationTracker {
+	if cfg.Client == nil {
+		cfg.Client = DeprecationClient
+	}
+	if cfg.MaxClients <= 0 {
+		cfg.MaxClients = 1000
+	}
+	return &DeprecationTracker{cfg: cfg, clock: clock.Or(cfg.Clock), routes: map[string]*deprecatedUsage{}}
+}
+
+// DeprecationTracker counts the requests to deprecated routes per client;
+// see NewDeprecationTracker.
+type DeprecationTracker struct {
+	cfg   DeprecationConfig
+	clock clock.Clock
+
+	mu     sync.Mutex
+	routes map[string]*deprecatedUsage // "METHOD /path" -> usage
+}
+
+// deprecatedUsage is the usage of one route.
+type deprecatedUsage struct {
+	method, path string
+	requests     int64
+	lastSeen     time.Time
+	clients      map[string]*DeprecatedClient
+}
+
+// DeprecationClient identifies the caller of a deprecated route: the
+// principal's type and ID, e.g. "api_key:key_123", or the User-Agent of
+// anonymous requests, e.g. "ua:gallery-sync/1.2".
+func DeprecationClient(c *gin.Context) string {
+	if p, ok := auth.GetPrincipal(c); ok && p.ID != "" {
+		return p.Type + ":" + p.ID
+	}
+	if ua := c.Request.UserAgent(); ua != "" {
+		return "ua:" + ua
+	}
+	return "ua:unknown"
+}
+
+// Middleware returns the middleware recording requests to deprecated
+// routes. It records after the handlers run, so the principal set by auth
+// middleware registered after it is known.
+func (t *DeprecationTracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		meta, ok := MetaOf(c)
+		if !ok || !meta.Deprecated {
+			return
+		}
+		t.record(c.Request.Method, c.FullPath(), t.cfg.Client(c))
+
+		if t.cfg.Sink != nil {
+			principalType := "anonymous"
+			if p, ok := auth.GetPrincipal(c); ok && p.Type != "" {
+				principalType = p.Type
+			}
+			t.cfg.Sink.Count(MetricDeprecatedRequests, 1,
+				metrics.Tag{Key: "method", Value: c.Request.Method},
+				metrics.Tag{Key: "route", Value: c.FullPath()},
+				metrics.Tag{Key: "principal_type", Value: principalType},
+			)
+		}
+	}
+}
+
+// record counts a request by client to method and path.
+func (t *DeprecationTracker) record(method, path, client string) {
+	now := t.clock.Now()
+	key := method + " " + path
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, ok := t.routes[key]
+	if !ok {
+		u = &deprecatedUsage{method: method, path: path, clients: map[string]*DeprecatedClient{}}
+		t.routes[key] = u
+	}
+	u.requests++
+	u.lastSeen = now
+
+	dc, ok := u.clients[client]
+	if !ok && len(u.clients) >= t.cfg.MaxClients {
+		client = OtherClients
+		dc, ok = u.clients[client]
+	}
+	if !ok {
+		dc = &DeprecatedClient{Client: client, FirstSeen: now}
+		u.clients[client] = dc
+	}
+	dc.Requests++
+	dc.LastSeen = now
+}
+
+// Usage returns the deprecated routes called so far, by path and method.
+func (t *DeprecationTracker) Usage() []DeprecatedRoute {
+	t.mu.Lock()
+	usage := make([]DeprecatedRoute, 0, len(t.routes))
+	for _, u := range t.routes {
+		r := DeprecatedRoute{
+			Object:   "deprecated_route",
+			Method:   u.method,
+			Path:     u.path,
+			Requests: u.requests,
+			LastSeen: u.lastSeen,
+			Clients:  make([]DeprecatedClient, 0, len(u.clients)),
+		}
+		for _, dc := range u.clients {
+			r.Clients = append(r.Clients, *dc)
+		}
+		sort.Slice(r.Clients, func(i, j int) bool {
+			if r.Clients[i].Requests != r.Clients[j].Requests {
+				return r.Clients[i].Requests > r.Clients[j].Requests
+			}
+			return r.Clients[i].Client < r.Clients[j].Client
+		})
+		usage = append(usage, r)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Path != usage[j].Path {
+			return usage[i].Path < usage[j].Path
+		}
+		return usage[i].Method < usage[j].Method
+	})
+	return usage
+}
+
+// Handler returns a handler listing Usage. Mount it behind admin auth:
+// client IDs and user agents identify integrators.
+func (t *DeprecationTracker) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		usage := t.Usage()
+		response.ListResponse(c, usage, int64(len(usage)), len(usage), 0)
+	}
+}
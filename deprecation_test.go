@@ -0,0 +1,129 @@
+package ginapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/clock"
+	"github.com/doujins-org/ginapi/metrics"
+)
+
+// countSink records the tags of the counts it receives.
+type countSink struct {
+	mu     sync.Mutex
+	counts []map[string]string
+}
+
+func (s *countSink) Count(name string, value int64, tags ...metrics.Tag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := map[string]string{"name": name}
+	for _, t := range tags {
+		m[t.Key] = t.Value
+	}
+	s.counts = append(s.counts, m)
+}
+
+func (s *countSink) Gauge(string, float64, ...metrics.Tag)        {}
+func (s *countSink) Timing(string, time.Duration, ...metrics.Tag) {}
+
+func TestDeprecationTracker(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	sink := &countSink{}
+	tracker := ginapi.NewDeprecationTracker(ginapi.DeprecationConfig{
+		Sink:       sink,
+		MaxClients: 2,
+		Clock:      clock.Func(func() time.Time { return now }),
+	})
+
+	router := gin.New()
+	router.Use(tracker.Middleware())
+	router.Use(func(c *gin.Context) {
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			auth.SetPrincipal(c, auth.Principal{ID: key, Type: auth.TypeAPIKey})
+		}
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	ginapi.Handle(router, http.MethodGet, "/v1/deprecation-test/galleries", ginapi.RouteMeta{Deprecated: true}, ok)
+	router.GET("/v1/deprecation-test/tags", ginapi.Route(ok, ginapi.Meta{Deprecated: true}))
+	router.GET("/v2/deprecation-test/galleries", ok)
+
+	send := func(path, apiKey, userAgent string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		req.Header.Set("User-Agent", userAgent)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	send("/v1/deprecation-test/galleries", "key_1", "")
+	send("/v1/deprecation-test/galleries", "key_1", "")
+	send("/v1/deprecation-test/galleries", "", "gallery-sync/1.2")
+	send("/v1/deprecation-test/galleries", "key_2", "") // past MaxClients
+	send("/v1/deprecation-test/tags", "", "")
+	send("/v2/deprecation-test/galleries", "key_1", "")
+
+	usage := tracker.Usage()
+	if len(usage) != 2 {
+		t.Fatalf("expected 2 deprecated routes, got %+v", usage)
+	}
+	galleries := usage[0]
+	if galleries.Path != "/v1/deprecation-test/galleries" || galleries.Requests != 4 || !galleries.LastSeen.Equal(now) {
+		t.Errorf("unexpected usage %+v", galleries)
+	}
+	wantClients := []struct {
+		client   string
+		requests int64
+	}{{"api_key:key_1", 2}, {"other", 1}, {"ua:gallery-sync/1.2", 1}}
+	if len(galleries.Clients) != len(wantClients) {
+		t.Fatalf("expected %d clients, got %+v", len(wantClients), galleries.Clients)
+	}
+	for i, want := range wantClients {
+		if got := galleries.Clients[i]; got.Client != want.client || got.Requests != want.requests {
+			t.Errorf("expected %s with %d requests, got %s with %d", want.client, want.requests, got.Client, got.Requests)
+		}
+	}
+	if tags := usage[1]; tags.Path != "/v1/deprecation-test/tags" || tags.Clients[0].Client != "ua:unknown" {
+		t.Errorf("unexpected usage %+v", tags)
+	}
+
+	if len(sink.counts) != 5 {
+		t.Fatalf("expected 5 counts, got %d", len(sink.counts))
+	}
+	if got := sink.counts[0]; got["name"] != ginapi.MetricDeprecatedRequests || got["route"] != "/v1/deprecation-test/galleries" || got["principal_type"] != "api_key" {
+		t.Errorf("unexpected count %v", got)
+	}
+	if got := sink.counts[2]["principal_type"]; got != "anonymous" {
+		t.Errorf("expected anonymous, got %s", got)
+	}
+}
+
+func TestDeprecationTrackerHandler(t *testing.T) {
+	tracker := ginapi.NewDeprecationTracker(ginapi.DeprecationConfig{})
+	router := gin.New()
+	router.Use(tracker.Middleware())
+	router.GET("/v1/deprecation-handler-test", ginapi.Route(func(c *gin.Context) {}, ginapi.Meta{Deprecated: true}))
+	router.GET("/admin/deprecations", tracker.Handler())
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/deprecation-handler-test", nil))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/deprecations", nil))
+
+	var list struct {
+		Data []ginapi.DeprecatedRoute `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Data) != 1 || list.Data[0].Object != "deprecated_route" || list.Data[0].Requests != 1 {
+		t.Errorf("unexpected list %s", w.Body.String())
+	}
+}
@@ -0,0 +1,209 @@
+// Package events collects domain events emitted while handling a request
+// and hands them to a pluggable publisher once the response is written, so
+// services publish changes triggered by API calls the same way everywhere.
+//
+// Events are only published for successful (< 400) responses, outbox-style:
+// a handler that emits "gallery.updated" and then fails with a 500 doesn't
+// announce a change that was rolled back.
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrNotCollecting is returned by Emit when no collector is installed.
+var ErrNotCollecting = errors.New("events: no collector in context")
+
+// Event is a domain event emitted by a handler.
+type Event struct {
+	Name    string    // e.g. "gallery.updated"
+	Payload any       // published as-is; the publisher decides the encoding
+	Time    time.Time // when Emit was called
+}
+
+// Publisher delivers the events collected for one request.
+type Publisher interface {
+	Publish(ctx context.Context, events []Event) error
+}
+
+// PublisherFunc adapts a function to the Publisher interface.
+type PublisherFunc func(ctx context.Context, events []Event) error
+
+// Publish calls f(ctx, events).
+func (f PublisherFunc) Publish(ctx context.Context, events []Event) error {
+	return f(ctx, events)
+}
+
+// Collector accumulates the events for one request. It is safe for
+// concurrent use, so handlers may emit from goroutines they wait for.
+type Collector struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// Add records an event.
+func (c *Collector) Add(name string, payload any) {
+	c.mu.Lock()
+	c.events = append(c.events, Event{Name: name, Payload: payload, Time: time.Now()})
+	c.mu.Unlock()
+}
+
+// Events returns the recorded events in emission order.
+func (c *Collector) Events() []Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Event(nil), c.events...)
+}
+
+// Discard drops the recorded events, e.g. after a transaction rolls back
+// in a handler that still responds with success.
+func (c *Collector) Discard() {
+	c.mu.Lock()
+	c.events = nil
+	c.mu.Unlock()
+}
+
+// collectorKey is the gin context key for the request's collector.
+const collectorKey = "ginapi.events"
+
+// collectorContextKey is the request context key for the request's collector.
+type collectorContextKey struct{}
+
+// WithCollector returns a copy of ctx with a new collector, and the collector.
+// This is the net/http equivalent of Middleware; call Publish after writing
+// the response.
+func WithCollector(ctx context.Context) (context.Context, *Collector) {
+	coll := &Collector{}
+	return context.WithValue(ctx, collectorContextKey{}, coll), coll
+}
+
+// CollectorFromContext returns the request's collector, or nil if none is
+// installed. ctx may be a *gin.Context or a request context.
+func CollectorFromContext(ctx context.Context) *Collector {
+	if c, ok := ctx.(*gin.Context); ok {
+		if v, exists := c.Get(collectorKey); exists {
+			if coll, ok := v.(*Collector); ok {
+				return coll
+			}
+		}
+		if c.Request == nil {
+			return nil
+		}
+		ctx = c.Request.Context()
+	}
+	if ctx == nil {
+		return nil
+	}
+	coll, _ := ctx.Value(collectorContextKey{}).(*Collector)
+	return coll
+}
+
+// Emit records an event for publishing after the response is written.
+// ctx may be a *gin.Context or any context derived from the request context.
+// Returns ErrNotCollecting if Middleware (or WithCollector) isn't installed.
+//
+//	events.Emit(c, "gallery.updated", GalleryUpdated{ID: gallery.ID})
+//	response.Object(c, gallery)
+func Emit(ctx context.Context, name string, payload any) error {
+	coll := CollectorFromContext(ctx)
+	if coll == nil {
+		return ErrNotCollecting
+	}
+	coll.Add(name, payload)
+	return nil
+}
+
+// Discard drops the events emitted so far in the request.
+func Discard(ctx context.Context) {
+	if coll := CollectorFromContext(ctx); coll != nil {
+		coll.Discard()
+	}
+}
+
+// Config configures the events middleware.
+type Config struct {
+	// Publisher receives each request's events (required)
+	Publisher Publisher
+	// Async publishes on a new goroutine instead of before the handler chain
+	// returns, so publishing never delays the end of the response
+	Async bool
+	// Timeout bounds each Publish call (defaults to 5s)
+	Timeout time.Duration
+	// Logger for publish failures (defaults to slog.Default())
+	Logger *slog.Logger
+}
+
+// Middleware returns middleware that installs a collector for each request
+// and, after the handlers below it have written a successful response,
+// passes the emitted events to cfg.Publisher. Publish runs with a context
+// detached from the request's cancellation, so a client disconnecting
+// doesn't cancel it. Failures are logged.
+//
+//	router.Use(events.Middleware(events.Config{
+//	    Publisher: events.PublisherFunc(func(ctx context.Context, evs []events.Event) error {
+//	        return outbox.Insert(ctx, evs)
+//	    }),
+//	}))
+func Middleware(cfg Config) gin.HandlerFunc {
+	if cfg.Publisher == nil {
+		panic("events: Middleware requires a Publisher")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	return func(c *gin.Context) {
+		ctx, coll := WithCollector(c.Request.Context())
+		c.Set(collectorKey, coll)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+		evs := coll.Events()
+		if len(evs) == 0 {
+			return
+		}
+
+		// c is recycled once the chain returns, so don't touch it in publish
+		method, path := c.Request.Method, c.Request.URL.Path
+		publish := func() {
+			if err := Publish(context.WithoutCancel(ctx), cfg.Publisher, evs, cfg.Timeout); err != nil {
+				cfg.Logger.Error("events publish failed",
+					"error", err, "count", len(evs), "method", method, "path", path)
+			}
+		}
+		if cfg.Async {
+			go publish()
+			return
+		}
+		publish()
+	}
+}
+
+// Publish hands events to pub with the given timeout (0 for none).
+// Middleware calls it after each successful request; net/http services
+// using WithCollector call it after writing the response.
+func Publish(ctx context.Context, pub Publisher, events []Event, timeout time.Duration) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return pub.Publish(ctx, events)
+}
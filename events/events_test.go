@@ -0,0 +1,172 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/events"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// recorder is a Publisher that records what it was given.
+type recorder struct {
+	mu        sync.Mutex
+	published [][]events.Event
+	err       error
+}
+
+func (r *recorder) Publish(ctx context.Context, evs []events.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	r.published = append(r.published, evs)
+	return r.err
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   gin.HandlerFunc
+		wantNames []string
+	}{
+		{
+			name: "published after success",
+			handler: func(c *gin.Context) {
+				_ = events.Emit(c, "gallery.updated", "gal_1")
+				_ = events.Emit(c.Request.Context(), "tag.added", "tag_1")
+				response.Object(c, gin.H{"object": "gallery", "id": "gal_1"})
+			},
+			wantNames: []string{"gallery.updated", "tag.added"},
+		},
+		{
+			name: "dropped on error",
+			handler: func(c *gin.Context) {
+				_ = events.Emit(c, "gallery.updated", "gal_1")
+				response.InternalError(c, "commit failed")
+			},
+		},
+		{
+			name: "discarded",
+			handler: func(c *gin.Context) {
+				_ = events.Emit(c, "gallery.updated", "gal_1")
+				events.Discard(c)
+				response.Success(c, "nothing changed")
+			},
+		},
+		{
+			name:    "nothing emitted",
+			handler: func(c *gin.Context) { response.NoContent(c) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := &recorder{}
+			router := gin.New()
+			router.Use(events.Middleware(events.Config{Publisher: pub}))
+			router.POST("/test", tt.handler)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/test", nil)
+			router.ServeHTTP(w, req)
+
+			if len(tt.wantNames) == 0 {
+				if len(pub.published) != 0 {
+					t.Errorf("expected nothing published, got %v", pub.published)
+				}
+				return
+			}
+			if len(pub.published) != 1 {
+				t.Fatalf("expected 1 publish, got %d", len(pub.published))
+			}
+			got := pub.published[0]
+			if len(got) != len(tt.wantNames) {
+				t.Fatalf("expected %d events, got %d", len(tt.wantNames), len(got))
+			}
+			for i, name := range tt.wantNames {
+				if got[i].Name != name {
+					t.Errorf("expected event %d to be %s, got %s", i, name, got[i].Name)
+				}
+				if got[i].Time.IsZero() {
+					t.Errorf("expected event %d to have a time", i)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareAsync(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	pub := events.PublisherFunc(func(ctx context.Context, evs []events.Event) error {
+		defer wg.Done()
+		if len(evs) != 1 || evs[0].Payload != 42 {
+			t.Errorf("expected one event with payload 42, got %v", evs)
+		}
+		return nil
+	})
+
+	router := gin.New()
+	router.Use(events.Middleware(events.Config{Publisher: pub, Async: true}))
+	router.POST("/test", func(c *gin.Context) {
+		_ = events.Emit(c, "gallery.viewed", 42)
+		response.NoContent(c)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/test", nil)
+	router.ServeHTTP(w, req)
+	wg.Wait()
+}
+
+func TestMiddlewarePublishError(t *testing.T) {
+	pub := &recorder{err: errors.New("broker down")}
+	router := gin.New()
+	router.Use(events.Middleware(events.Config{
+		Publisher: pub,
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}))
+	router.POST("/test", func(c *gin.Context) {
+		_ = events.Emit(c, "gallery.updated", nil)
+		response.Success(c, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/test", nil)
+	router.ServeHTTP(w, req)
+
+	// The response is already sent, so a publish failure doesn't change it
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestEmitWithoutCollector(t *testing.T) {
+	if err := events.Emit(context.Background(), "gallery.updated", nil); !errors.Is(err, events.ErrNotCollecting) {
+		t.Errorf("expected ErrNotCollecting, got %v", err)
+	}
+}
+
+func TestWithCollector(t *testing.T) {
+	ctx, coll := events.WithCollector(context.Background())
+	_ = events.Emit(ctx, "a", 1)
+	_ = events.Emit(ctx, "b", 2)
+
+	pub := &recorder{}
+	if err := events.Publish(ctx, pub, coll.Events(), 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pub.published) != 1 || len(pub.published[0]) != 2 {
+		t.Errorf("expected one publish of 2 events, got %v", pub.published)
+	}
+}
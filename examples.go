@@ -0,0 +1,133 @@
+package ginapi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Example is a sample response of a route, declared in RouteMeta so it
+// is published with the route and served by Mock:
+//
+//	ginapi.Handle(api, http.MethodGet, "/galleries/:id", ginapi.RouteMeta{
+//	    Response: models.Gallery{},
+//	    Examples: []ginapi.Example{
+//	        {Name: "gallery", Body: models.Gallery{Object: "gallery", ID: "gal_1", Title: "Summer"}},
+//	        ginapi.ErrorExample("not_found", http.StatusNotFound, response.ErrorInfo{
+//	            Type: response.ErrorTypeNotFound, Code: response.ErrorCodeResourceNotFound, Message: "gallery not found",
+//	        }),
+//	    },
+//	}, getGallery)
+type Example struct {
+	// Name identifies the example, e.g. "gallery" or "not_found"
+	Name string `json:"name"`
+	// Summary describes the case, e.g. "the gallery was deleted"
+	Summary string `json:"summary,omitempty"`
+	// Status is the response status (defaults to 200)
+	Status int `json:"status"`
+	// Body is the response body as sent, marshaled to JSON; nil for none
+	Body any `json:"body,omitempty"`
+}
+
+// ErrorExample returns an Example of an error response with info, in the
+// envelope the response package sends.
+func ErrorExample(name string, status int, info response.ErrorInfo) Example {
+	if info.DocURL == "" {
+		info.DocURL = response.ErrorDocURL(info.Code)
+	}
+	return Example{Name: name, Summary: info.Message, Status: status, Body: response.Error{Object: "error", Error: info}}
+}
+
+// status returns the status of e, defaulting to 200.
+func (e Example) status() int {
+	if e.Status == 0 {
+		return http.StatusOK
+	}
+	return e.Status
+}
+
+// Mock returns an engine serving the examples of routes, usually
+// ginapi.Routes(router), for frontends and other services to develop
+// against before the real endpoints exist:
+//
+//	mock := ginapi.Mock(ginapi.Routes(router))
+//	http.ListenAndServe(":8081", mock)
+//
+// Each route responds with its first 2xx example, or its first if it has
+// none. Clients pick another with the Prefer header: "Prefer:
+// example=not_found" selects by name and "Prefer: code=404" by status;
+// the choice is echoed in Preference-Applied. A preference no example
+// matches is a 404 so a typo isn't mistaken for the default. Routes
+// without examples aren't served.
+func Mock(routes []RouteInfo) *gin.Engine {
+	engine := gin.New()
+	for _, r := range routes {
+		if len(r.Examples) == 0 {
+			continue
+		}
+		engine.Handle(r.Method, r.Path, mockHandler(r))
+	}
+	return engine
+}
+
+// mockHandler serves the examples of r.
+func mockHandler(r RouteInfo) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Prefer")
+		e, applied, ok := pickExample(r.Examples, c.Request.Header.Values("Prefer"))
+		if !ok {
+			response.ErrorWithInfo(c, http.StatusNotFound, response.ErrorInfo{
+				Type:    response.ErrorTypeNotFound,
+				Message: fmt.Sprintf("no example matching %q for %s %s", applied, r.Method, r.Path),
+			})
+			return
+		}
+		if applied != "" {
+			c.Header("Preference-Applied", applied)
+		}
+		if e.Body == nil || c.Request.Method == http.MethodHead {
+			c.Status(e.status())
+			return
+		}
+		c.JSON(e.status(), e.Body)
+	}
+}
+
+// pickExample selects the example the Prefer header values ask for,
+// returning the applied preference.
+func pickExample(examples []Example, prefer []string) (Example, string, bool) {
+	for _, value := range prefer {
+		for _, pref := range strings.Split(value, ",") {
+			key, v, _ := strings.Cut(strings.TrimSpace(pref), "=")
+			v = strings.Trim(strings.TrimSpace(v), `"`)
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "example":
+				for _, e := range examples {
+					if e.Name == v {
+						return e, "example=" + v, true
+					}
+				}
+				return Example{}, "example=" + v, false
+			case "code":
+				status, _ := strconv.Atoi(v)
+				for _, e := range examples {
+					if e.status() == status {
+						return e, "code=" + v, true
+					}
+				}
+				return Example{}, "code=" + v, false
+			}
+		}
+	}
+	for _, e := range examples {
+		if e.status() < 300 {
+			return e, "", true
+		}
+	}
+	return examples[0], "", true
+}
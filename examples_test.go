@@ -0,0 +1,87 @@
+package ginapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestMock(t *testing.T) {
+	router := gin.New()
+	ginapi.Handle(router, http.MethodGet, "/examples/galleries/:id", ginapi.RouteMeta{
+		Examples: []ginapi.Example{
+			ginapi.ErrorExample("not_found", http.StatusNotFound, response.ErrorInfo{
+				Type:    response.ErrorTypeNotFound,
+				Code:    response.ErrorCodeResourceNotFound,
+				Message: "gallery not found",
+			}),
+			{Name: "gallery", Body: gin.H{"object": "gallery", "id": "gal_1"}},
+			{Name: "archived", Body: gin.H{"object": "gallery", "id": "gal_2"}},
+		},
+	}, func(c *gin.Context) {})
+	router.DELETE("/examples/galleries/:id", ginapi.Route(deleteGallery, ginapi.Meta{
+		Examples: []ginapi.Example{{Name: "deleted", Status: http.StatusNoContent}},
+	}))
+	router.GET("/examples/tags", func(c *gin.Context) {})
+
+	routes := ginapi.Routes(router)
+	if len(routes[1].Examples) != 3 {
+		t.Fatalf("expected 3 examples listed, got %+v", routes[1].Examples)
+	}
+	mock := ginapi.Mock(routes)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		prefer      string
+		wantStatus  int
+		wantID      string
+		wantCode    string
+		wantApplied string
+	}{
+		{name: "first success", method: "GET", path: "/examples/galleries/gal_9", wantStatus: 200, wantID: "gal_1"},
+		{name: "by name", method: "GET", path: "/examples/galleries/gal_9", prefer: "example=archived", wantStatus: 200, wantID: "gal_2", wantApplied: "example=archived"},
+		{name: "by code", method: "GET", path: "/examples/galleries/gal_9", prefer: "code=404", wantStatus: 404, wantCode: response.ErrorCodeResourceNotFound, wantApplied: "code=404"},
+		{name: "other preferences ignored", method: "GET", path: "/examples/galleries/gal_9", prefer: `respond-async, example="not_found"`, wantStatus: 404, wantCode: response.ErrorCodeResourceNotFound, wantApplied: "example=not_found"},
+		{name: "unknown example", method: "GET", path: "/examples/galleries/gal_9", prefer: "example=missing", wantStatus: 404},
+		{name: "no body", method: "DELETE", path: "/examples/galleries/gal_9", wantStatus: 204},
+		{name: "no examples", method: "GET", path: "/examples/tags", wantStatus: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.prefer != "" {
+				req.Header.Set("Prefer", tt.prefer)
+			}
+			w := httptest.NewRecorder()
+			mock.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body)
+			}
+			if got := w.Header().Get("Preference-Applied"); got != tt.wantApplied {
+				t.Errorf("expected Preference-Applied %q, got %q", tt.wantApplied, got)
+			}
+			if tt.wantID != "" {
+				var body struct{ ID string }
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.ID != tt.wantID {
+					t.Errorf("expected id %s, got %s", tt.wantID, w.Body)
+				}
+			}
+			if tt.wantCode != "" {
+				e, err := response.ParseError(w.Body.Bytes())
+				if err != nil || e.Error.Code != tt.wantCode {
+					t.Errorf("expected code %s, got %s", tt.wantCode, w.Body)
+				}
+			}
+		})
+	}
+}
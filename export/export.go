@@ -0,0 +1,205 @@
+// Package export is the shared implementation of "export my data"
+// features: clients start an export with POST /exports, poll GET
+// /exports/{id} until it succeeds, then download the file from the signed
+// URL it carries. Rows are produced in the background, through a
+// tasks.Queue, and streamed to CSV or NDJSON files that are deleted once
+// they expire:
+//
+//	exporter := export.NewExporter(export.Config{
+//	    Types: map[string]export.Type{
+//	        "galleries": {
+//	            Columns: []string{"id", "title", "created"},
+//	            Produce: func(ctx context.Context, e export.Export, w export.RowWriter) error {
+//	                return store.EachGallery(ctx, e.OwnerID, func(g Gallery) error { return w.Write(g) })
+//	            },
+//	        },
+//	    },
+//	    Files:  export.DirFiles("/var/lib/api/exports"),
+//	    Queue:  worker,
+//	    Secret: cfg.ExportURLKey,
+//	})
+//	worker.Handle(export.TaskName, exporter.Run)
+//	exporter.Register(api.Group("/exports", auth.Require()))
+package export
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format is the file format of an export.
+type Format string
+
+// Formats.
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// Statuses of an export.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// ErrNotFound is returned by Store.Get for unknown exports.
+var ErrNotFound = errors.New("export: not found")
+
+// Export is the job object of an export.
+type Export struct {
+	Object string            `json:"object"` // Always "export"
+	ID     string            `json:"id"`     // "exp_" and 16 hex digits
+	Type   string            `json:"type"`   // e.g. "galleries"
+	Format Format            `json:"format"`
+	Params map[string]string `json:"params,omitempty"`
+	Status string            `json:"status"`
+	// Rows written so far, once running
+	Rows int64 `json:"rows"`
+	// Error describes why a failed export failed
+	Error string `json:"error,omitempty"`
+	// DownloadURL is a signed URL for the file, once succeeded
+	DownloadURL string     `json:"download_url,omitempty"`
+	Created     time.Time  `json:"created"`
+	Completed   *time.Time `json:"completed,omitempty"`
+	// ExpiresAt is when the export and its file are deleted
+	ExpiresAt time.Time `json:"expires_at"`
+	// OwnerID is the ID of the principal who started it
+	OwnerID string `json:"-"`
+}
+
+// fileName is the name of e's file in Files.
+func (e Export) fileName() string {
+	return e.ID + "." + string(e.Format)
+}
+
+// newID returns a random export ID.
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "exp_" + hex.EncodeToString(b)
+}
+
+// Store keeps export job objects.
+type Store interface {
+	// Save creates or replaces e
+	Save(ctx context.Context, e Export) error
+	// Get returns the export id, or ErrNotFound
+	Get(ctx context.Context, id string) (Export, error)
+	// Expired returns the exports that expired before t
+	Expired(ctx context.Context, t time.Time) ([]Export, error)
+	// Delete removes the export id, if it exists
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryStore is an in-process Store, for tests and single-instance
+// services.
+type MemoryStore struct {
+	mu      sync.Mutex
+	exports map[string]Export
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{exports: map[string]Export{}}
+}
+
+func (s *MemoryStore) Save(ctx context.Context, e Export) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exports[e.ID] = e
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (Export, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.exports[id]
+	if !ok {
+		return Export{}, ErrNotFound
+	}
+	return e, nil
+}
+
+func (s *MemoryStore) Expired(ctx context.Context, t time.Time) ([]Export, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expired []Export
+	for _, e := range s.exports {
+		if e.ExpiresAt.Before(t) {
+			expired = append(expired, e)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ID < expired[j].ID })
+	return expired, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.exports, id)
+	return nil
+}
+
+// Files stores export files, e.g. on disk or in object storage.
+type Files interface {
+	// Create returns a writer for a new file name, replacing any existing one
+	Create(ctx context.Context, name string) (io.WriteCloser, error)
+	// Open returns the file name for reading
+	Open(ctx context.Context, name string) (io.ReadSeekCloser, error)
+	// Remove deletes the file name, if it exists
+	Remove(ctx context.Context, name string) error
+}
+
+// DirFiles returns Files kept in dir, which must exist. On several
+// instances it must be shared storage.
+func DirFiles(dir string) Files {
+	return dirFiles(dir)
+}
+
+type dirFiles string
+
+// path returns the path of name, which is always a file in the directory.
+func (d dirFiles) path(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name[0] == '.' {
+		return "", errors.New("export: invalid file name " + name)
+	}
+	return filepath.Join(string(d), name), nil
+}
+
+func (d dirFiles) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	p, err := d.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Create(p)
+}
+
+func (d dirFiles) Open(ctx context.Context, name string) (io.ReadSeekCloser, error) {
+	p, err := d.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (d dirFiles) Remove(ctx context.Context, name string) error {
+	p, err := d.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+package export
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/clock"
+	"github.com/doujins-org/ginapi/response"
+	"github.com/doujins-org/ginapi/tasks"
+)
+
+// TaskName is the task Exporter enqueues; register Run for it.
+const TaskName = "export.run"
+
+// Type is a kind of export a service offers.
+type Type struct {
+	// Columns are the CSV columns, the JSON field names of the rows
+	// (required for CSV)
+	Columns []string
+	// Produce writes the rows of e, usually those owned by e.OwnerID,
+	// filtered by e.Params. It runs in the background, with the request
+	// values of the request that started the export.
+	Produce func(ctx context.Context, e Export, w RowWriter) error
+}
+
+// Config configures an Exporter.
+type Config struct {
+	// Types maps the type names clients request to their producers
+	// (required)
+	Types map[string]Type
+	// Files stores the export files (required)
+	Files Files
+	// Queue runs the exports; register Run for TaskName on it (required)
+	Queue tasks.Queue
+	// Secret signs download URLs (required)
+	Secret []byte
+	// Store keeps the job objects (defaults to NewMemoryStore())
+	Store Store
+	// TTL is how long exports are kept, from their creation and again from
+	// their completion (defaults to 24h)
+	TTL time.Duration
+	// URLTTL is how long download URLs work (defaults to 1h)
+	URLTTL time.Duration
+	// MaxAttempts at producing an export before it fails (defaults to 3)
+	MaxAttempts int
+	// Clock defaults to clock.System
+	Clock clock.Clock
+}
+
+// Exporter runs exports and serves their endpoints; see the package
+// documentation.
+type Exporter struct {
+	cfg Config
+}
+
+// NewExporter returns an Exporter for cfg. It panics if a required field
+// is missing or a CSV type has no columns.
+func NewExporter(cfg Config) *Exporter {
+	if len(cfg.Types) == 0 || cfg.Files == nil || cfg.Queue == nil || len(cfg.Secret) == 0 {
+		panic("export: Config requires Types, Files, Queue, and Secret")
+	}
+	for name, t := range cfg.Types {
+		if t.Produce == nil {
+			panic("export: type " + name + " has no Produce")
+		}
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.URLTTL <= 0 {
+		cfg.URLTTL = time.Hour
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	cfg.Clock = clock.Or(cfg.Clock)
+	return &Exporter{cfg: cfg}
+}
+
+// Register adds the endpoints to r, usually a group at /exports behind
+// auth.Require: POST to start an export, GET /:id to poll it, and GET
+// /:id/download for the file, which needs no credentials but a signed URL.
+func (x *Exporter) Register(r gin.IRoutes) {
+	r.POST("", x.Create)
+	r.GET("/:id", x.Get)
+	r.GET("/:id/download", x.Download)
+}
+
+// createRequest is the body of POST /exports.
+type createRequest struct {
+	Type   string            `json:"type"`
+	Format Format            `json:"format"`
+	Params map[string]string `json:"params"`
+}
+
+// Create starts an export of the type and format (csv, the default, or
+// ndjson) in the JSON body, answering 202 with the pending export.
+func (x *Exporter) Create(c *gin.Context) {
+	p, ok := auth.GetPrincipal(c)
+	if !ok {
+		response.Unauthorized(c)
+		return
+	}
+	var req createRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestWithCode(c, response.ErrorCodeInvalidFormat, "request body must be a JSON object")
+		return
+	}
+	t, ok := x.cfg.Types[req.Type]
+	if !ok {
+		response.ErrorWithInfo(c, http.StatusBadRequest, response.ErrorInfo{
+			Type:    response.ErrorTypeInvalidRequest,
+			Code:    response.ErrorCodeInvalidParam,
+			Message: fmt.Sprintf("unknown export type %q", req.Type),
+			Param:   "type",
+		})
+		return
+	}
+	if req.Format == "" {
+		req.Format = FormatCSV
+	}
+	if (req.Format != FormatCSV && req.Format != FormatNDJSON) || (req.Format == FormatCSV && len(t.Columns) == 0) {
+		response.ErrorWithInfo(c, http.StatusBadRequest, response.ErrorInfo{
+			Type:    response.ErrorTypeInvalidRequest,
+			Code:    response.ErrorCodeInvalidParam,
+			Message: fmt.Sprintf("format %q is not supported for %s exports", req.Format, req.Type),
+			Param:   "format",
+		})
+		return
+	}
+
+	now := x.cfg.Clock.Now().UTC().Truncate(time.Second)
+	e := Export{
+		Object:    "export",
+		ID:        newID(),
+		Type:      req.Type,
+		Format:    req.Format,
+		Params:    req.Params,
+		Status:    StatusPending,
+		Created:   now,
+		ExpiresAt: now.Add(x.cfg.TTL),
+		OwnerID:   p.ID,
+	}
+	ctx := c.Request.Context()
+	if err := x.cfg.Store.Save(ctx, e); err != nil {
+		response.InternalError(c, "failed to save export: "+err.Error())
+		return
+	}
+	if err := x.cfg.Queue.Enqueue(c, TaskName, e.ID, tasks.Options{MaxAttempts: x.cfg.MaxAttempts}); err != nil {
+		_ = x.cfg.Store.Delete(ctx, e.ID)
+		if errors.Is(err, tasks.ErrQueueFull) {
+			response.ServiceUnavailable(c, "too many exports in progress; try again later")
+			return
+		}
+		response.InternalError(c, "failed to enqueue export: "+err.Error())
+		return
+	}
+	response.Accepted(c, e)
+}
+
+// Get returns the caller's export, with a fresh download URL once it
+// succeeded. Other principals' exports are 404s.
+func (x *Exporter) Get(c *gin.Context) {
+	e, ok := x.owned(c)
+	if !ok {
+		return
+	}
+	if e.Status == StatusSucceeded {
+		e.DownloadURL = x.downloadURL(c.Request.URL.Path+"/download", e.ID)
+	}
+	response.Object(c, e)
+}
+
+// owned loads the export in the id parameter and checks the caller owns
+// it, sending the error otherwise.
+func (x *Exporter) owned(c *gin.Context) (Export, bool) {
+	p, ok := auth.GetPrincipal(c)
+	if !ok {
+		response.Unauthorized(c)
+		return Export{}, false
+	}
+	e, err := x.cfg.Store.Get(c.Request.Context(), c.Param("id"))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		response.InternalError(c, "failed to load export: "+err.Error())
+		return Export{}, false
+	}
+	exists := err == nil && !x.expired(e)
+	if !response.DenyOrHide(c, exists && e.OwnerID == p.ID, exists) {
+		return Export{}, false
+	}
+	return e, true
+}
+
+// expired reports whether e is past its expiry, though not yet cleaned up.
+func (x *Exporter) expired(e Export) bool {
+	return !x.cfg.Clock.Now().Before(e.ExpiresAt)
+}
+
+// Download sends the file of a succeeded export to a request with a valid
+// signed URL. Expired URLs are 403s, so clients know to fetch a new one.
+func (x *Exporter) Download(c *gin.Context) {
+	id := c.Param("id")
+	if !x.verify(id, c.Query("expires"), c.Query("sig")) {
+		response.ForbiddenWithMessage(c, "download URL is invalid or expired; get a new one from the export")
+		return
+	}
+	ctx := c.Request.Context()
+	e, err := x.cfg.Store.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) || (err == nil && (e.Status != StatusSucceeded || x.expired(e))) {
+		response.NotFound(c, "export")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "failed to load export: "+err.Error())
+		return
+	}
+	f, err := x.cfg.Files.Open(ctx, e.fileName())
+	if err != nil {
+		response.InternalError(c, "failed to open export file: "+err.Error())
+		return
+	}
+	defer f.Close()
+
+	name := e.Type + "-" + e.Created.Format("20060102") + "." + string(e.Format)
+	contentType := "text/csv; charset=utf-8"
+	if e.Format == FormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	response.Content(c, f, response.ContentInfo{Name: name, Type: contentType, ModTime: *e.Completed})
+}
+
+// downloadURL returns the signed URL of the download endpoint at path.
+func (x *Exporter) downloadURL(path, id string) string {
+	expires := strconv.FormatInt(x.cfg.Clock.Now().Add(x.cfg.URLTTL).Unix(), 10)
+	q := url.Values{"expires": {expires}, "sig": {x.signature(id, expires)}}
+	return path + "?" + q.Encode()
+}
+
+// signature signs an export ID and URL expiry.
+func (x *Exporter) signature(id, expires string) string {
+	mac := hmac.New(sha256.New, x.cfg.Secret)
+	mac.Write([]byte(id + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the signature and expiry of a download URL.
+func (x *Exporter) verify(id, expires, sig string) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || x.cfg.Clock.Now().Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(x.signature(id, expires)))
+}
+
+// Run is the tasks.Handler producing an export; its payload is the export
+// ID. Errors are returned for the queue to retry, and the export fails
+// after MaxAttempts.
+func (x *Exporter) Run(ctx context.Context, task tasks.Task) error {
+	id, _ := task.Payload.(string)
+	e, err := x.cfg.Store.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil // cleaned up before it ran
+	}
+	if err != nil {
+		return err
+	}
+	t, ok := x.cfg.Types[e.Type]
+	if !ok {
+		return x.fail(ctx, e, fmt.Errorf("export: unknown type %s", e.Type))
+	}
+
+	e.Status = StatusRunning
+	if err := x.cfg.Store.Save(ctx, e); err != nil {
+		return err
+	}
+	rows, err := x.write(ctx, e, t)
+	if err != nil {
+		_ = x.cfg.Files.Remove(ctx, e.fileName())
+		if task.Attempt >= x.cfg.MaxAttempts {
+			return x.fail(ctx, e, err)
+		}
+		return err
+	}
+
+	now := x.cfg.Clock.Now().UTC().Truncate(time.Second)
+	e.Status = StatusSucceeded
+	e.Rows = rows
+	e.Completed = &now
+	e.ExpiresAt = now.Add(x.cfg.TTL)
+	return x.cfg.Store.Save(ctx, e)
+}
+
+// write produces the rows of e into its file.
+func (x *Exporter) write(ctx context.Context, e Export, t Type) (int64, error) {
+	f, err := x.cfg.Files.Create(ctx, e.fileName())
+	if err != nil {
+		return 0, err
+	}
+	var w interface {
+		RowWriter
+		io.Closer
+	}
+	if e.Format == FormatNDJSON {
+		w = NewNDJSONWriter(f)
+	} else {
+		w = NewCSVWriter(f, t.Columns)
+	}
+	err = t.Produce(ctx, e, w)
+	if err == nil {
+		err = w.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return w.Rows(), err
+}
+
+// fail marks e failed, reporting err, which clients don't see.
+func (x *Exporter) fail(ctx context.Context, e Export, err error) error {
+	response.ReportError(ctx, nil, "task "+TaskName, err)
+	now := x.cfg.Clock.Now().UTC().Truncate(time.Second)
+	e.Status = StatusFailed
+	e.Error = "export failed"
+	e.Completed = &now
+	return x.cfg.Store.Save(ctx, e)
+}
+
+// Cleanup deletes the exports past their expiry and their files, returning
+// how many it deleted. Run it periodically, e.g. hourly.
+func (x *Exporter) Cleanup(ctx context.Context) (int, error) {
+	expired, err := x.cfg.Store.Expired(ctx, x.cfg.Clock.Now())
+	if err != nil {
+		return 0, err
+	}
+	var errs []error
+	n := 0
+	for _, e := range expired {
+		if err := x.cfg.Files.Remove(ctx, e.fileName()); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := x.cfg.Store.Delete(ctx, e.ID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		n++
+	}
+	return n, errors.Join(errs...)
+}
@@ -0,0 +1,186 @@
+package export_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/apitest"
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/export"
+	"github.com/doujins-org/ginapi/tasks"
+)
+
+// exportServer is an exporter mounted on a router, with its worker.
+type exportServer struct {
+	router   *gin.Engine
+	exporter *export.Exporter
+	worker   *tasks.Worker
+	clock    *apitest.Clock
+}
+
+func newExportServer(t *testing.T, produce func(ctx context.Context, e export.Export, w export.RowWriter) error) *exportServer {
+	t.Helper()
+	s := &exportServer{clock: apitest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))}
+	s.worker = tasks.NewWorker(tasks.WorkerConfig{
+		Backoff: func(int) time.Duration { return time.Millisecond },
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	s.exporter = export.NewExporter(export.Config{
+		Types: map[string]export.Type{
+			"galleries": {Columns: []string{"id", "title"}, Produce: produce},
+		},
+		Files:  export.DirFiles(t.TempDir()),
+		Queue:  s.worker,
+		Secret: []byte("secret"),
+		Clock:  s.clock,
+	})
+	s.worker.Handle(export.TaskName, s.exporter.Run)
+
+	s.router = gin.New()
+	s.router.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-User"); id != "" {
+			auth.SetPrincipal(c, auth.Principal{ID: id, Type: auth.TypeUser})
+		}
+		c.Next()
+	})
+	s.exporter.Register(s.router.Group("/exports"))
+	return s
+}
+
+func (s *exportServer) do(method, path, user, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// wait runs the queued exports.
+func (s *exportServer) wait(t *testing.T) {
+	t.Helper()
+	if err := s.worker.Shutdown(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func decodeExport(t *testing.T, w *httptest.ResponseRecorder) export.Export {
+	t.Helper()
+	var e export.Export
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatalf("expected an export, got %s", w.Body)
+	}
+	return e
+}
+
+func TestExporter(t *testing.T) {
+	s := newExportServer(t, func(ctx context.Context, e export.Export, w export.RowWriter) error {
+		if e.OwnerID != "usr_1" || e.Params["tag"] != "beach" {
+			return errors.New("unexpected export")
+		}
+		_ = w.Write(map[string]any{"id": "gal_1", "title": "Summer"})
+		return w.Write(map[string]any{"id": "gal_2", "title": "Winter"})
+	})
+
+	w := s.do("POST", "/exports", "usr_1", `{"type":"galleries","params":{"tag":"beach"}}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body)
+	}
+	created := decodeExport(t, w)
+	if created.Object != "export" || created.Status != export.StatusPending || created.Format != export.FormatCSV {
+		t.Errorf("unexpected export %+v", created)
+	}
+	s.wait(t)
+
+	if w := s.do("GET", "/exports/"+created.ID, "usr_2", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected another user to get 404, got %d", w.Code)
+	}
+	w = s.do("GET", "/exports/"+created.ID, "usr_1", "")
+	e := decodeExport(t, w)
+	if e.Status != export.StatusSucceeded || e.Rows != 2 || e.DownloadURL == "" {
+		t.Fatalf("expected a succeeded export with a download URL, got %s", w.Body)
+	}
+
+	w = s.do("GET", e.DownloadURL, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	if want := "id,title\ngal_1,Summer\ngal_2,Winter\n"; w.Body.String() != want {
+		t.Errorf("expected %q, got %q", want, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="galleries-20240101.csv"` {
+		t.Errorf("unexpected Content-Disposition %q", got)
+	}
+
+	if w := s.do("GET", strings.Replace(e.DownloadURL, "sig=", "sig=x", 1), "", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected a bad signature to get 403, got %d", w.Code)
+	}
+	s.clock.Advance(2 * time.Hour)
+	if w := s.do("GET", e.DownloadURL, "", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected an expired URL to get 403, got %d", w.Code)
+	}
+
+	s.clock.Advance(24 * time.Hour)
+	if n, err := s.exporter.Cleanup(context.Background()); n != 1 || err != nil {
+		t.Errorf("expected 1 export cleaned up, got %d, %v", n, err)
+	}
+	if w := s.do("GET", "/exports/"+created.ID, "usr_1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected a cleaned up export to get 404, got %d", w.Code)
+	}
+}
+
+func TestExporterFailure(t *testing.T) {
+	var attempts atomic.Int32
+	s := newExportServer(t, func(ctx context.Context, e export.Export, w export.RowWriter) error {
+		attempts.Add(1)
+		return errors.New("database down")
+	})
+
+	created := decodeExport(t, s.do("POST", "/exports", "usr_1", `{"type":"galleries","format":"ndjson"}`))
+	s.wait(t)
+
+	e := decodeExport(t, s.do("GET", "/exports/"+created.ID, "usr_1", ""))
+	if e.Status != export.StatusFailed || e.Error != "export failed" || e.DownloadURL != "" {
+		t.Errorf("expected a failed export, got %+v", e)
+	}
+	if attempts.Load() != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts.Load())
+	}
+}
+
+func TestExporterCreateErrors(t *testing.T) {
+	s := newExportServer(t, func(ctx context.Context, e export.Export, w export.RowWriter) error { return nil })
+	defer s.wait(t)
+
+	tests := []struct {
+		name       string
+		user       string
+		body       string
+		wantStatus int
+	}{
+		{"anonymous", "", `{"type":"galleries"}`, http.StatusUnauthorized},
+		{"malformed", "usr_1", `[`, http.StatusBadRequest},
+		{"unknown type", "usr_1", `{"type":"tags"}`, http.StatusBadRequest},
+		{"unknown format", "usr_1", `{"type":"galleries","format":"xlsx"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := s.do("POST", "/exports", tt.user, tt.body); w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// RowWriter receives the rows of an export.
+type RowWriter interface {
+	// Write adds a row: a struct, marshaled like a response object, or a
+	// map[string]any
+	Write(row any) error
+	// Rows returns the number of rows written
+	Rows() int64
+}
+
+// CSVWriter writes rows as CSV, one column per field named in columns.
+type CSVWriter struct {
+	w       *csv.Writer
+	columns []string
+	header  bool
+	rows    int64
+}
+
+// NewCSVWriter returns a CSVWriter writing to w. The header row is
+// columns, the JSON field names of the rows. Strings starting with =, +,
+// -, or @ are prefixed with ' so spreadsheets don't run them as formulas.
+func NewCSVWriter(w io.Writer, columns []string) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w), columns: columns}
+}
+
+func (w *CSVWriter) Write(row any) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	fields, err := rowFields(row)
+	if err != nil {
+		return err
+	}
+	record := make([]string, len(w.columns))
+	for i, col := range w.columns {
+		record[i] = csvValue(fields[col])
+	}
+	if err := w.w.Write(record); err != nil {
+		return err
+	}
+	w.rows++
+	return nil
+}
+
+func (w *CSVWriter) Rows() int64 {
+	return w.rows
+}
+
+// Close writes the header if no row was written, and flushes.
+func (w *CSVWriter) Close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *CSVWriter) writeHeader() error {
+	if w.header {
+		return nil
+	}
+	w.header = true
+	return w.w.Write(w.columns)
+}
+
+// rowFields returns the fields of row by JSON name.
+func rowFields(row any) (map[string]any, error) {
+	if m, ok := row.(map[string]any); ok {
+		return m, nil
+	}
+	b, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// csvValue formats a field value as a CSV cell.
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		if v != "" && (v[0] == '=' || v[0] == '+' || v[0] == '-' || v[0] == '@' || v[0] == '\t' || v[0] == '\r') {
+			return "'" + v
+		}
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// NDJSONWriter writes rows as newline-delimited JSON.
+type NDJSONWriter struct {
+	buf  *bufio.Writer
+	enc  *json.Encoder
+	rows int64
+}
+
+// NewNDJSONWriter returns an NDJSONWriter writing to w.
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	return &NDJSONWriter{buf: buf, enc: enc}
+}
+
+func (w *NDJSONWriter) Write(row any) error {
+	if err := w.enc.Encode(row); err != nil {
+		return err
+	}
+	w.rows++
+	return nil
+}
+
+func (w *NDJSONWriter) Rows() int64 {
+	return w.rows
+}
+
+// Close flushes the buffered rows.
+func (w *NDJSONWriter) Close() error {
+	return w.buf.Flush()
+}
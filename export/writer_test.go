@@ -0,0 +1,79 @@
+package export_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/doujins-org/ginapi/export"
+)
+
+type row struct {
+	ID    string   `json:"id"`
+	Title string   `json:"title"`
+	Views int64    `json:"views"`
+	Tags  []string `json:"tags"`
+}
+
+func TestCSVWriter(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []any
+		want string
+	}{
+		{name: "header only", want: "id,title,views,tags\n"},
+		{
+			name: "structs",
+			rows: []any{row{ID: "gal_1", Title: "Summer, 2024", Views: 12, Tags: []string{"a"}}},
+			want: "id,title,views,tags\ngal_1,\"Summer, 2024\",12,\"[\"\"a\"\"]\"\n",
+		},
+		{
+			name: "maps and missing fields",
+			rows: []any{map[string]any{"id": "gal_2", "views": 1.5}},
+			want: "id,title,views,tags\ngal_2,,1.5,\n",
+		},
+		{
+			name: "formulas escaped",
+			rows: []any{row{ID: "gal_3", Title: "=HYPERLINK(\"x\")", Views: -1}},
+			want: "id,title,views,tags\ngal_3,\"'=HYPERLINK(\"\"x\"\")\",-1,\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := export.NewCSVWriter(&buf, []string{"id", "title", "views", "tags"})
+			for _, r := range tt.rows {
+				if err := w.Write(r); err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, buf.String())
+			}
+			if w.Rows() != int64(len(tt.rows)) {
+				t.Errorf("expected %d rows, got %d", len(tt.rows), w.Rows())
+			}
+		})
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := export.NewNDJSONWriter(&buf)
+	_ = w.Write(row{ID: "gal_1", Title: "<b>"})
+	_ = w.Write(map[string]any{"id": "gal_2"})
+	if err := w.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := "{\"id\":\"gal_1\",\"title\":\"<b>\",\"views\":0,\"tags\":null}\n{\"id\":\"gal_2\"}\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+	if w.Rows() != 2 {
+		t.Errorf("expected 2 rows, got %d", w.Rows())
+	}
+}
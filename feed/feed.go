@@ -0,0 +1,248 @@
+// Package feed renders RSS 2.0, Atom, and JSON Feed documents and XML
+// sitemaps for sites served under language prefixes ("/ja/galleries"),
+// and serves them with caching. Paths are written once and resolved
+// against a Site, so each language gets its own absolute URLs:
+//
+//	site := feed.Site{BaseURL: "https://example.com", Languages: []string{"en", "ja"}, DefaultLanguage: "en"}
+//	router.GET("/:lang/feeds/galleries.atom", site.FeedHandler(feed.FeedConfig{
+//	    Name:   "new-galleries",
+//	    Format: feed.FormatAtom,
+//	    Load: func(ctx context.Context, lang string) (feed.Feed, error) {
+//	        galleries, err := store.NewestGalleries(ctx, lang, 50)
+//	        ...
+//	        return feed.Feed{Title: i18n.T(lang, "feeds.new_galleries"), Link: "/galleries", Items: items}, nil
+//	    },
+//	}))
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Format is a feed format.
+type Format string
+
+// Formats.
+const (
+	FormatRSS  Format = "rss"
+	FormatAtom Format = "atom"
+	FormatJSON Format = "json"
+)
+
+// contentTypes are the media types of the formats.
+var contentTypes = map[Format]string{
+	FormatRSS:  "application/rss+xml; charset=utf-8",
+	FormatAtom: "application/atom+xml; charset=utf-8",
+	FormatJSON: "application/feed+json; charset=utf-8",
+}
+
+// Site resolves paths to absolute URLs in each language.
+type Site struct {
+	// BaseURL is the scheme and host, e.g. "https://example.com"
+	BaseURL string
+	// Languages are the language prefixes the site is served under; none
+	// for a site without them
+	Languages []string
+	// DefaultLanguage is used for requests without a language prefix
+	DefaultLanguage string
+}
+
+// URL returns the absolute URL of path in lang, e.g. "/galleries" in "ja"
+// is "https://example.com/ja/galleries". Without a lang, the path isn't
+// prefixed. Absolute URLs are returned unchanged.
+func (s Site) URL(lang, path string) string {
+	if strings.Contains(path, "://") {
+		return path
+	}
+	if path == "" || path[0] != '/' {
+		path = "/" + path
+	}
+	base := strings.TrimSuffix(s.BaseURL, "/")
+	if lang == "" {
+		return base + path
+	}
+	return base + "/" + lang + path
+}
+
+// Feed is a feed in one language.
+type Feed struct {
+	Title       string
+	Description string
+	// Language of the feed, e.g. "ja"; set by FeedHandler
+	Language string
+	// Link is the path of the page the feed mirrors, e.g. "/galleries"
+	Link string
+	// Self is the path the feed is served at (defaults to the request's
+	// in FeedHandler)
+	Self string
+	// Updated defaults to the newest item's time
+	Updated time.Time
+	// Author is the name credited for items without their own
+	Author string
+	Items  []Item
+}
+
+// Item is an entry of a feed.
+type Item struct {
+	// ID is a permanent, unique identifier (defaults to the item's URL)
+	ID    string
+	Title string
+	// Link is the path or absolute URL of the item's page
+	Link string
+	// Summary is plain text
+	Summary string
+	// ContentHTML is the item's full content, as HTML
+	ContentHTML string
+	Published   time.Time
+	// Updated defaults to Published
+	Updated    time.Time
+	Author     string
+	Categories []string
+	// Image is the path, without a language prefix, or absolute URL of a
+	// representative image
+	Image string
+}
+
+// updated returns when f last changed.
+func (f Feed) updated() time.Time {
+	t := f.Updated
+	if !t.IsZero() {
+		return t
+	}
+	for _, item := range f.Items {
+		if u := item.updated(); u.After(t) {
+			t = u
+		}
+	}
+	return t
+}
+
+// updated returns when item last changed.
+func (item Item) updated() time.Time {
+	if !item.Updated.IsZero() {
+		return item.Updated
+	}
+	return item.Published
+}
+
+// Render encodes f in format, with its paths resolved in f.Language.
+func (s Site) Render(f Feed, format Format) ([]byte, error) {
+	switch format {
+	case FormatRSS:
+		return s.rss(f)
+	case FormatAtom:
+		return s.atom(f)
+	case FormatJSON:
+		return s.jsonFeed(f)
+	}
+	return nil, fmt.Errorf("feed: unknown format %q", format)
+}
+
+// FeedConfig configures a feed handler.
+type FeedConfig struct {
+	// Name identifies the feed in the cache, e.g. "new-galleries"
+	// (required)
+	Name string
+	// Format of the feed (required)
+	Format Format
+	// Load returns the feed in a language (required)
+	Load func(ctx context.Context, lang string) (Feed, error)
+	// TTL is how long a rendered feed is cached, here and, as its max-age,
+	// by clients and CDNs (defaults to 10m)
+	TTL time.Duration
+	// Cache keeps rendered feeds (defaults to cache.Default())
+	Cache *cache.Cache
+}
+
+// rendered is a cached feed or sitemap.
+type rendered struct {
+	Body    []byte    `json:"body"`
+	Updated time.Time `json:"updated"`
+}
+
+// FeedHandler returns a handler serving the feed in the language of the
+// request's path prefix, or DefaultLanguage without a supported one (a
+// 404 if there is none). Rendered feeds are cached for TTL per language,
+// and sent with a public Cache-Control and Last-Modified, answering 304
+// when unchanged. It panics if the config is incomplete.
+func (s Site) FeedHandler(cfg FeedConfig) gin.HandlerFunc {
+	if cfg.Name == "" || cfg.Load == nil || contentTypes[cfg.Format] == "" {
+		panic("feed: FeedConfig requires a Name, a Load function, and a known Format")
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 10 * time.Minute
+	}
+
+	return func(c *gin.Context) {
+		lang, ok := s.language(c)
+		if !ok {
+			response.NotFoundWithMessage(c, "feed not found")
+			return
+		}
+		key := "feed:" + cfg.Name + ":" + string(cfg.Format) + ":" + lang
+		self := s.URL("", c.Request.URL.Path)
+		r, err := cache.DoWith(c.Request.Context(), cacheOr(cfg.Cache), key, cfg.TTL, func(ctx context.Context) (rendered, error) {
+			f, err := cfg.Load(ctx, lang)
+			if err != nil {
+				return rendered{}, err
+			}
+			if f.Language == "" {
+				f.Language = lang
+			}
+			if f.Self == "" {
+				f.Self = self
+			}
+			body, err := s.Render(f, cfg.Format)
+			return rendered{Body: body, Updated: f.updated()}, err
+		})
+		if err != nil {
+			response.InternalError(c, "failed to build feed: "+err.Error())
+			return
+		}
+		serve(c, contentTypes[cfg.Format], r, cfg.TTL)
+	}
+}
+
+// language returns the language of the request's path prefix.
+func (s Site) language(c *gin.Context) (string, bool) {
+	if len(s.Languages) == 0 {
+		return "", true
+	}
+	if lang := middleware.ExtractLanguageFromPath(c.Request.URL.Path); slices.Contains(s.Languages, lang) {
+		return lang, true
+	}
+	return s.DefaultLanguage, s.DefaultLanguage != ""
+}
+
+// serve writes r with caching headers, or 304 if the client has it.
+func serve(c *gin.Context, contentType string, r rendered, ttl time.Duration) {
+	h := c.Writer.Header()
+	response.CachePolicy{Public: true, MaxAge: ttl}.Apply(h)
+	if !r.Updated.IsZero() {
+		h.Set("Last-Modified", r.Updated.UTC().Format(http.TimeFormat))
+		if !response.ModifiedSince(c, r.Updated) {
+			response.NotModified(c)
+			return
+		}
+	}
+	c.Data(http.StatusOK, contentType, r.Body)
+}
+
+// cacheOr returns c, or the default cache if c is nil.
+func cacheOr(c *cache.Cache) *cache.Cache {
+	if c == nil {
+		return cache.Default()
+	}
+	return c
+}
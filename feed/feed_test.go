@@ -0,0 +1,198 @@
+package feed_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/feed"
+)
+
+var site = feed.Site{BaseURL: "https://example.com/", Languages: []string{"en", "ja"}, DefaultLanguage: "en"}
+
+func TestSiteURL(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		path string
+		want string
+	}{
+		{"language prefix", "ja", "/galleries", "https://example.com/ja/galleries"},
+		{"no language", "", "/galleries", "https://example.com/galleries"},
+		{"relative path", "en", "galleries", "https://example.com/en/galleries"},
+		{"absolute URL", "ja", "https://cdn.example.com/a.png", "https://cdn.example.com/a.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := site.URL(tt.lang, tt.path); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func testFeed() feed.Feed {
+	published := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return feed.Feed{
+		Title:    "New galleries",
+		Language: "ja",
+		Link:     "/galleries",
+		Self:     "/feeds/galleries",
+		Items: []feed.Item{{
+			Title:       "Gallery <1>",
+			Link:        "/galleries/1",
+			Summary:     "A gallery",
+			ContentHTML: "<p>A gallery</p>",
+			Published:   published,
+			Categories:  []string{"art"},
+			Image:       "/covers/1.webp",
+		}},
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		format feed.Format
+		want   []string
+	}{
+		{feed.FormatRSS, []string{
+			`<rss version="2.0"`,
+			`<link>https://example.com/ja/galleries</link>`,
+			`<atom:link href="https://example.com/ja/feeds/galleries" rel="self" type="application/rss+xml">`,
+			`<guid isPermaLink="true">https://example.com/ja/galleries/1</guid>`,
+			`<pubDate>Sun, 01 Mar 2026 12:00:00 +0000</pubDate>`,
+			`<enclosure url="https://example.com/covers/1.webp" type="image/webp" length="0">`,
+		}},
+		{feed.FormatAtom, []string{
+			`<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="ja">`,
+			`<id>https://example.com/ja/feeds/galleries</id>`,
+			`<updated>2026-03-01T12:00:00Z</updated>`,
+			`<title>Gallery &lt;1&gt;</title>`,
+			`<content type="html">&lt;p&gt;A gallery&lt;/p&gt;</content>`,
+			`<category term="art">`,
+		}},
+		{feed.FormatJSON, []string{
+			`"version":"https://jsonfeed.org/version/1.1"`,
+			`"feed_url":"https://example.com/ja/feeds/galleries"`,
+			`"id":"https://example.com/ja/galleries/1"`,
+			`"content_html":"<p>A gallery</p>"`,
+			`"image":"https://example.com/covers/1.webp"`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			body, err := site.Render(testFeed(), tt.format)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(body), want) {
+					t.Errorf("expected output to contain %s, got %s", want, body)
+				}
+			}
+		})
+	}
+
+	if _, err := site.Render(testFeed(), "yaml"); err == nil {
+		t.Errorf("expected an error for an unknown format, got nil")
+	}
+}
+
+func TestFeedHandler(t *testing.T) {
+	var loads []string
+	router := gin.New()
+	handler := site.FeedHandler(feed.FeedConfig{
+		Name:   "galleries",
+		Format: feed.FormatJSON,
+		Cache:  cache.New(cache.Config{}),
+		Load: func(ctx context.Context, lang string) (feed.Feed, error) {
+			loads = append(loads, lang)
+			f := testFeed()
+			f.Language, f.Self = "", ""
+			return f, nil
+		},
+	})
+	router.GET("/:lang/feeds/galleries", handler)
+	router.GET("/feeds/galleries", handler)
+
+	tests := []struct {
+		name     string
+		path     string
+		header   string
+		wantCode int
+		wantLang string
+		wantSelf string
+	}{
+		{"language prefix", "/ja/feeds/galleries", "", http.StatusOK, "ja", "https://example.com/ja/feeds/galleries"},
+		{"default language", "/feeds/galleries", "", http.StatusOK, "en", "https://example.com/feeds/galleries"},
+		{"unsupported language", "/fr/feeds/galleries", "", http.StatusOK, "en", "https://example.com/feeds/galleries"},
+		{"not modified", "/ja/feeds/galleries", "Sun, 01 Mar 2026 12:00:00 GMT", http.StatusNotModified, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("If-Modified-Since", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "public, max-age=600" {
+				t.Errorf("expected public Cache-Control, got %q", got)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/feed+json; charset=utf-8" {
+				t.Errorf("expected a JSON Feed content type, got %q", got)
+			}
+			var body struct {
+				Language string `json:"language"`
+				FeedURL  string `json:"feed_url"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode feed: %v", err)
+			}
+			if body.Language != tt.wantLang || body.FeedURL != tt.wantSelf {
+				t.Errorf("expected language %q at %q, got %q at %q", tt.wantLang, tt.wantSelf, body.Language, body.FeedURL)
+			}
+		})
+	}
+
+	if strings.Join(loads, ",") != "ja,en" {
+		t.Errorf("expected one load per language, got %q", loads)
+	}
+}
+
+func TestFeedHandlerWithoutLanguage(t *testing.T) {
+	s := feed.Site{BaseURL: "https://example.com", Languages: []string{"en"}}
+	router := gin.New()
+	router.GET("/*path", s.FeedHandler(feed.FeedConfig{
+		Name:   "galleries",
+		Format: feed.FormatRSS,
+		Cache:  cache.New(cache.Config{}),
+		Load: func(ctx context.Context, lang string) (feed.Feed, error) {
+			return testFeed(), nil
+		},
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fr/feeds/galleries", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,305 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"path"
+	"strings"
+	"time"
+)
+
+// rssDoc is an RSS 2.0 document.
+type rssDoc struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Self          *atomLink `xml:"atom:link,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title,omitempty"`
+	Link        string        `xml:"link,omitempty"`
+	GUID        rssGUID       `xml:"guid"`
+	Description string        `xml:"description,omitempty"`
+	PubDate     string        `xml:"pubDate,omitempty"`
+	Categories  []string      `xml:"category"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int    `xml:"length,attr"`
+}
+
+// rss encodes f as RSS 2.0. Items carry their content, or summary, as the
+// description; RSS authors must be email addresses, so they are left out.
+func (s Site) rss(f Feed) ([]byte, error) {
+	ch := rssChannel{
+		Title:       f.Title,
+		Link:        s.URL(f.Language, f.Link),
+		Description: f.Description,
+		Language:    f.Language,
+	}
+	if t := f.updated(); !t.IsZero() {
+		ch.LastBuildDate = t.UTC().Format(time.RFC1123Z)
+	}
+	if f.Self != "" {
+		ch.Self = &atomLink{Href: s.URL(f.Language, f.Self), Rel: "self", Type: "application/rss+xml"}
+	}
+	for _, item := range f.Items {
+		link := s.URL(f.Language, item.Link)
+		ri := rssItem{
+			Title:       item.Title,
+			Link:        link,
+			GUID:        rssGUID{IsPermaLink: item.ID == "", Value: item.ID},
+			Description: item.ContentHTML,
+			Categories:  item.Categories,
+		}
+		if ri.GUID.Value == "" {
+			ri.GUID.Value = link
+		}
+		if ri.Description == "" {
+			ri.Description = item.Summary
+		}
+		if !item.Published.IsZero() {
+			ri.PubDate = item.Published.UTC().Format(time.RFC1123Z)
+		}
+		if item.Image != "" {
+			ri.Enclosure = &rssEnclosure{URL: s.URL("", item.Image), Type: imageType(item.Image)}
+		}
+		ch.Items = append(ch.Items, ri)
+	}
+	return marshalXML(rssDoc{Version: "2.0", AtomNS: "http://www.w3.org/2005/Atom", Channel: ch})
+}
+
+// atomDoc is an Atom feed.
+type atomDoc struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang     string      `xml:"xml:lang,attr,omitempty"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   *atomAuthor `xml:"author,omitempty"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Author     *atomAuthor    `xml:"author,omitempty"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    *atomContent   `xml:"content,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// atom encodes f as Atom. The feed's ID is its self URL, or its page's
+// URL without one.
+func (s Site) atom(f Feed) ([]byte, error) {
+	link := s.URL(f.Language, f.Link)
+	doc := atomDoc{
+		Lang:     f.Language,
+		ID:       link,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Updated:  atomTime(f.updated()),
+		Links:    []atomLink{{Href: link, Rel: "alternate", Type: "text/html"}},
+	}
+	if f.Self != "" {
+		doc.ID = s.URL(f.Language, f.Self)
+		doc.Links = append(doc.Links, atomLink{Href: doc.ID, Rel: "self", Type: "application/atom+xml"})
+	}
+	if f.Author != "" {
+		doc.Author = &atomAuthor{Name: f.Author}
+	}
+	for _, item := range f.Items {
+		itemLink := s.URL(f.Language, item.Link)
+		e := atomEntry{
+			ID:      item.ID,
+			Title:   item.Title,
+			Link:    atomLink{Href: itemLink, Rel: "alternate"},
+			Updated: atomTime(item.updated()),
+			Summary: item.Summary,
+		}
+		if e.ID == "" {
+			e.ID = itemLink
+		}
+		if !item.Published.IsZero() {
+			e.Published = atomTime(item.Published)
+		}
+		if item.Author != "" {
+			e.Author = &atomAuthor{Name: item.Author}
+		}
+		if item.ContentHTML != "" {
+			e.Content = &atomContent{Type: "html", Value: item.ContentHTML}
+		}
+		for _, c := range item.Categories {
+			e.Categories = append(e.Categories, atomCategory{Term: c})
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+	return marshalXML(doc)
+}
+
+// atomTime formats t as an RFC 3339 time; Atom requires one, so a zero t
+// is the Unix epoch.
+func atomTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Unix(0, 0)
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// jsonFeedDoc is a JSON Feed 1.1 document.
+type jsonFeedDoc struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url,omitempty"`
+	FeedURL     string           `json:"feed_url,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Language    string           `json:"language,omitempty"`
+	Authors     []jsonFeedAuthor `json:"authors,omitempty"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url,omitempty"`
+	Title         string           `json:"title,omitempty"`
+	ContentHTML   string           `json:"content_html,omitempty"`
+	ContentText   string           `json:"content_text,omitempty"`
+	Summary       string           `json:"summary,omitempty"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished *time.Time       `json:"date_published,omitempty"`
+	DateModified  *time.Time       `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+// jsonFeed encodes f as JSON Feed 1.1.
+func (s Site) jsonFeed(f Feed) ([]byte, error) {
+	doc := jsonFeedDoc{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: s.URL(f.Language, f.Link),
+		Description: f.Description,
+		Language:    f.Language,
+		Items:       []jsonFeedItem{},
+	}
+	if f.Self != "" {
+		doc.FeedURL = s.URL(f.Language, f.Self)
+	}
+	if f.Author != "" {
+		doc.Authors = []jsonFeedAuthor{{Name: f.Author}}
+	}
+	for _, item := range f.Items {
+		ji := jsonFeedItem{
+			ID:          item.ID,
+			URL:         s.URL(f.Language, item.Link),
+			Title:       item.Title,
+			ContentHTML: item.ContentHTML,
+			Summary:     item.Summary,
+			Tags:        item.Categories,
+		}
+		if ji.ID == "" {
+			ji.ID = ji.URL
+		}
+		if ji.ContentHTML == "" {
+			// JSON Feed items need content; fall back to the summary
+			ji.ContentText = item.Summary
+		}
+		if item.Image != "" {
+			ji.Image = s.URL("", item.Image)
+		}
+		if !item.Published.IsZero() {
+			t := item.Published.UTC()
+			ji.DatePublished = &t
+		}
+		if !item.Updated.IsZero() {
+			t := item.Updated.UTC()
+			ji.DateModified = &t
+		}
+		if item.Author != "" {
+			ji.Authors = []jsonFeedAuthor{{Name: item.Author}}
+		}
+		doc.Items = append(doc.Items, ji)
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// marshalXML encodes v as an indented XML document with a declaration.
+func marshalXML(v any) ([]byte, error) {
+	b, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+// imageTypes are the media types of image extensions.
+var imageTypes = map[string]string{
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".avif": "image/avif",
+	".svg":  "image/svg+xml",
+}
+
+// imageType returns the media type of an image URL by its extension,
+// defaulting to JPEG.
+func imageType(url string) string {
+	if t, ok := imageTypes[strings.ToLower(path.Ext(url))]; ok {
+		return t
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,140 @@
+package feed
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// MaxSitemapURLs is the most URLs a sitemap may list.
+const MaxSitemapURLs = 50000
+
+// Page is a page listed in a sitemap.
+type Page struct {
+	// Path of the page without a language prefix, e.g. "/galleries/123"
+	Path string
+	// LastModified is when the page last changed, if known
+	LastModified time.Time
+	// ChangeFrequency hints how often the page changes, e.g. "daily"
+	ChangeFrequency string
+	// Priority is the page's importance relative to the site's others,
+	// from 0 to 1; 0 leaves it out
+	Priority float64
+}
+
+type sitemapDoc struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	XHTMLNS string       `xml:"xmlns:xhtml,attr,omitempty"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string             `xml:"loc"`
+	LastMod    string             `xml:"lastmod,omitempty"`
+	ChangeFreq string             `xml:"changefreq,omitempty"`
+	Priority   string             `xml:"priority,omitempty"`
+	Alternates []sitemapAlternate `xml:"xhtml:link"`
+}
+
+type sitemapAlternate struct {
+	Rel      string `xml:"rel,attr"`
+	HrefLang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+// Sitemap encodes pages as an XML sitemap. On a site with Languages each
+// page is listed once per language, with hreflang alternates linking its
+// translations and an x-default pointing at the unprefixed path, which
+// redirects to the visitor's language. It fails past MaxSitemapURLs URLs;
+// split larger sites over several sitemaps.
+func (s Site) Sitemap(pages []Page) ([]byte, error) {
+	langs := s.Languages
+	if len(langs) == 0 {
+		langs = []string{""}
+	}
+	if n := len(pages) * len(langs); n > MaxSitemapURLs {
+		return nil, fmt.Errorf("feed: sitemap has %d URLs, more than %d", n, MaxSitemapURLs)
+	}
+
+	var doc sitemapDoc
+	if len(s.Languages) > 0 {
+		doc.XHTMLNS = "http://www.w3.org/1999/xhtml"
+	}
+	for _, p := range pages {
+		var alternates []sitemapAlternate
+		if len(s.Languages) > 0 {
+			for _, lang := range s.Languages {
+				alternates = append(alternates, sitemapAlternate{Rel: "alternate", HrefLang: lang, Href: s.URL(lang, p.Path)})
+			}
+			alternates = append(alternates, sitemapAlternate{Rel: "alternate", HrefLang: "x-default", Href: s.URL("", p.Path)})
+		}
+		for _, lang := range langs {
+			u := sitemapURL{Loc: s.URL(lang, p.Path), ChangeFreq: p.ChangeFrequency, Alternates: alternates}
+			if !p.LastModified.IsZero() {
+				u.LastMod = p.LastModified.UTC().Format(time.RFC3339)
+			}
+			if p.Priority > 0 {
+				u.Priority = strconv.FormatFloat(p.Priority, 'f', 1, 64)
+			}
+			doc.URLs = append(doc.URLs, u)
+		}
+	}
+	return marshalXML(doc)
+}
+
+// SitemapConfig configures a sitemap handler.
+type SitemapConfig struct {
+	// Name identifies the sitemap in the cache (defaults to "sitemap")
+	Name string
+	// Load returns the pages to list (required)
+	Load func(ctx context.Context) ([]Page, error)
+	// TTL is how long the rendered sitemap is cached, here and by clients
+	// (defaults to 1h)
+	TTL time.Duration
+	// Cache keeps the rendered sitemap (defaults to cache.Default())
+	Cache *cache.Cache
+}
+
+// SitemapHandler returns a handler serving the Sitemap of the pages Load
+// returns, cached like FeedHandler's feeds, with Last-Modified from the
+// most recently modified page. It panics without a Load function.
+func (s Site) SitemapHandler(cfg SitemapConfig) gin.HandlerFunc {
+	if cfg.Load == nil {
+		panic("feed: SitemapConfig requires a Load function")
+	}
+	if cfg.Name == "" {
+		cfg.Name = "sitemap"
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Hour
+	}
+
+	return func(c *gin.Context) {
+		r, err := cache.DoWith(c.Request.Context(), cacheOr(cfg.Cache), "feed:"+cfg.Name, cfg.TTL, func(ctx context.Context) (rendered, error) {
+			pages, err := cfg.Load(ctx)
+			if err != nil {
+				return rendered{}, err
+			}
+			body, err := s.Sitemap(pages)
+			var updated time.Time
+			for _, p := range pages {
+				if p.LastModified.After(updated) {
+					updated = p.LastModified
+				}
+			}
+			return rendered{Body: body, Updated: updated}, err
+		})
+		if err != nil {
+			response.InternalError(c, "failed to build sitemap: "+err.Error())
+			return
+		}
+		serve(c, "application/xml; charset=utf-8", r, cfg.TTL)
+	}
+}
@@ -0,0 +1,108 @@
+package feed_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/feed"
+)
+
+func TestSitemap(t *testing.T) {
+	pages := []feed.Page{{
+		Path:            "/galleries/1",
+		LastModified:    time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		ChangeFrequency: "daily",
+		Priority:        0.8,
+	}}
+
+	tests := []struct {
+		name   string
+		site   feed.Site
+		want   []string
+		absent []string
+	}{
+		{
+			"languages",
+			site,
+			[]string{
+				`<loc>https://example.com/en/galleries/1</loc>`,
+				`<loc>https://example.com/ja/galleries/1</loc>`,
+				`<xhtml:link rel="alternate" hreflang="ja" href="https://example.com/ja/galleries/1">`,
+				`<xhtml:link rel="alternate" hreflang="x-default" href="https://example.com/galleries/1">`,
+				`<lastmod>2026-03-01T12:00:00Z</lastmod>`,
+				`<changefreq>daily</changefreq>`,
+				`<priority>0.8</priority>`,
+			},
+			nil,
+		},
+		{
+			"no languages",
+			feed.Site{BaseURL: "https://example.com"},
+			[]string{`<loc>https://example.com/galleries/1</loc>`},
+			[]string{"xhtml", "hreflang"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := tt.site.Sitemap(pages)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(body), want) {
+					t.Errorf("expected sitemap to contain %s, got %s", want, body)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(string(body), absent) {
+					t.Errorf("expected sitemap not to contain %s, got %s", absent, body)
+				}
+			}
+		})
+	}
+}
+
+func TestSitemapLimit(t *testing.T) {
+	pages := make([]feed.Page, feed.MaxSitemapURLs/2+1)
+	if _, err := site.Sitemap(pages); err == nil {
+		t.Errorf("expected an error past %d URLs, got nil", feed.MaxSitemapURLs)
+	}
+}
+
+func TestSitemapHandler(t *testing.T) {
+	loads := 0
+	router := gin.New()
+	router.GET("/sitemap.xml", site.SitemapHandler(feed.SitemapConfig{
+		Cache: cache.New(cache.Config{}),
+		Load: func(ctx context.Context) ([]feed.Page, error) {
+			loads++
+			return []feed.Page{{Path: "/", LastModified: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}}, nil
+		},
+	}))
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if got := w.Header().Get("Last-Modified"); got != "Sun, 01 Mar 2026 12:00:00 GMT" {
+			t.Errorf("expected Last-Modified of the newest page, got %q", got)
+		}
+		if !strings.Contains(w.Body.String(), "<loc>https://example.com/en/</loc>") {
+			t.Errorf("expected the page in the sitemap, got %s", w.Body.String())
+		}
+	}
+	if loads != 1 {
+		t.Errorf("expected the sitemap to be cached, got %d loads", loads)
+	}
+}
@@ -0,0 +1,153 @@
+// Package format renders dates, relative times, and numbers for the
+// request's language, for pre-formatted display fields like display_date.
+//
+//	f := format.Get(c)
+//	gallery.DisplayDate = f.Date(gallery.PublishedAt)
+//	gallery.DisplayViews = f.Number(gallery.Views)
+//
+// Dates are formatted in the time's own location; convert with In first.
+// Unsupported languages fall back to English.
+package format
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+// Formatter formats values for one language.
+type Formatter struct {
+	lang    string
+	locale  *locale
+	printer *message.Printer
+}
+
+// For returns a Formatter for a language code such as "ja" or "pt-BR".
+func For(lang string) Formatter {
+	base := strings.ToLower(lang)
+	if i := strings.IndexAny(base, "-_"); i >= 0 {
+		base = base[:i]
+	}
+	loc, ok := locales[base]
+	if !ok {
+		base, loc = "en", locales["en"]
+	}
+
+	tag, err := language.Parse(lang)
+	if err != nil {
+		tag = language.Make(base)
+	}
+	return Formatter{lang: base, locale: loc, printer: message.NewPrinter(tag)}
+}
+
+// Get returns a Formatter for the language detected by middleware.Language.
+func Get(c *gin.Context) Formatter {
+	return For(middleware.GetLanguage(c))
+}
+
+// FromContext returns a Formatter for the language stored by
+// middleware.WithLanguage (or LanguageHandler).
+func FromContext(ctx context.Context) Formatter {
+	return For(middleware.LanguageFromContext(ctx))
+}
+
+// Language returns the language the Formatter resolved to.
+func (f Formatter) Language() string {
+	return f.lang
+}
+
+// Date formats t as a long date, e.g. "March 5, 2024" or "2024年3月5日".
+func (f Formatter) Date(t time.Time) string {
+	monthName := ""
+	if f.locale.months[0] != "" {
+		monthName = f.locale.months[t.Month()-1]
+	}
+	return fmt.Sprintf(f.locale.longDate, t.Year(), monthName, t.Day(), int(t.Month()))
+}
+
+// ShortDate formats t as a numeric date, e.g. "3/5/2024" or "05.03.2024".
+func (f Formatter) ShortDate(t time.Time) string {
+	return t.Format(f.locale.shortDate)
+}
+
+// Time formats the time of day, e.g. "3:04 PM" or "15:04".
+func (f Formatter) Time(t time.Time) string {
+	return t.Format(f.locale.timeLayout)
+}
+
+// DateTime formats t as a long date followed by the time of day.
+func (f Formatter) DateTime(t time.Time) string {
+	return f.Date(t) + " " + f.Time(t)
+}
+
+// Relative formats t relative to now, e.g. "3 minutes ago", "in 2 days",
+// or "3分前". Differences under 45 seconds are "just now".
+func (f Formatter) Relative(t, now time.Time) string {
+	d := now.Sub(t)
+	pattern := f.locale.past
+	if d < 0 {
+		d, pattern = -d, f.locale.future
+	}
+	if d < 45*time.Second {
+		return f.locale.justNow
+	}
+
+	n, u := relativeUnit(d)
+	form := f.locale.units[u][1]
+	if f.locale.isOne(n) {
+		form = f.locale.units[u][0]
+	}
+	return fmt.Sprintf(pattern, fmt.Sprintf(form, n))
+}
+
+// relativeUnit picks the largest unit that fits d, rounding to nearest.
+func relativeUnit(d time.Duration) (int64, unit) {
+	const (
+		dayDur   = 24 * time.Hour
+		monthDur = 30 * dayDur
+		yearDur  = 365 * dayDur
+	)
+	round := func(unitDur time.Duration) int64 {
+		return int64(math.Round(float64(d) / float64(unitDur)))
+	}
+
+	switch {
+	case d < 45*time.Minute:
+		if d < time.Minute {
+			return round(time.Second), second
+		}
+		return max(round(time.Minute), 1), minute
+	case d < 22*time.Hour:
+		return max(round(time.Hour), 1), hour
+	case d < 26*dayDur:
+		return max(round(dayDur), 1), day
+	case d < 320*dayDur:
+		return max(round(monthDur), 1), month
+	}
+	return max(round(yearDur), 1), year
+}
+
+// Number formats an integer or float with the locale's grouping and decimal
+// separators, e.g. 1234567 as "1,234,567" or "1.234.567".
+func (f Formatter) Number(v any) string {
+	return f.printer.Sprint(number.Decimal(v))
+}
+
+// Decimal formats v with exactly digits fraction digits.
+func (f Formatter) Decimal(v float64, digits int) string {
+	return f.printer.Sprint(number.Decimal(v, number.MinFractionDigits(digits), number.MaxFractionDigits(digits)))
+}
+
+// Percent formats a ratio as a percentage, e.g. 0.25 as "25%".
+func (f Formatter) Percent(v float64) string {
+	return f.printer.Sprint(number.Percent(v))
+}
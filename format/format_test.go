@@ -0,0 +1,112 @@
+package format_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/format"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+var published = time.Date(2024, time.March, 5, 15, 4, 0, 0, time.UTC)
+
+func TestDate(t *testing.T) {
+	tests := []struct {
+		lang      string
+		date      string
+		shortDate string
+		dateTime  string
+	}{
+		{"en", "March 5, 2024", "3/5/2024", "March 5, 2024 3:04 PM"},
+		{"en-GB", "March 5, 2024", "3/5/2024", "March 5, 2024 3:04 PM"},
+		{"ja", "2024年3月5日", "2024/03/05", "2024年3月5日 15:04"},
+		{"ko", "2024년 3월 5일", "2024. 3. 5.", "2024년 3월 5일 15:04"},
+		{"de", "5. März 2024", "05.03.2024", "5. März 2024 15:04"},
+		{"pt-BR", "5 de março de 2024", "05/03/2024", "5 de março de 2024 15:04"},
+		{"xx", "March 5, 2024", "3/5/2024", "March 5, 2024 3:04 PM"},
+	}
+
+	for _, tt := range tests {
+		f := format.For(tt.lang)
+		if got := f.Date(published); got != tt.date {
+			t.Errorf("%s Date: expected %q, got %q", tt.lang, tt.date, got)
+		}
+		if got := f.ShortDate(published); got != tt.shortDate {
+			t.Errorf("%s ShortDate: expected %q, got %q", tt.lang, tt.shortDate, got)
+		}
+		if got := f.DateTime(published); got != tt.dateTime {
+			t.Errorf("%s DateTime: expected %q, got %q", tt.lang, tt.dateTime, got)
+		}
+	}
+}
+
+func TestRelative(t *testing.T) {
+	now := published
+
+	tests := []struct {
+		lang string
+		t    time.Time
+		want string
+	}{
+		{"en", now.Add(-10 * time.Second), "just now"},
+		{"en", now.Add(-time.Minute), "1 minute ago"},
+		{"en", now.Add(-3 * time.Minute), "3 minutes ago"},
+		{"en", now.Add(2 * 24 * time.Hour), "in 2 days"},
+		{"en", now.Add(-400 * 24 * time.Hour), "1 year ago"},
+		{"ja", now.Add(-3 * time.Minute), "3分前"},
+		{"ko", now.Add(5 * time.Hour), "5시간 후"},
+		{"zh", now.Add(-60 * 24 * time.Hour), "2个月前"},
+		{"es", now.Add(-time.Hour), "hace 1 hora"},
+		{"fr", now.Add(-3 * 24 * time.Hour), "il y a 3 jours"},
+		{"de", now.Add(-3 * 24 * time.Hour), "vor 3 Tagen"},
+		{"de", now.Add(24 * time.Hour), "in 1 Tag"},
+	}
+
+	for _, tt := range tests {
+		if got := format.For(tt.lang).Relative(tt.t, now); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.lang, tt.want, got)
+		}
+	}
+}
+
+func TestNumber(t *testing.T) {
+	tests := []struct {
+		lang    string
+		number  string
+		decimal string
+	}{
+		{"en", "1,234,567", "1,234.50"},
+		{"de", "1.234.567", "1.234,50"},
+		{"fr", "1\u00a0234\u00a0567", "1\u00a0234,50"},
+		{"ja", "1,234,567", "1,234.50"},
+	}
+
+	for _, tt := range tests {
+		f := format.For(tt.lang)
+		if got := f.Number(1234567); got != tt.number {
+			t.Errorf("%s Number: expected %q, got %q", tt.lang, tt.number, got)
+		}
+		if got := f.Decimal(1234.5, 2); got != tt.decimal {
+			t.Errorf("%s Decimal: expected %q, got %q", tt.lang, tt.decimal, got)
+		}
+	}
+
+	if got := format.For("en").Percent(0.25); got != "25%" {
+		t.Errorf("expected 25%%, got %q", got)
+	}
+}
+
+func TestGet(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("language", "ja")
+
+	if got := format.Get(c).Language(); got != "ja" {
+		t.Errorf("expected ja, got %s", got)
+	}
+}
@@ -0,0 +1,177 @@
+package format
+
+// unit is a relative-time unit.
+type unit int
+
+const (
+	second unit = iota
+	minute
+	hour
+	day
+	month
+	year
+)
+
+// locale holds the formatting data for one language. Plural forms are
+// "one" and "other", which covers every language here; isOne decides which
+// applies (French treats 0 as singular).
+type locale struct {
+	months     [12]string
+	longDate   string // fmt pattern: %[1]d year, %[2]s month name, %[3]d day, %[4]d month number
+	shortDate  string // time layout
+	timeLayout string // time layout
+	justNow    string
+	past       string // fmt pattern wrapping the quantity, e.g. "%s ago"
+	future     string
+	units      [6][2]string // [unit][one, other], each with a %d verb
+	isOne      func(n int64) bool
+}
+
+func one(n int64) bool       { return n == 1 }
+func zeroOrOne(n int64) bool { return n == 0 || n == 1 }
+func noPlurals(n int64) bool { return false }
+
+var locales = map[string]*locale{
+	"en": {
+		months:     [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		longDate:   "%[2]s %[3]d, %[1]d",
+		shortDate:  "1/2/2006",
+		timeLayout: "3:04 PM",
+		justNow:    "just now",
+		past:       "%s ago",
+		future:     "in %s",
+		units: [6][2]string{
+			{"%d second", "%d seconds"},
+			{"%d minute", "%d minutes"},
+			{"%d hour", "%d hours"},
+			{"%d day", "%d days"},
+			{"%d month", "%d months"},
+			{"%d year", "%d years"},
+		},
+		isOne: one,
+	},
+	"ja": {
+		longDate:   "%[1]d年%[4]d月%[3]d日",
+		shortDate:  "2006/01/02",
+		timeLayout: "15:04",
+		justNow:    "たった今",
+		past:       "%s前",
+		future:     "%s後",
+		units: [6][2]string{
+			{"%d秒", "%d秒"},
+			{"%d分", "%d分"},
+			{"%d時間", "%d時間"},
+			{"%d日", "%d日"},
+			{"%dか月", "%dか月"},
+			{"%d年", "%d年"},
+		},
+		isOne: noPlurals,
+	},
+	"ko": {
+		longDate:   "%[1]d년 %[4]d월 %[3]d일",
+		shortDate:  "2006. 1. 2.",
+		timeLayout: "15:04",
+		justNow:    "방금",
+		past:       "%s 전",
+		future:     "%s 후",
+		units: [6][2]string{
+			{"%d초", "%d초"},
+			{"%d분", "%d분"},
+			{"%d시간", "%d시간"},
+			{"%d일", "%d일"},
+			{"%d개월", "%d개월"},
+			{"%d년", "%d년"},
+		},
+		isOne: noPlurals,
+	},
+	"zh": {
+		longDate:   "%[1]d年%[4]d月%[3]d日",
+		shortDate:  "2006/1/2",
+		timeLayout: "15:04",
+		justNow:    "刚刚",
+		past:       "%s前",
+		future:     "%s后",
+		units: [6][2]string{
+			{"%d秒", "%d秒"},
+			{"%d分钟", "%d分钟"},
+			{"%d小时", "%d小时"},
+			{"%d天", "%d天"},
+			{"%d个月", "%d个月"},
+			{"%d年", "%d年"},
+		},
+		isOne: noPlurals,
+	},
+	"es": {
+		months:     [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		longDate:   "%[3]d de %[2]s de %[1]d",
+		shortDate:  "2/1/2006",
+		timeLayout: "15:04",
+		justNow:    "ahora",
+		past:       "hace %s",
+		future:     "dentro de %s",
+		units: [6][2]string{
+			{"%d segundo", "%d segundos"},
+			{"%d minuto", "%d minutos"},
+			{"%d hora", "%d horas"},
+			{"%d día", "%d días"},
+			{"%d mes", "%d meses"},
+			{"%d año", "%d años"},
+		},
+		isOne: one,
+	},
+	"fr": {
+		months:     [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		longDate:   "%[3]d %[2]s %[1]d",
+		shortDate:  "02/01/2006",
+		timeLayout: "15:04",
+		justNow:    "à l’instant",
+		past:       "il y a %s",
+		future:     "dans %s",
+		units: [6][2]string{
+			{"%d seconde", "%d secondes"},
+			{"%d minute", "%d minutes"},
+			{"%d heure", "%d heures"},
+			{"%d jour", "%d jours"},
+			{"%d mois", "%d mois"},
+			{"%d an", "%d ans"},
+		},
+		isOne: zeroOrOne,
+	},
+	"de": {
+		months:     [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		longDate:   "%[3]d. %[2]s %[1]d",
+		shortDate:  "02.01.2006",
+		timeLayout: "15:04",
+		justNow:    "gerade eben",
+		past:       "vor %s",
+		future:     "in %s",
+		// Both "vor" and "in" take the dative plural
+		units: [6][2]string{
+			{"%d Sekunde", "%d Sekunden"},
+			{"%d Minute", "%d Minuten"},
+			{"%d Stunde", "%d Stunden"},
+			{"%d Tag", "%d Tagen"},
+			{"%d Monat", "%d Monaten"},
+			{"%d Jahr", "%d Jahren"},
+		},
+		isOne: one,
+	},
+	"pt": {
+		months:     [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		longDate:   "%[3]d de %[2]s de %[1]d",
+		shortDate:  "02/01/2006",
+		timeLayout: "15:04",
+		justNow:    "agora",
+		past:       "há %s",
+		future:     "em %s",
+		units: [6][2]string{
+			{"%d segundo", "%d segundos"},
+			{"%d minuto", "%d minutos"},
+			{"%d hora", "%d horas"},
+			{"%d dia", "%d dias"},
+			{"%d mês", "%d meses"},
+			{"%d ano", "%d anos"},
+		},
+		isOne: one,
+	},
+}
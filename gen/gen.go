@@ -0,0 +1,367 @@
+// Package gen generates typed Go clients for ginapi services from the route
+// metadata declared with ginapi.Handle, so service-to-service callers don't
+// hand-write request code. Routes opt in by naming their client method and
+// declaring their body and response types:
+//
+//	ginapi.Handle(api, http.MethodGet, "/galleries/:id", ginapi.RouteMeta{
+//	    Name:     "GetGallery",
+//	    Response: models.Gallery{},
+//	}, getGallery)
+//	ginapi.Handle(api, http.MethodGet, "/galleries", ginapi.RouteMeta{
+//	    Name:     "ListGalleries",
+//	    Response: models.Gallery{},
+//	    List:     true,
+//	}, listGalleries)
+//
+// A small program in the service repo then writes the client:
+//
+//	src, err := gen.Client(gen.Config{Package: "galleryclient", Routes: ginapi.Routes(router)})
+//	...
+//	os.WriteFile("galleryclient/client.go", src, 0o644)
+//
+// The generated methods return *T, *response.List[T], and the errors of
+// response.FromHTTPResponse, so callers match on the same codes the
+// service sends:
+//
+//	client := galleryclient.New("http://galleries.internal")
+//	g, err := client.GetGallery(ctx, "gal_1")
+//	if errors.Is(err, response.ErrorInfo{Code: response.ErrorCodeResourceNotFound}) {
+//	    ...
+//	}
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/doujins-org/ginapi"
+)
+
+// responseImport is the import path of the response package, which the
+// generated code uses for lists and errors.
+const responseImport = "github.com/doujins-org/ginapi/response"
+
+// Config configures client generation.
+type Config struct {
+	// Package is the generated package name (required)
+	Package string
+	// ImportPath is the generated package's import path, so types declared
+	// in it are referenced unqualified. Optional.
+	ImportPath string
+	// Routes are the routes to generate methods for, usually
+	// ginapi.Routes(router). Routes without a Name are skipped.
+	Routes []ginapi.RouteInfo
+}
+
+// Client returns the gofmt-ed source of a client for cfg.Routes. It fails
+// on duplicate method names and on types it cannot reference by name
+// (anonymous structs, generic instantiations).
+func Client(cfg Config) ([]byte, error) {
+	if cfg.Package == "" {
+		return nil, fmt.Errorf("gen: Config.Package is required")
+	}
+
+	g := &generator{
+		cfg:     cfg,
+		imports: map[string]string{responseImport: "response"},
+		names:   map[string]bool{"response": true},
+	}
+	for _, name := range stdImports {
+		g.names[name] = true
+	}
+	var methods bytes.Buffer
+	seen := map[string]string{}
+	for _, r := range cfg.Routes {
+		if r.Name == "" {
+			continue
+		}
+		route := r.Method + " " + r.Path
+		if prev, ok := seen[r.Name]; ok {
+			return nil, fmt.Errorf("gen: %s and %s are both named %s", prev, route, r.Name)
+		}
+		if !token.IsExported(r.Name) {
+			return nil, fmt.Errorf("gen: %s: method name %q is not an exported identifier", route, r.Name)
+		}
+		seen[r.Name] = route
+		if err := g.method(&methods, r); err != nil {
+			return nil, fmt.Errorf("gen: %s: %w", route, err)
+		}
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by ginapi/gen. DO NOT EDIT.\n\npackage %s\n\n", cfg.Package)
+	src.WriteString("import (\n\t\"bytes\"\n\t\"context\"\n\t\"encoding/json\"\n\t\"io\"\n\t\"net/http\"\n\t\"net/url\"\n\t\"strings\"\n\n")
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if name := g.imports[p]; name == path.Base(p) {
+			fmt.Fprintf(&src, "\t%q\n", p)
+		} else {
+			fmt.Fprintf(&src, "\t%s %q\n", name, p)
+		}
+	}
+	src.WriteString(")\n\n")
+	src.WriteString(clientPrelude)
+	src.Write(methods.Bytes())
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gen: formatting generated source: %w", err)
+	}
+	return out, nil
+}
+
+// clientPrelude is the Client type and request plumbing of every generated
+// client.
+const clientPrelude = `// Client calls the service. The zero value is not usable; use New.
+type Client struct {
+	// BaseURL is the service origin, without a trailing slash
+	BaseURL string
+	// HTTPClient sends requests (defaults to http.DefaultClient)
+	HTTPClient *http.Client
+	// Header is added to every request, e.g. for service credentials
+	Header http.Header
+}
+
+// New returns a Client for the service at baseURL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// do sends a request and decodes the response into out. Error responses
+// are returned as *response.HTTPError.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := response.FromHTTPResponse(resp); err != nil {
+		return err
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+`
+
+// generator accumulates the imports the generated methods need.
+type generator struct {
+	cfg     Config
+	imports map[string]string // import path -> package name
+	names   map[string]bool   // package names in use
+}
+
+// stdImports are the package names the generated code always imports.
+var stdImports = []string{"bytes", "context", "json", "io", "http", "url", "strings"}
+
+// reservedParams are the method parameter names path parameters must not
+// shadow.
+var reservedParams = map[string]bool{"c": true, "ctx": true, "query": true, "body": true, "out": true, "err": true}
+
+// method writes the client method for r.
+func (g *generator) method(buf *bytes.Buffer, r ginapi.RouteInfo) error {
+	params := []string{"ctx context.Context"}
+	var pathExpr []string
+	for i, seg := range strings.Split(r.Path, "/") {
+		prefix := ""
+		if i > 0 {
+			prefix = "/"
+		}
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			name := g.paramName(seg[1:])
+			params = append(params, name+" string")
+			pathExpr = append(pathExpr, strconv.Quote(prefix), "url.PathEscape("+name+")")
+		case strings.HasPrefix(seg, "*"):
+			// gin's catch-all value starts with "/"
+			name := g.paramName(seg[1:])
+			params = append(params, name+" string")
+			pathExpr = append(pathExpr, name)
+		default:
+			pathExpr = append(pathExpr, strconv.Quote(prefix+seg))
+		}
+	}
+
+	queryArg := "nil"
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		params = append(params, "query url.Values")
+		queryArg = "query"
+	}
+	bodyArg := "nil"
+	if r.Request != nil {
+		typ, err := g.typeExpr(r.Request)
+		if err != nil {
+			return err
+		}
+		params = append(params, "body "+typ)
+		bodyArg = "body"
+	}
+
+	var result string
+	if r.Response != nil {
+		resp := r.Response
+		if resp.Kind() == reflect.Pointer && !r.List {
+			resp = resp.Elem() // the method returns a pointer anyway
+		}
+		typ, err := g.typeExpr(resp)
+		if err != nil {
+			return err
+		}
+		result = typ
+		if r.List {
+			result = "response.List[" + typ + "]"
+		}
+	}
+
+	fmt.Fprintf(buf, "\n// %s calls %s %s.", r.Name, r.Method, r.Path)
+	if r.Summary != "" {
+		fmt.Fprintf(buf, "\n// %s", r.Summary)
+	}
+	if r.Deprecated {
+		buf.WriteString("\n//\n// Deprecated: the route is scheduled for removal.")
+	}
+	fmt.Fprintf(buf, "\nfunc (c *Client) %s(%s) ", r.Name, strings.Join(params, ", "))
+	call := fmt.Sprintf("c.do(ctx, %q, %s, %s, %s, ", r.Method, joinExpr(pathExpr), queryArg, bodyArg)
+	if result == "" {
+		fmt.Fprintf(buf, "error {\n\treturn %snil)\n}\n", call)
+		return nil
+	}
+	fmt.Fprintf(buf, "(*%s, error) {\n\tvar out %s\n\tif err := %s&out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn &out, nil\n}\n",
+		result, result, call)
+	return nil
+}
+
+// typeExpr returns the Go expression for t, adding the imports it needs.
+func (g *generator) typeExpr(t reflect.Type) (string, error) {
+	if t.Name() != "" {
+		if strings.Contains(t.Name(), "[") {
+			return "", fmt.Errorf("generic type %s is not supported", t)
+		}
+		if t.PkgPath() == "" || t.PkgPath() == g.cfg.ImportPath {
+			return t.Name(), nil
+		}
+		return g.importName(t.PkgPath()) + "." + t.Name(), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		elem, err := g.typeExpr(t.Elem())
+		return "*" + elem, err
+	case reflect.Slice:
+		elem, err := g.typeExpr(t.Elem())
+		return "[]" + elem, err
+	case reflect.Array:
+		elem, err := g.typeExpr(t.Elem())
+		return "[" + strconv.Itoa(t.Len()) + "]" + elem, err
+	case reflect.Map:
+		key, err := g.typeExpr(t.Key())
+		if err != nil {
+			return "", err
+		}
+		elem, err := g.typeExpr(t.Elem())
+		return "map[" + key + "]" + elem, err
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "any", nil
+		}
+	}
+	return "", fmt.Errorf("unnamed type %s is not supported", t)
+}
+
+// importName returns the package name for importPath, picking a unique
+// alias when two imported packages share a name.
+func (g *generator) importName(importPath string) string {
+	if name, ok := g.imports[importPath]; ok {
+		return name
+	}
+	base := strings.NewReplacer("-", "", ".", "").Replace(path.Base(importPath))
+	name := base
+	for i := 2; g.names[name] || token.IsKeyword(name); i++ {
+		name = base + strconv.Itoa(i)
+	}
+	g.imports[importPath] = name
+	g.names[name] = true
+	return name
+}
+
+// paramName turns a path parameter name like "gallery_id" into a Go
+// parameter name like "galleryID", avoiding keywords and package names.
+func (g *generator) paramName(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '-' })
+	var b strings.Builder
+	for i, p := range parts {
+		if i == 0 {
+			b.WriteString(strings.ToLower(p[:1]) + p[1:])
+			continue
+		}
+		if strings.EqualFold(p, "id") || strings.EqualFold(p, "url") {
+			b.WriteString(strings.ToUpper(p))
+		} else {
+			b.WriteString(strings.ToUpper(p[:1]) + p[1:])
+		}
+	}
+	name := b.String()
+	if name == "" || token.IsKeyword(name) || reservedParams[name] || g.names[name] {
+		name += "Param"
+	}
+	return name
+}
+
+// joinExpr concatenates string expressions, merging adjacent literals.
+func joinExpr(exprs []string) string {
+	var merged []string
+	for _, e := range exprs {
+		if n := len(merged); n > 0 && strings.HasPrefix(e, `"`) && strings.HasPrefix(merged[n-1], `"`) {
+			a, _ := strconv.Unquote(merged[n-1])
+			b, _ := strconv.Unquote(e)
+			merged[n-1] = strconv.Quote(a + b)
+			continue
+		}
+		merged = append(merged, e)
+	}
+	if len(merged) == 0 {
+		return `"/"`
+	}
+	return strings.Join(merged, " + ")
+}
@@ -0,0 +1,104 @@
+package gen_test
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/gen"
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+func noop(c *gin.Context) {}
+
+func TestClient(t *testing.T) {
+	router := gin.New()
+	api := router.Group("/gen/api")
+	ginapi.Handle(api, http.MethodGet, "/limits", ginapi.RouteMeta{
+		Name: "ListLimits", Response: pagination.Limits{}, List: true, Summary: "Lists limits.",
+	}, noop)
+	ginapi.Handle(api, http.MethodGet, "/limits/:route_id", ginapi.RouteMeta{
+		Name: "GetLimits", Response: &pagination.Limits{},
+	}, noop)
+	ginapi.Handle(api, http.MethodPut, "/limits/:url", ginapi.RouteMeta{
+		Name: "PutLimits", Request: pagination.Limits{}, Response: map[string][]pagination.Limits{}, Deprecated: true,
+	}, noop)
+	ginapi.Handle(api, http.MethodDelete, "/files/*path", ginapi.RouteMeta{Name: "DeleteFile"}, noop)
+	ginapi.Handle(api, http.MethodGet, "/internal", ginapi.RouteMeta{}, noop)
+
+	src, err := gen.Client(gen.Config{Package: "limitsclient", Routes: ginapi.Routes(router)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code := string(src)
+
+	for _, want := range []string{
+		"// Code generated by ginapi/gen. DO NOT EDIT.",
+		"package limitsclient",
+		`"github.com/doujins-org/ginapi/pagination"`,
+		"// ListLimits calls GET /gen/api/limits.\n// Lists limits.\n",
+		"func (c *Client) ListLimits(ctx context.Context, query url.Values) (*response.List[pagination.Limits], error) {",
+		`c.do(ctx, "GET", "/gen/api/limits", query, nil, &out)`,
+		"func (c *Client) GetLimits(ctx context.Context, routeID string, query url.Values) (*pagination.Limits, error) {",
+		`"/gen/api/limits/"+url.PathEscape(routeID)`,
+		"// Deprecated: the route is scheduled for removal.",
+		"func (c *Client) PutLimits(ctx context.Context, urlParam string, body pagination.Limits) (*map[string][]pagination.Limits, error) {",
+		"func (c *Client) DeleteFile(ctx context.Context, path string) error {",
+		`c.do(ctx, "DELETE", "/gen/api/files"+path, nil, nil, nil)`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected generated code to contain %q, got:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "/gen/api/internal") {
+		t.Error("expected unnamed routes to be skipped")
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    gen.Config
+		errMsg string
+	}{
+		{
+			name:   "missing package",
+			cfg:    gen.Config{},
+			errMsg: "Package is required",
+		},
+		{
+			name: "duplicate name",
+			cfg: gen.Config{Package: "p", Routes: []ginapi.RouteInfo{
+				{Method: "GET", Path: "/a", Name: "Get"},
+				{Method: "GET", Path: "/b", Name: "Get"},
+			}},
+			errMsg: "are both named Get",
+		},
+		{
+			name: "unexported name",
+			cfg: gen.Config{Package: "p", Routes: []ginapi.RouteInfo{
+				{Method: "GET", Path: "/a", Name: "get"},
+			}},
+			errMsg: "not an exported identifier",
+		},
+		{
+			name: "anonymous struct",
+			cfg: gen.Config{Package: "p", Routes: []ginapi.RouteInfo{
+				{Method: "GET", Path: "/a", Name: "Get", Response: reflect.TypeOf(struct{ ID string }{})},
+			}},
+			errMsg: "is not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gen.Client(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
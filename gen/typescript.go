@@ -0,0 +1,299 @@
+package gen
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// TypeScriptConfig configures TypeScript type emission.
+type TypeScriptConfig struct {
+	// Routes contribute their Request and Response types, usually
+	// ginapi.Routes(router)
+	Routes []ginapi.RouteInfo
+	// Types are further values whose types to emit, e.g. models.Gallery{}
+	Types []any
+}
+
+// typeParam stands in for T when reflecting on the generic list types.
+type typeParam struct{}
+
+// builtinTS are the response package types every emitted file declares,
+// with their "object" discriminators as literal types.
+var builtinTS = []struct {
+	name    string
+	typ     reflect.Type
+	generic bool
+	object  string
+}{
+	{"List", reflect.TypeOf(response.List[typeParam]{}), true, "list"},
+	{"SearchAfterList", reflect.TypeOf(response.SearchAfterList[typeParam]{}), true, "list"},
+	{"KeysetList", reflect.TypeOf(response.KeysetList[typeParam]{}), true, "list"},
+	{"ErrorResponse", reflect.TypeOf(response.Error{}), false, "error"},
+	{"ErrorInfo", reflect.TypeOf(response.ErrorInfo{}), false, ""},
+	{"DeletedObject", reflect.TypeOf(response.DeletedObject{}), false, ""},
+	{"SoftDeletedObject", reflect.TypeOf(response.SoftDeletedObject{}), false, ""},
+	{"AbsentObject", reflect.TypeOf(response.AbsentObject{}), false, ""},
+	{"Message", reflect.TypeOf(response.Message{}), false, "message"},
+	{"Warning", reflect.TypeOf(response.Warning{}), false, ""},
+	{"PartialResponse", reflect.TypeOf(response.PartialResponse{}), false, "partial"},
+	{"SectionError", reflect.TypeOf(response.SectionError{}), false, ""},
+}
+
+// TypeScript returns TypeScript declarations for the response envelopes
+// (List<T>, ErrorResponse, DeletedObject, ...) and for the types of
+// cfg.Routes and cfg.Types, so frontend types follow the Go structs:
+//
+//	src, err := gen.TypeScript(gen.TypeScriptConfig{Routes: ginapi.Routes(router)})
+//	...
+//	os.WriteFile("web/src/api/types.ts", src, 0o644)
+//
+// Struct fields follow encoding/json: tag names, omitempty fields are
+// optional, pointers are nullable, embedded structs are flattened, and
+// time.Time and []byte are strings. It fails if two types share a name.
+func TypeScript(cfg TypeScriptConfig) ([]byte, error) {
+	e := &tsEmitter{
+		names:    map[string]reflect.Type{},
+		declared: map[reflect.Type]string{},
+		decls:    map[string]string{},
+	}
+	for _, b := range builtinTS {
+		e.names[b.name] = b.typ
+		e.declared[b.typ] = b.name
+	}
+
+	var roots []reflect.Type
+	for _, r := range cfg.Routes {
+		for _, t := range []reflect.Type{r.Request, r.Response} {
+			if t != nil {
+				roots = append(roots, t)
+			}
+		}
+	}
+	for _, v := range cfg.Types {
+		roots = append(roots, reflect.TypeOf(v))
+	}
+	for _, t := range roots {
+		if _, err := e.typeRef(t); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by ginapi/gen. DO NOT EDIT.\n")
+	for _, b := range builtinTS {
+		name := b.name
+		if b.generic {
+			name += "<T>"
+		}
+		body, err := e.structBody(b.typ, b.object)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&out, "\nexport interface %s %s\n", name, body)
+	}
+
+	names := make([]string, 0, len(e.decls))
+	for name := range e.decls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out.WriteString("\n" + e.decls[name] + "\n")
+	}
+	return out.Bytes(), nil
+}
+
+// tsEmitter collects declarations for named types.
+type tsEmitter struct {
+	names    map[string]reflect.Type // declared name -> type
+	declared map[reflect.Type]string // type -> declared name
+	decls    map[string]string       // name -> declaration
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// typeRef returns the TypeScript type for t, declaring named types.
+func (e *tsEmitter) typeRef(t reflect.Type) (string, error) {
+	switch {
+	case t == reflect.TypeOf(typeParam{}):
+		return "T", nil
+	case t == timeType:
+		return "string", nil
+	case t == rawMessageType:
+		return "unknown", nil
+	}
+	if name, ok := e.declared[t]; ok {
+		return name, nil
+	}
+	if t.Kind() == reflect.Pointer {
+		return e.typeRef(t.Elem())
+	}
+
+	// Types with custom encodings can't be described from their fields
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return e.declare(t, func() (string, error) { return "unknown", nil })
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return e.declare(t, func() (string, error) { return "string", nil })
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return e.declare(t, func() (string, error) { return "boolean", nil })
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return e.declare(t, func() (string, error) { return "number", nil })
+	case reflect.String:
+		return e.declare(t, func() (string, error) { return "string", nil })
+	case reflect.Interface:
+		return e.declare(t, func() (string, error) { return "unknown", nil })
+	case reflect.Slice, reflect.Array:
+		return e.declare(t, func() (string, error) {
+			if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+				return "string", nil // base64
+			}
+			elem, err := e.typeRef(t.Elem())
+			if strings.Contains(elem, " ") {
+				elem = "(" + elem + ")"
+			}
+			return elem + "[]", err
+		})
+	case reflect.Map:
+		return e.declare(t, func() (string, error) {
+			elem, err := e.typeRef(t.Elem())
+			return "Record<string, " + elem + ">", err
+		})
+	case reflect.Struct:
+		return e.declare(t, func() (string, error) { return e.structBody(t, "") })
+	}
+	return "", fmt.Errorf("gen: type %s has no JSON encoding", t)
+}
+
+// declare returns the name of t, declaring it with the type body returns
+// if t is named, or returns the body inline otherwise.
+func (e *tsEmitter) declare(t reflect.Type, body func() (string, error)) (string, error) {
+	name := t.Name()
+	if name == "" || t.PkgPath() == "" {
+		return body()
+	}
+	if strings.Contains(name, "[") {
+		return "", fmt.Errorf("gen: generic type %s is not supported", t)
+	}
+	if prev, ok := e.names[name]; ok && prev != t {
+		return "", fmt.Errorf("gen: %s and %s are both named %s", prev, t, name)
+	}
+	e.names[name] = t
+	e.declared[t] = name
+
+	b, err := body()
+	if err != nil {
+		return "", err
+	}
+	if t.Kind() == reflect.Struct && strings.HasPrefix(b, "{") {
+		e.decls[name] = "export interface " + name + " " + b
+	} else {
+		e.decls[name] = "export type " + name + " = " + b + ";"
+	}
+	return name, nil
+}
+
+// structBody returns the interface body for struct t. object, if set, is
+// the literal type of its "object" field.
+func (e *tsEmitter) structBody(t reflect.Type, object string) (string, error) {
+	var b strings.Builder
+	b.WriteString("{\n")
+	if err := e.fields(&b, t, object); err != nil {
+		return "", err
+	}
+	b.WriteString("}")
+	return b.String(), nil
+}
+
+// fields writes the fields of struct t, flattening embedded structs.
+func (e *tsEmitter) fields(b *strings.Builder, t reflect.Type, object string) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := e.fields(b, ft, object); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		var typ string
+		var err error
+		switch {
+		case name == "object" && object != "":
+			typ = `"` + object + `"`
+		case hasOption(opts, "string"):
+			typ = "string"
+		default:
+			typ, err = e.typeRef(f.Type)
+			if err != nil {
+				return fmt.Errorf("%w (field %s.%s)", err, t, f.Name)
+			}
+		}
+		if f.Type.Kind() == reflect.Pointer {
+			typ += " | null"
+		}
+		optional := ""
+		if hasOption(opts, "omitempty") || hasOption(opts, "omitzero") {
+			optional = "?"
+		}
+		fmt.Fprintf(b, "  %s%s: %s;\n", tsPropertyName(name), optional, typ)
+	}
+	return nil
+}
+
+// tsPropertyName quotes names that aren't valid identifiers.
+func tsPropertyName(name string) string {
+	for i, r := range name {
+		if r == '_' || r == '$' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || (i > 0 && '0' <= r && r <= '9') {
+			continue
+		}
+		return `"` + name + `"`
+	}
+	return name
+}
+
+// hasOption reports whether a json tag's comma-separated options include opt.
+func hasOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package gen_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/gen"
+	"github.com/doujins-org/ginapi/response"
+)
+
+type tsTag struct {
+	Name string `json:"name"`
+}
+
+type tsStatus string
+
+type tsGallery struct {
+	ID       string          `json:"id"`
+	Title    *string         `json:"title,omitempty"`
+	Tags     []tsTag         `json:"tags"`
+	Status   tsStatus        `json:"status"`
+	Created  time.Time       `json:"created_at"`
+	Parent   *tsGallery      `json:"parent"`
+	Views    int64           `json:"views,string"`
+	Facets   response.Facets `json:"facets"`
+	Internal string          `json:"-"`
+	hidden   int
+}
+
+type tsCreateGallery struct {
+	Title string `json:"title"`
+}
+
+func TestTypeScript(t *testing.T) {
+	router := gin.New()
+	ginapi.Handle(router, http.MethodPost, "/gen/ts/galleries", ginapi.RouteMeta{
+		Name: "CreateGallery", Request: tsCreateGallery{}, Response: tsGallery{},
+	}, noop)
+
+	src, err := gen.TypeScript(gen.TypeScriptConfig{Routes: ginapi.Routes(router)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code := string(src)
+
+	for _, want := range []string{
+		"export interface List<T> {\n  object: \"list\";\n  data: T[];\n  total: number;",
+		"  facets?: Facets;\n",
+		"export interface ErrorResponse {\n  object: \"error\";\n  error: ErrorInfo;\n}",
+		"export interface ErrorInfo {\n  type: string;\n  code?: string;\n  message: string;\n  param?: string;\n  pointer?: string;\n  doc_url?: string;\n  details?: Record<string, unknown>;\n}",
+		"export interface SoftDeletedObject {\n  object: string;\n  id: string;\n  deleted: boolean;\n  deleted_at: string;\n}",
+		"export type Facets = Record<string, Facet>;",
+		"export interface tsCreateGallery {\n  title: string;\n}",
+		"export type tsStatus = string;",
+		"export interface tsGallery {\n" +
+			"  id: string;\n" +
+			"  title?: string | null;\n" +
+			"  tags: tsTag[];\n" +
+			"  status: tsStatus;\n" +
+			"  created_at: string;\n" +
+			"  parent: tsGallery | null;\n" +
+			"  views: string;\n" +
+			"  facets: Facets;\n" +
+			"}",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "Internal") || strings.Contains(code, "hidden") {
+		t.Error("expected skipped and unexported fields to be left out")
+	}
+}
+
+func TestTypeScriptNameClash(t *testing.T) {
+	type List struct {
+		ID string `json:"id"`
+	}
+	_, err := gen.TypeScript(gen.TypeScriptConfig{Types: []any{List{}}})
+	if err == nil || !strings.Contains(err.Error(), "both named List") {
+		t.Errorf("expected a name clash error, got %v", err)
+	}
+}
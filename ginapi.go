@@ -0,0 +1,3 @@
+// Package ginapi provides router-level helpers that tie the middleware,
+// response, and pagination packages together.
+package ginapi
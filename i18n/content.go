@@ -0,0 +1,104 @@
+package i18n
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Text is a value with the language it is written in, for fields clients
+// should mark with a lang attribute when it isn't the page's language.
+type Text struct {
+	Value string `json:"value"`
+	Lang  string `json:"lang"`
+}
+
+// Chain returns the languages to try for content in lang, best first:
+// lang, its base language ("pt" for "pt-br"), then fallbacks, normalized
+// and without duplicates.
+func Chain(lang string, fallbacks ...string) []string {
+	var chain []string
+	add := func(l string) {
+		if l = normalize(l); l != "" && !slices.Contains(chain, l) {
+			chain = append(chain, l)
+		}
+	}
+	add(lang)
+	if base, _, found := strings.Cut(normalize(lang), "-"); found {
+		add(base)
+	}
+	for _, l := range fallbacks {
+		add(l)
+	}
+	return chain
+}
+
+// Pick returns the value of values, keyed by language, in the first
+// language of chain that has one, and that language. If none does, the
+// value in the alphabetically first language is returned, so content that
+// has any translation is never blank; the language is "" only if values
+// is empty.
+func Pick[T any](values map[string]T, chain []string) (T, string) {
+	normalized := make(map[string]string, len(values)) // normalized -> key
+	for k := range values {
+		normalized[normalize(k)] = k
+	}
+	for _, lang := range chain {
+		if k, ok := normalized[normalize(lang)]; ok {
+			return values[k], k
+		}
+	}
+
+	var zero T
+	if len(values) == 0 {
+		return zero, ""
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return values[keys[0]], keys[0]
+}
+
+// Localize picks the translation of values for the request's language,
+// falling back to its base language and then to the default catalog's
+// fallback language (see Chain and Pick), and returns the language
+// served. When ctx is a *gin.Context and that language isn't the
+// request's, it is added to the response's Content-Language, so the
+// header lists every language the response contains:
+//
+//	title, lang := i18n.Localize(c, gallery.Titles) // map[string]string
+func Localize[T any](ctx context.Context, values map[string]T) (T, string) {
+	requested := Language(ctx)
+	v, lang := Pick(values, Chain(requested, defaultCatalog.fallback))
+	if c, ok := ctx.(*gin.Context); ok && lang != "" && normalize(lang) != normalize(requested) {
+		addContentLanguage(c, lang)
+	}
+	return v, lang
+}
+
+// LocalizeText is Localize for text, returning the value with its language.
+func LocalizeText(ctx context.Context, values map[string]string) Text {
+	v, lang := Localize(ctx, values)
+	return Text{Value: v, Lang: lang}
+}
+
+// addContentLanguage adds lang to the response's Content-Language.
+func addContentLanguage(c *gin.Context, lang string) {
+	h := c.Writer.Header()
+	current := h.Get("Content-Language")
+	for _, l := range strings.Split(current, ",") {
+		if normalize(strings.TrimSpace(l)) == normalize(lang) {
+			return
+		}
+	}
+	if current == "" {
+		h.Set("Content-Language", lang)
+		return
+	}
+	h.Set("Content-Language", current+", "+lang)
+}
@@ -0,0 +1,93 @@
+package i18n_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/i18n"
+)
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		lang      string
+		fallbacks []string
+		want      string
+	}{
+		{"ja", []string{"en"}, "ja,en"},
+		{"pt-BR", []string{"en"}, "pt-br,pt,en"},
+		{"en", []string{"en"}, "en"},
+		{"", []string{"en"}, "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			if got := strings.Join(i18n.Chain(tt.lang, tt.fallbacks...), ","); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPick(t *testing.T) {
+	titles := map[string]string{"en": "Summer", "pt": "Verão", "zh-TW": "夏天"}
+
+	tests := []struct {
+		name      string
+		values    map[string]string
+		chain     []string
+		wantValue string
+		wantLang  string
+	}{
+		{"first choice", titles, []string{"pt", "en"}, "Verão", "pt"},
+		{"fallback", titles, []string{"ja", "en"}, "Summer", "en"},
+		{"normalized keys", titles, []string{"zh-tw"}, "夏天", "zh-TW"},
+		{"any translation", titles, []string{"ja"}, "Summer", "en"},
+		{"empty", nil, []string{"ja"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, lang := i18n.Pick(tt.values, tt.chain)
+			if value != tt.wantValue || lang != tt.wantLang {
+				t.Errorf("expected %q in %q, got %q in %q", tt.wantValue, tt.wantLang, value, lang)
+			}
+		})
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	titles := map[string]string{"en": "Summer", "ja": "夏"}
+
+	tests := []struct {
+		name       string
+		lang       string
+		wantText   i18n.Text
+		wantHeader string
+	}{
+		{"translated", "ja", i18n.Text{Value: "夏", Lang: "ja"}, "ja"},
+		{"fallback", "fr", i18n.Text{Value: "Summer", Lang: "en"}, "fr, en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/galleries/1", nil)
+			c.Set("language", tt.lang)
+			c.Header("Content-Language", tt.lang)
+
+			got := i18n.LocalizeText(c, titles)
+			i18n.LocalizeText(c, titles) // languages are listed once
+
+			if got != tt.wantText {
+				t.Errorf("expected %+v, got %+v", tt.wantText, got)
+			}
+			if h := w.Header().Get("Content-Language"); h != tt.wantHeader {
+				t.Errorf("expected Content-Language %q, got %q", tt.wantHeader, h)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+// Package i18n provides message catalogs keyed off the language resolved by
+// middleware.Language, with CLDR plural rules and template interpolation.
+//
+// Catalogs are JSON or TOML files named after their language ("en.json",
+// "ja.toml"), so they embed directly:
+//
+//	//go:embed locales
+//	var locales embed.FS
+//
+//	catalog := i18n.New("en")
+//	if err := catalog.LoadFS(locales, "locales"); err != nil {
+//	    log.Fatal(err)
+//	}
+//	i18n.SetDefault(catalog)
+//
+//	msg := i18n.T(c, "gallery.not_found", i18n.Args{"ID": id})
+//
+// Nested keys are joined with dots. A message is either a string or a table
+// of CLDR plural forms (zero, one, two, few, many, other) selected by the
+// "Count" argument:
+//
+//	{
+//	  "gallery": {
+//	    "not_found": "Gallery {{.ID}} not found",
+//	    "count": {"one": "{{.Count}} gallery", "other": "{{.Count}} galleries"}
+//	  }
+//	}
+package i18n
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pelletier/go-toml/v2"
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+// Args are the template arguments for a message. "Count" selects the plural form.
+type Args map[string]any
+
+// CountArg is the argument that selects the plural form.
+const CountArg = "Count"
+
+// pluralForms maps catalog keys to CLDR plural forms.
+var pluralForms = map[string]plural.Form{
+	"zero":  plural.Zero,
+	"one":   plural.One,
+	"two":   plural.Two,
+	"few":   plural.Few,
+	"many":  plural.Many,
+	"other": plural.Other,
+}
+
+// Catalog holds messages for every language. Load catalogs at startup;
+// a Catalog is safe for concurrent reads once loading is done.
+type Catalog struct {
+	fallback string
+	messages map[string]map[string]message // lang -> key -> message
+}
+
+// message is one catalog entry: its text per plural form (just Other for
+// messages without plurals).
+type message struct {
+	forms map[plural.Form]text
+}
+
+// text is a message string, parsed as a template only if it interpolates.
+type text struct {
+	literal string
+	tmpl    *template.Template
+}
+
+// New returns an empty catalog. Keys missing in a language are looked up in
+// fallback before the key itself is returned.
+func New(fallback string) *Catalog {
+	return &Catalog{
+		fallback: fallback,
+		messages: map[string]map[string]message{},
+	}
+}
+
+// LoadFS loads every .json and .toml file in dir, using the file name
+// (without extension) as the language.
+func (c *Catalog) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		ext := path.Ext(name)
+		if ext != ".json" && ext != ".toml" {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		var raw map[string]any
+		if ext == ".json" {
+			err = json.Unmarshal(data, &raw)
+		} else {
+			err = toml.Unmarshal(data, &raw)
+		}
+		if err != nil {
+			return fmt.Errorf("i18n: %s: %w", name, err)
+		}
+		if err := c.Add(strings.TrimSuffix(name, ext), raw); err != nil {
+			return fmt.Errorf("i18n: %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Add merges messages for lang into the catalog. Values are strings, plural
+// tables, or nested tables whose keys are joined with dots.
+func (c *Catalog) Add(lang string, messages map[string]any) error {
+	lang = normalize(lang)
+	if c.messages[lang] == nil {
+		c.messages[lang] = map[string]message{}
+	}
+	return c.add(lang, "", messages)
+}
+
+func (c *Catalog) add(lang, prefix string, messages map[string]any) error {
+	for k, v := range messages {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch v := v.(type) {
+		case string:
+			t, err := parseText(key, v)
+			if err != nil {
+				return err
+			}
+			c.messages[lang][key] = message{forms: map[plural.Form]text{plural.Other: t}}
+		case map[string]any:
+			if !isPluralTable(v) {
+				if err := c.add(lang, key, v); err != nil {
+					return err
+				}
+				continue
+			}
+			msg := message{forms: map[plural.Form]text{}}
+			for form, s := range v {
+				t, err := parseText(key, s.(string))
+				if err != nil {
+					return err
+				}
+				msg.forms[pluralForms[form]] = t
+			}
+			c.messages[lang][key] = msg
+		default:
+			return fmt.Errorf("%s: unsupported value of type %T", key, v)
+		}
+	}
+	return nil
+}
+
+// isPluralTable reports whether m is a table of plural forms: string values
+// only, plural form keys only, and an "other" form.
+func isPluralTable(m map[string]any) bool {
+	if _, ok := m["other"]; !ok {
+		return false
+	}
+	for k, v := range m {
+		if _, ok := pluralForms[k]; !ok {
+			return false
+		}
+		if _, ok := v.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// parseText parses s as a template if it contains an action.
+func parseText(key, s string) (text, error) {
+	if !strings.Contains(s, "{{") {
+		return text{literal: s}, nil
+	}
+	tmpl, err := template.New(key).Option("missingkey=zero").Parse(s)
+	if err != nil {
+		return text{}, fmt.Errorf("%s: %w", key, err)
+	}
+	return text{tmpl: tmpl}, nil
+}
+
+// Languages returns the languages with loaded messages, sorted.
+func (c *Catalog) Languages() []string {
+	langs := make([]string, 0, len(c.messages))
+	for lang := range c.messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Translate returns the message for key in lang, falling back to the
+// catalog's fallback language and then to the key itself.
+func (c *Catalog) Translate(lang, key string, args Args) string {
+	lang = normalize(lang)
+	msg, ok := c.lookup(lang, key)
+	if !ok {
+		if msg, ok = c.lookup(c.fallback, key); !ok {
+			return key
+		}
+		lang = c.fallback
+	}
+
+	t := msg.forms[plural.Other]
+	if count, ok := pluralCount(args); ok && len(msg.forms) > 1 {
+		if form, ok := msg.forms[pluralForm(lang, count)]; ok {
+			t = form
+		}
+	}
+	return t.render(args)
+}
+
+// lookup finds key in lang, then in lang's base language ("pt" for "pt-br").
+func (c *Catalog) lookup(lang, key string) (message, bool) {
+	if msg, ok := c.messages[lang][key]; ok {
+		return msg, true
+	}
+	if base, _, found := strings.Cut(lang, "-"); found {
+		msg, ok := c.messages[base][key]
+		return msg, ok
+	}
+	return message{}, false
+}
+
+// render executes the text with args.
+func (t text) render(args Args) string {
+	if t.tmpl == nil {
+		return t.literal
+	}
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, args); err != nil {
+		return t.tmpl.Name()
+	}
+	return buf.String()
+}
+
+// pluralForm returns the CLDR cardinal plural form of count in lang.
+func pluralForm(lang string, count int) plural.Form {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return plural.Other
+	}
+	if count < 0 {
+		count = -count
+	}
+	return plural.Cardinal.MatchPlural(tag, count%10000000, 0, 0, 0, 0)
+}
+
+// pluralCount returns the Count argument as an int.
+func pluralCount(args Args) (int, bool) {
+	switch n := args[CountArg].(type) {
+	case int:
+		return n, true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case uint:
+		return int(n), true
+	case uint32:
+		return int(n), true
+	case uint64:
+		return int(n), true
+	}
+	return 0, false
+}
+
+// normalize lowercases a language code and uses "-" as the separator.
+func normalize(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+}
+
+// defaultCatalog is used by T.
+var defaultCatalog = New("en")
+
+// SetDefault sets the catalog used by T. Call it once at startup.
+func SetDefault(c *Catalog) {
+	defaultCatalog = c
+}
+
+// Default returns the catalog used by T.
+func Default() *Catalog {
+	return defaultCatalog
+}
+
+// T translates key into the request's language using the default catalog.
+// ctx may be a *gin.Context (language from middleware.Language) or a request
+// context (language from middleware.WithLanguage).
+func T(ctx context.Context, key string, args ...Args) string {
+	var a Args
+	if len(args) > 0 {
+		a = args[0]
+	}
+	return defaultCatalog.Translate(Language(ctx), key, a)
+}
+
+// Language returns the language resolved for ctx, or "" if none.
+func Language(ctx context.Context) string {
+	if c, ok := ctx.(*gin.Context); ok {
+		if lang, exists := c.Get("language"); exists {
+			if s, ok := lang.(string); ok {
+				return s
+			}
+		}
+		if c.Request != nil {
+			return middleware.LanguageFromContext(c.Request.Context())
+		}
+		return ""
+	}
+	return middleware.LanguageFromContext(ctx)
+}
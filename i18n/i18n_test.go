@@ -0,0 +1,88 @@
+package i18n_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/i18n"
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func loadCatalog(t *testing.T) *i18n.Catalog {
+	t.Helper()
+	catalog := i18n.New("en")
+	if err := catalog.LoadFS(os.DirFS("testdata"), "locales"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return catalog
+}
+
+func TestTranslate(t *testing.T) {
+	catalog := loadCatalog(t)
+
+	tests := []struct {
+		name string
+		lang string
+		key  string
+		args i18n.Args
+		want string
+	}{
+		{name: "literal", lang: "en", key: "greeting", want: "Hello"},
+		{name: "interpolation", lang: "en", key: "gallery.not_found", args: i18n.Args{"ID": "gal_1"}, want: "Gallery gal_1 not found"},
+		{name: "toml", lang: "ja", key: "gallery.not_found", args: i18n.Args{"ID": "gal_1"}, want: "ギャラリーgal_1が見つかりません"},
+		{name: "plural one", lang: "en", key: "gallery.count", args: i18n.Args{"Count": 1}, want: "1 gallery"},
+		{name: "plural other", lang: "en", key: "gallery.count", args: i18n.Args{"Count": 5}, want: "5 galleries"},
+		{name: "no plurals", lang: "ja", key: "gallery.count", args: i18n.Args{"Count": 1}, want: "1件のギャラリー"},
+		{name: "russian few", lang: "ru", key: "gallery.count", args: i18n.Args{"Count": 3}, want: "3 галереи"},
+		{name: "russian many", lang: "ru", key: "gallery.count", args: i18n.Args{"Count": 5}, want: "5 галерей"},
+		{name: "russian one", lang: "ru", key: "gallery.count", args: i18n.Args{"Count": 21}, want: "21 галерея"},
+		{name: "region falls back to base", lang: "ja-JP", key: "gallery.count", args: i18n.Args{"Count": 2}, want: "2件のギャラリー"},
+		{name: "missing key falls back", lang: "ja", key: "greeting", want: "Hello"},
+		{name: "unknown key", lang: "ja", key: "missing.key", want: "missing.key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := catalog.Translate(tt.lang, tt.key, tt.args); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	langs := catalog.Languages()
+	if len(langs) != 3 || langs[0] != "en" || langs[1] != "ja" || langs[2] != "ru" {
+		t.Errorf("expected [en ja ru], got %v", langs)
+	}
+}
+
+func TestT(t *testing.T) {
+	i18n.SetDefault(loadCatalog(t))
+	defer i18n.SetDefault(i18n.New("en"))
+
+	ctx := middleware.WithLanguage(context.Background(), "ja")
+	if got := i18n.T(ctx, "gallery.not_found", i18n.Args{"ID": "gal_1"}); got != "ギャラリーgal_1が見つかりません" {
+		t.Errorf("unexpected request context translation %q", got)
+	}
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("language", "ja")
+	if got := i18n.T(c, "gallery.count", i18n.Args{"Count": 2}); got != "2件のギャラリー" {
+		t.Errorf("unexpected gin context translation %q", got)
+	}
+
+	if got := i18n.T(context.Background(), "greeting"); got != "Hello" {
+		t.Errorf("expected fallback language without a resolved language, got %q", got)
+	}
+}
+
+func TestAddInvalidTemplate(t *testing.T) {
+	catalog := i18n.New("en")
+	if err := catalog.Add("en", map[string]any{"broken": "{{.ID"}); err == nil {
+		t.Error("expected template parse error")
+	}
+}
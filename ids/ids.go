@@ -0,0 +1,293 @@
+// Package ids formats integer primary keys as the prefixed external IDs
+// clients see, e.g. "gal_4C92", and parses them back:
+//
+//	var galleryIDs = ids.Formatter{Prefix: "gal", Checksum: true}
+//
+//	response.Object(c, Gallery{ID: galleryIDs.Format(g.ID), ...})
+//
+//	id, err := galleryIDs.Parse(c.Param("id"))
+//	if err != nil {
+//	    var pe *ids.ParseError
+//	    if errors.As(err, &pe) {
+//	        response.ErrorWithInfo(c, pe.Status, pe.ErrorInfo())
+//	    }
+//	    return
+//	}
+package ids
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+var (
+	// ErrMalformed is wrapped by the ParseError of an ID that isn't in the
+	// Formatter's format, so no record can have it.
+	ErrMalformed = errors.New("ids: malformed ID")
+	// ErrChecksum is wrapped by the ParseError of an ID whose check
+	// character doesn't match, most likely a typo.
+	ErrChecksum = errors.New("ids: checksum mismatch")
+)
+
+// ParseError is an ID a Formatter couldn't parse.
+type ParseError struct {
+	// ID is the ID as given
+	ID string
+	// Status is 404 for malformed IDs, so they look like any other unknown
+	// one, and 400 for checksum mismatches
+	Status int
+	// Code is response.ErrorCodeInvalidIDChecksum for checksum mismatches,
+	// and empty for 404s
+	Code string
+	// Message describes the problem, e.g. `id "gal_4C9" not found`
+	Message string
+
+	err error
+}
+
+func (e *ParseError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns ErrMalformed or ErrChecksum.
+func (e *ParseError) Unwrap() error {
+	return e.err
+}
+
+// ErrorInfo returns the error details to send for e.
+func (e *ParseError) ErrorInfo() response.ErrorInfo {
+	if e.Status == http.StatusNotFound {
+		return response.ErrorInfo{Type: response.ErrorTypeNotFound, Message: e.Message}
+	}
+	return response.ErrorInfo{Type: response.ErrorTypeInvalidRequest, Code: e.Code, Message: e.Message}
+}
+
+// Formatter formats the integer keys of one entity as external IDs: the
+// prefix, "_", and the key in the alphabet. The zero value formats IDs in
+// base62 without a prefix.
+type Formatter struct {
+	// Prefix names the entity, e.g. "gal"
+	Prefix string
+	// Alphabet the keys are encoded in (defaults to Base62). Changing it
+	// changes every ID.
+	Alphabet *Alphabet
+	// Checksum appends a check character (Luhn mod N over the alphabet),
+	// so Parse can tell an ID with a typo in it (ErrChecksum) from one
+	// that doesn't exist. It catches every single mistyped character and
+	// most swaps of adjacent ones. Turning it on changes every ID.
+	Checksum bool
+	// MinLength pads the part after the prefix, check character included,
+	// to at least this many characters with leading zeros, so IDs don't
+	// give away how few records there are
+	MinLength int
+	// MaxLength, if set, is the longest part after the prefix Parse
+	// accepts, so garbage is rejected before it is decoded. Format panics
+	// for keys that don't fit.
+	MaxLength int
+}
+
+// Format returns the external ID of key n.
+func (f Formatter) Format(n uint64) string {
+	return f.prefix() + f.withCheck(f.alphabet().encode(n))
+}
+
+// MustMatch reports whether s is an ID in f's format: it has the prefix,
+// a length within bounds, only characters of the alphabet, and a matching
+// check character. Use it to reject garbage before a lookup, e.g. in
+// routing.
+func (f Formatter) MustMatch(s string) bool {
+	_, err := f.Parse(s)
+	return err == nil
+}
+
+// Parse returns the key of the external ID id. Errors are a *ParseError
+// wrapping ErrMalformed or ErrChecksum.
+func (f Formatter) Parse(id string) (uint64, error) {
+	body, err := f.body(id)
+	if err != nil {
+		return 0, err
+	}
+	a := f.alphabet()
+	n, ok := a.decode(body)
+	// Only the form Format returns is accepted, so a key has one ID
+	if !ok || len(body) != max(len(a.encode(n)), f.MinLength-f.checkLen()) {
+		return 0, f.malformed(id)
+	}
+	return n, nil
+}
+
+// body returns the encoded value of id, checking its prefix, length,
+// characters, and check character.
+func (f Formatter) body(id string) (string, error) {
+	a := f.alphabet()
+	body, ok := strings.CutPrefix(id, f.prefix())
+	if !ok || len(body) <= f.checkLen() || len(body) < f.MinLength || (f.MaxLength > 0 && len(body) > f.MaxLength) || !a.valid(body) {
+		return "", f.malformed(id)
+	}
+	if !f.Checksum {
+		return body, nil
+	}
+	if int(a.index[body[len(body)-1]]) != a.checkValue(body[:len(body)-1]) {
+		return "", &ParseError{
+			ID:      id,
+			Status:  http.StatusBadRequest,
+			Code:    response.ErrorCodeInvalidIDChecksum,
+			Message: fmt.Sprintf("id %q is mistyped: its check character doesn't match", id),
+			err:     ErrChecksum,
+		}
+	}
+	return body[:len(body)-1], nil
+}
+
+// withCheck returns body padded to MinLength with its check character, if
+// f has one. It panics if the result is longer than MaxLength.
+func (f Formatter) withCheck(body string) string {
+	a := f.alphabet()
+	if pad := f.MinLength - f.checkLen() - len(body); pad > 0 {
+		body = strings.Repeat(a.chars[:1], pad) + body
+	}
+	if f.Checksum {
+		body += string(a.chars[a.checkValue(body)])
+	}
+	if f.MaxLength > 0 && len(body) > f.MaxLength {
+		panic(fmt.Sprintf("ids: %s IDs are longer than their MaxLength of %d", f.Prefix, f.MaxLength))
+	}
+	return body
+}
+
+// checkLen returns the length of the check character.
+func (f Formatter) checkLen() int {
+	if f.Checksum {
+		return 1
+	}
+	return 0
+}
+
+// alphabet returns the alphabet of f.
+func (f Formatter) alphabet() *Alphabet {
+	if f.Alphabet == nil {
+		return Base62
+	}
+	return f.Alphabet
+}
+
+// prefix returns what IDs start with.
+func (f Formatter) prefix() string {
+	if f.Prefix == "" {
+		return ""
+	}
+	return f.Prefix + "_"
+}
+
+// malformed returns the error for an ID that isn't in f's format.
+func (f Formatter) malformed(id string) error {
+	return &ParseError{ID: id, Status: http.StatusNotFound, Message: fmt.Sprintf("id %q not found", id), err: ErrMalformed}
+}
+
+// Alphabet is the set of characters keys are encoded in.
+type Alphabet struct {
+	chars   string
+	index   [256]int16 // character -> value, -1 if not in the alphabet
+	uuidLen int        // length of the longest 128-bit value
+}
+
+var (
+	// Base62 is the default alphabet: digits, then upper and lowercase
+	// letters. IDs are short and case-sensitive.
+	Base62 = NewAlphabet("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+	// CrockfordBase32 leaves out I, L, O, and U, for IDs that are read
+	// aloud or typed from print. Parsing ignores case and reads O as 0 and
+	// I and L as 1.
+	CrockfordBase32 = newCrockfordBase32()
+)
+
+// NewAlphabet returns the alphabet of chars, in value order. It panics if
+// chars has fewer than 2 characters, repeats one, or has one that isn't
+// an ASCII letter or digit.
+func NewAlphabet(chars string) *Alphabet {
+	if len(chars) < 2 {
+		panic("ids: an alphabet needs at least 2 characters")
+	}
+	a := &Alphabet{chars: chars}
+	for i := range a.index {
+		a.index[i] = -1
+	}
+	for i := 0; i < len(chars); i++ {
+		ch := chars[i]
+		if !('0' <= ch && ch <= '9' || 'A' <= ch && ch <= 'Z' || 'a' <= ch && ch <= 'z') {
+			panic(fmt.Sprintf("ids: alphabet character %q isn't an ASCII letter or digit", ch))
+		}
+		if a.index[ch] >= 0 {
+			panic(fmt.Sprintf("ids: alphabet character %q is repeated", ch))
+		}
+		a.index[ch] = int16(i)
+	}
+	a.uuidLen = len(a.encode128(uuid.Max))
+	return a
+}
+
+// newCrockfordBase32 returns CrockfordBase32.
+func newCrockfordBase32() *Alphabet {
+	a := NewAlphabet("0123456789ABCDEFGHJKMNPQRSTVWXYZ")
+	a.index['O'], a.index['I'], a.index['L'] = a.index['0'], a.index['1'], a.index['1']
+	for ch := 'a'; ch <= 'z'; ch++ {
+		a.index[ch] = a.index[ch-'a'+'A']
+	}
+	return a
+}
+
+// encode returns n in the alphabet, most significant digit first.
+func (a *Alphabet) encode(n uint64) string {
+	base := uint64(len(a.chars))
+	var buf [64]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = a.chars[n%base]
+		if n /= base; n == 0 {
+			break
+		}
+	}
+	return string(buf[i:])
+}
+
+// decode returns the value of s. ok is false if it overflows.
+func (a *Alphabet) decode(s string) (n uint64, ok bool) {
+	base := uint64(len(a.chars))
+	for i := 0; i < len(s); i++ {
+		d := uint64(a.index[s[i]])
+		if n > (^uint64(0)-d)/base {
+			return 0, false
+		}
+		n = n*base + d
+	}
+	return n, true
+}
+
+// valid reports whether every character of s is in the alphabet.
+func (a *Alphabet) valid(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if a.index[s[i]] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// checkValue returns the value of the Luhn mod N check character of s.
+func (a *Alphabet) checkValue(s string) int {
+	n := len(a.chars)
+	factor, sum := 2, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		addend := factor * int(a.index[s[i]])
+		sum += addend/n + addend%n
+		factor = 3 - factor
+	}
+	return (n - sum%n) % n
+}
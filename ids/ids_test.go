@@ -0,0 +1,203 @@
+package ids_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/doujins-org/ginapi/ids"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestFormatter(t *testing.T) {
+	tests := []struct {
+		name string
+		f    ids.Formatter
+		n    uint64
+		want string
+	}{
+		{"zero", ids.Formatter{Prefix: "gal"}, 0, "gal_0"},
+		{"base62", ids.Formatter{Prefix: "gal"}, 12345, "gal_3D7"},
+		{"max", ids.Formatter{Prefix: "gal"}, 1<<64 - 1, "gal_LygHa16AHYF"},
+		{"no prefix", ids.Formatter{}, 61, "z"},
+		{"checksum", ids.Formatter{Prefix: "gal", Checksum: true}, 12345, "gal_3D7T"},
+		{"crockford", ids.Formatter{Prefix: "ord", Alphabet: ids.CrockfordBase32}, 12345, "ord_C1S"},
+		{"custom alphabet", ids.Formatter{Alphabet: ids.NewAlphabet("01")}, 5, "101"},
+		{"min length", ids.Formatter{Prefix: "gal", MinLength: 6}, 12345, "gal_0003D7"},
+		{"min length with checksum", ids.Formatter{Prefix: "gal", Checksum: true, MinLength: 6}, 12345, "gal_003D7T"},
+		{"min length reached", ids.Formatter{Prefix: "gal", MinLength: 2}, 12345, "gal_3D7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.f.Format(tt.n)
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			if n, err := tt.f.Parse(got); err != nil || n != tt.n {
+				t.Errorf("expected %s to parse back to %d, got %d (%v)", got, tt.n, n, err)
+			}
+		})
+	}
+}
+
+func TestFormatterParseErrors(t *testing.T) {
+	f := ids.Formatter{Prefix: "gal", Checksum: true}
+
+	tests := []struct {
+		id         string
+		wantErr    error
+		wantStatus int
+	}{
+		{"gal_3D8T", ids.ErrChecksum, http.StatusBadRequest}, // mistyped character
+		{"gal_D37T", ids.ErrChecksum, http.StatusBadRequest}, // swapped characters
+		{"usr_3D7T", ids.ErrMalformed, http.StatusNotFound},  // other prefix
+		{"gal_3D-7T", ids.ErrMalformed, http.StatusNotFound}, // not base62
+		{"gal_T", ids.ErrMalformed, http.StatusNotFound},     // no room for a value
+		{"gal_03D7T", ids.ErrMalformed, http.StatusNotFound}, // leading zero
+		{"gal_", ids.ErrMalformed, http.StatusNotFound},      // empty
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			_, err := f.Parse(tt.id)
+			var pe *ids.ParseError
+			if !errors.As(err, &pe) || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected a *ParseError wrapping %v, got %v", tt.wantErr, err)
+			}
+			if pe.Status != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, pe.Status)
+			}
+			if tt.wantErr == ids.ErrChecksum && pe.ErrorInfo().Code != response.ErrorCodeInvalidIDChecksum {
+				t.Errorf("expected code %s, got %s", response.ErrorCodeInvalidIDChecksum, pe.ErrorInfo().Code)
+			}
+		})
+	}
+}
+
+func TestCrockfordBase32(t *testing.T) {
+	f := ids.Formatter{Prefix: "ord", Alphabet: ids.CrockfordBase32}
+
+	tests := []struct {
+		id   string
+		want uint64
+		ok   bool
+	}{
+		{"ord_C1S", 12345, true},
+		{"ord_c1s", 12345, true}, // case-insensitive
+		{"ord_CIS", 12345, true}, // I read as 1
+		{"ord_ClS", 12345, true}, // l read as 1
+		{"ord_1O", 32, true},     // O read as 0
+		{"ord_CUS", 0, false},    // U isn't in the alphabet
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			n, err := f.Parse(tt.id)
+			if (err == nil) != tt.ok || n != tt.want {
+				t.Errorf("expected %d (ok %v), got %d (%v)", tt.want, tt.ok, n, err)
+			}
+		})
+	}
+}
+
+func TestNewAlphabetPanics(t *testing.T) {
+	for _, chars := range []string{"", "0", "00", "0_"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected NewAlphabet(%q) to panic", chars)
+				}
+			}()
+			ids.NewAlphabet(chars)
+		}()
+	}
+}
+
+func TestFormatterMustMatch(t *testing.T) {
+	f := ids.Formatter{Prefix: "gal", Checksum: true, MinLength: 4, MaxLength: 8}
+
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{f.Format(12345), true},
+		{f.Format(1), true},
+		{"gal_3DT", false},       // shorter than MinLength
+		{"gal_3D8T", false},      // checksum
+		{"gal_0003D7T", false},   // padded past MinLength
+		{"gal_3D7TTTTTT", false}, // longer than MaxLength
+		{"gal_3D7'--", false},
+		{"3D7T", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if got := f.MustMatch(tt.id); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatterMaxLengthPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Format to panic for a key longer than MaxLength")
+		}
+	}()
+	ids.Formatter{Prefix: "gal", MaxLength: 2}.Format(12345)
+}
+
+func TestFormatterParseOverflow(t *testing.T) {
+	if _, err := (ids.Formatter{Prefix: "gal"}).Parse("gal_LygHa16AHYG"); !errors.Is(err, ids.ErrMalformed) {
+		t.Errorf("expected ErrMalformed for a value past uint64, got %v", err)
+	}
+}
+
+// benchIDs is the Formatter of the benchmarks, with the options most
+// services turn on.
+var (
+	benchIDs = ids.Formatter{Prefix: "gal", Checksum: true}
+	benchID  = benchIDs.Format(1234567)
+)
+
+// formatterBudgets are the allocations allowed per call of Format and
+// Parse. They guard against per-request regressions; raise one only with
+// a reason in the commit.
+var formatterBudgets = []struct {
+	name   string
+	fn     func()
+	allocs float64
+}{
+	{"Format", func() { _ = benchIDs.Format(1234567) }, 3},
+	{"Parse", func() { _, _ = benchIDs.Parse(benchID) }, 0},
+}
+
+func TestFormatterAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector adds allocations")
+	}
+	for _, tt := range formatterBudgets {
+		t.Run(tt.name, func(t *testing.T) {
+			allocs := testing.AllocsPerRun(100, tt.fn)
+			if allocs > tt.allocs {
+				t.Errorf("expected at most %v allocations, got %v", tt.allocs, allocs)
+			}
+		})
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = benchIDs.Format(uint64(i))
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = benchIDs.Parse(benchID)
+	}
+}
@@ -0,0 +1,104 @@
+package ids
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ItemError is an item of a list of IDs ParseMany couldn't parse.
+type ItemError struct {
+	// Index of the item in the list
+	Index int
+	// Value is the item as given
+	Value string
+	// Err is the parse error, usually a *ParseError
+	Err error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the parse error of the item.
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// ParseMany parses a list of IDs with parse, for batch endpoints that
+// report each item: it returns the values of the valid items, in order,
+// and an *ItemError for each of the others.
+//
+//	keys, invalid := ids.ParseMany(galleryIDs.Parse, req.GalleryIDs)
+//	galleries, err := repo.GetMany(ctx, keys)
+//	... // report invalid alongside the items that weren't found
+//
+// parse may be the Parse, ParseUUID, or Normalize of a Formatter, or the
+// Parse of a Migration.
+func ParseMany[T any](parse func(id string) (T, error), ids []string) ([]T, []*ItemError) {
+	values := make([]T, 0, len(ids))
+	var invalid []*ItemError
+	for i, id := range ids {
+		v, err := parse(id)
+		if err != nil {
+			invalid = append(invalid, &ItemError{Index: i, Value: id, Err: err})
+			continue
+		}
+		values = append(values, v)
+	}
+	return values, invalid
+}
+
+// Normalize checks id with Parse, or ParseUUID for IDs of UUIDs, and
+// returns it as Format or FormatUUID would, for code that handles IDs as
+// strings. A UUID in its hyphenated form, e.g.
+// "0190a5b2-7c1e-7d3a-9f4e-1b2c3d4e5f60", is returned as FormatUUID
+// would. Errors are those of Parse.
+func (f Formatter) Normalize(id string) (string, error) {
+	n, err := f.Parse(id)
+	if err == nil {
+		return f.Format(n), nil
+	}
+	if !errors.Is(err, ErrMalformed) {
+		return "", err
+	}
+	if u, uerr := f.ParseUUID(id); uerr == nil {
+		return f.FormatUUID(u), nil
+	}
+	if len(id) == 36 {
+		if u, uerr := uuid.Parse(id); uerr == nil {
+			return f.FormatUUID(u), nil
+		}
+	}
+	return "", err
+}
+
+var (
+	formattersMu sync.RWMutex
+	formatters   = map[string]Formatter{}
+)
+
+// Register makes f the Formatter of its prefix, for packages that only
+// know an ID's prefix, such as the id tags of bind. Call it at startup. It
+// panics if f has no Prefix.
+func Register(f Formatter) {
+	if f.Prefix == "" {
+		panic("ids: Register requires a Formatter with a Prefix")
+	}
+	formattersMu.Lock()
+	formatters[f.Prefix] = f
+	formattersMu.Unlock()
+}
+
+// Lookup returns the Formatter registered for prefix, or a base62
+// Formatter with that prefix if there is none.
+func Lookup(prefix string) Formatter {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+	if f, ok := formatters[prefix]; ok {
+		return f
+	}
+	return Formatter{Prefix: prefix}
+}
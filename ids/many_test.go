@@ -0,0 +1,67 @@
+package ids_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/doujins-org/ginapi/ids"
+)
+
+func TestParseMany(t *testing.T) {
+	f := ids.Formatter{Prefix: "gal", Checksum: true}
+	keys, invalid := ids.ParseMany(f.Parse, []string{"gal_3D7T", "usr_3D7T", "gal_3D8T", "gal_3D7T"})
+
+	if want := []uint64{12345, 12345}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("expected %v, got %v", want, keys)
+	}
+	if len(invalid) != 2 {
+		t.Fatalf("expected 2 invalid items, got %v", invalid)
+	}
+	if invalid[0].Index != 1 || !errors.Is(invalid[0], ids.ErrMalformed) {
+		t.Errorf("expected item 1 to be malformed, got %v", invalid[0])
+	}
+	if invalid[1].Index != 2 || invalid[1].Value != "gal_3D8T" || !errors.Is(invalid[1], ids.ErrChecksum) {
+		t.Errorf("expected item 2 to fail its checksum, got %v", invalid[1])
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	f := ids.Formatter{Prefix: "gal"}
+	u := ids.NewUUID()
+
+	tests := []struct {
+		id      string
+		want    string
+		wantErr error
+	}{
+		{"gal_3D7", "gal_3D7", nil},
+		{f.FormatUUID(u), f.FormatUUID(u), nil},
+		{u.String(), f.FormatUUID(u), nil},
+		{"gal_03D7", "", ids.ErrMalformed},
+		{"usr_3D7", "", ids.ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, err := f.Normalize(tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	ids.Register(ids.Formatter{Prefix: "ord", Checksum: true})
+
+	if f := ids.Lookup("ord"); !f.Checksum {
+		t.Errorf("expected the registered Formatter, got %+v", f)
+	}
+	if f := ids.Lookup("unregistered"); f.Prefix != "unregistered" || f.Checksum {
+		t.Errorf("expected a plain Formatter, got %+v", f)
+	}
+}
@@ -0,0 +1,91 @@
+package ids
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/doujins-org/ginapi/metrics"
+)
+
+// MetricLegacyReads counts the IDs a Migration parsed in a legacy format.
+const MetricLegacyReads = "ids.legacy_reads"
+
+// Legacy formats, the values of the format tag of MetricLegacyReads.
+const (
+	FormatNumeric = "numeric"
+	FormatLegacy  = "legacy"
+)
+
+// Migration parses the IDs of an entity moving to a Formatter's format
+// while clients still send the IDs they were given before, raw keys or
+// e.g. hashids, so public IDs can change without breaking them overnight:
+//
+//	var galleryIDs = ids.Migration{
+//	    Formatter: ids.Formatter{Prefix: "gal", Checksum: true},
+//	    Numeric:   true,
+//	    Legacy:    func(id string) (uint64, bool) { return hashids.Decode(id) },
+//	    Sink:      statsd,
+//	}
+//
+// Format returns new IDs only. Once MetricLegacyReads stays at zero, the
+// Migration can be replaced by its Formatter.
+type Migration struct {
+	// Formatter is the new format. It needs a Prefix, so new IDs can be
+	// told from legacy ones.
+	Formatter Formatter
+	// Numeric accepts raw decimal keys, e.g. "12345"
+	Numeric bool
+	// Legacy, if set, returns the key of an ID in the legacy format, and
+	// false for other IDs
+	Legacy func(id string) (uint64, bool)
+	// Sink receives a MetricLegacyReads count per ID parsed in a legacy
+	// format, tagged with the prefix and the format (FormatNumeric or
+	// FormatLegacy). Optional.
+	Sink metrics.Sink
+}
+
+// Format returns the external ID of key n in the new format.
+func (m Migration) Format(n uint64) string {
+	return m.Formatter.Format(n)
+}
+
+// Parse returns the key of id, in the new format or a legacy one. IDs in
+// neither get the error of the new format's Parse.
+func (m Migration) Parse(id string) (uint64, error) {
+	n, err := m.Formatter.Parse(id)
+	if err == nil || !errors.Is(err, ErrMalformed) {
+		return n, err
+	}
+	if m.Numeric {
+		if key, perr := strconv.ParseUint(id, 10, 64); perr == nil {
+			m.count(FormatNumeric)
+			return key, nil
+		}
+	}
+	if m.Legacy != nil {
+		if key, ok := m.Legacy(id); ok {
+			m.count(FormatLegacy)
+			return key, nil
+		}
+	}
+	return 0, err
+}
+
+// Normalize returns id, in the new format or a legacy one, in the new
+// format. Errors are those of Parse.
+func (m Migration) Normalize(id string) (string, error) {
+	n, err := m.Parse(id)
+	if err != nil {
+		return "", err
+	}
+	return m.Format(n), nil
+}
+
+// count records a read of a legacy format.
+func (m Migration) count(format string) {
+	if m.Sink != nil {
+		m.Sink.Count(MetricLegacyReads, 1,
+			metrics.Tag{Key: "prefix", Value: m.Formatter.Prefix},
+			metrics.Tag{Key: "format", Value: format})
+	}
+}
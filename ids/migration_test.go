@@ -0,0 +1,94 @@
+package ids_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/ids"
+	"github.com/doujins-org/ginapi/metrics"
+)
+
+// formatSink records the format tag of the counts it receives.
+type formatSink struct {
+	formats []string
+}
+
+func (s *formatSink) Count(name string, value int64, tags ...metrics.Tag) {
+	for _, t := range tags {
+		if name == ids.MetricLegacyReads && t.Key == "format" {
+			s.formats = append(s.formats, t.Value)
+		}
+	}
+}
+
+func (s *formatSink) Gauge(string, float64, ...metrics.Tag)        {}
+func (s *formatSink) Timing(string, time.Duration, ...metrics.Tag) {}
+
+func TestMigration(t *testing.T) {
+	tests := []struct {
+		id         string
+		want       uint64
+		wantErr    error
+		wantFormat string
+	}{
+		{"gal_3D7T", 12345, nil, ""},
+		{"12345", 12345, nil, ids.FormatNumeric},
+		{"xK9", 12345, nil, ids.FormatLegacy},
+		{"gal_3D8T", 0, ids.ErrChecksum, ""}, // typo in a new ID isn't retried
+		{"usr_3D7T", 0, ids.ErrMalformed, ""},
+		{"-1", 0, ids.ErrMalformed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			sink := &formatSink{}
+			m := ids.Migration{
+				Formatter: ids.Formatter{Prefix: "gal", Checksum: true},
+				Numeric:   true,
+				Legacy: func(id string) (uint64, bool) {
+					return 12345, id == "xK9"
+				},
+				Sink: sink,
+			}
+
+			got, err := m.Parse(tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+			var wantFormats []string
+			if tt.wantFormat != "" {
+				wantFormats = []string{tt.wantFormat}
+			}
+			if len(sink.formats) != len(wantFormats) || (len(wantFormats) > 0 && sink.formats[0] != wantFormats[0]) {
+				t.Errorf("expected legacy reads %v, got %v", wantFormats, sink.formats)
+			}
+		})
+	}
+}
+
+func TestMigrationWithoutLegacyFormats(t *testing.T) {
+	m := ids.Migration{Formatter: ids.Formatter{Prefix: "gal"}}
+	if _, err := m.Parse("12345"); !errors.Is(err, ids.ErrMalformed) {
+		t.Errorf("expected ErrMalformed, got %v", err)
+	}
+	if got := m.Format(12345); got != "gal_3D7" {
+		t.Errorf("expected gal_3D7, got %s", got)
+	}
+}
+
+func TestMigrationNormalize(t *testing.T) {
+	m := ids.Migration{Formatter: ids.Formatter{Prefix: "gal", Checksum: true}, Numeric: true}
+
+	for _, id := range []string{"gal_3D7T", "12345"} {
+		if got, err := m.Normalize(id); err != nil || got != "gal_3D7T" {
+			t.Errorf("%s: expected gal_3D7T, got %q, %v", id, got, err)
+		}
+	}
+	if _, err := m.Normalize("gal_3D8T"); !errors.Is(err, ids.ErrChecksum) {
+		t.Errorf("expected ErrChecksum, got %v", err)
+	}
+}
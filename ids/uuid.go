@@ -0,0 +1,108 @@
+package ids
+
+import (
+	"math/bits"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// NewUUID returns a new version 7 UUID. Its first 48 bits are the Unix
+// time in milliseconds, so new rows land at the end of a primary key index
+// instead of all over it.
+func NewUUID() uuid.UUID {
+	return uuid.Must(uuid.NewV7())
+}
+
+// FormatUUID returns the external ID of u with the given prefix, e.g.
+// "gal_1yh7Lq3bsp4AVsGzWOc7u4", in base62. See Formatter.FormatUUID.
+func FormatUUID(prefix string, u uuid.UUID) string {
+	return Formatter{Prefix: prefix}.FormatUUID(u)
+}
+
+// ParseUUID returns the UUID of an ID from FormatUUID.
+func ParseUUID(prefix, id string) (uuid.UUID, error) {
+	return Formatter{Prefix: prefix}.ParseUUID(id)
+}
+
+// FormatUUID returns the external ID of u: the prefix, "_", and the 128
+// bits of u in the alphabet, padded to a fixed length (22 characters in
+// base62). As the alphabets are in ASCII order, IDs of UUIDv7s sort by
+// creation time like the UUIDs do.
+func (f Formatter) FormatUUID(u uuid.UUID) string {
+	a := f.alphabet()
+	body := a.encode128(u)
+	if pad := a.uuidLen - len(body); pad > 0 {
+		body = strings.Repeat(a.chars[:1], pad) + body
+	}
+	return f.prefix() + f.withCheck(body)
+}
+
+// ParseUUID returns the UUID of an ID from FormatUUID. Errors are a
+// *ParseError wrapping ErrMalformed or ErrChecksum.
+func (f Formatter) ParseUUID(id string) (uuid.UUID, error) {
+	body, err := f.body(id)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	a := f.alphabet()
+	u, ok := a.decode128(body)
+	if !ok || len(body) != max(a.uuidLen, f.MinLength-f.checkLen()) {
+		return uuid.UUID{}, f.malformed(id)
+	}
+	return u, nil
+}
+
+// encode128 returns the 128 bits of u in the alphabet, most significant
+// digit first.
+func (a *Alphabet) encode128(u uuid.UUID) string {
+	hi, lo := uint128(u)
+	base := uint64(len(a.chars))
+	var buf [128]byte
+	i := len(buf)
+	for {
+		var r uint64
+		hi, r = hi/base, hi%base
+		lo, r = bits.Div64(r, lo, base)
+		i--
+		buf[i] = a.chars[r]
+		if hi == 0 && lo == 0 {
+			break
+		}
+	}
+	return string(buf[i:])
+}
+
+// decode128 returns the 128-bit value of s. ok is false if it overflows.
+func (a *Alphabet) decode128(s string) (u uuid.UUID, ok bool) {
+	var hi, lo uint64
+	base := uint64(len(a.chars))
+	for i := 0; i < len(s); i++ {
+		// (hi, lo) = (hi, lo)*base + digit
+		hiHi, hiLo := bits.Mul64(hi, base)
+		loHi, loLo := bits.Mul64(lo, base)
+		if hiHi != 0 {
+			return u, false
+		}
+		var carry uint64
+		lo, carry = bits.Add64(loLo, uint64(a.index[s[i]]), 0)
+		hi, carry = bits.Add64(hiLo, loHi, carry)
+		if carry != 0 {
+			return u, false
+		}
+	}
+	for i := 0; i < 8; i++ {
+		u[i] = byte(hi >> (56 - 8*i))
+		u[8+i] = byte(lo >> (56 - 8*i))
+	}
+	return u, true
+}
+
+// uint128 returns the high and low 64 bits of u.
+func uint128(u uuid.UUID) (hi, lo uint64) {
+	for i := 0; i < 8; i++ {
+		hi = hi<<8 | uint64(u[i])
+		lo = lo<<8 | uint64(u[8+i])
+	}
+	return hi, lo
+}
@@ -0,0 +1,80 @@
+package ids_test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/doujins-org/ginapi/ids"
+)
+
+func TestFormatUUID(t *testing.T) {
+	tests := []struct {
+		name string
+		u    uuid.UUID
+		want string
+	}{
+		{"nil", uuid.Nil, "gal_0000000000000000000000"},
+		{"max", uuid.Max, "gal_7n42DGM5Tflk9n8mt7Fhc7"},
+		{"v4", uuid.MustParse("f47ac10b-58cc-4372-a567-0e02b2c3d479"), "gal_7RKE2sawAICsEsyZKHWW6r"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids.FormatUUID("gal", tt.u)
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			if u, err := ids.ParseUUID("gal", got); err != nil || u != tt.u {
+				t.Errorf("expected %s to parse back to %s, got %s (%v)", got, tt.u, u, err)
+			}
+		})
+	}
+}
+
+func TestFormatterUUID(t *testing.T) {
+	f := ids.Formatter{Prefix: "ord", Alphabet: ids.CrockfordBase32, Checksum: true}
+	u := ids.NewUUID()
+
+	id := f.FormatUUID(u)
+	if len(id) != len("ord_")+26+1 {
+		t.Errorf("expected 26 base32 characters and a check character, got %s", id)
+	}
+	if got, err := f.ParseUUID(id); err != nil || got != u {
+		t.Errorf("expected %s, got %s (%v)", u, got, err)
+	}
+}
+
+func TestParseUUIDErrors(t *testing.T) {
+	tests := []string{
+		"gal_7n42DGM5Tflk9n8mt7Fhc8", // past 128 bits
+		"gal_7n42DGM5Tflk9n8mt7Fhc",  // short
+		"gal_1",
+		"usr_0000000000000000000000",
+	}
+
+	for _, id := range tests {
+		t.Run(id, func(t *testing.T) {
+			if _, err := ids.ParseUUID("gal", id); !errors.Is(err, ids.ErrMalformed) {
+				t.Errorf("expected ErrMalformed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewUUIDSortsByCreation(t *testing.T) {
+	var created []string
+	for i := 0; i < 100; i++ {
+		u := ids.NewUUID()
+		if u.Version() != 7 {
+			t.Fatalf("expected a version 7 UUID, got version %d", u.Version())
+		}
+		created = append(created, ids.FormatUUID("gal", u))
+	}
+
+	if !sort.StringsAreSorted(created) {
+		t.Errorf("expected IDs to sort in creation order, got %v", created)
+	}
+}
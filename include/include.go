@@ -0,0 +1,112 @@
+// Package include parses ?include= and runs the loaders for the requested
+// relations, so handlers only load what the client asked for and each
+// relation is loaded once per request (batch loaders avoid N+1 queries).
+package include
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrUnknownInclude is returned by Bind for a relation not in the allowed list.
+var ErrUnknownInclude = errors.New("unknown include")
+
+// Set is the set of relations requested via ?include=.
+type Set map[string]struct{}
+
+// Loader loads one relation, typically by batch-loading it for every item in
+// the response and assigning the results.
+type Loader func(ctx context.Context) error
+
+// Loaders maps relation names to their loaders.
+type Loaders map[string]Loader
+
+// Bind parses ?include=author,tags (repeated params are also accepted) and
+// returns an error wrapping ErrUnknownInclude for relations not in allowed.
+//
+//	inc, err := include.Bind(c, []string{"author", "tags"})
+//	if err != nil {
+//	    response.BadRequestParam(c, "include", err.Error())
+//	    return
+//	}
+//	err = inc.Load(ctx, include.Loaders{
+//	    "author": func(ctx context.Context) error { return loadAuthors(ctx, galleries) },
+//	    "tags":   func(ctx context.Context) error { return loadTags(ctx, galleries) },
+//	})
+func Bind(c *gin.Context, allowed []string) (Set, error) {
+	return FromRequest(c.Request, allowed)
+}
+
+// FromRequest is the net/http equivalent of Bind.
+func FromRequest(r *http.Request, allowed []string) (Set, error) {
+	set := Set{}
+	for _, value := range r.URL.Query()["include"] {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !slices.Contains(allowed, name) {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownInclude, name)
+			}
+			set[name] = struct{}{}
+		}
+	}
+	return set, nil
+}
+
+// Has reports whether the relation was requested.
+func (s Set) Has(name string) bool {
+	_, ok := s[name]
+	return ok
+}
+
+// Load runs the loaders for the requested relations, in name order, stopping
+// at the first error. Relations without a loader are skipped.
+func (s Set) Load(ctx context.Context, loaders Loaders) error {
+	for _, name := range s.names(loaders) {
+		if err := loaders[name](ctx); err != nil {
+			return fmt.Errorf("include %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// LoadConcurrent runs the loaders for the requested relations concurrently,
+// at most limit at a time (no limit if limit <= 0). The first error cancels
+// the context passed to the remaining loaders.
+func (s Set) LoadConcurrent(ctx context.Context, loaders Loaders, limit int) error {
+	g, ctx := errgroup.WithContext(ctx)
+	if limit > 0 {
+		g.SetLimit(limit)
+	}
+	for _, name := range s.names(loaders) {
+		load := loaders[name]
+		g.Go(func() error {
+			if err := load(ctx); err != nil {
+				return fmt.Errorf("include %s: %w", name, err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// names returns the requested relations that have loaders, sorted.
+func (s Set) names(loaders Loaders) []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		if _, ok := loaders[name]; ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,96 @@
+package include_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/doujins-org/ginapi/include"
+)
+
+func TestFromRequest(t *testing.T) {
+	allowed := []string{"author", "tags", "pages"}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", query: "", want: nil},
+		{name: "comma separated", query: "?include=author,tags", want: []string{"author", "tags"}},
+		{name: "repeated", query: "?include=author&include=pages", want: []string{"author", "pages"}},
+		{name: "whitespace and empties", query: "?include=author,%20tags,,", want: []string{"author", "tags"}},
+		{name: "unknown", query: "?include=author,secrets", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := include.FromRequest(httptest.NewRequest("GET", "/galleries"+tt.query, nil), allowed)
+			if tt.wantErr {
+				if !errors.Is(err, include.ErrUnknownInclude) {
+					t.Errorf("expected ErrUnknownInclude, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(set) != len(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, set)
+			}
+			for _, name := range tt.want {
+				if !set.Has(name) {
+					t.Errorf("expected %s to be included", name)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	set, _ := include.FromRequest(httptest.NewRequest("GET", "/galleries?include=author,tags", nil), []string{"author", "tags", "pages"})
+
+	var calls []string
+	loaders := include.Loaders{
+		"author": func(ctx context.Context) error { calls = append(calls, "author"); return nil },
+		"tags":   func(ctx context.Context) error { calls = append(calls, "tags"); return nil },
+		"pages":  func(ctx context.Context) error { calls = append(calls, "pages"); return nil },
+	}
+	if err := set.Load(context.Background(), loaders); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(calls) != 2 || calls[0] != "author" || calls[1] != "tags" {
+		t.Errorf("expected [author tags], got %v", calls)
+	}
+
+	boom := errors.New("boom")
+	loaders["tags"] = func(ctx context.Context) error { return boom }
+	if err := set.Load(context.Background(), loaders); !errors.Is(err, boom) {
+		t.Errorf("expected loader error, got %v", err)
+	}
+}
+
+func TestLoadConcurrent(t *testing.T) {
+	set, _ := include.FromRequest(httptest.NewRequest("GET", "/galleries?include=author,tags,pages", nil), []string{"author", "tags", "pages"})
+
+	var count atomic.Int32
+	load := func(ctx context.Context) error { count.Add(1); return nil }
+	if err := set.LoadConcurrent(context.Background(), include.Loaders{"author": load, "tags": load, "pages": load}, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count.Load() != 3 {
+		t.Errorf("expected 3 loads, got %d", count.Load())
+	}
+
+	boom := errors.New("boom")
+	err := set.LoadConcurrent(context.Background(), include.Loaders{
+		"author": func(ctx context.Context) error { return boom },
+		"tags":   func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() },
+	}, 0)
+	if !errors.Is(err, boom) {
+		t.Errorf("expected loader error, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// rowReader reads the rows of an input.
+type rowReader[T any] interface {
+	// next reads the next row, decoding it if decode is set. A row that
+	// can't be decoded is returned as a RowError; err is io.EOF at the end
+	// of the input.
+	next(decode bool) (v *T, rowErr *RowError, err error)
+}
+
+// csvRows reads CSV rows, mapping columns to fields by their JSON names.
+type csvRows[T any] struct {
+	r       *csv.Reader
+	header  []string
+	columns []*csvColumn // by header position; nil for ignored columns
+}
+
+// csvColumn is the struct field a CSV column is decoded into.
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+// newCSVRows reads the header row of r.
+func newCSVRows[T any](r io.Reader) (*csvRows[T], error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("ingest: CSV rows need a struct type, got %s", t)
+	}
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	header = append([]string(nil), header...)
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff") // spreadsheet byte order mark
+	}
+
+	fields := map[string]reflect.StructField{}
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+	columns := make([]*csvColumn, len(header))
+	for i, name := range header {
+		f, ok := fields[strings.TrimSpace(name)]
+		if !ok {
+			continue
+		}
+		if !decodable(f.Type) {
+			return nil, fmt.Errorf("ingest: unsupported type %s for CSV column %s", f.Type, name)
+		}
+		columns[i] = &csvColumn{name: strings.TrimSpace(name), index: f.Index}
+	}
+	return &csvRows[T]{r: cr, header: header, columns: columns}, nil
+}
+
+func (r *csvRows[T]) next(decode bool) (*T, *RowError, error) {
+	record, err := r.r.Read()
+	var pe *csv.ParseError
+	if errors.As(err, &pe) && !errors.Is(err, errTooLarge) {
+		return nil, &RowError{Code: ErrorCodeInvalidRow, Message: "row is not valid CSV: " + pe.Err.Error()}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !decode {
+		return nil, nil, nil
+	}
+	if len(record) != len(r.header) {
+		return nil, &RowError{
+			Code:    ErrorCodeInvalidRow,
+			Message: fmt.Sprintf("row has %d columns, expected %d", len(record), len(r.header)),
+		}, nil
+	}
+	v := new(T)
+	rv := reflect.ValueOf(v).Elem()
+	for i, col := range r.columns {
+		if col == nil || record[i] == "" {
+			continue
+		}
+		if rowErr := setCell(rv.FieldByIndex(col.index), col.name, record[i]); rowErr != nil {
+			return nil, rowErr, nil
+		}
+	}
+	return v, nil, nil
+}
+
+// decodable reports whether setCell can decode a cell into a field of type
+// t.
+func decodable(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return t.Elem().Kind() != reflect.Pointer && decodable(t.Elem())
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+var textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// setCell decodes value, a non-empty cell of column name, into fv.
+func setCell(fv reflect.Value, name, value string) *RowError {
+	invalid := func(kind string) *RowError {
+		return &RowError{
+			Field:   name,
+			Code:    response.ErrorCodeInvalidParam,
+			Message: fmt.Sprintf("%s must be %s, got %q", name, kind, value),
+		}
+	}
+	if fv.Kind() == reflect.Pointer {
+		fv.Set(reflect.New(fv.Type().Elem()))
+		fv = fv.Elem()
+	}
+	if fv.Type() == timeType {
+		for _, layout := range []string{time.RFC3339, time.DateOnly} {
+			if t, err := time.Parse(layout, value); err == nil {
+				fv.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return invalid("an RFC 3339 time or a date")
+	}
+	if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(value)); err != nil {
+			return &RowError{Field: name, Code: response.ErrorCodeInvalidParam, Message: fmt.Sprintf("%s is invalid: %v", name, err)}
+		}
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return invalid("an integer")
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return invalid("a non-negative integer")
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return invalid("a number")
+		}
+		fv.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return invalid("true or false")
+		}
+		fv.SetBool(b)
+	}
+	return nil
+}
+
+// ndjsonRows reads newline-delimited JSON rows, skipping blank lines.
+type ndjsonRows[T any] struct {
+	r *bufio.Reader
+}
+
+func newNDJSONRows[T any](r io.Reader) *ndjsonRows[T] {
+	return &ndjsonRows[T]{r: bufio.NewReader(r)}
+}
+
+func (r *ndjsonRows[T]) next(decode bool) (*T, *RowError, error) {
+	var line []byte
+	for len(line) == 0 {
+		b, err := r.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(bytes.TrimSpace(b)) == 0) {
+			return nil, nil, err
+		}
+		line = bytes.TrimSpace(b)
+	}
+	if !decode {
+		return nil, nil, nil
+	}
+	v := new(T)
+	if err := json.Unmarshal(line, v); err != nil {
+		var te *json.UnmarshalTypeError
+		if errors.As(err, &te) && te.Field != "" {
+			return nil, &RowError{
+				Field:   te.Field,
+				Code:    response.ErrorCodeInvalidParam,
+				Message: fmt.Sprintf("%s can't be a JSON %s", te.Field, te.Value),
+			}, nil
+		}
+		return nil, &RowError{Code: ErrorCodeInvalidRow, Message: "row is not valid JSON: " + err.Error()}, nil
+	}
+	return v, nil, nil
+}
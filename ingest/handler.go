@@ -0,0 +1,56 @@
+package ingest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Handle imports the request body with Read, in the Format of its
+// Content-Type, from the row in the resume_from query parameter, and
+// answers 200 with the Result, even when some rows failed. Other content
+// types are a 415, and bodies declaring a Content-Length over MaxBytes a
+// 413 import_too_large. If fn aborts the import it answers 500 naming the
+// row; the rows before it were imported, so the client can resume from it.
+func Handle[T any](c *gin.Context, cfg Config, fn RowFunc[T]) {
+	cfg = cfg.withDefaults()
+	format, ok := FormatOf(c.GetHeader("Content-Type"))
+	if !ok {
+		response.UnsupportedMediaType(c, "imports must be text/csv or application/x-ndjson")
+		return
+	}
+	from := 1
+	if s := c.Query("resume_from"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			response.ErrorWithInfo(c, http.StatusBadRequest, response.ErrorInfo{
+				Type:    response.ErrorTypeInvalidRequest,
+				Code:    response.ErrorCodeInvalidParam,
+				Message: "resume_from must be a positive row number",
+				Param:   "resume_from",
+			})
+			return
+		}
+		from = n
+	}
+	if c.Request.ContentLength > cfg.MaxBytes {
+		response.ErrorWithInfo(c, http.StatusRequestEntityTooLarge, response.ErrorInfo{
+			Type:    response.ErrorTypeInvalidRequest,
+			Code:    ErrorCodeImportTooLarge,
+			Message: fmt.Sprintf("imports are limited to %d bytes; split the file into several requests", cfg.MaxBytes),
+			Details: map[string]any{"max_bytes": cfg.MaxBytes},
+		})
+		return
+	}
+
+	res, err := Read(c.Request.Context(), c.Request.Body, format, from, cfg, fn)
+	if err != nil {
+		response.InternalError(c, fmt.Sprintf("import failed after %d rows: %v", res.Rows, err))
+		return
+	}
+	response.Object(c, res)
+}
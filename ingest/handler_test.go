@@ -0,0 +1,76 @@
+package ingest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/ingest"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestHandle(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		query       string
+		body        string
+		wantStatus  int
+		wantCode    string
+		wantRows    int
+	}{
+		{"csv", "text/csv", "", "title\na\nb\n", http.StatusOK, "", 2},
+		{"ndjson", "application/x-ndjson", "", `{"title":"a"}` + "\n", http.StatusOK, "", 1},
+		{"partial failure", "text/csv", "", "title\na\n\"\"\n", http.StatusOK, "", 2},
+		{"resumed", "text/csv", "?resume_from=2", "title\na\nb\n", http.StatusOK, "", 1},
+		{"invalid resume_from", "text/csv", "?resume_from=0", "title\na\n", http.StatusBadRequest, "invalid_param", 0},
+		{"json", "application/json", "", `[{"title":"a"}]`, http.StatusUnsupportedMediaType, "", 0},
+		{"too large", "text/csv", "", "title\n" + strings.Repeat("a\n", 40), http.StatusRequestEntityTooLarge, "import_too_large", 0},
+		{"row aborts", "text/csv", "", "title\nfail\n", http.StatusInternalServerError, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/import", func(c *gin.Context) {
+				ingest.Handle(c, ingest.Config{MaxBytes: 64}, func(ctx context.Context, row int, v *galleryRow) error {
+					if v.Title == "fail" {
+						return errors.New("database is down")
+					}
+					return nil
+				})
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/import"+tt.query, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if tt.wantCode != "" {
+					er, err := response.ParseError(w.Body.Bytes())
+					if err != nil || er.Error.Code != tt.wantCode {
+						t.Errorf("expected %s, got %s", tt.wantCode, w.Body.String())
+					}
+				}
+				return
+			}
+			var res ingest.Result
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("expected an import_result, got %s", w.Body.String())
+			}
+			if res.Object != "import_result" || res.Rows != tt.wantRows || !res.Complete {
+				t.Errorf("expected %d rows, got %s", tt.wantRows, w.Body.String())
+			}
+		})
+	}
+}
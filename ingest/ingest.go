@@ -0,0 +1,290 @@
+// Package ingest is the write-direction counterpart of package export:
+// helpers for endpoints that import CSV or NDJSON files row by row. Rows
+// are decoded into a struct, validated by its binding tags, and handed to
+// a callback one at a time, so a file is never held in memory. Rows that
+// fail are reported in the Result with their row number and field, and the
+// rest are imported:
+//
+//	api.POST("/galleries/import", func(c *gin.Context) {
+//	    owner := auth.GetPrincipal(c).ID
+//	    ingest.Handle(c, ingest.Config{MaxRows: 5000}, func(ctx context.Context, row int, g *GalleryInput) error {
+//	        return store.CreateGallery(ctx, owner, g)
+//	    })
+//	})
+//
+// Imports are limited in size, rows, failures, and time. One that stops at
+// a limit reports the row it stopped at as next_row; the client sends the
+// same file again with ?resume_from=<next_row> to continue, so a large
+// file is imported in several requests. Files received with package upload
+// are imported with Read in a background task, the same way.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"time"
+
+	"github.com/doujins-org/ginapi/bind"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Format is the file format of an import.
+type Format string
+
+// Formats.
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// FormatOf returns the Format of a Content-Type: text/csv for CSV, and
+// application/x-ndjson, application/ndjson, or application/jsonl for
+// NDJSON.
+func FormatOf(contentType string) (Format, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	switch mediaType {
+	case "text/csv":
+		return FormatCSV, true
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return FormatNDJSON, true
+	}
+	return "", false
+}
+
+// Reasons an import stopped before the end of its input, in
+// Result.StoppedBy.
+const (
+	StopMaxBytes  = "max_bytes"
+	StopMaxRows   = "max_rows"
+	StopMaxErrors = "max_errors"
+	StopTimeout   = "timeout"
+)
+
+// Error codes of ingest.
+const (
+	// ErrorCodeInvalidRow is a row that can't be decoded, e.g. malformed
+	// JSON or a CSV row with the wrong number of columns
+	ErrorCodeInvalidRow = "invalid_row"
+	// ErrorCodeImportTooLarge is a request body over Config.MaxBytes
+	ErrorCodeImportTooLarge = "import_too_large"
+)
+
+// Config limits an import.
+type Config struct {
+	// MaxBytes is the most input read; an import stops at the row that
+	// crosses it (defaults to 32MiB)
+	MaxBytes int64
+	// MaxRows is the most rows handled (defaults to 10000)
+	MaxRows int
+	// MaxErrors is the most rows that may fail before the import stops
+	// (defaults to 100)
+	MaxErrors int
+	// Timeout is how long the rows may take; the RowFunc's context has
+	// this deadline (defaults to 30s)
+	Timeout time.Duration
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 32 << 20
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = 10000
+	}
+	if cfg.MaxErrors <= 0 {
+		cfg.MaxErrors = 100
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return cfg
+}
+
+// RowFunc imports row, a decoded and validated row numbered from 1 (the
+// CSV header isn't counted). To fail just this row it returns a *RowError,
+// or an error with an ErrorInfo method such as a *bind.SchemaError; any
+// other error aborts the import.
+type RowFunc[T any] func(ctx context.Context, row int, v *T) error
+
+// RowError is a row that failed.
+type RowError struct {
+	Row int `json:"row"`
+	// Field is the JSON name of the offending field, if any
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+// Result reports the outcome of an import.
+type Result struct {
+	Object string `json:"object"` // Always "import_result"
+	// Rows handled, starting at the resumed row
+	Rows      int `json:"rows"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	// Errors lists the failed rows, in order
+	Errors []RowError `json:"errors"`
+	// Complete is whether the input was read to its end
+	Complete bool `json:"complete"`
+	// StoppedBy is the limit an incomplete import stopped at
+	StoppedBy string `json:"stopped_by,omitempty"`
+	// NextRow is the row to resume an incomplete import from
+	NextRow int `json:"next_row,omitempty"`
+}
+
+// errorInfoer is an error that knows its response error details.
+type errorInfoer interface {
+	ErrorInfo() response.ErrorInfo
+}
+
+// Read imports the rows of r, in format, from row from (1 to start at the
+// beginning), calling fn for each row that decodes and validates. CSV
+// input starts with a header row of JSON field names; columns with other
+// names are ignored. Empty CSV cells leave fields at their zero value, or
+// nil for pointers. It returns the Result so far and an error if fn aborts
+// the import, r fails, or the context is canceled.
+func Read[T any](ctx context.Context, r io.Reader, format Format, from int, cfg Config, fn RowFunc[T]) (Result, error) {
+	cfg = cfg.withDefaults()
+	if from < 1 {
+		from = 1
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	res := Result{Object: "import_result", Errors: []RowError{}}
+	input := &limitReader{r: r, n: cfg.MaxBytes}
+	var rows rowReader[T]
+	switch format {
+	case FormatCSV:
+		cr, err := newCSVRows[T](input)
+		if errors.Is(err, io.EOF) {
+			res.Complete = true
+			return res, nil
+		}
+		if errors.Is(err, errTooLarge) {
+			res.StoppedBy, res.NextRow = StopMaxBytes, from
+			return res, nil
+		}
+		if err != nil {
+			return res, err
+		}
+		rows = cr
+	case FormatNDJSON:
+		rows = newNDJSONRows[T](input)
+	default:
+		return res, fmt.Errorf("ingest: unsupported format %q", format)
+	}
+
+	stop := func(reason string, row int) (Result, error) {
+		res.StoppedBy, res.NextRow = reason, row
+		return res, nil
+	}
+	for row := 1; ; row++ {
+		v, rowErr, err := rows.next(row >= from)
+		switch {
+		case errors.Is(err, io.EOF):
+			res.Complete = true
+			return res, nil
+		case errors.Is(err, errTooLarge):
+			return stop(StopMaxBytes, max(row, from))
+		case err != nil:
+			return res, err
+		}
+		if row < from {
+			continue
+		}
+		switch {
+		case res.Rows == cfg.MaxRows:
+			return stop(StopMaxRows, row)
+		case res.Failed == cfg.MaxErrors:
+			return stop(StopMaxErrors, row)
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return stop(StopTimeout, row)
+		case ctx.Err() != nil:
+			return res, ctx.Err()
+		}
+
+		if rowErr == nil {
+			if err := bind.Validate(ctx, v); err != nil {
+				if rowErr = rowFailure(err); rowErr == nil {
+					return res, err
+				}
+			}
+		}
+		if rowErr == nil {
+			if err := fn(ctx, row, v); err != nil {
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return stop(StopTimeout, row)
+				}
+				if rowErr = rowFailure(err); rowErr == nil {
+					return res, fmt.Errorf("ingest: row %d: %w", row, err)
+				}
+			}
+		}
+
+		res.Rows++
+		if rowErr != nil {
+			rowErr.Row = row
+			res.Failed++
+			res.Errors = append(res.Errors, *rowErr)
+			continue
+		}
+		res.Succeeded++
+	}
+}
+
+// rowFailure returns the RowError for err, or nil if err doesn't fail a
+// row.
+func rowFailure(err error) *RowError {
+	var re *RowError
+	if errors.As(err, &re) {
+		copied := *re
+		return &copied
+	}
+	var ei errorInfoer
+	if errors.As(err, &ei) {
+		info := ei.ErrorInfo()
+		return &RowError{Field: info.Param, Code: info.Code, Message: info.Message}
+	}
+	return nil
+}
+
+// errTooLarge is returned by limitReader past its limit.
+var errTooLarge = errors.New("ingest: input too large")
+
+// limitReader reads at most n bytes from r, then fails with errTooLarge if
+// r has more.
+type limitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if l.n <= 0 {
+		var b [1]byte
+		n, err := l.r.Read(b[:])
+		if n > 0 {
+			return 0, errTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
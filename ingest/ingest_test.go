@@ -0,0 +1,230 @@
+package ingest_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/ingest"
+)
+
+type galleryRow struct {
+	Title     string    `json:"title" binding:"required"`
+	Pages     int       `json:"pages" binding:"min=0"`
+	Rating    *float64  `json:"rating"`
+	Published time.Time `json:"published"`
+	Secret    string    `json:"-"`
+}
+
+// collect returns a RowFunc recording the rows it imports.
+func collect(rows *[]galleryRow) ingest.RowFunc[galleryRow] {
+	return func(ctx context.Context, row int, v *galleryRow) error {
+		*rows = append(*rows, *v)
+		return nil
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        ingest.Format
+		wantOK      bool
+	}{
+		{"text/csv", ingest.FormatCSV, true},
+		{"text/csv; charset=utf-8", ingest.FormatCSV, true},
+		{"application/x-ndjson", ingest.FormatNDJSON, true},
+		{"application/jsonl", ingest.FormatNDJSON, true},
+		{"application/json", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			got, ok := ingest.FormatOf(tt.contentType)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("expected %q %v, got %q %v", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	input := "\ufefftitle,pages,rating,published,extra,Secret\n" +
+		"Summer,12,4.5,2024-03-01,x,s\n" +
+		"\"Winter, 2023\",3,,2024-01-02T15:04:05Z,y,s\n"
+	var rows []galleryRow
+	res, err := ingest.Read(context.Background(), strings.NewReader(input), ingest.FormatCSV, 1, ingest.Config{}, collect(&rows))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.Object != "import_result" || res.Rows != 2 || res.Succeeded != 2 || res.Failed != 0 || !res.Complete {
+		t.Errorf("expected 2 complete rows, got %+v", res)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	if rows[0].Title != "Summer" || rows[0].Pages != 12 || rows[0].Rating == nil || *rows[0].Rating != 4.5 {
+		t.Errorf("expected the first row decoded, got %+v", rows[0])
+	}
+	if !rows[0].Published.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) || rows[0].Secret != "" {
+		t.Errorf("expected the date and no ignored field, got %+v", rows[0])
+	}
+	if rows[1].Title != "Winter, 2023" || rows[1].Rating != nil {
+		t.Errorf("expected a quoted title and a nil rating, got %+v", rows[1])
+	}
+}
+
+func TestReadRowErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    ingest.Format
+		input     string
+		wantField string
+		wantCode  string
+	}{
+		{"csv missing required", ingest.FormatCSV, "title,pages\n,3\n", "title", "missing_param"},
+		{"csv bad integer", ingest.FormatCSV, "title,pages\nSummer,many\n", "pages", "invalid_param"},
+		{"csv validation rule", ingest.FormatCSV, "title,pages\nSummer,-1\n", "pages", "invalid_param"},
+		{"csv column count", ingest.FormatCSV, "title,pages\nSummer\n", "", "invalid_row"},
+		{"csv bad quotes", ingest.FormatCSV, "title,pages\n\"Sum\"mer,1\n", "", "invalid_row"},
+		{"ndjson wrong type", ingest.FormatNDJSON, `{"title":"Summer","pages":"many"}`, "pages", "invalid_param"},
+		{"ndjson malformed", ingest.FormatNDJSON, `{"title":`, "", "invalid_row"},
+		{"ndjson missing required", ingest.FormatNDJSON, `{"pages":1}`, "title", "missing_param"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []galleryRow
+			res, err := ingest.Read(context.Background(), strings.NewReader(tt.input), tt.format, 1, ingest.Config{}, collect(&rows))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if res.Failed != 1 || res.Succeeded != 0 || len(rows) != 0 || !res.Complete {
+				t.Fatalf("expected one failed row, got %+v", res)
+			}
+			got := res.Errors[0]
+			if got.Row != 1 || got.Field != tt.wantField || got.Code != tt.wantCode || got.Message == "" {
+				t.Errorf("expected %s on %q in row 1, got %+v", tt.wantCode, tt.wantField, got)
+			}
+		})
+	}
+}
+
+func TestReadPartialFailure(t *testing.T) {
+	input := `{"title":"a"}` + "\n\n" + `{"pages":1}` + "\n" + `{"title":"c","pages":2}` + "\n" + `{"title":"taken"}`
+	var rows []galleryRow
+	res, err := ingest.Read(context.Background(), strings.NewReader(input), ingest.FormatNDJSON, 1, ingest.Config{},
+		func(ctx context.Context, row int, v *galleryRow) error {
+			if v.Title == "taken" {
+				return &ingest.RowError{Field: "title", Code: "already_exists", Message: "title is taken"}
+			}
+			rows = append(rows, *v)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.Rows != 4 || res.Succeeded != 2 || res.Failed != 2 || !res.Complete {
+		t.Errorf("expected 2 of 4 rows imported, got %+v", res)
+	}
+	if len(res.Errors) != 2 || res.Errors[0].Row != 2 || res.Errors[1].Row != 4 || res.Errors[1].Code != "already_exists" {
+		t.Errorf("expected rows 2 and 4 to fail, got %+v", res.Errors)
+	}
+	if len(rows) != 2 || rows[1].Title != "c" {
+		t.Errorf("expected the valid rows imported, got %+v", rows)
+	}
+}
+
+func TestReadLimits(t *testing.T) {
+	valid := "title\na\nb\nc\nd\n"
+	tests := []struct {
+		name          string
+		input         string
+		from          int
+		cfg           ingest.Config
+		wantRows      int
+		wantStoppedBy string
+		wantNextRow   int
+	}{
+		{"max rows", valid, 1, ingest.Config{MaxRows: 2}, 2, ingest.StopMaxRows, 3},
+		{"max rows at the end", valid, 1, ingest.Config{MaxRows: 4}, 4, "", 0},
+		{"max errors", "title\n\n,\n,\nd\n", 1, ingest.Config{MaxErrors: 2}, 2, ingest.StopMaxErrors, 3},
+		{"max bytes", valid, 1, ingest.Config{MaxBytes: 9}, 1, ingest.StopMaxBytes, 2},
+		{"resumed", valid, 3, ingest.Config{}, 2, "", 0},
+		{"resumed with max rows", valid, 2, ingest.Config{MaxRows: 2}, 2, ingest.StopMaxRows, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []galleryRow
+			res, err := ingest.Read(context.Background(), strings.NewReader(tt.input), ingest.FormatCSV, tt.from, tt.cfg, collect(&rows))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if res.Rows != tt.wantRows || res.StoppedBy != tt.wantStoppedBy || res.NextRow != tt.wantNextRow {
+				t.Errorf("expected %d rows stopped by %q at %d, got %+v", tt.wantRows, tt.wantStoppedBy, tt.wantNextRow, res)
+			}
+			if res.Complete != (tt.wantStoppedBy == "") {
+				t.Errorf("expected complete %v, got %+v", tt.wantStoppedBy == "", res)
+			}
+		})
+	}
+}
+
+func TestReadResumeSkipsRows(t *testing.T) {
+	var imported []int
+	_, err := ingest.Read(context.Background(), strings.NewReader("title\na\nb\nc\n"), ingest.FormatCSV, 2, ingest.Config{},
+		func(ctx context.Context, row int, v *galleryRow) error {
+			imported = append(imported, row)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(imported) != 2 || imported[0] != 2 || imported[1] != 3 {
+		t.Errorf("expected rows 2 and 3, got %v", imported)
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	res, err := ingest.Read(context.Background(), strings.NewReader("title\na\nb\n"), ingest.FormatCSV, 1, ingest.Config{Timeout: 10 * time.Millisecond},
+		func(ctx context.Context, row int, v *galleryRow) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.Rows != 0 || res.StoppedBy != ingest.StopTimeout || res.NextRow != 1 {
+		t.Errorf("expected a timeout at row 1, got %+v", res)
+	}
+}
+
+func TestReadAborts(t *testing.T) {
+	errDown := errors.New("database is down")
+	res, err := ingest.Read(context.Background(), strings.NewReader("title\na\nb\n"), ingest.FormatCSV, 1, ingest.Config{},
+		func(ctx context.Context, row int, v *galleryRow) error {
+			if row == 2 {
+				return errDown
+			}
+			return nil
+		})
+	if !errors.Is(err, errDown) {
+		t.Errorf("expected the row error, got %v", err)
+	}
+	if res.Succeeded != 1 || res.Complete {
+		t.Errorf("expected the first row imported, got %+v", res)
+	}
+}
+
+func TestReadEmpty(t *testing.T) {
+	for _, format := range []ingest.Format{ingest.FormatCSV, ingest.FormatNDJSON} {
+		var rows []galleryRow
+		res, err := ingest.Read(context.Background(), strings.NewReader(""), format, 1, ingest.Config{}, collect(&rows))
+		if err != nil || !res.Complete || res.Rows != 0 {
+			t.Errorf("%s: expected an empty complete import, got %+v, %v", format, res, err)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package ginapi
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/pagination"
+	"github.com/doujins-org/ginapi/response"
+	"github.com/doujins-org/ginapi/static"
+)
+
+// RouteMeta is metadata declared when registering a route with Handle.
+type RouteMeta struct {
+	// Scopes the principal needs, as enforced by the route's auth middleware
+	Scopes []string
+	// Deprecated marks routes scheduled for removal
+	Deprecated bool
+	// Summary is a one-line description
+	Summary string
+	// Name is the method name in generated clients (see package gen);
+	// routes without one are left out of them
+	Name string
+	// Request is a value of the request body type, e.g. CreateGallery{}
+	Request any
+	// Response is a value of the response object type, or of the item type
+	// with List
+	Response any
+	// List marks routes responding with a list of Response
+	List bool
+	// Tags group the route in generated documentation
+	Tags []string
+	// RateLimitPolicy names the rate limit rules covering the route; see
+	// RateLimitPolicy
+	RateLimitPolicy string
+	// MaxLimit caps the route's page size; Route enforces it
+	MaxLimit int
+	// ContentTypes are the request body types accepted, e.g.
+	// "application/json"; Route enforces them like
+	// middleware.RequireContentType
+	ContentTypes []string
+	// MaxBodyBytes caps the request body; Route enforces it, and reading
+	// past it fails
+	MaxBodyBytes int64
+	// Examples are sample responses, one per case worth documenting (the
+	// success and each error), served by Mock
+	Examples []Example
+	// Hints are resources browsers should preload for the page an HTML
+	// entry point serves, e.g. a language-prefixed shell; Route sends them
+	// like static.SendEarlyHints, except on routes with a Response (JSON)
+	Hints []static.Hint
+	// NoHEAD stops Handle registering a GET route for HEAD too, e.g. to
+	// register a HEAD handler of its own
+	NoHEAD bool
+}
+
+// RouteInfo describes a registered route for auditing.
+type RouteInfo struct {
+	Object     string   `json:"object"` // Always "route"
+	Method     string   `json:"method"`
+	Path       string   `json:"path"`
+	Handler    string   `json:"handler"`
+	Middleware []string `json:"middleware"`
+	Scopes     []string `json:"scopes"`
+	Deprecated bool     `json:"deprecated"`
+	Summary    string   `json:"summary,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	// RateLimitPolicy and MaxLimit are declared in RouteMeta
+	RateLimitPolicy string `json:"rate_limit_policy,omitempty"`
+	MaxLimit        int    `json:"max_limit,omitempty"`
+	// ContentTypes and MaxBodyBytes are declared in RouteMeta
+	ContentTypes []string `json:"content_types,omitempty"`
+	MaxBodyBytes int64    `json:"max_body_bytes,omitempty"`
+	// Pagination is the page size limits registered with
+	// pagination.SetRouteLimits, if any
+	Pagination *pagination.Limits `json:"pagination,omitempty"`
+	// Name, List, Request, and Response are the client metadata declared
+	// in RouteMeta
+	Name     string       `json:"name,omitempty"`
+	List     bool         `json:"list,omitempty"`
+	Request  reflect.Type `json:"-"`
+	Response reflect.Type `json:"-"`
+	// Examples are the sample responses declared in RouteMeta
+	Examples []Example `json:"examples,omitempty"`
+	// Hints are the Link header values of the hints declared in RouteMeta
+	Hints []string `json:"hints,omitempty"`
+}
+
+// registeredRoute is what Handle records about a route.
+type registeredRoute struct {
+	meta  RouteMeta
+	chain []string
+	// head marks the HEAD route Handle adds for a GET route
+	head bool
+}
+
+var (
+	routesMu sync.RWMutex
+	routes   = map[string]registeredRoute{} // "METHOD /path" -> route
+)
+
+// Handle registers handlers for method and relativePath on r, like r.Handle, and
+// records meta and the full middleware chain for Routes and RoutesHandler.
+// It panics if the chain breaks an OrderRule, so mis-ordered middleware
+// fails at startup rather than in production.
+// r should be the engine or a group, so the chain includes the middleware
+// added with Use; other gin.IRoutes only contribute their own handlers.
+//
+//	ginapi.Handle(admin, http.MethodDelete, "/galleries/:id", ginapi.RouteMeta{
+//	    Scopes: []string{"galleries:delete"},
+//	}, deleteGallery)
+//
+// GET routes also answer HEAD with the same handlers, as GETAndHEAD does,
+// unless meta has NoHEAD.
+func Handle(r gin.IRoutes, method, relativePath string, meta RouteMeta, handlers ...gin.HandlerFunc) {
+	fullPath := relativePath
+	var chain []string
+	if g := routerGroup(r); g != nil {
+		fullPath = joinPaths(g.BasePath(), relativePath)
+		chain = handlerNames(g.Handlers)
+	}
+	chain = append(chain, handlerNames(handlers)...)
+
+	if errs := checkChain(method, fullPath, chain); len(errs) > 0 {
+		panic(errors.Join(errs...))
+	}
+
+	routesMu.Lock()
+	routes[method+" "+fullPath] = registeredRoute{meta: meta, chain: chain}
+	head := method == http.MethodGet && !meta.NoHEAD
+	if head {
+		routes[http.MethodHead+" "+fullPath] = registeredRoute{meta: meta, chain: chain, head: true}
+	}
+	routesMu.Unlock()
+
+	r.Handle(method, relativePath, handlers...)
+	if head {
+		r.Handle(http.MethodHead, relativePath, handlers...)
+	}
+}
+
+// Routes lists every route registered on engine, sorted by path then
+// method. Routes registered through Handle include their middleware chain
+// and metadata; others only the engine's global middleware, and the
+// metadata of a handler wrapped with Route. The HEAD routes Handle adds
+// for GET routes are left out, as they are the GET routes.
+func Routes(engine *gin.Engine) []RouteInfo {
+	global := handlerNames(engine.Handlers)
+
+	routesMu.RLock()
+	defer routesMu.RUnlock()
+
+	infos := make([]RouteInfo, 0, len(engine.Routes()))
+	for _, r := range engine.Routes() {
+		info := RouteInfo{
+			Object:     "route",
+			Method:     r.Method,
+			Path:       r.Path,
+			Handler:    r.Handler,
+			Middleware: global,
+			Scopes:     []string{},
+		}
+		reg := routes[r.Method+" "+r.Path]
+		if reg.head {
+			continue
+		}
+		if n := len(reg.chain); n > 0 {
+			info.Middleware = reg.chain[:n-1]
+		}
+		if a, ok := annotation(r.HandlerFunc); ok {
+			info.Handler = a.name
+		}
+		meta, _ := routeMeta(reg.meta, r.HandlerFunc)
+		if meta.Scopes != nil {
+			info.Scopes = meta.Scopes
+		}
+		info.Deprecated = meta.Deprecated
+		info.Summary = meta.Summary
+		info.Tags = meta.Tags
+		info.RateLimitPolicy = meta.RateLimitPolicy
+		info.MaxLimit = meta.MaxLimit
+		info.ContentTypes = meta.ContentTypes
+		info.MaxBodyBytes = meta.MaxBodyBytes
+		info.Name = meta.Name
+		info.List = meta.List
+		info.Examples = meta.Examples
+		for _, h := range meta.Hints {
+			info.Hints = append(info.Hints, h.String())
+		}
+		if meta.Request != nil {
+			info.Request = reflect.TypeOf(meta.Request)
+		}
+		if meta.Response != nil {
+			info.Response = reflect.TypeOf(meta.Response)
+		}
+		if info.Middleware == nil {
+			info.Middleware = []string{}
+		}
+		if l, ok := pagination.RouteLimits(r.Path); ok {
+			info.Pagination = &l
+		}
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Path != infos[j].Path {
+			return infos[i].Path < infos[j].Path
+		}
+		return infos[i].Method < infos[j].Method
+	})
+	return infos
+}
+
+// RoutesHandler returns a handler listing the routes of engine, for
+// auditing what is actually exposed. Mount it behind admin auth:
+//
+//	admin.GET("/routes", ginapi.RoutesHandler(router))
+func RoutesHandler(engine *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		infos := Routes(engine)
+		response.ListResponse(c, infos, int64(len(infos)), len(infos), 0)
+	}
+}
+
+// routerGroup returns the group behind r, if it has one.
+func routerGroup(r gin.IRoutes) *gin.RouterGroup {
+	switch g := r.(type) {
+	case *gin.RouterGroup:
+		return g
+	case *gin.Engine:
+		return &g.RouterGroup
+	}
+	return nil
+}
+
+// handlerNames returns the function names of handlers, as gin reports them.
+// Layers wrapped by a LayerTimer keep the name of the handler they wrap.
+func handlerNames(handlers []gin.HandlerFunc) []string {
+	names := make([]string, len(handlers))
+	for i, h := range handlers {
+		if name, ok := wrappedName(h); ok {
+			names[i] = name
+			continue
+		}
+		names[i] = runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+	}
+	return names
+}
+
+// joinPaths joins a group base path and a relative path the way gin does.
+func joinPaths(base, rel string) string {
+	if rel == "" {
+		return base
+	}
+	joined := path.Join(base, rel)
+	if strings.HasSuffix(rel, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+	return joined
+}
@@ -0,0 +1,94 @@
+package ginapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+func requireAdmin(c *gin.Context) { c.Next() }
+
+func deleteGallery(c *gin.Context) { c.Status(http.StatusNoContent) }
+
+func TestRoutes(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.GET("/health", func(c *gin.Context) {})
+
+	admin := router.Group("/introspect/admin", requireAdmin)
+	ginapi.Handle(admin, http.MethodDelete, "/galleries/:id", ginapi.RouteMeta{
+		Scopes:     []string{"galleries:delete"},
+		Deprecated: true,
+	}, deleteGallery)
+
+	routes := ginapi.Routes(router)
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(routes))
+	}
+
+	health := routes[0]
+	if health.Path != "/health" || len(health.Scopes) != 0 || health.Deprecated {
+		t.Errorf("unexpected health route %+v", health)
+	}
+	if len(health.Middleware) != 1 || !strings.Contains(health.Middleware[0], "middleware.Recovery") {
+		t.Errorf("expected global middleware [Recovery], got %v", health.Middleware)
+	}
+
+	del := routes[1]
+	if del.Method != http.MethodDelete || del.Path != "/introspect/admin/galleries/:id" {
+		t.Errorf("unexpected route %s %s", del.Method, del.Path)
+	}
+	if !strings.HasSuffix(del.Handler, ".deleteGallery") {
+		t.Errorf("expected handler deleteGallery, got %s", del.Handler)
+	}
+	if len(del.Middleware) != 2 || !strings.HasSuffix(del.Middleware[1], ".requireAdmin") {
+		t.Errorf("expected middleware [Recovery requireAdmin], got %v", del.Middleware)
+	}
+	if len(del.Scopes) != 1 || del.Scopes[0] != "galleries:delete" || !del.Deprecated {
+		t.Errorf("expected scope and deprecation, got %+v", del)
+	}
+}
+
+func TestRoutesPaginationLimits(t *testing.T) {
+	router := gin.New()
+	router.GET("/introspect/search", func(c *gin.Context) {})
+	router.GET("/introspect/unlimited", func(c *gin.Context) {})
+	pagination.SetRouteLimits("/introspect/search", 10, 50)
+
+	routes := ginapi.Routes(router)
+	if l := routes[0].Pagination; l == nil || l.Default != 10 || l.Max != 50 {
+		t.Errorf("expected limits 10/50 on /introspect/search, got %+v", l)
+	}
+	if routes[1].Pagination != nil {
+		t.Errorf("expected no limits on /introspect/unlimited, got %+v", routes[1].Pagination)
+	}
+}
+
+func TestRoutesHandler(t *testing.T) {
+	router := gin.New()
+	router.GET("/routes", ginapi.RoutesHandler(router))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/routes", nil)
+	router.ServeHTTP(w, req)
+
+	var result struct {
+		Object string             `json:"object"`
+		Data   []ginapi.RouteInfo `json:"data"`
+		Total  int64              `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if result.Object != "list" || result.Total != 1 || result.Data[0].Object != "route" || result.Data[0].Path != "/routes" {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+}
@@ -0,0 +1,310 @@
+package ginapi
+
+import (
+	"math/rand/v2"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/metrics"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// MetricLayerDuration is the timing of a layer's own work per sampled
+// request, excluding the layers it calls.
+const MetricLayerDuration = "http.server.layer.duration"
+
+// Names of the layers a LayerTimer adds.
+const (
+	// RequestLayer is the whole request; its self time is what the timed
+	// layers don't account for
+	RequestLayer = "(request)"
+	// HandlerLayer is the route's handlers, and the middleware added to
+	// its groups after Instrument
+	HandlerLayer = "(handler)"
+)
+
+// layersKey is the gin context key of a sampled request's layerTrace.
+const layersKey = "ginapi.layers"
+
+// LayerTimerConfig configures a LayerTimer.
+type LayerTimerConfig struct {
+	// Sink receives a MetricLayerDuration timing per layer of each sampled
+	// request, tagged with the layer. Optional.
+	Sink metrics.Sink
+	// SampleRate is the fraction of requests timed (defaults to 0.01)
+	SampleRate float64
+}
+
+// LayerGraph is the layers a route's sampled requests went through, in
+// the order they were entered, with their average times.
+type LayerGraph struct {
+	Object  string        `json:"object"` // Always "layer_graph"
+	Route   string        `json:"route"`
+	Samples int64         `json:"samples"`
+	Layers  []LayerTiming `json:"layers"`
+}
+
+// LayerTiming is the average time of one layer of a route.
+type LayerTiming struct {
+	Layer string `json:"layer"`
+	// Depth is how many wrapped layers called into this one
+	Depth int `json:"depth"`
+	// SelfMS is the time spent in the layer itself, and TotalMS including
+	// the layers it called, in milliseconds per request
+	SelfMS  float64 `json:"self_ms"`
+	TotalMS float64 `json:"total_ms"`
+}
+
+// NewLayerTimer returns an opt-in timer measuring how long each
+// middleware layer takes for a sample of requests, to tell whether the
+// middleware stack or the handlers account for a route's latency. Install
+// its Middleware first, then the layers to time, and Instrument the group
+// so they are wrapped before routes are registered:
+//
+//	layers := ginapi.NewLayerTimer(ginapi.LayerTimerConfig{Sink: statsd})
+//	router.Use(layers.Middleware(), middleware.Recovery(), middleware.Language(langCfg), ...)
+//	layers.Instrument(&router.RouterGroup)
+//	... // register routes
+//	admin.GET("/layers", layers.Handler())
+//
+// Each layer's self time excludes the layers it calls; what runs after the
+// instrumented middleware is timed as HandlerLayer. Wrap times middleware
+// added later, e.g. to a group, as layers within it. Unsampled requests
+// only pay for a context lookup per layer. It panics if SampleRate is
+// outside [0, 1].
+func NewLayerTimer(cfg LayerTimerConfig) *LayerTimer {
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		panic("ginapi: LayerTimerConfig.SampleRate must be between 0 and 1")
+	}
+	if cfg.SampleRate == 0 {
+		cfg.SampleRate = 0.01
+	}
+	t := &LayerTimer{cfg: cfg, routes: map[string]*routeLayers{}}
+	t.middleware = t.sample
+	t.handler = t.wrap(HandlerLayer, func(c *gin.Context) { c.Next() })
+	wrappedMu.Lock()
+	wrapped[handlerID(t.handler)] = "github.com/doujins-org/ginapi.(*LayerTimer).Instrument"
+	wrappedMu.Unlock()
+	return t
+}
+
+// LayerTimer times the middleware layers of sampled requests; see
+// NewLayerTimer.
+type LayerTimer struct {
+	cfg        LayerTimerConfig
+	middleware gin.HandlerFunc
+	handler    gin.HandlerFunc // times HandlerLayer
+
+	mu     sync.Mutex
+	routes map[string]*routeLayers
+}
+
+// routeLayers sums the layer times of a route's sampled requests.
+type routeLayers struct {
+	samples int64
+	layers  []*layerSum
+}
+
+// layerSum sums the times of one layer.
+type layerSum struct {
+	name        string
+	depth       int
+	self, total time.Duration
+}
+
+// layerTrace records the layers of one sampled request.
+type layerTrace struct {
+	stack   []*layerRecord // the layers currently running
+	records []*layerRecord // every layer, in the order entered
+}
+
+// layerRecord is one layer's time in a request.
+type layerRecord struct {
+	name       string
+	depth      int
+	total, sub time.Duration // sub is the time of the layers it called
+	start      time.Time
+}
+
+// Middleware returns the middleware sampling requests. Install it before
+// the layers to time.
+func (t *LayerTimer) Middleware() gin.HandlerFunc {
+	return t.middleware
+}
+
+// sample traces sampled requests and records their layers.
+func (t *LayerTimer) sample(c *gin.Context) {
+	if rand.Float64() >= t.cfg.SampleRate {
+		c.Next()
+		return
+	}
+	trace := &layerTrace{}
+	c.Set(layersKey, trace)
+	root := trace.enter(RequestLayer)
+	c.Next()
+	trace.exit(root)
+
+	if route := c.FullPath(); route != "" {
+		t.record(route, trace.records)
+	}
+}
+
+// Wrap returns h timed as a layer named after its function. Routes and
+// the middleware order checks still see h's name.
+func (t *LayerTimer) Wrap(h gin.HandlerFunc) gin.HandlerFunc {
+	fn := handlerNames([]gin.HandlerFunc{h})[0]
+	w := t.wrap(layerName(fn), h)
+	wrappedMu.Lock()
+	wrapped[handlerID(w)] = fn
+	wrappedMu.Unlock()
+	return w
+}
+
+var (
+	wrappedMu sync.RWMutex
+	wrapped   = map[uintptr]string{} // handlerID -> name of the wrapped handler
+)
+
+// wrappedName returns the name of the handler h wraps, if Wrap returned h.
+func wrappedName(h gin.HandlerFunc) (string, bool) {
+	wrappedMu.RLock()
+	defer wrappedMu.RUnlock()
+	name, ok := wrapped[handlerID(h)]
+	return name, ok
+}
+
+// wrap returns h timed as layer name.
+func (t *LayerTimer) wrap(name string, h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, ok := c.Get(layersKey)
+		if !ok {
+			h(c)
+			return
+		}
+		trace := v.(*layerTrace)
+		r := trace.enter(name)
+		defer trace.exit(r)
+		h(c)
+	}
+}
+
+// Instrument wraps the middleware of g added so far, except the timer's
+// own, and times what follows as HandlerLayer, so the routes registered on
+// g afterwards time them. Call it once per group.
+func (t *LayerTimer) Instrument(g *gin.RouterGroup) {
+	own := handlerID(t.middleware)
+	for i, h := range g.Handlers {
+		if handlerID(h) != own {
+			g.Handlers[i] = t.Wrap(h)
+		}
+	}
+	g.Handlers = append(g.Handlers, t.handler)
+}
+
+// enter starts timing layer name.
+func (tr *layerTrace) enter(name string) *layerRecord {
+	r := &layerRecord{name: name, depth: len(tr.stack), start: time.Now()}
+	tr.stack = append(tr.stack, r)
+	tr.records = append(tr.records, r)
+	return r
+}
+
+// exit stops timing r, which must be the innermost running layer, and
+// charges its time to the layer that called it.
+func (tr *layerTrace) exit(r *layerRecord) {
+	r.total = time.Since(r.start)
+	tr.stack = tr.stack[:len(tr.stack)-1]
+	if n := len(tr.stack); n > 0 {
+		tr.stack[n-1].sub += r.total
+	}
+}
+
+// record adds a sampled request's layers to route and the sink.
+func (t *LayerTimer) record(route string, records []*layerRecord) {
+	if t.cfg.Sink != nil {
+		for _, r := range records {
+			t.cfg.Sink.Timing(MetricLayerDuration, r.total-r.sub, metrics.Tag{Key: "layer", Value: r.name})
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rl := t.routes[route]
+	if rl == nil {
+		rl = &routeLayers{}
+		t.routes[route] = rl
+	}
+	rl.samples++
+	for i, r := range records {
+		if i == len(rl.layers) {
+			rl.layers = append(rl.layers, &layerSum{name: r.name, depth: r.depth})
+		}
+		sum := rl.layers[i]
+		if sum.name != r.name || sum.depth != r.depth {
+			// A request that took another path, e.g. aborted early; time
+			// it against the layer of that name
+			sum = rl.find(r.name, r.depth)
+		}
+		sum.self += r.total - r.sub
+		sum.total += r.total
+	}
+}
+
+// find returns the sum of layer name at depth, adding it if missing.
+func (rl *routeLayers) find(name string, depth int) *layerSum {
+	for _, s := range rl.layers {
+		if s.name == name && s.depth == depth {
+			return s
+		}
+	}
+	s := &layerSum{name: name, depth: depth}
+	rl.layers = append(rl.layers, s)
+	return s
+}
+
+// Graphs returns the layer graphs of the routes sampled so far, by route.
+func (t *LayerTimer) Graphs() []LayerGraph {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	graphs := make([]LayerGraph, 0, len(t.routes))
+	for route, rl := range t.routes {
+		n := float64(rl.samples)
+		g := LayerGraph{Object: "layer_graph", Route: route, Samples: rl.samples, Layers: make([]LayerTiming, len(rl.layers))}
+		for i, s := range rl.layers {
+			g.Layers[i] = LayerTiming{
+				Layer:   s.name,
+				Depth:   s.depth,
+				SelfMS:  float64(s.self) / float64(time.Millisecond) / n,
+				TotalMS: float64(s.total) / float64(time.Millisecond) / n,
+			}
+		}
+		graphs = append(graphs, g)
+	}
+	sort.Slice(graphs, func(i, j int) bool { return graphs[i].Route < graphs[j].Route })
+	return graphs
+}
+
+// Handler returns a handler listing Graphs. Mount it behind admin auth.
+func (t *LayerTimer) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphs := t.Graphs()
+		response.ListResponse(c, graphs, int64(len(graphs)), len(graphs), 0)
+	}
+}
+
+// closureSuffix matches the ".func1" suffixes of closure names.
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+$`)
+
+// layerName shortens a handler's function name to its package and
+// function, e.g. "middleware.Recovery".
+func layerName(fn string) string {
+	if i := strings.LastIndexByte(fn, '/'); i >= 0 {
+		fn = fn[i+1:]
+	}
+	return closureSuffix.ReplaceAllString(fn, "")
+}
@@ -0,0 +1,101 @@
+package ginapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+)
+
+func slowLayer(c *gin.Context) {
+	time.Sleep(5 * time.Millisecond)
+	c.Next()
+}
+
+func fastLayer(c *gin.Context) {
+	c.Next()
+}
+
+func TestLayerTimer(t *testing.T) {
+	timer := ginapi.NewLayerTimer(ginapi.LayerTimerConfig{SampleRate: 1})
+	router := gin.New()
+	router.Use(timer.Middleware(), slowLayer, fastLayer)
+	timer.Instrument(&router.RouterGroup)
+	router.GET("/layers/galleries", func(c *gin.Context) {
+		time.Sleep(2 * time.Millisecond)
+		c.Status(http.StatusNoContent)
+	})
+
+	for range 2 {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/layers/galleries", nil))
+	}
+
+	graphs := timer.Graphs()
+	if len(graphs) != 1 || graphs[0].Route != "/layers/galleries" || graphs[0].Samples != 2 {
+		t.Fatalf("unexpected graphs %+v", graphs)
+	}
+	layers := graphs[0].Layers
+	wantLayers := []struct {
+		name  string
+		depth int
+	}{{ginapi.RequestLayer, 0}, {"ginapi_test.slowLayer", 1}, {"ginapi_test.fastLayer", 2}, {ginapi.HandlerLayer, 3}}
+	if len(layers) != len(wantLayers) {
+		t.Fatalf("expected %d layers, got %+v", len(wantLayers), layers)
+	}
+	for i, want := range wantLayers {
+		if layers[i].Layer != want.name || layers[i].Depth != want.depth {
+			t.Errorf("expected layer %d to be %s at depth %d, got %s at %d", i, want.name, want.depth, layers[i].Layer, layers[i].Depth)
+		}
+		if layers[i].SelfMS > layers[i].TotalMS {
+			t.Errorf("expected self time within total time, got %+v", layers[i])
+		}
+	}
+	if layers[1].SelfMS < 5 {
+		t.Errorf("expected slowLayer to take at least 5ms itself, got %v", layers[1].SelfMS)
+	}
+	if layers[3].SelfMS < 2 || layers[2].SelfMS >= 2 {
+		t.Errorf("expected the handler's time in %s, got %+v", ginapi.HandlerLayer, layers)
+	}
+	if layers[0].TotalMS < 7 {
+		t.Errorf("expected the request to take at least 7ms, got %v", layers[0].TotalMS)
+	}
+}
+
+func TestLayerTimerUnsampled(t *testing.T) {
+	timer := ginapi.NewLayerTimer(ginapi.LayerTimerConfig{SampleRate: 0.000001})
+	router := gin.New()
+	router.Use(timer.Middleware(), timer.Wrap(fastLayer))
+	router.GET("/layers/unsampled", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/layers/unsampled", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+	if graphs := timer.Graphs(); len(graphs) != 0 {
+		t.Errorf("expected no graphs, got %+v", graphs)
+	}
+}
+
+func TestLayerTimerKeepsHandlerNames(t *testing.T) {
+	timer := ginapi.NewLayerTimer(ginapi.LayerTimerConfig{})
+	router := gin.New()
+	router.Use(timer.Middleware(), fastLayer)
+	timer.Instrument(&router.RouterGroup)
+	ginapi.Handle(router, http.MethodGet, "/layers/names", ginapi.RouteMeta{}, func(c *gin.Context) {})
+
+	for _, r := range ginapi.Routes(router) {
+		if r.Path != "/layers/names" {
+			continue
+		}
+		if len(r.Middleware) < 2 || r.Middleware[1] != "github.com/doujins-org/ginapi_test.fastLayer" {
+			t.Errorf("expected the wrapped middleware's name, got %v", r.Middleware)
+		}
+		return
+	}
+	t.Error("expected the route to be listed")
+}
@@ -0,0 +1,196 @@
+package ginapi
+
+import (
+	"net/http"
+	"sync"
+	"unsafe"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/pagination"
+	"github.com/doujins-org/ginapi/response"
+	"github.com/doujins-org/ginapi/static"
+)
+
+// Meta is the route metadata declared with Route. It is RouteMeta, so the
+// same declaration works with Handle.
+type Meta = RouteMeta
+
+// annotatedHandler is what Route records about a handler it wrapped.
+type annotatedHandler struct {
+	meta Meta
+	name string // the wrapped handler's name
+}
+
+var (
+	annotatedMu sync.RWMutex
+	annotated   = map[uintptr]annotatedHandler{} // handlerID -> wrapped handler
+)
+
+// Route returns handler annotated with meta, for registering with plain
+// gin methods:
+//
+//	galleries.GET("/search", ginapi.Route(searchGalleries, ginapi.Meta{
+//	    Summary:         "Search galleries",
+//	    Scopes:          []string{"galleries:read"},
+//	    RateLimitPolicy: "search",
+//	    MaxLimit:        50,
+//	    Tags:            []string{"galleries"},
+//	}))
+//
+// The one declaration is enforced and published: requests without every
+// scope in Scopes are rejected (401 auth_required when anonymous, 403
+// insufficient_permission otherwise), bodies not of a type in
+// ContentTypes are rejected with 415, bodies are capped at MaxBodyBytes,
+// page sizes are capped at MaxLimit (see pagination.SetMaxLimit),
+// RateLimitPolicy selects the route's rate limit rules (see
+// RateLimitPolicy), Hints are sent as Link headers and 103 Early Hints to
+// clients preferring HTML, and Routes lists the route with its metadata and
+// handler's name. Middleware reads it with MetaOf.
+func Route(handler gin.HandlerFunc, meta Meta) gin.HandlerFunc {
+	h := func(c *gin.Context) {
+		if len(meta.Scopes) > 0 && !requireScopes(c, meta.Scopes) {
+			return
+		}
+		if len(meta.ContentTypes) > 0 && !middleware.CheckContentType(c, meta.ContentTypes...) {
+			c.Abort()
+			return
+		}
+		if meta.MaxBodyBytes > 0 && c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, meta.MaxBodyBytes)
+		}
+		if meta.MaxLimit > 0 {
+			pagination.SetMaxLimit(c, meta.MaxLimit)
+		}
+		if len(meta.Hints) > 0 && meta.Response == nil {
+			static.SendEarlyHints(c, meta.Hints...)
+		}
+		handler(c)
+	}
+	annotatedMu.Lock()
+	annotated[handlerID(h)] = annotatedHandler{meta: meta, name: handlerNames([]gin.HandlerFunc{handler})[0]}
+	annotatedMu.Unlock()
+	return h
+}
+
+// requireScopes rejects the request unless its principal has every scope.
+func requireScopes(c *gin.Context, scopes []string) bool {
+	p, ok := auth.GetPrincipal(c)
+	if !ok || p.Type == auth.TypeGuest {
+		response.ErrorWithInfo(c, http.StatusUnauthorized, response.ErrorInfo{
+			Type:    response.ErrorTypeAuthentication,
+			Code:    response.ErrorCodeAuthRequired,
+			Message: "authentication required",
+		})
+		c.Abort()
+		return false
+	}
+	for _, scope := range scopes {
+		if !p.HasScope(scope) {
+			response.ErrorWithInfo(c, http.StatusForbidden, response.ErrorInfo{
+				Type:    response.ErrorTypeForbidden,
+				Code:    response.ErrorCodeInsufficientPermission,
+				Message: "missing scope " + scope,
+			})
+			c.Abort()
+			return false
+		}
+	}
+	return true
+}
+
+// MetaOf returns the metadata of the route serving c, declared with Route
+// or Handle. It works in middleware too, before the handler runs.
+func MetaOf(c *gin.Context) (Meta, bool) {
+	routesMu.RLock()
+	reg, registered := routes[c.Request.Method+" "+c.FullPath()]
+	routesMu.RUnlock()
+	meta, annotated := routeMeta(reg.meta, c.Handler())
+	return meta, registered || annotated
+}
+
+// RateLimitPolicy returns a middleware.RateLimitRule Match function
+// selecting the routes whose Meta.RateLimitPolicy is name, so rules are
+// declared once per policy rather than per route:
+//
+//	{Name: "search", Dimensions: []middleware.Dimension{middleware.ByIP()}, Limit: 30, Window: time.Minute,
+//	    Match: ginapi.RateLimitPolicy("search")},
+func RateLimitPolicy(name string) func(c *gin.Context) bool {
+	return func(c *gin.Context) bool {
+		meta, ok := MetaOf(c)
+		return ok && meta.RateLimitPolicy == name
+	}
+}
+
+// routeMeta merges the metadata declared with Handle and with Route for a
+// route whose final handler is h, reporting whether h came from Route.
+func routeMeta(meta Meta, h gin.HandlerFunc) (Meta, bool) {
+	a, ok := annotation(h)
+	if !ok {
+		return meta, false
+	}
+	return mergeMeta(meta, a.meta), true
+}
+
+// annotation returns what Route recorded about h, if it returned h.
+func annotation(h gin.HandlerFunc) (annotatedHandler, bool) {
+	if h == nil {
+		return annotatedHandler{}, false
+	}
+	annotatedMu.RLock()
+	defer annotatedMu.RUnlock()
+	a, ok := annotated[handlerID(h)]
+	return a, ok
+}
+
+// handlerID identifies a func value. The closures Route returns share
+// their code, so reflect can't tell them apart, but each is a separate
+// allocation: a func value is a pointer to it.
+func handlerID(h gin.HandlerFunc) uintptr {
+	return *(*uintptr)(unsafe.Pointer(&h))
+}
+
+// mergeMeta fills the zero fields of meta, declared with Handle, from
+// those declared with Route.
+func mergeMeta(meta, from Meta) Meta {
+	if meta.Scopes == nil {
+		meta.Scopes = from.Scopes
+	}
+	meta.Deprecated = meta.Deprecated || from.Deprecated
+	if meta.Summary == "" {
+		meta.Summary = from.Summary
+	}
+	if meta.Name == "" {
+		meta.Name = from.Name
+	}
+	if meta.Request == nil {
+		meta.Request = from.Request
+	}
+	if meta.Response == nil {
+		meta.Response, meta.List = from.Response, from.List
+	}
+	if meta.RateLimitPolicy == "" {
+		meta.RateLimitPolicy = from.RateLimitPolicy
+	}
+	if meta.MaxLimit == 0 {
+		meta.MaxLimit = from.MaxLimit
+	}
+	if meta.Tags == nil {
+		meta.Tags = from.Tags
+	}
+	if meta.ContentTypes == nil {
+		meta.ContentTypes = from.ContentTypes
+	}
+	if meta.MaxBodyBytes == 0 {
+		meta.MaxBodyBytes = from.MaxBodyBytes
+	}
+	if meta.Examples == nil {
+		meta.Examples = from.Examples
+	}
+	if meta.Hints == nil {
+		meta.Hints = from.Hints
+	}
+	return meta
+}
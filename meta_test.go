@@ -0,0 +1,195 @@
+package ginapi_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/pagination"
+	"github.com/doujins-org/ginapi/static"
+)
+
+func searchGalleries(c *gin.Context) {
+	c.String(http.StatusOK, strconv.Itoa(pagination.BindDefault(c).Limit))
+}
+
+func TestRoute(t *testing.T) {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if scopes := c.GetHeader("X-Scopes"); scopes != "" {
+			auth.SetPrincipal(c, auth.Principal{ID: "u1", Type: auth.TypeUser, Scopes: strings.Split(scopes, ",")})
+		}
+		c.Next()
+	})
+	router.Use(middleware.RateLimit(middleware.RateLimitConfig{
+		Rules: []middleware.RateLimitRule{{
+			Name:       "search",
+			Dimensions: []middleware.Dimension{middleware.ByIP()},
+			Limit:      4,
+			Window:     time.Minute,
+			Match:      ginapi.RateLimitPolicy("search"),
+		}},
+	}))
+	router.GET("/meta/search", ginapi.Route(searchGalleries, ginapi.Meta{
+		Scopes:          []string{"galleries:read"},
+		RateLimitPolicy: "search",
+		MaxLimit:        50,
+	}))
+	router.GET("/meta/popular", ginapi.Route(searchGalleries, ginapi.Meta{}))
+
+	tests := []struct {
+		name       string
+		path       string
+		scopes     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"anonymous", "/meta/search", "", http.StatusUnauthorized, ""},
+		{"missing scope", "/meta/search", "galleries:write", http.StatusForbidden, ""},
+		{"max limit", "/meta/search?limit=80", "galleries:read", http.StatusOK, "50"},
+		{"within max limit", "/meta/search?limit=30", "galleries:read", http.StatusOK, "30"},
+		{"rate limited by policy", "/meta/search", "galleries:read", http.StatusTooManyRequests, ""},
+		{"other policy", "/meta/popular?limit=80", "", http.StatusOK, "80"},
+		{"other policy not limited", "/meta/popular", "", http.StatusOK, "20"},
+		{"other policy still not limited", "/meta/popular", "", http.StatusOK, "20"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.scopes != "" {
+				req.Header.Set("X-Scopes", tt.scopes)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("expected body %s, got %s", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouteIntrospection(t *testing.T) {
+	router := gin.New()
+	var seen ginapi.Meta
+	router.Use(func(c *gin.Context) {
+		seen, _ = ginapi.MetaOf(c)
+		c.Next()
+	})
+	router.GET("/meta/galleries", ginapi.Route(searchGalleries, ginapi.Meta{
+		Summary: "Search galleries",
+		Tags:    []string{"galleries"},
+	}))
+	ginapi.Handle(router, http.MethodDelete, "/meta/galleries/:id", ginapi.RouteMeta{
+		Scopes: []string{"galleries:delete"},
+	}, ginapi.Route(deleteGallery, ginapi.Meta{Summary: "Delete a gallery", Deprecated: true}))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/meta/galleries", nil))
+	if seen.Summary != "Search galleries" {
+		t.Errorf("expected MetaOf to return the route's metadata, got %+v", seen)
+	}
+
+	routes := ginapi.Routes(router)
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(routes))
+	}
+	get, del := routes[0], routes[1]
+	if !strings.HasSuffix(get.Handler, ".searchGalleries") {
+		t.Errorf("expected handler searchGalleries, got %s", get.Handler)
+	}
+	if get.Summary != "Search galleries" || len(get.Tags) != 1 || get.Tags[0] != "galleries" {
+		t.Errorf("expected summary and tags, got %+v", get)
+	}
+	if !strings.HasSuffix(del.Handler, ".deleteGallery") {
+		t.Errorf("expected handler deleteGallery, got %s", del.Handler)
+	}
+	if del.Summary != "Delete a gallery" || !del.Deprecated || len(del.Scopes) != 1 || del.Scopes[0] != "galleries:delete" {
+		t.Errorf("expected metadata merged from Handle and Route, got %+v", del)
+	}
+}
+
+func TestRouteBody(t *testing.T) {
+	router := gin.New()
+	router.POST("/meta/body", ginapi.Route(func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.Status(http.StatusCreated)
+	}, ginapi.Meta{ContentTypes: []string{"application/json"}, MaxBodyBytes: 8}))
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{"accepted", "application/json", `{"a":1}`, http.StatusCreated},
+		{"wrong content type", "text/plain", `{"a":1}`, http.StatusUnsupportedMediaType},
+		{"too large", "application/json", `{"a":"long"}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/meta/body", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestRouteHints(t *testing.T) {
+	hints := []static.Hint{{URL: "/assets/app.js"}}
+	router := gin.New()
+	router.GET("/:lang/galleries", ginapi.Route(func(c *gin.Context) {
+		c.String(http.StatusOK, "<html>app</html>")
+	}, ginapi.Meta{Hints: hints}))
+	router.GET("/api/galleries", ginapi.Route(func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}, ginapi.Meta{Hints: hints, Response: struct{}{}}))
+
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		wantLink string
+	}{
+		{"browser", "/ja/galleries", "text/html,application/xhtml+xml,*/*;q=0.8", "</assets/app.js>; rel=preload; as=script"},
+		{"no accept", "/ja/galleries", "", "</assets/app.js>; rel=preload; as=script"},
+		{"json client", "/ja/galleries", "application/json", ""},
+		{"json route", "/api/galleries", "text/html", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Link"); got != tt.wantLink {
+				t.Errorf("expected Link %q, got %q", tt.wantLink, got)
+			}
+		})
+	}
+}
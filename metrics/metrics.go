@@ -0,0 +1,59 @@
+// Package metrics sends application metrics to a pluggable backend. A
+// Sink takes counters, gauges, and timings with tags; StatsD sends them to
+// a StatsD server or a DataDog agent, and middleware.Metrics records
+// request metrics with any Sink:
+//
+//	sink, err := metrics.NewStatsD(metrics.StatsDConfig{
+//	    Addr:   "127.0.0.1:8125",
+//	    Prefix: "api.",
+//	    Tags:   []metrics.Tag{{Key: "env", Value: "prod"}},
+//	})
+//	...
+//	defer sink.Close()
+//	router.Use(middleware.Metrics(sink))
+package metrics
+
+import "time"
+
+// Sink receives metrics. Implementations must be safe for concurrent use
+// and must not block: metrics are dropped rather than slow requests.
+type Sink interface {
+	// Count adds value to the counter name
+	Count(name string, value int64, tags ...Tag)
+	// Gauge sets the gauge name to value
+	Gauge(name string, value float64, tags ...Tag)
+	// Timing records d in the distribution of durations name
+	Timing(name string, d time.Duration, tags ...Tag)
+}
+
+// Histogrammer is implemented by sinks that record distributions of
+// values other than durations, such as byte sizes. StatsD does; Sink
+// doesn't require it so existing sinks keep working.
+type Histogrammer interface {
+	// Histogram records value in the distribution name
+	Histogram(name string, value float64, tags ...Tag)
+}
+
+// Histogram records value in the distribution name if sink is a
+// Histogrammer, and drops it otherwise.
+func Histogram(sink Sink, name string, value float64, tags ...Tag) {
+	if h, ok := sink.(Histogrammer); ok {
+		h.Histogram(name, value, tags...)
+	}
+}
+
+// Tag is a dimension of a metric, e.g. route:/galleries/:id.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Discard is a Sink that drops every metric.
+var Discard Sink = discard{}
+
+type discard struct{}
+
+func (discard) Count(string, int64, ...Tag)          {}
+func (discard) Gauge(string, float64, ...Tag)        {}
+func (discard) Timing(string, time.Duration, ...Tag) {}
+func (discard) Histogram(string, float64, ...Tag)    {}
@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsDConfig configures a StatsD sink.
+type StatsDConfig struct {
+	// Addr is the UDP address of the server or agent (defaults to
+	// "127.0.0.1:8125", the DataDog agent's)
+	Addr string
+	// Prefix is prepended to metric names, e.g. "api."
+	Prefix string
+	// Tags are added to every metric, e.g. env:prod
+	Tags []Tag
+	// Plain leaves tags out, for StatsD servers without the DogStatsD tag
+	// extension
+	Plain bool
+	// MaxPacketSize is the largest datagram sent (defaults to 1432 bytes,
+	// which fits an Ethernet MTU)
+	MaxPacketSize int
+	// FlushInterval is how often buffered metrics are sent (defaults to
+	// 100ms)
+	FlushInterval time.Duration
+}
+
+// StatsD is a Sink sending metrics over UDP in the StatsD line format,
+// with tags in the DogStatsD format ("|#key:value,...") unless Plain.
+// Metrics are batched into datagrams of up to MaxPacketSize bytes and sent
+// every FlushInterval, or sooner when a datagram fills. Send errors are
+// ignored, since the server may be down.
+type StatsD struct {
+	conn   net.Conn
+	prefix string
+	tags   string
+	plain  bool
+	max    int
+
+	mu   sync.Mutex
+	buf  []byte
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// NewStatsD returns a StatsD sink for cfg. Close it to send the last
+// metrics.
+func NewStatsD(cfg StatsDConfig) (*StatsD, error) {
+	if cfg.Addr == "" {
+		cfg.Addr = "127.0.0.1:8125"
+	}
+	if cfg.MaxPacketSize <= 0 {
+		cfg.MaxPacketSize = 1432
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 100 * time.Millisecond
+	}
+	conn, err := net.Dial("udp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &StatsD{
+		conn:   conn,
+		prefix: cfg.Prefix,
+		plain:  cfg.Plain,
+		max:    cfg.MaxPacketSize,
+		done:   make(chan struct{}),
+	}
+	s.tags = string(appendTags(nil, cfg.Tags))
+	s.wg.Add(1)
+	go s.run(cfg.FlushInterval)
+	return s, nil
+}
+
+func (s *StatsD) Count(name string, value int64, tags ...Tag) {
+	s.send(name, strconv.AppendInt(nil, value, 10), "c", tags)
+}
+
+func (s *StatsD) Gauge(name string, value float64, tags ...Tag) {
+	s.send(name, strconv.AppendFloat(nil, value, 'f', -1, 64), "g", tags)
+}
+
+func (s *StatsD) Timing(name string, d time.Duration, tags ...Tag) {
+	ms := float64(d) / float64(time.Millisecond)
+	s.send(name, strconv.AppendFloat(nil, ms, 'f', -1, 64), "ms", tags)
+}
+
+// Histogram sends value as a StatsD histogram ("h"), which the DataDog
+// agent aggregates into percentiles.
+func (s *StatsD) Histogram(name string, value float64, tags ...Tag) {
+	s.send(name, strconv.AppendFloat(nil, value, 'f', -1, 64), "h", tags)
+}
+
+// Flush sends the buffered metrics now.
+func (s *StatsD) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+// Close sends the buffered metrics and closes the connection.
+func (s *StatsD) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		err = s.Flush()
+		if cerr := s.conn.Close(); err == nil {
+			err = cerr
+		}
+	})
+	return err
+}
+
+// run flushes every interval until Close.
+func (s *StatsD) run(interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = s.Flush()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// send buffers the line "<prefix><name>:<value>|<kind>|#<tags>".
+func (s *StatsD) send(name string, value []byte, kind string, tags []Tag) {
+	line := make([]byte, 0, 64)
+	line = append(line, s.prefix...)
+	line = appendName(line, name)
+	line = append(line, ':')
+	line = append(line, value...)
+	line = append(line, '|')
+	line = append(line, kind...)
+	if !s.plain && (s.tags != "" || len(tags) > 0) {
+		line = append(line, "|#"...)
+		line = append(line, s.tags...)
+		if s.tags != "" && len(tags) > 0 {
+			line = append(line, ',')
+		}
+		line = appendTags(line, tags)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.buf) > 0 && len(s.buf)+1+len(line) > s.max {
+		_ = s.flush()
+	}
+	if len(s.buf) > 0 {
+		s.buf = append(s.buf, '\n')
+	}
+	s.buf = append(s.buf, line...)
+}
+
+// flush sends the buffer; s.mu must be held.
+func (s *StatsD) flush() error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+	_, err := s.conn.Write(s.buf)
+	s.buf = s.buf[:0]
+	return err
+}
+
+// appendName appends name with the characters the line format reserves
+// replaced by underscores.
+func appendName(b []byte, name string) []byte {
+	return append(b, strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '\n':
+			return '_'
+		}
+		return r
+	}, name)...)
+}
+
+// appendTags appends tags as "key:value,...", with the characters the
+// DogStatsD format reserves in tags replaced by underscores.
+func appendTags(b []byte, tags []Tag) []byte {
+	for i, t := range tags {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, tagReplacer.Replace(t.Key)...)
+		if t.Value != "" {
+			b = append(b, ':')
+			b = append(b, tagReplacer.Replace(t.Value)...)
+		}
+	}
+	return b
+}
+
+var tagReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
@@ -0,0 +1,125 @@
+package metrics_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/metrics"
+)
+
+// listen returns a UDP listener and a function reading one datagram.
+func listen(t *testing.T) (string, func() string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	read := func() string {
+		t.Helper()
+		buf := make([]byte, 65536)
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("expected a datagram, got %v", err)
+		}
+		return string(buf[:n])
+	}
+	return conn.LocalAddr().String(), read
+}
+
+func TestStatsD(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  metrics.StatsDConfig
+		send func(s *metrics.StatsD)
+		want string
+	}{
+		{
+			name: "dogstatsd tags",
+			cfg:  metrics.StatsDConfig{Prefix: "api.", Tags: []metrics.Tag{{Key: "env", Value: "prod"}}},
+			send: func(s *metrics.StatsD) {
+				s.Count("requests", 1, metrics.Tag{Key: "route", Value: "/galleries/:id"})
+				s.Gauge("queue.depth", 2.5)
+				s.Timing("latency", 1500*time.Microsecond)
+				s.Histogram("response.size", 2048)
+			},
+			want: "api.requests:1|c|#env:prod,route:/galleries/:id\napi.queue.depth:2.5|g|#env:prod\napi.latency:1.5|ms|#env:prod\napi.response.size:2048|h|#env:prod",
+		},
+		{
+			name: "plain",
+			cfg:  metrics.StatsDConfig{Plain: true, Tags: []metrics.Tag{{Key: "env", Value: "prod"}}},
+			send: func(s *metrics.StatsD) {
+				s.Count("requests", 3, metrics.Tag{Key: "route", Value: "/"})
+			},
+			want: "requests:3|c",
+		},
+		{
+			name: "reserved characters",
+			send: func(s *metrics.StatsD) {
+				s.Count("a:b|c", 1, metrics.Tag{Key: "q", Value: "x,y|z#"}, metrics.Tag{Key: "flag"})
+			},
+			want: "a_b_c:1|c|#q:x_y_z_,flag",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, read := listen(t)
+			tt.cfg.Addr = addr
+			tt.cfg.FlushInterval = time.Hour
+			s, err := metrics.NewStatsD(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			tt.send(s)
+			if err := s.Flush(); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got := read(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStatsDPacketSize(t *testing.T) {
+	addr, read := listen(t)
+	s, err := metrics.NewStatsD(metrics.StatsDConfig{Addr: addr, MaxPacketSize: 40, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.Count("first.counter", 1)  // 17 bytes
+	s.Count("second.counter", 1) // 18 bytes, fits with the newline
+	s.Count("third.counter", 1)  // sent in the next datagram
+
+	if got := read(); got != "first.counter:1|c\nsecond.counter:1|c" {
+		t.Errorf("expected the first two lines, got %q", got)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := read(); got != "third.counter:1|c" {
+		t.Errorf("expected Close to flush the last line, got %q", got)
+	}
+}
+
+func TestStatsDFlushInterval(t *testing.T) {
+	addr, read := listen(t)
+	s, err := metrics.NewStatsD(metrics.StatsDConfig{Addr: addr, FlushInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.Count("requests", 1)
+	if got := read(); !strings.HasPrefix(got, "requests:1|c") {
+		t.Errorf("expected a periodic flush, got %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// RequireAcceptable returns middleware that rejects requests whose Accept
+// header rules out every media type the routes below can produce, with a
+// 406 listing them. Requests without an Accept header are allowed.
+//
+//	api.Use(middleware.RequireAcceptable("application/json", response.MsgpackMediaType))
+func RequireAcceptable(offers ...string) gin.HandlerFunc {
+	if len(offers) == 0 {
+		panic("middleware: RequireAcceptable requires at least one media type")
+	}
+	return func(c *gin.Context) {
+		if _, ok := response.Acceptable(c.Request, offers...); !ok {
+			response.NotAcceptable(c, offers...)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestRequireAcceptable(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.RequireAcceptable("application/json", response.MsgpackMediaType))
+	router.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		accept     string
+		wantStatus int
+	}{
+		{"", http.StatusOK},
+		{"application/json", http.StatusOK},
+		{"application/*", http.StatusOK},
+		{"*/*", http.StatusOK},
+		{"text/html, application/json;q=0.1", http.StatusOK},
+		{"text/html", http.StatusNotAcceptable},
+		{"application/json;q=0, text/*", http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("Accept %q: expected status %d, got %d", tt.accept, tt.wantStatus, w.Code)
+		}
+		if tt.wantStatus != http.StatusNotAcceptable {
+			continue
+		}
+		var result response.Error
+		_ = json.Unmarshal(w.Body.Bytes(), &result)
+		if result.Error.Code != response.ErrorCodeNotAcceptable || !strings.Contains(result.Error.Message, response.MsgpackMediaType) {
+			t.Errorf("Accept %q: expected not_acceptable listing the media types, got %s", tt.accept, w.Body.String())
+		}
+		if !strings.Contains(w.Header().Get("Vary"), "Accept") {
+			t.Errorf("Accept %q: expected Vary: Accept, got %q", tt.accept, w.Header().Get("Vary"))
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"container/list"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ParseAcceptLanguage parses the Accept-Language header and returns the best
+// supported language based on q-values. Exported for use by redirect middleware.
+//
+// Each range matches its longest supported prefix, so "pt-BR" is "pt-br"
+// when that is supported and "pt" otherwise, and "zh-Hant-TW" tries
+// "zh-hant-tw", "zh-hant", then "zh". Ranges with equal q-values rank in
+// header order (RFC 9110, section 12.4.2), so "en;q=0.8, ja;q=0.8" is
+// "en" whatever the supported set.
+//
+// It runs on every request without a language cookie, so it scans the header
+// in place and does not allocate unless a matching tag has uppercase letters.
+func ParseAcceptLanguage(header string, supported map[string]struct{}) string {
+	var scratch [8]byte
+	var best string
+	bestQ := -1.0
+
+	for start := 0; start <= len(header); {
+		end := strings.IndexByte(header[start:], ',')
+		if end < 0 {
+			end = len(header)
+		} else {
+			end += start
+		}
+		tag, q := parseLanguageRange(header[start:end])
+		start = end + 1
+
+		// Only a higher q beats an earlier range; q=0 means "not acceptable"
+		if tag == "" || q <= 0 || q <= bestQ {
+			continue
+		}
+		if lang := supportedPrefix(supported, tag, &scratch); lang != "" {
+			best, bestQ = lang, q
+		}
+	}
+
+	return strings.ToLower(best)
+}
+
+// parseLanguageRange returns the language tag and q-value of one
+// Accept-Language entry (e.g. "en-US;q=0.9" -> "en-US", 0.9), in its
+// original case.
+func parseLanguageRange(part string) (string, float64) {
+	part = strings.TrimSpace(part)
+	lang := part
+	q := 1.0
+
+	// Parse q-value if present (e.g., "en-US;q=0.9")
+	if idx := strings.IndexByte(part, ';'); idx >= 0 {
+		lang = part[:idx]
+		param := strings.TrimSpace(part[idx+1:])
+		if v, ok := strings.CutPrefix(param, "q="); ok {
+			// Malformed or out-of-range values (including NaN) are ignored
+			if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+				q = f
+			}
+		}
+	}
+	return strings.TrimSpace(lang), q
+}
+
+// supportedPrefix returns the longest prefix of tag, cut at hyphens, that
+// is supported ("zh-Hant-TW", then "zh-Hant", then "zh"), in its
+// original case, or "" if none is.
+func supportedPrefix(supported map[string]struct{}, tag string, scratch *[8]byte) string {
+	for {
+		if isSupported(supported, tag, scratch) {
+			return tag
+		}
+		hyphen := strings.LastIndexByte(tag, '-')
+		if hyphen < 0 {
+			return ""
+		}
+		tag = tag[:hyphen]
+	}
+}
+
+// isSupported reports whether the lowercase form of lang is in supported,
+// lowercasing short ASCII tags into scratch to avoid allocating.
+func isSupported(supported map[string]struct{}, lang string, scratch *[8]byte) bool {
+	if len(lang) > len(scratch) {
+		_, ok := supported[strings.ToLower(lang)]
+		return ok
+	}
+	for i := 0; i < len(lang); i++ {
+		b := lang[i]
+		if b >= 0x80 {
+			_, ok := supported[strings.ToLower(lang)]
+			return ok
+		}
+		if 'A' <= b && b <= 'Z' {
+			b += 'a' - 'A'
+		}
+		scratch[i] = b
+	}
+	_, ok := supported[string(scratch[:len(lang)])]
+	return ok
+}
+
+const (
+	// acceptLanguageCacheSize is the number of distinct headers cached per
+	// Language middleware. Browsers send a few hundred distinct values at most.
+	acceptLanguageCacheSize = 1024
+	// maxCachedAcceptLanguage is the longest header cached, so oversized
+	// headers can't pin memory.
+	maxCachedAcceptLanguage = 256
+)
+
+// acceptLanguageCache is a bounded LRU of ParseAcceptLanguage results for a
+// fixed supported set, keyed by the raw header.
+type acceptLanguageCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   list.List // front is most recently used
+}
+
+type acceptLanguageEntry struct {
+	header string
+	lang   string
+}
+
+func newAcceptLanguageCache(size int) *acceptLanguageCache {
+	return &acceptLanguageCache{size: size, entries: make(map[string]*list.Element)}
+}
+
+// parse returns ParseAcceptLanguage(header, supported), from the cache when
+// the same header was seen recently. supported must not change between calls.
+func (c *acceptLanguageCache) parse(header string, supported map[string]struct{}) string {
+	if len(header) > maxCachedAcceptLanguage {
+		return ParseAcceptLanguage(header, supported)
+	}
+
+	c.mu.Lock()
+	if el, ok := c.entries[header]; ok {
+		c.order.MoveToFront(el)
+		lang := el.Value.(*acceptLanguageEntry).lang
+		c.mu.Unlock()
+		return lang
+	}
+	c.mu.Unlock()
+
+	lang := ParseAcceptLanguage(header, supported)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[header]; ok {
+		return lang
+	}
+	c.entries[header] = c.order.PushFront(&acceptLanguageEntry{header: header, lang: lang})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*acceptLanguageEntry).header)
+	}
+	return lang
+}
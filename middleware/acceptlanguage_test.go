@@ -0,0 +1,142 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	supported := middleware.BuildSupportedMap([]string{"en", "ja", "zh"})
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"ja", "ja"},
+		{"JA-jp", "ja"},
+		{"fr, ja;q=0.5, en;q=0.8", "en"},
+		{"en;q=0.5,ja;q=0.5", "en"},
+		{" zh-Hant-TW ; q=0.9 , fr", "zh"},
+		{"ja;q=bogus, en;q=0.9", "ja"},
+		{"fr, de", ""},
+		{",,;q=1,-", ""},
+		{"verylongtag, ja;q=0.1", "ja"},
+		{"*", ""},
+		{"ja;q=0, en;q=0.1", "en"},
+		{"en;q=0", ""},
+		{"ja;q=NaN, en;q=0.9", "ja"},
+		{"ja;q=5, en", "ja"},
+		{"ja;q=-1, en;q=0.9", "ja"},
+	}
+
+	for _, tt := range tests {
+		if got := middleware.ParseAcceptLanguage(tt.header, supported); got != tt.want {
+			t.Errorf("ParseAcceptLanguage(%q): expected %q, got %q", tt.header, tt.want, got)
+		}
+	}
+}
+
+func TestParseAcceptLanguageRegionsAndTies(t *testing.T) {
+	supported := middleware.BuildSupportedMap([]string{"en", "ja", "pt", "pt-BR", "zh-Hant"})
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"tie in header order", "en;q=0.8, ja;q=0.8", "en"},
+		{"tie in header order reversed", "ja;q=0.8, en;q=0.8", "ja"},
+		{"implicit q ties", "ja, en", "ja"},
+		{"higher q later", "en;q=0.5, ja;q=0.9", "ja"},
+		{"exact region", "pt-BR", "pt-br"},
+		{"base when region unsupported", "pt-PT", "pt"},
+		{"header order over region at equal q", "pt, pt-BR", "pt"},
+		{"script subtag", "zh-Hant-TW", "zh-hant"},
+		{"unsupported base of a supported region", "zh-CN, en;q=0.1", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := middleware.ParseAcceptLanguage(tt.header, supported); got != tt.want {
+				t.Errorf("ParseAcceptLanguage(%q): expected %q, got %q", tt.header, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseAcceptLanguageAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector adds allocations")
+	}
+	supported := middleware.BuildSupportedMap([]string{"en", "ja", "ko"})
+	header := "FR-fr,fr;q=0.9,ko-KR;q=0.8,en-US;q=0.7,en;q=0.6"
+
+	allocs := testing.AllocsPerRun(100, func() {
+		middleware.ParseAcceptLanguage(header, supported)
+	})
+	if allocs != 0 {
+		t.Errorf("expected 0 allocations, got %v", allocs)
+	}
+}
+
+func TestLanguageAcceptLanguageCache(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.Language(middleware.LanguageConfig{Supported: []string{"en", "ja"}}))
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, middleware.GetLanguage(c))
+	})
+
+	// Repeated headers are served from the cache; results must not mix up
+	for i := 0; i < 3; i++ {
+		for header, want := range map[string]string{"ja-JP,en;q=0.5": "ja", "en-GB": "en", "fr": "en"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept-Language", header)
+			router.ServeHTTP(w, req)
+			if w.Body.String() != want {
+				t.Errorf("Accept-Language %q: expected %q, got %q", header, want, w.Body.String())
+			}
+		}
+	}
+}
+
+func BenchmarkParseAcceptLanguage(b *testing.B) {
+	supported := middleware.BuildSupportedMap([]string{"en", "ja", "ko"})
+	header := "fr-FR,fr;q=0.9,ko-KR;q=0.8,en-US;q=0.7,en;q=0.6"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		middleware.ParseAcceptLanguage(header, supported)
+	}
+}
+
+func FuzzParseAcceptLanguage(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"ja,en;q=0.9",
+		"en-US;q=0.8, zh-CN;q=0.9, *;q=0.1",
+		"JA;q=1.0,fr",
+		"en;q=0",
+		"ja;q=NaN,en;q=0.5",
+		"ko;q=1e400,en",
+		",,;;q=,",
+	} {
+		f.Add(seed)
+	}
+	supported := middleware.BuildSupportedMap([]string{"en", "ja", "zh"})
+
+	f.Fuzz(func(t *testing.T, header string) {
+		lang := middleware.ParseAcceptLanguage(header, supported)
+		if lang == "" {
+			return
+		}
+		if _, ok := supported[lang]; !ok {
+			t.Errorf("expected a supported language, got %q for %q", lang, header)
+		}
+	})
+}
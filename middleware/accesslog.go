@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteLogPolicy adjusts access logging for a route.
+type RouteLogPolicy struct {
+	// SampleRate is the fraction of successful (< 400) requests logged,
+	// e.g. 0.01 for 1%. 0 logs them all; use Skip to log none. Client and
+	// server errors are always logged.
+	SampleRate float64
+	// Level overrides the level of successful requests, e.g. slog.LevelDebug
+	// for probes nobody reads at info level. Nil keeps the default.
+	Level slog.Leveler
+	// Skip drops successful requests entirely
+	Skip bool
+}
+
+// AccessLogConfig configures the access log.
+type AccessLogConfig struct {
+	// Logger receives the entries (defaults to slog.Default())
+	Logger *slog.Logger
+	// Routes maps route patterns to policies. A key ending in "*" matches
+	// every route with that prefix, e.g. "/internal/*"; exact keys win,
+	// then the longest prefix.
+	Routes map[string]RouteLogPolicy
+	// Default applies to routes without a policy
+	Default RouteLogPolicy
+}
+
+// AccessLog returns middleware that logs one entry per request with the
+// method, route, path, status, duration, response size, client IP, and
+// request ID. Successful requests log at info, 4xx at warn, and 5xx at
+// error. High-volume routes can be sampled or demoted, while their errors
+// are still all kept:
+//
+//	router.Use(middleware.AccessLog(middleware.AccessLogConfig{
+//	    Routes: map[string]middleware.RouteLogPolicy{
+//	        "/healthz":    {Skip: true},
+//	        "/api/ping":   {SampleRate: 0.01},
+//	        "/internal/*": {Level: slog.LevelDebug},
+//	    },
+//	}))
+//
+// Sampled entries carry a sample_rate attribute, so counts can be scaled
+// back up. It panics if a SampleRate is outside [0, 1].
+func AccessLog(cfg AccessLogConfig) gin.HandlerFunc {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	policies := newLogPolicies(cfg)
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		route := c.FullPath()
+		policy := policies.lookup(route)
+
+		level := slog.LevelInfo
+		var attrs []slog.Attr
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		default:
+			if policy.Skip {
+				return
+			}
+			if policy.Level != nil {
+				level = policy.Level.Level()
+			}
+			if policy.SampleRate > 0 && policy.SampleRate < 1 {
+				if rand.Float64() >= policy.SampleRate {
+					return
+				}
+				attrs = append(attrs, slog.Float64("sample_rate", policy.SampleRate))
+			}
+		}
+
+		if !logger.Enabled(c, level) {
+			return
+		}
+		attrs = append(attrs,
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("client_ip", c.ClientIP()),
+		)
+		if id := c.Writer.Header().Get("X-Request-ID"); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		logger.LogAttrs(c, level, "request", attrs...)
+	}
+}
+
+// logPolicies resolves route patterns to policies.
+type logPolicies struct {
+	exact    map[string]RouteLogPolicy
+	prefixes []logPrefix // longest first
+	fallback RouteLogPolicy
+}
+
+// logPrefix is the policy of a "prefix*" pattern.
+type logPrefix struct {
+	prefix string
+	policy RouteLogPolicy
+}
+
+// newLogPolicies validates and indexes the configured policies.
+func newLogPolicies(cfg AccessLogConfig) *logPolicies {
+	p := &logPolicies{exact: map[string]RouteLogPolicy{}, fallback: cfg.Default}
+	check := func(name string, policy RouteLogPolicy) {
+		if policy.SampleRate < 0 || policy.SampleRate > 1 {
+			panic("middleware: AccessLog sample rate for " + name + " must be between 0 and 1")
+		}
+	}
+	check("Default", cfg.Default)
+	for pattern, policy := range cfg.Routes {
+		check(pattern, policy)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			p.prefixes = append(p.prefixes, logPrefix{prefix, policy})
+		} else {
+			p.exact[pattern] = policy
+		}
+	}
+	sort.Slice(p.prefixes, func(i, j int) bool { return len(p.prefixes[i].prefix) > len(p.prefixes[j].prefix) })
+	return p
+}
+
+// lookup returns the policy for a route pattern.
+func (p *logPolicies) lookup(route string) RouteLogPolicy {
+	if policy, ok := p.exact[route]; ok {
+		return policy
+	}
+	for _, lp := range p.prefixes {
+		if strings.HasPrefix(route, lp.prefix) {
+			return lp.policy
+		}
+	}
+	return p.fallback
+}
@@ -0,0 +1,122 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Header("X-Request-ID", "req_1")
+		c.Next()
+	})
+	router.Use(middleware.AccessLog(middleware.AccessLogConfig{
+		Logger: logger,
+		Routes: map[string]middleware.RouteLogPolicy{
+			"/healthz":         {Skip: true},
+			"/internal/*":      {Level: slog.LevelDebug},
+			"/internal/audit":  {},
+			"/internal/jobs/*": {SampleRate: 1},
+		},
+	}))
+	status := func(code int) gin.HandlerFunc {
+		return func(c *gin.Context) { c.String(code, "ok") }
+	}
+	router.GET("/galleries", status(http.StatusOK))
+	router.GET("/missing", status(http.StatusNotFound))
+	router.GET("/healthz", status(http.StatusOK))
+	router.GET("/broken-healthz", status(http.StatusInternalServerError))
+	router.GET("/internal/stats", status(http.StatusOK))
+	router.GET("/internal/audit", status(http.StatusOK))
+	router.GET("/internal/jobs/run", status(http.StatusOK))
+	router.GET("/internal/fail", status(http.StatusServiceUnavailable))
+
+	tests := []struct {
+		path      string
+		wantLevel string // "" for no entry
+	}{
+		{"/galleries", "INFO"},
+		{"/missing", "WARN"},
+		{"/healthz", ""},
+		{"/broken-healthz", "ERROR"},
+		{"/internal/stats", ""},        // demoted to debug
+		{"/internal/audit", "INFO"},    // exact key beats the prefix
+		{"/internal/jobs/run", "INFO"}, // longest prefix wins
+		{"/internal/fail", "ERROR"},    // errors ignore the demotion
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			buf.Reset()
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if tt.wantLevel == "" {
+				if buf.Len() != 0 {
+					t.Errorf("expected no entry, got %s", buf.String())
+				}
+				return
+			}
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("expected one JSON entry, got %q: %v", buf.String(), err)
+			}
+			if entry["level"] != tt.wantLevel || entry["route"] != tt.path || entry["request_id"] != "req_1" {
+				t.Errorf("unexpected entry %v", entry)
+			}
+		})
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	var buf bytes.Buffer
+	router := gin.New()
+	router.Use(middleware.AccessLog(middleware.AccessLogConfig{
+		Logger:  slog.New(slog.NewJSONHandler(&buf, nil)),
+		Default: middleware.RouteLogPolicy{SampleRate: 0.1},
+	}))
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/fail", func(c *gin.Context) { c.Status(http.StatusBadGateway) })
+
+	for i := 0; i < 1000; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+	}
+	lines := strings.Count(buf.String(), "\n")
+	if lines < 40 || lines > 200 {
+		t.Errorf("expected about 100 of 1000 entries at a 10%% sample rate, got %d", lines)
+	}
+	if lines > 0 && !strings.Contains(buf.String(), `"sample_rate":0.1`) {
+		t.Error("expected sampled entries to carry sample_rate")
+	}
+
+	buf.Reset()
+	for i := 0; i < 20; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 20 {
+		t.Errorf("expected every error to be logged, got %d of 20", lines)
+	}
+}
+
+func TestAccessLogInvalidSampleRate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	middleware.AccessLog(middleware.AccessLogConfig{Routes: map[string]middleware.RouteLogPolicy{"/x": {SampleRate: 1.5}}})
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// CacheControl returns middleware that declares the Cache-Control policy for
+// the routes below it. Handlers can override it per response with
+// response.NoStore, response.PublicCache, or response.SetCacheControl.
+// Server errors are always sent with no-store so a CDN never caches an outage.
+//
+//	public := router.Group("/api/v1/tags", middleware.CacheControl(response.CachePolicy{
+//	    Public: true,
+//	    MaxAge: 10 * time.Minute,
+//	}))
+func CacheControl(policy response.CachePolicy) gin.HandlerFunc {
+	value := policy.String()
+
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", value)
+		c.Next()
+	}
+}
@@ -0,0 +1,35 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestCacheControl(t *testing.T) {
+	router := gin.New()
+	tags := router.Group("/tags", middleware.CacheControl(response.CachePolicy{Public: true, MaxAge: 10 * time.Minute}))
+	tags.GET("", func(c *gin.Context) { response.Success(c, "ok") })
+	tags.GET("/mine", func(c *gin.Context) {
+		response.NoStore(c)
+		response.Success(c, "ok")
+	})
+
+	tests := map[string]string{
+		"/tags":      "public, max-age=600",
+		"/tags/mine": "no-store",
+	}
+	for target, want := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if got := w.Header().Get("Cache-Control"); got != want {
+			t.Errorf("%s: expected '%s', got '%s'", target, want, got)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+)
+
+// CanaryConfig configures a canary rollout.
+type CanaryConfig struct {
+	// Name identifies the rollout in exposure logs and the X-Canary
+	// header, and salts the bucketing so rollouts don't share buckets
+	// (required)
+	Name string
+	// Percent of requests sent to Handler, from 0 to 100
+	Percent float64
+	// Handler serves canary requests instead of the route's handlers, e.g.
+	// the rewritten endpoint or a proxy.Handler to the new service
+	// (required)
+	Handler gin.HandlerFunc
+	// Key makes assignment sticky: requests with the same key get the same
+	// variant (defaults to the principal ID, which for guests is their
+	// device, else the client IP)
+	Key func(c *gin.Context) string
+	// OnExposure is called for every request with the variant it got
+	// (defaults to logging "canary exposure" with slog.Default() at info)
+	OnExposure func(c *gin.Context, name string, canary bool)
+}
+
+// Canary returns middleware that sends Percent of a route's requests to
+// an alternate handler, for canarying a rewritten endpoint in process:
+//
+//	galleries.GET("/:id", middleware.Canary(middleware.CanaryConfig{
+//	    Name:    "gallery-v2",
+//	    Percent: 5,
+//	    Handler: getGalleryV2,
+//	}), getGallery)
+//
+// Assignment is sticky per Key, so a user sees one version throughout, and
+// raising Percent only moves users from the old version to the new one.
+// Canary responses carry an X-Canary header with the rollout name. Install
+// it after auth middleware. It panics if Name or Handler is missing or
+// Percent is outside [0, 100].
+func Canary(cfg CanaryConfig) gin.HandlerFunc {
+	return NewCanaryRollout(cfg).Middleware()
+}
+
+// CanaryRollout is the router behind Canary, with a percentage that can be
+// changed at runtime to ramp the rollout up or roll it back.
+type CanaryRollout struct {
+	cfg       CanaryConfig
+	threshold atomic.Uint64
+}
+
+// NewCanaryRollout returns a CanaryRollout for cfg.
+func NewCanaryRollout(cfg CanaryConfig) *CanaryRollout {
+	if cfg.Name == "" || cfg.Handler == nil {
+		panic("middleware: CanaryConfig requires a Name and Handler")
+	}
+	if cfg.Key == nil {
+		cfg.Key = principalOrIP
+	}
+	if cfg.OnExposure == nil {
+		cfg.OnExposure = logExposure
+	}
+	r := &CanaryRollout{cfg: cfg}
+	r.SetPercent(cfg.Percent)
+	return r
+}
+
+// canaryBuckets is the bucketing resolution: percentages have two decimals.
+const canaryBuckets = 10000
+
+// SetPercent changes the share of requests sent to the canary. It panics
+// if percent is outside [0, 100].
+func (r *CanaryRollout) SetPercent(percent float64) {
+	if percent < 0 || percent > 100 || math.IsNaN(percent) {
+		panic(fmt.Sprintf("middleware: canary percent %v must be between 0 and 100", percent))
+	}
+	r.threshold.Store(uint64(math.Round(percent * canaryBuckets / 100)))
+}
+
+// Middleware returns the gin middleware; see Canary.
+func (r *CanaryRollout) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		canary := canaryBucket(r.cfg.Name, r.cfg.Key(c)) < r.threshold.Load()
+		r.cfg.OnExposure(c, r.cfg.Name, canary)
+		if !canary {
+			c.Next()
+			return
+		}
+		c.Header("X-Canary", r.cfg.Name)
+		r.cfg.Handler(c)
+		c.Abort()
+	}
+}
+
+// canaryBucket hashes key into one of canaryBuckets buckets for a rollout.
+func canaryBucket(name, key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return h.Sum64() % canaryBuckets
+}
+
+// principalOrIP is the default canary Key.
+func principalOrIP(c *gin.Context) string {
+	if p, ok := auth.GetPrincipal(c); ok && p.ID != "" {
+		return p.ID
+	}
+	return c.ClientIP()
+}
+
+// logExposure is the default OnExposure.
+func logExposure(c *gin.Context, name string, canary bool) {
+	variant := "control"
+	if canary {
+		variant = "canary"
+	}
+	slog.LogAttrs(c, slog.LevelInfo, "canary exposure",
+		slog.String("canary", name),
+		slog.String("variant", variant),
+		slog.String("route", c.FullPath()),
+	)
+}
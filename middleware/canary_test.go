@@ -0,0 +1,113 @@
+package middleware_test
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func newCanaryRouter(rollout *middleware.CanaryRollout) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-User"); id != "" {
+			auth.SetPrincipal(c, auth.Principal{ID: id, Type: auth.TypeUser})
+		}
+	})
+	router.GET("/galleries/:id", rollout.Middleware(), func(c *gin.Context) { c.String(http.StatusOK, "v1") })
+	return router
+}
+
+func canaryShare(t *testing.T, router *gin.Engine, users int) (share float64, byUser map[string]string) {
+	t.Helper()
+	byUser = map[string]string{}
+	canary := 0
+	for i := range users {
+		req := httptest.NewRequest(http.MethodGet, "/galleries/gal_1", nil)
+		req.Header.Set("X-User", fmt.Sprintf("usr_%d", i))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		byUser[req.Header.Get("X-User")] = w.Body.String()
+		if w.Body.String() == "v2" {
+			canary++
+			if w.Header().Get("X-Canary") != "gallery-v2" {
+				t.Errorf("expected X-Canary gallery-v2, got %q", w.Header().Get("X-Canary"))
+			}
+		}
+	}
+	return float64(canary) / float64(users) * 100, byUser
+}
+
+func TestCanary(t *testing.T) {
+	var exposures, canaryExposures int
+	rollout := middleware.NewCanaryRollout(middleware.CanaryConfig{
+		Name:    "gallery-v2",
+		Percent: 10,
+		Handler: func(c *gin.Context) { c.String(http.StatusOK, "v2") },
+		OnExposure: func(_ *gin.Context, name string, canary bool) {
+			exposures++
+			if canary {
+				canaryExposures++
+			}
+		},
+	})
+	router := newCanaryRouter(rollout)
+
+	share, before := canaryShare(t, router, 2000)
+	if math.Abs(share-10) > 3 {
+		t.Errorf("expected about 10%% canary, got %.1f%%", share)
+	}
+	if exposures != 2000 || float64(canaryExposures)/20 != share {
+		t.Errorf("expected an exposure per request, got %d (%d canary)", exposures, canaryExposures)
+	}
+
+	// Sticky: the same users get the same variant, and ramping up only
+	// moves users to the canary
+	_, again := canaryShare(t, router, 2000)
+	rollout.SetPercent(50)
+	share, after := canaryShare(t, router, 2000)
+	if math.Abs(share-50) > 5 {
+		t.Errorf("expected about 50%% canary, got %.1f%%", share)
+	}
+	for user, variant := range before {
+		if again[user] != variant {
+			t.Fatalf("expected %s to keep %s, got %s", user, variant, again[user])
+		}
+		if variant == "v2" && after[user] != "v2" {
+			t.Fatalf("expected %s to stay on the canary after ramping up", user)
+		}
+	}
+
+	rollout.SetPercent(0)
+	if share, _ := canaryShare(t, router, 500); share != 0 {
+		t.Errorf("expected no canary at 0%%, got %.1f%%", share)
+	}
+}
+
+func TestCanaryInvalidConfig(t *testing.T) {
+	handler := func(c *gin.Context) {}
+	tests := []struct {
+		name string
+		cfg  middleware.CanaryConfig
+	}{
+		{"missing name", middleware.CanaryConfig{Handler: handler}},
+		{"missing handler", middleware.CanaryConfig{Name: "x"}},
+		{"percent", middleware.CanaryConfig{Name: "x", Handler: handler, Percent: 101}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			middleware.Canary(tt.cfg)
+		})
+	}
+}
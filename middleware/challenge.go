@@ -0,0 +1,294 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"math/bits"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/clock"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// ChallengeConfig configures abuse challenges.
+type ChallengeConfig struct {
+	// Secret signs proof-of-work challenges (required)
+	Secret []byte
+	// Difficulty is the number of leading zero bits a solution's SHA-256
+	// must have (defaults to 20, about a second of work in a browser)
+	Difficulty int
+	// TTL is how long a challenge can be solved and redeemed (defaults to 2m)
+	TTL time.Duration
+	// Header carries the issued challenge (defaults to "X-Challenge")
+	Header string
+	// SolutionHeader carries a solved challenge (defaults to "X-Challenge-Solution")
+	SolutionHeader string
+	// CaptchaHeader carries a captcha token (defaults to "X-Captcha-Token")
+	CaptchaHeader string
+	// VerifyCaptcha, if set, offers a captcha alongside proof of work and
+	// verifies tokens with the captcha provider
+	VerifyCaptcha func(ctx context.Context, token string) (bool, error)
+	// Store records redeemed tokens (defaults to an in-memory store). Use
+	// a shared store (see redisstore) when several instances verify
+	// challenges, or a token can be redeemed once on each of them.
+	Store ChallengeStore
+	// Clock times challenge expiry (defaults to clock.System)
+	Clock clock.Clock
+}
+
+// Challenger issues and verifies challenges for clients that a rate limiter
+// or scraping heuristic has flagged. Instead of a flat 429, the limiter
+// responds with Challenge; the client retries with the solution, Verify
+// checks it, and ChallengePassed tells the limiter to let the request through:
+//
+//	challenger := middleware.NewChallenger(middleware.ChallengeConfig{Secret: cfg.ChallengeSecret})
+//	router.Use(challenger.Verify())
+//	router.Use(func(c *gin.Context) {
+//	    if !middleware.ChallengePassed(c) && !limiter.Allow(c.ClientIP()) {
+//	        challenger.Challenge(c)
+//	        c.Abort()
+//	        return
+//	    }
+//	    c.Next()
+//	})
+//
+// The proof-of-work challenge is sent in the X-Challenge header as
+//
+//	pow; token=<token>; difficulty=20
+//
+// and solved by finding a nonce such that SHA-256("<token>:<nonce>") starts
+// with difficulty zero bits (see SolveChallenge), sent back as
+// X-Challenge-Solution: <token>:<nonce>. Tokens are bound to the client IP,
+// expire after TTL, and are redeemed once per Store.
+type Challenger struct {
+	cfg ChallengeConfig
+}
+
+// ChallengeStore records redeemed challenge tokens. Implementations must
+// be safe for concurrent use; a shared store (see redisstore) makes tokens
+// single-use across instances.
+type ChallengeStore interface {
+	// Redeem marks token used until expires and reports whether it was
+	// unused.
+	Redeem(ctx context.Context, token string, expires time.Time) (bool, error)
+}
+
+// NewChallenger returns a Challenger for cfg.
+func NewChallenger(cfg ChallengeConfig) *Challenger {
+	if len(cfg.Secret) == 0 {
+		panic("middleware: NewChallenger requires a Secret")
+	}
+	if cfg.Difficulty == 0 {
+		cfg.Difficulty = 20
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = 2 * time.Minute
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-Challenge"
+	}
+	if cfg.SolutionHeader == "" {
+		cfg.SolutionHeader = "X-Challenge-Solution"
+	}
+	if cfg.CaptchaHeader == "" {
+		cfg.CaptchaHeader = "X-Captcha-Token"
+	}
+	cfg.Clock = clock.Or(cfg.Clock)
+	if cfg.Store == nil {
+		cfg.Store = newMemoryChallengeStore(cfg.Clock, 0)
+	}
+	return &Challenger{cfg: cfg}
+}
+
+// Challenge sends a 429 with code challenge_required and a fresh challenge
+// in the challenge header. The caller should abort.
+func (ch *Challenger) Challenge(c *gin.Context) {
+	offers := []string{"pow; token=" + ch.issue(c.ClientIP(), ch.cfg.Clock.Now()) +
+		"; difficulty=" + strconv.Itoa(ch.cfg.Difficulty)}
+	if ch.cfg.VerifyCaptcha != nil {
+		offers = append(offers, "captcha")
+	}
+	c.Header(ch.cfg.Header, strings.Join(offers, ", "))
+	response.AddExposeHeaders(c.Writer.Header(), ch.cfg.Header)
+	response.ErrorWithInfo(c, http.StatusTooManyRequests, response.ErrorInfo{
+		Type:    response.ErrorTypeRateLimit,
+		Code:    response.ErrorCodeChallengeRequired,
+		Message: "too many requests; solve the challenge and retry",
+	})
+}
+
+// Verify returns middleware that checks solved challenges and captcha
+// tokens. A valid one marks the request for ChallengePassed; an invalid,
+// expired, or reused one gets a fresh challenge, and a failing Store a 503.
+// Requests carrying neither pass through untouched.
+func (ch *Challenger) Verify() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if solution := c.GetHeader(ch.cfg.SolutionHeader); solution != "" {
+			ok, err := ch.redeem(c.Request.Context(), solution, c.ClientIP(), ch.cfg.Clock.Now())
+			if err != nil {
+				response.ReportError(c, c.Request, c.FullPath(), err)
+				response.ServiceUnavailable(c, "challenge verification is unavailable, try again later")
+				c.Abort()
+				return
+			}
+			if !ok {
+				ch.Challenge(c)
+				c.Abort()
+				return
+			}
+			c.Set(challengePassedKey, true)
+		} else if token := c.GetHeader(ch.cfg.CaptchaHeader); token != "" && ch.cfg.VerifyCaptcha != nil {
+			ok, err := ch.cfg.VerifyCaptcha(c.Request.Context(), token)
+			if err != nil {
+				response.ReportError(c, c.Request, c.FullPath(), err)
+				response.ServiceUnavailable(c, "captcha verification is unavailable, try again later")
+				c.Abort()
+				return
+			}
+			if !ok {
+				ch.Challenge(c)
+				c.Abort()
+				return
+			}
+			c.Set(challengePassedKey, true)
+		}
+		c.Next()
+	}
+}
+
+// challengePassedKey is the gin context key marking a verified challenge.
+const challengePassedKey = "ginapi.challenge_passed"
+
+// ChallengePassed reports whether Verify accepted a solved challenge or
+// captcha token for this request.
+func ChallengePassed(c *gin.Context) bool {
+	return c.GetBool(challengePassedKey)
+}
+
+// SolveChallenge finds a nonce for a proof-of-work token and returns the
+// solution header value. It is what clients do in JavaScript; Go clients
+// and tests can call it directly.
+func SolveChallenge(token string, difficulty int) string {
+	for nonce := uint64(0); ; nonce++ {
+		solution := token + ":" + strconv.FormatUint(nonce, 10)
+		if leadingZeroBits(sha256.Sum256([]byte(solution))) >= difficulty {
+			return solution
+		}
+	}
+}
+
+// challengeTokenSize is the size of a decoded token payload: expiry
+// (8 bytes), difficulty (1), random (16), then a SHA-256 HMAC (32).
+const challengeTokenSize = 8 + 1 + 16 + sha256.Size
+
+// issue returns a new token for clientIP.
+func (ch *Challenger) issue(clientIP string, now time.Time) string {
+	payload := make([]byte, 25, challengeTokenSize)
+	binary.BigEndian.PutUint64(payload, uint64(now.Add(ch.cfg.TTL).Unix()))
+	payload[8] = byte(min(ch.cfg.Difficulty, 255))
+	rand.Read(payload[9:])
+	return base64.RawURLEncoding.EncodeToString(append(payload, ch.sign(payload, clientIP)...))
+}
+
+// sign returns the HMAC binding payload to clientIP.
+func (ch *Challenger) sign(payload []byte, clientIP string) []byte {
+	mac := hmac.New(sha256.New, ch.cfg.Secret)
+	mac.Write(payload)
+	mac.Write([]byte(clientIP))
+	return mac.Sum(nil)
+}
+
+// redeem verifies a "<token>:<nonce>" solution for clientIP and marks the
+// token used in the Store.
+func (ch *Challenger) redeem(ctx context.Context, solution, clientIP string, now time.Time) (bool, error) {
+	token, _, ok := strings.Cut(solution, ":")
+	if !ok {
+		return false, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != challengeTokenSize {
+		return false, nil
+	}
+	payload, sig := raw[:25], raw[25:]
+	if !hmac.Equal(sig, ch.sign(payload, clientIP)) {
+		return false, nil
+	}
+	expires := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)
+	if !now.Before(expires) {
+		return false, nil
+	}
+	if leadingZeroBits(sha256.Sum256([]byte(solution))) < int(payload[8]) {
+		return false, nil
+	}
+	return ch.cfg.Store.Redeem(ctx, token, expires)
+}
+
+// MemoryChallengeStore is an in-process ChallengeStore, for
+// single-instance services and tests. It holds a bounded number of
+// unexpired tokens; when full, it refuses redemptions until some expire
+// rather than forget tokens that could be replayed.
+type MemoryChallengeStore struct {
+	clock     clock.Clock
+	maxTokens int
+	mu        sync.Mutex
+	redeemed  map[string]time.Time
+}
+
+// NewMemoryChallengeStore returns an empty MemoryChallengeStore holding up
+// to maxTokens unexpired tokens (defaults to 65536).
+func NewMemoryChallengeStore(maxTokens int) *MemoryChallengeStore {
+	return newMemoryChallengeStore(clock.System, maxTokens)
+}
+
+// newMemoryChallengeStore returns an empty store timed by clk.
+func newMemoryChallengeStore(clk clock.Clock, maxTokens int) *MemoryChallengeStore {
+	if maxTokens <= 0 {
+		maxTokens = 65536
+	}
+	return &MemoryChallengeStore{clock: clk, maxTokens: maxTokens, redeemed: map[string]time.Time{}}
+}
+
+// Redeem implements ChallengeStore.
+func (s *MemoryChallengeStore) Redeem(_ context.Context, token string, expires time.Time) (bool, error) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if exp, used := s.redeemed[token]; used && now.Before(exp) {
+		return false, nil
+	}
+	if len(s.redeemed) >= s.maxTokens {
+		for t, exp := range s.redeemed {
+			if !now.Before(exp) {
+				delete(s.redeemed, t)
+			}
+		}
+		if len(s.redeemed) >= s.maxTokens {
+			return false, nil
+		}
+	}
+	s.redeemed[token] = expires
+	return true, nil
+}
+
+// leadingZeroBits counts the leading zero bits of a hash.
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,205 @@
+package middleware_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+// challengeRouter challenges every request that hasn't passed a challenge.
+func challengeRouter(ch *middleware.Challenger) *gin.Engine {
+	router := gin.New()
+	router.Use(ch.Verify())
+	router.Use(func(c *gin.Context) {
+		if !middleware.ChallengePassed(c) {
+			ch.Challenge(c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	})
+	router.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func serveChallenge(router http.Handler, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// challengeToken extracts the proof-of-work token from a challenge header.
+func challengeToken(t *testing.T, header string) string {
+	t.Helper()
+	for _, part := range strings.Split(header, ";") {
+		if token, ok := strings.CutPrefix(strings.TrimSpace(part), "token="); ok {
+			return token
+		}
+	}
+	t.Fatalf("no token in challenge %q", header)
+	return ""
+}
+
+func TestChallenge(t *testing.T) {
+	ch := middleware.NewChallenger(middleware.ChallengeConfig{Secret: []byte("secret"), Difficulty: 8})
+	router := challengeRouter(ch)
+
+	w := serveChallenge(router, "1.2.3.4:1000", nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"challenge_required"`) {
+		t.Errorf("expected challenge_required code, got %s", w.Body.String())
+	}
+	header := w.Header().Get("X-Challenge")
+	if !strings.HasPrefix(header, "pow; token=") || !strings.HasSuffix(header, "; difficulty=8") {
+		t.Fatalf("unexpected challenge header %q", header)
+	}
+	solution := middleware.SolveChallenge(challengeToken(t, header), 8)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		solution   string
+		wantStatus int
+	}{
+		{"other client", "5.6.7.8:1000", solution, http.StatusTooManyRequests},
+		{"solved", "1.2.3.4:1000", solution, http.StatusOK},
+		{"reused", "1.2.3.4:1000", solution, http.StatusTooManyRequests},
+		{"malformed", "1.2.3.4:1000", "garbage", http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		w := serveChallenge(router, tt.remoteAddr, map[string]string{"X-Challenge-Solution": tt.solution})
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.wantStatus, w.Code)
+		}
+	}
+}
+
+func TestChallengeUnsolved(t *testing.T) {
+	ch := middleware.NewChallenger(middleware.ChallengeConfig{Secret: []byte("secret"), Difficulty: 16})
+	router := challengeRouter(ch)
+
+	w := serveChallenge(router, "1.2.3.4:1000", nil)
+	token := challengeToken(t, w.Header().Get("X-Challenge"))
+
+	// Find a nonce that falls short of the required difficulty
+	var solution string
+	for nonce := 0; ; nonce++ {
+		solution = token + ":" + strconv.Itoa(nonce)
+		if sum := sha256.Sum256([]byte(solution)); sum[0] != 0 {
+			break
+		}
+	}
+
+	w = serveChallenge(router, "1.2.3.4:1000", map[string]string{"X-Challenge-Solution": solution})
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429 for an unsolved challenge, got %d", w.Code)
+	}
+}
+
+func TestChallengeCaptcha(t *testing.T) {
+	ch := middleware.NewChallenger(middleware.ChallengeConfig{
+		Secret: []byte("secret"),
+		VerifyCaptcha: func(_ context.Context, token string) (bool, error) {
+			return token == "good", nil
+		},
+	})
+	router := challengeRouter(ch)
+
+	w := serveChallenge(router, "1.2.3.4:1000", nil)
+	if !strings.HasSuffix(w.Header().Get("X-Challenge"), ", captcha") {
+		t.Errorf("expected captcha offer, got %q", w.Header().Get("X-Challenge"))
+	}
+
+	tests := []struct {
+		token      string
+		wantStatus int
+	}{
+		{"good", http.StatusOK},
+		{"bad", http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		w := serveChallenge(router, "1.2.3.4:1000", map[string]string{"X-Captcha-Token": tt.token})
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.token, tt.wantStatus, w.Code)
+		}
+	}
+}
+
+func TestChallengeSharedStore(t *testing.T) {
+	store := middleware.NewMemoryChallengeStore(0)
+	first := challengeRouter(middleware.NewChallenger(middleware.ChallengeConfig{Secret: []byte("secret"), Difficulty: 8, Store: store}))
+	second := challengeRouter(middleware.NewChallenger(middleware.ChallengeConfig{Secret: []byte("secret"), Difficulty: 8, Store: store}))
+
+	w := serveChallenge(first, "1.2.3.4:1000", nil)
+	solution := middleware.SolveChallenge(challengeToken(t, w.Header().Get("X-Challenge")), 8)
+
+	if w := serveChallenge(first, "1.2.3.4:1000", map[string]string{"X-Challenge-Solution": solution}); w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if w := serveChallenge(second, "1.2.3.4:1000", map[string]string{"X-Challenge-Solution": solution}); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected a token redeemed on one instance rejected on another, got %d", w.Code)
+	}
+}
+
+type failingChallengeStore struct{}
+
+func (failingChallengeStore) Redeem(context.Context, string, time.Time) (bool, error) {
+	return false, errors.New("store down")
+}
+
+func TestChallengeStoreError(t *testing.T) {
+	router := challengeRouter(middleware.NewChallenger(middleware.ChallengeConfig{Secret: []byte("secret"), Difficulty: 8, Store: failingChallengeStore{}}))
+
+	w := serveChallenge(router, "1.2.3.4:1000", nil)
+	solution := middleware.SolveChallenge(challengeToken(t, w.Header().Get("X-Challenge")), 8)
+
+	w = serveChallenge(router, "1.2.3.4:1000", map[string]string{"X-Challenge-Solution": solution})
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+}
+
+func TestMemoryChallengeStore(t *testing.T) {
+	ctx := context.Background()
+	s := middleware.NewMemoryChallengeStore(2)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		token   string
+		expires time.Time
+		want    bool
+	}{
+		{"expired entry", "old", now.Add(-time.Minute), true},
+		{"new", "a", now.Add(time.Hour), true},
+		{"reused", "a", now.Add(time.Hour), false},
+		{"full sweeps expired entries", "b", now.Add(time.Hour), true},
+		{"full of unexpired tokens", "c", now.Add(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		ok, err := s.Redeem(ctx, tt.token, tt.expires)
+		if err != nil || ok != tt.want {
+			t.Errorf("%s: expected %v, got %v %v", tt.name, tt.want, ok, err)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// DefaultChaosHeader is the header game-day requests carry the chaos key in.
+const DefaultChaosHeader = "X-Chaos-Key"
+
+// ChaosRule injects faults into the requests of matching routes.
+type ChaosRule struct {
+	// Method restricts the rule to one method. Empty matches any.
+	Method string
+	// Route is a route pattern, e.g. "/api/galleries/:id". A pattern ending
+	// in "*" matches every route with that prefix; "*" matches all.
+	Route string
+	// Latency is added before the handler runs
+	Latency time.Duration
+	// Jitter adds up to this much random latency on top of Latency
+	Jitter time.Duration
+	// ErrorRate is the fraction of requests answered with ErrorStatus
+	// instead of reaching the handler, e.g. 0.1
+	ErrorRate float64
+	// ErrorStatus of injected errors (defaults to 503)
+	ErrorStatus int
+	// DropRate is the fraction of requests whose connection is closed
+	// without a response
+	DropRate float64
+}
+
+// ChaosConfig configures fault injection.
+type ChaosConfig struct {
+	// Enabled turns fault injection on. It is off by default; set it from
+	// the environment (see ginapi.Config) and only in staging.
+	Enabled bool
+	// Key must be sent in Header for a request to be affected, so only
+	// game-day traffic sees faults (required when Enabled)
+	Key string
+	// Header carrying the key (defaults to "X-Chaos-Key")
+	Header string
+	// Rules are tried in order; the first match applies
+	Rules []ChaosRule
+}
+
+// Chaos returns middleware that injects latency, errors, and dropped
+// connections into matching requests, for resilience testing against
+// staging without code changes in each service. It does nothing unless
+// cfg.Enabled, and only affects requests carrying cfg.Key:
+//
+//	router.Use(middleware.Chaos(middleware.ChaosConfig{
+//	    Enabled: os.Getenv("CHAOS_ENABLED") == "true",
+//	    Key:     os.Getenv("CHAOS_KEY"),
+//	    Rules: []middleware.ChaosRule{
+//	        {Route: "/api/search*", Latency: 2 * time.Second, Jitter: time.Second},
+//	        {Method: http.MethodPost, Route: "/api/galleries", ErrorRate: 0.2, DropRate: 0.05},
+//	    },
+//	}))
+//
+// Register it with router.Use so rules can match on the route. Affected
+// responses carry an X-Chaos header naming the faults injected. It panics
+// if cfg is enabled without a Key or a rate is outside [0, 1].
+func Chaos(cfg ChaosConfig) gin.HandlerFunc {
+	if !cfg.Enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+	if cfg.Key == "" {
+		panic("middleware: ChaosConfig.Key is required when Enabled")
+	}
+	if cfg.Header == "" {
+		cfg.Header = DefaultChaosHeader
+	}
+	cfg.Rules = slices.Clone(cfg.Rules)
+	for i, rule := range cfg.Rules {
+		if err := rule.validate(); err != nil {
+			panic(fmt.Sprintf("middleware: chaos rule %d: %v", i, err))
+		}
+		if rule.ErrorStatus == 0 {
+			cfg.Rules[i].ErrorStatus = http.StatusServiceUnavailable
+		}
+	}
+	key := []byte(cfg.Key)
+
+	return func(c *gin.Context) {
+		sent := c.GetHeader(cfg.Header)
+		if sent == "" || subtle.ConstantTimeCompare([]byte(sent), key) != 1 {
+			c.Next()
+			return
+		}
+		rule, ok := matchChaosRule(cfg.Rules, c.Request.Method, c.FullPath())
+		if !ok {
+			c.Next()
+			return
+		}
+
+		if delay := rule.Latency + jitter(rule.Jitter); delay > 0 {
+			c.Header("X-Chaos", "latency")
+			select {
+			case <-time.After(delay):
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+		}
+		if rule.DropRate > 0 && rand.Float64() < rule.DropRate {
+			// Recovery re-panics this, and net/http closes the connection
+			panic(http.ErrAbortHandler)
+		}
+		if rule.ErrorRate > 0 && rand.Float64() < rule.ErrorRate {
+			c.Writer.Header().Add("X-Chaos", "error")
+			code := response.ErrorCodeServiceUnavailable
+			if rule.ErrorStatus != http.StatusServiceUnavailable {
+				code = response.ErrorCodeInternal
+			}
+			response.ErrorWithInfo(c, rule.ErrorStatus, response.ErrorInfo{
+				Type:    response.ErrorTypeAPI,
+				Code:    code,
+				Message: "injected fault",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// validate checks the rule's rates and status.
+func (r ChaosRule) validate() error {
+	if r.ErrorRate < 0 || r.ErrorRate > 1 {
+		return fmt.Errorf("error rate %v must be between 0 and 1", r.ErrorRate)
+	}
+	if r.DropRate < 0 || r.DropRate > 1 {
+		return fmt.Errorf("drop rate %v must be between 0 and 1", r.DropRate)
+	}
+	if r.ErrorStatus != 0 && (r.ErrorStatus < 400 || r.ErrorStatus > 599) {
+		return fmt.Errorf("error status %d must be 4xx or 5xx", r.ErrorStatus)
+	}
+	if r.Latency < 0 || r.Jitter < 0 {
+		return fmt.Errorf("latency must not be negative")
+	}
+	return nil
+}
+
+// matchChaosRule returns the first rule matching method and route.
+func matchChaosRule(rules []ChaosRule, method, route string) (ChaosRule, bool) {
+	for _, rule := range rules {
+		if rule.Method != "" && rule.Method != method {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(rule.Route, "*"); ok {
+			if strings.HasPrefix(route, prefix) {
+				return rule, true
+			}
+		} else if rule.Route == route {
+			return rule, true
+		}
+	}
+	return ChaosRule{}, false
+}
+
+// jitter returns a random duration in [0, max).
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return rand.N(max)
+}
+
+// ParseChaosRules parses rules from their text form, so they can come from
+// the environment. Rules are separated by ";" and consist of an optional
+// method, a route pattern, and key=value settings (latency, jitter, error,
+// status, drop):
+//
+//	/api/search* latency=2s jitter=1s; POST /api/galleries error=0.2 drop=0.05
+func ParseChaosRules(s string) ([]ChaosRule, error) {
+	var rules []ChaosRule
+	for _, text := range strings.Split(s, ";") {
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		var rule ChaosRule
+		if !strings.HasPrefix(fields[0], "/") && !strings.HasPrefix(fields[0], "*") && !strings.Contains(fields[0], "=") {
+			rule.Method = strings.ToUpper(fields[0])
+			fields = fields[1:]
+		}
+		if len(fields) == 0 || strings.Contains(fields[0], "=") {
+			return nil, fmt.Errorf("chaos rule %q: missing route", strings.TrimSpace(text))
+		}
+		rule.Route = fields[0]
+
+		for _, setting := range fields[1:] {
+			name, value, ok := strings.Cut(setting, "=")
+			if !ok {
+				return nil, fmt.Errorf("chaos rule %q: %q is not key=value", strings.TrimSpace(text), setting)
+			}
+			var err error
+			switch name {
+			case "latency":
+				rule.Latency, err = time.ParseDuration(value)
+			case "jitter":
+				rule.Jitter, err = time.ParseDuration(value)
+			case "error":
+				rule.ErrorRate, err = strconv.ParseFloat(value, 64)
+			case "status":
+				rule.ErrorStatus, err = strconv.Atoi(value)
+			case "drop":
+				rule.DropRate, err = strconv.ParseFloat(value, 64)
+			default:
+				err = fmt.Errorf("unknown setting")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("chaos rule %q: invalid %s: %q", strings.TrimSpace(text), name, value)
+			}
+		}
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("chaos rule %q: %w", strings.TrimSpace(text), err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,171 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func newChaosRouter(cfg middleware.ChaosConfig) *gin.Engine {
+	router := gin.New()
+	router.Use(middleware.Chaos(cfg))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	router.GET("/api/galleries", ok)
+	router.POST("/api/galleries", ok)
+	router.GET("/api/search", ok)
+	return router
+}
+
+func TestChaosErrors(t *testing.T) {
+	rules := []middleware.ChaosRule{
+		{Method: http.MethodPost, Route: "/api/galleries", ErrorRate: 1, ErrorStatus: http.StatusInternalServerError},
+		{Route: "/api/*", ErrorRate: 1},
+	}
+	tests := []struct {
+		name     string
+		enabled  bool
+		method   string
+		target   string
+		key      string
+		want     int
+		wantBody string
+	}{
+		{"disabled", false, "GET", "/api/galleries", "secret", http.StatusOK, "ok"},
+		{"no key", true, "GET", "/api/galleries", "", http.StatusOK, "ok"},
+		{"wrong key", true, "GET", "/api/galleries", "guess", http.StatusOK, "ok"},
+		{"prefix rule", true, "GET", "/api/search", "secret", http.StatusServiceUnavailable, `"code":"service_unavailable"`},
+		{"method rule", true, "POST", "/api/galleries", "secret", http.StatusInternalServerError, `"code":"internal"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newChaosRouter(middleware.ChaosConfig{Enabled: tt.enabled, Key: "secret", Rules: rules})
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.key != "" {
+				req.Header.Set("X-Chaos-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %s, got %s", tt.wantBody, w.Body.String())
+			}
+			injected := w.Header().Get("X-Chaos") == "error"
+			if injected != (tt.want != http.StatusOK) {
+				t.Errorf("unexpected X-Chaos header %q", w.Header().Get("X-Chaos"))
+			}
+		})
+	}
+}
+
+func TestChaosLatency(t *testing.T) {
+	router := newChaosRouter(middleware.ChaosConfig{
+		Enabled: true,
+		Key:     "secret",
+		Rules:   []middleware.ChaosRule{{Route: "/api/search", Latency: 30 * time.Millisecond}},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/api/search", nil)
+	req.Header.Set("X-Chaos-Key", "secret")
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	router.ServeHTTP(w, req)
+
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("expected at least 30ms of latency, got %s", elapsed)
+	}
+	if w.Code != http.StatusOK || w.Header().Get("X-Chaos") != "latency" {
+		t.Errorf("expected delayed 200, got %d with X-Chaos %q", w.Code, w.Header().Get("X-Chaos"))
+	}
+}
+
+func TestChaosDrop(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.Recovery(), middleware.Chaos(middleware.ChaosConfig{
+		Enabled: true,
+		Key:     "secret",
+		Rules:   []middleware.ChaosRule{{Route: "*", DropRate: 1}},
+	}))
+	router.GET("/api/galleries", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/galleries", nil)
+	req.Header.Set("X-Chaos-Key", "secret")
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler panic, got %v", v)
+		}
+	}()
+	router.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestChaosInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  middleware.ChaosConfig
+	}{
+		{"missing key", middleware.ChaosConfig{Enabled: true}},
+		{"error rate", middleware.ChaosConfig{Enabled: true, Key: "k", Rules: []middleware.ChaosRule{{Route: "*", ErrorRate: 2}}}},
+		{"status", middleware.ChaosConfig{Enabled: true, Key: "k", Rules: []middleware.ChaosRule{{Route: "*", ErrorStatus: 200}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			middleware.Chaos(tt.cfg)
+		})
+	}
+}
+
+func TestParseChaosRules(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []middleware.ChaosRule
+		wantErr string
+	}{
+		{"", nil, ""},
+		{
+			"/api/search* latency=2s jitter=500ms; post /api/galleries error=0.2 status=500 drop=0.05",
+			[]middleware.ChaosRule{
+				{Route: "/api/search*", Latency: 2 * time.Second, Jitter: 500 * time.Millisecond},
+				{Method: "POST", Route: "/api/galleries", ErrorRate: 0.2, ErrorStatus: 500, DropRate: 0.05},
+			},
+			"",
+		},
+		{"GET latency=1s", nil, `chaos rule "GET latency=1s": missing route`},
+		{"/api error=lots", nil, `chaos rule "/api error=lots": invalid error: "lots"`},
+		{"/api timeout=1s", nil, `chaos rule "/api timeout=1s": invalid timeout: "1s"`},
+		{"/api drop", nil, `chaos rule "/api drop": "drop" is not key=value`},
+		{"/api drop=1.5", nil, `chaos rule "/api drop=1.5": drop rate 1.5 must be between 0 and 1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := middleware.ParseChaosRules(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultAppHeader is the header our own mobile apps send to identify themselves.
+// The value format is "<app>/<version>", e.g. "doujins-ios/2.4.1".
+const DefaultAppHeader = "X-Client-App"
+
+// Device classes reported by the built-in User-Agent parser.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+// Client describes the client that made the request.
+type Client struct {
+	UserAgent string   // raw User-Agent header
+	Device    string   // device class (see Device* constants)
+	OS        string   // e.g. "ios", "android", "windows", "macos", "linux"
+	Browser   string   // e.g. "chrome", "safari", "firefox", "edge"
+	App       *AppInfo // set when the request came from one of our apps
+}
+
+// AppInfo identifies one of our own apps from the app header.
+type AppInfo struct {
+	Name    string // e.g. "doujins-ios"
+	Version string // e.g. "2.4.1"
+}
+
+// AtLeast reports whether the app version is >= the given dotted version.
+// Missing or non-numeric components compare as 0.
+func (a *AppInfo) AtLeast(version string) bool {
+	if a == nil {
+		return false
+	}
+	return compareVersions(a.Version, version) >= 0
+}
+
+// UserAgentParser parses a User-Agent header into client details.
+// Implement this to plug in a full parser (e.g. uap-go) in place of the built-in one.
+type UserAgentParser interface {
+	Parse(userAgent string) Client
+}
+
+// UserAgentParserFunc adapts a function to the UserAgentParser interface.
+type UserAgentParserFunc func(userAgent string) Client
+
+// Parse calls f(userAgent).
+func (f UserAgentParserFunc) Parse(userAgent string) Client {
+	return f(userAgent)
+}
+
+// ClientInfoConfig configures the client info middleware.
+type ClientInfoConfig struct {
+	// Parser for the User-Agent header (defaults to the built-in ParseUserAgent)
+	Parser UserAgentParser
+	// AppHeader identifying our own apps (defaults to "X-Client-App")
+	AppHeader string
+	// Apps lists the names of our apps, e.g. "doujins-ios". Any client can
+	// send the app header, so Client.App is only set for these names, and
+	// never if Apps is empty.
+	Apps []string
+}
+
+// ClientInfo returns middleware that parses the User-Agent into device class,
+// OS, and browser, and detects our own apps via the app header.
+//
+// The result is stored in gin context and retrieved via GetClientInfo(c),
+// or via ClientFromContext(ctx) in layers without gin.
+func ClientInfo(cfg ClientInfoConfig) gin.HandlerFunc {
+	parser := cfg.Parser
+	if parser == nil {
+		parser = UserAgentParserFunc(ParseUserAgent)
+	}
+
+	appHeader := cfg.AppHeader
+	if appHeader == "" {
+		appHeader = DefaultAppHeader
+	}
+	apps := make(map[string]bool, len(cfg.Apps))
+	for _, name := range cfg.Apps {
+		apps[strings.ToLower(name)] = true
+	}
+
+	return func(c *gin.Context) {
+		ua := c.GetHeader("User-Agent")
+		client := parser.Parse(ua)
+		client.UserAgent = ua
+		if app := parseAppHeader(c.GetHeader(appHeader)); app != nil && apps[app.Name] {
+			client.App = app
+		}
+
+		c.Set("client_info", client)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), clientContextKey{}, client))
+
+		c.Next()
+	}
+}
+
+// GetClientInfo retrieves the client details from the gin context.
+// Returns a Client with Device "unknown" if the middleware is not installed
+// (see SetStrictMode).
+func GetClientInfo(c *gin.Context) Client {
+	if c == nil {
+		misuse("GetClientInfo", "with a nil context")
+		return Client{Device: DeviceUnknown}
+	}
+	if v, exists := c.Get("client_info"); exists {
+		if client, ok := v.(Client); ok {
+			return client
+		}
+	}
+	misuse("GetClientInfo", "without the ClientInfo middleware")
+	return Client{Device: DeviceUnknown}
+}
+
+// clientContextKey is the request context key for client details.
+type clientContextKey struct{}
+
+// ClientFromContext retrieves the client details stored by the ClientInfo middleware.
+// The second return value is false if none are present.
+func ClientFromContext(ctx context.Context) (Client, bool) {
+	if ctx == nil {
+		misuse("ClientFromContext", "with a nil context")
+		return Client{}, false
+	}
+	client, ok := ctx.Value(clientContextKey{}).(Client)
+	return client, ok
+}
+
+// ParseUserAgent is the built-in User-Agent parser. It recognizes the common
+// browsers, operating systems, and crawlers using substring matching; it is
+// intentionally simple and does not extract versions.
+func ParseUserAgent(userAgent string) Client {
+	ua := strings.ToLower(userAgent)
+	client := Client{Device: DeviceUnknown}
+	if ua == "" {
+		return client
+	}
+
+	// Bots first - many crawlers also claim to be Mozilla/Chrome
+	for _, marker := range []string{"bot", "crawler", "spider", "slurp", "curl/", "wget/", "python-requests", "go-http-client"} {
+		if strings.Contains(ua, marker) {
+			client.Device = DeviceBot
+			return client
+		}
+	}
+
+	// Operating system and device class
+	switch {
+	case strings.Contains(ua, "ipad"):
+		client.OS, client.Device = "ios", DeviceTablet
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipod"):
+		client.OS, client.Device = "ios", DeviceMobile
+	case strings.Contains(ua, "android"):
+		client.OS = "android"
+		if strings.Contains(ua, "mobile") {
+			client.Device = DeviceMobile
+		} else {
+			client.Device = DeviceTablet
+		}
+	case strings.Contains(ua, "windows"):
+		client.OS, client.Device = "windows", DeviceDesktop
+	case strings.Contains(ua, "macintosh") || strings.Contains(ua, "mac os x"):
+		client.OS, client.Device = "macos", DeviceDesktop
+	case strings.Contains(ua, "cros"):
+		client.OS, client.Device = "chromeos", DeviceDesktop
+	case strings.Contains(ua, "linux"):
+		client.OS, client.Device = "linux", DeviceDesktop
+	}
+
+	// Browser - order matters, since Edge/Opera also contain "chrome" and Chrome contains "safari"
+	switch {
+	case strings.Contains(ua, "edg/") || strings.Contains(ua, "edge/"):
+		client.Browser = "edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		client.Browser = "opera"
+	case strings.Contains(ua, "samsungbrowser"):
+		client.Browser = "samsung"
+	case strings.Contains(ua, "firefox/") || strings.Contains(ua, "fxios/"):
+		client.Browser = "firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		client.Browser = "chrome"
+	case strings.Contains(ua, "safari/"):
+		client.Browser = "safari"
+	}
+
+	return client
+}
+
+// parseAppHeader parses "<app>/<version>" into AppInfo.
+// Returns nil for empty or malformed values, including versions that
+// aren't dotted numbers.
+func parseAppHeader(value string) *AppInfo {
+	value = strings.TrimSpace(value)
+	name, version, ok := strings.Cut(value, "/")
+	if !ok {
+		return nil
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if name == "" || !validVersion(version) {
+		return nil
+	}
+	return &AppInfo{Name: name, Version: version}
+}
+
+// compareVersions compares dotted numeric versions, returning -1, 0, or 1.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// validVersion reports whether version is a dotted version number, e.g.
+// "2.4.1".
+func validVersion(version string) bool {
+	for _, part := range strings.Split(version, ".") {
+		if part == "" || len(part) > 9 || strings.Trim(part, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
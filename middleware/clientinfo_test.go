@@ -0,0 +1,172 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		name        string
+		ua          string
+		wantDevice  string
+		wantOS      string
+		wantBrowser string
+	}{
+		{
+			name:        "desktop chrome on windows",
+			ua:          "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			wantDevice:  middleware.DeviceDesktop,
+			wantOS:      "windows",
+			wantBrowser: "chrome",
+		},
+		{
+			name:        "iphone safari",
+			ua:          "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			wantDevice:  middleware.DeviceMobile,
+			wantOS:      "ios",
+			wantBrowser: "safari",
+		},
+		{
+			name:        "android tablet",
+			ua:          "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			wantDevice:  middleware.DeviceTablet,
+			wantOS:      "android",
+			wantBrowser: "chrome",
+		},
+		{
+			name:        "edge is not chrome",
+			ua:          "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0",
+			wantDevice:  middleware.DeviceDesktop,
+			wantOS:      "windows",
+			wantBrowser: "edge",
+		},
+		{
+			name:       "crawler",
+			ua:         "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			wantDevice: middleware.DeviceBot,
+		},
+		{
+			name:       "empty",
+			ua:         "",
+			wantDevice: middleware.DeviceUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := middleware.ParseUserAgent(tt.ua)
+			if got.Device != tt.wantDevice {
+				t.Errorf("Device = %q, want %q", got.Device, tt.wantDevice)
+			}
+			if got.OS != tt.wantOS {
+				t.Errorf("OS = %q, want %q", got.OS, tt.wantOS)
+			}
+			if got.Browser != tt.wantBrowser {
+				t.Errorf("Browser = %q, want %q", got.Browser, tt.wantBrowser)
+			}
+		})
+	}
+}
+
+func TestClientInfoMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.ClientInfo(middleware.ClientInfoConfig{Apps: []string{"doujins-ios"}}))
+
+	var got middleware.Client
+	var fromCtx bool
+	router.GET("/test", func(c *gin.Context) {
+		got = middleware.GetClientInfo(c)
+		_, fromCtx = middleware.ClientFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("User-Agent", "Doujins/2.4.1 (iPhone; iOS 17.0)")
+	req.Header.Set(middleware.DefaultAppHeader, "doujins-ios/2.4.1")
+	router.ServeHTTP(w, req)
+
+	if !fromCtx {
+		t.Error("expected client info in request context")
+	}
+	if got.OS != "ios" || got.Device != middleware.DeviceMobile {
+		t.Errorf("unexpected client: %+v", got)
+	}
+	if got.App == nil || got.App.Name != "doujins-ios" || got.App.Version != "2.4.1" {
+		t.Fatalf("unexpected app: %+v", got.App)
+	}
+	if !got.App.AtLeast("2.4") || got.App.AtLeast("2.10.0") {
+		t.Error("unexpected AtLeast result")
+	}
+}
+
+func TestClientInfoAppAllowlist(t *testing.T) {
+	tests := []struct {
+		name   string
+		apps   []string
+		header string
+		want   string
+	}{
+		{"listed app", []string{"doujins-ios"}, "doujins-ios/2.4.1", "doujins-ios 2.4.1"},
+		{"case-insensitive", []string{"Doujins-iOS"}, "DOUJINS-IOS/v2.4", "doujins-ios 2.4"},
+		{"unlisted app", []string{"doujins-ios"}, "evil-app/1.0", ""},
+		{"no allowlist", nil, "doujins-ios/2.4.1", ""},
+		{"malformed version", []string{"doujins-ios"}, "doujins-ios/2.x", ""},
+		{"empty version part", []string{"doujins-ios"}, "doujins-ios/2..1", ""},
+		{"no version", []string{"doujins-ios"}, "doujins-ios", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(middleware.ClientInfo(middleware.ClientInfoConfig{Apps: tt.apps}))
+			router.GET("/test", func(c *gin.Context) {
+				if app := middleware.GetClientInfo(c).App; app != nil {
+					c.String(http.StatusOK, app.Name+" "+app.Version)
+				}
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Header.Set(middleware.DefaultAppHeader, tt.header)
+			router.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected app %q, got %q", tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestClientInfoCustomParser(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.ClientInfo(middleware.ClientInfoConfig{
+		Parser: middleware.UserAgentParserFunc(func(string) middleware.Client {
+			return middleware.Client{Device: "tv"}
+		}),
+	}))
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, middleware.GetClientInfo(c).Device)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Body.String() != "tv" {
+		t.Errorf("expected 'tv', got '%s'", w.Body.String())
+	}
+}
+
+func TestGetClientInfoWithoutMiddleware(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if got := middleware.GetClientInfo(c); got.Device != middleware.DeviceUnknown || got.App != nil {
+		t.Errorf("expected unknown client, got %+v", got)
+	}
+}
@@ -0,0 +1,262 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// CoalesceConfig configures request coalescing.
+type CoalesceConfig struct {
+	// Class partitions requests by who is asking, for handlers whose output
+	// depends on the principal (defaults to the auth.Principal's type and ID
+	// and the response audiences, which decide field redaction, so no
+	// caller is handed another's response). Return "" for anonymous
+	// requests.
+	Class func(c *gin.Context) string
+	// MaxBodyBytes is the largest response shared with waiting requests
+	// (defaults to 1MB). Larger responses make waiters run the handler.
+	MaxBodyBytes int
+	// Headers are the request headers that are part of the key, for the
+	// negotiation the routes do (defaults to Accept, Accept-Encoding,
+	// API-Version, and Key-Case)
+	Headers []string
+}
+
+// defaultCoalesceHeaders are the request headers the response negotiation
+// of this module reads.
+var defaultCoalesceHeaders = []string{"Accept", "Accept-Encoding", response.DefaultVersionHeader, "Key-Case"}
+
+// defaultCoalesceClass keys requests on the principal and the response
+// audiences. Anonymous requests without audiences share "".
+func defaultCoalesceClass(c *gin.Context) string {
+	audiences := strings.Join(response.Audiences(c), ",")
+	if p, ok := auth.GetPrincipal(c); ok {
+		return p.Type + ":" + p.ID + "|" + audiences
+	}
+	return audiences
+}
+
+// conditionalHeaders make a response depend on what the client has cached.
+var conditionalHeaders = []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range", "Range"}
+
+// Coalesce returns middleware that collapses concurrent identical GET
+// requests into a single handler execution and fans the response out to
+// all of them, protecting the database during cache-miss stampedes on
+// popular pages. Requests are identical when the path, query, language
+// (GetLanguage), Class, and Headers match. Conditional and range requests
+// are never coalesced, as their response depends on the client's copy.
+//
+// Only the first request (the leader) runs the handlers below; requests
+// arriving while it runs wait for it and replay its status, headers, and
+// body. Set-Cookie and Server-Timing are never replayed, and headers the waiter's own
+// middleware already set, such as X-Request-ID or rate limit headers, are
+// kept; Vary is merged. A waiter only replays the response if
+// its request also matches the leader's on every header the response
+// Varies on; Accept-Language and Cookie count as matching when the language
+// and Class do. If the leader panics, its response is too large, or it
+// Varies on "*" or on a header that differs, waiters run the handlers
+// themselves. Install it after
+// Language and auth middleware, and only on routes whose responses don't
+// depend on anything outside the key.
+//
+//	galleries.GET("/:id", middleware.Coalesce(middleware.CoalesceConfig{}), getGallery)
+func Coalesce(cfg CoalesceConfig) gin.HandlerFunc {
+	class := cfg.Class
+	if class == nil {
+		class = defaultCoalesceClass
+	}
+	maxBody := cfg.MaxBodyBytes
+	if maxBody == 0 {
+		maxBody = 1 << 20
+	}
+	headers := cfg.Headers
+	if headers == nil {
+		headers = defaultCoalesceHeaders
+	}
+
+	g := &flightGroup{flights: map[string]*flight{}}
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet || isConditional(c.Request) {
+			c.Next()
+			return
+		}
+
+		lang, _ := detectedLanguage(c)
+		var key strings.Builder
+		key.WriteString(c.Request.URL.Path + "?" + c.Request.URL.RawQuery + "|" + lang + "|" + class(c))
+		for _, name := range headers {
+			key.WriteString("|" + strings.Join(c.Request.Header.Values(name), ","))
+		}
+		f, leader := g.join(key.String())
+		if leader {
+			g.lead(c, key.String(), f, maxBody)
+			return
+		}
+
+		select {
+		case <-f.done:
+		case <-c.Request.Context().Done():
+			c.Abort()
+			return
+		}
+		if f.resp == nil || !f.resp.matches(c.Request) {
+			c.Next()
+			return
+		}
+		f.resp.replay(c)
+		c.Abort()
+	}
+}
+
+// flightGroup tracks the in-flight leader for each key.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is one leader execution. resp is set before done is closed, and
+// left nil if the response can't be shared.
+type flight struct {
+	done chan struct{}
+	resp *capturedResponse
+}
+
+// join returns the in-flight execution for key, or starts one if there is
+// none, reporting whether the caller is its leader.
+func (g *flightGroup) join(key string) (*flight, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f, ok := g.flights[key]; ok {
+		return f, false
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	return f, true
+}
+
+// lead runs the handlers while capturing the response for the waiters.
+// A panic still releases the waiters (without a response) and propagates.
+func (g *flightGroup) lead(c *gin.Context, key string, f *flight, maxBody int) {
+	w := &captureWriter{ResponseWriter: c.Writer, max: maxBody}
+	c.Writer = w
+
+	defer func() {
+		c.Writer = w.ResponseWriter
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+
+	c.Next()
+
+	if w.overflow {
+		return
+	}
+	header := w.Header().Clone()
+	header.Del("Set-Cookie")
+	// Waiters time their own request, if ServerTiming runs before Coalesce
+	header.Del("Server-Timing")
+	vary := map[string]string{}
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			switch name {
+			case "*":
+				return
+			case "", "Accept-Language", "Cookie":
+				continue
+			}
+			vary[name] = strings.Join(c.Request.Header.Values(name), ",")
+		}
+	}
+	f.resp = &capturedResponse{status: w.Status(), header: header, body: w.buf.Bytes(), vary: vary}
+}
+
+// isConditional reports whether r is a conditional or range request.
+func isConditional(r *http.Request) bool {
+	for _, name := range conditionalHeaders {
+		if r.Header.Get(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// captureWriter copies the body written through it, up to max bytes.
+type captureWriter struct {
+	gin.ResponseWriter
+	buf      bytes.Buffer
+	max      int
+	overflow bool
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *captureWriter) capture(b []byte) {
+	if w.overflow {
+		return
+	}
+	if w.buf.Len()+len(b) > w.max {
+		w.overflow = true
+		w.buf = bytes.Buffer{}
+		return
+	}
+	w.buf.Write(b)
+}
+
+// capturedResponse is a response recorded from a leader, with the leader's
+// values of the request headers it Varies on.
+type capturedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+	vary   map[string]string
+}
+
+// matches reports whether r has the leader's values of the headers the
+// response Varies on.
+func (r *capturedResponse) matches(req *http.Request) bool {
+	for name, value := range r.vary {
+		if strings.Join(req.Header.Values(name), ",") != value {
+			return false
+		}
+	}
+	return true
+}
+
+// replay writes the captured response to c. Headers already set for c's
+// request are per request, so only those it lacks are copied.
+func (r *capturedResponse) replay(c *gin.Context) {
+	h := c.Writer.Header()
+	for k, v := range r.header {
+		switch {
+		case k == "Vary":
+			response.AddVary(h, v...)
+		case len(h[k]) == 0:
+			h[k] = append([]string(nil), v...)
+		}
+	}
+	c.Writer.WriteHeader(r.status)
+	if len(r.body) == 0 {
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	_, _ = c.Writer.Write(r.body)
+}
@@ -0,0 +1,264 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// coalesceRouter returns a router whose /galleries handler blocks until
+// release is closed and counts its executions.
+func coalesceRouter(cfg middleware.CoalesceConfig, release chan struct{}, calls *atomic.Int32) *gin.Engine {
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.GET("/galleries", middleware.Coalesce(cfg), func(c *gin.Context) {
+		n := calls.Add(1)
+		<-release
+		if c.Query("panic") == "1" && n == 1 {
+			panic("leader failed")
+		}
+		c.SetCookie("session", "abc", 60, "/", "", false, true)
+		c.Header("X-Page", c.Query("page"))
+		response.Object(c, gin.H{"object": "list", "page": c.Query("page")})
+	})
+	return router
+}
+
+// concurrentGets sends the requests at once and returns their recorders
+// after release has been closed and all have completed.
+func concurrentGets(router http.Handler, release chan struct{}, urls ...string) []*httptest.ResponseRecorder {
+	reqs := make([]*http.Request, len(urls))
+	for i, url := range urls {
+		reqs[i], _ = http.NewRequest("GET", url, nil)
+	}
+	return concurrentRequests(router, release, reqs...)
+}
+
+// concurrentRequests is concurrentGets for prepared requests.
+func concurrentRequests(router http.Handler, release chan struct{}, reqs ...*http.Request) []*httptest.ResponseRecorder {
+	recorders := make([]*httptest.ResponseRecorder, len(reqs))
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(w *httptest.ResponseRecorder, req *http.Request) {
+			defer wg.Done()
+			router.ServeHTTP(w, req)
+		}(recorders[i], req)
+	}
+	// Let every request reach the handler or start waiting
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	return recorders
+}
+
+func TestCoalesce(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	router := coalesceRouter(middleware.CoalesceConfig{}, release, &calls)
+
+	recorders := concurrentGets(router, release,
+		"/galleries?page=1", "/galleries?page=1", "/galleries?page=1", "/galleries?page=2")
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 handler executions, got %d", got)
+	}
+
+	cookies := 0
+	for i, w := range recorders {
+		wantPage := "1"
+		if i == 3 {
+			wantPage = "2"
+		}
+		if w.Code != http.StatusOK {
+			t.Errorf("request %d: expected status 200, got %d", i, w.Code)
+		}
+		if want := `{"object":"list","page":"` + wantPage + `"}`; w.Body.String() != want {
+			t.Errorf("request %d: expected %s, got %s", i, want, w.Body.String())
+		}
+		if w.Header().Get("X-Page") != wantPage {
+			t.Errorf("request %d: expected X-Page %s, got %q", i, wantPage, w.Header().Get("X-Page"))
+		}
+		if w.Header().Get("Set-Cookie") != "" {
+			cookies++
+		}
+	}
+	// Only the two leaders set the cookie
+	if cookies != 2 {
+		t.Errorf("expected 2 responses with Set-Cookie, got %d", cookies)
+	}
+}
+
+func TestCoalesceKeepsPerRequestHeaders(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	var n atomic.Int32
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Header("X-Request-ID", "req-"+strconv.Itoa(int(n.Add(1))))
+	}, middleware.ServerTiming())
+	router.GET("/galleries", middleware.Coalesce(middleware.CoalesceConfig{}), func(c *gin.Context) {
+		calls.Add(1)
+		end := middleware.StartSpan(c, "db")
+		<-release
+		end()
+		c.Header("X-Page", "1")
+		response.Object(c, gin.H{"object": "list"})
+	})
+
+	recorders := concurrentGets(router, release, "/galleries", "/galleries")
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 1 handler execution, got %d", got)
+	}
+	ids := map[string]bool{}
+	timed := 0
+	for i, w := range recorders {
+		ids[w.Header().Get("X-Request-ID")] = true
+		if w.Header().Get("X-Page") != "1" {
+			t.Errorf("request %d: expected X-Page 1, got %q", i, w.Header().Get("X-Page"))
+		}
+		if w.Header().Get("Server-Timing") != "" {
+			timed++
+		}
+	}
+	if !ids["req-1"] || !ids["req-2"] {
+		t.Errorf("expected request IDs req-1 and req-2, got %v", ids)
+	}
+	// Only the leader timed a span
+	if timed != 1 {
+		t.Errorf("expected 1 response with Server-Timing, got %d", timed)
+	}
+}
+
+func TestCoalesceKeysOnPrincipal(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-User"); id != "" {
+			auth.SetPrincipal(c, auth.Principal{ID: id, Type: auth.TypeUser})
+		}
+	})
+	router.GET("/me", middleware.Coalesce(middleware.CoalesceConfig{}), func(c *gin.Context) {
+		calls.Add(1)
+		<-release
+		p, _ := auth.GetPrincipal(c)
+		response.Object(c, gin.H{"id": p.ID})
+	})
+
+	get := func(user string) *http.Request {
+		req, _ := http.NewRequest("GET", "/me", nil)
+		req.Header.Set("X-User", user)
+		return req
+	}
+	reqs := []*http.Request{get("u1"), get("u2"), get("u1")}
+	recorders := concurrentRequests(router, release, reqs...)
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 handler executions, got %d", got)
+	}
+	for i, w := range recorders {
+		if want := `{"id":"` + reqs[i].Header.Get("X-User") + `"}`; w.Body.String() != want {
+			t.Errorf("request %d: expected %s, got %s", i, want, w.Body.String())
+		}
+	}
+}
+
+func TestCoalesceLeaderPanic(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	router := coalesceRouter(middleware.CoalesceConfig{}, release, &calls)
+
+	recorders := concurrentGets(router, release, "/galleries?panic=1", "/galleries?panic=1")
+
+	statuses := map[int]int{}
+	for _, w := range recorders {
+		statuses[w.Code]++
+	}
+	// The leader gets the 500; the waiter runs the handler itself
+	if statuses[http.StatusInternalServerError] != 1 || statuses[http.StatusOK] != 1 {
+		t.Errorf("expected one 500 and one 200, got %v", statuses)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 handler executions, got %d", got)
+	}
+}
+
+func TestCoalesceMaxBodyBytes(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	router := coalesceRouter(middleware.CoalesceConfig{MaxBodyBytes: 8}, release, &calls)
+
+	recorders := concurrentGets(router, release, "/galleries?page=1", "/galleries?page=1")
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 handler executions, got %d", got)
+	}
+	for i, w := range recorders {
+		if w.Body.String() != `{"object":"list","page":"1"}` {
+			t.Errorf("request %d: unexpected body %s", i, w.Body.String())
+		}
+	}
+}
+
+func TestCoalesceKeysOnNegotiation(t *testing.T) {
+	get := func(header, value string) *http.Request {
+		req, _ := http.NewRequest("GET", "/galleries?page=1", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		return req
+	}
+
+	tests := []struct {
+		name      string
+		reqs      []*http.Request
+		wantCalls int32
+	}{
+		{"same headers", []*http.Request{get("Accept", "application/json"), get("Accept", "application/json")}, 1},
+		{"different Accept", []*http.Request{get("Accept", "application/json"), get("Accept", "application/msgpack")}, 2},
+		{"different key case", []*http.Request{get("", ""), get("Key-Case", "camel")}, 2},
+		{"conditional", []*http.Request{get("", ""), get("If-None-Match", `"abc"`)}, 2},
+		{"different Varied header", []*http.Request{get("X-Region", "eu"), get("X-Region", "us")}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			var calls atomic.Int32
+			router := gin.New()
+			router.GET("/galleries", middleware.Coalesce(middleware.CoalesceConfig{}), func(c *gin.Context) {
+				calls.Add(1)
+				<-release
+				response.AddVary(c.Writer.Header(), "X-Region")
+				response.Object(c, gin.H{"region": c.GetHeader("X-Region")})
+			})
+
+			recorders := concurrentRequests(router, release, tt.reqs...)
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("expected %d handler executions, got %d", tt.wantCalls, got)
+			}
+			for i, w := range recorders {
+				if tt.reqs[i].Header.Get("Accept") == "application/msgpack" {
+					continue
+				}
+				if want := `{"region":"` + tt.reqs[i].Header.Get("X-Region") + `"}`; w.Body.String() != want {
+					t.Errorf("request %d: expected %s, got %s", i, want, w.Body.String())
+				}
+			}
+		})
+	}
+}
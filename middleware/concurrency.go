@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// ConcurrencyLimitConfig configures the concurrency limiter.
+type ConcurrencyLimitConfig struct {
+	// MaxInFlight is the number of requests handled at once (required)
+	MaxInFlight int
+	// MaxQueue is the number of requests waiting for a slot (defaults to MaxInFlight)
+	MaxQueue int
+	// QueueTimeout is how long a request waits for a slot (defaults to 5s)
+	QueueTimeout time.Duration
+	// RetryAfter is sent with 503 responses (defaults to 1s)
+	RetryAfter time.Duration
+}
+
+// ConcurrencyLimit returns middleware that bounds the number of requests
+// handled at once. Requests over the limit wait in a queue ordered by
+// GetPriority (set by PriorityClassifier), so when the server is saturated,
+// paid-tier traffic is admitted before anonymous scraping:
+//
+//   - Freed slots go to the highest-priority waiter, first come first served
+//     within a priority
+//   - When the queue is full, a request evicts the oldest lowest-priority
+//     waiter if it outranks it, otherwise it is rejected
+//   - PriorityCritical requests (health checks) bypass the limiter entirely
+//
+// Rejected and timed-out requests get 503 with a Retry-After header.
+func ConcurrencyLimit(cfg ConcurrencyLimitConfig) gin.HandlerFunc {
+	if cfg.MaxInFlight <= 0 {
+		panic("middleware: ConcurrencyLimit requires MaxInFlight > 0")
+	}
+	if cfg.MaxQueue == 0 {
+		cfg.MaxQueue = cfg.MaxInFlight
+	}
+	if cfg.QueueTimeout == 0 {
+		cfg.QueueTimeout = 5 * time.Second
+	}
+	if cfg.RetryAfter == 0 {
+		cfg.RetryAfter = time.Second
+	}
+	retryAfter := strconv.Itoa(max(int(cfg.RetryAfter/time.Second), 1))
+
+	l := &limiter{max: cfg.MaxInFlight, maxQueue: cfg.MaxQueue}
+
+	return func(c *gin.Context) {
+		p := GetPriority(c)
+		if p >= PriorityCritical {
+			c.Next()
+			return
+		}
+
+		if err := l.acquire(c.Request.Context(), p, cfg.QueueTimeout); err != nil {
+			c.Header("Retry-After", retryAfter)
+			response.ServiceUnavailable(c, "server is busy, try again later")
+			c.Abort()
+			return
+		}
+		defer l.release()
+
+		c.Next()
+	}
+}
+
+// errBusy is returned when a request is rejected, evicted, or times out.
+var errBusy = errors.New("server busy")
+
+// limiter is a counting semaphore whose waiters are admitted by priority.
+type limiter struct {
+	mu       sync.Mutex
+	max      int
+	maxQueue int
+	inFlight int
+	seq      uint64
+	queue    waitQueue
+}
+
+// waiter is a request waiting for a slot. ready is closed once it is
+// admitted or evicted; admitted tells which.
+type waiter struct {
+	priority Priority
+	seq      uint64
+	index    int
+	admitted bool
+	ready    chan struct{}
+}
+
+// acquire takes a slot, waiting up to timeout if none is free.
+func (l *limiter) acquire(ctx context.Context, p Priority, timeout time.Duration) error {
+	l.mu.Lock()
+	if l.inFlight < l.max {
+		l.inFlight++
+		l.mu.Unlock()
+		return nil
+	}
+
+	if len(l.queue) >= l.maxQueue {
+		lowest := l.queue.lowest()
+		if lowest == nil || lowest.priority >= p {
+			l.mu.Unlock()
+			return errBusy
+		}
+		heap.Remove(&l.queue, lowest.index)
+		close(lowest.ready)
+	}
+
+	l.seq++
+	w := &waiter{priority: p, seq: l.seq, ready: make(chan struct{})}
+	heap.Push(&l.queue, w)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-w.ready:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w.admitted {
+		return nil
+	}
+	if w.index >= 0 {
+		heap.Remove(&l.queue, w.index)
+	}
+	return errBusy
+}
+
+// release frees a slot, handing it straight to the next waiter if any.
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.queue) == 0 {
+		l.inFlight--
+		return
+	}
+	w := heap.Pop(&l.queue).(*waiter)
+	w.admitted = true
+	close(w.ready)
+}
+
+// waitQueue is a heap of waiters, highest priority (then oldest) first.
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waitQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() any {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*q = old[:n-1]
+	return w
+}
+
+// lowest returns the oldest waiter with the lowest priority, or nil if empty.
+func (q waitQueue) lowest() *waiter {
+	var lowest *waiter
+	for _, w := range q {
+		if lowest == nil || w.priority < lowest.priority ||
+			(w.priority == lowest.priority && w.seq < lowest.seq) {
+			lowest = w
+		}
+	}
+	return lowest
+}
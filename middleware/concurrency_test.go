@@ -0,0 +1,139 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+// limitedRouter returns a router whose /work handler blocks until release is
+// closed, recording the X-Priority of each request it admits.
+func limitedRouter(cfg middleware.ConcurrencyLimitConfig, release chan struct{}) (*gin.Engine, *[]string, *sync.Mutex) {
+	var (
+		mu       sync.Mutex
+		admitted []string
+	)
+	router := gin.New()
+	router.Use(middleware.PriorityClassifier(middleware.PriorityConfig{Header: "X-Priority"}))
+	router.Use(middleware.ConcurrencyLimit(cfg))
+	router.GET("/work", func(c *gin.Context) {
+		mu.Lock()
+		admitted = append(admitted, c.GetHeader("X-Priority"))
+		mu.Unlock()
+		<-release
+		c.Status(http.StatusOK)
+	})
+	return router, &admitted, &mu
+}
+
+// serveAsync sends a request in the background. Read the returned recorder
+// only after done is closed.
+func serveAsync(router http.Handler, priority string, wg *sync.WaitGroup) (w *httptest.ResponseRecorder, done chan struct{}) {
+	w = httptest.NewRecorder()
+	done = make(chan struct{})
+	req, _ := http.NewRequest("GET", "/work", nil)
+	req.Header.Set("X-Priority", priority)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		router.ServeHTTP(w, req)
+	}()
+	// Give the request time to take a slot or join the queue
+	time.Sleep(20 * time.Millisecond)
+	return w, done
+}
+
+func TestConcurrencyLimitAdmitsByPriority(t *testing.T) {
+	release := make(chan struct{})
+	router, admitted, mu := limitedRouter(middleware.ConcurrencyLimitConfig{MaxInFlight: 1, MaxQueue: 2}, release)
+
+	var wg sync.WaitGroup
+	serveAsync(router, "normal", &wg)
+	serveAsync(router, "low", &wg)
+	serveAsync(router, "high", &wg)
+	close(release)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"normal", "high", "low"}
+	if len(*admitted) != len(want) {
+		t.Fatalf("expected %v, got %v", want, *admitted)
+	}
+	for i := range want {
+		if (*admitted)[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, *admitted)
+			break
+		}
+	}
+}
+
+func TestConcurrencyLimitQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	router, _, _ := limitedRouter(middleware.ConcurrencyLimitConfig{MaxInFlight: 1, MaxQueue: 1}, release)
+
+	var wg sync.WaitGroup
+	serveAsync(router, "normal", &wg)
+	low, lowDone := serveAsync(router, "low", &wg)
+	high, _ := serveAsync(router, "high", &wg)
+	rejected, rejectedDone := serveAsync(router, "normal", &wg)
+	<-lowDone
+	<-rejectedDone
+
+	// The high request evicted the queued low one, and a normal request
+	// can't evict a high one
+	for name, w := range map[string]*httptest.ResponseRecorder{"low": low, "normal": rejected} {
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected status 503, got %d", name, w.Code)
+		}
+		if w.Header().Get("Retry-After") != "1" {
+			t.Errorf("%s: expected Retry-After 1, got %q", name, w.Header().Get("Retry-After"))
+		}
+	}
+
+	close(release)
+	wg.Wait()
+	if high.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", high.Code)
+	}
+}
+
+func TestConcurrencyLimitTimeout(t *testing.T) {
+	release := make(chan struct{})
+	router, _, _ := limitedRouter(middleware.ConcurrencyLimitConfig{MaxInFlight: 1, QueueTimeout: 10 * time.Millisecond}, release)
+
+	var wg sync.WaitGroup
+	serveAsync(router, "normal", &wg)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/work", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+
+	close(release)
+	wg.Wait()
+}
+
+func TestConcurrencyLimitCriticalBypass(t *testing.T) {
+	release := make(chan struct{})
+	router, _, _ := limitedRouter(middleware.ConcurrencyLimitConfig{MaxInFlight: 1, QueueTimeout: 10 * time.Millisecond}, release)
+
+	var wg sync.WaitGroup
+	serveAsync(router, "normal", &wg)
+	critical, _ := serveAsync(router, "critical", &wg)
+
+	close(release)
+	wg.Wait()
+	if critical.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", critical.Code)
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// RequireContentType returns middleware that rejects request bodies whose
+// Content-Type isn't one of types with 415 Unsupported Media Type, so
+// handlers don't discover bad content types through bind errors:
+//
+//	api := router.Group("/api", middleware.RequireContentType("application/json"))
+//
+// Types may end in "/*" (e.g. "multipart/*"). Parameters are ignored except
+// charset, which must be UTF-8 when present. POST and PUT requests must have
+// a body; other methods are only checked when they send one.
+func RequireContentType(types ...string) gin.HandlerFunc {
+	allowed, expected := contentTypes(types)
+	return func(c *gin.Context) {
+		if !checkContentType(c, allowed, expected) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CheckContentType checks the request body's Content-Type like
+// RequireContentType, from a handler. It sends the 415 and reports false
+// when the check fails; the caller should return.
+func CheckContentType(c *gin.Context, types ...string) bool {
+	allowed, expected := contentTypes(types)
+	return checkContentType(c, allowed, expected)
+}
+
+// contentTypes normalizes types for matchesMediaType and lists them for
+// error messages.
+func contentTypes(types []string) ([]string, string) {
+	allowed := make([]string, len(types))
+	for i, t := range types {
+		allowed[i] = strings.ToLower(strings.TrimSpace(t))
+	}
+	return allowed, strings.Join(types, ", ")
+}
+
+// checkContentType sends a 415 and reports false unless the request body
+// has one of the allowed types.
+func checkContentType(c *gin.Context, allowed []string, expected string) bool {
+	r := c.Request
+	if !hasBody(r) {
+		if r.Method == http.MethodPost || r.Method == http.MethodPut {
+			response.UnsupportedMediaType(c, "request body required ("+expected+")")
+			return false
+		}
+		return true
+	}
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !matchesMediaType(mediaType, allowed) {
+		response.UnsupportedMediaType(c, "Content-Type must be "+expected)
+		return false
+	}
+	if charset, ok := params["charset"]; ok && !isUTF8(charset) {
+		response.UnsupportedMediaType(c, "charset must be utf-8")
+		return false
+	}
+	return true
+}
+
+// hasBody reports whether r has a request body.
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+// matchesMediaType reports whether mediaType (lowercase, from
+// mime.ParseMediaType) matches one of allowed, which may end in "/*".
+func matchesMediaType(mediaType string, allowed []string) bool {
+	for _, a := range allowed {
+		if a == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "*"); ok && strings.HasSuffix(prefix, "/") && strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUTF8 reports whether charset names UTF-8.
+func isUTF8(charset string) bool {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "utf-8", "utf8":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestRequireContentType(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.RequireContentType("application/json", "multipart/*"))
+	router.Any("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{"json", "POST", "application/json", `{}`, http.StatusOK},
+		{"json with charset", "POST", "application/json; charset=UTF-8", `{}`, http.StatusOK},
+		{"uppercase type", "PATCH", "Application/JSON", `{}`, http.StatusOK},
+		{"wildcard", "POST", "multipart/form-data; boundary=x", "--x--", http.StatusOK},
+		{"wrong type", "POST", "text/plain", "hi", http.StatusUnsupportedMediaType},
+		{"missing type", "PUT", "", `{}`, http.StatusUnsupportedMediaType},
+		{"malformed type", "POST", "application/", `{}`, http.StatusUnsupportedMediaType},
+		{"wrong charset", "POST", "application/json; charset=latin1", `{}`, http.StatusUnsupportedMediaType},
+		{"post without body", "POST", "", "", http.StatusUnsupportedMediaType},
+		{"put without body", "PUT", "application/json", "", http.StatusUnsupportedMediaType},
+		{"get", "GET", "", "", http.StatusOK},
+		{"delete without body", "DELETE", "", "", http.StatusOK},
+		{"delete with wrong body", "DELETE", "text/plain", "x", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, "/test", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
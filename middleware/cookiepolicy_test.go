@@ -0,0 +1,147 @@
+package middleware_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestCookiePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    middleware.CookiePolicyConfig
+		https  bool
+		cookie http.Cookie
+		noBody bool
+		want   string
+	}{
+		{
+			name:   "https adds secure and samesite",
+			https:  true,
+			cookie: http.Cookie{Name: "session", Value: "abc", Path: "/", HttpOnly: true},
+			want:   "session=abc; Path=/; HttpOnly; Secure; SameSite=Lax",
+		},
+		{
+			name:   "plain http keeps insecure",
+			cookie: http.Cookie{Name: "session", Value: "abc", Path: "/"},
+			want:   "session=abc; Path=/; SameSite=Lax",
+		},
+		{
+			name:   "always secure",
+			cfg:    middleware.CookiePolicyConfig{Secure: true, SameSite: http.SameSiteStrictMode},
+			cookie: http.Cookie{Name: "session", Value: "abc", Path: "/"},
+			want:   "session=abc; Path=/; Secure; SameSite=Strict",
+		},
+		{
+			name:   "handler samesite kept",
+			https:  true,
+			cookie: http.Cookie{Name: "session", Value: "abc", SameSite: http.SameSiteStrictMode},
+			want:   "session=abc; Secure; SameSite=Strict",
+		},
+		{
+			name:   "samesite none forces secure",
+			cookie: http.Cookie{Name: "embed", Value: "1", SameSite: http.SameSiteNoneMode},
+			want:   "embed=1; Secure; SameSite=None",
+		},
+		{
+			name:   "host prefix",
+			cfg:    middleware.CookiePolicyConfig{HostPrefix: true},
+			https:  true,
+			cookie: http.Cookie{Name: "session", Value: "abc", Path: "/"},
+			want:   "__Host-session=abc; Path=/; Secure; SameSite=Lax",
+		},
+		{
+			name:   "no host prefix with domain",
+			cfg:    middleware.CookiePolicyConfig{HostPrefix: true},
+			https:  true,
+			cookie: http.Cookie{Name: "session", Value: "abc", Path: "/", Domain: "doujins.com"},
+			want:   "session=abc; Path=/; Domain=doujins.com; Secure; SameSite=Lax",
+		},
+		{
+			name:   "no host prefix over http",
+			cfg:    middleware.CookiePolicyConfig{HostPrefix: true},
+			cookie: http.Cookie{Name: "session", Value: "abc", Path: "/"},
+			want:   "session=abc; Path=/; SameSite=Lax",
+		},
+		{
+			name: "override",
+			cfg: middleware.CookiePolicyConfig{HostPrefix: true, Overrides: map[string]middleware.CookieRule{
+				"lang": {NoHostPrefix: true, SameSite: http.SameSiteStrictMode},
+			}},
+			https:  true,
+			cookie: http.Cookie{Name: "lang", Value: "ja", Path: "/", SameSite: http.SameSiteLaxMode},
+			want:   "lang=ja; Path=/; Secure; SameSite=Strict",
+		},
+		{
+			name:   "skip",
+			cfg:    middleware.CookiePolicyConfig{Overrides: map[string]middleware.CookieRule{"legacy": {Skip: true}}},
+			https:  true,
+			cookie: http.Cookie{Name: "legacy", Value: "1"},
+			want:   "legacy=1",
+		},
+		{
+			name:   "bodyless response",
+			https:  true,
+			cookie: http.Cookie{Name: "session", Value: "", Path: "/", MaxAge: -1},
+			noBody: true,
+			want:   "session=; Path=/; Max-Age=0; Secure; SameSite=Lax",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(middleware.CookiePolicy(tt.cfg))
+			router.GET("/", func(c *gin.Context) {
+				http.SetCookie(c.Writer, &tt.cookie)
+				if tt.noBody {
+					c.Status(http.StatusNoContent)
+					return
+				}
+				c.String(http.StatusOK, "ok")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.https {
+				req.TLS = &tls.ConnectionState{}
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Set-Cookie"); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCookiePolicyIncomingHostPrefix(t *testing.T) {
+	var session, theme string
+	router := gin.New()
+	router.Use(middleware.CookiePolicy(middleware.CookiePolicyConfig{HostPrefix: true}))
+	router.GET("/", func(c *gin.Context) {
+		session, _ = c.Cookie("session")
+		theme, _ = c.Cookie("theme")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	// A subdomain could have planted the plain session cookie
+	req.Header.Set("Cookie", "session=planted; __Host-session=real; theme=dark")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if session != "real" {
+		t.Errorf("expected the __Host- session cookie, got %q", session)
+	}
+	if theme != "dark" {
+		t.Errorf("expected other cookies kept, got %q", theme)
+	}
+	if strings.Contains(req.Header.Get("Cookie"), "planted") {
+		t.Errorf("expected the planted cookie dropped, got %s", req.Header.Get("Cookie"))
+	}
+}
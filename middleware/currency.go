@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// CurrencyConfig configures the currency detection middleware.
+type CurrencyConfig struct {
+	// Supported ISO 4217 currencies (e.g., []string{"USD", "JPY", "EUR"})
+	Supported []string
+	// Default currency if none detected (defaults to "USD")
+	Default string
+	// QueryParam to check for currency override (defaults to "currency")
+	QueryParam string
+	// CookieName to check for currency preference (defaults to "currency")
+	CookieName string
+	// CountryHeader carrying the client's country from a CDN or geo-IP
+	// proxy, e.g. "CF-IPCountry". Empty disables it.
+	CountryHeader string
+	// Countries maps ISO 3166 country codes to currencies, overriding and
+	// extending the built-in table (e.g. {"CH": "EUR"})
+	Countries map[string]string
+}
+
+// Currency returns middleware that detects the user's display currency from:
+// 1. Query parameter (?currency=JPY)
+// 2. Cookie (user's saved preference)
+// 3. Accept-Language regions (ja-JP -> JPY), by q-value
+// 4. CountryHeader (geo-IP country)
+// 5. Default currency
+//
+// Only Supported currencies (and Default) are returned. The result is
+// stored in gin context and retrieved via GetCurrency(c), or via
+// CurrencyFromContext(ctx) in layers without gin. Accept-Language, Cookie,
+// and CountryHeader are added to Vary.
+func Currency(cfg CurrencyConfig) gin.HandlerFunc {
+	r := newCurrencyResolver(cfg)
+
+	return func(c *gin.Context) {
+		cur := r.resolve(c)
+		c.Set(currencyKey, cur)
+		c.Request = c.Request.WithContext(WithCurrency(c.Request.Context(), cur))
+
+		vary := []string{"Accept-Language", "Cookie"}
+		if r.countryHeader != "" {
+			vary = append(vary, r.countryHeader)
+		}
+		response.AddVary(c.Writer.Header(), vary...)
+
+		c.Next()
+	}
+}
+
+// currencyResolver holds a normalized CurrencyConfig.
+type currencyResolver struct {
+	supported     map[string]struct{}
+	fallback      string
+	queryParam    string
+	cookieName    string
+	countryHeader string
+	countries     map[string]string
+}
+
+// newCurrencyResolver normalizes cfg, applying defaults.
+func newCurrencyResolver(cfg CurrencyConfig) *currencyResolver {
+	r := &currencyResolver{
+		supported:     make(map[string]struct{}, len(cfg.Supported)),
+		fallback:      strings.ToUpper(strings.TrimSpace(cfg.Default)),
+		queryParam:    cfg.QueryParam,
+		cookieName:    cfg.CookieName,
+		countryHeader: cfg.CountryHeader,
+		countries:     make(map[string]string, len(countryCurrencies)+len(cfg.Countries)),
+	}
+	for _, cur := range cfg.Supported {
+		r.supported[strings.ToUpper(cur)] = struct{}{}
+	}
+	if r.fallback == "" {
+		r.fallback = "USD"
+	}
+	r.supported[r.fallback] = struct{}{}
+	if r.queryParam == "" {
+		r.queryParam = "currency"
+	}
+	if r.cookieName == "" {
+		r.cookieName = "currency"
+	}
+	for country, cur := range countryCurrencies {
+		r.countries[country] = cur
+	}
+	for country, cur := range cfg.Countries {
+		r.countries[strings.ToUpper(country)] = strings.ToUpper(cur)
+	}
+	return r
+}
+
+// resolve applies the detection order documented on Currency.
+func (r *currencyResolver) resolve(c *gin.Context) string {
+	if cur, ok := r.supportedCurrency(c.Query(r.queryParam)); ok {
+		return cur
+	}
+	if v, err := c.Cookie(r.cookieName); err == nil {
+		if cur, ok := r.supportedCurrency(v); ok {
+			return cur
+		}
+	}
+	if cur, ok := r.fromAcceptLanguage(c.GetHeader("Accept-Language")); ok {
+		return cur
+	}
+	if r.countryHeader != "" {
+		if cur, ok := r.supportedCurrency(r.countries[strings.ToUpper(strings.TrimSpace(c.GetHeader(r.countryHeader)))]); ok {
+			return cur
+		}
+	}
+	return r.fallback
+}
+
+// supportedCurrency normalizes cur and reports whether it is supported.
+func (r *currencyResolver) supportedCurrency(cur string) (string, bool) {
+	cur = strings.ToUpper(strings.TrimSpace(cur))
+	_, ok := r.supported[cur]
+	return cur, ok && cur != ""
+}
+
+// fromAcceptLanguage returns the supported currency of the highest-q
+// Accept-Language entry with a region (e.g. "ja-JP" or "zh-Hant-TW").
+func (r *currencyResolver) fromAcceptLanguage(header string) (string, bool) {
+	best, bestQ := "", -1.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q <= bestQ {
+			continue
+		}
+		if cur, ok := r.supportedCurrency(r.countries[languageRegion(tag)]); ok {
+			best, bestQ = cur, q
+		}
+	}
+	return best, best != ""
+}
+
+// languageRegion returns the uppercase region subtag of a language tag
+// ("en-GB" -> "GB"), or "" if it has none.
+func languageRegion(tag string) string {
+	subtags := strings.Split(strings.TrimSpace(tag), "-")
+	for _, s := range subtags[1:] {
+		if len(s) == 2 {
+			return strings.ToUpper(s)
+		}
+	}
+	return ""
+}
+
+// countryCurrencies is the built-in country to currency table.
+var countryCurrencies = map[string]string{
+	"US": "USD", "CA": "CAD", "MX": "MXN", "BR": "BRL", "AR": "ARS",
+	"GB": "GBP", "CH": "CHF", "SE": "SEK", "NO": "NOK", "DK": "DKK", "PL": "PLN", "CZ": "CZK", "HU": "HUF", "RU": "RUB", "TR": "TRY", "UA": "UAH",
+	"AT": "EUR", "BE": "EUR", "CY": "EUR", "DE": "EUR", "EE": "EUR", "ES": "EUR", "FI": "EUR", "FR": "EUR", "GR": "EUR", "HR": "EUR",
+	"IE": "EUR", "IT": "EUR", "LT": "EUR", "LU": "EUR", "LV": "EUR", "MT": "EUR", "NL": "EUR", "PT": "EUR", "SI": "EUR", "SK": "EUR",
+	"JP": "JPY", "KR": "KRW", "CN": "CNY", "TW": "TWD", "HK": "HKD", "SG": "SGD", "TH": "THB", "VN": "VND", "ID": "IDR", "PH": "PHP", "MY": "MYR", "IN": "INR",
+	"AU": "AUD", "NZ": "NZD", "ZA": "ZAR", "IL": "ILS", "AE": "AED", "SA": "SAR",
+}
+
+// currencyKey is the gin context key for the detected currency.
+const currencyKey = "ginapi.currency"
+
+// GetCurrency retrieves the detected currency from the gin context.
+// Returns "USD" as fallback if not set (see SetStrictMode).
+func GetCurrency(c *gin.Context) string {
+	if c == nil {
+		misuse("GetCurrency", "with a nil context")
+		return "USD"
+	}
+	if v, ok := c.Get(currencyKey); ok {
+		if cur, ok := v.(string); ok && cur != "" {
+			return cur
+		}
+	}
+	misuse("GetCurrency", "without the Currency middleware")
+	return "USD"
+}
+
+// currencyContextKey is the request context key for the detected currency.
+type currencyContextKey struct{}
+
+// WithCurrency returns a copy of ctx carrying the given currency code.
+// The code is normalized to uppercase.
+func WithCurrency(ctx context.Context, cur string) context.Context {
+	return context.WithValue(ctx, currencyContextKey{}, strings.ToUpper(strings.TrimSpace(cur)))
+}
+
+// CurrencyFromContext retrieves the currency stored by WithCurrency (or the
+// Currency middleware). Returns "" if ctx is nil or carries no currency.
+func CurrencyFromContext(ctx context.Context) string {
+	if ctx == nil {
+		misuse("CurrencyFromContext", "with a nil context")
+		return ""
+	}
+	cur, _ := ctx.Value(currencyContextKey{}).(string)
+	return cur
+}
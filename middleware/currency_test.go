@@ -0,0 +1,71 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestCurrency(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.Currency(middleware.CurrencyConfig{
+		Supported:     []string{"usd", "JPY", "EUR", "KRW"},
+		CountryHeader: "CF-IPCountry",
+		Countries:     map[string]string{"CH": "eur"},
+	}))
+	router.GET("/test", func(c *gin.Context) {
+		cur := middleware.GetCurrency(c)
+		if fromCtx := middleware.CurrencyFromContext(c.Request.Context()); fromCtx != cur {
+			t.Errorf("expected request context currency %q, got %q", cur, fromCtx)
+		}
+		c.String(http.StatusOK, cur)
+	})
+
+	tests := []struct {
+		name    string
+		query   string
+		cookie  string
+		accept  string
+		country string
+		want    string
+	}{
+		{"default", "", "", "", "", "USD"},
+		{"query", "?currency=jpy", "", "de-DE", "KR", "JPY"},
+		{"unsupported query", "?currency=GBP", "", "", "", "USD"},
+		{"cookie", "", "EUR", "ja-JP", "", "EUR"},
+		{"accept-language region", "", "", "en, ja-JP;q=0.8", "DE", "JPY"},
+		{"accept-language by q", "", "", "ja-JP;q=0.5, ko-KR;q=0.9", "", "KRW"},
+		{"accept-language script and region", "", "", "zh-Hant-TW, de-AT;q=0.7", "", "EUR"},
+		{"unsupported region falls through", "", "", "en-GB", "KR", "KRW"},
+		{"country override", "", "", "", "ch", "EUR"},
+		{"unknown country", "", "", "", "ZZ", "USD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test"+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "currency", Value: tt.cookie})
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept-Language", tt.accept)
+			}
+			if tt.country != "" {
+				req.Header.Set("CF-IPCountry", tt.country)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, w.Body.String())
+			}
+			if vary := w.Header().Values("Vary"); len(vary) == 0 {
+				t.Errorf("expected Vary header")
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/clock"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// DedupeConfig configures duplicate request suppression.
+type DedupeConfig struct {
+	// Window is how long after a request completes an identical one counts
+	// as a duplicate (defaults to 2s)
+	Window time.Duration
+	// Key identifies who sent the request (defaults to the principal ID,
+	// else the client IP)
+	Key func(c *gin.Context) string
+	// Methods deduplicated (defaults to POST)
+	Methods []string
+	// MaxBodyBytes bounds the request bodies hashed and the responses kept
+	// for replay (defaults to 1MB). Larger requests are never deduplicated;
+	// duplicates of larger responses get a 409.
+	MaxBodyBytes int
+	// Clock times the window (defaults to clock.System)
+	Clock clock.Clock
+}
+
+// Dedupe returns middleware that stops double submissions, such as a
+// double-clicked favorite or comment button, from running twice. A request
+// is a duplicate of another with the same Key, method, URL, and body hash
+// that is still running or completed within Window. Duplicates wait for
+// the original and get its response replayed with X-Deduplicated: true,
+// or a 409 duplicate_request if the response was too large to keep.
+//
+//	comments.POST("", middleware.Dedupe(middleware.DedupeConfig{}), createComment)
+//
+// If the original fails with a 5xx or panics, it isn't remembered, so a
+// retry runs normally. This is not idempotency: it only catches repeats
+// within seconds at one instance, without client-supplied keys. Install it
+// after auth middleware.
+func Dedupe(cfg DedupeConfig) gin.HandlerFunc {
+	if cfg.Window <= 0 {
+		cfg.Window = 2 * time.Second
+	}
+	if cfg.Key == nil {
+		cfg.Key = principalOrIP
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{http.MethodPost}
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 1 << 20
+	}
+	cfg.Clock = clock.Or(cfg.Clock)
+	d := &dedupe{cfg: cfg, requests: map[string]*dedupeEntry{}}
+
+	return func(c *gin.Context) {
+		if !slices.Contains(cfg.Methods, c.Request.Method) {
+			c.Next()
+			return
+		}
+		sum, ok := hashBody(c.Request, cfg.MaxBodyBytes)
+		if !ok {
+			c.Next()
+			return
+		}
+		key := cfg.Key(c) + "|" + c.Request.Method + " " + c.Request.URL.RequestURI() + "|" + sum
+
+		e, original := d.join(key)
+		if original {
+			d.run(c, key, e)
+			return
+		}
+		select {
+		case <-e.done:
+		case <-c.Request.Context().Done():
+			c.Abort()
+			return
+		}
+		switch {
+		case e.failed:
+			c.Next()
+		case e.resp == nil:
+			response.ErrorWithInfo(c, http.StatusConflict, response.ErrorInfo{
+				Type:    response.ErrorTypeConflict,
+				Code:    response.ErrorCodeDuplicateRequest,
+				Message: "an identical request was just submitted",
+			})
+			c.Abort()
+		default:
+			c.Header("X-Deduplicated", "true")
+			e.resp.replay(c)
+			c.Abort()
+		}
+	}
+}
+
+// hashBody returns the hex SHA-256 of the request body and restores the
+// body for the handlers. ok is false if the body is larger than max.
+func hashBody(r *http.Request, max int) (string, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:]), true
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, int64(max)+1))
+	rest := r.Body
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), rest), rest}
+	if err != nil || len(body) > max {
+		return "", false
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// dedupe remembers recent requests.
+type dedupe struct {
+	cfg DedupeConfig
+
+	mu        sync.Mutex
+	requests  map[string]*dedupeEntry
+	lastSweep time.Time
+}
+
+// dedupeEntry is one original request. resp, failed, and completed are set
+// before done is closed.
+type dedupeEntry struct {
+	done      chan struct{}
+	resp      *capturedResponse
+	failed    bool
+	completed time.Time
+}
+
+// join returns the running or recent request for key, or records a new
+// one, reporting whether the caller is the original.
+func (d *dedupe) join(key string) (*dedupeEntry, bool) {
+	now := d.cfg.Clock.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now.Sub(d.lastSweep) > d.cfg.Window {
+		for k, e := range d.requests {
+			if d.expired(e, now) {
+				delete(d.requests, k)
+			}
+		}
+		d.lastSweep = now
+	}
+	if e, ok := d.requests[key]; ok && !d.expired(e, now) {
+		return e, false
+	}
+	e := &dedupeEntry{done: make(chan struct{})}
+	d.requests[key] = e
+	return e, true
+}
+
+// expired reports whether e completed more than Window ago. Callers hold d.mu.
+func (d *dedupe) expired(e *dedupeEntry, now time.Time) bool {
+	select {
+	case <-e.done:
+		return now.Sub(e.completed) > d.cfg.Window
+	default:
+		return false
+	}
+}
+
+// run runs the handlers for the original request, capturing its response
+// for duplicates. Failed requests are forgotten, so they can be retried.
+func (d *dedupe) run(c *gin.Context, key string, e *dedupeEntry) {
+	w := &captureWriter{ResponseWriter: c.Writer, max: d.cfg.MaxBodyBytes}
+	c.Writer = w
+	e.failed = true
+
+	defer func() {
+		c.Writer = w.ResponseWriter
+		d.mu.Lock()
+		if e.failed {
+			delete(d.requests, key)
+		}
+		e.completed = d.cfg.Clock.Now()
+		close(e.done)
+		d.mu.Unlock()
+	}()
+
+	c.Next()
+
+	if w.Status() >= 500 {
+		return
+	}
+	e.failed = false
+	if !w.overflow {
+		header := w.Header().Clone()
+		header.Del("Set-Cookie")
+		e.resp = &capturedResponse{status: w.Status(), header: header, body: w.buf.Bytes()}
+	}
+}
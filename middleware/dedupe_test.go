@@ -0,0 +1,191 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/apitest"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// dedupeRouter returns a router whose POST /favorites handler echoes the
+// request body, counting its executions. A body of "fail" gets a 500 and
+// "big" a response larger than 16 bytes.
+func dedupeRouter(cfg middleware.DedupeConfig, calls *atomic.Int32) *gin.Engine {
+	cfg.Key = func(c *gin.Context) string { return c.GetHeader("X-User") }
+	router := gin.New()
+	h := func(c *gin.Context) {
+		n := calls.Add(1)
+		body, _ := io.ReadAll(c.Request.Body)
+		switch string(body) {
+		case "fail":
+			response.InternalError(c, "failed")
+			return
+		case "big":
+			c.String(http.StatusCreated, strings.Repeat("x", 32))
+			return
+		}
+		c.Header("X-Call", strconv.Itoa(int(n)))
+		c.String(http.StatusCreated, string(body))
+	}
+	router.POST("/favorites", middleware.Dedupe(cfg), h)
+	router.GET("/favorites", middleware.Dedupe(cfg), h)
+	return router
+}
+
+func dedupeRequest(router http.Handler, method, user, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, "/favorites", strings.NewReader(body))
+	req.Header.Set("X-User", user)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDedupe(t *testing.T) {
+	type request struct {
+		method, user, body string
+		advance            time.Duration
+	}
+	tests := []struct {
+		name      string
+		requests  []request
+		wantCalls int32
+		wantLast  int
+		wantDedup bool
+	}{
+		{
+			name:      "duplicate is replayed",
+			requests:  []request{{"POST", "u1", "gallery=1", 0}, {"POST", "u1", "gallery=1", time.Second}},
+			wantCalls: 1,
+			wantLast:  http.StatusCreated,
+			wantDedup: true,
+		},
+		{
+			name:      "different body",
+			requests:  []request{{"POST", "u1", "gallery=1", 0}, {"POST", "u1", "gallery=2", 0}},
+			wantCalls: 2,
+			wantLast:  http.StatusCreated,
+		},
+		{
+			name:      "different user",
+			requests:  []request{{"POST", "u1", "gallery=1", 0}, {"POST", "u2", "gallery=1", 0}},
+			wantCalls: 2,
+			wantLast:  http.StatusCreated,
+		},
+		{
+			name:      "after the window",
+			requests:  []request{{"POST", "u1", "gallery=1", 0}, {"POST", "u1", "gallery=1", 3 * time.Second}},
+			wantCalls: 2,
+			wantLast:  http.StatusCreated,
+		},
+		{
+			name:      "GET is not deduplicated",
+			requests:  []request{{"GET", "u1", "", 0}, {"GET", "u1", "", 0}},
+			wantCalls: 2,
+			wantLast:  http.StatusCreated,
+		},
+		{
+			name:      "failures are retried",
+			requests:  []request{{"POST", "u1", "fail", 0}, {"POST", "u1", "fail", 0}},
+			wantCalls: 2,
+			wantLast:  http.StatusInternalServerError,
+		},
+		{
+			name:      "large response conflicts",
+			requests:  []request{{"POST", "u1", "big", 0}, {"POST", "u1", "big", 0}},
+			wantCalls: 1,
+			wantLast:  http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := apitest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			var calls atomic.Int32
+			router := dedupeRouter(middleware.DedupeConfig{MaxBodyBytes: 16, Clock: clock}, &calls)
+
+			var first, last *httptest.ResponseRecorder
+			for _, r := range tt.requests {
+				clock.Advance(r.advance)
+				last = dedupeRequest(router, r.method, r.user, r.body)
+				if first == nil {
+					first = last
+				}
+			}
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("expected %d handler executions, got %d", tt.wantCalls, got)
+			}
+			if last.Code != tt.wantLast {
+				t.Errorf("expected status %d, got %d", tt.wantLast, last.Code)
+			}
+			if got := last.Header().Get("X-Deduplicated") == "true"; got != tt.wantDedup {
+				t.Errorf("expected deduplicated %v, got %v", tt.wantDedup, got)
+			}
+			if tt.wantDedup {
+				if last.Body.String() != first.Body.String() || last.Header().Get("X-Call") != first.Header().Get("X-Call") {
+					t.Errorf("expected the first response replayed, got %q (call %s)", last.Body.String(), last.Header().Get("X-Call"))
+				}
+			}
+			if tt.wantLast == http.StatusConflict {
+				e, err := response.ParseError(last.Body.Bytes())
+				if err != nil || e.Error.Code != response.ErrorCodeDuplicateRequest {
+					t.Errorf("expected code %s, got %+v (%v)", response.ErrorCodeDuplicateRequest, e.Error, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDedupeInFlight(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	router := gin.New()
+	router.POST("/comments", middleware.Dedupe(middleware.DedupeConfig{}), func(c *gin.Context) {
+		calls.Add(1)
+		<-release
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusCreated, string(body))
+	})
+
+	recorders := make([]*httptest.ResponseRecorder, 3)
+	var wg sync.WaitGroup
+	for i := range recorders {
+		recorders[i] = httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/comments", strings.NewReader("first!"))
+		wg.Add(1)
+		go func(w *httptest.ResponseRecorder) {
+			defer wg.Done()
+			router.ServeHTTP(w, req)
+		}(recorders[i])
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 1 handler execution, got %d", got)
+	}
+	deduplicated := 0
+	for i, w := range recorders {
+		if w.Code != http.StatusCreated || w.Body.String() != "first!" {
+			t.Errorf("request %d: expected 201 first!, got %d %s", i, w.Code, w.Body.String())
+		}
+		if w.Header().Get("X-Deduplicated") == "true" {
+			deduplicated++
+		}
+	}
+	if deduplicated != 2 {
+		t.Errorf("expected 2 deduplicated responses, got %d", deduplicated)
+	}
+}
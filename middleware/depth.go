@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/pagination"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// OffsetDepthConfig configures the offset depth limit.
+type OffsetDepthConfig struct {
+	// MaxOffset is the deepest offset served (defaults to 10000)
+	MaxOffset int
+	// Locate returns the sort key and ID of the item at offset-1 (the last
+	// item before the requested page) under sort, for the suggested cursor.
+	// Nil, or an error, omits the cursor.
+	Locate func(c *gin.Context, sort string, offset int) (sortKey, id any, err error)
+}
+
+// OffsetDepthLimit returns middleware that rejects ?offset= past MaxOffset
+// with a 400 offset_too_deep (see response.OffsetTooDeep), nudging clients
+// of deep pages toward cursor pagination while shallow pages keep working.
+// With Locate, the error carries a keyset cursor for the same position, so
+// the client can continue with ?after= on the same route:
+//
+//	api.GET("/galleries", middleware.OffsetDepthLimit(middleware.OffsetDepthConfig{
+//	    MaxOffset: 10000,
+//	    Locate: func(c *gin.Context, sort string, offset int) (any, any, error) {
+//	        g, err := repo.GalleryAt(c, sort, offset-1)
+//	        return g.CreatedAt, g.ID, err
+//	    },
+//	}), listGalleries) // handles both ?offset= and ?after=
+func OffsetDepthLimit(cfg OffsetDepthConfig) gin.HandlerFunc {
+	if cfg.MaxOffset <= 0 {
+		cfg.MaxOffset = 10000
+	}
+
+	return func(c *gin.Context) {
+		p := pagination.Bind(c)
+		if p.Offset <= cfg.MaxOffset {
+			c.Next()
+			return
+		}
+
+		var cursor string
+		if cfg.Locate != nil {
+			sortKey, id, err := cfg.Locate(c, p.Sort, p.Offset)
+			if err == nil {
+				cursor, err = pagination.EncodeKeysetCursor(p.Sort, sortKey, id)
+			}
+			if err != nil {
+				response.ReportError(c, c.Request, c.FullPath(), err)
+				cursor = ""
+			}
+		}
+		response.OffsetTooDeep(c, cfg.MaxOffset, cursor)
+		c.Abort()
+	}
+}
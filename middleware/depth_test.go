@@ -0,0 +1,111 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+func TestOffsetDepthLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	locate := func(c *gin.Context, sort string, offset int) (any, any, error) {
+		return int64(offset * 10), int64(offset), nil
+	}
+	failing := func(c *gin.Context, sort string, offset int) (any, any, error) {
+		return nil, nil, errors.New("db down")
+	}
+
+	tests := []struct {
+		name       string
+		cfg        middleware.OffsetDepthConfig
+		query      string
+		wantStatus int
+		wantCursor bool
+	}{
+		{
+			name:       "shallow offset passes",
+			cfg:        middleware.OffsetDepthConfig{MaxOffset: 100, Locate: locate},
+			query:      "offset=100",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "default depth",
+			query:      "offset=10000",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "deep offset without locate",
+			cfg:        middleware.OffsetDepthConfig{MaxOffset: 100},
+			query:      "offset=101",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "deep offset with cursor",
+			cfg:        middleware.OffsetDepthConfig{MaxOffset: 100, Locate: locate},
+			query:      "offset=500&sort=-created_at",
+			wantStatus: http.StatusBadRequest,
+			wantCursor: true,
+		},
+		{
+			name:       "locate error omits cursor",
+			cfg:        middleware.OffsetDepthConfig{MaxOffset: 100, Locate: failing},
+			query:      "offset=500",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/items", middleware.OffsetDepthLimit(tt.cfg), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items?"+tt.query, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Error struct {
+					Code   string `json:"code"`
+					Param  string `json:"param"`
+					Cursor string `json:"cursor"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if body.Error.Code != "offset_too_deep" || body.Error.Param != "offset" {
+				t.Errorf("expected offset_too_deep on offset, got %q on %q", body.Error.Code, body.Error.Param)
+			}
+			if !tt.wantCursor {
+				if body.Error.Cursor != "" {
+					t.Errorf("expected no cursor, got %q", body.Error.Cursor)
+				}
+				return
+			}
+
+			cursor, err := pagination.DecodeKeysetCursor(body.Error.Cursor)
+			if err != nil {
+				t.Fatalf("expected a keyset cursor, got %q: %v", body.Error.Cursor, err)
+			}
+			if cursor.Sort != "-created_at" || cursor.SortKey != int64(5000) || cursor.ID != int64(500) {
+				t.Errorf("expected cursor at offset 500, got %+v", cursor)
+			}
+		})
+	}
+}
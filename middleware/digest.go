@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// DigestConfig configures request body digest verification.
+type DigestConfig struct {
+	// Required rejects requests with a body but no digest the middleware
+	// can check (400 missing_param)
+	Required bool
+	// MemoryBytes is how much of a body is buffered in memory before the
+	// rest is spooled to a temporary file (defaults to 1MiB)
+	MemoryBytes int64
+	// TempDir holds spooled bodies (defaults to os.TempDir())
+	TempDir string
+}
+
+// VerifyDigest returns middleware that checks request bodies against the
+// digest the client sent, so a body truncated or corrupted on the way (by
+// a CDN, say) is rejected instead of stored:
+//
+//	uploads.POST("", middleware.VerifyDigest(middleware.DigestConfig{}), createUpload)
+//
+// It accepts Content-MD5 (RFC 1864), Digest (RFC 3230) with SHA-512,
+// SHA-256, or MD5, and Content-Digest (RFC 9530) with sha-512 or sha-256;
+// when several are sent, every supported one must match. The body is read
+// and hashed before the handler runs, then replayed to it. Mismatches get
+// a 400 digest_mismatch naming the header in param; requests without a
+// digest pass unless Required.
+func VerifyDigest(cfg DigestConfig) gin.HandlerFunc {
+	if cfg.MemoryBytes <= 0 {
+		cfg.MemoryBytes = 1 << 20
+	}
+
+	return func(c *gin.Context) {
+		if !hasBody(c.Request) {
+			c.Next()
+			return
+		}
+		digests := requestDigests(c.Request.Header)
+		if len(digests) == 0 {
+			if cfg.Required {
+				response.ErrorWithInfo(c, http.StatusBadRequest, response.ErrorInfo{
+					Type:    response.ErrorTypeInvalidRequest,
+					Code:    response.ErrorCodeMissingParam,
+					Message: "a Content-Digest, Digest, or Content-MD5 header is required",
+					Param:   "Content-Digest",
+				})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		hashes := make([]io.Writer, len(digests))
+		for i, d := range digests {
+			hashes[i] = d.hash
+		}
+		body, cleanup, err := spoolBody(c.Request.Body, io.MultiWriter(hashes...), cfg)
+		if err != nil {
+			if c.Request.Context().Err() == nil {
+				response.BadRequestWithCode(c, response.ErrorCodeInvalidFormat, "failed to read request body")
+			}
+			c.Abort()
+			return
+		}
+		defer cleanup()
+
+		for _, d := range digests {
+			if !bytes.Equal(d.hash.Sum(nil), d.want) {
+				response.ErrorWithInfo(c, http.StatusBadRequest, response.ErrorInfo{
+					Type:    response.ErrorTypeInvalidRequest,
+					Code:    response.ErrorCodeDigestMismatch,
+					Message: "request body does not match its " + d.header + " header; it may have been truncated",
+					Param:   d.header,
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Request.Body = body
+		c.Next()
+	}
+}
+
+// requestDigest is one digest a request claims for its body.
+type requestDigest struct {
+	header string
+	want   []byte
+	hash   hash.Hash
+}
+
+// digestAlgorithms are the supported Digest and Content-Digest algorithms,
+// by lowercase name.
+var digestAlgorithms = map[string]func() hash.Hash{
+	"sha-512": sha512.New,
+	"sha-256": sha256.New,
+	"md5":     md5.New,
+}
+
+// requestDigests parses the supported digests of the request's headers.
+// Malformed values are kept with an empty digest, so they fail to match.
+func requestDigests(h http.Header) []requestDigest {
+	var digests []requestDigest
+	if v := h.Get("Content-MD5"); v != "" {
+		want, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+		digests = append(digests, requestDigest{header: "Content-MD5", want: want, hash: md5.New()})
+	}
+	// Digest: SHA-256=<base64>, MD5=<base64>
+	for _, field := range strings.Split(strings.Join(h.Values("Digest"), ","), ",") {
+		alg, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		newHash, known := digestAlgorithms[strings.ToLower(alg)]
+		if !ok || !known {
+			continue
+		}
+		want, _ := base64.StdEncoding.DecodeString(value)
+		digests = append(digests, requestDigest{header: "Digest", want: want, hash: newHash()})
+	}
+	// Content-Digest: sha-256=:<base64>:
+	for _, field := range strings.Split(strings.Join(h.Values("Content-Digest"), ","), ",") {
+		alg, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		alg = strings.ToLower(alg)
+		if !ok || alg == "md5" {
+			continue
+		}
+		newHash, known := digestAlgorithms[alg]
+		if !known {
+			continue
+		}
+		want, _ := base64.StdEncoding.DecodeString(strings.Trim(value, ":"))
+		digests = append(digests, requestDigest{header: "Content-Digest", want: want, hash: newHash()})
+	}
+	return digests
+}
+
+// spoolBody reads body into memory, or a temporary file past
+// cfg.MemoryBytes, copying it to w. It returns a reader over the whole
+// body and a function removing the temporary file.
+func spoolBody(body io.ReadCloser, w io.Writer, cfg DigestConfig) (io.ReadCloser, func(), error) {
+	defer body.Close()
+	var buf bytes.Buffer
+	n, err := io.Copy(io.MultiWriter(&buf, w), io.LimitReader(body, cfg.MemoryBytes+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if n <= cfg.MemoryBytes {
+		return io.NopCloser(&buf), func() {}, nil
+	}
+
+	f, err := os.CreateTemp(cfg.TempDir, "ginapi-body-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	if _, err := buf.WriteTo(f); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if _, err := io.Copy(io.MultiWriter(f, w), body); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return io.NopCloser(f), cleanup, nil
+}
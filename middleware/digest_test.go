@@ -0,0 +1,89 @@
+package middleware_test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestVerifyDigest(t *testing.T) {
+	body := strings.Repeat("chapter page data ", 100)
+	md5Sum := md5.Sum([]byte(body))
+	shaSum := sha256.Sum256([]byte(body))
+	contentMD5 := base64.StdEncoding.EncodeToString(md5Sum[:])
+	sha := base64.StdEncoding.EncodeToString(shaSum[:])
+
+	tests := []struct {
+		name       string
+		cfg        middleware.DigestConfig
+		body       string
+		headers    map[string]string
+		wantStatus int
+		wantParam  string
+	}{
+		{"content-md5", middleware.DigestConfig{}, body, map[string]string{"Content-MD5": contentMD5}, http.StatusCreated, ""},
+		{"digest", middleware.DigestConfig{}, body, map[string]string{"Digest": "SHA-256=" + sha}, http.StatusCreated, ""},
+		{"content-digest", middleware.DigestConfig{}, body, map[string]string{"Content-Digest": "sha-256=:" + sha + ":"}, http.StatusCreated, ""},
+		{"spooled to disk", middleware.DigestConfig{MemoryBytes: 64}, body, map[string]string{"Digest": "sha-256=" + sha + ", md5=" + contentMD5}, http.StatusCreated, ""},
+		{"truncated", middleware.DigestConfig{}, body[:1000], map[string]string{"Content-MD5": contentMD5}, http.StatusBadRequest, "Content-MD5"},
+		{"truncated spooled", middleware.DigestConfig{MemoryBytes: 64}, body[:1000], map[string]string{"Content-Digest": "sha-256=:" + sha + ":"}, http.StatusBadRequest, "Content-Digest"},
+		{"one of several wrong", middleware.DigestConfig{}, body, map[string]string{"Content-MD5": contentMD5, "Digest": "SHA-256=" + contentMD5}, http.StatusBadRequest, "Digest"},
+		{"malformed", middleware.DigestConfig{}, body, map[string]string{"Content-MD5": "not base64"}, http.StatusBadRequest, "Content-MD5"},
+		{"unsupported algorithm", middleware.DigestConfig{}, body, map[string]string{"Digest": "UNIXsum=30637"}, http.StatusCreated, ""},
+		{"no digest", middleware.DigestConfig{}, body, nil, http.StatusCreated, ""},
+		{"no digest required", middleware.DigestConfig{Required: true}, body, nil, http.StatusBadRequest, "Content-Digest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.TempDir = t.TempDir()
+			var received string
+			router := gin.New()
+			router.POST("/uploads", middleware.VerifyDigest(tt.cfg), func(c *gin.Context) {
+				b, _ := io.ReadAll(c.Request.Body)
+				received = string(b)
+				c.Status(http.StatusCreated)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/uploads", strings.NewReader(tt.body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusCreated && received != tt.body {
+				t.Errorf("expected the handler to read the whole body, got %d bytes", len(received))
+			}
+			if tt.wantParam != "" {
+				e, err := response.ParseError(w.Body.Bytes())
+				if err != nil || e.Error.Param != tt.wantParam {
+					t.Errorf("expected param %s, got %+v (%v)", tt.wantParam, e.Error, err)
+				}
+				if tt.cfg.Required && tt.headers == nil {
+					return
+				}
+				if e.Error.Code != response.ErrorCodeDigestMismatch {
+					t.Errorf("expected code %s, got %s", response.ErrorCodeDigestMismatch, e.Error.Code)
+				}
+			}
+			if entries, _ := os.ReadDir(tt.cfg.TempDir); len(entries) != 0 {
+				t.Errorf("expected spooled bodies removed, found %d files", len(entries))
+			}
+		})
+	}
+}
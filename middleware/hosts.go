@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// AllowedHosts returns middleware that rejects requests whose Host header is
+// not in the allowlist, protecting services on shared ingress against
+// DNS-rebinding and host header confusion.
+//
+// Entries are matched case-insensitively and without the port. A leading
+// wildcard matches any subdomain: "*.doujins.com" matches "cdn.doujins.com"
+// but not "doujins.com" itself.
+//
+// Requests without a Host get 400; hosts outside the allowlist get 421.
+func AllowedHosts(hosts []string) gin.HandlerFunc {
+	return NewHostAllowlist(hosts).Middleware()
+}
+
+// HostAllowlist is the check behind AllowedHosts, with an allowlist that
+// can be replaced at runtime.
+type HostAllowlist struct {
+	hosts atomic.Pointer[hostMatcher]
+}
+
+// NewHostAllowlist returns a HostAllowlist for hosts.
+func NewHostAllowlist(hosts []string) *HostAllowlist {
+	a := &HostAllowlist{}
+	a.Update(hosts)
+	return a
+}
+
+// Update replaces the allowlist.
+func (a *HostAllowlist) Update(hosts []string) {
+	a.hosts.Store(newHostMatcher(hosts))
+}
+
+// Middleware returns the gin middleware; see AllowedHosts.
+func (a *HostAllowlist) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		host := hostWithoutPort(c.Request.Host)
+		if host == "" {
+			response.BadRequestParam(c, "Host", "missing Host header")
+			c.Abort()
+			return
+		}
+
+		if a.hosts.Load().allows(host) {
+			c.Next()
+			return
+		}
+
+		response.MisdirectedRequest(c, "host not allowed")
+		c.Abort()
+	}
+}
+
+// hostMatcher holds a normalized allowlist.
+type hostMatcher struct {
+	exact    map[string]struct{}
+	suffixes []string
+}
+
+func newHostMatcher(hosts []string) *hostMatcher {
+	m := &hostMatcher{exact: make(map[string]struct{}, len(hosts))}
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if strings.HasPrefix(h, "*.") {
+			m.suffixes = append(m.suffixes, h[1:]) // keep the leading dot
+		} else if h != "" {
+			m.exact[h] = struct{}{}
+		}
+	}
+	return m
+}
+
+// allows reports whether the normalized host is on the allowlist.
+func (m *hostMatcher) allows(host string) bool {
+	if _, ok := m.exact[host]; ok {
+		return true
+	}
+	for _, suffix := range m.suffixes {
+		if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostWithoutPort lowercases host and strips any port and trailing dot.
+func hostWithoutPort(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimPrefix(strings.TrimSuffix(host, "]"), "[")
+	return strings.TrimSuffix(host, ".")
+}
@@ -0,0 +1,88 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestAllowedHosts(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.AllowedHosts([]string{"doujins.com", "*.doujins.com", "localhost"}))
+	router.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		host       string
+		wantStatus int
+	}{
+		{"doujins.com", http.StatusOK},
+		{"DOUJINS.com:443", http.StatusOK},
+		{"cdn.doujins.com", http.StatusOK},
+		{"localhost:8080", http.StatusOK},
+		{"doujins.com.", http.StatusOK},
+		{"evil.com", http.StatusMisdirectedRequest},
+		{"notdoujins.com", http.StatusMisdirectedRequest},
+		{"", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Host = tt.host
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestAllowedHostsErrorBody(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.AllowedHosts([]string{"doujins.com"}))
+	router.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Host = "attacker.example"
+	router.ServeHTTP(w, req)
+
+	var result response.Error
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if result.Error.Code != response.ErrorCodeHostNotAllowed {
+		t.Errorf("expected code '%s', got '%s'", response.ErrorCodeHostNotAllowed, result.Error.Code)
+	}
+}
+
+func TestHostAllowlistUpdate(t *testing.T) {
+	allowlist := middleware.NewHostAllowlist([]string{"doujins.com"})
+	router := gin.New()
+	router.Use(allowlist.Middleware())
+	router.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func() int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Host = "cdn.example.com"
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if got := get(); got != http.StatusMisdirectedRequest {
+		t.Errorf("expected status 421 before the update, got %d", got)
+	}
+	allowlist.Update([]string{"doujins.com", "*.example.com"})
+	if got := get(); got != http.StatusOK {
+		t.Errorf("expected status 200 after the update, got %d", got)
+	}
+}
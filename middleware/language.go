@@ -2,11 +2,18 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
-	"strconv"
+	"net/url"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+	"github.com/doujins-org/ginapi/tenant"
 )
 
 const (
@@ -22,22 +29,160 @@ type LanguageConfig struct {
 	Supported []string
 	// Default language if none detected (defaults to "en")
 	Default string
+	// HostDefaults replaces Default on some hosts, for a deployment serving
+	// several domains; see HostDefaults
+	HostDefaults HostDefaults
 	// QueryParam to check for language override (defaults to "lang")
 	QueryParam string
 	// CookieName to check for language preference (defaults to "lang")
 	CookieName string
+	// NoPathPrefix skips the URL path prefix, for API-only services whose
+	// first path segments aren't languages but may look like supported
+	// ones, e.g. "/id/123" with Indonesian supported
+	NoPathPrefix bool
+	// Reserved are first path segments never taken as a language prefix,
+	// e.g. "api", "img", or "cdn"
+	Reserved []string
+	// Tenants, if set, returns the languages of the request's tenant (see
+	// package tenant), replacing Supported and Default; false keeps them.
+	// It is called once per tenant ID, until Update.
+	Tenants func(t tenant.Tenant) (TenantLanguages, bool)
+}
+
+// TenantLanguages are the languages of one tenant, for
+// LanguageConfig.Tenants.
+type TenantLanguages struct {
+	// Supported replaces LanguageConfig.Supported; empty keeps it
+	Supported []string
+	// Default replaces LanguageConfig.Default; empty keeps it.
+	// HostDefaults still apply on top of it.
+	Default string
 }
 
 // Language returns middleware that detects user language from:
 // 1. Query parameter (?lang=ja) - for API routes
-// 2. URL path prefix (/ja/...) - for frontend routes
+// 2. URL path prefix (/ja/...) - for frontend routes, unless NoPathPrefix
 // 3. Cookie (user's saved preference)
 // 4. Accept-Language header with q-value parsing
-// 5. Default language
+// 5. Default language, per host with HostDefaults
+//
+// With Tenants set, the supported languages and the default are those of
+// the request's tenant, so white-label deployments sharing a build can
+// offer different languages:
+//
+//	router.Use(tenant.Middleware(tenant.ByHost(hosts)))
+//	router.Use(middleware.Language(middleware.LanguageConfig{
+//	    Supported: []string{"en"},
+//	    Tenants: func(t tenant.Tenant) (middleware.TenantLanguages, bool) {
+//	        if t.ID == "jp" {
+//	            return middleware.TenantLanguages{Supported: []string{"ja", "en"}, Default: "ja"}, true
+//	        }
+//	        return middleware.TenantLanguages{}, false
+//	    },
+//	}))
 //
 // The detected language is stored in gin context and retrieved via GetLanguage(c).
-// The Content-Language header is set on the response.
+// The Content-Language header is set on the response, and the request headers
+// detection read are added to Vary: the cookie when the language didn't come
+// from the URL, and Accept-Language when there was no cookie either.
 func Language(cfg LanguageConfig) gin.HandlerFunc {
+	return NewLanguageDetector(cfg).Middleware()
+}
+
+// LanguageHandler is the net/http equivalent of Language, for the standard
+// library mux and chi (both use the func(http.Handler) http.Handler shape).
+// Retrieve the language with LanguageFromContext(r.Context()).
+func LanguageHandler(cfg LanguageConfig) func(http.Handler) http.Handler {
+	return NewLanguageDetector(cfg).Handler()
+}
+
+// LanguageDetector is the language detection behind Language and
+// LanguageHandler, with a config that can be replaced at runtime, e.g. to
+// add a supported language without a restart:
+//
+//	detector := middleware.NewLanguageDetector(cfg)
+//	router.Use(detector.Middleware())
+//
+//	watcher.OnChange(func(cfg middleware.LanguageConfig) { detector.Update(cfg) })
+type LanguageDetector struct {
+	resolver atomic.Pointer[languageResolver]
+}
+
+// NewLanguageDetector returns a LanguageDetector for cfg.
+func NewLanguageDetector(cfg LanguageConfig) *LanguageDetector {
+	d := &LanguageDetector{}
+	d.Update(cfg)
+	return d
+}
+
+// Update replaces the config. Requests already being handled keep the
+// config they started with.
+func (d *LanguageDetector) Update(cfg LanguageConfig) {
+	d.resolver.Store(newLanguageResolver(cfg))
+}
+
+// Middleware returns the gin middleware; see Language.
+func (d *LanguageDetector) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang, vary := d.resolver.Load().forRequest(c.Request).resolveVary(c.Request)
+
+		// Store in gin context (use GetLanguage(c) to retrieve)
+		c.Set("language", lang)
+
+		// Store in request context for layers without gin (use LanguageFromContext)
+		if c.Request != nil {
+			c.Request = c.Request.WithContext(WithLanguage(c.Request.Context(), lang))
+		}
+
+		// Set response headers; caches must key on the headers detection read
+		c.Header("Content-Language", lang)
+		response.AddVary(c.Writer.Header(), vary...)
+
+		c.Next()
+	}
+}
+
+// Handler returns the net/http middleware; see LanguageHandler.
+func (d *LanguageDetector) Handler() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lang, vary := d.resolver.Load().forRequest(r).resolveVary(r)
+			w.Header().Set("Content-Language", lang)
+			response.AddVary(w.Header(), vary...)
+			next.ServeHTTP(w, r.WithContext(WithLanguage(r.Context(), lang)))
+		})
+	}
+}
+
+// ResolveLanguage determines the language for r using the same rules as the
+// Language middleware. Prefer the middleware on hot paths; this rebuilds the
+// supported map on every call.
+func ResolveLanguage(r *http.Request, cfg LanguageConfig) string {
+	if r == nil {
+		misuse("ResolveLanguage", "with a nil request")
+	}
+	return newLanguageResolver(cfg).forRequest(r).resolve(r)
+}
+
+// languageResolver holds a normalized LanguageConfig.
+type languageResolver struct {
+	supported  map[string]struct{}
+	fallback   string
+	hosts      *hostDefaults
+	queryParam string
+	cookieName string
+	noPath     bool
+	reserved   map[string]struct{}
+	accept     *acceptLanguageCache
+
+	// cfg and tenants resolve per-tenant resolvers, cached in byTenant
+	cfg      LanguageConfig
+	tenants  func(t tenant.Tenant) (TenantLanguages, bool)
+	byTenant sync.Map // tenant ID -> *languageResolver
+}
+
+// newLanguageResolver normalizes cfg, applying defaults.
+func newLanguageResolver(cfg LanguageConfig) *languageResolver {
 	// Build supported language map for fast lookup
 	supportedMap := make(map[string]struct{}, len(cfg.Supported))
 	for _, lang := range cfg.Supported {
@@ -63,148 +208,177 @@ func Language(cfg LanguageConfig) gin.HandlerFunc {
 		cookieName = "lang"
 	}
 
-	return func(c *gin.Context) {
-		lang := resolveLanguage(c, supportedMap, defaultLang, queryParam, cookieName)
-
-		// Store in gin context (use GetLanguage(c) to retrieve)
-		c.Set("language", lang)
-
-		// Set response header
-		c.Header("Content-Language", lang)
+	return &languageResolver{
+		supported:  supportedMap,
+		fallback:   defaultLang,
+		hosts:      newHostDefaults(cfg.HostDefaults),
+		queryParam: queryParam,
+		cookieName: cookieName,
+		noPath:     cfg.NoPathPrefix,
+		reserved:   BuildSupportedMap(cfg.Reserved),
+		accept:     newAcceptLanguageCache(acceptLanguageCacheSize),
+		cfg:        cfg,
+		tenants:    cfg.Tenants,
+	}
+}
 
-		c.Next()
+// forRequest returns the resolver for the tenant of r: lr, or one with the
+// tenant's languages.
+func (lr *languageResolver) forRequest(r *http.Request) *languageResolver {
+	if lr.tenants == nil || r == nil {
+		return lr
+	}
+	t, ok := tenant.FromContext(r.Context())
+	if !ok {
+		return lr
+	}
+	if cached, ok := lr.byTenant.Load(t.ID); ok {
+		return cached.(*languageResolver)
 	}
+	resolver := lr
+	if langs, ok := lr.tenants(t); ok {
+		cfg := lr.cfg
+		cfg.Tenants = nil
+		if len(langs.Supported) > 0 {
+			cfg.Supported = langs.Supported
+		}
+		if langs.Default != "" {
+			cfg.Default = langs.Default
+		}
+		resolver = newLanguageResolver(cfg)
+	}
+	cached, _ := lr.byTenant.LoadOrStore(t.ID, resolver)
+	return cached.(*languageResolver)
 }
 
-// resolveLanguage determines the best language from available sources.
-func resolveLanguage(c *gin.Context, supported map[string]struct{}, fallback, queryParam, cookieName string) string {
-	if c == nil || c.Request == nil {
-		return fallback
+// resolve determines the best language from available sources.
+func (lr *languageResolver) resolve(r *http.Request) string {
+	lang, _ := lr.resolveVary(r)
+	return lang
+}
+
+// resolveVary determines the best language from available sources, and
+// returns the request headers it read. The query and path are part of the
+// URL, which caches key on anyway.
+func (lr *languageResolver) resolveVary(r *http.Request) (lang string, vary []string) {
+	if r == nil || r.URL == nil {
+		return lr.fallback, nil
 	}
+	supported := lr.supported
 
 	// 1. Check query parameter (for API routes like /api/v1/videos?lang=ja)
-	if lang := strings.ToLower(strings.TrimSpace(c.Query(queryParam))); lang != "" {
+	if lang := strings.ToLower(strings.TrimSpace(r.URL.Query().Get(lr.queryParam))); lang != "" {
 		if _, ok := supported[lang]; ok {
-			return lang
+			return lang, nil
 		}
 	}
 
 	// 2. Check URL path prefix (for frontend routes like /ja/videos)
-	if lang := extractLanguageFromPath(c.Request.URL.Path); lang != "" {
-		if _, ok := supported[lang]; ok {
-			return lang
+	if !lr.noPath {
+		if lang := extractLanguageFromPath(r.URL.Path); lang != "" && !isReserved(lr.reserved, lang) {
+			if _, ok := supported[lang]; ok {
+				return lang, nil
+			}
 		}
 	}
 
 	// 3. Check cookie (user's saved preference)
-	if cookieName != "" {
-		if lang, err := c.Cookie(cookieName); err == nil && lang != "" {
+	if lr.cookieName != "" {
+		vary = append(vary, "Cookie")
+		if lang := cookieValue(r, lr.cookieName); lang != "" {
 			lang = strings.ToLower(strings.TrimSpace(lang))
 			if _, ok := supported[lang]; ok {
-				return lang
+				return lang, vary
 			}
 		}
 	}
 
 	// 4. Check Accept-Language header
-	if header := c.GetHeader("Accept-Language"); header != "" {
-		if lang := ParseAcceptLanguage(header, supported); lang != "" {
-			return lang
+	vary = append(vary, "Accept-Language")
+	if header := r.Header.Get("Accept-Language"); header != "" {
+		if lang := lr.accept.parse(header, supported); lang != "" {
+			return lang, vary
 		}
 	}
 
-	return fallback
+	// 5. Default for the host, or the default
+	return lr.hosts.lookup(r.Host, lr.fallback), vary
 }
 
-// extractLanguageFromPath extracts a 2-3 character language code from URL path prefix.
-// e.g., "/ja/galleries" -> "ja"
-func extractLanguageFromPath(path string) string {
-	trimmed := strings.TrimPrefix(path, "/")
-	parts := strings.SplitN(trimmed, "/", 2)
-	if len(parts) == 0 {
+// cookieValue returns the unescaped value of the named cookie, matching gin's c.Cookie.
+func cookieValue(r *http.Request, name string) string {
+	cookie, err := r.Cookie(name)
+	if err != nil {
 		return ""
 	}
-	first := parts[0]
-	if len(first) == 2 || len(first) == 3 {
-		return strings.ToLower(first)
+	value, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return ""
 	}
-	return ""
+	return value
 }
 
-// ParseAcceptLanguage parses the Accept-Language header and returns the best
-// supported language based on q-values. Exported for use by redirect middleware.
-func ParseAcceptLanguage(header string, supported map[string]struct{}) string {
-	type candidate struct {
-		lang string
-		q    float64
+// extractLanguageFromPath extracts a 2-3 letter language code from URL path prefix.
+// e.g., "/ja/galleries" -> "ja". Segments with anything but ASCII letters
+// ("/..", "/v1") are not language codes.
+func extractLanguageFromPath(path string) string {
+	first, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if len(first) != 2 && len(first) != 3 {
+		return ""
 	}
-
-	var candidates []candidate
-	parts := strings.Split(header, ",")
-
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part == "" {
-			continue
+	for i := 0; i < len(first); i++ {
+		if b := first[i] | 0x20; b < 'a' || b > 'z' {
+			return ""
 		}
-
-		lang := part
-		q := 1.0
-
-		// Parse q-value if present (e.g., "en-US;q=0.9")
-		if idx := strings.Index(part, ";"); idx >= 0 {
-			lang = part[:idx]
-			param := strings.TrimSpace(part[idx+1:])
-			if strings.HasPrefix(param, "q=") {
-				if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
-					q = v
-				}
-			}
-		}
-
-		// Extract base language (e.g., "en-US" -> "en")
-		if hyphen := strings.Index(lang, "-"); hyphen >= 0 {
-			lang = lang[:hyphen]
-		}
-		lang = strings.ToLower(strings.TrimSpace(lang))
-
-		if lang == "" {
-			continue
-		}
-
-		candidates = append(candidates, candidate{lang: lang, q: q})
 	}
-
-	// Find highest q-value language that's supported
-	var bestLang string
-	var bestQ float64 = -1
-
-	for _, c := range candidates {
-		if _, ok := supported[c.lang]; ok && c.q > bestQ {
-			bestLang = c.lang
-			bestQ = c.q
-		}
-	}
-
-	return bestLang
+	return strings.ToLower(first)
 }
 
 // GetLanguage retrieves the detected language from the gin context.
-// Returns "en" as fallback if not set.
+// Returns "en" as fallback if not set (see SetStrictMode).
 func GetLanguage(c *gin.Context) string {
 	if c == nil {
+		misuse("GetLanguage", "with a nil context")
 		return "en"
 	}
+	if lang, ok := detectedLanguage(c); ok {
+		return lang
+	}
+	misuse("GetLanguage", "without the Language middleware")
+	return "en"
+}
 
+// detectedLanguage returns the language set by the Language middleware.
+func detectedLanguage(c *gin.Context) (string, bool) {
 	if lang, exists := c.Get("language"); exists {
 		if s, ok := lang.(string); ok && s != "" {
-			return s
+			return s, true
 		}
 	}
+	return "", false
+}
 
-	return "en"
+// languageContextKey is the request context key for the detected language.
+type languageContextKey struct{}
+
+// WithLanguage returns a copy of ctx carrying the given language code.
+// The code is normalized to lowercase.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageContextKey{}, strings.ToLower(strings.TrimSpace(lang)))
 }
 
+// LanguageFromContext retrieves the language stored by WithLanguage (or the
+// Language middleware). Returns "" if ctx is nil or carries no language.
+func LanguageFromContext(ctx context.Context) string {
+	if ctx == nil {
+		misuse("LanguageFromContext", "with a nil context")
+		return ""
+	}
+	if lang, ok := ctx.Value(languageContextKey{}).(string); ok {
+		return lang
+	}
+	return ""
+}
 
 // BuildSupportedMap creates a map of supported languages for fast lookup.
 // Useful for redirect middleware that needs to check language validity.
@@ -216,7 +390,7 @@ func BuildSupportedMap(languages []string) map[string]struct{} {
 	return m
 }
 
-// ExtractLanguageFromPath extracts a 2-3 character language code from URL path prefix.
+// ExtractLanguageFromPath extracts a 2-3 letter language code from URL path prefix.
 // e.g., "/ja/galleries" -> "ja", "/galleries" -> ""
 // Exported for use by redirect middleware.
 func ExtractLanguageFromPath(path string) string {
@@ -229,6 +403,100 @@ type LanguageRedirectConfig struct {
 	Supported []string
 	// Default language if none detected (defaults to "en")
 	Default string
+	// HostDefaults replaces Default on some hosts; see HostDefaults
+	HostDefaults HostDefaults
+	// Reserved are first path segments that aren't pages: paths under
+	// them, e.g. "/api/..." for "api", are never redirected
+	Reserved []string
+	// Redirects, if set, are legacy URL redirects checked before the
+	// language redirect; see RedirectMap
+	Redirects *RedirectMap
+}
+
+// isReserved reports whether the lowercase path segment is reserved.
+func isReserved(reserved map[string]struct{}, segment string) bool {
+	_, ok := reserved[segment]
+	return ok
+}
+
+// HostDefaults resolves the default language per request host, so one
+// binary can serve example.jp in Japanese and example.com in English:
+//
+//	HostDefaults: middleware.HostDefaults{
+//	    Hosts: map[string]string{"*.jp": "ja", "example.jp": "ja", "example.com": "en"},
+//	}
+//
+// The default only applies when the request names no language it
+// accepts; a visitor of example.jp whose browser asks for English still
+// gets English.
+type HostDefaults struct {
+	// Hosts maps hosts to their default language. Keys are exact hosts or
+	// "*." suffixes ("*.jp", or ".jp"); an exact host wins over suffixes,
+	// and longer suffixes over shorter ones.
+	Hosts map[string]string
+	// Func, if set, returns the default language for a host (lowercased,
+	// without its port) before Hosts are checked; "" defers to them
+	Func func(host string) string
+}
+
+// hostDefaults holds normalized HostDefaults.
+type hostDefaults struct {
+	exact    map[string]string
+	suffixes []hostDefault // longest first
+	fn       func(host string) string
+}
+
+type hostDefault struct {
+	suffix, lang string
+}
+
+// newHostDefaults normalizes d; it returns nil if d is empty.
+func newHostDefaults(d HostDefaults) *hostDefaults {
+	if len(d.Hosts) == 0 && d.Func == nil {
+		return nil
+	}
+	h := &hostDefaults{exact: map[string]string{}, fn: d.Func}
+	for host, lang := range d.Hosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if suffix, ok := strings.CutPrefix(host, "*"); ok || strings.HasPrefix(host, ".") {
+			if !ok {
+				suffix = host
+			}
+			h.suffixes = append(h.suffixes, hostDefault{suffix: suffix, lang: lang})
+		} else if host != "" {
+			h.exact[host] = lang
+		}
+	}
+	sort.Slice(h.suffixes, func(i, j int) bool {
+		if len(h.suffixes[i].suffix) != len(h.suffixes[j].suffix) {
+			return len(h.suffixes[i].suffix) > len(h.suffixes[j].suffix)
+		}
+		return h.suffixes[i].suffix < h.suffixes[j].suffix
+	})
+	return h
+}
+
+// lookup returns the default language for the request host, or fallback.
+func (h *hostDefaults) lookup(requestHost, fallback string) string {
+	if h == nil {
+		return fallback
+	}
+	host := hostWithoutPort(requestHost)
+	if h.fn != nil {
+		if lang := strings.ToLower(strings.TrimSpace(h.fn(host))); lang != "" {
+			return lang
+		}
+	}
+	if lang, ok := h.exact[host]; ok {
+		return lang
+	}
+	for _, d := range h.suffixes {
+		if strings.HasSuffix(host, d.suffix) && len(host) > len(d.suffix) {
+			return d.lang
+		}
+	}
+	return fallback
 }
 
 // HandleLanguageRedirect checks if a language redirect is needed and performs it.
@@ -248,18 +516,32 @@ type LanguageRedirectConfig struct {
 // Behavior:
 //   - If URL has a valid language prefix (e.g., /en/videos): set cookie, return false
 //   - If URL has NO language prefix (e.g., /videos): redirect to prefixed URL, return true
+//   - If URL starts with a Reserved segment (e.g., /api/videos): return false
+//
+// Paths in cfg.Redirects are redirected first, with a 301 (308 for methods
+// with a body) keeping the query string. A legacy path with a supported
+// language prefix, e.g. /ja/g/123 for a /g/:id rule, keeps the prefix on
+// the target; other targets without one get it from a second, language
+// redirect, so the permanent redirect doesn't depend on the visitor.
 func HandleLanguageRedirect(c *gin.Context, cfg LanguageRedirectConfig) bool {
+	supportedMap := BuildSupportedMap(cfg.Supported)
+	if cfg.Redirects != nil && cfg.Redirects.redirect(c, supportedMap) {
+		return true
+	}
 	if len(cfg.Supported) == 0 {
 		return false
 	}
 
-	supportedMap := BuildSupportedMap(cfg.Supported)
 	defaultLang := strings.ToLower(strings.TrimSpace(cfg.Default))
 	if defaultLang == "" {
 		defaultLang = "en"
 	}
+	defaultLang = newHostDefaults(cfg.HostDefaults).lookup(c.Request.Host, defaultLang)
 
 	path := c.Request.URL.Path
+	if first, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/"); isReserved(BuildSupportedMap(cfg.Reserved), strings.ToLower(first)) {
+		return false
+	}
 
 	// Check if URL already has a language prefix
 	langFromPath := extractLanguageFromPath(path)
@@ -288,7 +570,11 @@ func HandleLanguageRedirect(c *gin.Context, cfg LanguageRedirectConfig) bool {
 
 // DetectPreferredLanguage determines user's preferred language.
 // Priority: cookie → Accept-Language → default
+// Adds the headers it read to Vary, since the result (typically a redirect)
+// depends on them: Cookie, and Accept-Language unless the cookie decided.
 func DetectPreferredLanguage(c *gin.Context, supportedMap map[string]struct{}, defaultLang string) string {
+	response.AddVary(c.Writer.Header(), "Cookie")
+
 	// 1. Check cookie (user's saved preference)
 	if lang, err := c.Cookie(LanguageCookieName); err == nil && lang != "" {
 		lang = strings.ToLower(strings.TrimSpace(lang))
@@ -298,6 +584,7 @@ func DetectPreferredLanguage(c *gin.Context, supportedMap map[string]struct{}, d
 	}
 
 	// 2. Check Accept-Language header
+	response.AddVary(c.Writer.Header(), "Accept-Language")
 	if header := c.GetHeader("Accept-Language"); header != "" {
 		if lang := ParseAcceptLanguage(header, supportedMap); lang != "" {
 			return lang
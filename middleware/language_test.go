@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/tenant"
 )
 
 func init() {
@@ -36,6 +37,57 @@ func TestLanguageFromQueryParam(t *testing.T) {
 	if w.Header().Get("Content-Language") != "ja" {
 		t.Errorf("expected Content-Language 'ja', got '%s'", w.Header().Get("Content-Language"))
 	}
+	if w.Header().Get("Vary") != "" {
+		t.Errorf("expected no Vary for a language from the URL, got '%s'", w.Header().Get("Vary"))
+	}
+}
+
+func TestLanguageVary(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.Language(middleware.LanguageConfig{
+		Supported: []string{"en", "ja"},
+		Default:   "en",
+	}))
+	router.GET("/*path", func(c *gin.Context) {
+		c.String(http.StatusOK, middleware.GetLanguage(c))
+	})
+
+	tests := []struct {
+		name     string
+		target   string
+		cookie   string
+		accept   string
+		wantLang string
+		wantVary string
+	}{
+		{"query", "/galleries?lang=ja", "en", "en", "ja", ""},
+		{"path", "/ja/galleries", "en", "en", "ja", ""},
+		{"cookie", "/galleries", "ja", "en", "ja", "Cookie"},
+		{"unsupported cookie", "/galleries", "fr", "ja", "ja", "Cookie, Accept-Language"},
+		{"accept language", "/galleries", "", "ja", "ja", "Cookie, Accept-Language"},
+		{"default", "/galleries", "", "", "en", "Cookie, Accept-Language"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tt.target, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "lang", Value: tt.cookie})
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept-Language", tt.accept)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.wantLang {
+				t.Errorf("expected %s, got %s", tt.wantLang, w.Body.String())
+			}
+			if got := w.Header().Get("Vary"); got != tt.wantVary {
+				t.Errorf("expected Vary %q, got %q", tt.wantVary, got)
+			}
+		})
+	}
 }
 
 func TestLanguageFromPath(t *testing.T) {
@@ -58,6 +110,84 @@ func TestLanguageFromPath(t *testing.T) {
 	}
 }
 
+func TestLanguageNoPathPrefix(t *testing.T) {
+	tests := []struct {
+		name         string
+		noPathPrefix bool
+		want         string
+	}{
+		{"path prefix", false, "id"},
+		{"no path prefix", true, "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(middleware.Language(middleware.LanguageConfig{
+				Supported:    []string{"en", "id"},
+				NoPathPrefix: tt.noPathPrefix,
+			}))
+			router.GET("/id/:id", func(c *gin.Context) {
+				c.String(http.StatusOK, middleware.GetLanguage(c))
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/id/123", nil)
+			router.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestLanguageReserved(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.Language(middleware.LanguageConfig{
+		Supported: []string{"en", "id"},
+		Reserved:  []string{"ID"},
+	}))
+	router.GET("/id/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, middleware.GetLanguage(c))
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/id/123", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Body.String() != "en" {
+		t.Errorf("expected a reserved segment not to be a language, got %q", w.Body.String())
+	}
+}
+
+func TestLanguageRedirectReserved(t *testing.T) {
+	tests := []struct {
+		path         string
+		wantRedirect bool
+	}{
+		{"/galleries", true},
+		{"/api/galleries", false},
+		{"/API/galleries", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", tt.path, nil)
+
+			redirected := middleware.HandleLanguageRedirect(c, middleware.LanguageRedirectConfig{
+				Supported: []string{"en", "ja"},
+				Reserved:  []string{"api"},
+			})
+			if redirected != tt.wantRedirect {
+				t.Errorf("expected redirect %v, got %v", tt.wantRedirect, redirected)
+			}
+		})
+	}
+}
+
 func TestLanguageFromAcceptHeader(t *testing.T) {
 	router := gin.New()
 	router.Use(middleware.Language(middleware.LanguageConfig{
@@ -100,6 +230,135 @@ func TestLanguageDefault(t *testing.T) {
 	}
 }
 
+func TestLanguageHostDefaults(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.Language(middleware.LanguageConfig{
+		Supported: []string{"en", "ja", "ko"},
+		Default:   "en",
+		HostDefaults: middleware.HostDefaults{
+			Hosts: map[string]string{"*.jp": "ja", ".co.jp": "ko", "shop.example.jp": "en"},
+			Func: func(host string) string {
+				if host == "kr.example.com" {
+					return "ko"
+				}
+				return ""
+			},
+		},
+	}))
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, middleware.GetLanguage(c))
+	})
+
+	tests := []struct {
+		name           string
+		host           string
+		acceptLanguage string
+		want           string
+	}{
+		{"suffix", "example.jp:8080", "", "ja"},
+		{"longest suffix", "example.co.jp", "", "ko"},
+		{"exact host", "shop.example.jp", "", "en"},
+		{"func", "kr.example.com", "", "ko"},
+		{"unmatched host", "example.com", "", "en"},
+		{"header wins", "example.jp", "en", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Host = tt.host
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestLanguageTenants(t *testing.T) {
+	calls := map[string]int{}
+	router := gin.New()
+	router.Use(tenant.Middleware(tenant.ByHost(map[string]tenant.Tenant{
+		"example.jp":  {ID: "jp"},
+		"example.kr":  {ID: "kr"},
+		"example.com": {ID: "global"},
+	})))
+	router.Use(middleware.Language(middleware.LanguageConfig{
+		Supported: []string{"en", "de"},
+		Default:   "en",
+		Tenants: func(t tenant.Tenant) (middleware.TenantLanguages, bool) {
+			calls[t.ID]++
+			switch t.ID {
+			case "jp":
+				return middleware.TenantLanguages{Supported: []string{"ja", "en"}, Default: "ja"}, true
+			case "kr":
+				return middleware.TenantLanguages{Default: "de"}, true
+			}
+			return middleware.TenantLanguages{}, false
+		},
+	}))
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, middleware.GetLanguage(c))
+	})
+
+	tests := []struct {
+		name  string
+		host  string
+		query string
+		want  string
+	}{
+		{"tenant default", "example.jp", "", "ja"},
+		{"tenant supported", "example.jp", "?lang=en", "en"},
+		{"unsupported by tenant", "example.jp", "?lang=de", "ja"},
+		{"tenant default only", "example.kr", "?lang=de", "de"},
+		{"tenant without override", "example.com", "?lang=ja", "en"},
+		{"no tenant", "example.org", "?lang=de", "de"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test"+tt.query, nil)
+			req.Host = tt.host
+			router.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, w.Body.String())
+			}
+		})
+	}
+
+	for id, n := range calls {
+		if n != 1 {
+			t.Errorf("expected the languages of %s to be resolved once, got %d", id, n)
+		}
+	}
+}
+
+func TestLanguageRedirectHostDefaults(t *testing.T) {
+	router := gin.New()
+	router.NoRoute(func(c *gin.Context) {
+		middleware.HandleLanguageRedirect(c, middleware.LanguageRedirectConfig{
+			Supported:    []string{"en", "ja"},
+			HostDefaults: middleware.HostDefaults{Hosts: map[string]string{"*.jp": "ja"}},
+		})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/galleries", nil)
+	req.Host = "example.jp"
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Location"); got != "/ja/galleries" {
+		t.Errorf("expected a redirect to /ja/galleries, got %q", got)
+	}
+}
+
 func TestLanguageContext(t *testing.T) {
 	ctx := context.Background()
 	ctx = middleware.WithLanguage(ctx, "JA")
@@ -121,3 +380,96 @@ func TestLanguageContextEmpty(t *testing.T) {
 		t.Errorf("expected empty string, got '%s'", lang)
 	}
 }
+
+func TestLanguageHandler(t *testing.T) {
+	handler := middleware.LanguageHandler(middleware.LanguageConfig{
+		Supported: []string{"en", "ja"},
+		Default:   "en",
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(middleware.LanguageFromContext(r.Context())))
+	}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "ja"})
+	handler.ServeHTTP(w, req)
+
+	if w.Body.String() != "ja" {
+		t.Errorf("expected 'ja', got '%s'", w.Body.String())
+	}
+	if w.Header().Get("Content-Language") != "ja" {
+		t.Errorf("expected Content-Language 'ja', got '%s'", w.Header().Get("Content-Language"))
+	}
+}
+
+func TestResolveLanguage(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/ko/galleries", nil)
+	lang := middleware.ResolveLanguage(req, middleware.LanguageConfig{Supported: []string{"en", "ko"}})
+	if lang != "ko" {
+		t.Errorf("expected 'ko', got '%s'", lang)
+	}
+}
+
+func TestLanguageDetectorUpdate(t *testing.T) {
+	detector := middleware.NewLanguageDetector(middleware.LanguageConfig{Supported: []string{"en"}})
+	router := gin.New()
+	router.Use(detector.Middleware())
+	router.GET("/test", func(c *gin.Context) { c.String(http.StatusOK, middleware.GetLanguage(c)) })
+
+	get := func() string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test?lang=ja", nil)
+		router.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	if got := get(); got != "en" {
+		t.Errorf("expected en before the update, got %s", got)
+	}
+	detector.Update(middleware.LanguageConfig{Supported: []string{"en", "ja"}})
+	if got := get(); got != "ja" {
+		t.Errorf("expected ja after the update, got %s", got)
+	}
+}
+
+func FuzzExtractLanguageFromPath(f *testing.F) {
+	for _, seed := range []string{"", "/", "/ja/galleries", "/EN", "/fil/x", "/galleries", "/../x", "//ja", "/v1/items", "/é/x"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, path string) {
+		lang := middleware.ExtractLanguageFromPath(path)
+		if lang == "" {
+			return
+		}
+		if len(lang) < 2 || len(lang) > 3 {
+			t.Fatalf("expected a 2-3 letter code, got %q for %q", lang, path)
+		}
+		for i := 0; i < len(lang); i++ {
+			if lang[i] < 'a' || lang[i] > 'z' {
+				t.Fatalf("expected lowercase ASCII letters, got %q for %q", lang, path)
+			}
+		}
+	})
+}
+
+func TestExtractLanguageFromPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/ja/galleries", "ja"},
+		{"/EN", "en"},
+		{"/fil/x", "fil"},
+		{"/galleries", ""},
+		{"/../x", ""},
+		{"/v1/items", ""},
+		{"/é/x", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := middleware.ExtractLanguageFromPath(tt.path); got != tt.want {
+			t.Errorf("ExtractLanguageFromPath(%q): expected %q, got %q", tt.path, tt.want, got)
+		}
+	}
+}
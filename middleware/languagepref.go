@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// LanguagePreferenceConfig configures SetLanguageHandler.
+type LanguagePreferenceConfig struct {
+	// Supported languages (e.g., []string{"en", "ja", "ko", "zh"})
+	Supported []string
+	// CookieName for the preference (defaults to "lang")
+	CookieName string
+	// MaxAge of the cookie in seconds (defaults to 1 year)
+	MaxAge int
+	// Path of the cookie (defaults to "/")
+	Path string
+	// Domain of the cookie, e.g. ".example.com" to share across subdomains
+	Domain string
+	// Secure restricts the cookie to HTTPS
+	Secure bool
+	// SameSite policy (defaults to Lax)
+	SameSite http.SameSite
+}
+
+// languagePreferenceRequest is the body accepted by SetLanguageHandler.
+type languagePreferenceRequest struct {
+	Language string `json:"language"`
+}
+
+// SetLanguageHandler returns a handler for saving the user's language,
+// e.g. POST /api/v1/preferences/language with {"language": "ja"}.
+//
+// The language is validated against Supported, written to the cookie the
+// Language middleware reads (not HttpOnly, so frontends can read it too),
+// applied to the current request, and confirmed with a Message response.
+//
+//	api.POST("/preferences/language", middleware.SetLanguageHandler(middleware.LanguagePreferenceConfig{
+//	    Supported: []string{"en", "ja", "ko", "zh"},
+//	    Domain:    ".example.com",
+//	    Secure:    true,
+//	}))
+func SetLanguageHandler(cfg LanguagePreferenceConfig) gin.HandlerFunc {
+	if cfg.CookieName == "" {
+		cfg.CookieName = LanguageCookieName
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = LanguageCookieMaxAge
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+	supported := BuildSupportedMap(cfg.Supported)
+
+	return func(c *gin.Context) {
+		var req languagePreferenceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequestWithCode(c, response.ErrorCodeInvalidFormat, "invalid request body")
+			return
+		}
+
+		lang := strings.ToLower(strings.TrimSpace(req.Language))
+		if lang == "" {
+			response.ErrorWithInfo(c, http.StatusBadRequest, response.ErrorInfo{
+				Type:    response.ErrorTypeInvalidRequest,
+				Code:    response.ErrorCodeMissingParam,
+				Message: "language is required",
+				Param:   "language",
+			})
+			return
+		}
+		if _, ok := supported[lang]; !ok {
+			response.ErrorWithInfo(c, http.StatusBadRequest, response.ErrorInfo{
+				Type:    response.ErrorTypeInvalidRequest,
+				Code:    response.ErrorCodeInvalidParam,
+				Message: "unsupported language: " + lang,
+				Param:   "language",
+			})
+			return
+		}
+
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     cfg.CookieName,
+			Value:    lang,
+			MaxAge:   cfg.MaxAge,
+			Path:     cfg.Path,
+			Domain:   cfg.Domain,
+			Secure:   cfg.Secure,
+			SameSite: cfg.SameSite,
+		})
+
+		c.Set("language", lang)
+		c.Request = c.Request.WithContext(WithLanguage(c.Request.Context(), lang))
+		c.Header("Content-Language", lang)
+
+		response.Success(c, "language updated")
+	}
+}
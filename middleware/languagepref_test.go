@@ -0,0 +1,74 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestSetLanguageHandler(t *testing.T) {
+	router := gin.New()
+	router.POST("/preferences/language", middleware.SetLanguageHandler(middleware.LanguagePreferenceConfig{
+		Supported: []string{"en", "ja"},
+		Domain:    ".example.com",
+		Secure:    true,
+	}))
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+		wantCookie string
+	}{
+		{
+			name:       "valid",
+			body:       `{"language":"JA"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"object":"message","message":"language updated"}`,
+			wantCookie: "lang=ja; Path=/; Domain=example.com; Max-Age=31536000; Secure; SameSite=Lax",
+		},
+		{
+			name:       "unsupported",
+			body:       `{"language":"fr"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"object":"error","error":{"type":"invalid_request","code":"invalid_param","message":"unsupported language: fr","param":"language"}}`,
+		},
+		{
+			name:       "missing",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"object":"error","error":{"type":"invalid_request","code":"missing_param","message":"language is required","param":"language"}}`,
+		},
+		{
+			name:       "malformed",
+			body:       `{`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"object":"error","error":{"type":"invalid_request","code":"invalid_format","message":"invalid request body"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/preferences/language", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("expected %s, got %s", tt.wantBody, w.Body.String())
+			}
+			if got := w.Header().Get("Set-Cookie"); got != tt.wantCookie {
+				t.Errorf("expected cookie '%s', got '%s'", tt.wantCookie, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LegacyUnixSeconds is a LegacyRule.DateLayouts entry matching Unix
+// timestamps in seconds, e.g. "1700000000".
+const LegacyUnixSeconds = "unix"
+
+// LegacyRule rewrites one legacy request shape into the current contract.
+// Renames run first; DateFields and IDFields name the current fields.
+type LegacyRule struct {
+	// Name identifies the rule in usage counts, e.g. "galleries.page_param"
+	Name string
+	// Method restricts the rule to one method. Empty matches any.
+	Method string
+	// Route restricts the rule to one route pattern, e.g. "/galleries/:id".
+	// Empty matches any.
+	Route string
+	// Query renames query parameters, old name to current name
+	Query map[string]string
+	// Fields renames top-level JSON body fields, old name to current name
+	Fields map[string]string
+	// DateFields are query parameters and JSON body fields whose values are
+	// rewritten to RFC 3339 when they match one of DateLayouts
+	DateFields []string
+	// DateLayouts are the legacy time layouts (time.Parse layouts or
+	// LegacyUnixSeconds), tried in order and parsed in UTC
+	DateLayouts []string
+	// IDFields are path parameters, query parameters, and JSON body fields
+	// whose values ConvertID rewrites
+	IDFields []string
+	// ConvertID maps a legacy ID to the current format. It returns false
+	// for IDs already in the current format, which are left as is.
+	ConvertID func(id string) (string, bool)
+}
+
+// LegacyConfig configures legacy request rewriting.
+type LegacyConfig struct {
+	// Rules are applied in order
+	Rules []LegacyRule
+	// MaxBodyBytes is the largest JSON body rewritten (defaults to 1 MiB).
+	// Larger bodies pass through unchanged.
+	MaxBodyBytes int
+	// OnRewrite, if set, is called for every rule that changed a request,
+	// e.g. to export usage by app version (see GetClientInfo)
+	OnRewrite func(c *gin.Context, rule string)
+}
+
+// LegacyRewriter rewrites legacy request shapes (old parameter names, date
+// formats, and ID formats) into the current contract before handlers bind
+// them, so the API can evolve while old app versions linger. It counts how
+// often each rule fires, which tells when a rule can be removed:
+//
+//	legacy := middleware.NewLegacyRewriter(middleware.LegacyConfig{
+//	    Rules: []middleware.LegacyRule{{
+//	        Name:        "galleries.since",
+//	        Route:       "/api/galleries",
+//	        Query:       map[string]string{"page_size": "limit", "since": "updated_since"},
+//	        DateFields:  []string{"updated_since"},
+//	        DateLayouts: []string{"2006-01-02", middleware.LegacyUnixSeconds},
+//	    }},
+//	})
+//	router.Use(legacy.Middleware())
+//
+//	// later, e.g. from an admin endpoint
+//	legacy.Usage() // map[galleries.since:1234]
+type LegacyRewriter struct {
+	cfg LegacyConfig
+
+	mu    sync.Mutex
+	usage map[string]int64
+}
+
+// NewLegacyRewriter returns a LegacyRewriter for cfg. It panics if a rule
+// has no Name, or has IDFields without ConvertID.
+func NewLegacyRewriter(cfg LegacyConfig) *LegacyRewriter {
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 1 << 20
+	}
+	for _, rule := range cfg.Rules {
+		if rule.Name == "" {
+			panic("middleware: LegacyRule.Name is required")
+		}
+		if len(rule.IDFields) > 0 && rule.ConvertID == nil {
+			panic("middleware: LegacyRule " + strconv.Quote(rule.Name) + " has IDFields but no ConvertID")
+		}
+	}
+	return &LegacyRewriter{cfg: cfg, usage: map[string]int64{}}
+}
+
+// Usage returns how many requests each rule has rewritten since startup.
+// Rules that never fired are included with a count of 0.
+func (lr *LegacyRewriter) Usage() map[string]int64 {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	out := make(map[string]int64, len(lr.cfg.Rules))
+	for _, rule := range lr.cfg.Rules {
+		out[rule.Name] = lr.usage[rule.Name]
+	}
+	return out
+}
+
+// Middleware returns the gin middleware. Register it with router.Use so
+// rules can match on the route, and before middleware that reads the query
+// through gin (c.Query caches it).
+func (lr *LegacyRewriter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body map[string]any
+		bodyRead, bodyChanged, queryChanged := false, false, false
+		query := c.Request.URL.Query()
+
+		for _, rule := range lr.cfg.Rules {
+			if (rule.Method != "" && rule.Method != c.Request.Method) || (rule.Route != "" && rule.Route != c.FullPath()) {
+				continue
+			}
+			if !bodyRead && (len(rule.Fields) > 0 || len(rule.DateFields) > 0 || len(rule.IDFields) > 0) {
+				body = lr.readBody(c)
+				bodyRead = true
+			}
+
+			changed := rewriteQuery(rule, query)
+			queryChanged = queryChanged || changed
+			if body != nil && rewriteBody(rule, body) {
+				changed, bodyChanged = true, true
+			}
+			if rewriteParams(rule, c.Params) {
+				changed = true
+			}
+			if changed {
+				lr.count(c, rule.Name)
+			}
+		}
+
+		if queryChanged {
+			c.Request.URL.RawQuery = query.Encode()
+		}
+		if bodyChanged {
+			if b, err := json.Marshal(body); err == nil {
+				c.Request.Body = io.NopCloser(bytes.NewReader(b))
+				c.Request.ContentLength = int64(len(b))
+				c.Request.Header.Set("Content-Length", strconv.Itoa(len(b)))
+			}
+		}
+		c.Next()
+	}
+}
+
+// count records that rule rewrote the request.
+func (lr *LegacyRewriter) count(c *gin.Context, rule string) {
+	lr.mu.Lock()
+	lr.usage[rule]++
+	lr.mu.Unlock()
+	if lr.cfg.OnRewrite != nil {
+		lr.cfg.OnRewrite(c, rule)
+	}
+}
+
+// readBody decodes a JSON object body for rewriting. Other bodies, and
+// bodies over MaxBodyBytes, are put back untouched and nil is returned.
+func (lr *LegacyRewriter) readBody(c *gin.Context) map[string]any {
+	r := c.Request
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return nil
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(r.Body, int64(lr.cfg.MaxBodyBytes)+1))
+	if err != nil || len(buf) > lr.cfg.MaxBodyBytes {
+		r.Body = readCloser{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+		return nil
+	}
+	r.Body = io.NopCloser(bytes.NewReader(buf))
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	var body map[string]any
+	if dec.Decode(&body) != nil || body == nil {
+		return nil // let the handler report malformed bodies
+	}
+	return body
+}
+
+// readCloser pairs a reader with the original body's Close.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// rewriteQuery applies rule to query parameters, reporting any change.
+func rewriteQuery(rule LegacyRule, query url.Values) bool {
+	changed := false
+	for _, old := range sortedKeys(rule.Query) {
+		vals, ok := query[old]
+		if !ok {
+			continue
+		}
+		delete(query, old)
+		if _, current := query[rule.Query[old]]; !current {
+			query[rule.Query[old]] = vals // the current name wins when both are sent
+		}
+		changed = true
+	}
+	for _, name := range rule.DateFields {
+		for i, v := range query[name] {
+			if s, ok := convertDate(rule.DateLayouts, v); ok {
+				query[name][i] = s
+				changed = true
+			}
+		}
+	}
+	for _, name := range rule.IDFields {
+		for i, v := range query[name] {
+			if s, ok := rule.ConvertID(v); ok {
+				query[name][i] = s
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// rewriteBody applies rule to top-level JSON fields, reporting any change.
+func rewriteBody(rule LegacyRule, body map[string]any) bool {
+	changed := false
+	for _, old := range sortedKeys(rule.Fields) {
+		v, ok := body[old]
+		if !ok {
+			continue
+		}
+		delete(body, old)
+		if _, current := body[rule.Fields[old]]; !current {
+			body[rule.Fields[old]] = v
+		}
+		changed = true
+	}
+	for _, name := range rule.DateFields {
+		var raw string
+		switch v := body[name].(type) {
+		case string:
+			raw = v
+		case json.Number:
+			raw = v.String()
+		default:
+			continue
+		}
+		if s, ok := convertDate(rule.DateLayouts, raw); ok {
+			body[name] = s
+			changed = true
+		}
+	}
+	for _, name := range rule.IDFields {
+		var raw string
+		switch v := body[name].(type) {
+		case string:
+			raw = v
+		case json.Number:
+			raw = v.String() // legacy numeric IDs
+		default:
+			continue
+		}
+		if s, ok := rule.ConvertID(raw); ok {
+			body[name] = s
+			changed = true
+		}
+	}
+	return changed
+}
+
+// rewriteParams applies rule's ID conversion to path parameters.
+func rewriteParams(rule LegacyRule, params gin.Params) bool {
+	changed := false
+	for _, name := range rule.IDFields {
+		for i := range params {
+			if params[i].Key != name {
+				continue
+			}
+			if s, ok := rule.ConvertID(params[i].Value); ok {
+				params[i].Value = s
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// convertDate parses v with the first matching layout and formats it as
+// RFC 3339. Values already in RFC 3339 are left alone.
+func convertDate(layouts []string, v string) (string, bool) {
+	if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return "", false
+	}
+	for _, layout := range layouts {
+		if layout == LegacyUnixSeconds {
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return time.Unix(n, 0).UTC().Format(time.RFC3339), true
+			}
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, v, time.UTC); err == nil {
+			return t.UTC().Format(time.RFC3339Nano), true
+		}
+	}
+	return "", false
+}
+
+// sortedKeys returns the keys of m in order, so renames are deterministic.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
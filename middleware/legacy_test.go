@@ -0,0 +1,180 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestLegacyRewriter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	convertID := func(id string) (string, bool) {
+		if strings.HasPrefix(id, "gal_") {
+			return "", false
+		}
+		return "gal_" + id, true
+	}
+	rules := []middleware.LegacyRule{
+		{
+			Name:        "galleries.query",
+			Route:       "/galleries",
+			Query:       map[string]string{"page_size": "limit", "since": "updated_since"},
+			DateFields:  []string{"updated_since"},
+			DateLayouts: []string{"2006-01-02", middleware.LegacyUnixSeconds},
+		},
+		{
+			Name:        "galleries.body",
+			Method:      http.MethodPost,
+			Fields:      map[string]string{"name": "title"},
+			DateFields:  []string{"published_at"},
+			DateLayouts: []string{"2006-01-02 15:04:05"},
+		},
+		{
+			Name:      "galleries.ids",
+			Route:     "/galleries/:id",
+			IDFields:  []string{"id"},
+			ConvertID: convertID,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		body      string
+		wantQuery string
+		wantParam string
+		wantBody  string
+		wantRules []string
+	}{
+		{
+			name:      "renames and converts query",
+			method:    http.MethodGet,
+			target:    "/galleries?page_size=5&since=2024-01-02",
+			wantQuery: "limit=5&updated_since=2024-01-02T00%3A00%3A00Z",
+			wantRules: []string{"galleries.query"},
+		},
+		{
+			name:      "unix timestamps",
+			method:    http.MethodGet,
+			target:    "/galleries?since=1700000000",
+			wantQuery: "updated_since=2023-11-14T22%3A13%3A20Z",
+			wantRules: []string{"galleries.query"},
+		},
+		{
+			name:      "current name wins",
+			method:    http.MethodGet,
+			target:    "/galleries?page_size=5&limit=10",
+			wantQuery: "limit=10",
+			wantRules: []string{"galleries.query"},
+		},
+		{
+			name:      "current requests are untouched",
+			method:    http.MethodGet,
+			target:    "/galleries?limit=5&updated_since=2024-01-02T00:00:00Z",
+			wantQuery: "limit=5&updated_since=2024-01-02T00:00:00Z",
+		},
+		{
+			name:      "rewrites body fields",
+			method:    http.MethodPost,
+			target:    "/galleries",
+			body:      `{"name":"Summer","published_at":"2024-01-02 03:04:05","views":12}`,
+			wantBody:  `{"published_at":"2024-01-02T03:04:05Z","title":"Summer","views":12}`,
+			wantRules: []string{"galleries.body"},
+		},
+		{
+			name:     "leaves current bodies byte for byte",
+			method:   http.MethodPost,
+			target:   "/galleries",
+			body:     `{"views": 12, "title": "Summer"}`,
+			wantBody: `{"views": 12, "title": "Summer"}`,
+		},
+		{
+			name:      "converts path IDs",
+			method:    http.MethodGet,
+			target:    "/galleries/42",
+			wantParam: "gal_42",
+			wantRules: []string{"galleries.ids"},
+		},
+		{
+			name:      "current path IDs",
+			method:    http.MethodGet,
+			target:    "/galleries/gal_42",
+			wantParam: "gal_42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fired []string
+			legacy := middleware.NewLegacyRewriter(middleware.LegacyConfig{
+				Rules:     rules,
+				OnRewrite: func(c *gin.Context, rule string) { fired = append(fired, rule) },
+			})
+
+			var gotQuery, gotParam, gotBody string
+			handler := func(c *gin.Context) {
+				gotQuery = c.Request.URL.RawQuery
+				gotParam = c.Param("id")
+				b, _ := io.ReadAll(c.Request.Body)
+				gotBody = string(b)
+			}
+			router := gin.New()
+			router.Use(legacy.Middleware())
+			router.GET("/galleries", handler)
+			router.POST("/galleries", handler)
+			router.GET("/galleries/:id", handler)
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if gotQuery != tt.wantQuery {
+				t.Errorf("expected query %q, got %q", tt.wantQuery, gotQuery)
+			}
+			if gotParam != tt.wantParam {
+				t.Errorf("expected id %q, got %q", tt.wantParam, gotParam)
+			}
+			if gotBody != tt.wantBody {
+				t.Errorf("expected body %s, got %s", tt.wantBody, gotBody)
+			}
+			if strings.Join(fired, ",") != strings.Join(tt.wantRules, ",") {
+				t.Errorf("expected rules %v, got %v", tt.wantRules, fired)
+			}
+			for _, rule := range tt.wantRules {
+				if legacy.Usage()[rule] != 1 {
+					t.Errorf("expected usage 1 for %s, got %v", rule, legacy.Usage())
+				}
+			}
+		})
+	}
+}
+
+func TestNewLegacyRewriterPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		rule middleware.LegacyRule
+	}{
+		{"missing name", middleware.LegacyRule{Query: map[string]string{"a": "b"}}},
+		{"ids without converter", middleware.LegacyRule{Name: "ids", IDFields: []string{"id"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			middleware.NewLegacyRewriter(middleware.LegacyConfig{Rules: []middleware.LegacyRule{tt.rule}})
+		})
+	}
+}
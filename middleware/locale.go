@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// LocaleConfig configures LocaleBootstrap.
+type LocaleConfig struct {
+	// Supported languages, in the order the SPA should list them
+	// (e.g., []string{"en", "ja", "ko", "zh"})
+	Supported []string
+	// Timezone returns the request's timezone, for apps that resolve one
+	// (omitted from the response when nil or empty)
+	Timezone func(c *gin.Context) string
+}
+
+// Locale is the object returned by LocaleBootstrap.
+type Locale struct {
+	Object    string           `json:"object"` // Always "locale"
+	Language  string           `json:"language"`
+	Languages []LocaleLanguage `json:"languages"`
+	Currency  string           `json:"currency,omitempty"`
+	Timezone  string           `json:"timezone,omitempty"`
+}
+
+// LocaleLanguage describes one supported language.
+type LocaleLanguage struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"` // In the language itself, e.g. "日本語"
+	Direction string `json:"dir"`  // "ltr" or "rtl"
+}
+
+// rtlLanguages are the base languages written right to left.
+var rtlLanguages = map[string]bool{
+	"ar": true, "arc": true, "ckb": true, "dv": true, "fa": true, "he": true,
+	"ku": true, "ps": true, "sd": true, "syr": true, "ug": true, "ur": true, "yi": true,
+}
+
+// LocaleBootstrap returns a handler describing the request's locale, so an
+// SPA can bootstrap from the server's negotiation instead of repeating it
+// client-side, e.g. GET /api/v1/locale:
+//
+//	api.GET("/locale", middleware.LocaleBootstrap(middleware.LocaleConfig{
+//	    Supported: []string{"en", "ja", "ar"},
+//	}))
+//
+// It responds with the language the Language middleware resolved, the
+// supported languages with their native names and text direction, and the
+// currency when the Currency middleware ran:
+//
+//	{"object":"locale","language":"ja","languages":[{"code":"ja","name":"日本語","dir":"ltr"},...],"currency":"JPY"}
+func LocaleBootstrap(cfg LocaleConfig) gin.HandlerFunc {
+	languages := make([]LocaleLanguage, 0, len(cfg.Supported))
+	for _, code := range cfg.Supported {
+		languages = append(languages, describeLanguage(code))
+	}
+
+	return func(c *gin.Context) {
+		locale := Locale{
+			Object:    "locale",
+			Language:  GetLanguage(c),
+			Languages: languages,
+		}
+		if v, ok := c.Get(currencyKey); ok {
+			locale.Currency, _ = v.(string)
+		}
+		if cfg.Timezone != nil {
+			locale.Timezone = cfg.Timezone(c)
+		}
+		response.Object(c, locale)
+	}
+}
+
+// describeLanguage returns the native name and direction of a language
+// code, falling back to the code as its name.
+func describeLanguage(code string) LocaleLanguage {
+	l := LocaleLanguage{Code: code, Name: code, Direction: "ltr"}
+	tag, err := language.Parse(code)
+	if err != nil {
+		return l
+	}
+	if name := display.Self.Name(tag); name != "" {
+		l.Name = name
+	}
+	base, _ := tag.Base()
+	if rtlLanguages[base.String()] {
+		l.Direction = "rtl"
+	}
+	return l
+}
@@ -0,0 +1,79 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestLocaleBootstrap(t *testing.T) {
+	supported := []string{"en", "ja", "ar"}
+	withCurrency := gin.New()
+	withCurrency.Use(middleware.Language(middleware.LanguageConfig{Supported: supported}))
+	withCurrency.Use(middleware.Currency(middleware.CurrencyConfig{Supported: []string{"USD", "JPY"}}))
+	withCurrency.GET("/locale", middleware.LocaleBootstrap(middleware.LocaleConfig{
+		Supported: supported,
+		Timezone:  func(c *gin.Context) string { return "Asia/Tokyo" },
+	}))
+
+	languageOnly := gin.New()
+	languageOnly.Use(middleware.Language(middleware.LanguageConfig{Supported: supported}))
+	languageOnly.GET("/locale", middleware.LocaleBootstrap(middleware.LocaleConfig{Supported: supported}))
+
+	tests := []struct {
+		name         string
+		router       *gin.Engine
+		acceptLang   string
+		wantLanguage string
+		wantCurrency string
+		wantTimezone string
+	}{
+		{"all installed", withCurrency, "ja-JP", "ja", "JPY", "Asia/Tokyo"},
+		{"language only", languageOnly, "ar", "ar", "", ""},
+		{"default language", languageOnly, "fr", "en", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/locale", nil)
+			req.Header.Set("Accept-Language", tt.acceptLang)
+			w := httptest.NewRecorder()
+			tt.router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			var got middleware.Locale
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("expected JSON, got %s", w.Body.String())
+			}
+			if got.Object != "locale" || got.Language != tt.wantLanguage {
+				t.Errorf("expected locale in %s, got %+v", tt.wantLanguage, got)
+			}
+			if got.Currency != tt.wantCurrency {
+				t.Errorf("expected currency %q, got %q", tt.wantCurrency, got.Currency)
+			}
+			if got.Timezone != tt.wantTimezone {
+				t.Errorf("expected timezone %q, got %q", tt.wantTimezone, got.Timezone)
+			}
+			want := []middleware.LocaleLanguage{
+				{Code: "en", Name: "English", Direction: "ltr"},
+				{Code: "ja", Name: "日本語", Direction: "ltr"},
+				{Code: "ar", Name: "العربية", Direction: "rtl"},
+			}
+			if len(got.Languages) != len(want) {
+				t.Fatalf("expected %d languages, got %+v", len(want), got.Languages)
+			}
+			for i, l := range want {
+				if got.Languages[i] != l {
+					t.Errorf("expected %+v, got %+v", l, got.Languages[i])
+				}
+			}
+		})
+	}
+}
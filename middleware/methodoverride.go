@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMethodOverrideHeader is the header carrying the intended method.
+const DefaultMethodOverrideHeader = "X-HTTP-Method-Override"
+
+// MethodOverrideConfig configures the method override middleware.
+type MethodOverrideConfig struct {
+	// Header carrying the intended method (defaults to "X-HTTP-Method-Override")
+	Header string
+	// FormField, if set (e.g. "_method"), is also checked on form POSTs
+	FormField string
+	// Allowed target methods (defaults to PUT, PATCH, DELETE)
+	Allowed []string
+	// Logger for the override audit log (defaults to slog.Default())
+	Logger *slog.Logger
+}
+
+// MethodOverride returns an http.Handler wrapper that lets clients behind
+// proxies which only allow GET/POST tunnel other methods through a POST.
+// It rewrites the method before the request reaches the router, so the
+// route for the overridden method handles it, with its own middleware
+// chain, and every middleware sees the overridden method:
+//
+//	router := gin.New()
+//	...
+//	http.ListenAndServe(addr, middleware.MethodOverride(middleware.MethodOverrideConfig{})(router))
+//
+// Only POST requests are overridden, and only to methods in cfg.Allowed.
+// Every override is logged with the original and target method.
+func MethodOverride(cfg MethodOverrideConfig) func(http.Handler) http.Handler {
+	header := cfg.Header
+	if header == "" {
+		header = DefaultMethodOverrideHeader
+	}
+
+	allowedMethods := cfg.Allowed
+	if len(allowedMethods) == 0 {
+		allowedMethods = []string{http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	allowed := make(map[string]struct{}, len(allowedMethods))
+	for _, m := range allowedMethods {
+		allowed[strings.ToUpper(m)] = struct{}{}
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			method := strings.ToUpper(strings.TrimSpace(r.Header.Get(header)))
+			if method == "" && cfg.FormField != "" && isFormContentType(r.Header.Get("Content-Type")) {
+				method = strings.ToUpper(strings.TrimSpace(r.PostFormValue(cfg.FormField)))
+			}
+			if method == "" || method == http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if _, ok := allowed[method]; !ok {
+				logger.Warn("method override rejected",
+					"from", http.MethodPost, "to", method,
+					"path", r.URL.Path, "remote_addr", r.RemoteAddr)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			logger.Info("method override",
+				"from", http.MethodPost, "to", method,
+				"path", r.URL.Path, "remote_addr", r.RemoteAddr)
+
+			r = r.Clone(r.Context())
+			r.Method = method
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isFormContentType reports whether the Content-Type header carries form
+// fields.
+func isFormContentType(header string) bool {
+	contentType, _, _ := mime.ParseMediaType(header)
+	return contentType == "application/x-www-form-urlencoded" || contentType == "multipart/form-data"
+}
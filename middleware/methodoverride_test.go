@@ -0,0 +1,95 @@
+package middleware_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func newMethodOverrideRouter(cfg middleware.MethodOverrideConfig) http.Handler {
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	router := gin.New()
+	router.POST("/items/1", func(c *gin.Context) { c.String(http.StatusOK, "POST") })
+	router.PUT("/items/1", func(c *gin.Context) { c.String(http.StatusOK, "PUT") })
+	router.DELETE("/items/1", func(c *gin.Context) { c.String(http.StatusOK, "DELETE") })
+	router.GET("/items/1", func(c *gin.Context) { c.String(http.StatusOK, "GET") })
+	return middleware.MethodOverride(cfg)(router)
+}
+
+func TestMethodOverrideHeader(t *testing.T) {
+	router := newMethodOverrideRouter(middleware.MethodOverrideConfig{})
+
+	tests := []struct {
+		name     string
+		method   string
+		override string
+		want     string
+	}{
+		{"post without override", "POST", "", "POST"},
+		{"post to put", "POST", "PUT", "PUT"},
+		{"lowercase override", "POST", "delete", "DELETE"},
+		{"not allowlisted", "POST", "GET", "POST"},
+		{"only post is overridden", "GET", "DELETE", "GET"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, "/items/1", nil)
+			if tt.override != "" {
+				req.Header.Set(middleware.DefaultMethodOverrideHeader, tt.override)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected '%s', got '%s'", tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestMethodOverrideFormField(t *testing.T) {
+	router := newMethodOverrideRouter(middleware.MethodOverrideConfig{FormField: "_method"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/items/1", strings.NewReader("_method=DELETE"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	router.ServeHTTP(w, req)
+
+	if w.Body.String() != "DELETE" {
+		t.Errorf("expected 'DELETE', got '%s'", w.Body.String())
+	}
+}
+
+func TestMethodOverrideBeforeRouting(t *testing.T) {
+	var seen []string
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		seen = append(seen, c.Request.Method)
+		c.Next()
+	})
+	router.PUT("/items/1", func(c *gin.Context) { c.String(http.StatusOK, "PUT") })
+	handler := middleware.MethodOverride(middleware.MethodOverrideConfig{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/items/1", nil)
+	req.Header.Set(middleware.DefaultMethodOverrideHeader, "PUT")
+	handler.ServeHTTP(w, req)
+
+	if w.Body.String() != "PUT" {
+		t.Errorf("expected 'PUT', got '%s'", w.Body.String())
+	}
+	if len(seen) != 1 || seen[0] != "PUT" {
+		t.Errorf("expected the middleware to run once and see PUT, got %v", seen)
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/metrics"
+)
+
+// Names of the metrics recorded by Metrics.
+const (
+	MetricRequests        = "http.server.requests"
+	MetricRequestDuration = "http.server.request.duration"
+	MetricRequestSize     = "http.server.request.size"
+	MetricResponseSize    = "http.server.response.size"
+)
+
+// Metrics returns middleware recording each request in sink: a
+// MetricRequests count and a MetricRequestDuration timing, both tagged
+// with the method, the route pattern (or "unmatched"), and the status
+// code. Route patterns rather than paths keep the number of series bounded.
+// Use metrics.StatsD to report to a StatsD server or DataDog agent.
+//
+// Sinks that are a metrics.Histogrammer, as StatsD is, also get the body
+// sizes in bytes as MetricRequestSize and MetricResponseSize histograms
+// with the same tags, for per-route size percentiles. NewSizeTracker
+// computes them in process, to flag routes over a size budget.
+func Metrics(sink metrics.Sink) gin.HandlerFunc {
+	if sink == nil {
+		panic("middleware: Metrics requires a Sink")
+	}
+	_, sizes := sink.(metrics.Histogrammer)
+	return func(c *gin.Context) {
+		start := time.Now()
+		var requestSize func() int64
+		if sizes {
+			requestSize = countRequestBody(c)
+		}
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		tags := []metrics.Tag{
+			{Key: "method", Value: c.Request.Method},
+			{Key: "route", Value: route},
+			{Key: "status", Value: strconv.Itoa(status)},
+		}
+		sink.Count(MetricRequests, 1, tags...)
+		sink.Timing(MetricRequestDuration, time.Since(start), tags...)
+		if sizes {
+			metrics.Histogram(sink, MetricRequestSize, float64(requestSize()), tags...)
+			metrics.Histogram(sink, MetricResponseSize, float64(responseSize(c)), tags...)
+		}
+	}
+}
+
+// countRequestBody counts the bytes read from c's request body, and
+// returns a function giving the body's size: its Content-Length, or the
+// bytes read if more (bodies of unknown length).
+func countRequestBody(c *gin.Context) func() int64 {
+	length := max(c.Request.ContentLength, 0)
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return func() int64 { return length }
+	}
+	body := &countingBody{ReadCloser: c.Request.Body}
+	c.Request.Body = body
+	return func() int64 { return max(length, body.n) }
+}
+
+// countingBody counts the bytes read from a request body.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// responseSize returns the bytes of response body written to c.
+func responseSize(c *gin.Context) int64 {
+	return int64(max(c.Writer.Size(), 0))
+}
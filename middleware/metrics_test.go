@@ -0,0 +1,146 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/metrics"
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+// recordingSink records the metrics it receives as "name tags".
+type recordingSink struct {
+	mu      sync.Mutex
+	counts  []string
+	timings []string
+	gauges  map[string]float64 // by "name tags"
+}
+
+func (s *recordingSink) Count(name string, value int64, tags ...metrics.Tag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts = append(s.counts, name+" "+tagString(tags))
+}
+
+func (s *recordingSink) Gauge(name string, value float64, tags ...metrics.Tag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gauges == nil {
+		s.gauges = map[string]float64{}
+	}
+	s.gauges[name+" "+tagString(tags)] = value
+}
+
+func (s *recordingSink) Timing(name string, d time.Duration, tags ...metrics.Tag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timings = append(s.timings, name+" "+tagString(tags))
+}
+
+func tagString(tags []metrics.Tag) string {
+	var s string
+	for _, t := range tags {
+		s += t.Key + ":" + t.Value + " "
+	}
+	return s
+}
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   string
+	}{
+		{"route pattern", http.MethodGet, "/galleries/123", "method:GET route:/galleries/:id status:200 "},
+		{"error status", http.MethodPost, "/galleries", "method:POST route:/galleries status:422 "},
+		{"unmatched", http.MethodGet, "/nope", "method:GET route:unmatched status:404 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recordingSink{}
+			router := gin.New()
+			router.Use(middleware.Metrics(sink))
+			router.GET("/galleries/:id", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+			router.POST("/galleries", func(c *gin.Context) { c.Status(http.StatusUnprocessableEntity) })
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+			if len(sink.counts) != 1 || sink.counts[0] != middleware.MetricRequests+" "+tt.want {
+				t.Errorf("expected a request count tagged %q, got %q", tt.want, sink.counts)
+			}
+			if len(sink.timings) != 1 || sink.timings[0] != middleware.MetricRequestDuration+" "+tt.want {
+				t.Errorf("expected a duration tagged %q, got %q", tt.want, sink.timings)
+			}
+		})
+	}
+}
+
+// histogramSink is a recordingSink recording histograms as "name value".
+type histogramSink struct {
+	recordingSink
+	histograms []string
+}
+
+func (s *histogramSink) Histogram(name string, value float64, tags ...metrics.Tag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.histograms = append(s.histograms, name+" "+strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+func TestMetricsSizes(t *testing.T) {
+	tests := []struct {
+		name string
+		req  func() *http.Request
+		want []string
+	}{
+		{
+			name: "content length",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"title":"x"}`))
+			},
+			want: []string{middleware.MetricRequestSize + " 13", middleware.MetricResponseSize + " 13"},
+		},
+		{
+			name: "unknown length",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("chunked body"))
+				req.ContentLength = -1
+				return req
+			},
+			want: []string{middleware.MetricRequestSize + " 12", middleware.MetricResponseSize + " 12"},
+		},
+		{
+			name: "no body",
+			req:  func() *http.Request { return httptest.NewRequest(http.MethodPost, "/echo", nil) },
+			want: []string{middleware.MetricRequestSize + " 0", middleware.MetricResponseSize + " 0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &histogramSink{}
+			router := gin.New()
+			router.Use(middleware.Metrics(sink))
+			router.POST("/echo", func(c *gin.Context) {
+				body, _ := io.ReadAll(c.Request.Body)
+				c.Data(http.StatusOK, "text/plain", body)
+			})
+
+			router.ServeHTTP(httptest.NewRecorder(), tt.req())
+
+			if strings.Join(sink.histograms, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("expected %q, got %q", tt.want, sink.histograms)
+			}
+		})
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !race
+
+package middleware_test
+
+// raceEnabled is whether the race detector is on. It adds allocations, so
+// allocation budgets are skipped under it.
+const raceEnabled = false
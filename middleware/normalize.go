@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrailingSlashPolicy controls how NormalizePath treats a trailing slash.
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashIgnore leaves trailing slashes as they are.
+	TrailingSlashIgnore TrailingSlashPolicy = iota
+	// TrailingSlashStrip redirects "/galleries/" to "/galleries".
+	TrailingSlashStrip
+	// TrailingSlashAdd redirects "/galleries" to "/galleries/".
+	TrailingSlashAdd
+)
+
+// NormalizePathConfig configures the path normalization middleware.
+type NormalizePathConfig struct {
+	// TrailingSlash policy (defaults to TrailingSlashIgnore)
+	TrailingSlash TrailingSlashPolicy
+	// CollapseSlashes redirects "/ja//galleries" to "/ja/galleries"
+	CollapseSlashes bool
+	// Lowercase redirects "/JA/Galleries" to "/ja/galleries"
+	Lowercase bool
+	// SkipPrefixes are left untouched (e.g. "/api/" where IDs are case-sensitive)
+	SkipPrefixes []string
+	// Language, if set, folds the language prefix into the same redirect so a
+	// request like "/Galleries/" goes straight to "/en/galleries" instead of
+	// bouncing through HandleLanguageRedirect as a second hop.
+	Language *LanguageRedirectConfig
+}
+
+// NormalizePath returns middleware that redirects non-canonical paths with a
+// 301 (308 for methods with a body, so the body is resubmitted).
+// The query string is preserved. A redirect that also adds the language
+// prefix is a 302 (307), as the prefix depends on the visitor's cookie and
+// Accept-Language and must not be cached as permanent.
+//
+// Leading slashes and backslashes are always collapsed into one, whatever
+// the config, so "//evil.com/" never becomes a protocol-relative target.
+//
+// Register it with router.Use so it also runs for NoRoute requests.
+func NormalizePath(cfg NormalizePathConfig) gin.HandlerFunc {
+	var supportedMap map[string]struct{}
+	var defaultLang string
+	if cfg.Language != nil && len(cfg.Language.Supported) > 0 {
+		supportedMap = BuildSupportedMap(cfg.Language.Supported)
+		defaultLang = strings.ToLower(strings.TrimSpace(cfg.Language.Default))
+		if defaultLang == "" {
+			defaultLang = "en"
+		}
+	}
+
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		for _, prefix := range cfg.SkipPrefixes {
+			if strings.HasPrefix(path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		normalized := normalizePath(path, cfg)
+		if normalized == path {
+			c.Next()
+			return
+		}
+
+		// Already redirecting - add the language prefix now rather than in a second hop
+		permanent := true
+		if supportedMap != nil {
+			lang := extractLanguageFromPath(normalized)
+			if _, ok := supportedMap[lang]; !ok {
+				normalized = "/" + DetectPreferredLanguage(c, supportedMap, defaultLang) + normalized
+				permanent = false
+			}
+		}
+
+		target := normalized
+		if c.Request.URL.RawQuery != "" {
+			target += "?" + c.Request.URL.RawQuery
+		}
+
+		bodyless := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+		var status int
+		switch {
+		case permanent && bodyless:
+			status = http.StatusMovedPermanently
+		case permanent:
+			status = http.StatusPermanentRedirect
+		case bodyless:
+			status = http.StatusFound
+		default:
+			status = http.StatusTemporaryRedirect
+		}
+
+		c.Redirect(status, target)
+		c.Abort()
+	}
+}
+
+// normalizePath applies the configured rules to path.
+func normalizePath(path string, cfg NormalizePathConfig) string {
+	// "//host" and "/\host" are protocol-relative URLs to browsers
+	if rest := strings.TrimLeft(path, "/\\"); len(path)-len(rest) > 1 {
+		path = "/" + rest
+	}
+
+	if cfg.CollapseSlashes {
+		for strings.Contains(path, "//") {
+			path = strings.ReplaceAll(path, "//", "/")
+		}
+	}
+
+	if cfg.Lowercase {
+		path = strings.ToLower(path)
+	}
+
+	if path != "/" {
+		switch cfg.TrailingSlash {
+		case TrailingSlashStrip:
+			path = strings.TrimRight(path, "/")
+			if path == "" {
+				path = "/"
+			}
+		case TrailingSlashAdd:
+			if !strings.HasSuffix(path, "/") {
+				path += "/"
+			}
+		}
+	}
+
+	return path
+}
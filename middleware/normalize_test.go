@@ -0,0 +1,112 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestNormalizePath(t *testing.T) {
+	cfg := middleware.NormalizePathConfig{
+		TrailingSlash:   middleware.TrailingSlashStrip,
+		CollapseSlashes: true,
+		Lowercase:       true,
+		SkipPrefixes:    []string{"/api/"},
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"canonical path passes", "GET", "/ja/galleries", http.StatusOK, ""},
+		{"root passes", "GET", "/", http.StatusOK, ""},
+		{"trailing slash", "GET", "/ja/galleries/", http.StatusMovedPermanently, "/ja/galleries"},
+		{"duplicate slashes", "GET", "/ja//galleries", http.StatusMovedPermanently, "/ja/galleries"},
+		{"uppercase keeps query", "GET", "/JA/Galleries?Sort=New", http.StatusMovedPermanently, "/ja/galleries?Sort=New"},
+		{"post uses 308", "POST", "/ja/galleries/", http.StatusPermanentRedirect, "/ja/galleries"},
+		{"skip prefix", "GET", "/api/Galleries/", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(middleware.NormalizePath(cfg))
+			router.NoRoute(func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.target, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("expected Location '%s', got '%s'", tt.wantLocation, got)
+			}
+		})
+	}
+}
+
+func TestNormalizePathFoldsLanguageRedirect(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.NormalizePath(middleware.NormalizePathConfig{
+		TrailingSlash: middleware.TrailingSlashStrip,
+		Language: &middleware.LanguageRedirectConfig{
+			Supported: []string{"en", "ja"},
+			Default:   "en",
+		},
+	}))
+	router.NoRoute(func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/galleries/", nil)
+	req.Header.Set("Accept-Language", "ja")
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Location"); got != "/ja/galleries" {
+		t.Errorf("expected single redirect to '/ja/galleries', got '%s'", got)
+	}
+	if w.Code != http.StatusFound {
+		t.Errorf("expected language-dependent redirect to be a %d, got %d", http.StatusFound, w.Code)
+	}
+	if got := w.Header().Get("Vary"); got != "Cookie, Accept-Language" {
+		t.Errorf("expected language-dependent redirect to set Vary, got '%s'", got)
+	}
+}
+
+func TestNormalizePathCollapsesLeadingSlashes(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.NormalizePath(middleware.NormalizePathConfig{
+		TrailingSlash: middleware.TrailingSlashStrip,
+	}))
+	router.NoRoute(func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		target       string
+		wantLocation string
+	}{
+		{"//evil.com/", "/evil.com"},
+		{"/\\evil.com/", "/evil.com"},
+		{"///evil.com", "/evil.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/", nil)
+			req.URL.Path = tt.target
+			router.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("expected Location '%s', got '%s'", tt.wantLocation, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// PrincipalConcurrencyConfig configures PrincipalConcurrencyLimit.
+type PrincipalConcurrencyConfig struct {
+	// MaxInFlight is the number of requests a principal may have in flight
+	// at once (required)
+	MaxInFlight int
+	// Limit overrides MaxInFlight per request, e.g. by tier; 0 keeps
+	// MaxInFlight. Optional.
+	Limit func(c *gin.Context) int
+	// Key identifies the principal (defaults to the authenticated
+	// principal's type and ID, or the client IP for anonymous requests)
+	Key func(c *gin.Context) string
+	// RetryAfter is sent with 429 responses (defaults to 1s)
+	RetryAfter time.Duration
+}
+
+// PrincipalConcurrencyLimit returns middleware capping the requests each
+// principal has in flight at once, so one tenant running parallel
+// crawlers can't take every worker. Unlike RateLimit it counts concurrent
+// requests, not requests per window, and unlike ConcurrencyLimit it is
+// per principal and never queues: requests over the cap get a 429
+// concurrency_limit_exceeded with a Retry-After header right away.
+// Install it after authentication:
+//
+//	router.Use(auth.Authenticate(jwtAuth, apiKeyAuth))
+//	router.Use(middleware.PrincipalConcurrencyLimit(middleware.PrincipalConcurrencyConfig{
+//	    MaxInFlight: 8,
+//	    Limit: func(c *gin.Context) int {
+//	        if p, _ := auth.GetPrincipal(c); p.Tier == "enterprise" {
+//	            return 32
+//	        }
+//	        return 0
+//	    },
+//	}))
+//
+// PriorityCritical requests bypass the limit. It panics if MaxInFlight
+// isn't positive.
+func PrincipalConcurrencyLimit(cfg PrincipalConcurrencyConfig) gin.HandlerFunc {
+	if cfg.MaxInFlight <= 0 {
+		panic("middleware: PrincipalConcurrencyLimit requires MaxInFlight > 0")
+	}
+	if cfg.Key == nil {
+		cfg.Key = principalKey
+	}
+	if cfg.RetryAfter == 0 {
+		cfg.RetryAfter = time.Second
+	}
+	retryAfter := max(int(cfg.RetryAfter/time.Second), 1)
+
+	var (
+		mu       sync.Mutex
+		inFlight = map[string]int{}
+	)
+	release := func(key string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if inFlight[key]--; inFlight[key] <= 0 {
+			delete(inFlight, key)
+		}
+	}
+
+	return func(c *gin.Context) {
+		if GetPriority(c) >= PriorityCritical {
+			c.Next()
+			return
+		}
+		limit := cfg.MaxInFlight
+		if cfg.Limit != nil {
+			if n := cfg.Limit(c); n > 0 {
+				limit = n
+			}
+		}
+
+		key := cfg.Key(c)
+		mu.Lock()
+		if inFlight[key] >= limit {
+			mu.Unlock()
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			response.ErrorWithInfo(c, http.StatusTooManyRequests, response.ErrorInfo{
+				Type:    response.ErrorTypeRateLimit,
+				Code:    response.ErrorCodeConcurrencyLimitExceeded,
+				Message: "too many concurrent requests, wait for one to finish",
+				Details: map[string]any{
+					"limit":       limit,
+					"retry_after": retryAfter,
+				},
+			})
+			c.Abort()
+			return
+		}
+		inFlight[key]++
+		mu.Unlock()
+		defer release(key)
+
+		c.Next()
+	}
+}
+
+// principalKey is the default PrincipalConcurrencyConfig.Key.
+func principalKey(c *gin.Context) string {
+	if p, ok := auth.GetPrincipal(c); ok && p.ID != "" {
+		return p.Type + ":" + p.ID
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,116 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestPrincipalConcurrencyLimit(t *testing.T) {
+	release := make(chan struct{})
+	router := gin.New()
+	router.Use(middleware.PriorityClassifier(middleware.PriorityConfig{Header: "X-Priority"}))
+	router.Use(func(c *gin.Context) {
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			tier := "free"
+			if key == "key_big" {
+				tier = "enterprise"
+			}
+			auth.SetPrincipal(c, auth.Principal{ID: key, Type: auth.TypeAPIKey, Tier: tier})
+		}
+	})
+	router.Use(middleware.PrincipalConcurrencyLimit(middleware.PrincipalConcurrencyConfig{
+		MaxInFlight: 1,
+		Limit: func(c *gin.Context) int {
+			if p, _ := auth.GetPrincipal(c); p.Tier == "enterprise" {
+				return 2
+			}
+			return 0
+		},
+	}))
+	router.GET("/work", func(c *gin.Context) {
+		<-release
+		c.Status(http.StatusOK)
+	})
+
+	send := func(apiKey, priority string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/work", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		req.Header.Set("X-Priority", priority)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Hold key_1's slot and both of key_big's
+	var wg sync.WaitGroup
+	held := []*httptest.ResponseRecorder{}
+	var mu sync.Mutex
+	for _, key := range []string{"key_1", "key_big", "key_big"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := send(key, "normal")
+			mu.Lock()
+			held = append(held, w)
+			mu.Unlock()
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	tests := []struct {
+		name       string
+		apiKey     string
+		priority   string
+		wantStatus int
+	}{
+		{"over the limit", "key_1", "normal", http.StatusTooManyRequests},
+		{"over the tier limit", "key_big", "normal", http.StatusTooManyRequests},
+		{"critical bypasses", "key_1", "critical", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantStatus == http.StatusOK {
+				// Let the held requests and this one finish
+				close(release)
+			}
+			w := send(tt.apiKey, tt.priority)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if w.Code != http.StatusTooManyRequests {
+				return
+			}
+			if w.Header().Get("Retry-After") != "1" {
+				t.Errorf("expected Retry-After 1, got %q", w.Header().Get("Retry-After"))
+			}
+			var result response.Error
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Error.Code != response.ErrorCodeConcurrencyLimitExceeded {
+				t.Errorf("expected concurrency_limit_exceeded, got %s", result.Error.Code)
+			}
+		})
+	}
+	wg.Wait()
+
+	for _, w := range held {
+		if w.Code != http.StatusOK {
+			t.Errorf("expected the held requests to succeed, got %d", w.Code)
+		}
+	}
+	// The slots were released
+	if w := send("key_1", "normal"); w.Code != http.StatusOK {
+		t.Errorf("expected a freed slot, got %d", w.Code)
+	}
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Priority ranks a request for admission when the server is saturated.
+// Higher values are admitted first.
+type Priority int
+
+const (
+	// PriorityLow is for traffic that can be shed first, e.g. anonymous scraping.
+	PriorityLow Priority = iota
+	// PriorityNormal is the default for unclassified requests.
+	PriorityNormal
+	// PriorityHigh is for traffic that should survive overload, e.g. paid tiers.
+	PriorityHigh
+	// PriorityCritical bypasses ConcurrencyLimit entirely, e.g. health checks.
+	PriorityCritical
+)
+
+// String returns the priority name ("low", "normal", "high", "critical").
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	case PriorityCritical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// ParsePriority parses a priority name, case-insensitively.
+// The second return value is false for unknown names.
+func ParsePriority(s string) (Priority, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low":
+		return PriorityLow, true
+	case "normal":
+		return PriorityNormal, true
+	case "high":
+		return PriorityHigh, true
+	case "critical":
+		return PriorityCritical, true
+	}
+	return PriorityNormal, false
+}
+
+// PriorityConfig configures request classification.
+type PriorityConfig struct {
+	// Routes assigns priorities by route pattern (c.FullPath()), e.g.
+	// {"/healthz": PriorityCritical}. Checked first.
+	Routes map[string]Priority
+	// Classify assigns a priority from the request, e.g. by principal tier.
+	// Return false to fall through to Header and Default.
+	Classify func(c *gin.Context) (Priority, bool)
+	// Header carrying a priority name from trusted internal callers.
+	// Empty disables it; only set it behind a proxy that strips the header
+	// from external traffic.
+	Header string
+	// Default priority for unclassified requests (defaults to PriorityNormal)
+	Default *Priority
+}
+
+// PriorityClassifier returns middleware that classifies each request by
+// route, then Classify, then Header, falling back to Default. The result
+// is read by ConcurrencyLimit, so install it first:
+//
+//	router.Use(middleware.PriorityClassifier(middleware.PriorityConfig{
+//	    Routes: map[string]middleware.Priority{"/healthz": middleware.PriorityCritical},
+//	    Classify: func(c *gin.Context) (middleware.Priority, bool) {
+//	        if user, ok := auth.User(c); ok && user.Paid {
+//	            return middleware.PriorityHigh, true
+//	        }
+//	        return middleware.PriorityLow, !auth.IsAuthenticated(c)
+//	    },
+//	}))
+//	router.Use(middleware.ConcurrencyLimit(middleware.ConcurrencyLimitConfig{MaxInFlight: 256}))
+//
+// The result is stored in gin context and retrieved via GetPriority(c),
+// or via PriorityFromContext(ctx) in layers without gin.
+func PriorityClassifier(cfg PriorityConfig) gin.HandlerFunc {
+	def := PriorityNormal
+	if cfg.Default != nil {
+		def = *cfg.Default
+	}
+
+	return func(c *gin.Context) {
+		p := classify(c, cfg, def)
+		c.Set(priorityKey, p)
+		c.Request = c.Request.WithContext(WithPriority(c.Request.Context(), p))
+		c.Next()
+	}
+}
+
+// classify applies the classification order documented on PriorityClassifier.
+func classify(c *gin.Context, cfg PriorityConfig, def Priority) Priority {
+	if p, ok := cfg.Routes[c.FullPath()]; ok {
+		return p
+	}
+	if cfg.Classify != nil {
+		if p, ok := cfg.Classify(c); ok {
+			return p
+		}
+	}
+	if cfg.Header != "" {
+		if p, ok := ParsePriority(c.GetHeader(cfg.Header)); ok {
+			return p
+		}
+	}
+	return def
+}
+
+// priorityKey is the gin context key for the request priority.
+const priorityKey = "ginapi.priority"
+
+// GetPriority returns the priority assigned by PriorityClassifier, falling
+// back to WithPriority on the request context, then PriorityNormal.
+func GetPriority(c *gin.Context) Priority {
+	if c == nil {
+		misuse("GetPriority", "with a nil context")
+		return PriorityNormal
+	}
+	if v, ok := c.Get(priorityKey); ok {
+		if p, ok := v.(Priority); ok {
+			return p
+		}
+	}
+	if c.Request != nil {
+		if p, ok := PriorityFromContext(c.Request.Context()); ok {
+			return p
+		}
+	}
+	return PriorityNormal
+}
+
+// priorityContextKey is the request context key for the request priority.
+type priorityContextKey struct{}
+
+// WithPriority returns a copy of ctx with the given priority.
+// This is the net/http equivalent of PriorityClassifier.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, p)
+}
+
+// PriorityFromContext returns the priority stored by WithPriority.
+// The second return value is false if none is present.
+func PriorityFromContext(ctx context.Context) (Priority, bool) {
+	if ctx == nil {
+		misuse("PriorityFromContext", "with a nil context")
+		return PriorityNormal, false
+	}
+	p, ok := ctx.Value(priorityContextKey{}).(Priority)
+	return p, ok
+}
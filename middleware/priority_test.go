@@ -0,0 +1,73 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestPriorityClassifier(t *testing.T) {
+	low := middleware.PriorityLow
+	router := gin.New()
+	router.Use(middleware.PriorityClassifier(middleware.PriorityConfig{
+		Routes: map[string]middleware.Priority{"/healthz": middleware.PriorityCritical},
+		Classify: func(c *gin.Context) (middleware.Priority, bool) {
+			if c.GetHeader("Authorization") == "Bearer paid" {
+				return middleware.PriorityHigh, true
+			}
+			return 0, false
+		},
+		Header:  "X-Priority",
+		Default: &low,
+	}))
+	handler := func(c *gin.Context) {
+		p, _ := middleware.PriorityFromContext(c.Request.Context())
+		if p != middleware.GetPriority(c) {
+			t.Errorf("expected context priority %s, got %s", middleware.GetPriority(c), p)
+		}
+		c.String(http.StatusOK, middleware.GetPriority(c).String())
+	}
+	router.GET("/healthz", handler)
+	router.GET("/galleries", handler)
+
+	tests := []struct {
+		name   string
+		path   string
+		header map[string]string
+		want   string
+	}{
+		{"route", "/healthz", map[string]string{"Authorization": "Bearer paid"}, "critical"},
+		{"classify", "/galleries", map[string]string{"Authorization": "Bearer paid", "X-Priority": "low"}, "high"},
+		{"header", "/galleries", map[string]string{"X-Priority": "Normal"}, "normal"},
+		{"unknown header", "/galleries", map[string]string{"X-Priority": "urgent"}, "low"},
+		{"default", "/galleries", nil, "low"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetPriorityDefault(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+
+	if got := middleware.GetPriority(c); got != middleware.PriorityNormal {
+		t.Errorf("expected normal, got %s", got)
+	}
+}
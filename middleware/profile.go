@@ -0,0 +1,254 @@
+package middleware
+
+import (
+	"math/rand/v2"
+	"runtime/metrics"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/clock"
+	ginmetrics "github.com/doujins-org/ginapi/metrics"
+)
+
+// Names of the gauges published by a Profiler, per request of a route.
+const (
+	MetricProfileAllocBytes = "http.server.profile.alloc_bytes"
+	MetricProfileAllocs     = "http.server.profile.allocs"
+	MetricProfileCPU        = "http.server.profile.cpu_ms"
+)
+
+// ProfileConfig configures a Profiler.
+type ProfileConfig struct {
+	// Sink receives the top routes every Interval (required)
+	Sink ginmetrics.Sink
+	// SampleRate is the fraction of requests measured (defaults to 0.01)
+	SampleRate float64
+	// Top is the number of routes published by allocated bytes and by CPU
+	// time (defaults to 10)
+	Top int
+	// Interval is how often the top routes are published (defaults to 1m)
+	Interval time.Duration
+	// Clock defaults to clock.System
+	Clock clock.Clock
+}
+
+// RouteProfile is what the sampled requests of a route cost, on average.
+type RouteProfile struct {
+	Object string `json:"object"` // Always "route_profile"
+	Route  string `json:"route"`
+	// Samples is the number of requests measured
+	Samples int64 `json:"samples"`
+	// AllocBytes and Allocs are the heap bytes and objects allocated per
+	// request
+	AllocBytes float64 `json:"alloc_bytes"`
+	Allocs     float64 `json:"allocs"`
+	// CPUMS is the CPU time per request in milliseconds; 0 where the
+	// platform doesn't report process CPU time
+	CPUMS float64 `json:"cpu_ms"`
+}
+
+// NewProfiler returns an opt-in profiler measuring the heap allocations
+// (from runtime/metrics) and CPU time of a sample of requests, and
+// publishing the most expensive routes to a metrics sink, so hot
+// endpoints can be found without attaching pprof:
+//
+//	profiler := middleware.NewProfiler(middleware.ProfileConfig{Sink: statsd})
+//	router.Use(profiler.Middleware())
+//	admin.GET("/profile", func(c *gin.Context) { c.JSON(http.StatusOK, profiler.Top()) })
+//
+// The counters are process-wide, so a request is charged with whatever
+// concurrent requests did while it ran: single samples are noisy, and the
+// averages over many samples are what ranks routes. Every Interval, on the
+// next sampled request, the Top routes by allocated bytes and by CPU time
+// are published as MetricProfile* gauges tagged with the route, and the
+// counts restart. It panics without a Sink or if SampleRate is outside
+// [0, 1].
+func NewProfiler(cfg ProfileConfig) *Profiler {
+	if cfg.Sink == nil {
+		panic("middleware: ProfileConfig requires a Sink")
+	}
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		panic("middleware: profile SampleRate must be between 0 and 1")
+	}
+	if cfg.SampleRate == 0 {
+		cfg.SampleRate = 0.01
+	}
+	if cfg.Top <= 0 {
+		cfg.Top = 10
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	p := &Profiler{cfg: cfg, clock: clock.Or(cfg.Clock), routes: map[string]*routeCost{}}
+	p.published = p.clock.Now()
+	return p
+}
+
+// Profiler samples the cost of requests per route; see NewProfiler.
+type Profiler struct {
+	cfg   ProfileConfig
+	clock clock.Clock
+
+	mu        sync.Mutex
+	routes    map[string]*routeCost
+	published time.Time
+}
+
+// routeCost sums the costs of the sampled requests of a route.
+type routeCost struct {
+	samples    int64
+	allocBytes uint64
+	allocs     uint64
+	cpu        time.Duration
+}
+
+// allocMetrics are the runtime/metrics read around sampled requests.
+var allocMetrics = []string{"/gc/heap/allocs:bytes", "/gc/heap/allocs:objects"}
+
+// costSnapshot is the process's cumulative cost at an instant.
+type costSnapshot struct {
+	allocBytes uint64
+	allocs     uint64
+	cpu        time.Duration
+}
+
+// takeSnapshot reads the process's cost so far.
+func takeSnapshot() costSnapshot {
+	samples := make([]metrics.Sample, len(allocMetrics))
+	for i, name := range allocMetrics {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+	s := costSnapshot{cpu: processCPUTime()}
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		s.allocBytes = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		s.allocs = samples[1].Value.Uint64()
+	}
+	return s
+}
+
+// Middleware returns the middleware measuring sampled requests. Unmatched
+// routes aren't measured.
+func (p *Profiler) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rand.Float64() >= p.cfg.SampleRate {
+			c.Next()
+			return
+		}
+		before := takeSnapshot()
+		c.Next()
+		after := takeSnapshot()
+
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		p.record(route, before, after)
+		p.maybePublish()
+	}
+}
+
+func (p *Profiler) record(route string, before, after costSnapshot) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := p.routes[route]
+	if r == nil {
+		r = &routeCost{}
+		p.routes[route] = r
+	}
+	r.samples++
+	r.allocBytes += after.allocBytes - before.allocBytes
+	r.allocs += after.allocs - before.allocs
+	if after.cpu > before.cpu {
+		r.cpu += after.cpu - before.cpu
+	}
+}
+
+// Top returns the average costs of the routes sampled since the last
+// publication, by allocated bytes, most first.
+func (p *Profiler) Top() []RouteProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.profiles()
+}
+
+// profiles returns the route profiles by allocated bytes; p.mu must be
+// held.
+func (p *Profiler) profiles() []RouteProfile {
+	out := make([]RouteProfile, 0, len(p.routes))
+	for route, r := range p.routes {
+		n := float64(r.samples)
+		out = append(out, RouteProfile{
+			Object:     "route_profile",
+			Route:      route,
+			Samples:    r.samples,
+			AllocBytes: float64(r.allocBytes) / n,
+			Allocs:     float64(r.allocs) / n,
+			CPUMS:      float64(r.cpu) / float64(time.Millisecond) / n,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].AllocBytes != out[j].AllocBytes {
+			return out[i].AllocBytes > out[j].AllocBytes
+		}
+		return out[i].Route < out[j].Route
+	})
+	return out
+}
+
+// Publish sends the Top routes by allocated bytes and by CPU time to the
+// Sink now, and restarts the counts.
+func (p *Profiler) Publish() {
+	p.mu.Lock()
+	profiles := p.reset()
+	p.mu.Unlock()
+	p.publish(profiles)
+}
+
+// maybePublish publishes once an Interval has passed.
+func (p *Profiler) maybePublish() {
+	p.mu.Lock()
+	if p.clock.Now().Sub(p.published) < p.cfg.Interval {
+		p.mu.Unlock()
+		return
+	}
+	profiles := p.reset()
+	p.mu.Unlock()
+	p.publish(profiles)
+}
+
+// reset returns the route profiles and restarts the counts; p.mu must be
+// held.
+func (p *Profiler) reset() []RouteProfile {
+	profiles := p.profiles()
+	p.routes = map[string]*routeCost{}
+	p.published = p.clock.Now()
+	return profiles
+}
+
+// publish sends the top routes of profiles to the Sink.
+func (p *Profiler) publish(profiles []RouteProfile) {
+	top := map[string]bool{}
+	for i := 0; i < len(profiles) && i < p.cfg.Top; i++ {
+		top[profiles[i].Route] = true
+	}
+	byCPU := append([]RouteProfile(nil), profiles...)
+	sort.SliceStable(byCPU, func(i, j int) bool { return byCPU[i].CPUMS > byCPU[j].CPUMS })
+	for i := 0; i < len(byCPU) && i < p.cfg.Top && byCPU[i].CPUMS > 0; i++ {
+		top[byCPU[i].Route] = true
+	}
+	for _, rp := range profiles {
+		if !top[rp.Route] {
+			continue
+		}
+		tag := ginmetrics.Tag{Key: "route", Value: rp.Route}
+		p.cfg.Sink.Gauge(MetricProfileAllocBytes, rp.AllocBytes, tag)
+		p.cfg.Sink.Gauge(MetricProfileAllocs, rp.Allocs, tag)
+		p.cfg.Sink.Gauge(MetricProfileCPU, rp.CPUMS, tag)
+	}
+}
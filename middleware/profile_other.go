@@ -0,0 +1,10 @@
+//go:build !unix
+
+package middleware
+
+import "time"
+
+// processCPUTime returns 0: CPU time isn't measured on this platform.
+func processCPUTime() time.Duration {
+	return 0
+}
@@ -0,0 +1,79 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/apitest"
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+var profileSink []byte
+
+func TestProfiler(t *testing.T) {
+	clk := apitest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sink := &recordingSink{}
+	profiler := middleware.NewProfiler(middleware.ProfileConfig{Sink: sink, SampleRate: 1, Top: 1, Interval: time.Minute, Clock: clk})
+
+	router := gin.New()
+	router.Use(profiler.Middleware())
+	router.GET("/heavy", func(c *gin.Context) {
+		profileSink = make([]byte, 1<<20)
+		c.Status(http.StatusOK)
+	})
+	router.GET("/light", func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func(path string) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	for i := 0; i < 3; i++ {
+		get("/heavy")
+		get("/light")
+	}
+	get("/unmatched")
+
+	top := profiler.Top()
+	if len(top) != 2 {
+		t.Fatalf("expected 2 routes, got %+v", top)
+	}
+	if top[0].Route != "/heavy" || top[0].Samples != 3 || top[0].AllocBytes < 1<<20 || top[0].Allocs < 1 {
+		t.Errorf("expected /heavy to allocate at least 1MiB per request, got %+v", top[0])
+	}
+	if top[1].Route != "/light" || top[1].AllocBytes >= top[0].AllocBytes {
+		t.Errorf("expected /light to allocate less, got %+v", top[1])
+	}
+	if len(sink.gauges) != 0 {
+		t.Errorf("expected nothing published before the interval, got %v", sink.gauges)
+	}
+
+	clk.Advance(time.Minute)
+	get("/light")
+
+	if _, ok := sink.gauges[middleware.MetricProfileAllocBytes+" route:/heavy "]; !ok {
+		t.Errorf("expected /heavy published, got %v", sink.gauges)
+	}
+	if _, ok := sink.gauges[middleware.MetricProfileCPU+" route:/heavy "]; !ok {
+		t.Errorf("expected the CPU time of /heavy published, got %v", sink.gauges)
+	}
+	if len(profiler.Top()) != 0 {
+		t.Errorf("expected the counts to restart, got %+v", profiler.Top())
+	}
+}
+
+func TestProfilerSampleRate(t *testing.T) {
+	profiler := middleware.NewProfiler(middleware.ProfileConfig{Sink: &recordingSink{}, SampleRate: 0.000001})
+	router := gin.New()
+	router.Use(profiler.Middleware())
+	router.GET("/", func(c *gin.Context) {})
+
+	for i := 0; i < 100; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if top := profiler.Top(); len(top) > 1 || (len(top) == 1 && top[0].Samples > 2) {
+		t.Errorf("expected almost no samples, got %+v", top)
+	}
+}
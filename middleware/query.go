@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// RepeatedParams is the policy for query parameters given more than once.
+type RepeatedParams int
+
+const (
+	// RepeatedKeepAll keeps every value, for params bound as lists (the default).
+	RepeatedKeepAll RepeatedParams = iota
+	// RepeatedFirst keeps the first value.
+	RepeatedFirst
+	// RepeatedLast keeps the last value.
+	RepeatedLast
+	// RepeatedReject responds 400 naming the repeated param.
+	RepeatedReject
+)
+
+// NormalizeQueryConfig configures query parameter normalization.
+type NormalizeQueryConfig struct {
+	// Repeated is the policy for repeated params (defaults to RepeatedKeepAll)
+	Repeated RepeatedParams
+	// ListParams are always kept in full, whatever the Repeated policy
+	// (e.g. "include", "id")
+	ListParams []string
+	// Lowercase lists params whose values are lowercased (e.g. "sort", "order")
+	Lowercase []string
+	// Strict rejects params not in Allowed with a 400 naming the param
+	Strict bool
+	// Allowed params in Strict mode
+	Allowed []string
+}
+
+// NormalizeQuery returns middleware that normalizes the query string before
+// handlers bind it: values are trimmed, repeated params are collapsed per
+// Repeated, and Lowercase values are lowercased. In Strict mode, typo'd
+// params get a 400 instead of silently doing nothing:
+//
+//	api.GET("/galleries", middleware.NormalizeQuery(middleware.NormalizeQueryConfig{
+//	    Repeated:  middleware.RepeatedLast,
+//	    Lowercase: []string{"sort"},
+//	    Strict:    true,
+//	    Allowed:   []string{"limit", "offset", "sort", "lang"},
+//	}), listGalleries)
+//
+// The normalized query replaces c.Request.URL.RawQuery, with params sorted
+// by name, so install it before anything reads the query.
+func NormalizeQuery(cfg NormalizeQueryConfig) gin.HandlerFunc {
+	allowed := toSet(cfg.Allowed)
+	lists := toSet(cfg.ListParams)
+	lower := toSet(cfg.Lowercase)
+
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		for key, values := range query {
+			if _, ok := allowed[key]; cfg.Strict && !ok {
+				response.ErrorWithInfo(c, http.StatusBadRequest, response.ErrorInfo{
+					Type:    response.ErrorTypeInvalidRequest,
+					Code:    response.ErrorCodeInvalidParam,
+					Message: "unknown parameter: " + key,
+					Param:   key,
+				})
+				c.Abort()
+				return
+			}
+
+			for i, v := range values {
+				v = strings.TrimSpace(v)
+				if _, ok := lower[key]; ok {
+					v = strings.ToLower(v)
+				}
+				values[i] = v
+			}
+
+			if _, ok := lists[key]; ok || len(values) < 2 {
+				continue
+			}
+			switch cfg.Repeated {
+			case RepeatedFirst:
+				query[key] = values[:1]
+			case RepeatedLast:
+				query[key] = values[len(values)-1:]
+			case RepeatedReject:
+				response.ErrorWithInfo(c, http.StatusBadRequest, response.ErrorInfo{
+					Type:    response.ErrorTypeInvalidRequest,
+					Code:    response.ErrorCodeInvalidParam,
+					Message: "parameter must not be repeated: " + key,
+					Param:   key,
+				})
+				c.Abort()
+				return
+			}
+		}
+		c.Request.URL.RawQuery = query.Encode()
+		c.Next()
+	}
+}
+
+// toSet returns the strings as a set.
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,127 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        middleware.NormalizeQueryConfig
+		query      string
+		wantStatus int
+		wantQuery  string
+		wantParam  string
+	}{
+		{
+			name:       "trims values",
+			query:      "q=%20hello%20&limit=%2010",
+			wantStatus: http.StatusOK,
+			wantQuery:  "limit=10&q=hello",
+		},
+		{
+			name:       "keeps repeated values by default",
+			query:      "tag=a&tag=b",
+			wantStatus: http.StatusOK,
+			wantQuery:  "tag=a&tag=b",
+		},
+		{
+			name:       "keeps first",
+			cfg:        middleware.NormalizeQueryConfig{Repeated: middleware.RepeatedFirst},
+			query:      "sort=a&sort=b",
+			wantStatus: http.StatusOK,
+			wantQuery:  "sort=a",
+		},
+		{
+			name:       "keeps last",
+			cfg:        middleware.NormalizeQueryConfig{Repeated: middleware.RepeatedLast},
+			query:      "sort=a&sort=b",
+			wantStatus: http.StatusOK,
+			wantQuery:  "sort=b",
+		},
+		{
+			name:       "list params are kept in full",
+			cfg:        middleware.NormalizeQueryConfig{Repeated: middleware.RepeatedLast, ListParams: []string{"id"}},
+			query:      "id=1&id=2&sort=a&sort=b",
+			wantStatus: http.StatusOK,
+			wantQuery:  "id=1&id=2&sort=b",
+		},
+		{
+			name:       "rejects repeated",
+			cfg:        middleware.NormalizeQueryConfig{Repeated: middleware.RepeatedReject},
+			query:      "sort=a&sort=b",
+			wantStatus: http.StatusBadRequest,
+			wantParam:  "sort",
+		},
+		{
+			name:       "lowercases configured values",
+			cfg:        middleware.NormalizeQueryConfig{Lowercase: []string{"sort"}},
+			query:      "sort=Name&q=Name",
+			wantStatus: http.StatusOK,
+			wantQuery:  "q=Name&sort=name",
+		},
+		{
+			name:       "strict allows known params",
+			cfg:        middleware.NormalizeQueryConfig{Strict: true, Allowed: []string{"limit"}},
+			query:      "limit=10",
+			wantStatus: http.StatusOK,
+			wantQuery:  "limit=10",
+		},
+		{
+			name:       "strict rejects unknown params",
+			cfg:        middleware.NormalizeQueryConfig{Strict: true, Allowed: []string{"limit"}},
+			query:      "limit=10&limt=5",
+			wantStatus: http.StatusBadRequest,
+			wantParam:  "limt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotQuery string
+			router := gin.New()
+			router.Use(middleware.NormalizeQuery(tt.cfg))
+			router.GET("/test", func(c *gin.Context) {
+				gotQuery = c.Request.URL.RawQuery
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test?"+tt.query, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if gotQuery != tt.wantQuery {
+					t.Errorf("expected query %q, got %q", tt.wantQuery, gotQuery)
+				}
+				return
+			}
+
+			var body struct {
+				Error struct {
+					Code  string `json:"code"`
+					Param string `json:"param"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if body.Error.Code != "invalid_param" {
+				t.Errorf("expected code invalid_param, got %q", body.Error.Code)
+			}
+			if body.Error.Param != tt.wantParam {
+				t.Errorf("expected param %q, got %q", tt.wantParam, body.Error.Param)
+			}
+		})
+	}
+}
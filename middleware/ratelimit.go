@@ -0,0 +1,475 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/clock"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Dimension extracts one component of a rate limit key from the request.
+// Rules combine dimensions, so a limit can apply per IP, per IP and route
+// group, per IP and language section, and so on.
+type Dimension func(c *gin.Context) string
+
+// ByIP keys on the client IP (c.ClientIP, which honors gin's trusted proxies).
+func ByIP() Dimension {
+	return func(c *gin.Context) string { return c.ClientIP() }
+}
+
+// ByRoute keys on the matched route pattern.
+func ByRoute() Dimension {
+	return func(c *gin.Context) string { return c.FullPath() }
+}
+
+// ByRouteGroup keys on the first of prefixes the route pattern starts
+// with, or "" if none does, so e.g. every /api/galleries route shares one
+// budget.
+func ByRouteGroup(prefixes ...string) Dimension {
+	return func(c *gin.Context) string {
+		route := c.FullPath()
+		for _, p := range prefixes {
+			if strings.HasPrefix(route, p) {
+				return p
+			}
+		}
+		return ""
+	}
+}
+
+// ByLanguagePrefix keys on the language prefix of the URL path ("ja" for
+// /ja/galleries), or "" for unprefixed paths. Scraping tends to concentrate
+// on particular language sections.
+func ByLanguagePrefix() Dimension {
+	return func(c *gin.Context) string { return ExtractLanguageFromPath(c.Request.URL.Path) }
+}
+
+// ByHeader keys on a request header, e.g. an API key ID set by a gateway.
+func ByHeader(name string) Dimension {
+	return func(c *gin.Context) string { return c.GetHeader(name) }
+}
+
+// RateLimitRule is one limit: at most Limit requests per Window for each
+// distinct combination of Dimensions.
+type RateLimitRule struct {
+	// Name identifies the rule in keys (defaults to "rule<index>")
+	Name string
+	// Dimensions make up the key (required)
+	Dimensions []Dimension
+	// Limit is the number of requests allowed per Window (required)
+	Limit int
+	// Window is the counting period (required)
+	Window time.Duration
+	// SoftLimit, if set, is a threshold below Limit past which requests
+	// still succeed but carry an X-RateLimit-Warning header, and the first
+	// one per key and window is logged
+	SoftLimit int
+	// Match restricts the rule to some requests, e.g. one language section.
+	// Nil applies it to all.
+	Match func(c *gin.Context) bool
+}
+
+// RateLimitStore counts requests per key in fixed windows. Implementations
+// must be safe for concurrent use; a shared store (see redisstore) applies
+// limits across instances.
+type RateLimitStore interface {
+	// Increment adds one to key's count in the current window, starting a
+	// new window of the given length if none is open, and returns the new
+	// count and the time until the window resets.
+	Increment(ctx context.Context, key string, window time.Duration) (count int64, resetIn time.Duration, err error)
+}
+
+// RateLimitConfig configures the rate limiter.
+type RateLimitConfig struct {
+	// Rules are checked in order; a request must pass all of them (required)
+	Rules []RateLimitRule
+	// Store holds the counters (defaults to an in-memory store)
+	Store RateLimitStore
+	// Challenger, if set, answers limited requests with a challenge instead
+	// of a flat 429, and lets requests that passed one through
+	Challenger *Challenger
+	// Clock times the windows of the default store (defaults to
+	// clock.System)
+	Clock clock.Clock
+	// WarnOnly never blocks: requests over a Limit are answered normally,
+	// with the warning header and log of a SoftLimit. It introduces limits
+	// to an existing API without breaking its clients.
+	WarnOnly bool
+	// Bypasses let trusted traffic, e.g. internal batch jobs or admins,
+	// skip Rules. The first one a request matches applies.
+	Bypasses []RateLimitBypass
+	// Logger receives the warnings and bypass decisions (defaults to
+	// slog.Default())
+	Logger *slog.Logger
+}
+
+// RateLimitBypass exempts trusted requests from the rules, identified by a
+// token or a predicate. They count against Burst instead, if set, so a
+// runaway internal job is still bounded without being throttled alongside
+// the public internet.
+type RateLimitBypass struct {
+	// Name identifies the bypass in logs and keys (defaults to
+	// "bypass<index>")
+	Name string
+	// Tokens are accepted in Header, e.g. one per internal service. They
+	// are compared in constant time.
+	Tokens []string
+	// Header carrying a token (defaults to "X-RateLimit-Bypass")
+	Header string
+	// Trusted, if set, reports whether the request's principal bypasses
+	// the rules, e.g. admin users
+	Trusted func(c *gin.Context) bool
+	// Burst is the pool the bypassing requests count against instead of
+	// the rules. Nil leaves them unlimited.
+	Burst *RateLimitRule
+}
+
+// RateLimitBypassHeader is the default header of RateLimitBypass tokens.
+const RateLimitBypassHeader = "X-RateLimit-Bypass"
+
+// RateLimitWarningHeader is set on requests past a rule's SoftLimit, or
+// past its Limit in WarnOnly mode.
+const RateLimitWarningHeader = "X-RateLimit-Warning"
+
+// RateLimit returns middleware that enforces rules, responding 429 with a
+// Retry-After header (or a challenge, with Challenger) when any is exceeded.
+// Dimensions let a tighter limit cover only part of the traffic:
+//
+//	router.Use(middleware.RateLimit(middleware.RateLimitConfig{
+//	    Rules: []middleware.RateLimitRule{
+//	        {Name: "ip", Dimensions: []middleware.Dimension{middleware.ByIP()}, Limit: 600, Window: time.Minute},
+//	        {
+//	            Name:       "ja-galleries",
+//	            Dimensions: []middleware.Dimension{middleware.ByIP(), middleware.ByRouteGroup("/:lang/galleries"), middleware.ByLanguagePrefix()},
+//	            Limit:      60,
+//	            Window:     time.Minute,
+//	            Match:      func(c *gin.Context) bool { return middleware.ExtractLanguageFromPath(c.Request.URL.Path) == "ja" },
+//	        },
+//	    },
+//	}))
+//
+// Rules with a SoftLimit warn before they block: past it, responses carry
+// X-RateLimit-Warning (e.g. `rule=ip; limit=600; window=60; used=451;
+// reset=17`, one per rule) and the principal or IP is logged once per
+// window. Store errors are reported (see response.SetReporter) and fail
+// open.
+//
+// Bypasses exempt trusted requests, which count against their own Burst
+// pool instead. Every bypass is logged at Info with the principal, as is
+// every request presenting a token that isn't accepted, at Warn:
+//
+//	Bypasses: []middleware.RateLimitBypass{
+//	    {Name: "batch", Tokens: []string{os.Getenv("BATCH_RATE_TOKEN")}, Burst: &middleware.RateLimitRule{
+//	        Dimensions: []middleware.Dimension{middleware.ByHeader("X-Service")}, Limit: 10000, Window: time.Minute,
+//	    }},
+//	    {Name: "admin", Trusted: func(c *gin.Context) bool {
+//	        p, _ := auth.GetPrincipal(c)
+//	        return slices.Contains(p.Roles, "admin")
+//	    }},
+//	},
+func RateLimit(cfg RateLimitConfig) gin.HandlerFunc {
+	return NewRateLimiter(cfg).Middleware()
+}
+
+// RateLimiter is the limiter behind RateLimit, with rules that can be
+// replaced at runtime, e.g. to tighten a limit during an incident:
+//
+//	limiter := middleware.NewRateLimiter(cfg)
+//	router.Use(limiter.Middleware())
+//
+//	watcher.OnChange(func(rules []middleware.RateLimitRule) { limiter.Update(rules) })
+//
+// Counters are kept across updates, keyed by rule name.
+type RateLimiter struct {
+	rules      atomic.Pointer[[]RateLimitRule]
+	bypasses   []rateLimitBypass
+	store      RateLimitStore
+	challenger *Challenger
+	warnOnly   bool
+	logger     *slog.Logger
+}
+
+// NewRateLimiter returns a RateLimiter for cfg. It panics if cfg has no
+// rules or an invalid one.
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	if len(cfg.Rules) == 0 {
+		panic("middleware: RateLimit requires at least one rule")
+	}
+	l := &RateLimiter{
+		bypasses:   make([]rateLimitBypass, len(cfg.Bypasses)),
+		store:      cfg.Store,
+		challenger: cfg.Challenger,
+		warnOnly:   cfg.WarnOnly,
+		logger:     cfg.Logger,
+	}
+	if err := l.Update(cfg.Rules); err != nil {
+		panic(err.Error())
+	}
+	for i, b := range cfg.Bypasses {
+		if len(b.Tokens) == 0 && b.Trusted == nil {
+			panic("middleware: RateLimit bypasses require Tokens or Trusted")
+		}
+		if b.Name == "" {
+			b.Name = "bypass" + strconv.Itoa(i)
+		}
+		if b.Header == "" {
+			b.Header = RateLimitBypassHeader
+		}
+		l.bypasses[i] = rateLimitBypass{RateLimitBypass: b}
+		if b.Burst != nil {
+			burst, err := validRateLimitRule(*b.Burst, b.Name+"-burst")
+			if err != nil {
+				panic(err.Error())
+			}
+			burst.Name = "bypass:" + burst.Name
+			l.bypasses[i].rules = []RateLimitRule{burst}
+		}
+	}
+	if l.store == nil {
+		l.store = newMemoryRateLimitStore(clock.Or(cfg.Clock))
+	}
+	if l.logger == nil {
+		l.logger = slog.Default()
+	}
+	return l
+}
+
+// Update replaces the rules. If any rule is invalid, it returns an error
+// and keeps the old ones. Empty rules turn the limit off until the next
+// Update. Requests already being checked keep the rules they started with.
+func (l *RateLimiter) Update(rules []RateLimitRule) error {
+	valid := make([]RateLimitRule, len(rules))
+	for i, r := range rules {
+		var err error
+		if valid[i], err = validRateLimitRule(r, "rule"+strconv.Itoa(i)); err != nil {
+			return err
+		}
+	}
+	l.rules.Store(&valid)
+	return nil
+}
+
+// Rules returns the rules in effect.
+func (l *RateLimiter) Rules() []RateLimitRule {
+	return append([]RateLimitRule(nil), *l.rules.Load()...)
+}
+
+// Middleware returns the gin middleware; see RateLimit.
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.challenger != nil && ChallengePassed(c) {
+			c.Next()
+			return
+		}
+
+		active := *l.rules.Load()
+		if b, ok := matchBypass(c, l.bypasses, l.logger); ok {
+			active = b.rules
+		}
+		for _, r := range active {
+			if r.Match != nil && !r.Match(c) {
+				continue
+			}
+			count, resetIn, err := l.store.Increment(c.Request.Context(), rateLimitKey(c, r), r.Window)
+			if err != nil {
+				response.ReportError(c, c.Request, c.FullPath(), err)
+				continue
+			}
+			over := count > int64(r.Limit)
+			if !over || l.warnOnly {
+				if over || (r.SoftLimit > 0 && count > int64(r.SoftLimit)) {
+					c.Writer.Header().Add(RateLimitWarningHeader, fmt.Sprintf("rule=%s; limit=%d; window=%d; used=%d; reset=%d",
+						r.Name, r.Limit, int(r.Window/time.Second), count, int((resetIn+time.Second-1)/time.Second)))
+				}
+				// Counts pass each threshold once per window
+				if (r.SoftLimit > 0 && count == int64(r.SoftLimit)+1) || count == int64(r.Limit)+1 {
+					logRateLimitWarning(c, l.logger, r, count)
+				}
+				continue
+			}
+
+			if l.challenger != nil {
+				l.challenger.Challenge(c)
+			} else {
+				retryAfter := max(int((resetIn+time.Second-1)/time.Second), 1)
+				c.Header("Retry-After", strconv.Itoa(retryAfter))
+				response.ErrorWithInfo(c, http.StatusTooManyRequests, response.ErrorInfo{
+					Type:    response.ErrorTypeRateLimit,
+					Code:    response.ErrorCodeRateLimitExceeded,
+					Message: "rate limit exceeded, try again later",
+					Details: map[string]any{
+						"rule":           r.Name,
+						"limit":          r.Limit,
+						"window_seconds": int(r.Window / time.Second),
+						"retry_after":    retryAfter,
+					},
+				})
+			}
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// validRateLimitRule checks r and names it name if it has no name.
+func validRateLimitRule(r RateLimitRule, name string) (RateLimitRule, error) {
+	if len(r.Dimensions) == 0 || r.Limit <= 0 || r.Window <= 0 {
+		return r, errors.New("middleware: RateLimit rules require Dimensions, Limit > 0, and Window > 0")
+	}
+	if r.SoftLimit < 0 || r.SoftLimit >= r.Limit {
+		return r, errors.New("middleware: RateLimit SoftLimit must be below Limit")
+	}
+	if r.Name == "" {
+		r.Name = name
+	}
+	return r, nil
+}
+
+// rateLimitBypass is a RateLimitBypass with its validated burst rules.
+type rateLimitBypass struct {
+	RateLimitBypass
+	rules []RateLimitRule
+}
+
+// matchBypass returns the first bypass the request matches, logging the
+// decision.
+func matchBypass(c *gin.Context, bypasses []rateLimitBypass, logger *slog.Logger) (*rateLimitBypass, bool) {
+	for i := range bypasses {
+		b := &bypasses[i]
+		reason := ""
+		if token := c.GetHeader(b.Header); token != "" && len(b.Tokens) > 0 {
+			if !validBypassToken(token, b.Tokens) {
+				logger.LogAttrs(c, slog.LevelWarn, "rate limit bypass token rejected",
+					slog.String("bypass", b.Name),
+					slog.String("principal", principalOrIP(c)),
+					slog.String("ip", c.ClientIP()),
+					slog.String("route", c.FullPath()),
+				)
+				continue
+			}
+			reason = "token"
+		} else if b.Trusted != nil && b.Trusted(c) {
+			reason = "trusted"
+		} else {
+			continue
+		}
+
+		pool := "unlimited"
+		if len(b.rules) > 0 {
+			pool = b.rules[0].Name
+		}
+		logger.LogAttrs(c, slog.LevelInfo, "rate limit bypassed",
+			slog.String("bypass", b.Name),
+			slog.String("reason", reason),
+			slog.String("pool", pool),
+			slog.String("principal", principalOrIP(c)),
+			slog.String("ip", c.ClientIP()),
+			slog.String("route", c.FullPath()),
+		)
+		return b, true
+	}
+	return nil, false
+}
+
+// validBypassToken reports whether token is one of tokens, comparing in
+// constant time.
+func validBypassToken(token string, tokens []string) bool {
+	valid := false
+	for _, t := range tokens {
+		if t != "" && subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// logRateLimitWarning logs that the client passed a threshold of r.
+func logRateLimitWarning(c *gin.Context, logger *slog.Logger, r RateLimitRule, count int64) {
+	msg := "rate limit soft threshold exceeded"
+	if count > int64(r.Limit) {
+		msg = "rate limit exceeded (warn only)"
+	}
+	logger.LogAttrs(c, slog.LevelWarn, msg,
+		slog.String("rule", r.Name),
+		slog.String("principal", principalOrIP(c)),
+		slog.String("ip", c.ClientIP()),
+		slog.String("route", c.FullPath()),
+		slog.Int("limit", r.Limit),
+		slog.Int("soft_limit", r.SoftLimit),
+		slog.Duration("window", r.Window),
+	)
+}
+
+// rateLimitKey joins the rule name and its dimension values.
+func rateLimitKey(c *gin.Context, r RateLimitRule) string {
+	var b strings.Builder
+	b.WriteString(r.Name)
+	for _, d := range r.Dimensions {
+		b.WriteByte('|')
+		b.WriteString(d(c))
+	}
+	return b.String()
+}
+
+// MemoryRateLimitStore is an in-process RateLimitStore, for single-instance
+// services and tests.
+type MemoryRateLimitStore struct {
+	clock   clock.Clock
+	mu      sync.Mutex
+	windows map[string]rateWindow
+}
+
+type rateWindow struct {
+	count   int64
+	expires time.Time
+}
+
+// NewMemoryRateLimitStore returns an empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return newMemoryRateLimitStore(clock.System)
+}
+
+// newMemoryRateLimitStore returns an empty store timed by clk.
+func newMemoryRateLimitStore(clk clock.Clock) *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{clock: clk, windows: map[string]rateWindow{}}
+}
+
+// Increment implements RateLimitStore.
+func (s *MemoryRateLimitStore) Increment(_ context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.windows[key]
+	if !ok || !now.Before(w.expires) {
+		if len(s.windows) >= 65536 {
+			s.sweep(now)
+		}
+		w = rateWindow{expires: now.Add(window)}
+	}
+	w.count++
+	s.windows[key] = w
+	return w.count, w.expires.Sub(now), nil
+}
+
+// sweep drops expired windows. Called with s.mu held.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for k, w := range s.windows {
+		if !now.Before(w.expires) {
+			delete(s.windows, k)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/apitest"
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestRateLimit(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.RateLimit(middleware.RateLimitConfig{
+		Rules: []middleware.RateLimitRule{
+			{Name: "ip", Dimensions: []middleware.Dimension{middleware.ByIP()}, Limit: 3, Window: time.Minute},
+			{
+				Name:       "ja",
+				Dimensions: []middleware.Dimension{middleware.ByIP(), middleware.ByLanguagePrefix()},
+				Limit:      1,
+				Window:     time.Minute,
+				Match: func(c *gin.Context) bool {
+					return middleware.ExtractLanguageFromPath(c.Request.URL.Path) == "ja"
+				},
+			},
+		},
+	}))
+	router.GET("/:lang/galleries", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		path       string
+		remoteAddr string
+		wantStatus int
+		wantRule   string
+	}{
+		{"/ja/galleries", "1.2.3.4:1000", http.StatusOK, ""},
+		{"/ja/galleries", "1.2.3.4:1000", http.StatusTooManyRequests, "ja"}, // ja limit
+		{"/en/galleries", "1.2.3.4:1000", http.StatusOK, ""},                // other sections unaffected
+		{"/ja/galleries", "5.6.7.8:1000", http.StatusOK, ""},                // other clients unaffected
+		{"/en/galleries", "1.2.3.4:1000", http.StatusTooManyRequests, "ip"}, // ip limit
+	}
+
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		req.RemoteAddr = tt.remoteAddr
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("request %d (%s from %s): expected status %d, got %d", i, tt.path, tt.remoteAddr, tt.wantStatus, w.Code)
+		}
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Errorf("request %d: expected Retry-After", i)
+		}
+		if tt.wantRule != "" {
+			var body struct {
+				Error struct {
+					Details map[string]any `json:"details"`
+				} `json:"error"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if d := body.Error.Details; d["rule"] != tt.wantRule || d["window_seconds"] != float64(60) {
+				t.Errorf("request %d: expected details for rule %s, got %v", i, tt.wantRule, d)
+			}
+		}
+	}
+}
+
+func TestRateLimiterUpdate(t *testing.T) {
+	rule := func(limit int) []middleware.RateLimitRule {
+		return []middleware.RateLimitRule{{Name: "ip", Dimensions: []middleware.Dimension{middleware.ByIP()}, Limit: limit, Window: time.Minute}}
+	}
+	limiter := middleware.NewRateLimiter(middleware.RateLimitConfig{Rules: rule(1)})
+	router := gin.New()
+	router.Use(limiter.Middleware())
+	router.GET("/galleries", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/galleries", nil))
+		return w.Code
+	}
+
+	if get() != http.StatusOK || get() != http.StatusTooManyRequests {
+		t.Fatal("expected the second request to be limited")
+	}
+	if err := limiter.Update(rule(3)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The count carries over: 2 used of 3
+	if code := get(); code != http.StatusOK {
+		t.Errorf("expected the raised limit to apply, got %d", code)
+	}
+	if err := limiter.Update(rule(0)); err == nil {
+		t.Error("expected an invalid rule to be rejected")
+	}
+	if got := limiter.Rules(); len(got) != 1 || got[0].Limit != 3 {
+		t.Errorf("expected the old rules to be kept, got %+v", got)
+	}
+	if err := limiter.Update(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code := get(); code != http.StatusOK {
+		t.Errorf("expected no limit without rules, got %d", code)
+	}
+}
+
+func TestRateLimitWindowReset(t *testing.T) {
+	clk := apitest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	router := gin.New()
+	router.Use(middleware.RateLimit(middleware.RateLimitConfig{
+		Rules: []middleware.RateLimitRule{{Dimensions: []middleware.Dimension{middleware.ByIP()}, Limit: 1, Window: time.Minute}},
+		Clock: clk,
+	}))
+	router.GET("/galleries", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		advance    time.Duration
+		wantStatus int
+		wantRetry  string
+	}{
+		{0, http.StatusOK, ""},
+		{20 * time.Second, http.StatusTooManyRequests, "40"},
+		{39 * time.Second, http.StatusTooManyRequests, "1"},
+		{time.Second, http.StatusOK, ""}, // new window
+	}
+	for i, tt := range tests {
+		clk.Advance(tt.advance)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/galleries", nil))
+		if w.Code != tt.wantStatus || w.Header().Get("Retry-After") != tt.wantRetry {
+			t.Errorf("request %d: expected %d with Retry-After %q, got %d with %q", i, tt.wantStatus, tt.wantRetry, w.Code, w.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestRateLimitSoftLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		warnOnly   bool
+		wantStatus []int
+		wantWarned []bool
+		wantLogs   int
+	}{
+		{
+			"soft then hard",
+			false,
+			[]int{200, 200, 200, 429},
+			[]bool{false, true, true, false},
+			1,
+		},
+		{
+			"warn only",
+			true,
+			[]int{200, 200, 200, 200, 200},
+			[]bool{false, true, true, true, true},
+			2, // past the soft limit, and past the limit
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				auth.SetPrincipal(c, auth.Principal{ID: "user-1"})
+			})
+			router.Use(middleware.RateLimit(middleware.RateLimitConfig{
+				Rules: []middleware.RateLimitRule{
+					{Name: "ip", Dimensions: []middleware.Dimension{middleware.ByIP()}, Limit: 3, SoftLimit: 1, Window: time.Minute},
+				},
+				WarnOnly: tt.warnOnly,
+				Logger:   slog.New(slog.NewJSONHandler(&buf, nil)),
+			}))
+			router.GET("/galleries", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			for i, want := range tt.wantStatus {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/galleries", nil))
+
+				if w.Code != want {
+					t.Errorf("request %d: expected status %d, got %d", i, want, w.Code)
+				}
+				warning := w.Header().Get(middleware.RateLimitWarningHeader)
+				if (warning != "") != tt.wantWarned[i] {
+					t.Errorf("request %d: expected warning %v, got %q", i, tt.wantWarned[i], warning)
+				}
+				if warning != "" && !strings.HasPrefix(warning, "rule=ip; limit=3; window=60; used=") {
+					t.Errorf("request %d: expected the rule in the warning, got %q", i, warning)
+				}
+			}
+
+			logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(logs) != tt.wantLogs {
+				t.Fatalf("expected %d warnings logged, got %q", tt.wantLogs, logs)
+			}
+			if !strings.Contains(logs[0], `"principal":"user-1"`) {
+				t.Errorf("expected the principal logged, got %s", logs[0])
+			}
+		})
+	}
+}
+
+func TestRateLimitChallenge(t *testing.T) {
+	ch := middleware.NewChallenger(middleware.ChallengeConfig{Secret: []byte("secret"), Difficulty: 4})
+	router := gin.New()
+	router.Use(ch.Verify())
+	router.Use(middleware.RateLimit(middleware.RateLimitConfig{
+		Rules:      []middleware.RateLimitRule{{Dimensions: []middleware.Dimension{middleware.ByIP()}, Limit: 1, Window: time.Minute}},
+		Challenger: ch,
+	}))
+	router.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func(solution string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "1.2.3.4:1000"
+		if solution != "" {
+			req.Header.Set("X-Challenge-Solution", solution)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	serve("")
+	w := serve("")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("X-Challenge") == "" {
+		t.Fatalf("expected a challenge, got %d", w.Code)
+	}
+
+	w = serve(middleware.SolveChallenge(challengeToken(t, w.Header().Get("X-Challenge")), 4))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected solved challenge to pass, got %d", w.Code)
+	}
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	s := middleware.NewMemoryRateLimitStore()
+
+	for want := int64(1); want <= 3; want++ {
+		count, resetIn, err := s.Increment(context.Background(), "k", time.Minute)
+		if err != nil || count != want {
+			t.Errorf("expected count %d, got %d (%v)", want, count, err)
+		}
+		if resetIn <= 0 || resetIn > time.Minute {
+			t.Errorf("expected reset within the window, got %s", resetIn)
+		}
+	}
+
+	if count, _, _ := s.Increment(context.Background(), "k", time.Nanosecond); count != 4 {
+		t.Errorf("expected the open window to be kept, got %d", count)
+	}
+	time.Sleep(time.Millisecond)
+	if count, _, _ := s.Increment(context.Background(), "other", time.Nanosecond); count != 1 {
+		t.Errorf("expected a new key to start at 1, got %d", count)
+	}
+}
+
+func TestRateLimitBypass(t *testing.T) {
+	var logs bytes.Buffer
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader("X-User") == "admin" {
+			auth.SetPrincipal(c, auth.Principal{ID: "usr_admin", Roles: []string{"admin"}})
+		}
+		c.Next()
+	})
+	router.Use(middleware.RateLimit(middleware.RateLimitConfig{
+		Rules: []middleware.RateLimitRule{{Name: "ip", Dimensions: []middleware.Dimension{middleware.ByIP()}, Limit: 1, Window: time.Minute}},
+		Bypasses: []middleware.RateLimitBypass{
+			{
+				Name:   "batch",
+				Tokens: []string{"batch-secret"},
+				Burst:  &middleware.RateLimitRule{Dimensions: []middleware.Dimension{middleware.ByIP()}, Limit: 3, Window: time.Minute},
+			},
+			{
+				Name: "admin",
+				Trusted: func(c *gin.Context) bool {
+					p, _ := auth.GetPrincipal(c)
+					return len(p.Roles) > 0 && p.Roles[0] == "admin"
+				},
+			},
+		},
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	}))
+	router.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func(header, value string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "1.2.3.4:1000"
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{"public", "", "", http.StatusOK},
+		{"public over the limit", "", "", http.StatusTooManyRequests},
+		{"wrong token", middleware.RateLimitBypassHeader, "guess", http.StatusTooManyRequests},
+		{"token", middleware.RateLimitBypassHeader, "batch-secret", http.StatusOK},
+		{"token", middleware.RateLimitBypassHeader, "batch-secret", http.StatusOK},
+		{"token", middleware.RateLimitBypassHeader, "batch-secret", http.StatusOK},
+		{"token over the burst pool", middleware.RateLimitBypassHeader, "batch-secret", http.StatusTooManyRequests},
+		{"trusted principal", "X-User", "admin", http.StatusOK},
+		{"trusted principal unlimited", "X-User", "admin", http.StatusOK},
+	}
+
+	for i, tt := range tests {
+		if got := serve(tt.header, tt.value); got != tt.wantStatus {
+			t.Errorf("request %d (%s): expected status %d, got %d", i, tt.name, tt.wantStatus, got)
+		}
+	}
+
+	out := logs.String()
+	if n := strings.Count(out, `msg="rate limit bypassed" bypass=batch reason=token pool=bypass:batch-burst`); n != 4 {
+		t.Errorf("expected 4 logged token bypasses, got %d in %s", n, out)
+	}
+	if !strings.Contains(out, `msg="rate limit bypassed" bypass=admin reason=trusted pool=unlimited principal=usr_admin`) {
+		t.Errorf("expected the trusted bypass to be logged, got %s", out)
+	}
+	if !strings.Contains(out, `msg="rate limit bypass token rejected" bypass=batch`) {
+		t.Errorf("expected the rejected token to be logged, got %s", out)
+	}
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// RateLimitPersistConfig configures MemoryRateLimitStore.Persist.
+type RateLimitPersistConfig struct {
+	// Store keeps the snapshots, e.g. a redisstore.CacheStore (required)
+	Store cache.Store
+	// Key the snapshot is kept under (defaults to "ratelimit:snapshot");
+	// instances sharing a Store each need their own, stable across
+	// restarts, e.g. the pod name
+	Key string
+	// Interval is how often the counters are saved (defaults to 10s)
+	Interval time.Duration
+}
+
+// rateLimitSnapshot is the stored form of a MemoryRateLimitStore.
+type rateLimitSnapshot struct {
+	Windows []rateLimitSnapshotWindow `json:"windows"`
+}
+
+type rateLimitSnapshotWindow struct {
+	Key     string    `json:"key"`
+	Count   int64     `json:"count"`
+	Expires time.Time `json:"expires"`
+}
+
+// Save writes the open windows to store under key, expiring with the last
+// of them.
+func (s *MemoryRateLimitStore) Save(ctx context.Context, store cache.Store, key string) error {
+	now := s.clock.Now()
+	var snap rateLimitSnapshot
+	var ttl time.Duration
+	s.mu.Lock()
+	for k, w := range s.windows {
+		if !now.Before(w.expires) {
+			continue
+		}
+		snap.Windows = append(snap.Windows, rateLimitSnapshotWindow{Key: k, Count: w.count, Expires: w.expires})
+		ttl = max(ttl, w.expires.Sub(now))
+	}
+	s.mu.Unlock()
+
+	if len(snap.Windows) == 0 {
+		return store.Delete(ctx, key)
+	}
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	return store.Set(ctx, key, b, ttl)
+}
+
+// Load restores the windows saved under key that are still open. Requests
+// counted since the process started are added to the restored counts.
+func (s *MemoryRateLimitStore) Load(ctx context.Context, store cache.Store, key string) error {
+	b, ok, err := store.Get(ctx, key)
+	if err != nil || !ok {
+		return err
+	}
+	var snap rateLimitSnapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return fmt.Errorf("middleware: decoding rate limit snapshot: %w", err)
+	}
+
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sw := range snap.Windows {
+		if !now.Before(sw.Expires) {
+			continue
+		}
+		w, ok := s.windows[sw.Key]
+		if !ok || !now.Before(w.expires) {
+			w = rateWindow{expires: sw.Expires}
+		}
+		w.count += sw.Count
+		s.windows[sw.Key] = w
+	}
+	return nil
+}
+
+// Persist keeps the counters across restarts, so a deploy doesn't hand
+// every client a fresh budget. It loads the last snapshot, saves one every
+// Interval, and a last one when ctx is done (within 5s), then returns:
+//
+//	limits := middleware.NewMemoryRateLimitStore()
+//	go limits.Persist(ctx, middleware.RateLimitPersistConfig{
+//	    Store: redisstore.NewCacheStore(client, "galleries:"),
+//	    Key:   "ratelimit:" + os.Getenv("POD_NAME"),
+//	})
+//	router.Use(middleware.RateLimit(middleware.RateLimitConfig{Rules: rules, Store: limits}))
+//
+// Errors are reported (see response.SetReporter) and retried on the next
+// Interval. It panics without a Store.
+func (s *MemoryRateLimitStore) Persist(ctx context.Context, cfg RateLimitPersistConfig) {
+	if cfg.Store == nil {
+		panic("middleware: RateLimitPersistConfig requires a Store")
+	}
+	if cfg.Key == "" {
+		cfg.Key = "ratelimit:snapshot"
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+
+	if err := s.Load(ctx, cfg.Store, cfg.Key); err != nil {
+		response.ReportError(ctx, nil, "", err)
+	}
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Save(ctx, cfg.Store, cfg.Key); err != nil {
+				response.ReportError(ctx, nil, "", err)
+			}
+		case <-ctx.Done():
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			if err := s.Save(ctx, cfg.Store, cfg.Key); err != nil {
+				response.ReportError(ctx, nil, "", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package middleware_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestMemoryRateLimitStoreSaveLoad(t *testing.T) {
+	ctx := context.Background()
+	store := cache.NewMemoryStore(0)
+
+	before := middleware.NewMemoryRateLimitStore()
+	for i := 0; i < 3; i++ {
+		before.Increment(ctx, "ip|1.2.3.4", time.Minute)
+	}
+	before.Increment(ctx, "ip|5.6.7.8", time.Minute)
+	if err := before.Save(ctx, store, "snapshot"); err != nil {
+		t.Fatalf("expected no error saving, got %v", err)
+	}
+
+	after := middleware.NewMemoryRateLimitStore()
+	after.Increment(ctx, "ip|1.2.3.4", time.Minute) // counted before the load
+	if err := after.Load(ctx, store, "snapshot"); err != nil {
+		t.Fatalf("expected no error loading, got %v", err)
+	}
+
+	tests := []struct {
+		key  string
+		want int64
+	}{
+		{"ip|1.2.3.4", 5},
+		{"ip|5.6.7.8", 2},
+		{"ip|9.9.9.9", 1},
+	}
+	for _, tt := range tests {
+		count, resetIn, _ := after.Increment(ctx, tt.key, time.Minute)
+		if count != tt.want {
+			t.Errorf("%s: expected count %d, got %d", tt.key, tt.want, count)
+		}
+		if resetIn <= 0 || resetIn > time.Minute {
+			t.Errorf("%s: expected the window to be kept, got reset in %s", tt.key, resetIn)
+		}
+	}
+}
+
+func TestMemoryRateLimitStoreLoadMissing(t *testing.T) {
+	s := middleware.NewMemoryRateLimitStore()
+	if err := s.Load(context.Background(), cache.NewMemoryStore(0), "snapshot"); err != nil {
+		t.Errorf("expected no error without a snapshot, got %v", err)
+	}
+}
+
+func TestMemoryRateLimitStorePersist(t *testing.T) {
+	store := cache.NewMemoryStore(0)
+	s := middleware.NewMemoryRateLimitStore()
+	s.Increment(context.Background(), "k", time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Persist(ctx, middleware.RateLimitPersistConfig{Store: store, Interval: time.Hour})
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	if _, ok, _ := store.Get(context.Background(), "ratelimit:snapshot"); !ok {
+		t.Errorf("expected a snapshot saved on shutdown")
+	}
+}
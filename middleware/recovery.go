@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Recovery returns middleware that recovers from panics in the handlers
+// below it, reports them (with the panicking stack) to the Reporter set with
+// response.SetReporter, and responds with a structured 500 if nothing has
+// been written yet. Use it in place of gin.Recovery():
+//
+//	response.SetReporter(sentryReporter{})
+//	router.Use(middleware.Recovery())
+//
+// With response.SetExposeStacks, the 500's details include the panic and
+// its stack. http.ErrAbortHandler is re-panicked so net/http aborts the
+// response as intended.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			err, ok := v.(error)
+			if !ok {
+				err = fmt.Errorf("panic: %v", v)
+			}
+			response.ReportError(c, c.Request, c.FullPath(), err)
+
+			if !c.Writer.Written() {
+				info := response.ErrorInfo{
+					Type:    response.ErrorTypeAPI,
+					Code:    response.ErrorCodeInternal,
+					Message: "internal error",
+				}
+				if response.StacksExposed() {
+					info.Details = map[string]any{"panic": err.Error(), "stack": string(debug.Stack())}
+				}
+				response.ErrorWithInfo(c, http.StatusInternalServerError, info)
+			}
+			c.Abort()
+		}()
+
+		c.Next()
+	}
+}
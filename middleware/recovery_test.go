@@ -0,0 +1,126 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestRecovery(t *testing.T) {
+	var reports []response.Report
+	response.SetReporter(response.ReporterFunc(func(ctx context.Context, r response.Report) {
+		reports = append(reports, r)
+	}))
+	t.Cleanup(func() { response.SetReporter(nil) })
+
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.GET("/string", func(c *gin.Context) { panic("nil map") })
+	router.GET("/error", func(c *gin.Context) { panic(errors.New("bad state")) })
+	router.GET("/written", func(c *gin.Context) {
+		c.Status(http.StatusAccepted)
+		c.Writer.WriteHeaderNow()
+		panic("late")
+	})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantErr    string
+	}{
+		{"/string", http.StatusInternalServerError, "panic: nil map"},
+		{"/error", http.StatusInternalServerError, "bad state"},
+		{"/written", http.StatusAccepted, "panic: late"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			reports = nil
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if len(reports) != 1 {
+				t.Fatalf("expected 1 report, got %d", len(reports))
+			}
+			if reports[0].Err.Error() != tt.wantErr {
+				t.Errorf("expected error '%s', got '%v'", tt.wantErr, reports[0].Err)
+			}
+			if reports[0].Route != tt.path {
+				t.Errorf("expected route '%s', got '%s'", tt.path, reports[0].Route)
+			}
+			if !strings.Contains(string(reports[0].Stack), "recovery_test.go") {
+				t.Errorf("expected stack to include the panicking handler")
+			}
+		})
+	}
+}
+
+func TestRecoveryBody(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.GET("/test", func(c *gin.Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	router.ServeHTTP(w, req)
+
+	var result response.Error
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if result.Error.Code != response.ErrorCodeInternal || result.Error.Message != "internal error" {
+		t.Errorf("expected internal error, got %+v", result.Error)
+	}
+}
+
+func TestRecoveryExposeStacks(t *testing.T) {
+	response.SetExposeStacks(true)
+	t.Cleanup(func() { response.SetExposeStacks(false) })
+
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.GET("/test", func(c *gin.Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	router.ServeHTTP(w, req)
+
+	var result response.Error
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if result.Error.Details["panic"] != "panic: boom" {
+		t.Errorf("expected the panic in details, got %v", result.Error.Details["panic"])
+	}
+	if stack, _ := result.Error.Details["stack"].(string); !strings.Contains(stack, "recovery_test.go") {
+		t.Errorf("expected the panicking stack in details, got %q", stack)
+	}
+}
+
+func TestRecoveryAbortHandler(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.GET("/test", func(c *gin.Context) { panic(http.ErrAbortHandler) })
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("expected ErrAbortHandler to be re-panicked, got %v", v)
+		}
+	}()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	router.ServeHTTP(w, req)
+}
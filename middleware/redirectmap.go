@@ -0,0 +1,294 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedirectRule permanently redirects a legacy path.
+type RedirectRule struct {
+	// From is the legacy path, either exact ("/g/12345") or a pattern with
+	// gin-style parameters: ":name" matches one segment and a final
+	// "*name" the rest of the path ("/gallery/:id/:slug", "/old/*rest")
+	From string
+	// To is the target path or absolute URL. It may use the parameters of
+	// From as whole segments, e.g. "/galleries/:id".
+	To string
+}
+
+// RedirectMap is a table of legacy URL redirects, sized for thousands of
+// rules: exact paths are looked up in a map and patterns are indexed by
+// their first segment. The table can be replaced at runtime with Update,
+// e.g. when the file or database table it is loaded from changes. Set it
+// as LanguageRedirectConfig.Redirects:
+//
+//	f, err := os.Open("redirects.map")
+//	...
+//	rules, err := middleware.ParseRedirects(f)
+//	...
+//	redirects, err := middleware.NewRedirectMap(rules)
+//	...
+//	cfg := middleware.LanguageRedirectConfig{Supported: []string{"en", "ja"}, Redirects: redirects}
+//
+//	watcher.OnChange(func(rules []middleware.RedirectRule) {
+//	    if err := redirects.Update(rules); err != nil {
+//	        slog.Error("redirects not reloaded", "error", err)
+//	    }
+//	})
+//
+// Exact rules win over patterns, patterns starting with a literal segment
+// over those starting with a parameter, and otherwise the first rule
+// listed wins. Trailing slashes are ignored when matching.
+type RedirectMap struct {
+	table atomic.Pointer[redirectTable]
+}
+
+// NewRedirectMap returns a RedirectMap of rules, or the first invalid rule
+// as an error.
+func NewRedirectMap(rules []RedirectRule) (*RedirectMap, error) {
+	m := &RedirectMap{}
+	if err := m.Update(rules); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Update replaces the rules. If one is invalid, it returns an error and
+// keeps the current rules.
+func (m *RedirectMap) Update(rules []RedirectRule) error {
+	t, err := newRedirectTable(rules)
+	if err != nil {
+		return err
+	}
+	m.table.Store(t)
+	return nil
+}
+
+// Len returns the number of rules.
+func (m *RedirectMap) Len() int {
+	return m.table.Load().rules
+}
+
+// Lookup returns the target of a request path, without the query string.
+func (m *RedirectMap) Lookup(path string) (string, bool) {
+	return m.table.Load().lookup(path)
+}
+
+// redirect redirects the request if its path has a rule, keeping the
+// query string. If the path has a language prefix that has no rule, the
+// rest of it is looked up and the prefix is kept on local targets.
+func (m *RedirectMap) redirect(c *gin.Context, supported map[string]struct{}) bool {
+	r := c.Request
+	path := r.URL.Path
+	target, ok := m.Lookup(path)
+	if !ok {
+		lang := extractLanguageFromPath(path)
+		if _, supportedLang := supported[lang]; lang == "" || !supportedLang {
+			return false
+		}
+		if target, ok = m.Lookup(path[len(lang)+1:]); !ok {
+			return false
+		}
+		if strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//") {
+			if _, prefixed := supported[extractLanguageFromPath(target)]; !prefixed {
+				target = "/" + lang + target
+			}
+		}
+	}
+
+	if r.URL.RawQuery != "" {
+		if strings.Contains(target, "?") {
+			target += "&" + r.URL.RawQuery
+		} else {
+			target += "?" + r.URL.RawQuery
+		}
+	}
+	status := http.StatusMovedPermanently
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		status = http.StatusPermanentRedirect
+	}
+	c.Redirect(status, target)
+	c.Abort()
+	return true
+}
+
+// ParseRedirects reads redirect rules from r, one "from to" pair per line,
+// so an nginx map of legacy URLs can be loaded as is: a trailing ";" is
+// dropped, and blank lines and lines starting with "#" are skipped.
+func ParseRedirects(r io.Reader) ([]RedirectRule, error) {
+	var rules []RedirectRule
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimSuffix(text, ";"))
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("middleware: redirects line %d: expected \"from to\", got %q", line, text)
+		}
+		rules = append(rules, RedirectRule{From: fields[0], To: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("middleware: reading redirects: %w", err)
+	}
+	return rules, nil
+}
+
+// redirectTable holds validated rules.
+type redirectTable struct {
+	exact    map[string]string             // path -> target
+	literal  map[string][]*redirectPattern // first segment -> patterns
+	wildcard []*redirectPattern            // patterns starting with a parameter
+	rules    int
+}
+
+// redirectPattern is a RedirectRule with parameters.
+type redirectPattern struct {
+	from []string // segments of From
+	to   []string // segments of To
+}
+
+// newRedirectTable validates and indexes rules.
+func newRedirectTable(rules []RedirectRule) (*redirectTable, error) {
+	t := &redirectTable{exact: map[string]string{}, literal: map[string][]*redirectPattern{}, rules: len(rules)}
+	patterns := map[string]bool{}
+	for _, rule := range rules {
+		if !strings.HasPrefix(rule.From, "/") {
+			return nil, fmt.Errorf("middleware: redirect from %q: path must start with /", rule.From)
+		}
+		if rule.To == "" {
+			return nil, fmt.Errorf("middleware: redirect from %q has no target", rule.From)
+		}
+		from := trimTrailingSlash(rule.From)
+		if !strings.ContainsAny(from, ":*") {
+			if _, dup := t.exact[from]; dup {
+				return nil, fmt.Errorf("middleware: redirect from %q is listed twice", rule.From)
+			}
+			t.exact[from] = rule.To
+			continue
+		}
+
+		p, err := newRedirectPattern(from, rule.To)
+		if err != nil {
+			return nil, err
+		}
+		if patterns[from] {
+			return nil, fmt.Errorf("middleware: redirect from %q is listed twice", rule.From)
+		}
+		patterns[from] = true
+		if first := p.from[0]; isRedirectParam(first) {
+			t.wildcard = append(t.wildcard, p)
+		} else {
+			t.literal[first] = append(t.literal[first], p)
+		}
+	}
+	return t, nil
+}
+
+// newRedirectPattern parses the pattern from and its target.
+func newRedirectPattern(from, to string) (*redirectPattern, error) {
+	p := &redirectPattern{from: strings.Split(strings.TrimPrefix(from, "/"), "/"), to: strings.Split(to, "/")}
+	params := map[string]bool{}
+	for i, segment := range p.from {
+		if !isRedirectParam(segment) {
+			if strings.ContainsAny(segment, ":*") {
+				return nil, fmt.Errorf("middleware: redirect from %q: parameters must be whole segments", from)
+			}
+			continue
+		}
+		if segment[0] == '*' && i != len(p.from)-1 {
+			return nil, fmt.Errorf("middleware: redirect from %q: %s must be the last segment", from, segment)
+		}
+		params[segment[1:]] = true
+	}
+	for _, segment := range p.to {
+		if isRedirectParam(segment) && !params[segment[1:]] {
+			return nil, fmt.Errorf("middleware: redirect from %q: target uses %s, which the path doesn't have", from, segment)
+		}
+	}
+	return p, nil
+}
+
+// isRedirectParam reports whether a pattern segment is a parameter.
+func isRedirectParam(segment string) bool {
+	return len(segment) > 1 && (segment[0] == ':' || segment[0] == '*')
+}
+
+// lookup returns the target of path.
+func (t *redirectTable) lookup(path string) (string, bool) {
+	path = trimTrailingSlash(path)
+	if to, ok := t.exact[path]; ok {
+		return to, true
+	}
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for _, candidates := range [][]*redirectPattern{t.literal[segments[0]], t.wildcard} {
+		for _, p := range candidates {
+			if to, ok := p.match(segments); ok {
+				return to, true
+			}
+		}
+	}
+	return "", false
+}
+
+// match returns the target of the path segments if they match p. Paths with
+// empty segments don't match a catch-all, and targets that would point to
+// another host don't match at all.
+func (p *redirectPattern) match(segments []string) (string, bool) {
+	n := len(p.from)
+	catchAll := strings.HasPrefix(p.from[n-1], "*")
+	if len(segments) < n || (!catchAll && len(segments) > n) {
+		return "", false
+	}
+	params := make(map[string]string, n)
+	for i, segment := range p.from {
+		switch {
+		case catchAll && i == n-1:
+			rest := make([]string, len(segments)-i)
+			for j, s := range segments[i:] {
+				if s == "" {
+					return "", false
+				}
+				rest[j] = url.PathEscape(s)
+			}
+			params[segment[1:]] = strings.Join(rest, "/")
+		case isRedirectParam(segment):
+			if segments[i] == "" {
+				return "", false
+			}
+			params[segment[1:]] = url.PathEscape(segments[i])
+		case segment != segments[i]:
+			return "", false
+		}
+	}
+
+	to := make([]string, len(p.to))
+	for i, segment := range p.to {
+		if isRedirectParam(segment) {
+			segment = params[segment[1:]]
+		}
+		to[i] = segment
+	}
+	target := strings.Join(to, "/")
+	if strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		// A protocol-relative target would leave the site
+		return "", false
+	}
+	return target, true
+}
+
+// trimTrailingSlash removes the trailing slashes of a path other than "/".
+func trimTrailingSlash(path string) string {
+	for len(path) > 1 && strings.HasSuffix(path, "/") {
+		path = path[:len(path)-1]
+	}
+	return path
+}
@@ -0,0 +1,180 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestRedirectMapLookup(t *testing.T) {
+	redirects, err := middleware.NewRedirectMap([]middleware.RedirectRule{
+		{From: "/g/12345", To: "/galleries/12345-exact"},
+		{From: "/g/:id", To: "/galleries/:id"},
+		{From: "/gallery/:id/:slug/", To: "/galleries/:id"},
+		{From: "/old/*rest", To: "https://archive.example.com/*rest"},
+		{From: "/:section/index.php", To: "/:section"},
+		{From: "/legacy/*rest", To: "/*rest"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redirects.Len() != 6 {
+		t.Errorf("expected 6 rules, got %d", redirects.Len())
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/g/12345", "/galleries/12345-exact"},
+		{"/g/12345/", "/galleries/12345-exact"},
+		{"/g/999", "/galleries/999"},
+		{"/gallery/7/some-title", "/galleries/7"},
+		{"/old/a/b c", "https://archive.example.com/a/b%20c"},
+		{"/news/index.php", "/news"},
+		{"/g/1/extra", ""},
+		{"/g", ""},
+		{"/old", ""},
+		{"/other", ""},
+		{"/legacy/a/b", "/a/b"},
+		{"/legacy//evil.com/x", ""},
+		{"/legacy/a//b", ""},
+		{"/legacy/\\evil.com", "/%5Cevil.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := redirects.Lookup(tt.path)
+			if ok != (tt.want != "") || got != tt.want {
+				t.Errorf("expected %q, got %q (found %v)", tt.want, got, ok)
+			}
+		})
+	}
+}
+
+func TestRedirectMapInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule middleware.RedirectRule
+	}{
+		{"relative from", middleware.RedirectRule{From: "g/1", To: "/galleries/1"}},
+		{"no target", middleware.RedirectRule{From: "/g/1"}},
+		{"partial parameter", middleware.RedirectRule{From: "/g-:id", To: "/galleries/:id"}},
+		{"catch-all not last", middleware.RedirectRule{From: "/old/*rest/x", To: "/new"}},
+		{"unknown parameter", middleware.RedirectRule{From: "/g/:id", To: "/galleries/:slug"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := middleware.NewRedirectMap([]middleware.RedirectRule{tt.rule}); err == nil {
+				t.Errorf("expected an error for %+v", tt.rule)
+			}
+		})
+	}
+
+	if _, err := middleware.NewRedirectMap([]middleware.RedirectRule{
+		{From: "/g/1", To: "/a"},
+		{From: "/g/1/", To: "/b"},
+	}); err == nil {
+		t.Error("expected an error for a duplicate rule")
+	}
+}
+
+func TestRedirectMapUpdate(t *testing.T) {
+	redirects, _ := middleware.NewRedirectMap([]middleware.RedirectRule{{From: "/a", To: "/b"}})
+
+	if err := redirects.Update([]middleware.RedirectRule{{From: "bad", To: "/c"}}); err == nil {
+		t.Fatal("expected an error for an invalid rule")
+	}
+	if got, _ := redirects.Lookup("/a"); got != "/b" {
+		t.Errorf("expected the rules to be kept after a failed update, got %q", got)
+	}
+
+	if err := redirects.Update([]middleware.RedirectRule{{From: "/a", To: "/c"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := redirects.Lookup("/a"); got != "/c" {
+		t.Errorf("expected /c after the update, got %q", got)
+	}
+}
+
+func TestParseRedirects(t *testing.T) {
+	rules, err := middleware.ParseRedirects(strings.NewReader(`
+# legacy gallery URLs
+/g/1    /galleries/1;
+/g/:id  /galleries/:id
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []middleware.RedirectRule{{From: "/g/1", To: "/galleries/1"}, {From: "/g/:id", To: "/galleries/:id"}}
+	if len(rules) != len(want) {
+		t.Fatalf("expected %d rules, got %d", len(want), len(rules))
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("expected rule %d to be %+v, got %+v", i, want[i], rules[i])
+		}
+	}
+
+	if _, err := middleware.ParseRedirects(strings.NewReader("/g/1 /a /b\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected an error on line 1, got %v", err)
+	}
+}
+
+func TestLanguageRedirectRedirects(t *testing.T) {
+	redirects, err := middleware.NewRedirectMap([]middleware.RedirectRule{
+		{From: "/g/:id", To: "/galleries/:id"},
+		{From: "/promo", To: "/en/sale?ref=promo"},
+		{From: "/api/v0/:id", To: "/api/v1/:id"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := middleware.LanguageRedirectConfig{
+		Supported: []string{"en", "ja"},
+		Reserved:  []string{"api"},
+		Redirects: redirects,
+	}
+	router := gin.New()
+	router.NoRoute(func(c *gin.Context) {
+		if middleware.HandleLanguageRedirect(c, cfg) {
+			return
+		}
+		c.String(http.StatusOK, "page")
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantLoc    string
+	}{
+		{"legacy path", "GET", "/g/42?page=2", http.StatusMovedPermanently, "/galleries/42?page=2"},
+		{"language prefix kept", "GET", "/ja/g/42", http.StatusMovedPermanently, "/ja/galleries/42"},
+		{"target query merged", "GET", "/promo?utm=x", http.StatusMovedPermanently, "/en/sale?ref=promo&utm=x"},
+		{"reserved prefix", "POST", "/api/v0/7", http.StatusPermanentRedirect, "/api/v1/7"},
+		{"language redirect", "GET", "/galleries/42", http.StatusFound, "/en/galleries/42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.target, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if loc := w.Header().Get("Location"); loc != tt.wantLoc {
+				t.Errorf("expected Location %q, got %q", tt.wantLoc, loc)
+			}
+		})
+	}
+}
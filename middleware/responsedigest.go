@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/clock"
+	"github.com/doujins-org/ginapi/webhook"
+)
+
+// ResponseSignatureHeader carries the HMAC signature of a response, in the
+// webhook.SignatureHeader format: "t=<unix seconds>,v1=<hex HMAC-SHA256>".
+const ResponseSignatureHeader = "X-Response-Signature"
+
+// ResponseDigestConfig configures response digests.
+type ResponseDigestConfig struct {
+	// Secret signs responses in ResponseSignatureHeader; without one only
+	// the digests are sent
+	Secret []byte
+	// MaxBodyBytes is the largest response buffered to digest; larger and
+	// flushed responses are streamed without digests (defaults to 8MiB)
+	MaxBodyBytes int
+	// Clock timestamps signatures (defaults to clock.System)
+	Clock clock.Clock
+}
+
+// ResponseDigest returns middleware adding a SHA-256 digest of the
+// response body, as Content-Digest (RFC 9530) and Digest (RFC 3230), and
+// with a Secret an HMAC signature, so server-to-server consumers can
+// verify responses that pass through caching proxies they don't control:
+//
+//	internal := router.Group("/internal", middleware.ResponseDigest(middleware.ResponseDigestConfig{
+//	    Secret: cfg.ResponseSigningKey,
+//	}))
+//
+// The signature covers the request method and URI as well as the body, so
+// a proxy can't serve one URL's response for another; consumers check it
+// with VerifyResponseSignature. The body is buffered until the handlers
+// finish. Responses to HEAD, 204s, and 304s carry no body and get no
+// digest.
+func ResponseDigest(cfg ResponseDigestConfig) gin.HandlerFunc {
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 8 << 20
+	}
+	clk := clock.Or(cfg.Clock)
+
+	return func(c *gin.Context) {
+		w := &digestWriter{ResponseWriter: c.Writer, max: cfg.MaxBodyBytes}
+		c.Writer = w
+		defer func() { c.Writer = w.ResponseWriter }()
+		c.Next()
+
+		if w.streamed {
+			return
+		}
+		body := w.buf.Bytes()
+		h := w.Header()
+		status := w.Status()
+		if c.Request.Method != http.MethodHead && status != http.StatusNoContent && status != http.StatusNotModified {
+			sum := sha256.Sum256(body)
+			digest := base64.StdEncoding.EncodeToString(sum[:])
+			h.Set("Content-Digest", "sha-256=:"+digest+":")
+			h.Set("Digest", "SHA-256="+digest)
+		}
+		if cfg.Secret != nil {
+			h.Set(ResponseSignatureHeader, webhook.Sign(cfg.Secret, signedResponse(c.Request, body), clk.Now()))
+		}
+		if len(body) == 0 {
+			w.ResponseWriter.WriteHeaderNow()
+			return
+		}
+		_, _ = w.ResponseWriter.Write(body)
+	}
+}
+
+// VerifyResponseSignature checks the ResponseSignatureHeader of resp
+// against body, its raw body, for the request resp answers. The signature
+// must be made with one of secrets and be no older than tolerance
+// (webhook.DefaultTolerance if 0); set it to at least the response's
+// cache lifetime when it may be served from a cache. It returns
+// webhook.ErrInvalidSignature otherwise.
+func VerifyResponseSignature(resp *http.Response, body []byte, tolerance time.Duration, secrets ...[]byte) error {
+	if resp.Request == nil {
+		return webhook.ErrInvalidSignature
+	}
+	return webhook.Verify(signedResponse(resp.Request, body), resp.Header.Get(ResponseSignatureHeader), time.Now(), tolerance, secrets...)
+}
+
+// signedResponse is the payload signed for a response to r:
+// "<method> <request URI>\n<body>".
+func signedResponse(r *http.Request, body []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(r.Method + " " + r.URL.RequestURI() + "\n")
+	b.Write(body)
+	return b.Bytes()
+}
+
+// digestWriter holds back the response body until it can be digested,
+// streaming it instead once it grows past max or is flushed.
+type digestWriter struct {
+	gin.ResponseWriter
+	buf      bytes.Buffer
+	max      int
+	written  bool
+	streamed bool
+}
+
+func (w *digestWriter) Write(b []byte) (int, error) {
+	w.written = true
+	if !w.streamed && w.buf.Len()+len(b) > w.max {
+		w.stream()
+	}
+	if w.streamed {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+func (w *digestWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *digestWriter) WriteHeaderNow() {
+	w.written = true
+	if w.streamed {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *digestWriter) Written() bool {
+	return w.written || w.ResponseWriter.Written()
+}
+
+func (w *digestWriter) Size() int {
+	if w.streamed || !w.written {
+		return w.ResponseWriter.Size()
+	}
+	return w.buf.Len()
+}
+
+func (w *digestWriter) Flush() {
+	w.stream()
+	w.ResponseWriter.Flush()
+}
+
+// stream gives up on the digest, writing what is buffered and passing
+// later writes through.
+func (w *digestWriter) stream() {
+	if w.streamed {
+		return
+	}
+	w.streamed = true
+	w.ResponseWriter.WriteHeaderNow()
+	if w.buf.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf = bytes.Buffer{}
+	}
+}
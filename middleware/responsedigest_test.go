@@ -0,0 +1,90 @@
+package middleware_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+	"github.com/doujins-org/ginapi/webhook"
+)
+
+func TestResponseDigest(t *testing.T) {
+	secret := []byte("s3cret")
+	router := gin.New()
+	router.Use(middleware.ResponseDigest(middleware.ResponseDigestConfig{Secret: secret, MaxBodyBytes: 64}))
+	router.GET("/galleries/:id", func(c *gin.Context) {
+		response.Object(c, gin.H{"object": "gallery", "id": c.Param("id")})
+	})
+	router.HEAD("/galleries/:id", func(c *gin.Context) {
+		response.Object(c, gin.H{"object": "gallery", "id": c.Param("id")})
+	})
+	router.GET("/large", func(c *gin.Context) {
+		c.String(http.StatusOK, strings.Repeat("x", 100))
+	})
+	router.DELETE("/galleries/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		wantDigest    bool
+		wantSignature bool
+	}{
+		{name: "digest and signature", method: "GET", path: "/galleries/gal_1", wantDigest: true, wantSignature: true},
+		{name: "head", method: "HEAD", path: "/galleries/gal_1", wantSignature: true},
+		{name: "no content", method: "DELETE", path: "/galleries/gal_1", wantSignature: true},
+		{name: "too large to buffer", method: "GET", path: "/large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			resp := w.Result()
+			resp.Request = req
+			body := w.Body.Bytes()
+
+			if tt.method == "GET" && len(body) == 0 {
+				t.Fatalf("expected a body")
+			}
+			sum := sha256.Sum256(body)
+			digest := base64.StdEncoding.EncodeToString(sum[:])
+			wantContentDigest := ""
+			if tt.wantDigest {
+				wantContentDigest = "sha-256=:" + digest + ":"
+				if got := resp.Header.Get("Digest"); got != "SHA-256="+digest {
+					t.Errorf("expected Digest SHA-256=%s, got %q", digest, got)
+				}
+			}
+			if got := resp.Header.Get("Content-Digest"); got != wantContentDigest {
+				t.Errorf("expected Content-Digest %q, got %q", wantContentDigest, got)
+			}
+
+			err := middleware.VerifyResponseSignature(resp, body, 0, secret)
+			if tt.wantSignature != (err == nil) {
+				t.Fatalf("expected signature valid %v, got %v", tt.wantSignature, err)
+			}
+			if !tt.wantSignature {
+				return
+			}
+			if err := middleware.VerifyResponseSignature(resp, append(body, ' '), 0, secret); !errors.Is(err, webhook.ErrInvalidSignature) {
+				t.Errorf("expected a tampered body to fail, got %v", err)
+			}
+			resp.Request = httptest.NewRequest(tt.method, "/galleries/gal_2", nil)
+			if err := middleware.VerifyResponseSignature(resp, body, 0, secret); !errors.Is(err, webhook.ErrInvalidSignature) {
+				t.Errorf("expected another URL to fail, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serverTimingKey is the gin context key of the request's spans.
+const serverTimingKey = "ginapi.server_timing"
+
+// TimingSpan is a timed phase of a request, such as a database query.
+type TimingSpan struct {
+	Name  string
+	Start time.Time
+	// Duration is zero while the span is open
+	Duration time.Duration
+	// Open is true until the span's end function is called
+	Open bool
+}
+
+// serverTiming holds the spans of a request. Spans may be ended from
+// other goroutines.
+type serverTiming struct {
+	mu    sync.Mutex
+	spans []TimingSpan
+}
+
+// StartSpan starts timing a phase of the request and returns the function
+// ending it:
+//
+//	end := middleware.StartSpan(c, "db")
+//	galleries, err := repo.List(ctx, filter)
+//	end()
+//
+// ServerTiming sends the spans in the Server-Timing header, and Timeout
+// names the span that used up the request's budget. Calling end again
+// does nothing.
+func StartSpan(c *gin.Context, name string) (end func()) {
+	st := getServerTiming(c)
+	st.mu.Lock()
+	i := len(st.spans)
+	st.spans = append(st.spans, TimingSpan{Name: name, Start: time.Now(), Open: true})
+	st.mu.Unlock()
+
+	return func() {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		if s := &st.spans[i]; s.Open {
+			s.Duration, s.Open = time.Since(s.Start), false
+		}
+	}
+}
+
+// Spans returns the spans started with StartSpan, in the order they were
+// started.
+func Spans(c *gin.Context) []TimingSpan {
+	v, ok := c.Get(serverTimingKey)
+	if !ok {
+		return nil
+	}
+	st := v.(*serverTiming)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return append([]TimingSpan(nil), st.spans...)
+}
+
+// getServerTiming returns the spans of the request, adding them if needed.
+func getServerTiming(c *gin.Context) *serverTiming {
+	if v, ok := c.Get(serverTimingKey); ok {
+		return v.(*serverTiming)
+	}
+	st := &serverTiming{}
+	c.Set(serverTimingKey, st)
+	return st
+}
+
+// ServerTiming returns middleware sending the spans of StartSpan that have
+// ended by the time the response headers are written in the Server-Timing
+// header, e.g. "db;dur=12.4, render;dur=1.2", so browser devtools and
+// clients show where the time went. The header reveals the names of the
+// phases; install it on internal routes, or only for staff, if those are
+// sensitive.
+func ServerTiming() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &serverTimingWriter{ResponseWriter: c.Writer, timing: getServerTiming(c)}
+		c.Writer = w
+		defer func() { c.Writer = w.ResponseWriter }()
+
+		c.Next()
+		// Bodyless responses are written by gin after the handlers return
+		w.writeHeader()
+	}
+}
+
+// serverTimingWriter adds the Server-Timing header just before the headers
+// are written.
+type serverTimingWriter struct {
+	gin.ResponseWriter
+	timing *serverTiming
+	done   bool
+}
+
+func (w *serverTimingWriter) WriteHeaderNow() {
+	w.writeHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *serverTimingWriter) Write(b []byte) (int, error) {
+	w.writeHeader()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *serverTimingWriter) WriteString(s string) (int, error) {
+	w.writeHeader()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *serverTimingWriter) Flush() {
+	w.writeHeader()
+	w.ResponseWriter.Flush()
+}
+
+// writeHeader sets the header once, unless the headers are already
+// written.
+func (w *serverTimingWriter) writeHeader() {
+	if w.done || w.Written() {
+		return
+	}
+	w.done = true
+	w.timing.mu.Lock()
+	defer w.timing.mu.Unlock()
+	var parts []string
+	for _, s := range w.timing.spans {
+		if !s.Open {
+			parts = append(parts, s.Name+";dur="+strconv.FormatFloat(float64(s.Duration.Microseconds())/1000, 'f', -1, 64))
+		}
+	}
+	if len(parts) > 0 {
+		w.Header().Add("Server-Timing", strings.Join(parts, ", "))
+	}
+}
@@ -0,0 +1,54 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestServerTiming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.ServerTiming())
+	router.GET("/galleries", func(c *gin.Context) {
+		end := middleware.StartSpan(c, "db")
+		end()
+		end()
+		middleware.StartSpan(c, "open")
+		middleware.StartSpan(c, "cache")()
+		c.String(http.StatusOK, "ok")
+	})
+	router.GET("/empty", func(c *gin.Context) {
+		middleware.StartSpan(c, "db")()
+		c.Status(http.StatusNoContent)
+	})
+	router.GET("/none", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		path string
+		want string // pattern of the header
+	}{
+		{"/galleries", `^db;dur=[0-9.]+, cache;dur=[0-9.]+$`},
+		{"/empty", `^db;dur=[0-9.]+$`},
+		{"/none", `^$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if got := w.Header().Get("Server-Timing"); !regexp.MustCompile(tt.want).MatchString(got) {
+				t.Errorf("expected Server-Timing matching %s, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShadowConfig configures shadow traffic.
+type ShadowConfig struct {
+	// Name identifies the comparison in mismatch reports (required)
+	Name string
+	// Percent of requests mirrored to Handler, from 0 to 100
+	Percent float64
+	// Handler serves the mirrored requests, e.g. the rewritten endpoint or
+	// a proxy.Handler to the new backend (required). Its response is
+	// discarded after the comparison.
+	Handler gin.HandlerFunc
+	// Methods mirrored (defaults to GET and HEAD, which have no side
+	// effects to duplicate)
+	Methods []string
+	// Ignore lists volatile JSON fields left out of the comparison, as
+	// dotted paths where "*" matches any key or index, e.g. "meta.took_ms"
+	// or "data.*.score"
+	Ignore []string
+	// MaxBodyBytes is the largest request or response body compared;
+	// larger exchanges aren't mirrored (defaults to 1MB)
+	MaxBodyBytes int
+	// Timeout for Handler (defaults to 5s)
+	Timeout time.Duration
+	// MaxInFlight caps concurrent mirrored requests; samples past it are
+	// dropped (defaults to 16)
+	MaxInFlight int
+	// OnMismatch is called for each mirrored request whose response
+	// differs (defaults to logging "shadow mismatch" with slog.Default()
+	// at warn). r is a copy of the request.
+	OnMismatch func(r *http.Request, m ShadowMismatch)
+}
+
+// ShadowMismatch describes how a shadow response differed.
+type ShadowMismatch struct {
+	// Name of the comparison
+	Name string
+	// Route is the matched route pattern
+	Route string
+	// PrimaryStatus and ShadowStatus are the status codes of both responses
+	PrimaryStatus int
+	ShadowStatus  int
+	// Fields are the differing JSON paths, e.g. "data.3.id" (at most 10),
+	// or empty when the status or a non-JSON body differs
+	Fields []string
+}
+
+// maxMismatchFields is the most differing fields a ShadowMismatch lists.
+const maxMismatchFields = 10
+
+// Shadow returns middleware that mirrors a sample of a route's requests to
+// a secondary handler and reports responses that differ, for validating a
+// rewrite against production traffic before sending it any:
+//
+//	search.GET("", middleware.Shadow(middleware.ShadowConfig{
+//	    Name:    "search-v2",
+//	    Percent: 10,
+//	    Handler: proxy.Handler("http://search-v2:8080", proxy.Options{}),
+//	    Ignore:  []string{"meta.took_ms", "data.*.score"},
+//	}), searchGalleries)
+//
+// The client always gets the primary response. Mirrored requests run in
+// the background once it is written, with a copy of the request (path
+// params and context keys included) and a context that outlives it. JSON
+// bodies are compared as values, so key order and formatting don't count;
+// other bodies must match exactly. It panics if Name or Handler is
+// missing or Percent is outside [0, 100].
+func Shadow(cfg ShadowConfig) gin.HandlerFunc {
+	return NewShadowTraffic(cfg).Middleware()
+}
+
+// ShadowTraffic is the mirroring behind Shadow, with a Wait for mirrored
+// requests in flight.
+type ShadowTraffic struct {
+	cfg     ShadowConfig
+	methods map[string]bool
+	ignore  [][]string
+	slots   chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewShadowTraffic returns a ShadowTraffic for cfg.
+func NewShadowTraffic(cfg ShadowConfig) *ShadowTraffic {
+	if cfg.Name == "" || cfg.Handler == nil {
+		panic("middleware: ShadowConfig requires a Name and Handler")
+	}
+	if cfg.Percent < 0 || cfg.Percent > 100 || math.IsNaN(cfg.Percent) {
+		panic(fmt.Sprintf("middleware: shadow percent %v must be between 0 and 100", cfg.Percent))
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{http.MethodGet, http.MethodHead}
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 1 << 20
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = 16
+	}
+	if cfg.OnMismatch == nil {
+		cfg.OnMismatch = logMismatch
+	}
+
+	s := &ShadowTraffic{
+		cfg:     cfg,
+		methods: make(map[string]bool, len(cfg.Methods)),
+		slots:   make(chan struct{}, cfg.MaxInFlight),
+	}
+	for _, m := range cfg.Methods {
+		s.methods[strings.ToUpper(m)] = true
+	}
+	for _, path := range cfg.Ignore {
+		s.ignore = append(s.ignore, strings.Split(path, "."))
+	}
+	return s
+}
+
+// Wait blocks until the mirrored requests in flight are compared, e.g.
+// during graceful shutdown.
+func (s *ShadowTraffic) Wait() {
+	s.wg.Wait()
+}
+
+// Middleware returns the gin middleware; see Shadow.
+func (s *ShadowTraffic) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.methods[c.Request.Method] || rand.Float64()*100 >= s.cfg.Percent {
+			c.Next()
+			return
+		}
+		body, ok := s.readBody(c)
+		if !ok {
+			c.Next()
+			return
+		}
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			c.Next()
+			return
+		}
+
+		w := &captureWriter{ResponseWriter: c.Writer, max: s.cfg.MaxBodyBytes}
+		c.Writer = w
+		// Copy before the handlers run, so the mirror sees the request as
+		// the primary did
+		mirror := c.Copy()
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.overflow {
+			<-s.slots
+			return
+		}
+		primary := capturedResponse{status: w.Status(), header: w.Header().Clone(), body: w.buf.Bytes()}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() { <-s.slots }()
+			s.compare(mirror, body, primary)
+		}()
+	}
+}
+
+// readBody buffers the request body and restores it, reporting false if
+// it is too large to mirror.
+func (s *ShadowTraffic) readBody(c *gin.Context) ([]byte, bool) {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return nil, true
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, int64(s.cfg.MaxBodyBytes)+1))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil || len(body) > s.cfg.MaxBodyBytes {
+		return nil, false
+	}
+	return body, true
+}
+
+// compare runs the mirrored request and reports a mismatch with primary.
+func (s *ShadowTraffic) compare(mirror *gin.Context, body []byte, primary capturedResponse) {
+	defer func() {
+		if v := recover(); v != nil {
+			slog.Error("shadow handler panicked", slog.String("shadow", s.cfg.Name), slog.Any("panic", v))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(mirror.Request.Context()), s.cfg.Timeout)
+	defer cancel()
+	req := mirror.Request.Clone(ctx)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = req
+	c.Params = mirror.Params
+	c.Keys = mirror.Keys
+	s.cfg.Handler(c)
+	c.Writer.WriteHeaderNow()
+
+	m := ShadowMismatch{
+		Name:          s.cfg.Name,
+		Route:         mirror.FullPath(),
+		PrimaryStatus: primary.status,
+		ShadowStatus:  rec.Code,
+	}
+	fields, same := s.diff(primary.header, primary.body, rec.Header(), rec.Body.Bytes())
+	if same && m.PrimaryStatus == m.ShadowStatus {
+		return
+	}
+	m.Fields = fields
+	s.cfg.OnMismatch(req, m)
+}
+
+// diff compares two response bodies, as JSON values when both are JSON,
+// returning the differing fields.
+func (s *ShadowTraffic) diff(h1 http.Header, b1 []byte, h2 http.Header, b2 []byte) ([]string, bool) {
+	v1, ok1 := s.decode(h1, b1)
+	v2, ok2 := s.decode(h2, b2)
+	if !ok1 || !ok2 {
+		return nil, bytes.Equal(b1, b2)
+	}
+	var fields []string
+	diffValues("", v1, v2, &fields)
+	return fields, len(fields) == 0
+}
+
+// decode parses a JSON body and removes the ignored fields.
+func (s *ShadowTraffic) decode(h http.Header, body []byte) (any, bool) {
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	for _, path := range s.ignore {
+		v = removePath(v, path)
+	}
+	return v, true
+}
+
+// removePath returns v without the field at path.
+func removePath(v any, path []string) any {
+	if len(path) == 0 {
+		return nil
+	}
+	key, rest := path[0], path[1:]
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if key != "*" && k != key {
+				continue
+			}
+			if len(rest) == 0 {
+				delete(v, k)
+			} else {
+				v[k] = removePath(child, rest)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			if key != "*" && key != strconv.Itoa(i) {
+				continue
+			}
+			if len(rest) == 0 {
+				v[i] = nil
+			} else {
+				v[i] = removePath(child, rest)
+			}
+		}
+	}
+	return v
+}
+
+// diffValues appends the paths where a and b differ to fields.
+func diffValues(path string, a, b any, fields *[]string) {
+	if len(*fields) >= maxMismatchFields {
+		return
+	}
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(a)+len(b))
+		for k := range a {
+			keys = append(keys, k)
+		}
+		for k := range b {
+			if _, ok := a[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			diffValues(join(k), a[k], b[k], fields)
+		}
+		return
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			break
+		}
+		for i := range a {
+			diffValues(join(strconv.Itoa(i)), a[i], b[i], fields)
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		if path == "" {
+			path = "."
+		}
+		*fields = append(*fields, path)
+	}
+}
+
+// logMismatch is the default OnMismatch.
+func logMismatch(r *http.Request, m ShadowMismatch) {
+	slog.LogAttrs(r.Context(), slog.LevelWarn, "shadow mismatch",
+		slog.String("shadow", m.Name),
+		slog.String("route", m.Route),
+		slog.Int("primary_status", m.PrimaryStatus),
+		slog.Int("shadow_status", m.ShadowStatus),
+		slog.Any("fields", m.Fields),
+	)
+}
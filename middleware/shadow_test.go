@@ -0,0 +1,103 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestShadow(t *testing.T) {
+	var mu sync.Mutex
+	var mismatches []middleware.ShadowMismatch
+	shadow := middleware.NewShadowTraffic(middleware.ShadowConfig{
+		Name:    "search-v2",
+		Percent: 100,
+		Handler: func(c *gin.Context) {
+			switch c.Query("q") {
+			case "status":
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "boom"})
+			case "text":
+				c.String(http.StatusOK, "v2")
+			case "different":
+				c.JSON(http.StatusOK, gin.H{"data": []gin.H{{"id": c.Param("kind"), "score": 1}, {"id": "b"}}, "meta": gin.H{"took_ms": 9}})
+			default:
+				// Same values, other key order and volatile fields
+				c.Data(http.StatusOK, "application/json", []byte(`{"meta":{"took_ms":9},"data":[{"score":1,"id":"`+c.Param("kind")+`"},{"id":"a","score":2}]}`))
+			}
+		},
+		Ignore: []string{"meta.took_ms", "data.*.score"},
+		OnMismatch: func(r *http.Request, m middleware.ShadowMismatch) {
+			mu.Lock()
+			mismatches = append(mismatches, m)
+			mu.Unlock()
+		},
+	})
+
+	router := gin.New()
+	router.Any("/search/:kind", shadow.Middleware(), func(c *gin.Context) {
+		if c.Query("q") == "text" {
+			c.String(http.StatusOK, "v1")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": []gin.H{{"id": c.Param("kind"), "score": 5}, {"id": "a", "score": 3}}, "meta": gin.H{"took_ms": 120}})
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		wantStatus int
+		wantFields []string
+		wantReport bool
+	}{
+		{"same values", http.MethodGet, "", 0, nil, false},
+		{"different field", http.MethodGet, "different", http.StatusOK, []string{"data.1.id"}, true},
+		{"different status", http.MethodGet, "status", http.StatusInternalServerError, []string{"data", "error", "meta"}, true},
+		{"different text", http.MethodGet, "text", http.StatusOK, nil, true},
+		{"method not mirrored", http.MethodPost, "different", 0, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mismatches = nil
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, "/search/galleries?q="+tt.query, nil))
+			shadow.Wait()
+
+			if w.Code != http.StatusOK {
+				t.Errorf("expected the primary response, got %d", w.Code)
+			}
+			if !tt.wantReport {
+				if len(mismatches) != 0 {
+					t.Errorf("expected no mismatch, got %+v", mismatches)
+				}
+				return
+			}
+			if len(mismatches) != 1 {
+				t.Fatalf("expected 1 mismatch, got %+v", mismatches)
+			}
+			m := mismatches[0]
+			if m.Name != "search-v2" || m.Route != "/search/:kind" || m.PrimaryStatus != http.StatusOK || m.ShadowStatus != tt.wantStatus {
+				t.Errorf("expected a mismatch for search-v2 on /search/:kind, got %+v", m)
+			}
+			if !slices.Equal(m.Fields, tt.wantFields) {
+				t.Errorf("expected fields %v, got %v", tt.wantFields, m.Fields)
+			}
+		})
+	}
+}
+
+func TestShadowRequiresHandler(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic without a Handler")
+		}
+	}()
+	middleware.Shadow(middleware.ShadowConfig{Name: "search-v2", Percent: 10})
+}
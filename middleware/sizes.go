@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// SizeConfig configures a SizeTracker.
+type SizeConfig struct {
+	// Budget is the p99 response size, in bytes, routes should stay under;
+	// 0 means no budget
+	Budget int64
+	// Routes overrides Budget per route pattern, e.g. a larger budget for
+	// an export endpoint
+	Routes map[string]int64
+	// Samples is how many recent requests per route percentiles are
+	// computed over (defaults to 1000)
+	Samples int
+}
+
+// RouteSizes is the distribution of a route's recent body sizes.
+type RouteSizes struct {
+	Object   string         `json:"object"` // Always "route_sizes"
+	Method   string         `json:"method"`
+	Route    string         `json:"route"`
+	Samples  int            `json:"samples"`
+	Request  SizePercentile `json:"request"`
+	Response SizePercentile `json:"response"`
+	// Budget is the route's p99 response size budget in bytes, 0 without
+	// one
+	Budget int64 `json:"budget,omitempty"`
+	// OverBudget is set when the p99 response is larger than Budget
+	OverBudget bool `json:"over_budget"`
+}
+
+// SizePercentile is a distribution of body sizes, in bytes.
+type SizePercentile struct {
+	P50 int64 `json:"p50"`
+	P90 int64 `json:"p90"`
+	P99 int64 `json:"p99"`
+	Max int64 `json:"max"`
+}
+
+// NewSizeTracker returns a tracker of the request and response body sizes
+// of each route's recent requests, flagging routes whose p99 response is
+// over their byte budget, to find the payloads worth slimming down:
+//
+//	sizes := middleware.NewSizeTracker(middleware.SizeConfig{
+//	    Budget: 64 << 10,
+//	    Routes: map[string]int64{"/api/export": 4 << 20},
+//	})
+//	router.Use(sizes.Middleware())
+//	admin.GET("/sizes", sizes.Handler())
+//	...
+//	for _, r := range sizes.OverBudget() {
+//	    slog.Warn("response over size budget", "route", r.Route, "p99", r.Response.P99)
+//	}
+//
+// Sizes are kept in memory per process; Metrics sends them to a metrics
+// backend for percentiles across instances. Unmatched routes aren't
+// tracked. It panics if a budget is negative.
+func NewSizeTracker(cfg SizeConfig) *SizeTracker {
+	if cfg.Budget < 0 {
+		panic("middleware: SizeConfig.Budget must not be negative")
+	}
+	for route, budget := range cfg.Routes {
+		if budget < 0 {
+			panic("middleware: the size budget of " + route + " must not be negative")
+		}
+	}
+	if cfg.Samples <= 0 {
+		cfg.Samples = 1000
+	}
+	return &SizeTracker{cfg: cfg, routes: map[string]*routeSizes{}}
+}
+
+// SizeTracker tracks the body sizes of routes; see NewSizeTracker.
+type SizeTracker struct {
+	cfg SizeConfig
+
+	mu     sync.Mutex
+	routes map[string]*routeSizes // "METHOD /path" -> sizes
+}
+
+// routeSizes is a ring of the sizes of a route's recent requests.
+type routeSizes struct {
+	method, route       string
+	requests, responses []int64
+	next                int // index the next request is recorded at
+}
+
+// Middleware returns the middleware recording body sizes.
+func (t *SizeTracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestSize := countRequestBody(c)
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		t.record(c.Request.Method, route, requestSize(), responseSize(c))
+	}
+}
+
+// record adds a request's sizes to its route.
+func (t *SizeTracker) record(method, route string, request, response int64) {
+	key := method + " " + route
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.routes[key]
+	if !ok {
+		r = &routeSizes{method: method, route: route}
+		t.routes[key] = r
+	}
+	if len(r.requests) < t.cfg.Samples {
+		r.requests = append(r.requests, request)
+		r.responses = append(r.responses, response)
+		return
+	}
+	r.requests[r.next] = request
+	r.responses[r.next] = response
+	r.next = (r.next + 1) % t.cfg.Samples
+}
+
+// Sizes returns the size distributions of the routes requested so far,
+// by route and method.
+func (t *SizeTracker) Sizes() []RouteSizes {
+	t.mu.Lock()
+	sizes := make([]RouteSizes, 0, len(t.routes))
+	for _, r := range t.routes {
+		s := RouteSizes{
+			Object:   "route_sizes",
+			Method:   r.method,
+			Route:    r.route,
+			Samples:  len(r.responses),
+			Request:  percentiles(r.requests),
+			Response: percentiles(r.responses),
+			Budget:   t.budget(r.route),
+		}
+		s.OverBudget = s.Budget > 0 && s.Response.P99 > s.Budget
+		sizes = append(sizes, s)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].Route != sizes[j].Route {
+			return sizes[i].Route < sizes[j].Route
+		}
+		return sizes[i].Method < sizes[j].Method
+	})
+	return sizes
+}
+
+// OverBudget returns the routes whose p99 response is over their budget,
+// largest first.
+func (t *SizeTracker) OverBudget() []RouteSizes {
+	var over []RouteSizes
+	for _, s := range t.Sizes() {
+		if s.OverBudget {
+			over = append(over, s)
+		}
+	}
+	sort.SliceStable(over, func(i, j int) bool { return over[i].Response.P99 > over[j].Response.P99 })
+	return over
+}
+
+// budget returns the response size budget of route.
+func (t *SizeTracker) budget(route string) int64 {
+	if budget, ok := t.cfg.Routes[route]; ok {
+		return budget
+	}
+	return t.cfg.Budget
+}
+
+// percentiles returns the distribution of sizes, by the nearest-rank
+// method.
+func percentiles(sizes []int64) SizePercentile {
+	if len(sizes) == 0 {
+		return SizePercentile{}
+	}
+	sorted := slices.Clone(sizes)
+	slices.Sort(sorted)
+	rank := func(p float64) int64 {
+		i := int(math.Ceil(p*float64(len(sorted))-1e-9)) - 1
+		return sorted[max(i, 0)]
+	}
+	return SizePercentile{P50: rank(0.50), P90: rank(0.90), P99: rank(0.99), Max: sorted[len(sorted)-1]}
+}
+
+// Handler returns a handler listing Sizes. Mount it behind admin auth.
+func (t *SizeTracker) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sizes := t.Sizes()
+		response.ListResponse(c, sizes, int64(len(sizes)), len(sizes), 0)
+	}
+}
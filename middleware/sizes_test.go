@@ -0,0 +1,113 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestSizeTracker(t *testing.T) {
+	sizes := middleware.NewSizeTracker(middleware.SizeConfig{
+		Budget: 1000,
+		Routes: map[string]int64{"/export": 1 << 20},
+	})
+	router := gin.New()
+	router.Use(sizes.Middleware())
+	router.GET("/galleries", func(c *gin.Context) {
+		n, _ := strconv.Atoi(c.Query("n"))
+		c.String(http.StatusOK, strings.Repeat("x", n))
+	})
+	router.POST("/galleries", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	router.GET("/export", func(c *gin.Context) { c.String(http.StatusOK, strings.Repeat("x", 5000)) })
+
+	// 98 small lists and 2 large ones: the p99 is large
+	for i := range 100 {
+		n := 100
+		if i >= 98 {
+			n = 2000
+		}
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/galleries?n="+strconv.Itoa(n), nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/galleries", strings.NewReader(`{"title":"x"}`)))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/export", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unmatched", nil))
+
+	tests := []struct {
+		method       string
+		route        string
+		wantSamples  int
+		wantRequest  middleware.SizePercentile
+		wantResponse middleware.SizePercentile
+		wantBudget   int64
+		wantOver     bool
+	}{
+		{http.MethodGet, "/export", 1, middleware.SizePercentile{}, middleware.SizePercentile{P50: 5000, P90: 5000, P99: 5000, Max: 5000}, 1 << 20, false},
+		{http.MethodGet, "/galleries", 100, middleware.SizePercentile{}, middleware.SizePercentile{P50: 100, P90: 100, P99: 2000, Max: 2000}, 1000, true},
+		{http.MethodPost, "/galleries", 1, middleware.SizePercentile{P50: 13, P90: 13, P99: 13, Max: 13}, middleware.SizePercentile{}, 1000, false},
+	}
+	got := sizes.Sizes()
+	if len(got) != len(tests) {
+		t.Fatalf("expected %d routes, got %+v", len(tests), got)
+	}
+	for i, tt := range tests {
+		t.Run(tt.method+" "+tt.route, func(t *testing.T) {
+			s := got[i]
+			if s.Method != tt.method || s.Route != tt.route || s.Samples != tt.wantSamples {
+				t.Fatalf("expected %s %s with %d samples, got %s %s with %d", tt.method, tt.route, tt.wantSamples, s.Method, s.Route, s.Samples)
+			}
+			if s.Request != tt.wantRequest {
+				t.Errorf("expected request sizes %+v, got %+v", tt.wantRequest, s.Request)
+			}
+			if s.Response != tt.wantResponse {
+				t.Errorf("expected response sizes %+v, got %+v", tt.wantResponse, s.Response)
+			}
+			if s.Budget != tt.wantBudget || s.OverBudget != tt.wantOver {
+				t.Errorf("expected budget %d (over %v), got %d (over %v)", tt.wantBudget, tt.wantOver, s.Budget, s.OverBudget)
+			}
+		})
+	}
+
+	over := sizes.OverBudget()
+	if len(over) != 1 || over[0].Route != "/galleries" || over[0].Method != http.MethodGet {
+		t.Errorf("expected GET /galleries over budget, got %+v", over)
+	}
+}
+
+func TestSizeTrackerSamples(t *testing.T) {
+	sizes := middleware.NewSizeTracker(middleware.SizeConfig{Samples: 10})
+	router := gin.New()
+	router.Use(sizes.Middleware())
+	router.GET("/galleries", func(c *gin.Context) {
+		n, _ := strconv.Atoi(c.Query("n"))
+		c.String(http.StatusOK, strings.Repeat("x", n))
+	})
+	router.GET("/admin/sizes", sizes.Handler())
+
+	// Only the last 10 requests count
+	for _, n := range []int{9000, 10, 10, 10, 10, 10, 10, 10, 10, 10, 20} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/galleries?n="+strconv.Itoa(n), nil))
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/sizes", nil))
+	var result struct {
+		Data []middleware.RouteSizes `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Data) != 1 {
+		t.Fatalf("expected one route, got %+v", result.Data)
+	}
+	want := middleware.SizePercentile{P50: 10, P90: 10, P99: 20, Max: 20}
+	if got := result.Data[0]; got.Samples != 10 || got.Response != want || got.OverBudget {
+		t.Errorf("expected 10 samples with %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,347 @@
+package middleware
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/clock"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// SLO is the service level objective of a route.
+type SLO struct {
+	// Availability is the target fraction of requests without a 5xx, e.g.
+	// 0.999. Routes without an Availability or Latency aren't tracked.
+	Availability float64
+	// Latency is the threshold a request must finish within to count as
+	// fast; 0 means no latency objective
+	Latency time.Duration
+	// LatencyTarget is the target fraction of fast requests, e.g. 0.99
+	// (defaults to 0.99 when Latency is set)
+	LatencyTarget float64
+}
+
+// SLOConfig configures an SLOTracker.
+type SLOConfig struct {
+	// Routes maps route patterns to objectives. A key ending in "*"
+	// matches every route with that prefix, e.g. "/api/*"; exact keys win,
+	// then the longest prefix.
+	Routes map[string]SLO
+	// Default applies to routes without an objective
+	Default SLO
+	// Window is the period the error budget covers (defaults to 24h).
+	// Counts are kept in memory, so they restart with the process.
+	Window time.Duration
+	// BurnWindows are the windows burn rates are computed over (defaults
+	// to 5m, 1h, and 6h). They are rounded up to Window/1440.
+	BurnWindows []time.Duration
+	// Clock defaults to clock.System
+	Clock clock.Clock
+}
+
+// SLOStatus is the current compliance of a route with its objective.
+type SLOStatus struct {
+	Object string `json:"object"` // Always "slo_status"
+	Route  string `json:"route"`
+	// Requests counted over the window
+	Requests     int64               `json:"requests"`
+	Availability SLOObjectiveStatus  `json:"availability"`
+	Latency      *SLOObjectiveStatus `json:"latency,omitempty"`
+}
+
+// SLOObjectiveStatus is the compliance with one objective.
+type SLOObjectiveStatus struct {
+	// Target fraction of good requests
+	Target float64 `json:"target"`
+	// Actual fraction of good requests over the window (1 without traffic)
+	Actual float64 `json:"actual"`
+	// BudgetRemaining is the fraction of the error budget left over the
+	// window; negative once it is overspent
+	BudgetRemaining float64 `json:"budget_remaining"`
+	// BurnRates maps each burn window, e.g. "1h", to how fast the budget
+	// is being spent there: at 1 it lasts exactly the window, at 10 a tenth
+	// of it
+	BurnRates map[string]float64 `json:"burn_rates"`
+	// ThresholdMS is the latency threshold, for the latency objective
+	ThresholdMS int64 `json:"threshold_ms,omitempty"`
+}
+
+// sloBuckets is the number of buckets a window is counted in.
+const sloBuckets = 1440
+
+// NewSLOTracker returns a tracker computing per-route availability and
+// latency compliance, error budgets, and burn rates from the requests its
+// Middleware sees, so on-call can see which routes are burning budget
+// without a metrics pipeline:
+//
+//	slo := middleware.NewSLOTracker(middleware.SLOConfig{
+//	    Routes: map[string]middleware.SLO{
+//	        "/api/galleries/:id": {Availability: 0.999, Latency: 300 * time.Millisecond},
+//	        "/api/search":        {Availability: 0.99, Latency: time.Second, LatencyTarget: 0.95},
+//	    },
+//	    Default: middleware.SLO{Availability: 0.995},
+//	})
+//	router.Use(slo.Middleware())
+//	admin.GET("/slo", slo.Handler())
+//
+// Requests answered with a 5xx count against availability, and requests
+// slower than the threshold against latency. Unmatched routes aren't
+// tracked. It panics if a target is outside [0, 1).
+func NewSLOTracker(cfg SLOConfig) *SLOTracker {
+	if cfg.Window <= 0 {
+		cfg.Window = 24 * time.Hour
+	}
+	if len(cfg.BurnWindows) == 0 {
+		cfg.BurnWindows = []time.Duration{5 * time.Minute, time.Hour, 6 * time.Hour}
+	}
+	t := &SLOTracker{
+		clock:    clock.Or(cfg.Clock),
+		width:    max(cfg.Window/sloBuckets, time.Nanosecond),
+		exact:    map[string]SLO{},
+		fallback: checkSLO("Default", cfg.Default),
+		routes:   map[string]*sloRoute{},
+	}
+	for _, w := range cfg.BurnWindows {
+		if w <= 0 || w > cfg.Window {
+			panic(fmt.Sprintf("middleware: SLO burn window %v must be positive and within the window %v", w, cfg.Window))
+		}
+		t.burn = append(t.burn, sloBurnWindow{name: shortDuration(w), buckets: int((w + t.width - 1) / t.width)})
+	}
+	for pattern, slo := range cfg.Routes {
+		slo = checkSLO(pattern, slo)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			t.prefixes = append(t.prefixes, sloPrefix{prefix, slo})
+		} else {
+			t.exact[pattern] = slo
+		}
+	}
+	sort.Slice(t.prefixes, func(i, j int) bool { return len(t.prefixes[i].prefix) > len(t.prefixes[j].prefix) })
+	return t
+}
+
+// checkSLO validates slo and applies its defaults.
+func checkSLO(name string, slo SLO) SLO {
+	if slo.Availability < 0 || slo.Availability >= 1 || slo.LatencyTarget < 0 || slo.LatencyTarget >= 1 {
+		panic("middleware: SLO targets for " + name + " must be in [0, 1)")
+	}
+	if slo.Latency > 0 && slo.LatencyTarget == 0 {
+		slo.LatencyTarget = 0.99
+	}
+	return slo
+}
+
+// SLOTracker tracks routes against their objectives; see NewSLOTracker.
+type SLOTracker struct {
+	clock    clock.Clock
+	width    time.Duration // of a bucket
+	burn     []sloBurnWindow
+	exact    map[string]SLO
+	prefixes []sloPrefix // longest first
+	fallback SLO
+
+	mu     sync.RWMutex
+	routes map[string]*sloRoute
+}
+
+// sloPrefix is the objective of a "prefix*" pattern.
+type sloPrefix struct {
+	prefix string
+	slo    SLO
+}
+
+// sloBurnWindow is a burn window, in buckets.
+type sloBurnWindow struct {
+	name    string
+	buckets int
+}
+
+// sloRoute counts the requests of one route in a ring of buckets.
+type sloRoute struct {
+	slo     SLO
+	mu      sync.Mutex
+	buckets [sloBuckets]sloBucket
+}
+
+// sloBucket counts the requests of one bucket-wide interval.
+type sloBucket struct {
+	index  int64 // of the interval, since the Unix epoch
+	total  int64
+	errors int64
+	slow   int64
+}
+
+// Middleware returns the middleware recording requests; install it early
+// so the latency covers the whole chain.
+func (t *SLOTracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := t.clock.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		r := t.route(route)
+		if r == nil {
+			return
+		}
+		now := t.clock.Now()
+		slow := r.slo.Latency > 0 && now.Sub(start) > r.slo.Latency
+		r.record(t.bucket(now), c.Writer.Status() >= 500, slow)
+	}
+}
+
+// route returns the counters of route, or nil if it has no objective.
+func (t *SLOTracker) route(route string) *sloRoute {
+	t.mu.RLock()
+	r, ok := t.routes[route]
+	t.mu.RUnlock()
+	if ok {
+		return r
+	}
+
+	slo := t.lookup(route)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if r, ok := t.routes[route]; ok {
+		return r
+	}
+	if slo.Availability > 0 || slo.Latency > 0 {
+		r = &sloRoute{slo: slo}
+	}
+	t.routes[route] = r
+	return r
+}
+
+// lookup returns the objective for a route pattern.
+func (t *SLOTracker) lookup(route string) SLO {
+	if slo, ok := t.exact[route]; ok {
+		return slo
+	}
+	for _, p := range t.prefixes {
+		if strings.HasPrefix(route, p.prefix) {
+			return p.slo
+		}
+	}
+	return t.fallback
+}
+
+// bucket returns the index of the interval containing now.
+func (t *SLOTracker) bucket(now time.Time) int64 {
+	return now.UnixNano() / int64(t.width)
+}
+
+func (r *sloRoute) record(index int64, failed, slow bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := &r.buckets[index%sloBuckets]
+	if b.index != index {
+		*b = sloBucket{index: index}
+	}
+	b.total++
+	if failed {
+		b.errors++
+	}
+	if slow {
+		b.slow++
+	}
+}
+
+// sum returns the counts of the n intervals up to index.
+func (r *sloRoute) sum(index int64, n int) sloBucket {
+	var s sloBucket
+	for i := int64(0); i < int64(n); i++ {
+		b := r.buckets[(index-i)%sloBuckets]
+		if b.index == index-i {
+			s.total += b.total
+			s.errors += b.errors
+			s.slow += b.slow
+		}
+	}
+	return s
+}
+
+// Status returns the compliance of every tracked route that has had
+// requests, sorted by route.
+func (t *SLOTracker) Status() []SLOStatus {
+	index := t.bucket(t.clock.Now())
+	t.mu.RLock()
+	routes := make(map[string]*sloRoute, len(t.routes))
+	for route, r := range t.routes {
+		if r != nil {
+			routes[route] = r
+		}
+	}
+	t.mu.RUnlock()
+
+	statuses := make([]SLOStatus, 0, len(routes))
+	for route, r := range routes {
+		statuses = append(statuses, t.status(route, r, index))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Route < statuses[j].Route })
+	return statuses
+}
+
+// status computes the status of r at the interval index.
+func (t *SLOTracker) status(route string, r *sloRoute, index int64) SLOStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total := r.sum(index, sloBuckets)
+	s := SLOStatus{Object: "slo_status", Route: route, Requests: total.total}
+
+	objective := func(target float64, bad func(sloBucket) int64) SLOObjectiveStatus {
+		o := SLOObjectiveStatus{
+			Target:          target,
+			Actual:          1 - ratio(bad(total), total.total),
+			BudgetRemaining: 1 - ratio(bad(total), total.total)/(1-target),
+			BurnRates:       make(map[string]float64, len(t.burn)),
+		}
+		for _, w := range t.burn {
+			b := r.sum(index, w.buckets)
+			o.BurnRates[w.name] = ratio(bad(b), b.total) / (1 - target)
+		}
+		return o
+	}
+	s.Availability = objective(r.slo.Availability, func(b sloBucket) int64 { return b.errors })
+	if r.slo.Latency > 0 {
+		latency := objective(r.slo.LatencyTarget, func(b sloBucket) int64 { return b.slow })
+		latency.ThresholdMS = r.slo.Latency.Milliseconds()
+		s.Latency = &latency
+	}
+	return s
+}
+
+// ratio returns bad/total, or 0 without requests.
+func ratio(bad, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(bad) / float64(total)
+}
+
+// Handler returns a handler listing Status, for an on-call view of which
+// routes are burning budget. Mount it behind admin auth.
+func (t *SLOTracker) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		statuses := t.Status()
+		response.ListResponse(c, statuses, int64(len(statuses)), len(statuses), 0)
+	}
+}
+
+// shortDuration formats d compactly for burn window names, e.g. "5m",
+// "1h", or "1h30m".
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
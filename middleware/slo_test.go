@@ -0,0 +1,120 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/apitest"
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestSLOTracker(t *testing.T) {
+	clk := apitest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	slo := middleware.NewSLOTracker(middleware.SLOConfig{
+		Routes: map[string]middleware.SLO{
+			"/galleries/:id": {Availability: 0.9, Latency: 100 * time.Millisecond, LatencyTarget: 0.5},
+			"/internal/*":    {},
+		},
+		Default:     middleware.SLO{Availability: 0.99},
+		BurnWindows: []time.Duration{5 * time.Minute, time.Hour},
+		Clock:       clk,
+	})
+
+	router := gin.New()
+	router.Use(slo.Middleware())
+	router.GET("/galleries/:id", func(c *gin.Context) {
+		switch c.Param("id") {
+		case "fail":
+			c.Status(http.StatusInternalServerError)
+		case "slow":
+			clk.Advance(200 * time.Millisecond)
+		}
+	})
+	router.GET("/tags", func(c *gin.Context) {})
+	router.GET("/internal/health", func(c *gin.Context) {})
+	router.GET("/slo", slo.Handler())
+
+	get := func(path string) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// An hour ago: 10 good requests, outside the 5m window
+	for range 10 {
+		get("/galleries/ok")
+	}
+	clk.Advance(time.Hour - time.Minute)
+	// Now: 1 failure and 1 slow out of 4 requests
+	get("/galleries/ok")
+	get("/galleries/fail")
+	get("/galleries/slow")
+	get("/galleries/ok")
+	get("/tags")
+	get("/internal/health")
+	get("/missing")
+
+	statuses := slo.Status()
+	if len(statuses) != 2 || statuses[0].Route != "/galleries/:id" || statuses[1].Route != "/tags" {
+		t.Fatalf("expected /galleries/:id and /tags, got %+v", statuses)
+	}
+
+	g := statuses[0]
+	if g.Requests != 14 {
+		t.Errorf("expected 14 requests, got %d", g.Requests)
+	}
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"availability actual", g.Availability.Actual, 13.0 / 14},
+		{"availability budget", g.Availability.BudgetRemaining, 1 - (1.0/14)/0.1},
+		{"availability 5m burn", g.Availability.BurnRates["5m"], (1.0 / 4) / 0.1},
+		{"availability 1h burn", g.Availability.BurnRates["1h"], (1.0 / 14) / 0.1},
+		{"latency actual", g.Latency.Actual, 13.0 / 14},
+		{"latency 5m burn", g.Latency.BurnRates["5m"], (1.0 / 4) / 0.5},
+		{"tags budget", statuses[1].Availability.BudgetRemaining, 1},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, tt.got)
+		}
+	}
+	if g.Latency.ThresholdMS != 100 || statuses[1].Latency != nil {
+		t.Errorf("expected a latency objective on /galleries/:id only, got %+v, %+v", g.Latency, statuses[1].Latency)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slo", nil))
+	var list struct {
+		Data []middleware.SLOStatus `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list.Data) != 2 || list.Data[0].Object != "slo_status" {
+		t.Errorf("expected 2 statuses, got %s", w.Body)
+	}
+}
+
+func TestSLOTrackerPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  middleware.SLOConfig
+	}{
+		{"availability of 1", middleware.SLOConfig{Default: middleware.SLO{Availability: 1}}},
+		{"negative latency target", middleware.SLOConfig{Routes: map[string]middleware.SLO{"/x": {LatencyTarget: -0.1}}}},
+		{"burn window past window", middleware.SLOConfig{Window: time.Hour, BurnWindows: []time.Duration{2 * time.Hour}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic")
+				}
+			}()
+			middleware.NewSLOTracker(tt.cfg)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// StrictMode controls how the getters in this package (GetLanguage,
+// GetClientInfo, GetPriority, and the *FromContext variants) react to
+// misuse: a nil context, or a value their middleware never set. By default
+// they quietly return a fallback such as "en", which hides bugs like a
+// route group missing the Language middleware and serving the wrong
+// language.
+type StrictMode int32
+
+const (
+	// StrictOff returns the documented fallback silently (the default).
+	StrictOff StrictMode = iota
+	// StrictLog returns the fallback and logs the misuse at warn level,
+	// once per call site. Use it in production.
+	StrictLog
+	// StrictPanic panics on misuse. Use it in development and tests.
+	StrictPanic
+)
+
+var strictMode atomic.Int32
+
+// SetStrictMode sets how misuse is handled. Call it once at startup:
+//
+//	if cfg.Env == "development" {
+//	    middleware.SetStrictMode(middleware.StrictPanic)
+//	} else {
+//	    middleware.SetStrictMode(middleware.StrictLog)
+//	}
+func SetStrictMode(mode StrictMode) {
+	strictMode.Store(int32(mode))
+}
+
+// loggedMisuse records the call sites already logged by StrictLog.
+var loggedMisuse sync.Map
+
+// misuse reports that helper was called incorrectly, according to the
+// StrictMode. The reported call site is the caller of helper.
+func misuse(helper, problem string) {
+	mode := StrictMode(strictMode.Load())
+	if mode == StrictOff {
+		return
+	}
+
+	site := "unknown"
+	if _, file, line, ok := runtime.Caller(2); ok {
+		site = fmt.Sprintf("%s:%d", file, line)
+	}
+	msg := fmt.Sprintf("middleware: %s called %s at %s", helper, problem, site)
+
+	if mode == StrictPanic {
+		panic(msg)
+	}
+	if _, logged := loggedMisuse.LoadOrStore(site+" "+helper, struct{}{}); !logged {
+		slog.Warn(msg)
+	}
+}
@@ -0,0 +1,86 @@
+package middleware_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func TestStrictModePanic(t *testing.T) {
+	middleware.SetStrictMode(middleware.StrictPanic)
+	defer middleware.SetStrictMode(middleware.StrictOff)
+
+	bare, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	tests := []struct {
+		name  string
+		call  func()
+		panic string
+	}{
+		{"GetLanguage nil", func() { middleware.GetLanguage(nil) }, "GetLanguage called with a nil context"},
+		{"GetLanguage without middleware", func() { middleware.GetLanguage(bare) }, "GetLanguage called without the Language middleware"},
+		{"GetClientInfo without middleware", func() { middleware.GetClientInfo(bare) }, "GetClientInfo called without the ClientInfo middleware"},
+		{"GetPriority nil", func() { middleware.GetPriority(nil) }, "GetPriority called with a nil context"},
+		{"LanguageFromContext nil", func() { middleware.LanguageFromContext(nil) }, "LanguageFromContext called with a nil context"},
+		{"ResolveLanguage nil", func() { middleware.ResolveLanguage(nil, middleware.LanguageConfig{}) }, "ResolveLanguage called with a nil request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.Contains(msg, tt.panic) || !strings.Contains(msg, "strict_test.go:") {
+					t.Errorf("expected panic %q at the call site, got %q", tt.panic, msg)
+				}
+			}()
+			tt.call()
+		})
+	}
+}
+
+func TestStrictModeAllowsCorrectUse(t *testing.T) {
+	middleware.SetStrictMode(middleware.StrictPanic)
+	defer middleware.SetStrictMode(middleware.StrictOff)
+
+	router := gin.New()
+	router.Use(middleware.Language(middleware.LanguageConfig{Supported: []string{"en", "ja"}}))
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, middleware.GetLanguage(c))
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test?lang=ja", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Body.String() != "ja" {
+		t.Errorf("expected 'ja', got %q", w.Body.String())
+	}
+}
+
+func TestStrictModeLog(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(prev)
+
+	middleware.SetStrictMode(middleware.StrictLog)
+	defer middleware.SetStrictMode(middleware.StrictOff)
+
+	bare, _ := gin.CreateTestContext(httptest.NewRecorder())
+	for i := 0; i < 3; i++ {
+		if lang := middleware.GetLanguage(bare); lang != "en" {
+			t.Errorf("expected fallback 'en', got %q", lang)
+		}
+	}
+
+	if n := strings.Count(buf.String(), "GetLanguage called without the Language middleware"); n != 1 {
+		t.Errorf("expected 1 log line for the call site, got %d:\n%s", n, buf.String())
+	}
+}
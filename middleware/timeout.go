@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// TimeoutConfig configures Timeout.
+type TimeoutConfig struct {
+	// Timeout is the budget of a request (required)
+	Timeout time.Duration
+	// Logger receives a warning per timed-out request (defaults to
+	// slog.Default())
+	Logger *slog.Logger
+}
+
+// Timeout returns middleware giving each request a time budget: the
+// request context's deadline is set, and a request still running past it
+// gets a 504 with code timeout instead of whatever error the handler makes
+// of the canceled context, usually a 500. Handlers must pass the context
+// on for the deadline to stop their work:
+//
+//	api.Use(middleware.Timeout(middleware.TimeoutConfig{Timeout: 5 * time.Second}))
+//
+// Each timeout is logged with the phase that used up the budget, from
+// the spans of StartSpan: the innermost span still open at the deadline,
+// or else the longest one. Responses the handler had started before the
+// deadline are left alone.
+func Timeout(cfg TimeoutConfig) gin.HandlerFunc {
+	if cfg.Timeout <= 0 {
+		panic("middleware: Timeout requires Timeout > 0")
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), cfg.Timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		w := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx}
+		c.Writer = w
+
+		c.Next()
+
+		c.Writer = w.ResponseWriter
+		if ctx.Err() != context.DeadlineExceeded || (w.Written() && !w.dropped) {
+			return
+		}
+		phase, spent := budgetPhase(Spans(c))
+		logger.Warn("request timed out",
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"timeout", cfg.Timeout,
+			"phase", phase,
+			"phase_ms", spent.Milliseconds(),
+		)
+		if !w.Written() {
+			response.ErrorWithInfo(c, http.StatusGatewayTimeout, response.ErrorInfo{
+				Type:    response.ErrorTypeAPI,
+				Code:    response.ErrorCodeTimeout,
+				Message: "request timed out",
+			})
+		}
+	}
+}
+
+// budgetPhase returns the span that used up the budget and its time so
+// far: the last started of the open spans, which is the innermost, or the
+// longest span if all have ended. It is "handler" without spans.
+func budgetPhase(spans []TimingSpan) (string, time.Duration) {
+	for i := len(spans) - 1; i >= 0; i-- {
+		if spans[i].Open {
+			return spans[i].Name, time.Since(spans[i].Start)
+		}
+	}
+	phase, spent := "handler", time.Duration(0)
+	for _, s := range spans {
+		if s.Duration > spent {
+			phase, spent = s.Name, s.Duration
+		}
+	}
+	return phase, spent
+}
+
+// timeoutWriter drops the server error a handler writes after the
+// deadline, so Timeout can send its 504 instead.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx     context.Context
+	dropped bool
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	if !w.drop() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	if w.drop() {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	if w.drop() {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *timeoutWriter) Flush() {
+	if !w.drop() {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// drop reports whether the response is a server error started after the
+// deadline.
+func (w *timeoutWriter) drop() bool {
+	if !w.dropped && !w.ResponseWriter.Written() && w.Status() >= 500 && w.ctx.Err() == context.DeadlineExceeded {
+		w.dropped = true
+	}
+	return w.dropped
+}
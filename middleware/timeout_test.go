@@ -0,0 +1,104 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	router := gin.New()
+	router.Use(middleware.Timeout(middleware.TimeoutConfig{Timeout: 20 * time.Millisecond, Logger: logger}))
+	// waitDB waits for the deadline inside a "db" span
+	waitDB := func(c *gin.Context) {
+		defer middleware.StartSpan(c, "render")()
+		end := middleware.StartSpan(c, "db")
+		<-c.Request.Context().Done()
+		end()
+	}
+	router.GET("/slow", func(c *gin.Context) {
+		end := middleware.StartSpan(c, "auth")
+		end()
+		end = middleware.StartSpan(c, "db")
+		<-c.Request.Context().Done()
+	})
+	router.GET("/slow-error", func(c *gin.Context) {
+		waitDB(c)
+		response.InternalError(c, c.Request.Context().Err().Error())
+	})
+	router.GET("/no-spans", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	})
+	router.GET("/fast", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	router.GET("/started", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		<-c.Request.Context().Done()
+	})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantPhase  string // empty for no log
+	}{
+		{"/slow", http.StatusGatewayTimeout, "db"},
+		{"/slow-error", http.StatusGatewayTimeout, "render"},
+		{"/no-spans", http.StatusGatewayTimeout, "handler"},
+		{"/fast", http.StatusOK, ""},
+		{"/started", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			buf.Reset()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusGatewayTimeout {
+				var body response.Error
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("expected an error envelope, got %s", w.Body.String())
+				}
+				if body.Error.Code != response.ErrorCodeTimeout {
+					t.Errorf("expected code %s, got %s", response.ErrorCodeTimeout, body.Error.Code)
+				}
+			}
+
+			if tt.wantPhase == "" {
+				if buf.Len() > 0 {
+					t.Errorf("expected no log, got %s", buf.String())
+				}
+				return
+			}
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("expected a log entry, got %q", buf.String())
+			}
+			if entry["phase"] != tt.wantPhase {
+				t.Errorf("expected phase %s, got %v", tt.wantPhase, entry["phase"])
+			}
+			if !strings.Contains(entry["msg"].(string), "timed out") {
+				t.Errorf("expected a timeout message, got %v", entry["msg"])
+			}
+		})
+	}
+}
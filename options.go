@@ -0,0 +1,90 @@
+package ginapi
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Capabilities describes what a path accepts, as answered to OPTIONS by
+// HandleOptions.
+type Capabilities struct {
+	Object  string             `json:"object"` // Always "capabilities"
+	Path    string             `json:"path"`
+	Methods []MethodCapability `json:"methods"`
+}
+
+// MethodCapability is what one method of a path accepts, from its route
+// metadata.
+type MethodCapability struct {
+	Method       string   `json:"method"`
+	Summary      string   `json:"summary,omitempty"`
+	Scopes       []string `json:"scopes"`
+	ContentTypes []string `json:"content_types,omitempty"`
+	MaxBodyBytes int64    `json:"max_body_bytes,omitempty"`
+	MaxLimit     int      `json:"max_limit,omitempty"`
+	Deprecated   bool     `json:"deprecated,omitempty"`
+}
+
+// HandleOptions registers an OPTIONS handler for every path of engine that
+// doesn't have one, answering with an Allow header and the path's
+// Capabilities, from the metadata declared with Handle and Route. API
+// explorers and SDKs use it to discover what a route accepts before
+// calling it. Call it after registering the routes:
+//
+//	ginapi.HandleOptions(router)
+//
+// CORS preflights are OPTIONS requests too; install CORS middleware
+// globally so it answers them first.
+func HandleOptions(engine *gin.Engine) {
+	paths := map[string][]RouteInfo{}
+	var order []string
+	for _, r := range Routes(engine) {
+		if _, ok := paths[r.Path]; !ok {
+			order = append(order, r.Path)
+		}
+		paths[r.Path] = append(paths[r.Path], r)
+	}
+
+	for _, path := range order {
+		routes := paths[path]
+		if hasMethod(routes, http.MethodOptions) {
+			continue
+		}
+		caps := Capabilities{Object: "capabilities", Path: path, Methods: make([]MethodCapability, 0, len(routes))}
+		allow := []string{http.MethodOptions}
+		for _, r := range routes {
+			allow = append(allow, r.Method)
+			caps.Methods = append(caps.Methods, MethodCapability{
+				Method:       r.Method,
+				Summary:      r.Summary,
+				Scopes:       r.Scopes,
+				ContentTypes: r.ContentTypes,
+				MaxBodyBytes: r.MaxBodyBytes,
+				MaxLimit:     r.MaxLimit,
+				Deprecated:   r.Deprecated,
+			})
+		}
+		sort.Strings(allow)
+		allowHeader := strings.Join(allow, ", ")
+
+		engine.OPTIONS(path, func(c *gin.Context) {
+			c.Header("Allow", allowHeader)
+			response.Object(c, caps)
+		})
+	}
+}
+
+// hasMethod reports whether routes has one for method.
+func hasMethod(routes []RouteInfo, method string) bool {
+	for _, r := range routes {
+		if r.Method == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package ginapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+)
+
+func TestHandleOptions(t *testing.T) {
+	router := gin.New()
+	router.GET("/options/galleries/:id", ginapi.Route(searchGalleries, ginapi.Meta{Summary: "Get a gallery"}))
+	ginapi.Handle(router, http.MethodPatch, "/options/galleries/:id", ginapi.RouteMeta{
+		Scopes:       []string{"galleries:write"},
+		ContentTypes: []string{"application/json"},
+		MaxBodyBytes: 1 << 20,
+	}, func(c *gin.Context) {})
+	router.OPTIONS("/options/tags", func(c *gin.Context) { c.Status(http.StatusTeapot) })
+	router.GET("/options/tags", func(c *gin.Context) {})
+	ginapi.HandleOptions(router)
+
+	tests := []struct {
+		name        string
+		path        string
+		wantStatus  int
+		wantAllow   string
+		wantMethods []string
+	}{
+		{name: "capabilities", path: "/options/galleries/gal_1", wantStatus: 200,
+			wantAllow: "GET, OPTIONS, PATCH", wantMethods: []string{"GET", "PATCH"}},
+		{name: "existing options handler", path: "/options/tags", wantStatus: http.StatusTeapot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantAllow == "" {
+				return
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("expected Allow %q, got %q", tt.wantAllow, got)
+			}
+			var caps ginapi.Capabilities
+			if err := json.Unmarshal(w.Body.Bytes(), &caps); err != nil {
+				t.Fatalf("expected capabilities, got %s", w.Body)
+			}
+			if caps.Object != "capabilities" || caps.Path != "/options/galleries/:id" || len(caps.Methods) != len(tt.wantMethods) {
+				t.Fatalf("unexpected capabilities %+v", caps)
+			}
+			for i, m := range caps.Methods {
+				if m.Method != tt.wantMethods[i] {
+					t.Errorf("expected method %s, got %s", tt.wantMethods[i], m.Method)
+				}
+			}
+			get, patch := caps.Methods[0], caps.Methods[1]
+			if get.Summary != "Get a gallery" || len(get.Scopes) != 0 {
+				t.Errorf("unexpected GET capability %+v", get)
+			}
+			if len(patch.Scopes) != 1 || len(patch.ContentTypes) != 1 || patch.MaxBodyBytes != 1<<20 {
+				t.Errorf("unexpected PATCH capability %+v", patch)
+			}
+		})
+	}
+}
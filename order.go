@@ -0,0 +1,165 @@
+package ginapi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/tenant"
+)
+
+// OrderRule requires middleware matching Before to run before middleware
+// matching After in every chain that contains both. Names are function
+// names as reported by Routes; a name also matches the closures declared
+// inside that function, so the name of a middleware constructor matches
+// the handler it returns. Use FuncName to get them:
+//
+//	ginapi.AddOrderRules(ginapi.OrderRule{
+//	    Before: ginapi.FuncName(mw.RealIP),
+//	    After:  ginapi.FuncName((*middleware.RateLimiter).Middleware),
+//	    Reason: "RateLimit keys on the client IP",
+//	})
+type OrderRule struct {
+	Before string
+	After  string
+	// Reason is included in the error, to explain the constraint
+	Reason string
+}
+
+// DefaultOrderRules are the constraints between this module's middleware.
+// They are always checked. Constraints involving middleware from outside
+// the module, such as a RealIP before RateLimit, are added by the
+// application with AddOrderRules.
+var DefaultOrderRules = []OrderRule{
+	{
+		Before: FuncName(middleware.PriorityClassifier),
+		After:  FuncName(middleware.ConcurrencyLimit),
+		Reason: "ConcurrencyLimit admits requests by GetPriority",
+	},
+	{
+		Before: FuncName(middleware.PriorityClassifier),
+		After:  FuncName(middleware.PrincipalConcurrencyLimit),
+		Reason: "PrincipalConcurrencyLimit lets PriorityCritical requests bypass it",
+	},
+	{
+		Before: FuncName(tenant.Middleware),
+		After:  FuncName((*middleware.LanguageDetector).Middleware),
+		Reason: "LanguageDetector resolves LanguageConfig.Tenants by the request's tenant",
+	},
+	{
+		Before: FuncName((*middleware.LanguageDetector).Middleware),
+		After:  FuncName(middleware.Coalesce),
+		Reason: "Coalesce keys requests on GetLanguage",
+	},
+	{
+		Before: FuncName((*middleware.Challenger).Verify),
+		After:  FuncName((*middleware.RateLimiter).Middleware),
+		Reason: "RateLimit lets requests through on ChallengePassed",
+	},
+	{
+		Before: FuncName(middleware.Timeout),
+		After:  FuncName(middleware.Coalesce),
+		Reason: "Coalesce waiters only stop waiting at the deadline Timeout sets",
+	},
+	{
+		Before: FuncName(middleware.ServerTiming),
+		After:  FuncName(middleware.Coalesce),
+		Reason: "Coalesce waiters don't run the middleware after it, so they would get no Server-Timing",
+	},
+}
+
+var (
+	orderMu    sync.RWMutex
+	orderRules []OrderRule
+)
+
+// AddOrderRules adds constraints checked by Handle and CheckOrder, e.g.
+// that Language runs before handlers calling GetLanguage. Call it at
+// startup, before registering routes.
+func AddOrderRules(rules ...OrderRule) {
+	orderMu.Lock()
+	orderRules = append(orderRules, rules...)
+	orderMu.Unlock()
+}
+
+// FuncName returns the name of the function f, for OrderRule.
+func FuncName(f any) string {
+	name := runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// CheckOrder checks every route of engine against DefaultOrderRules and the
+// rules added with AddOrderRules, returning all violations. Routes
+// registered through Handle are already checked at registration; this
+// catches the rest, using the engine's global middleware for routes
+// registered directly on gin.
+func CheckOrder(engine *gin.Engine) error {
+	var errs []error
+	for _, r := range Routes(engine) {
+		chain := append(append([]string{}, r.Middleware...), r.Handler)
+		errs = append(errs, checkChain(r.Method, r.Path, chain)...)
+	}
+	return errors.Join(errs...)
+}
+
+// checkChain returns an error for every rule the chain violates.
+func checkChain(method, path string, chain []string) []error {
+	orderMu.RLock()
+	rules := append(append([]OrderRule{}, DefaultOrderRules...), orderRules...)
+	orderMu.RUnlock()
+
+	var errs []error
+	for _, rule := range rules {
+		before := lastMatch(chain, rule.Before)
+		after := firstMatch(chain, rule.After)
+		if before < 0 || after < 0 || before < after {
+			continue
+		}
+		msg := fmt.Sprintf("ginapi: %s %s: %s must run before %s", method, path, shortName(rule.Before), shortName(rule.After))
+		if rule.Reason != "" {
+			msg += " (" + rule.Reason + ")"
+		}
+		errs = append(errs, errors.New(msg))
+	}
+	return errs
+}
+
+// firstMatch returns the index of the first handler matching name, or -1.
+func firstMatch(chain []string, name string) int {
+	for i, h := range chain {
+		if matchesName(h, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// lastMatch returns the index of the last handler matching name, or -1.
+func lastMatch(chain []string, name string) int {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if matchesName(chain[i], name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchesName reports whether handler is the function name or a closure
+// declared inside it.
+func matchesName(handler, name string) bool {
+	return name != "" && (handler == name || strings.HasPrefix(handler, name+"."))
+}
+
+// shortName strips the import path from a function name.
+func shortName(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
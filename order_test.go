@@ -0,0 +1,95 @@
+package ginapi_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+func realIP() gin.HandlerFunc { return func(c *gin.Context) { c.Next() } }
+
+func rateLimit() gin.HandlerFunc { return func(c *gin.Context) { c.Next() } }
+
+func TestHandleOrder(t *testing.T) {
+	language := middleware.Language(middleware.LanguageConfig{Supported: []string{"en"}})
+	coalesce := middleware.Coalesce(middleware.CoalesceConfig{})
+	timeout := middleware.Timeout(middleware.TimeoutConfig{Timeout: time.Second})
+
+	tests := []struct {
+		name      string
+		chain     []gin.HandlerFunc
+		wantPanic string
+	}{
+		{"correct order", []gin.HandlerFunc{language, coalesce}, ""},
+		{"only one side", []gin.HandlerFunc{coalesce}, ""},
+		{"wrong order", []gin.HandlerFunc{coalesce, language}, "middleware.(*LanguageDetector).Middleware must run before middleware.Coalesce"},
+		{"timeout after coalesce", []gin.HandlerFunc{language, coalesce, timeout}, "middleware.Timeout must run before middleware.Coalesce"},
+		{"server timing after coalesce", []gin.HandlerFunc{language, coalesce, middleware.ServerTiming()}, "middleware.ServerTiming must run before middleware.Coalesce"},
+		{"timing stack", []gin.HandlerFunc{middleware.ServerTiming(), timeout, language, coalesce}, ""},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			group := router.Group("/order", tt.chain...)
+			path := "/" + string(rune('a'+i))
+
+			var got string
+			func() {
+				defer func() {
+					if v := recover(); v != nil {
+						got = v.(error).Error()
+					}
+				}()
+				ginapi.Handle(group, http.MethodGet, path, ginapi.RouteMeta{}, func(c *gin.Context) {})
+			}()
+
+			if tt.wantPanic == "" && got != "" {
+				t.Errorf("expected no panic, got %q", got)
+			}
+			if tt.wantPanic != "" && !strings.Contains(got, tt.wantPanic) {
+				t.Errorf("expected panic containing %q, got %q", tt.wantPanic, got)
+			}
+		})
+	}
+}
+
+func TestCheckOrder(t *testing.T) {
+	ginapi.AddOrderRules(ginapi.OrderRule{
+		Before: ginapi.FuncName(realIP),
+		After:  ginapi.FuncName(rateLimit),
+		Reason: "rateLimit keys on the client IP",
+	})
+
+	router := gin.New()
+	router.Use(middleware.ConcurrencyLimit(middleware.ConcurrencyLimitConfig{MaxInFlight: 1}))
+	router.Use(middleware.PriorityClassifier(middleware.PriorityConfig{}))
+	router.Use(rateLimit(), realIP())
+	router.GET("/check-order", func(c *gin.Context) {})
+
+	err := ginapi.CheckOrder(router)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"GET /check-order: middleware.PriorityClassifier must run before middleware.ConcurrencyLimit",
+		"ginapi_test.realIP must run before ginapi_test.rateLimit (rateLimit keys on the client IP)",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %q", want, err.Error())
+		}
+	}
+
+	ok := gin.New()
+	ok.Use(realIP(), rateLimit())
+	ok.GET("/check-order", func(c *gin.Context) {})
+	if err := ginapi.CheckOrder(ok); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package pagination
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/tenant"
+)
+
+// Limits are the page size limits of a route.
+type Limits struct {
+	Default int `json:"default_limit"`
+	Max     int `json:"max_limit"`
+}
+
+var (
+	limitsMu     sync.RWMutex
+	routeLimits  = map[string]Limits{} // route pattern -> limits
+	tenantLimits func(t tenant.Tenant) (Limits, bool)
+)
+
+// SetRouteLimits registers the page size limits of a route pattern (as
+// gin reports it from c.FullPath(), e.g. "/api/search"). Call it at
+// startup, next to the route registration:
+//
+//	pagination.SetRouteLimits("/api/search", 20, 50)
+//	pagination.SetRouteLimits("/admin/export", 100, 1000)
+//
+// BindDefault then uses them instead of the package defaults, and maxLimit
+// becomes a hard cap: BindWithDefaults and BindSearchAfter clamp their
+// limits to it, so no handler can page past it by passing a bigger number.
+// Registered limits show up in ginapi.Routes.
+func SetRouteLimits(route string, defaultLimit, maxLimit int) {
+	if maxLimit <= 0 || defaultLimit <= 0 || defaultLimit > maxLimit {
+		panic(fmt.Sprintf("pagination: invalid limits for %s: default %d, max %d", route, defaultLimit, maxLimit))
+	}
+	limitsMu.Lock()
+	routeLimits[route] = Limits{Default: defaultLimit, Max: maxLimit}
+	limitsMu.Unlock()
+}
+
+// RouteLimits returns the limits registered for a route pattern.
+func RouteLimits(route string) (Limits, bool) {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+	l, ok := routeLimits[route]
+	return l, ok
+}
+
+// AllRouteLimits returns every registered route pattern and its limits.
+func AllRouteLimits() map[string]Limits {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+	all := make(map[string]Limits, len(routeLimits))
+	for route, l := range routeLimits {
+		all[route] = l
+	}
+	return all
+}
+
+// SetTenantLimits resolves page size limits per tenant (see package
+// tenant), for white-label deployments whose list limits differ. Call it
+// once at startup:
+//
+//	pagination.SetTenantLimits(func(t tenant.Tenant) (pagination.Limits, bool) {
+//	    l, ok := plans[t.ID]
+//	    return l, ok
+//	})
+//
+// A tenant's Max caps the page size of its requests like a route's, on
+// top of any route limits, and BindDefault uses its limits instead of the
+// package defaults on routes without limits of their own. Requests
+// without a tenant, or for which fn returns false or invalid limits, keep
+// the defaults. fn is called on every request; nil removes it.
+func SetTenantLimits(fn func(t tenant.Tenant) (Limits, bool)) {
+	limitsMu.Lock()
+	tenantLimits = fn
+	limitsMu.Unlock()
+}
+
+// TenantLimits returns the limits of the tenant of ctx, which may be a
+// request context or a *gin.Context.
+func TenantLimits(ctx context.Context) (Limits, bool) {
+	limitsMu.RLock()
+	fn := tenantLimits
+	limitsMu.RUnlock()
+	if fn == nil {
+		return Limits{}, false
+	}
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return Limits{}, false
+	}
+	l, ok := fn(t)
+	if !ok || l.Max <= 0 || l.Default <= 0 || l.Default > l.Max {
+		return Limits{}, false
+	}
+	return l, true
+}
+
+// capLimits clamps defaultLimit and maxLimit to the hard cap of route.
+func capLimits(route string, defaultLimit, maxLimit int) (int, int) {
+	l, ok := RouteLimits(route)
+	if !ok {
+		return defaultLimit, maxLimit
+	}
+	maxLimit = min(maxLimit, l.Max)
+	return min(defaultLimit, maxLimit), maxLimit
+}
+
+// capRequestLimits clamps defaultLimit and maxLimit to the hard caps of a
+// net/http request's route and tenant.
+func capRequestLimits(r *http.Request, defaultLimit, maxLimit int) (int, int) {
+	defaultLimit, maxLimit = capLimits(requestRoute(r), defaultLimit, maxLimit)
+	return capTenantLimits(r.Context(), defaultLimit, maxLimit)
+}
+
+// capTenantLimits clamps defaultLimit and maxLimit to the hard cap of the
+// tenant of ctx.
+func capTenantLimits(ctx context.Context, defaultLimit, maxLimit int) (int, int) {
+	l, ok := TenantLimits(ctx)
+	if !ok {
+		return defaultLimit, maxLimit
+	}
+	maxLimit = min(maxLimit, l.Max)
+	return min(defaultLimit, maxLimit), maxLimit
+}
+
+// maxLimitKey is the gin context key for the request's page size cap.
+const maxLimitKey = "ginapi.max_limit"
+
+// SetMaxLimit caps the page size of the request at maxLimit, on top of any
+// route limits, for middleware that knows the route's cap, like the one
+// ginapi.Route installs for Meta.MaxLimit.
+func SetMaxLimit(c *gin.Context, maxLimit int) {
+	c.Set(maxLimitKey, maxLimit)
+}
+
+// capContextLimits clamps defaultLimit and maxLimit to the hard cap of the
+// request's route and tenant and the cap set with SetMaxLimit.
+func capContextLimits(c *gin.Context, defaultLimit, maxLimit int) (int, int) {
+	defaultLimit, maxLimit = capLimits(c.FullPath(), defaultLimit, maxLimit)
+	defaultLimit, maxLimit = capTenantLimits(c, defaultLimit, maxLimit)
+	if limit := c.GetInt(maxLimitKey); limit > 0 && limit < maxLimit {
+		maxLimit = limit
+		defaultLimit = min(defaultLimit, maxLimit)
+	}
+	return defaultLimit, maxLimit
+}
+
+// requestRoute returns the route pattern of a net/http request, from the
+// pattern ServeMux matched (without its method and host).
+func requestRoute(r *http.Request) string {
+	pattern := r.Pattern
+	if _, rest, ok := strings.Cut(pattern, " "); ok {
+		pattern = rest
+	}
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
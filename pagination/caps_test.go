@@ -0,0 +1,179 @@
+package pagination_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/pagination"
+	"github.com/doujins-org/ginapi/tenant"
+)
+
+func TestRouteLimits(t *testing.T) {
+	pagination.SetRouteLimits("/caps/search", 10, 50)
+	pagination.SetRouteLimits("/caps/export", 100, 1000)
+
+	tests := []struct {
+		name      string
+		route     string
+		query     string
+		bind      func(c *gin.Context) pagination.Params
+		wantLimit int
+	}{
+		{"registered default", "/caps/search", "", pagination.BindDefault, 10},
+		{"registered cap", "/caps/search", "?limit=80", pagination.BindDefault, 50},
+		{"cap above package max", "/caps/export", "?limit=800", pagination.BindDefault, 800},
+		{"unregistered", "/caps/other", "?limit=800", pagination.BindDefault, 100},
+		{
+			name:  "hard cap on explicit limits",
+			route: "/caps/search",
+			query: "?limit=500",
+			bind: func(c *gin.Context) pagination.Params {
+				return pagination.BindWithDefaults(c, 100, 1000)
+			},
+			wantLimit: 50,
+		},
+		{
+			name:  "default clamped to cap",
+			route: "/caps/search",
+			bind: func(c *gin.Context) pagination.Params {
+				return pagination.BindWithDefaults(c, 100, 1000)
+			},
+			wantLimit: 50,
+		},
+		{
+			name:  "request max limit",
+			route: "/caps/other",
+			query: "?limit=80",
+			bind: func(c *gin.Context) pagination.Params {
+				pagination.SetMaxLimit(c, 30)
+				return pagination.BindDefault(c)
+			},
+			wantLimit: 30,
+		},
+		{
+			name:  "default clamped to request max limit",
+			route: "/caps/search",
+			bind: func(c *gin.Context) pagination.Params {
+				pagination.SetMaxLimit(c, 5)
+				return pagination.BindDefault(c)
+			},
+			wantLimit: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got pagination.Params
+			router := gin.New()
+			router.GET(tt.route, func(c *gin.Context) { got = tt.bind(c) })
+
+			req := httptest.NewRequest(http.MethodGet, tt.route+tt.query, nil)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got.Limit != tt.wantLimit {
+				t.Errorf("expected limit %d, got %d", tt.wantLimit, got.Limit)
+			}
+		})
+	}
+}
+
+func TestRouteLimitsNetHTTP(t *testing.T) {
+	pagination.SetRouteLimits("/caps/nethttp", 5, 25)
+
+	var got pagination.Params
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /caps/nethttp", func(w http.ResponseWriter, r *http.Request) {
+		got = pagination.FromRequestWithDefaults(r, 20, 100)
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/caps/nethttp?limit=90", nil))
+
+	if got.Limit != 25 {
+		t.Errorf("expected limit 25, got %d", got.Limit)
+	}
+}
+
+func TestTenantLimits(t *testing.T) {
+	pagination.SetRouteLimits("/caps/tenant-search", 10, 50)
+	pagination.SetTenantLimits(func(t tenant.Tenant) (pagination.Limits, bool) {
+		switch t.ID {
+		case "small":
+			return pagination.Limits{Default: 5, Max: 20}, true
+		case "large":
+			return pagination.Limits{Default: 50, Max: 500}, true
+		case "invalid":
+			return pagination.Limits{Default: 50, Max: 10}, true
+		}
+		return pagination.Limits{}, false
+	})
+	t.Cleanup(func() { pagination.SetTenantLimits(nil) })
+
+	tests := []struct {
+		name      string
+		tenant    string
+		route     string
+		query     string
+		wantLimit int
+	}{
+		{"tenant default", "small", "/caps/tenant-other", "", 5},
+		{"tenant cap", "small", "/caps/tenant-other", "?limit=80", 20},
+		{"tenant above package max", "large", "/caps/tenant-other", "?limit=300", 300},
+		{"tenant caps route limits", "small", "/caps/tenant-search", "?limit=40", 20},
+		{"route limits win for larger tenants", "large", "/caps/tenant-search", "?limit=300", 50},
+		{"invalid tenant limits", "invalid", "/caps/tenant-other", "?limit=300", 100},
+		{"unknown tenant", "other", "/caps/tenant-other", "?limit=300", 100},
+		{"no tenant", "", "/caps/tenant-other", "?limit=300", 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got pagination.Params
+			router := gin.New()
+			router.GET(tt.route, func(c *gin.Context) {
+				if tt.tenant != "" {
+					tenant.Set(c, tenant.Tenant{ID: tt.tenant})
+				}
+				got = pagination.BindDefault(c)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.route+tt.query, nil)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got.Limit != tt.wantLimit {
+				t.Errorf("expected limit %d, got %d", tt.wantLimit, got.Limit)
+			}
+		})
+	}
+}
+
+func TestTenantLimitsNetHTTP(t *testing.T) {
+	pagination.SetTenantLimits(func(tenant.Tenant) (pagination.Limits, bool) {
+		return pagination.Limits{Default: 5, Max: 15}, true
+	})
+	t.Cleanup(func() { pagination.SetTenantLimits(nil) })
+
+	var got pagination.Params
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /caps/tenant-nethttp", func(w http.ResponseWriter, r *http.Request) {
+		got = pagination.FromRequestWithDefaults(r, 20, 100)
+	})
+	handler := tenant.Handler(func(*http.Request) (tenant.Tenant, bool) {
+		return tenant.Tenant{ID: "acme"}, true
+	})(mux)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/caps/tenant-nethttp?limit=90", nil))
+
+	if got.Limit != 15 {
+		t.Errorf("expected limit 15, got %d", got.Limit)
+	}
+}
+
+func TestSetRouteLimitsInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for default above max")
+		}
+	}()
+	pagination.SetRouteLimits("/caps/invalid", 100, 50)
+}
@@ -0,0 +1,237 @@
+package pagination
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/clock"
+)
+
+// ErrCountUnavailable is returned by a CountBudget counter when the exact
+// count ran out of time and there was no remembered count or fallback.
+var ErrCountUnavailable = errors.New("pagination: count unavailable")
+
+// Total is a list total that may be an estimate.
+type Total struct {
+	Value int64
+	// Estimate is set when Value isn't an exact count
+	Estimate bool
+}
+
+// Counter produces the total of a list. Implementations trade precision
+// for cost: CountFunc counts exactly, CachedCount reuses recent counts,
+// PostgresEstimate reads the planner's estimate, and CountBudget falls
+// back from one to another under load. Endpoints pick one without changing
+// how they respond (see response.ListWithCounter).
+type Counter interface {
+	Count(ctx context.Context) (Total, error)
+}
+
+// CountFunc is an exact Counter, e.g. a SELECT COUNT(*).
+type CountFunc func(ctx context.Context) (int64, error)
+
+// Count implements Counter.
+func (f CountFunc) Count(ctx context.Context) (Total, error) {
+	n, err := f(ctx)
+	return Total{Value: n}, err
+}
+
+// EstimateFunc is a Counter whose totals are estimates.
+type EstimateFunc func(ctx context.Context) (int64, error)
+
+// Count implements Counter.
+func (f EstimateFunc) Count(ctx context.Context) (Total, error) {
+	n, err := f(ctx)
+	return Total{Value: n, Estimate: true}, err
+}
+
+// RowQuerier runs a query returning one row; *sql.DB, *sql.Tx, and
+// *sql.Conn implement it.
+type RowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// PostgresEstimate returns a Counter reading the row count PostgreSQL's
+// planner keeps for table (pg_class.reltuples), which costs nothing but is
+// only as fresh as the last ANALYZE and ignores filters. Use it for
+// unfiltered listings of large tables. Tables never analyzed count as 0.
+func PostgresEstimate(db RowQuerier, table string) Counter {
+	return EstimateFunc(func(ctx context.Context) (int64, error) {
+		var n float64
+		err := db.QueryRowContext(ctx, "SELECT reltuples FROM pg_class WHERE oid = $1::regclass", table).Scan(&n)
+		if err != nil {
+			return 0, err
+		}
+		return max(int64(n), 0), nil // -1 until the first ANALYZE
+	})
+}
+
+// CachedCount returns a Counter that caches next's totals under key for
+// ttl in c (nil for cache.Default()), so instances sharing a store count
+// once per ttl. Totals served from the cache are flagged as estimates.
+func CachedCount(c *cache.Cache, key string, ttl time.Duration, next Counter) Counter {
+	return cachedCounter{cache: c, key: key, ttl: ttl, next: next}
+}
+
+type cachedCounter struct {
+	cache *cache.Cache
+	key   string
+	ttl   time.Duration
+	next  Counter
+}
+
+// Count implements Counter.
+func (cc cachedCounter) Count(ctx context.Context) (Total, error) {
+	c := cc.cache
+	if c == nil {
+		c = cache.Default()
+	}
+	fresh := false
+	total, err := cache.DoWith(ctx, c, cc.key, cc.ttl, func(ctx context.Context) (Total, error) {
+		fresh = true
+		return cc.next.Count(ctx)
+	})
+	if err == nil && !fresh {
+		total.Estimate = true
+	}
+	return total, err
+}
+
+// CountConfig configures a CountBudget.
+type CountConfig struct {
+	// Budget is how long the exact count may take (defaults to 250ms). It
+	// is also capped at half the time left before the context deadline,
+	// so the count can't eat the whole request timeout.
+	Budget time.Duration
+	// MaxAge is how long a remembered exact count may stand in for one
+	// that ran out of time (defaults to 10m)
+	MaxAge time.Duration
+	// MaxKeys bounds the remembered counts (defaults to 10000)
+	MaxKeys int
+	// Clock ages remembered counts (defaults to clock.System)
+	Clock clock.Clock
+}
+
+// CountBudget bounds exact counts in time, so listings stay fast when the
+// database is under pressure. Exact counts that finish in time are
+// remembered per key; when one runs out of time, its counter returns the
+// last exact count for the key, or the fallback's total, flagged as an
+// estimate:
+//
+//	var counts = pagination.NewCountBudget(pagination.CountConfig{Budget: 200 * time.Millisecond})
+//
+//	counter := counts.Counter("galleries:"+filter.Key(),
+//	    func(ctx context.Context) (int64, error) { return repo.Count(ctx, filter) },
+//	    pagination.PostgresEstimate(db, "galleries"),
+//	)
+//	response.ListWithCounter(c, galleries, counter, p)
+type CountBudget struct {
+	cfg CountConfig
+
+	mu     sync.Mutex
+	counts map[string]rememberedCount
+}
+
+type rememberedCount struct {
+	value int64
+	at    time.Time
+}
+
+// NewCountBudget returns a CountBudget for cfg.
+func NewCountBudget(cfg CountConfig) *CountBudget {
+	if cfg.Budget <= 0 {
+		cfg.Budget = 250 * time.Millisecond
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = 10 * time.Minute
+	}
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = 10000
+	}
+	cfg.Clock = clock.Or(cfg.Clock)
+	return &CountBudget{cfg: cfg, counts: map[string]rememberedCount{}}
+}
+
+// Counter returns a Counter running exact within the budget. If it runs
+// out of time, the counter falls back to the last exact count for key
+// younger than MaxAge, then to fallback (which may be nil), returning an
+// estimated Total; with neither it returns ErrCountUnavailable. Errors
+// from exact other than running out of time are returned as is.
+func (b *CountBudget) Counter(key string, exact CountFunc, fallback Counter) Counter {
+	return budgetCounter{budget: b, key: key, exact: exact, fallback: fallback}
+}
+
+type budgetCounter struct {
+	budget   *CountBudget
+	key      string
+	exact    CountFunc
+	fallback Counter
+}
+
+// Count implements Counter.
+func (bc budgetCounter) Count(ctx context.Context) (Total, error) {
+	b := bc.budget
+	limit := b.cfg.Budget
+	if deadline, ok := ctx.Deadline(); ok {
+		limit = min(limit, time.Until(deadline)/2)
+	}
+
+	countCtx, cancel := context.WithTimeout(ctx, limit)
+	n, err := bc.exact(countCtx)
+	timedOut := countCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+	cancel()
+	if err == nil {
+		b.remember(bc.key, n)
+		return Total{Value: n}, nil
+	}
+	if !timedOut {
+		return Total{}, err
+	}
+
+	if n, ok := b.recall(bc.key); ok {
+		return Total{Value: n, Estimate: true}, nil
+	}
+	if bc.fallback != nil {
+		total, err := bc.fallback.Count(ctx)
+		if err != nil {
+			return Total{}, err
+		}
+		total.Estimate = true
+		return total, nil
+	}
+	return Total{}, ErrCountUnavailable
+}
+
+// remember stores an exact count for key.
+func (b *CountBudget) remember(key string, n int64) {
+	now := b.cfg.Clock.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.counts[key]; !ok && len(b.counts) >= b.cfg.MaxKeys {
+		for k, rc := range b.counts {
+			if now.Sub(rc.at) > b.cfg.MaxAge {
+				delete(b.counts, k)
+			}
+		}
+		if len(b.counts) >= b.cfg.MaxKeys {
+			return
+		}
+	}
+	b.counts[key] = rememberedCount{value: n, at: now}
+}
+
+// recall returns the remembered count for key if it isn't too old.
+func (b *CountBudget) recall(key string) (int64, bool) {
+	now := b.cfg.Clock.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rc, ok := b.counts[key]
+	if !ok || now.Sub(rc.at) > b.cfg.MaxAge {
+		return 0, false
+	}
+	return rc.value, true
+}
@@ -0,0 +1,108 @@
+package pagination_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/apitest"
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+// slowCount blocks until ctx is done, like a COUNT hitting the budget.
+func slowCount(ctx context.Context) (int64, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func fixedCount(n int64) pagination.CountFunc {
+	return func(context.Context) (int64, error) { return n, nil }
+}
+
+func TestCountFuncs(t *testing.T) {
+	tests := []struct {
+		name    string
+		counter pagination.Counter
+		want    pagination.Total
+	}{
+		{"exact", fixedCount(7), pagination.Total{Value: 7}},
+		{"estimate", pagination.EstimateFunc(fixedCount(7)), pagination.Total{Value: 7, Estimate: true}},
+	}
+	for _, tt := range tests {
+		if got, err := tt.counter.Count(context.Background()); err != nil || got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v %v", tt.name, tt.want, got, err)
+		}
+	}
+}
+
+func TestCachedCount(t *testing.T) {
+	c := cache.New(cache.Config{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	calls := 0
+	counter := pagination.CachedCount(c, "galleries:count", time.Minute, pagination.CountFunc(func(context.Context) (int64, error) {
+		calls++
+		return 42, nil
+	}))
+
+	want := []pagination.Total{{Value: 42}, {Value: 42, Estimate: true}}
+	for i, w := range want {
+		if got, err := counter.Count(context.Background()); err != nil || got != w {
+			t.Errorf("call %d: expected %+v, got %+v %v", i, w, got, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 count, got %d", calls)
+	}
+}
+
+func TestCountBudget(t *testing.T) {
+	clk := apitest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	budget := pagination.NewCountBudget(pagination.CountConfig{Budget: 10 * time.Millisecond, MaxAge: time.Minute, Clock: clk})
+	errDB := errors.New("connection refused")
+
+	tests := []struct {
+		name     string
+		key      string
+		advance  time.Duration
+		exact    pagination.CountFunc
+		fallback pagination.Counter
+		want     pagination.Total
+		wantErr  error
+	}{
+		{"exact in budget", "galleries", 0, fixedCount(120), nil, pagination.Total{Value: 120}, nil},
+		{"remembered count", "galleries", 30 * time.Second, slowCount, fixedCount(999), pagination.Total{Value: 120, Estimate: true}, nil},
+		{"remembered count too old", "galleries", time.Minute, slowCount, fixedCount(999), pagination.Total{Value: 999, Estimate: true}, nil},
+		{"no fallback", "tags", 0, slowCount, nil, pagination.Total{}, pagination.ErrCountUnavailable},
+		{"other errors", "tags", 0, func(context.Context) (int64, error) { return 0, errDB }, fixedCount(5), pagination.Total{}, errDB},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk.Advance(tt.advance)
+			got, err := budget.Counter(tt.key, tt.exact, tt.fallback).Count(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCountBudgetRequestDeadline(t *testing.T) {
+	budget := pagination.NewCountBudget(pagination.CountConfig{Budget: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 40*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	got, err := budget.Counter("galleries", slowCount, fixedCount(50)).Count(ctx)
+	if err != nil || got != (pagination.Total{Value: 50, Estimate: true}) {
+		t.Fatalf("expected estimate 50, got %+v %v", got, err)
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Millisecond {
+		t.Errorf("expected the count to stop at half the request deadline, took %s", elapsed)
+	}
+}
@@ -0,0 +1,148 @@
+package pagination
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// ErrInvalidCursor is returned when a cursor token can't be decoded.
+var ErrInvalidCursor = errors.New("pagination: invalid cursor")
+
+// CursorConfig configures cursor encryption; see SetCursorEncryption.
+type CursorConfig struct {
+	// Keys are AES keys of 16, 24, or 32 bytes. The first encrypts new
+	// cursors; all of them decrypt. No keys disables encryption.
+	Keys [][]byte
+	// AcceptPlaintext lets DecodeCursor accept unencrypted cursors, while
+	// those issued before encryption was enabled are still in use
+	AcceptPlaintext bool
+}
+
+// cursorKeys are the ciphers of the configured keys, first the current one.
+type cursorKeys struct {
+	aeads           []cipher.AEAD
+	acceptPlaintext bool
+}
+
+var cursorEncryption atomic.Pointer[cursorKeys]
+
+// sealedCursor prefixes encrypted cursors. No JSON value starts with it,
+// so they can't be confused with plaintext ones.
+const sealedCursor = 0x01
+
+// cursorAD binds the ciphertext to its use, so values encrypted with the
+// same keys elsewhere aren't accepted as cursors.
+var cursorAD = []byte("ginapi cursor")
+
+// SetCursorEncryption makes EncodeCursor encrypt cursors with AES-GCM, so
+// clients can't read or forge their contents (sort values, IDs) to page
+// through rows they shouldn't see or skip ahead of scanning limits. Call
+// it at startup:
+//
+//	pagination.SetCursorEncryption(pagination.CursorConfig{Keys: [][]byte{cfg.CursorKey}})
+//
+// To rotate, put the new key first and keep the old one after it for as
+// long as its cursors may be in use. It panics if a key isn't 16, 24, or
+// 32 bytes.
+func SetCursorEncryption(cfg CursorConfig) {
+	if len(cfg.Keys) == 0 {
+		cursorEncryption.Store(nil)
+		return
+	}
+	keys := &cursorKeys{acceptPlaintext: cfg.AcceptPlaintext}
+	for i, key := range cfg.Keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			panic(fmt.Sprintf("pagination: cursor key %d: %v", i, err))
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic(fmt.Sprintf("pagination: cursor key %d: %v", i, err))
+		}
+		keys.aeads = append(keys.aeads, aead)
+	}
+	cursorEncryption.Store(keys)
+}
+
+// EncodeCursor encodes v as an opaque, URL-safe cursor token. Cursors are
+// opaque to clients but not tamper-proof unless SetCursorEncryption is
+// set; without it, don't put anything in them the client isn't allowed to
+// see or change.
+func EncodeCursor(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	if keys := cursorEncryption.Load(); keys != nil {
+		aead := keys.aeads[0]
+		sealed := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(b)+aead.Overhead())
+		sealed[0] = sealedCursor
+		if _, err := rand.Read(sealed[1:]); err != nil {
+			return "", err
+		}
+		b = aead.Seal(sealed, sealed[1:], b, cursorAD)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// openCursor returns the JSON of a decoded token, decrypting it when
+// cursors are encrypted.
+func openCursor(b []byte) ([]byte, bool) {
+	keys := cursorEncryption.Load()
+	if len(b) == 0 || b[0] != sealedCursor {
+		return b, keys == nil || keys.acceptPlaintext
+	}
+	if keys == nil {
+		return nil, false
+	}
+	for _, aead := range keys.aeads {
+		n := 1 + aead.NonceSize()
+		if len(b) < n {
+			return nil, false
+		}
+		if plain, err := aead.Open(nil, b[1:n], b[n:], cursorAD); err == nil {
+			return plain, true
+		}
+	}
+	return nil, false
+}
+
+// maxCursorLength bounds the tokens DecodeCursor accepts. Tokens come from
+// the query string, so this caps the decoding work a client can ask for.
+const maxCursorLength = 4096
+
+// DecodeCursor decodes a token produced by EncodeCursor into v.
+// Numbers decode as json.Number when v is an interface or []any, so large
+// integer sort values survive the round trip. Tokens longer than 4 KiB or
+// with data after the JSON value are invalid, and so are tokens that
+// aren't encrypted with one of the keys set with SetCursorEncryption.
+func DecodeCursor(token string, v any) error {
+	if len(token) > maxCursorLength {
+		return ErrInvalidCursor
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	b, ok := openCursor(b)
+	if !ok {
+		return ErrInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return ErrInvalidCursor
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return ErrInvalidCursor
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package pagination
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeletedFilter controls whether list endpoints return soft-deleted records.
+type DeletedFilter int
+
+const (
+	// ExcludeDeleted returns only live records (the default).
+	ExcludeDeleted DeletedFilter = iota
+	// IncludeDeleted returns live and soft-deleted records.
+	IncludeDeleted
+	// OnlyDeleted returns only soft-deleted records (e.g. an admin trash view).
+	OnlyDeleted
+)
+
+// BindDeletedFilter reads the include_deleted query parameter.
+// Accepts boolean values ("true", "1", "false", ...) and "only".
+// Missing or invalid values exclude deleted records, and so does asking
+// for deleted records when allowed is nil or returns false, so only the
+// callers it authorizes (e.g. admins) can see them:
+//
+//	filter := pagination.BindDeletedFilter(c, func(c *gin.Context) bool {
+//	    p, _ := auth.GetPrincipal(c)
+//	    return slices.Contains(p.Roles, "admin")
+//	})
+func BindDeletedFilter(c *gin.Context, allowed func(c *gin.Context) bool) DeletedFilter {
+	filter := parseDeletedFilter(c.Request)
+	if filter != ExcludeDeleted && (allowed == nil || !allowed(c)) {
+		return ExcludeDeleted
+	}
+	return filter
+}
+
+// DeletedFilterFromRequest is the net/http equivalent of BindDeletedFilter.
+func DeletedFilterFromRequest(r *http.Request, allowed func(r *http.Request) bool) DeletedFilter {
+	filter := parseDeletedFilter(r)
+	if filter != ExcludeDeleted && (allowed == nil || !allowed(r)) {
+		return ExcludeDeleted
+	}
+	return filter
+}
+
+// parseDeletedFilter returns the filter the request asks for.
+func parseDeletedFilter(r *http.Request) DeletedFilter {
+	value := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("include_deleted")))
+	if value == "only" {
+		return OnlyDeleted
+	}
+	if include, err := strconv.ParseBool(value); err == nil && include {
+		return IncludeDeleted
+	}
+	return ExcludeDeleted
+}
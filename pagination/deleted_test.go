@@ -0,0 +1,57 @@
+package pagination_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+func TestBindDeletedFilter(t *testing.T) {
+	allow := func(*gin.Context) bool { return true }
+	deny := func(*gin.Context) bool { return false }
+
+	tests := []struct {
+		name    string
+		query   string
+		allowed func(*gin.Context) bool
+		want    pagination.DeletedFilter
+	}{
+		{"missing", "", allow, pagination.ExcludeDeleted},
+		{"true", "?include_deleted=true", allow, pagination.IncludeDeleted},
+		{"1", "?include_deleted=1", allow, pagination.IncludeDeleted},
+		{"false", "?include_deleted=false", allow, pagination.ExcludeDeleted},
+		{"only", "?include_deleted=ONLY", allow, pagination.OnlyDeleted},
+		{"garbage", "?include_deleted=garbage", allow, pagination.ExcludeDeleted},
+		{"not authorized", "?include_deleted=true", deny, pagination.ExcludeDeleted},
+		{"only, not authorized", "?include_deleted=only", deny, pagination.ExcludeDeleted},
+		{"no predicate", "?include_deleted=true", nil, pagination.ExcludeDeleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request, _ = http.NewRequest("GET", "/test"+tt.query, nil)
+
+			if got := pagination.BindDeletedFilter(c, tt.allowed); got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDeletedFilterFromRequest(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/test?include_deleted=true", nil)
+	admin := func(r *http.Request) bool { return r.Header.Get("X-Admin") == "1" }
+
+	if got := pagination.DeletedFilterFromRequest(r, admin); got != pagination.ExcludeDeleted {
+		t.Errorf("expected %d, got %d", pagination.ExcludeDeleted, got)
+	}
+	r.Header.Set("X-Admin", "1")
+	if got := pagination.DeletedFilterFromRequest(r, admin); got != pagination.IncludeDeleted {
+		t.Errorf("expected %d, got %d", pagination.IncludeDeleted, got)
+	}
+}
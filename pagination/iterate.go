@@ -0,0 +1,75 @@
+package pagination
+
+import (
+	"context"
+	"iter"
+)
+
+// FetchFunc fetches one page of items for the given params.
+// Returning fewer than p.Limit items signals the last page.
+type FetchFunc[T any] func(ctx context.Context, p Params) ([]T, error)
+
+// Pages iterates over pages from fetch, starting at params.Offset, until a
+// short page is returned, fetch fails, or ctx is cancelled. The page size is
+// params.Limit, capped at MaxLimit (DefaultLimit if unset).
+//
+// Errors (including ctx.Err()) are yielded once as the final element.
+//
+//	for page, err := range pagination.Pages(ctx, params, repo.ListGalleries) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    writeCSV(page)
+//	}
+func Pages[T any](ctx context.Context, params Params, fetch FetchFunc[T]) iter.Seq2[[]T, error] {
+	params.Normalize(DefaultLimit, MaxLimit)
+
+	return func(yield func([]T, error) bool) {
+		p := params
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			page, err := fetch(ctx, p)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if len(page) > 0 && !yield(page, nil) {
+				return
+			}
+			if len(page) < p.Limit {
+				return
+			}
+			p.Offset += len(page)
+		}
+	}
+}
+
+// Iterate is like Pages but yields individual items.
+//
+//	for item, err := range pagination.Iterate(ctx, params, repo.ListGalleries) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    enc.Encode(item)
+//	}
+func Iterate[T any](ctx context.Context, params Params, fetch FetchFunc[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for page, err := range Pages(ctx, params, fetch) {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
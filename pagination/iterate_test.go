@@ -0,0 +1,101 @@
+package pagination_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+// fetchRange serves the integers [0, n) page by page.
+func fetchRange(n int, calls *[]pagination.Params) pagination.FetchFunc[int] {
+	return func(ctx context.Context, p pagination.Params) ([]int, error) {
+		*calls = append(*calls, p)
+		var page []int
+		for i := p.Offset; i < n && i < p.Offset+p.Limit; i++ {
+			page = append(page, i)
+		}
+		return page, nil
+	}
+}
+
+func TestIterate(t *testing.T) {
+	var calls []pagination.Params
+	var got []int
+	for item, err := range pagination.Iterate(context.Background(), pagination.Params{Limit: 2, Offset: 1}, fetchRange(6, &calls)) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, item)
+	}
+
+	if len(got) != 5 || got[0] != 1 || got[4] != 5 {
+		t.Errorf("unexpected items: %v", got)
+	}
+	if len(calls) != 3 {
+		t.Errorf("expected 3 fetches, got %d", len(calls))
+	}
+}
+
+func TestPagesCapsLimit(t *testing.T) {
+	var calls []pagination.Params
+	for _, err := range pagination.Pages(context.Background(), pagination.Params{Limit: 5000}, fetchRange(10, &calls)) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if calls[0].Limit != pagination.MaxLimit {
+		t.Errorf("expected limit capped at %d, got %d", pagination.MaxLimit, calls[0].Limit)
+	}
+}
+
+func TestPagesEarlyBreak(t *testing.T) {
+	var calls []pagination.Params
+	for range pagination.Pages(context.Background(), pagination.Params{Limit: 2}, fetchRange(100, &calls)) {
+		break
+	}
+
+	if len(calls) != 1 {
+		t.Errorf("expected 1 fetch after break, got %d", len(calls))
+	}
+}
+
+func TestPagesFetchError(t *testing.T) {
+	boom := errors.New("boom")
+	fetch := func(ctx context.Context, p pagination.Params) ([]int, error) { return nil, boom }
+
+	var gotErr error
+	for _, err := range pagination.Iterate(context.Background(), pagination.Params{}, fetch) {
+		gotErr = err
+	}
+
+	if !errors.Is(gotErr, boom) {
+		t.Errorf("expected fetch error, got %v", gotErr)
+	}
+}
+
+func TestPagesContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls []pagination.Params
+
+	var gotErr error
+	for page, err := range pagination.Pages(ctx, pagination.Params{Limit: 2}, fetchRange(100, &calls)) {
+		if err != nil {
+			gotErr = err
+			break
+		}
+		if page[0] == 2 {
+			cancel()
+		}
+	}
+
+	if !errors.Is(gotErr, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", gotErr)
+	}
+	if len(calls) != 2 {
+		t.Errorf("expected 2 fetches before cancellation, got %d", len(calls))
+	}
+}
@@ -0,0 +1,216 @@
+package pagination
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KeysetParams holds keyset (seek) pagination parameters. Instead of an
+// offset, each page starts after the sort key and ID of the last item the
+// client saw, so rows inserted or deleted concurrently never shift items
+// onto two pages or off all of them.
+type KeysetParams struct {
+	Limit int
+	Sort  string
+	// After is the position to continue from. Nil on the first page.
+	After *KeysetCursor
+}
+
+// KeysetCursor is the position of the last item of a page: its value of
+// the sort column and its ID, the tiebreaker for equal sort values.
+type KeysetCursor struct {
+	// Sort is the sort the cursor was issued for
+	Sort string
+	// SortKey is the sort column value: int64, float64, string, or time.Time
+	SortKey any
+	// ID is the item ID: int64 or string
+	ID any
+}
+
+// keysetToken is the encoded form of a KeysetCursor. Types are recorded
+// so values decode to what the database driver expects.
+type keysetToken struct {
+	Sort     string `json:"s,omitempty"`
+	Key      string `json:"k"`
+	KeyType  string `json:"kt"`
+	ID       string `json:"i"`
+	IDNumber bool   `json:"in,omitempty"`
+}
+
+// EncodeKeysetCursor encodes the position after an item as a cursor token.
+// sortKey may be any integer, float, string, or time.Time; id an integer
+// or string.
+func EncodeKeysetCursor(sort string, sortKey, id any) (string, error) {
+	tok := keysetToken{Sort: sort}
+	switch k := sortKey.(type) {
+	case time.Time:
+		tok.Key, tok.KeyType = k.UTC().Format(time.RFC3339Nano), "time"
+	case string:
+		tok.Key, tok.KeyType = k, "string"
+	case float64:
+		if math.IsNaN(k) || math.IsInf(k, 0) {
+			return "", fmt.Errorf("pagination: non-finite sort key %v", k)
+		}
+		tok.Key, tok.KeyType = strconv.FormatFloat(k, 'g', -1, 64), "float"
+	case float32:
+		if f := float64(k); math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("pagination: non-finite sort key %v", k)
+		}
+		tok.Key, tok.KeyType = strconv.FormatFloat(float64(k), 'g', -1, 32), "float"
+	default:
+		n, ok := toInt64(sortKey)
+		if !ok {
+			return "", fmt.Errorf("pagination: unsupported sort key type %T", sortKey)
+		}
+		tok.Key, tok.KeyType = strconv.FormatInt(n, 10), "int"
+	}
+
+	if s, ok := id.(string); ok {
+		tok.ID = s
+	} else if n, ok := toInt64(id); ok {
+		tok.ID, tok.IDNumber = strconv.FormatInt(n, 10), true
+	} else {
+		return "", fmt.Errorf("pagination: unsupported id type %T", id)
+	}
+	return EncodeCursor(tok)
+}
+
+// DecodeKeysetCursor decodes a token produced by EncodeKeysetCursor.
+func DecodeKeysetCursor(token string) (KeysetCursor, error) {
+	var tok keysetToken
+	if err := DecodeCursor(token, &tok); err != nil {
+		return KeysetCursor{}, err
+	}
+
+	c := KeysetCursor{Sort: tok.Sort, ID: tok.ID}
+	var err error
+	switch tok.KeyType {
+	case "time":
+		c.SortKey, err = time.Parse(time.RFC3339Nano, tok.Key)
+	case "string":
+		c.SortKey = tok.Key
+	case "float":
+		var f float64
+		if f, err = strconv.ParseFloat(tok.Key, 64); err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			err = ErrInvalidCursor // NaN and infinities don't order rows
+		}
+		c.SortKey = f
+	case "int":
+		c.SortKey, err = strconv.ParseInt(tok.Key, 10, 64)
+	default:
+		return KeysetCursor{}, ErrInvalidCursor
+	}
+	if err != nil {
+		return KeysetCursor{}, ErrInvalidCursor
+	}
+	if tok.IDNumber {
+		if c.ID, err = strconv.ParseInt(tok.ID, 10, 64); err != nil {
+			return KeysetCursor{}, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
+
+// BindKeyset extracts keyset pagination parameters.
+// Supports: limit, after (token from the previous response), sort (or sort_by).
+// Returns ErrInvalidCursor if the token is malformed or was issued for a
+// different sort. Limits registered for the route with SetRouteLimits cap
+// defaultLimit and maxLimit.
+func BindKeyset(c *gin.Context, defaultLimit, maxLimit int) (KeysetParams, error) {
+	defaultLimit, maxLimit = capContextLimits(c, defaultLimit, maxLimit)
+	return keysetFromRequest(c.Request, defaultLimit, maxLimit)
+}
+
+// KeysetFromRequest is the net/http equivalent of BindKeyset.
+func KeysetFromRequest(r *http.Request, defaultLimit, maxLimit int) (KeysetParams, error) {
+	defaultLimit, maxLimit = capRequestLimits(r, defaultLimit, maxLimit)
+	return keysetFromRequest(r, defaultLimit, maxLimit)
+}
+
+func keysetFromRequest(r *http.Request, defaultLimit, maxLimit int) (KeysetParams, error) {
+	params := FromRequest(r)
+	params.Normalize(defaultLimit, maxLimit)
+	p := KeysetParams{Limit: params.Limit, Sort: params.Sort}
+
+	if token := r.URL.Query().Get("after"); token != "" {
+		after, err := DecodeKeysetCursor(token)
+		if err != nil || after.Sort != p.Sort {
+			return p, ErrInvalidCursor
+		}
+		p.After = &after
+	}
+	return p, nil
+}
+
+// Where returns a SQL condition selecting the rows after the cursor, and
+// its arguments, for a query ordered by OrderBy with the same columns:
+//
+//	p, err := pagination.BindKeyset(c, 20, 100)
+//	...
+//	query := "SELECT ... FROM galleries"
+//	where, args := p.Where("created_at", "id", true, 1)
+//	if where != "" {
+//	    query += " WHERE " + where
+//	}
+//	query += " ORDER BY " + p.OrderBy("created_at", "id", true) + " LIMIT " + strconv.Itoa(p.Limit+1)
+//
+// It returns "" and no arguments on the first page. Placeholders are
+// numbered from argIndex ($1, $2 for Postgres), or are "?" when argIndex is
+// 0. The columns are interpolated, so pass constants, never client input.
+func (p KeysetParams) Where(sortColumn, idColumn string, desc bool, argIndex int) (string, []any) {
+	if p.After == nil {
+		return "", nil
+	}
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	a, b := "?", "?"
+	if argIndex > 0 {
+		a, b = "$"+strconv.Itoa(argIndex), "$"+strconv.Itoa(argIndex+1)
+	}
+	return "(" + sortColumn + ", " + idColumn + ") " + op + " (" + a + ", " + b + ")",
+		[]any{p.After.SortKey, p.After.ID}
+}
+
+// OrderBy returns the ORDER BY clause matching Where: the sort column, then
+// the ID as a tiebreaker, both in the same direction.
+func (p KeysetParams) OrderBy(sortColumn, idColumn string, desc bool) string {
+	dir := " ASC"
+	if desc {
+		dir = " DESC"
+	}
+	return sortColumn + dir + ", " + idColumn + dir
+}
+
+// toInt64 converts any integer type to int64.
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return int64(n), true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,189 @@
+package pagination_test
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+func TestKeysetCursorRoundTrip(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 30, 0, 123456789, time.UTC)
+
+	tests := []struct {
+		name    string
+		sortKey any
+		id      any
+		wantKey any
+		wantID  any
+	}{
+		{"time and int id", created, 42, created, int64(42)},
+		{"string and string id", "Zebra", "gal_9", "Zebra", "gal_9"},
+		{"float", 4.5, uint32(7), 4.5, int64(7)},
+		{"large int", int64(1) << 60, "x", int64(1) << 60, "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := pagination.EncodeKeysetCursor("-created_at", tt.sortKey, tt.id)
+			if err != nil {
+				t.Fatalf("encode failed: %v", err)
+			}
+			c, err := pagination.DecodeKeysetCursor(token)
+			if err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+			if c.Sort != "-created_at" {
+				t.Errorf("expected sort -created_at, got %q", c.Sort)
+			}
+			if k, ok := c.SortKey.(time.Time); ok {
+				if !k.Equal(tt.wantKey.(time.Time)) {
+					t.Errorf("expected key %v, got %v", tt.wantKey, k)
+				}
+			} else if c.SortKey != tt.wantKey {
+				t.Errorf("expected key %#v, got %#v", tt.wantKey, c.SortKey)
+			}
+			if c.ID != tt.wantID {
+				t.Errorf("expected id %#v, got %#v", tt.wantID, c.ID)
+			}
+		})
+	}
+}
+
+func TestEncodeKeysetCursorUnsupported(t *testing.T) {
+	if _, err := pagination.EncodeKeysetCursor("", []int{1}, 1); err == nil {
+		t.Error("expected error for unsupported sort key")
+	}
+	if _, err := pagination.EncodeKeysetCursor("", 1, 1.5); err == nil {
+		t.Error("expected error for unsupported id")
+	}
+	if _, err := pagination.EncodeKeysetCursor("", math.NaN(), 1); err == nil {
+		t.Error("expected error for a NaN sort key")
+	}
+	// {"k":"NaN","kt":"float","i":"1"}
+	if _, err := pagination.DecodeKeysetCursor("eyJrIjoiTmFOIiwia3QiOiJmbG9hdCIsImkiOiIxIn0"); !errors.Is(err, pagination.ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor for a NaN sort key, got %v", err)
+	}
+}
+
+func TestBindKeyset(t *testing.T) {
+	token, _ := pagination.EncodeKeysetCursor("created_at", 100, 7)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantLimit int
+		wantAfter bool
+		wantErr   bool
+	}{
+		{"first page", "?limit=10&sort=created_at", 10, false, false},
+		{"next page", "?sort=created_at&after=" + token, 20, true, false},
+		{"malformed", "?after=bm90LWpzb24", 20, false, true},
+		{"different sort", "?sort=title&after=" + token, 20, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/galleries"+tt.query, nil)
+
+			p, err := pagination.BindKeyset(c, 20, 100)
+			if tt.wantErr {
+				if !errors.Is(err, pagination.ErrInvalidCursor) {
+					t.Errorf("expected ErrInvalidCursor, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Limit != tt.wantLimit || (p.After != nil) != tt.wantAfter {
+				t.Errorf("unexpected params %+v", p)
+			}
+		})
+	}
+}
+
+func TestKeysetWhere(t *testing.T) {
+	first := pagination.KeysetParams{Limit: 20}
+	if where, args := first.Where("created_at", "id", true, 1); where != "" || args != nil {
+		t.Errorf("expected no condition on the first page, got %q %v", where, args)
+	}
+
+	p := pagination.KeysetParams{Limit: 20, After: &pagination.KeysetCursor{SortKey: int64(100), ID: int64(7)}}
+
+	tests := []struct {
+		desc      bool
+		argIndex  int
+		wantWhere string
+		wantOrder string
+	}{
+		{true, 1, "(created_at, id) < ($1, $2)", "created_at DESC, id DESC"},
+		{false, 3, "(created_at, id) > ($3, $4)", "created_at ASC, id ASC"},
+		{false, 0, "(created_at, id) > (?, ?)", "created_at ASC, id ASC"},
+	}
+
+	for _, tt := range tests {
+		where, args := p.Where("created_at", "id", tt.desc, tt.argIndex)
+		if where != tt.wantWhere {
+			t.Errorf("expected %q, got %q", tt.wantWhere, where)
+		}
+		if len(args) != 2 || args[0] != int64(100) || args[1] != int64(7) {
+			t.Errorf("unexpected args %v", args)
+		}
+		if order := p.OrderBy("created_at", "id", tt.desc); order != tt.wantOrder {
+			t.Errorf("expected %q, got %q", tt.wantOrder, order)
+		}
+	}
+}
+
+func FuzzDecodeKeysetCursor(f *testing.F) {
+	for _, args := range [][3]any{
+		{"-created_at", time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC), int64(7)},
+		{"title", "Zebra", "gal_1"},
+		{"", 1.5, int64(-3)},
+		{"views", int64(99), "x"},
+	} {
+		token, err := pagination.EncodeKeysetCursor(args[0].(string), args[1], args[2])
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(token)
+	}
+	f.Add("eyJrIjoiTmFOIiwia3QiOiJmbG9hdCIsImkiOiIxIn0")
+	f.Add("bm90LWpzb24")
+
+	f.Fuzz(func(t *testing.T, token string) {
+		cursor, err := pagination.DecodeKeysetCursor(token)
+		if err != nil {
+			return
+		}
+
+		again, err := pagination.EncodeKeysetCursor(cursor.Sort, cursor.SortKey, cursor.ID)
+		if err != nil {
+			t.Fatalf("re-encoding %+v failed: %v", cursor, err)
+		}
+		cursor2, err := pagination.DecodeKeysetCursor(again)
+		if err != nil {
+			t.Fatalf("decoding re-encoded %+v failed: %v", cursor, err)
+		}
+		if cursor2.Sort != cursor.Sort || cursor2.ID != cursor.ID || !sortKeysEqual(cursor.SortKey, cursor2.SortKey) {
+			t.Errorf("expected %+v after a round trip, got %+v", cursor, cursor2)
+		}
+	})
+}
+
+// sortKeysEqual compares decoded sort keys, times by instant.
+func sortKeysEqual(a, b any) bool {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	return a == b
+}
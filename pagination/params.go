@@ -2,6 +2,7 @@
 package pagination
 
 import (
+	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -36,12 +37,18 @@ func (p *Params) Normalize(defaultLimit, maxLimit int) {
 // Bind extracts pagination parameters from a Gin context.
 // Supports: limit, offset, sort (or sort_by)
 func Bind(c *gin.Context) Params {
-	limit, _ := strconv.Atoi(c.Query("limit"))
-	offset, _ := strconv.Atoi(c.Query("offset"))
+	return FromRequest(c.Request)
+}
+
+// FromRequest is the net/http equivalent of Bind.
+func FromRequest(r *http.Request) Params {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	offset, _ := strconv.Atoi(q.Get("offset"))
 
-	sort := c.Query("sort")
+	sort := q.Get("sort")
 	if sort == "" {
-		sort = c.Query("sort_by")
+		sort = q.Get("sort_by")
 	}
 
 	return Params{
@@ -51,14 +58,34 @@ func Bind(c *gin.Context) Params {
 	}
 }
 
-// BindWithDefaults extracts and normalizes pagination parameters.
+// BindWithDefaults extracts and normalizes pagination parameters. Limits
+// registered for the route with SetRouteLimits, and those of the tenant
+// (see SetTenantLimits), cap defaultLimit and maxLimit.
 func BindWithDefaults(c *gin.Context, defaultLimit, maxLimit int) Params {
+	defaultLimit, maxLimit = capContextLimits(c, defaultLimit, maxLimit)
 	p := Bind(c)
 	p.Normalize(defaultLimit, maxLimit)
 	return p
 }
 
-// BindDefault extracts pagination with standard defaults (limit 20, max 100).
+// FromRequestWithDefaults is the net/http equivalent of BindWithDefaults.
+// Route limits are looked up by the pattern ServeMux matched.
+func FromRequestWithDefaults(r *http.Request, defaultLimit, maxLimit int) Params {
+	defaultLimit, maxLimit = capRequestLimits(r, defaultLimit, maxLimit)
+	p := FromRequest(r)
+	p.Normalize(defaultLimit, maxLimit)
+	return p
+}
+
+// BindDefault extracts pagination with the limits registered for the route
+// with SetRouteLimits, the limits of the tenant (see SetTenantLimits), or
+// the standard defaults (limit 20, max 100).
 func BindDefault(c *gin.Context) Params {
+	if l, ok := RouteLimits(c.FullPath()); ok {
+		return BindWithDefaults(c, l.Default, l.Max)
+	}
+	if l, ok := TenantLimits(c); ok {
+		return BindWithDefaults(c, l.Default, l.Max)
+	}
 	return BindWithDefaults(c, DefaultLimit, MaxLimit)
 }
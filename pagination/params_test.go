@@ -158,3 +158,13 @@ func TestParams_Normalize(t *testing.T) {
 		})
 	}
 }
+
+func TestFromRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/items?limit=500&offset=-5&sort_by=name", nil)
+
+	p := pagination.FromRequestWithDefaults(req, pagination.DefaultLimit, pagination.MaxLimit)
+
+	if p.Limit != pagination.MaxLimit || p.Offset != 0 || p.Sort != "name" {
+		t.Errorf("unexpected params: %+v", p)
+	}
+}
@@ -0,0 +1,67 @@
+package pagination
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchAfterParams holds search_after pagination parameters for
+// Elasticsearch-backed lists, which can't page past the 10k offset window.
+type SearchAfterParams struct {
+	Limit int
+	// SearchAfter holds the sort values of the last hit on the previous page,
+	// to pass straight to the ES search_after clause. Nil on the first page.
+	SearchAfter []any
+	Sort        string
+}
+
+// BindSearchAfter extracts search_after pagination parameters.
+// Supports: limit, search_after (token from the previous response), sort (or sort_by).
+// Returns ErrInvalidCursor if the search_after token is malformed.
+// Limits registered for the route with SetRouteLimits cap defaultLimit and maxLimit.
+func BindSearchAfter(c *gin.Context, defaultLimit, maxLimit int) (SearchAfterParams, error) {
+	defaultLimit, maxLimit = capContextLimits(c, defaultLimit, maxLimit)
+	return searchAfterFromRequest(c.Request, defaultLimit, maxLimit)
+}
+
+// SearchAfterFromRequest is the net/http equivalent of BindSearchAfter.
+func SearchAfterFromRequest(r *http.Request, defaultLimit, maxLimit int) (SearchAfterParams, error) {
+	defaultLimit, maxLimit = capRequestLimits(r, defaultLimit, maxLimit)
+	return searchAfterFromRequest(r, defaultLimit, maxLimit)
+}
+
+func searchAfterFromRequest(r *http.Request, defaultLimit, maxLimit int) (SearchAfterParams, error) {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	sort := q.Get("sort")
+	if sort == "" {
+		sort = q.Get("sort_by")
+	}
+
+	p := SearchAfterParams{Limit: limit, Sort: sort}
+
+	if token := q.Get("search_after"); token != "" {
+		var values []any
+		if err := DecodeCursor(token, &values); err != nil || len(values) == 0 {
+			return p, ErrInvalidCursor
+		}
+		for _, v := range values {
+			switch v.(type) {
+			case map[string]any, []any:
+				return p, ErrInvalidCursor // sort values are scalars
+			}
+		}
+		p.SearchAfter = values
+	}
+
+	return p, nil
+}
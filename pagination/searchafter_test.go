@@ -0,0 +1,58 @@
+package pagination_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+func TestBindSearchAfter(t *testing.T) {
+	token, _ := pagination.EncodeCursor([]any{42, "gal_1"})
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/search?limit=500&sort=date&search_after="+token, nil)
+
+	p, err := pagination.BindSearchAfter(c, 20, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Limit != 100 {
+		t.Errorf("expected limit capped at 100, got %d", p.Limit)
+	}
+	if p.Sort != "date" {
+		t.Errorf("expected sort 'date', got '%s'", p.Sort)
+	}
+	if len(p.SearchAfter) != 2 || p.SearchAfter[1] != "gal_1" {
+		t.Errorf("unexpected search_after values: %#v", p.SearchAfter)
+	}
+}
+
+func TestBindSearchAfterFirstPage(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/search", nil)
+
+	p, err := pagination.BindSearchAfter(c, 20, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Limit != 20 || p.SearchAfter != nil {
+		t.Errorf("unexpected params: %+v", p)
+	}
+}
+
+func TestBindSearchAfterInvalid(t *testing.T) {
+	nested, _ := pagination.EncodeCursor([]any{1, []any{2}})
+	for _, token := range []string{"garbage", nested} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/search?search_after="+token, nil)
+
+		if _, err := pagination.BindSearchAfter(c, 20, 100); !errors.Is(err, pagination.ErrInvalidCursor) {
+			t.Errorf("search_after=%s: expected ErrInvalidCursor, got %v", token, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package pagination
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// PageToken is a page a pagination control can link to.
+type PageToken struct {
+	Page   int    `json:"page"` // 1-based page number
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Sort   string `json:"sort,omitempty"`
+	// Query is the query string selecting the page, e.g. "limit=20&offset=40"
+	Query string `json:"query"`
+}
+
+// URL returns u with the page's limit, offset, and sort set, keeping its
+// other query parameters (filters, search terms).
+func (t PageToken) URL(u *url.URL) string {
+	out := *u
+	q := out.Query()
+	q.Set("limit", strconv.Itoa(t.Limit))
+	q.Set("offset", strconv.Itoa(t.Offset))
+	if t.Sort != "" {
+		q.Set("sort", t.Sort)
+	}
+	out.RawQuery = q.Encode()
+	return out.String()
+}
+
+// PageLinks are the tokens for a pagination control. Prev and Next are nil
+// on the first and last page; First and Last are nil when there are no items.
+type PageLinks struct {
+	Current int        `json:"current"` // 1-based page number
+	Pages   int        `json:"pages"`   // number of pages
+	First   *PageToken `json:"first"`
+	Prev    *PageToken `json:"prev"`
+	Next    *PageToken `json:"next"`
+	Last    *PageToken `json:"last"`
+}
+
+// PageTokens computes the first, prev, next, and last pages for params
+// (normalized, e.g. from BindDefault) and the total item count, so SSR
+// templates and admin UIs can render pagination controls without repeating
+// the arithmetic:
+//
+//	p := pagination.BindDefault(c)
+//	galleries, total, err := repo.List(ctx, p)
+//	...
+//	links := pagination.PageTokens(p, total)
+//	c.HTML(http.StatusOK, "galleries.html", gin.H{"galleries": galleries, "links": links})
+//
+//	{{with .links.Next}}<a href="?{{.Query}}">Next</a>{{end}}
+//
+// An offset between page boundaries counts as the page it falls in; Prev
+// then goes back a full limit, clamped at 0, like the Link header.
+func PageTokens(params Params, total int64) PageLinks {
+	limit := params.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	offset := max(params.Offset, 0)
+
+	token := func(off int) *PageToken {
+		q := url.Values{}
+		q.Set("limit", strconv.Itoa(limit))
+		q.Set("offset", strconv.Itoa(off))
+		if params.Sort != "" {
+			q.Set("sort", params.Sort)
+		}
+		return &PageToken{
+			Page:   off/limit + 1,
+			Limit:  limit,
+			Offset: off,
+			Sort:   params.Sort,
+			Query:  q.Encode(),
+		}
+	}
+
+	links := PageLinks{Current: offset/limit + 1}
+	if total <= 0 {
+		return links
+	}
+	links.Pages = int((total + int64(limit) - 1) / int64(limit))
+	links.First = token(0)
+	links.Last = token(int((total - 1) / int64(limit) * int64(limit)))
+	if offset > 0 {
+		links.Prev = token(max(offset-limit, 0))
+	}
+	if int64(offset+limit) < total {
+		links.Next = token(offset + limit)
+	}
+	return links
+}
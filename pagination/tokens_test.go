@@ -0,0 +1,104 @@
+package pagination_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+func TestPageTokens(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      pagination.Params
+		total       int64
+		wantCurrent int
+		wantPages   int
+		wantPrev    string
+		wantNext    string
+		wantLast    string
+	}{
+		{
+			name:        "first page",
+			params:      pagination.Params{Limit: 20},
+			total:       45,
+			wantCurrent: 1,
+			wantPages:   3,
+			wantNext:    "limit=20&offset=20",
+			wantLast:    "limit=20&offset=40",
+		},
+		{
+			name:        "middle page with sort",
+			params:      pagination.Params{Limit: 20, Offset: 20, Sort: "-created_at"},
+			total:       45,
+			wantCurrent: 2,
+			wantPages:   3,
+			wantPrev:    "limit=20&offset=0&sort=-created_at",
+			wantNext:    "limit=20&offset=40&sort=-created_at",
+			wantLast:    "limit=20&offset=40&sort=-created_at",
+		},
+		{
+			name:        "last page",
+			params:      pagination.Params{Limit: 20, Offset: 40},
+			total:       45,
+			wantCurrent: 3,
+			wantPages:   3,
+			wantPrev:    "limit=20&offset=20",
+			wantLast:    "limit=20&offset=40",
+		},
+		{
+			name:        "unaligned offset",
+			params:      pagination.Params{Limit: 20, Offset: 5},
+			total:       45,
+			wantCurrent: 1,
+			wantPages:   3,
+			wantPrev:    "limit=20&offset=0",
+			wantNext:    "limit=20&offset=25",
+			wantLast:    "limit=20&offset=40",
+		},
+		{
+			name:        "empty",
+			params:      pagination.Params{Limit: 20},
+			total:       0,
+			wantCurrent: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links := pagination.PageTokens(tt.params, tt.total)
+			if links.Current != tt.wantCurrent || links.Pages != tt.wantPages {
+				t.Errorf("expected page %d of %d, got %d of %d", tt.wantCurrent, tt.wantPages, links.Current, links.Pages)
+			}
+			check := func(name string, tok *pagination.PageToken, want string) {
+				t.Helper()
+				got := ""
+				if tok != nil {
+					got = tok.Query
+				}
+				if got != want {
+					t.Errorf("expected %s %q, got %q", name, want, got)
+				}
+			}
+			check("prev", links.Prev, tt.wantPrev)
+			check("next", links.Next, tt.wantNext)
+			check("last", links.Last, tt.wantLast)
+			if tt.total > 0 && (links.First == nil || links.First.Offset != 0 || links.First.Page != 1) {
+				t.Errorf("unexpected first %+v", links.First)
+			}
+		})
+	}
+}
+
+func TestPageTokenURL(t *testing.T) {
+	u, _ := url.Parse("/galleries?tag=cats&offset=0")
+	links := pagination.PageTokens(pagination.Params{Limit: 10}, 25)
+
+	want := "/galleries?limit=10&offset=10&tag=cats"
+	if got := links.Next.URL(u); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if links.Last.Page != 3 {
+		t.Errorf("expected last page 3, got %d", links.Last.Page)
+	}
+}
@@ -0,0 +1,73 @@
+package pagination
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidUpdatedSince is returned when updated_since is neither a
+// timestamp nor a cursor token.
+var ErrInvalidUpdatedSince = errors.New("pagination: invalid updated_since")
+
+// UpdatedSince is the ?updated_since= value of an incremental sync request:
+// either a timestamp or an opaque cursor token (typically the sync_token from
+// the previous response, see EncodeCursor).
+type UpdatedSince struct {
+	Time   time.Time // Set when the value is a timestamp
+	Cursor string    // Set when the value is a cursor token
+}
+
+// IsZero reports whether updated_since was absent (a full sync).
+func (u UpdatedSince) IsZero() bool {
+	return u.Time.IsZero() && u.Cursor == ""
+}
+
+// BindUpdatedSince reads the updated_since query parameter. It accepts
+// RFC 3339 timestamps, Unix timestamps in seconds or milliseconds, and cursor
+// tokens from EncodeCursor.
+//
+//	since, err := pagination.BindUpdatedSince(c)
+//	if err != nil {
+//	    response.BadRequestParam(c, "updated_since", err.Error())
+//	    return
+//	}
+func BindUpdatedSince(c *gin.Context) (UpdatedSince, error) {
+	return UpdatedSinceFromRequest(c.Request)
+}
+
+// UpdatedSinceFromRequest is the net/http equivalent of BindUpdatedSince.
+func UpdatedSinceFromRequest(r *http.Request) (UpdatedSince, error) {
+	return ParseUpdatedSince(r.URL.Query().Get("updated_since"))
+}
+
+// ParseUpdatedSince parses an updated_since value. Empty values are a zero
+// UpdatedSince.
+func ParseUpdatedSince(value string) (UpdatedSince, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return UpdatedSince{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return UpdatedSince{Time: t}, nil
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+		// Values this large are milliseconds (seconds would be past year 33658)
+		if n >= 1e12 {
+			return UpdatedSince{Time: time.UnixMilli(n).UTC()}, nil
+		}
+		return UpdatedSince{Time: time.Unix(n, 0).UTC()}, nil
+	}
+
+	var raw json.RawMessage
+	if err := DecodeCursor(value, &raw); err != nil {
+		return UpdatedSince{}, ErrInvalidUpdatedSince
+	}
+	return UpdatedSince{Cursor: value}, nil
+}
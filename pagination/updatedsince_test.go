@@ -0,0 +1,52 @@
+package pagination_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+func TestUpdatedSinceFromRequest(t *testing.T) {
+	cursor, _ := pagination.EncodeCursor(map[string]any{"updated_at": "2024-03-05T15:04:00Z", "id": "gal_9"})
+
+	tests := []struct {
+		name       string
+		value      string
+		wantTime   time.Time
+		wantCursor string
+		wantErr    bool
+	}{
+		{name: "absent"},
+		{name: "rfc3339", value: "2024-03-05T15:04:00Z", wantTime: time.Date(2024, 3, 5, 15, 4, 0, 0, time.UTC)},
+		{name: "unix seconds", value: "1709651040", wantTime: time.Date(2024, 3, 5, 15, 4, 0, 0, time.UTC)},
+		{name: "unix millis", value: "1709651040000", wantTime: time.Date(2024, 3, 5, 15, 4, 0, 0, time.UTC)},
+		{name: "cursor", value: cursor, wantCursor: cursor},
+		{name: "garbage", value: "yesterday!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/galleries?updated_since="+url.QueryEscape(tt.value), nil)
+			got, err := pagination.UpdatedSinceFromRequest(req)
+			if tt.wantErr {
+				if !errors.Is(err, pagination.ErrInvalidUpdatedSince) {
+					t.Errorf("expected ErrInvalidUpdatedSince, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Time.Equal(tt.wantTime) || got.Cursor != tt.wantCursor {
+				t.Errorf("expected %v/%q, got %v/%q", tt.wantTime, tt.wantCursor, got.Time, got.Cursor)
+			}
+			if got.IsZero() != (tt.value == "") {
+				t.Errorf("expected IsZero %v", tt.value == "")
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package ginapi
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Profile is the environment-dependent behavior of the ginapi packages,
+// switched together so development conveniences can't leak into
+// production one flag at a time. Use one of the presets, usually picked
+// with GINAPI_ENVIRONMENT (see Config.Profile):
+//
+//	ginapi.Production.Apply("search is temporarily disabled")
+type Profile struct {
+	// Name of the environment, e.g. "production"
+	Name string
+	// ShowServerErrors sends the messages of InternalError and the other
+	// 5xx helpers as-is; otherwise they are hidden, see
+	// response.HideServerErrorMessages
+	ShowServerErrors bool
+	// ExposeStacks puts the panic and its stack in the 500s of
+	// middleware.Recovery; see response.SetExposeStacks
+	ExposeStacks bool
+	// PrettyJSON indents every JSON response; see response.SetPrettyJSON
+	PrettyJSON bool
+	// CheckShapes logs response types breaking the JSON conventions; see
+	// response.SetShapeCheck
+	CheckShapes bool
+	// AllowChaos permits fault injection; see Config.ChaosConfig
+	AllowChaos bool
+	// LogLevel is the minimum level logged
+	LogLevel slog.Level
+	// StrictMode for the middleware getters; see middleware.SetStrictMode
+	StrictMode middleware.StrictMode
+	// GinMode for gin.SetMode; debug mode logs every route and request
+	GinMode string
+}
+
+// The profile presets.
+var (
+	// Development shows everything and fails fast on misuse.
+	Development = Profile{
+		Name:             "development",
+		ShowServerErrors: true,
+		ExposeStacks:     true,
+		PrettyJSON:       true,
+		CheckShapes:      true,
+		AllowChaos:       true,
+		LogLevel:         slog.LevelDebug,
+		StrictMode:       middleware.StrictPanic,
+		GinMode:          gin.DebugMode,
+	}
+	// Staging behaves like production, but shows server error messages and
+	// permits game-day fault injection.
+	Staging = Profile{
+		Name:             "staging",
+		ShowServerErrors: true,
+		AllowChaos:       true,
+		LogLevel:         slog.LevelInfo,
+		StrictMode:       middleware.StrictLog,
+		GinMode:          gin.ReleaseMode,
+	}
+	// Production hides server error messages and stacks and refuses fault
+	// injection.
+	Production = Profile{
+		Name:       "production",
+		LogLevel:   slog.LevelInfo,
+		StrictMode: middleware.StrictLog,
+		GinMode:    gin.ReleaseMode,
+	}
+)
+
+// profiles are the presets by name.
+var profiles = []Profile{Development, Staging, Production}
+
+// ProfileNamed returns the preset named name, ignoring case.
+func ProfileNamed(name string) (Profile, bool) {
+	for _, p := range profiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// Apply sets the process-wide settings of p. allowedServerErrors are the
+// messages still shown when p hides server error messages. The log level
+// applies to slog's default handler; build your own handlers with
+// p.LogLevel. Call it once at startup, before creating the router:
+//
+//	cfg, err := ginapi.LoadConfig()
+//	...
+//	if profile, ok := cfg.Profile(); ok {
+//	    profile.Apply()
+//	}
+//	router := gin.New()
+func (p Profile) Apply(allowedServerErrors ...string) {
+	if p.ShowServerErrors {
+		response.ShowServerErrorMessages()
+	} else {
+		response.HideServerErrorMessages(allowedServerErrors...)
+	}
+	response.SetExposeStacks(p.ExposeStacks)
+	response.SetPrettyJSON(p.PrettyJSON)
+	response.SetShapeCheck(p.CheckShapes)
+	middleware.SetStrictMode(p.StrictMode)
+	slog.SetLogLoggerLevel(p.LogLevel)
+	if p.GinMode != "" {
+		gin.SetMode(p.GinMode)
+	}
+}
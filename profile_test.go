@@ -0,0 +1,119 @@
+package ginapi_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestProfileNamed(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"development", "development", true},
+		{"Staging", "staging", true},
+		{"production", "production", true},
+		{"prod", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := ginapi.ProfileNamed(tt.name)
+			if ok != tt.wantOK || p.Name != tt.want {
+				t.Errorf("expected %q (%v), got %q (%v)", tt.want, tt.wantOK, p.Name, ok)
+			}
+		})
+	}
+}
+
+func TestProfileApply(t *testing.T) {
+	mode := gin.Mode()
+	t.Cleanup(func() {
+		response.ShowServerErrorMessages()
+		response.SetExposeStacks(false)
+		response.SetPrettyJSON(false)
+		response.SetShapeCheck(false)
+		middleware.SetStrictMode(middleware.StrictOff)
+		slog.SetLogLoggerLevel(slog.LevelInfo)
+		gin.SetMode(mode)
+	})
+
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.GET("/panic", func(c *gin.Context) { panic("nil map") })
+	router.GET("/internal", func(c *gin.Context) { response.InternalError(c, "pq: deadlock detected") })
+
+	tests := []struct {
+		profile     ginapi.Profile
+		wantPretty  bool
+		wantStack   bool
+		wantMessage bool
+	}{
+		{ginapi.Development, true, true, true},
+		{ginapi.Staging, false, false, true},
+		{ginapi.Production, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile.Name, func(t *testing.T) {
+			tt.profile.Apply()
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+			if pretty := strings.Contains(w.Body.String(), "\n  "); pretty != tt.wantPretty {
+				t.Errorf("expected pretty %v, got %s", tt.wantPretty, w.Body.String())
+			}
+			var result response.Error
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := result.Error.Details["stack"]; ok != tt.wantStack {
+				t.Errorf("expected stack %v, got details %v", tt.wantStack, result.Error.Details)
+			}
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/internal", nil))
+			if shown := strings.Contains(w.Body.String(), "deadlock"); shown != tt.wantMessage {
+				t.Errorf("expected message shown %v, got %s", tt.wantMessage, w.Body.String())
+			}
+
+			if gin.Mode() != tt.profile.GinMode {
+				t.Errorf("expected gin mode %s, got %s", tt.profile.GinMode, gin.Mode())
+			}
+		})
+	}
+}
+
+func TestConfigProfile(t *testing.T) {
+	cfg, err := ginapi.LoadConfigFrom(env(map[string]string{
+		"GINAPI_ENVIRONMENT":   "staging",
+		"GINAPI_CHAOS_ENABLED": "true",
+		"GINAPI_CHAOS_KEY":     "game-day",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p, ok := cfg.Profile(); !ok || p.Name != "staging" {
+		t.Errorf("expected the staging profile, got %q (%v)", p.Name, ok)
+	}
+	if !cfg.ChaosConfig().Enabled {
+		t.Errorf("expected chaos enabled in staging")
+	}
+
+	cfg.Environment = "production"
+	if cfg.ChaosConfig().Enabled {
+		t.Errorf("expected chaos disabled in production")
+	}
+}
@@ -0,0 +1,333 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/clock"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Error codes sent by the content proxy when it refuses a request.
+const (
+	ErrorCodeInvalidSignature = "invalid_signature" // 403, missing, wrong, or expired signature
+	ErrorCodeContentRejected  = "content_rejected"  // 502, details.reason is "type" or "size"
+)
+
+// ContentConfig configures a ContentProxy.
+type ContentConfig struct {
+	// Hosts the proxy may fetch from (required). "*.example.com" matches
+	// subdomains of example.com.
+	Hosts []string
+	// Secret signs proxy URLs (required); see ContentProxy.SignURL
+	Secret []byte
+	// PreviousSecrets are still accepted, so Secret can be rotated without
+	// breaking URLs already embedded in pages
+	PreviousSecrets [][]byte
+	// Types are the media types served (defaults to JPEG, PNG, GIF, WebP,
+	// and AVIF images)
+	Types []string
+	// MaxSize is the largest body fetched (defaults to 10MiB)
+	MaxSize int64
+	// Timeout bounds each fetch, redirects included (defaults to 10s)
+	Timeout time.Duration
+	// Cache, if set, keeps fetched content for CacheTTL, and remote 404s
+	// for its NegativeTTL
+	Cache *cache.Cache
+	// CacheTTL is how long fetched content is cached (defaults to 1h)
+	CacheTTL time.Duration
+	// CacheControl of proxied responses (defaults to "public, max-age=86400")
+	CacheControl string
+	// Transport fetches remote content (defaults to http.DefaultTransport)
+	Transport http.RoundTripper
+	// Clock checks URL expiry (defaults to clock.System)
+	Clock clock.Clock
+}
+
+// ContentProxy serves remote images (or other allowlisted content) through
+// the API, e.g. avatars and covers hosted by partners, so clients never
+// talk to third-party hosts. Only URLs signed with ContentProxy.SignURL
+// are fetched, only from the allowlisted hosts, and only bodies of the
+// allowed types and size are passed on:
+//
+//	images := proxy.NewContentProxy(proxy.ContentConfig{
+//	    Hosts:  []string{"*.partner-cdn.com"},
+//	    Secret: []byte(os.Getenv("IMAGE_PROXY_SECRET")),
+//	    Cache:  cache.Default(),
+//	})
+//	api.GET("/image-proxy", images.Handler())
+//
+//	gallery.CoverURL = images.SignURL("/api/image-proxy", remoteCover, time.Time{})
+//
+// Refusals are structured errors: 400 invalid_param for a missing or
+// malformed url, 403 invalid_signature, 403 host_not_allowed, 502
+// content_rejected for bodies of the wrong type or size, 404 when the
+// remote has no such file, and 502/504 when it fails or times out.
+type ContentProxy struct {
+	cfg    ContentConfig
+	client *http.Client
+}
+
+// NewContentProxy returns a ContentProxy for cfg. It panics if cfg has no
+// Hosts or Secret.
+func NewContentProxy(cfg ContentConfig) *ContentProxy {
+	if len(cfg.Hosts) == 0 {
+		panic("proxy: ContentConfig.Hosts is required")
+	}
+	if len(cfg.Secret) == 0 {
+		panic("proxy: ContentConfig.Secret is required")
+	}
+	if len(cfg.Types) == 0 {
+		cfg.Types = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "image/avif"}
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 10 << 20
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = time.Hour
+	}
+	if cfg.CacheControl == "" {
+		cfg.CacheControl = "public, max-age=86400"
+	}
+	cfg.Clock = clock.Or(cfg.Clock)
+
+	p := &ContentProxy{cfg: cfg}
+	p.client = &http.Client{
+		Transport: cfg.Transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 {
+				return errors.New("too many redirects")
+			}
+			if !p.allowed(req.URL) {
+				return fmt.Errorf("redirect to %s is not allowed", req.URL.Host)
+			}
+			return nil
+		},
+	}
+	return p
+}
+
+// SignURL returns the proxy URL for remote: path (where Handler is
+// mounted) with url, expires, and sig query parameters. A zero expires
+// never expires.
+func (p *ContentProxy) SignURL(path, remote string, expires time.Time) string {
+	q := url.Values{"url": {remote}}
+	exp := ""
+	if !expires.IsZero() {
+		exp = strconv.FormatInt(expires.Unix(), 10)
+		q.Set("expires", exp)
+	}
+	q.Set("sig", contentSignature(p.cfg.Secret, remote, exp))
+	return path + "?" + q.Encode()
+}
+
+// contentSignature signs a remote URL and its expiry.
+func contentSignature(secret []byte, remote, expires string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(remote + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the signature and expiry of a proxy request.
+func (p *ContentProxy) verify(remote, expires, sig string) bool {
+	if expires != "" {
+		exp, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil || p.cfg.Clock.Now().Unix() > exp {
+			return false
+		}
+	}
+	for _, secret := range append([][]byte{p.cfg.Secret}, p.cfg.PreviousSecrets...) {
+		if hmac.Equal([]byte(sig), []byte(contentSignature(secret, remote, expires))) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed reports whether u may be fetched.
+func (p *ContentProxy) allowed(u *url.URL) bool {
+	if u.Scheme != "https" && u.Scheme != "http" || u.User != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range p.cfg.Hosts {
+		h = strings.ToLower(h)
+		if suffix, ok := strings.CutPrefix(h, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == h {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler returns the handler serving signed proxy URLs. Register it for
+// GET (and HEAD).
+func (p *ContentProxy) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		remote := c.Query("url")
+		u, err := url.Parse(remote)
+		if remote == "" || err != nil || !u.IsAbs() {
+			response.ErrorWithInfo(c, http.StatusBadRequest, response.ErrorInfo{
+				Type:    response.ErrorTypeInvalidRequest,
+				Code:    response.ErrorCodeInvalidParam,
+				Message: "url must be an absolute URL",
+				Param:   "url",
+			})
+			return
+		}
+		if !p.verify(remote, c.Query("expires"), c.Query("sig")) {
+			response.ErrorWithInfo(c, http.StatusForbidden, response.ErrorInfo{
+				Type:    response.ErrorTypeForbidden,
+				Code:    ErrorCodeInvalidSignature,
+				Message: "proxy URL signature is invalid or expired",
+				Param:   "sig",
+			})
+			return
+		}
+		if !p.allowed(u) {
+			response.ErrorWithInfo(c, http.StatusForbidden, response.ErrorInfo{
+				Type:    response.ErrorTypeForbidden,
+				Code:    response.ErrorCodeHostNotAllowed,
+				Message: "host " + u.Hostname() + " is not allowed",
+				Param:   "url",
+			})
+			return
+		}
+
+		content, err := p.load(c, remote)
+		if err != nil {
+			p.fail(c, err)
+			return
+		}
+		sum := sha256.Sum256(content.Body)
+		c.Header("Cache-Control", p.cfg.CacheControl)
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Content-Security-Policy", "default-src 'none'; sandbox")
+		response.Content(c, bytes.NewReader(content.Body), response.ContentInfo{
+			Type: content.Type,
+			ETag: `"` + hex.EncodeToString(sum[:16]) + `"`,
+		})
+	}
+}
+
+// remoteContent is a fetched body, as cached.
+type remoteContent struct {
+	Type string `json:"type"`
+	Body []byte `json:"body"`
+}
+
+// refusal is a fetch failure with the error sent to the client.
+type refusal struct {
+	status int
+	info   response.ErrorInfo
+}
+
+func (r *refusal) Error() string {
+	return r.info.Message
+}
+
+// load fetches remote, through the cache if configured.
+func (p *ContentProxy) load(ctx context.Context, remote string) (remoteContent, error) {
+	if p.cfg.Cache == nil {
+		return p.fetch(ctx, remote)
+	}
+	return cache.DoWith(ctx, p.cfg.Cache, "content-proxy:"+remote, p.cfg.CacheTTL, func(ctx context.Context) (remoteContent, error) {
+		return p.fetch(ctx, remote)
+	})
+}
+
+// fetch downloads remote, checking its type and size.
+func (p *ContentProxy) fetch(ctx context.Context, remote string) (remoteContent, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, remote, nil)
+	if err != nil {
+		return remoteContent{}, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return remoteContent{}, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return remoteContent{}, cache.ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return remoteContent{}, fmt.Errorf("fetching %s: status %d", remote, resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !slices.Contains(p.cfg.Types, mediaType) {
+		return remoteContent{}, rejected("type", fmt.Sprintf("remote content type %q is not allowed", mediaType))
+	}
+	if resp.ContentLength > p.cfg.MaxSize {
+		return remoteContent{}, rejected("size", "remote content is too large")
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, p.cfg.MaxSize+1))
+	if err != nil {
+		return remoteContent{}, err
+	}
+	if int64(len(body)) > p.cfg.MaxSize {
+		return remoteContent{}, rejected("size", "remote content is too large")
+	}
+	return remoteContent{Type: mediaType, Body: body}, nil
+}
+
+func rejected(reason, message string) *refusal {
+	return &refusal{status: http.StatusBadGateway, info: response.ErrorInfo{
+		Type:    response.ErrorTypeAPI,
+		Code:    ErrorCodeContentRejected,
+		Message: message,
+		Details: map[string]any{"reason": reason},
+	}}
+}
+
+// fail sends the error for a failed load.
+func (p *ContentProxy) fail(c *gin.Context, err error) {
+	var r *refusal
+	switch {
+	case errors.As(err, &r):
+		response.ErrorWithInfo(c, r.status, r.info)
+	case errors.Is(err, cache.ErrNotFound):
+		response.NotFound(c, "remote content")
+	case c.Request.Context().Err() != nil:
+		c.Abort() // the client went away
+	case errors.Is(err, context.DeadlineExceeded):
+		response.ErrorWithInfo(c, http.StatusGatewayTimeout, response.ErrorInfo{
+			Type:    response.ErrorTypeAPI,
+			Code:    response.ErrorCodeServiceUnavailable,
+			Message: "remote host timed out",
+		})
+	default:
+		response.ReportError(c, c.Request, c.FullPath(), err)
+		response.ErrorWithInfo(c, http.StatusBadGateway, response.ErrorInfo{
+			Type:    response.ErrorTypeAPI,
+			Code:    response.ErrorCodeServiceUnavailable,
+			Message: "remote host is unavailable",
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package proxy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/apitest"
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/proxy"
+)
+
+// pngBytes is the PNG signature, enough for the proxy.
+var pngBytes = "\x89PNG\r\n\x1a\n"
+
+// remote serves test images and misbehaving routes, counting fetches.
+func remote(t *testing.T, fetches *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		switch r.URL.Path {
+		case "/cover.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(pngBytes))
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<script>alert(1)</script>"))
+		case "/huge.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(strings.Repeat("x", 100)))
+		case "/slow.png":
+			time.Sleep(100 * time.Millisecond)
+		case "/elsewhere.png":
+			http.Redirect(w, r, "http://example.com/cover.png", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestContentProxy(t *testing.T) {
+	var fetches atomic.Int32
+	srv := remote(t, &fetches)
+	clk := apitest.NewClock(time.Now())
+	images := proxy.NewContentProxy(proxy.ContentConfig{
+		Hosts:   []string{"127.0.0.1"},
+		Secret:  []byte("secret"),
+		MaxSize: 50,
+		Timeout: 20 * time.Millisecond,
+		Clock:   clk,
+	})
+	router := gin.New()
+	router.GET("/image-proxy", images.Handler())
+
+	signed := func(path string) string { return images.SignURL("/image-proxy", srv.URL+path, time.Time{}) }
+	other := proxy.NewContentProxy(proxy.ContentConfig{Hosts: []string{"127.0.0.1"}, Secret: []byte("other")})
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantCode   string
+	}{
+		{"image", signed("/cover.png"), http.StatusOK, ""},
+		{"missing url", "/image-proxy", http.StatusBadRequest, "invalid_param"},
+		{"unsigned", "/image-proxy?url=" + url.QueryEscape(srv.URL+"/cover.png"), http.StatusForbidden, proxy.ErrorCodeInvalidSignature},
+		{"other secret", other.SignURL("/image-proxy", srv.URL+"/cover.png", time.Time{}), http.StatusForbidden, proxy.ErrorCodeInvalidSignature},
+		{"expired", images.SignURL("/image-proxy", srv.URL+"/cover.png", clk.Now().Add(-time.Minute)), http.StatusForbidden, proxy.ErrorCodeInvalidSignature},
+		{"not expired", images.SignURL("/image-proxy", srv.URL+"/cover.png", clk.Now().Add(time.Minute)), http.StatusOK, ""},
+		{"host not allowed", images.SignURL("/image-proxy", "https://example.com/cover.png", time.Time{}), http.StatusForbidden, "host_not_allowed"},
+		{"wrong type", signed("/page.html"), http.StatusBadGateway, proxy.ErrorCodeContentRejected},
+		{"too large", signed("/huge.png"), http.StatusBadGateway, proxy.ErrorCodeContentRejected},
+		{"not found", signed("/missing.png"), http.StatusNotFound, ""},
+		{"timeout", signed("/slow.png"), http.StatusGatewayTimeout, "service_unavailable"},
+		{"redirect off the allowlist", signed("/elsewhere.png"), http.StatusBadGateway, "service_unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantCode != "" && !strings.Contains(w.Body.String(), `"code":"`+tt.wantCode+`"`) {
+				t.Errorf("expected code %s, got %s", tt.wantCode, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				if w.Body.String() != pngBytes || w.Header().Get("Content-Type") != "image/png" {
+					t.Errorf("expected the PNG, got %q %q", w.Header().Get("Content-Type"), w.Body.String())
+				}
+				if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+					t.Errorf("expected nosniff, got %v", w.Header())
+				}
+			}
+		})
+	}
+}
+
+func TestContentProxyCache(t *testing.T) {
+	var fetches atomic.Int32
+	srv := remote(t, &fetches)
+	images := proxy.NewContentProxy(proxy.ContentConfig{
+		Hosts:  []string{"127.0.0.1"},
+		Secret: []byte("secret"),
+		Cache:  cache.New(cache.Config{}),
+	})
+	router := gin.New()
+	router.GET("/image-proxy", images.Handler())
+
+	for _, path := range []string{"/cover.png", "/cover.png", "/missing.png", "/missing.png"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, images.SignURL("/image-proxy", srv.URL+path, time.Time{}), nil))
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("expected 2 remote fetches, got %d", n)
+	}
+}
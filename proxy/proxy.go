@@ -0,0 +1,278 @@
+// Package proxy fronts upstream (legacy) services from a ginapi router, so
+// their responses look like the rest of the API to clients:
+//
+//	legacy := proxy.Handler("http://legacy-search:8080", proxy.Options{
+//	    StripPrefix: "/api/search",
+//	    Breaker:     proxy.NewBreaker(proxy.BreakerConfig{}),
+//	})
+//	api.Any("/search/*path", legacy)
+//
+// Hop-by-hop headers are stripped, the request ID, trace context, and
+// detected language are forwarded, upstream error bodies that aren't
+// error envelopes are rewritten into one, and a circuit breaker fails fast
+// while the upstream is down.
+//
+// ContentProxy serves remote images through the API from signed URLs.
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/clock"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Options configures a proxy handler.
+type Options struct {
+	// StripPrefix is removed from the request path before it is appended
+	// to the target path
+	StripPrefix string
+	// Transport sends upstream requests (defaults to http.DefaultTransport)
+	Transport http.RoundTripper
+	// Breaker, if set, fails requests fast with a 503 while the upstream
+	// keeps failing. Share one Breaker per upstream.
+	Breaker *Breaker
+	// MaxErrorBody is the most of an upstream error body read for
+	// translation (defaults to 64 KiB)
+	MaxErrorBody int64
+}
+
+// Handler returns a handler proxying requests to target, the upstream's
+// base URL. It panics if target is not an absolute URL.
+func Handler(target string, opts Options) gin.HandlerFunc {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		panic("proxy: Handler requires an absolute target URL, got " + strconv.Quote(target))
+	}
+	if opts.MaxErrorBody <= 0 {
+		opts.MaxErrorBody = 64 << 10
+	}
+	rp := newReverseProxy(u, opts)
+
+	return func(c *gin.Context) {
+		if opts.Breaker != nil {
+			if ok, retryAfter := opts.Breaker.allow(opts.Breaker.cfg.Clock.Now()); !ok {
+				c.Header("Retry-After", strconv.Itoa(max(int((retryAfter+time.Second-1)/time.Second), 1)))
+				response.ServiceUnavailable(c, "upstream is unavailable, try again later")
+				c.Abort()
+				return
+			}
+		}
+		// The request ID middleware may have generated an ID on the response
+		if id := c.Writer.Header().Get("X-Request-ID"); id != "" {
+			c.Request.Header.Set("X-Request-ID", id)
+		}
+		rp.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// newReverseProxy builds the httputil.ReverseProxy behind Handler.
+func newReverseProxy(target *url.URL, opts Options) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Transport: opts.Transport,
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			// Hop-by-hop and X-Forwarded-* headers are already removed from pr.Out
+			if opts.StripPrefix != "" {
+				pr.Out.URL.Path = strings.TrimPrefix(pr.Out.URL.Path, opts.StripPrefix)
+				pr.Out.URL.RawPath = ""
+			}
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			forwardContext(pr)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if opts.Breaker != nil {
+				opts.Breaker.record(resp.StatusCode < 500, opts.Breaker.cfg.Clock.Now())
+			}
+			if resp.StatusCode >= 400 {
+				return translateError(resp, opts.MaxErrorBody)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if ctxErr := r.Context().Err(); ctxErr != nil {
+				// A client that went away says nothing about the upstream;
+				// a deadline it missed does
+				if opts.Breaker != nil {
+					if errors.Is(ctxErr, context.DeadlineExceeded) {
+						opts.Breaker.record(false, opts.Breaker.cfg.Clock.Now())
+					} else {
+						opts.Breaker.release()
+					}
+				}
+				return
+			}
+			if opts.Breaker != nil {
+				opts.Breaker.record(false, opts.Breaker.cfg.Clock.Now())
+			}
+			response.ReportError(r.Context(), r, "", err)
+			response.WriteError(w, r, http.StatusBadGateway, response.ErrorInfo{
+				Type:    response.ErrorTypeAPI,
+				Code:    response.ErrorCodeServiceUnavailable,
+				Message: "upstream is unavailable, try again later",
+			})
+		},
+	}
+}
+
+// forwardContext passes the detected language upstream. The request ID
+// and trace headers (traceparent, tracestate) are end-to-end and pass as is.
+func forwardContext(pr *httputil.ProxyRequest) {
+	if lang := middleware.LanguageFromContext(pr.In.Context()); lang != "" {
+		pr.Out.Header.Set("Accept-Language", lang)
+	}
+}
+
+// translateError rewrites an upstream error body into the error envelope,
+// unless it already is one. 4xx messages from JSON bodies with a "message"
+// or "error" string are kept; 5xx messages are replaced so upstream
+// internals don't leak.
+func translateError(resp *http.Response, maxBody int64) error {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	if _, err := response.ParseError(body); err == nil {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return nil
+	}
+
+	info := response.ErrorInfo{
+		Type:    response.ErrorTypeForStatus(resp.StatusCode),
+		Message: http.StatusText(resp.StatusCode),
+	}
+	if resp.StatusCode < 500 {
+		if msg := legacyMessage(body); msg != "" {
+			info.Message = msg
+		}
+	}
+	out, err := json.Marshal(response.Error{Object: "error", Error: info})
+	if err != nil {
+		return err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(out))
+	resp.ContentLength = int64(len(out))
+	resp.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(out)))
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("ETag")
+	if resp.StatusCode >= 500 {
+		resp.Header.Set("Cache-Control", "no-store")
+	}
+	return nil
+}
+
+// legacyMessage returns the "message" or "error" string of a JSON object.
+func legacyMessage(body []byte) string {
+	var v struct {
+		Message string `json:"message"`
+		Error   any    `json:"error"`
+	}
+	if json.Unmarshal(body, &v) != nil {
+		return ""
+	}
+	if v.Message != "" {
+		return v.Message
+	}
+	s, _ := v.Error.(string)
+	return s
+}
+
+// BreakerConfig configures a circuit breaker.
+type BreakerConfig struct {
+	// Failures is the number of consecutive failures (transport errors and
+	// 5xx responses) that opens the breaker (defaults to 5)
+	Failures int
+	// Cooldown is how long the breaker stays open before letting a probe
+	// request through (defaults to 30s)
+	Cooldown time.Duration
+	// Clock times the cooldown (defaults to clock.System)
+	Clock clock.Clock
+}
+
+// Breaker is a consecutive-failure circuit breaker. While open it rejects
+// requests; after Cooldown it lets one probe through, which closes it on
+// success or reopens it on failure.
+type Breaker struct {
+	cfg BreakerConfig
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time // zero while closed
+	probing  bool
+}
+
+// NewBreaker returns a closed Breaker for cfg.
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.Failures <= 0 {
+		cfg.Failures = 5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	cfg.Clock = clock.Or(cfg.Clock)
+	return &Breaker{cfg: cfg}
+}
+
+// Open reports whether the breaker is currently rejecting requests.
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.openedAt.IsZero()
+}
+
+// allow reports whether a request may go upstream, or how long until the
+// breaker lets a probe through.
+func (b *Breaker) allow(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return true, 0
+	}
+	if wait := b.openedAt.Add(b.cfg.Cooldown).Sub(now); wait > 0 {
+		return false, wait
+	}
+	if b.probing {
+		return false, b.cfg.Cooldown
+	}
+	b.probing = true
+	return true, 0
+}
+
+// release ends a request without an outcome, such as one whose client went
+// away, so a probe it was can be retried.
+func (b *Breaker) release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// record counts the outcome of an upstream request.
+func (b *Breaker) record(success bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		b.failures, b.openedAt, b.probing = 0, time.Time{}, false
+		return
+	}
+	b.failures++
+	if b.probing || b.failures >= b.cfg.Failures {
+		b.openedAt, b.probing = now, false
+	}
+}
@@ -0,0 +1,244 @@
+package proxy_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/proxy"
+)
+
+// upstream is a legacy service echoing what it received, with error
+// routes in its own format.
+func upstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/missing":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"no such gallery"}`))
+		case "/v1/crash":
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`<pre>NullPointerException at db.go:12</pre>`))
+		case "/v1/native":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"object":"error","error":{"type":"conflict","code":"already_exists","message":"exists"}}`))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{
+				"path":       r.URL.Path,
+				"request_id": r.Header.Get("X-Request-ID"),
+				"trace":      r.Header.Get("traceparent"),
+				"language":   r.Header.Get("Accept-Language"),
+				"connection": r.Header.Get("Keep-Alive"),
+			})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// serve runs router on a real server: ReverseProxy needs a ResponseWriter
+// implementing http.CloseNotifier, which httptest.ResponseRecorder doesn't.
+func serve(t *testing.T, router *gin.Engine, req *http.Request) (*http.Response, []byte) {
+	t.Helper()
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	req.URL.Scheme, req.URL.Host, req.RequestURI = "http", srv.Listener.Addr().String(), ""
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, body
+}
+
+func TestHandler(t *testing.T) {
+	srv := upstream(t)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Header("X-Request-ID", "req_1")
+		c.Next()
+	})
+	router.Use(middleware.Language(middleware.LanguageConfig{Supported: []string{"en", "ja"}}))
+	router.Any("/legacy/*path", proxy.Handler(srv.URL+"/v1", proxy.Options{StripPrefix: "/legacy"}))
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		check      func(t *testing.T, body map[string]any)
+	}{
+		{
+			name:       "forwards context",
+			path:       "/legacy/galleries",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body map[string]any) {
+				want := map[string]any{
+					"path":       "/v1/galleries",
+					"request_id": "req_1",
+					"trace":      "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+					"language":   "ja",
+					"connection": "",
+				}
+				for k, v := range want {
+					if body[k] != v {
+						t.Errorf("expected %s %q, got %q", k, v, body[k])
+					}
+				}
+			},
+		},
+		{
+			name:       "legacy 4xx keeps its message",
+			path:       "/legacy/missing",
+			wantStatus: http.StatusNotFound,
+			check: func(t *testing.T, body map[string]any) {
+				e, _ := body["error"].(map[string]any)
+				if body["object"] != "error" || e["type"] != "not_found" || e["message"] != "no such gallery" {
+					t.Errorf("unexpected envelope %v", body)
+				}
+			},
+		},
+		{
+			name:       "legacy 5xx is hidden",
+			path:       "/legacy/crash",
+			wantStatus: http.StatusInternalServerError,
+			check: func(t *testing.T, body map[string]any) {
+				e, _ := body["error"].(map[string]any)
+				if e["type"] != "api" || e["message"] != "Internal Server Error" {
+					t.Errorf("unexpected envelope %v", body)
+				}
+			},
+		},
+		{
+			name:       "envelopes pass through",
+			path:       "/legacy/native",
+			wantStatus: http.StatusConflict,
+			check: func(t *testing.T, body map[string]any) {
+				e, _ := body["error"].(map[string]any)
+				if e["code"] != "already_exists" {
+					t.Errorf("unexpected envelope %v", body)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Language", "ja-JP,en;q=0.5")
+			req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			req.Header.Set("Connection", "Keep-Alive")
+			req.Header.Set("Keep-Alive", "timeout=5")
+			resp, raw := serve(t, router, req)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, resp.StatusCode, raw)
+			}
+			if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+				t.Errorf("expected JSON, got %q", ct)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(raw, &body); err != nil {
+				t.Fatalf("invalid JSON %s: %v", raw, err)
+			}
+			tt.check(t, body)
+		})
+	}
+}
+
+func TestHandlerBreaker(t *testing.T) {
+	srv := upstream(t)
+	breaker := proxy.NewBreaker(proxy.BreakerConfig{Failures: 2, Cooldown: 50 * time.Millisecond})
+
+	router := gin.New()
+	router.Any("/b/*path", proxy.Handler(srv.URL+"/v1", proxy.Options{StripPrefix: "/b", Breaker: breaker}))
+
+	get := func(path string) *http.Response {
+		resp, _ := serve(t, router, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp
+	}
+
+	for i := 0; i < 2; i++ {
+		if resp := get("/b/crash"); resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("expected upstream 500, got %d", resp.StatusCode)
+		}
+	}
+	if !breaker.Open() {
+		t.Fatal("expected the breaker to open after 2 failures")
+	}
+	resp := get("/b/galleries")
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "1" {
+		t.Errorf("expected a fast 503 with Retry-After, got %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if resp := get("/b/galleries"); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the probe to reach the upstream, got %d", resp.StatusCode)
+	}
+	if breaker.Open() {
+		t.Error("expected a successful probe to close the breaker")
+	}
+}
+
+func TestHandlerBreakerIgnoresClientCancel(t *testing.T) {
+	received := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	breaker := proxy.NewBreaker(proxy.BreakerConfig{Failures: 1})
+
+	done := make(chan struct{})
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		defer close(done)
+		c.Next()
+	})
+	router.Any("/slow/*path", proxy.Handler(srv.URL, proxy.Options{StripPrefix: "/slow", Breaker: breaker}))
+	front := httptest.NewServer(router)
+	t.Cleanup(front.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, front.URL+"/slow/x", nil)
+	go func() {
+		<-received
+		cancel()
+	}()
+	if _, err := http.DefaultClient.Do(req); err == nil {
+		t.Fatal("expected the canceled request to fail")
+	}
+	<-done
+
+	if breaker.Open() {
+		t.Error("expected a client cancel not to open the breaker")
+	}
+}
+
+func TestHandlerUnreachable(t *testing.T) {
+	srv := upstream(t)
+	url := srv.URL
+	srv.Close()
+
+	router := gin.New()
+	router.Any("/down/*path", proxy.Handler(url, proxy.Options{}))
+
+	resp, body := serve(t, router, httptest.NewRequest(http.MethodGet, "/down/x", nil))
+	if resp.StatusCode != http.StatusBadGateway || !strings.Contains(string(body), `"code":"service_unavailable"`) {
+		t.Errorf("expected a 502 envelope, got %d %s", resp.StatusCode, body)
+	}
+}
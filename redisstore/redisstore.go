@@ -0,0 +1,133 @@
+// Package redisstore implements ginapi's Store interfaces on go-redis, so
+// multi-instance deployments share state without each service writing its
+// own adapter. It is a separate package so services that don't use Redis
+// don't depend on go-redis.
+//
+// Every store takes a key prefix, so several stores (and services) can
+// share one Redis database.
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/middleware"
+)
+
+// CacheStore implements cache.Store.
+type CacheStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+var _ cache.Store = (*CacheStore)(nil)
+
+// NewCacheStore returns a cache.Store keeping entries under prefix.
+//
+//	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	cache.SetDefault(cache.New(cache.Config{
+//	    Store: redisstore.NewCacheStore(client, "galleries:cache:"),
+//	}))
+func NewCacheStore(client redis.UniversalClient, prefix string) *CacheStore {
+	return &CacheStore{client: client, prefix: prefix}
+}
+
+// Get implements cache.Store.
+func (s *CacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Set implements cache.Store.
+func (s *CacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, value, ttl).Err()
+}
+
+// Delete implements cache.Store.
+func (s *CacheStore) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
+
+// RateLimitStore implements middleware.RateLimitStore with one counter per
+// key and window, so limits apply across every instance.
+type RateLimitStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+var _ middleware.RateLimitStore = (*RateLimitStore)(nil)
+
+// NewRateLimitStore returns a middleware.RateLimitStore keeping counters
+// under prefix.
+//
+//	router.Use(middleware.RateLimit(middleware.RateLimitConfig{
+//	    Rules: rules,
+//	    Store: redisstore.NewRateLimitStore(client, "galleries:ratelimit:"),
+//	}))
+func NewRateLimitStore(client redis.UniversalClient, prefix string) *RateLimitStore {
+	return &RateLimitStore{client: client, prefix: prefix}
+}
+
+// incrementScript increments a counter, starting its window on first use,
+// and returns the count and the milliseconds until the window resets.
+var incrementScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// Increment implements middleware.RateLimitStore.
+func (s *RateLimitStore) Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	res, err := incrementScript.Run(ctx, s.client, []string{s.prefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return res[0], time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// ChallengeStore implements middleware.ChallengeStore with one key per
+// redeemed token, expiring with it, so tokens are single-use across every
+// instance.
+type ChallengeStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+var _ middleware.ChallengeStore = (*ChallengeStore)(nil)
+
+// NewChallengeStore returns a middleware.ChallengeStore keeping redeemed
+// tokens under prefix.
+//
+//	challenger := middleware.NewChallenger(middleware.ChallengeConfig{
+//	    Secret: cfg.ChallengeSecret,
+//	    Store:  redisstore.NewChallengeStore(client, "galleries:challenge:"),
+//	})
+func NewChallengeStore(client redis.UniversalClient, prefix string) *ChallengeStore {
+	return &ChallengeStore{client: client, prefix: prefix}
+}
+
+// Redeem implements middleware.ChallengeStore.
+func (s *ChallengeStore) Redeem(ctx context.Context, token string, expires time.Time) (bool, error) {
+	err := s.client.SetArgs(ctx, s.prefix+token, 1, redis.SetArgs{Mode: "NX", ExpireAt: expires}).Err()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	return err == nil, err
+}
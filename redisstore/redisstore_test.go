@@ -0,0 +1,126 @@
+package redisstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/redisstore"
+)
+
+func TestCacheStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+	s := redisstore.NewCacheStore(client, "test:")
+
+	if _, ok, err := s.Get(ctx, "missing"); ok || err != nil {
+		t.Errorf("expected a miss, got %v %v", ok, err)
+	}
+
+	if err := s.Set(ctx, "a", []byte("1"), time.Minute); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if v, ok, err := s.Get(ctx, "a"); !ok || err != nil || string(v) != "1" {
+		t.Errorf("expected 1, got %q %v %v", v, ok, err)
+	}
+	if !mr.Exists("test:a") {
+		t.Error("expected the key to be prefixed")
+	}
+	if ttl := mr.TTL("test:a"); ttl != time.Minute {
+		t.Errorf("expected ttl 1m, got %s", ttl)
+	}
+
+	mr.FastForward(2 * time.Minute)
+	if _, ok, _ := s.Get(ctx, "a"); ok {
+		t.Error("expected a to have expired")
+	}
+
+	_ = s.Set(ctx, "b", []byte("2"), time.Minute)
+	if err := s.Delete(ctx, "b"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, ok, _ := s.Get(ctx, "b"); ok {
+		t.Error("expected b to be deleted")
+	}
+}
+
+func TestCacheStoreWithCache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	c := cache.New(cache.Config{Store: redisstore.NewCacheStore(client, "test:")})
+	calls := 0
+	fetch := func(ctx context.Context) (string, error) {
+		calls++
+		return "hello", nil
+	}
+	for i := 0; i < 2; i++ {
+		if v, err := cache.DoWith(context.Background(), c, "greeting", time.Minute, fetch); v != "hello" || err != nil {
+			t.Errorf("expected hello, got %q %v", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 fetch, got %d", calls)
+	}
+}
+
+func TestRateLimitStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+	s := redisstore.NewRateLimitStore(client, "test:")
+
+	for want := int64(1); want <= 3; want++ {
+		count, resetIn, err := s.Increment(ctx, "ip|1.2.3.4", time.Minute)
+		if err != nil {
+			t.Fatalf("increment failed: %v", err)
+		}
+		if count != want {
+			t.Errorf("expected count %d, got %d", want, count)
+		}
+		if resetIn <= 0 || resetIn > time.Minute {
+			t.Errorf("expected reset within the window, got %s", resetIn)
+		}
+	}
+	if !mr.Exists("test:ip|1.2.3.4") {
+		t.Error("expected the key to be prefixed")
+	}
+
+	mr.FastForward(2 * time.Minute)
+	if count, _, _ := s.Increment(ctx, "ip|1.2.3.4", time.Minute); count != 1 {
+		t.Errorf("expected a new window after expiry, got %d", count)
+	}
+}
+
+func TestChallengeStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+	s := redisstore.NewChallengeStore(client, "test:")
+	expires := time.Now().Add(time.Minute)
+
+	if ok, err := s.Redeem(ctx, "tok", expires); err != nil || !ok {
+		t.Errorf("expected the first redemption to succeed, got %v %v", ok, err)
+	}
+	if ok, err := s.Redeem(ctx, "tok", expires); err != nil || ok {
+		t.Errorf("expected a reused token to be rejected, got %v %v", ok, err)
+	}
+	if !mr.Exists("test:tok") {
+		t.Error("expected the key to be prefixed")
+	}
+	if ttl := mr.TTL("test:tok"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("expected the key to expire with the token, got %s", ttl)
+	}
+}
@@ -0,0 +1,86 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeadersConfig configures Headers.
+type HeadersConfig struct {
+	// Endpoint is the absolute URL Handler is mounted at (required)
+	Endpoint string
+	// Group is the endpoint's name, referenced by the CSP report-to
+	// directive (defaults to "default")
+	Group string
+	// MaxAge is how long browsers remember the endpoint and NEL policy
+	// (defaults to 1 day)
+	MaxAge time.Duration
+	// NEL enables Network Error Logging
+	NEL bool
+	// NELFailureFraction is the fraction of failed requests reported
+	// (defaults to 1)
+	NELFailureFraction float64
+	// NELSuccessFraction is the fraction of successful requests reported,
+	// for a baseline (defaults to 0)
+	NELSuccessFraction float64
+}
+
+// Headers returns middleware telling browsers where to send reports:
+// Reporting-Endpoints for CSP reports, and, with NEL, the Report-To and
+// NEL headers NEL still requires. It panics if Endpoint is empty or a
+// fraction is outside [0, 1].
+func Headers(cfg HeadersConfig) gin.HandlerFunc {
+	if cfg.Endpoint == "" {
+		panic("reporting: HeadersConfig requires an Endpoint")
+	}
+	if cfg.Group == "" {
+		cfg.Group = "default"
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = 24 * time.Hour
+	}
+	if cfg.NELFailureFraction == 0 {
+		cfg.NELFailureFraction = 1
+	}
+	for _, f := range []float64{cfg.NELFailureFraction, cfg.NELSuccessFraction} {
+		if f < 0 || f > 1 {
+			panic(fmt.Sprintf("reporting: NEL fraction %v must be between 0 and 1", f))
+		}
+	}
+
+	endpoints := cfg.Group + "=" + strconv.Quote(cfg.Endpoint)
+	var reportTo, nel string
+	if cfg.NEL {
+		maxAge := int64(cfg.MaxAge.Seconds())
+		b, _ := json.Marshal(map[string]any{
+			"group":     cfg.Group,
+			"max_age":   maxAge,
+			"endpoints": []map[string]string{{"url": cfg.Endpoint}},
+		})
+		reportTo = string(b)
+		policy := map[string]any{
+			"report_to":        cfg.Group,
+			"max_age":          maxAge,
+			"failure_fraction": cfg.NELFailureFraction,
+		}
+		if cfg.NELSuccessFraction > 0 {
+			policy["success_fraction"] = cfg.NELSuccessFraction
+		}
+		b, _ = json.Marshal(policy)
+		nel = string(b)
+	}
+
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("Reporting-Endpoints", endpoints)
+		if nel != "" {
+			h.Set("Report-To", reportTo)
+			h.Set("NEL", nel)
+		}
+		c.Next()
+	}
+}
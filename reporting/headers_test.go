@@ -0,0 +1,73 @@
+package reporting_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/reporting"
+)
+
+func TestHeaders(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           reporting.HeadersConfig
+		wantEndpoints string
+		wantNEL       map[string]any
+	}{
+		{
+			name:          "reporting endpoints only",
+			cfg:           reporting.HeadersConfig{Endpoint: "https://api.example.com/reports"},
+			wantEndpoints: `default="https://api.example.com/reports"`,
+		},
+		{
+			name: "nel",
+			cfg: reporting.HeadersConfig{
+				Endpoint: "https://api.example.com/reports", Group: "csp", MaxAge: time.Hour,
+				NEL: true, NELSuccessFraction: 0.01,
+			},
+			wantEndpoints: `csp="https://api.example.com/reports"`,
+			wantNEL:       map[string]any{"report_to": "csp", "max_age": 3600.0, "failure_fraction": 1.0, "success_fraction": 0.01},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", reporting.Headers(tt.cfg), func(c *gin.Context) {})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if got := w.Header().Get("Reporting-Endpoints"); got != tt.wantEndpoints {
+				t.Errorf("expected Reporting-Endpoints %s, got %s", tt.wantEndpoints, got)
+			}
+			if tt.wantNEL == nil {
+				if w.Header().Get("NEL") != "" || w.Header().Get("Report-To") != "" {
+					t.Errorf("expected no NEL headers, got %v", w.Header())
+				}
+				return
+			}
+			var nel map[string]any
+			if err := json.Unmarshal([]byte(w.Header().Get("NEL")), &nel); err != nil {
+				t.Fatalf("expected a JSON NEL header, got %q", w.Header().Get("NEL"))
+			}
+			for k, v := range tt.wantNEL {
+				if nel[k] != v {
+					t.Errorf("expected NEL %s %v, got %v", k, v, nel[k])
+				}
+			}
+			var reportTo struct {
+				Group     string `json:"group"`
+				Endpoints []struct{ URL string }
+			}
+			if err := json.Unmarshal([]byte(w.Header().Get("Report-To")), &reportTo); err != nil ||
+				reportTo.Group != "csp" || len(reportTo.Endpoints) != 1 || reportTo.Endpoints[0].URL != tt.cfg.Endpoint {
+				t.Errorf("unexpected Report-To %q", w.Header().Get("Report-To"))
+			}
+		})
+	}
+}
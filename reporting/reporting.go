@@ -0,0 +1,302 @@
+// Package reporting collects the reports browsers send about a site:
+// Content Security Policy violations and Network Error Logging (NEL)
+// failures. Headers tells browsers where to send them, and Handler
+// receives, validates, and forwards them to a Sink:
+//
+//	router.Use(reporting.Headers(reporting.HeadersConfig{
+//	    Endpoint: "https://api.example.com/reports",
+//	    NEL:      true,
+//	}))
+//	router.POST("/reports", reporting.Handler(reporting.Config{
+//	    Sink: reporting.SinkFunc(func(ctx context.Context, reports []reporting.Report) error {
+//	        return analytics.Insert(ctx, reports)
+//	    }),
+//	}))
+//
+// Reference the endpoint in the CSP with both directives, so browsers
+// with and without the Reporting API send reports:
+//
+//	Content-Security-Policy: default-src 'self'; report-to default; report-uri /reports
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Report types.
+const (
+	TypeCSPViolation = "csp-violation"
+	TypeNetworkError = "network-error"
+)
+
+// ErrorCodeTooLarge is the error code of 413 responses to report bodies
+// over Config.MaxBodyBytes.
+const ErrorCodeTooLarge = "reports_too_large"
+
+// Report is one report, in the Reporting API format. Legacy CSP reports
+// (report-uri) are converted to it.
+type Report struct {
+	// Type is TypeCSPViolation, TypeNetworkError, or another type listed
+	// in Config.Types
+	Type string `json:"type"`
+	// Age is how long ago the report was generated, in milliseconds
+	Age int64 `json:"age"`
+	// URL of the document (CSP) or request (NEL) the report is about
+	URL string `json:"url"`
+	// UserAgent of the browser that sent it
+	UserAgent string `json:"user_agent"`
+	// Body is the type-specific body; see CSPViolation and NetworkError
+	Body json.RawMessage `json:"body"`
+}
+
+// CSPViolation is the body of a csp-violation report.
+type CSPViolation struct {
+	DocumentURL        string `json:"documentURL"`
+	Referrer           string `json:"referrer,omitempty"`
+	BlockedURL         string `json:"blockedURL,omitempty"`
+	EffectiveDirective string `json:"effectiveDirective"`
+	OriginalPolicy     string `json:"originalPolicy"`
+	SourceFile         string `json:"sourceFile,omitempty"`
+	Sample             string `json:"sample,omitempty"`
+	Disposition        string `json:"disposition"` // "enforce" or "report"
+	StatusCode         int    `json:"statusCode"`
+	LineNumber         int    `json:"lineNumber,omitempty"`
+	ColumnNumber       int    `json:"columnNumber,omitempty"`
+}
+
+// NetworkError is the body of a network-error report.
+type NetworkError struct {
+	Referrer         string  `json:"referrer,omitempty"`
+	SamplingFraction float64 `json:"sampling_fraction"`
+	ServerIP         string  `json:"server_ip,omitempty"`
+	Protocol         string  `json:"protocol,omitempty"`
+	Method           string  `json:"method,omitempty"`
+	StatusCode       int     `json:"status_code,omitempty"`
+	ElapsedTime      int64   `json:"elapsed_time,omitempty"` // ms
+	Phase            string  `json:"phase"`                  // "dns", "connection", or "application"
+	Type             string  `json:"type"`                   // e.g. "tcp.timed_out" or "ok"
+}
+
+// CSPViolation decodes the body of a csp-violation report.
+func (r Report) CSPViolation() (CSPViolation, bool) {
+	var v CSPViolation
+	if r.Type != TypeCSPViolation || json.Unmarshal(r.Body, &v) != nil {
+		return CSPViolation{}, false
+	}
+	return v, true
+}
+
+// NetworkError decodes the body of a network-error report.
+func (r Report) NetworkError() (NetworkError, bool) {
+	var v NetworkError
+	if r.Type != TypeNetworkError || json.Unmarshal(r.Body, &v) != nil {
+		return NetworkError{}, false
+	}
+	return v, true
+}
+
+// Sink receives the reports of one request.
+type Sink interface {
+	Report(ctx context.Context, reports []Report) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(ctx context.Context, reports []Report) error
+
+// Report calls f(ctx, reports).
+func (f SinkFunc) Report(ctx context.Context, reports []Report) error {
+	return f(ctx, reports)
+}
+
+// LogSink returns a Sink logging each report at warn with logger (nil for
+// slog.Default()).
+func LogSink(logger *slog.Logger) Sink {
+	return SinkFunc(func(ctx context.Context, reports []Report) error {
+		l := logger
+		if l == nil {
+			l = slog.Default()
+		}
+		for _, r := range reports {
+			l.LogAttrs(ctx, slog.LevelWarn, "browser report",
+				slog.String("type", r.Type),
+				slog.String("url", r.URL),
+				slog.String("user_agent", r.UserAgent),
+				slog.String("body", string(r.Body)),
+			)
+		}
+		return nil
+	})
+}
+
+// Config configures Handler.
+type Config struct {
+	// Sink receives the reports (defaults to LogSink(nil))
+	Sink Sink
+	// Types are the report types accepted; others are dropped (defaults to
+	// TypeCSPViolation and TypeNetworkError)
+	Types []string
+	// MaxBodyBytes caps the request body (defaults to 64KB)
+	MaxBodyBytes int64
+	// MaxReports caps the reports forwarded per request; the rest are
+	// dropped (defaults to 100)
+	MaxReports int
+}
+
+// Handler returns a handler receiving reports, for the endpoint Headers
+// and the CSP report-uri directive point at. It accepts the Reporting API
+// format (application/reports+json, a list of reports) and legacy CSP
+// reports (application/csp-report), validates them, and forwards those
+// of the accepted Types to the Sink with the request context. It answers
+// 204, 400 for malformed bodies, 413 for bodies over MaxBodyBytes, and
+// 415 for other content types. Sink errors are reported to the Reporter
+// set with response.SetReporter, and still answered with 204: browsers
+// don't retry.
+func Handler(cfg Config) gin.HandlerFunc {
+	if cfg.Sink == nil {
+		cfg.Sink = LogSink(nil)
+	}
+	if len(cfg.Types) == 0 {
+		cfg.Types = []string{TypeCSPViolation, TypeNetworkError}
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 64 << 10
+	}
+	if cfg.MaxReports <= 0 {
+		cfg.MaxReports = 100
+	}
+
+	return func(c *gin.Context) {
+		mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if mediaType != "application/reports+json" && mediaType != "application/csp-report" {
+			response.UnsupportedMediaType(c, "reports must be application/reports+json or application/csp-report")
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, cfg.MaxBodyBytes+1))
+		if err != nil {
+			response.BadRequest(c, "failed to read reports")
+			return
+		}
+		if int64(len(body)) > cfg.MaxBodyBytes {
+			response.ErrorWithInfo(c, http.StatusRequestEntityTooLarge, response.ErrorInfo{
+				Type:    response.ErrorTypeInvalidRequest,
+				Code:    ErrorCodeTooLarge,
+				Message: "reports exceed the maximum size",
+				Details: map[string]any{"max_size": cfg.MaxBodyBytes},
+			})
+			return
+		}
+
+		var reports []Report
+		if mediaType == "application/csp-report" {
+			reports, err = parseCSPReport(body, c.Request.UserAgent())
+		} else {
+			reports, err = parseReports(body)
+		}
+		if err != nil {
+			response.BadRequestWithCode(c, response.ErrorCodeInvalidFormat, err.Error())
+			return
+		}
+
+		reports = slices.DeleteFunc(reports, func(r Report) bool { return !slices.Contains(cfg.Types, r.Type) })
+		if len(reports) > cfg.MaxReports {
+			reports = reports[:cfg.MaxReports]
+		}
+		if len(reports) > 0 {
+			if err := cfg.Sink.Report(c.Request.Context(), reports); err != nil {
+				response.ReportError(c.Request.Context(), c.Request, c.FullPath(), err)
+			}
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// parseReports parses a Reporting API body.
+func parseReports(body []byte) ([]Report, error) {
+	var raw []struct {
+		Type      string          `json:"type"`
+		Age       int64           `json:"age"`
+		URL       string          `json:"url"`
+		UserAgent string          `json:"user_agent"`
+		Body      json.RawMessage `json:"body"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, errors.New("reports must be a JSON array of reports")
+	}
+	reports := make([]Report, 0, len(raw))
+	for i, r := range raw {
+		if r.Type == "" || r.URL == "" || !isObject(r.Body) {
+			return nil, fmt.Errorf("report %d needs a type, url, and body object", i)
+		}
+		reports = append(reports, Report(r))
+	}
+	return reports, nil
+}
+
+// parseCSPReport converts a legacy report-uri body to a csp-violation
+// report.
+func parseCSPReport(body []byte, userAgent string) ([]Report, error) {
+	var legacy struct {
+		Report *struct {
+			DocumentURI        string `json:"document-uri"`
+			Referrer           string `json:"referrer"`
+			BlockedURI         string `json:"blocked-uri"`
+			ViolatedDirective  string `json:"violated-directive"`
+			EffectiveDirective string `json:"effective-directive"`
+			OriginalPolicy     string `json:"original-policy"`
+			SourceFile         string `json:"source-file"`
+			ScriptSample       string `json:"script-sample"`
+			Disposition        string `json:"disposition"`
+			StatusCode         int    `json:"status-code"`
+			LineNumber         int    `json:"line-number"`
+			ColumnNumber       int    `json:"column-number"`
+		} `json:"csp-report"`
+	}
+	if err := json.Unmarshal(body, &legacy); err != nil || legacy.Report == nil || legacy.Report.DocumentURI == "" {
+		return nil, errors.New(`report must be a JSON object with a "csp-report" that has a document-uri`)
+	}
+	l := legacy.Report
+	v := CSPViolation{
+		DocumentURL:        l.DocumentURI,
+		Referrer:           l.Referrer,
+		BlockedURL:         l.BlockedURI,
+		EffectiveDirective: l.EffectiveDirective,
+		OriginalPolicy:     l.OriginalPolicy,
+		SourceFile:         l.SourceFile,
+		Sample:             l.ScriptSample,
+		Disposition:        l.Disposition,
+		StatusCode:         l.StatusCode,
+		LineNumber:         l.LineNumber,
+		ColumnNumber:       l.ColumnNumber,
+	}
+	if v.EffectiveDirective == "" {
+		// Older browsers send the whole directive, e.g. "script-src 'self'"
+		v.EffectiveDirective, _, _ = strings.Cut(l.ViolatedDirective, " ")
+	}
+	if v.Disposition == "" {
+		v.Disposition = "enforce"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return []Report{{Type: TypeCSPViolation, URL: v.DocumentURL, UserAgent: userAgent, Body: data}}, nil
+}
+
+// isObject reports whether data is a JSON object.
+func isObject(data json.RawMessage) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
@@ -0,0 +1,129 @@
+package reporting_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/reporting"
+	"github.com/doujins-org/ginapi/response"
+)
+
+const cspReport = `{"csp-report":{"document-uri":"https://example.com/g/1","violated-directive":"script-src 'self'",` +
+	`"original-policy":"script-src 'self'","blocked-uri":"https://evil.example/x.js","status-code":200}}`
+
+const reportsBody = `[
+	{"type":"csp-violation","age":10,"url":"https://example.com/","user_agent":"Mozilla/5.0",
+	 "body":{"documentURL":"https://example.com/","effectiveDirective":"img-src","disposition":"report","statusCode":200}},
+	{"type":"network-error","age":0,"url":"https://api.example.com/galleries","user_agent":"Mozilla/5.0",
+	 "body":{"phase":"connection","type":"tcp.timed_out","sampling_fraction":1,"elapsed_time":30000}},
+	{"type":"deprecation","url":"https://example.com/","body":{"id":"x"}}
+]`
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		maxReports  int
+		sinkErr     error
+		wantStatus  int
+		wantTypes   []string
+	}{
+		{name: "reporting api", contentType: "application/reports+json", body: reportsBody, wantStatus: 204,
+			wantTypes: []string{"csp-violation", "network-error"}},
+		{name: "max reports", contentType: "application/reports+json", body: reportsBody, maxReports: 1, wantStatus: 204,
+			wantTypes: []string{"csp-violation"}},
+		{name: "legacy csp", contentType: "application/csp-report", body: cspReport, wantStatus: 204,
+			wantTypes: []string{"csp-violation"}},
+		{name: "sink error", contentType: "application/csp-report", body: cspReport, sinkErr: errors.New("down"), wantStatus: 204,
+			wantTypes: []string{"csp-violation"}},
+		{name: "malformed", contentType: "application/reports+json", body: `{"type":"csp-violation"}`, wantStatus: 400},
+		{name: "missing body", contentType: "application/reports+json", body: `[{"type":"csp-violation","url":"https://example.com/"}]`, wantStatus: 400},
+		{name: "legacy without document", contentType: "application/csp-report", body: `{"csp-report":{}}`, wantStatus: 400},
+		{name: "wrong content type", contentType: "text/plain", body: cspReport, wantStatus: 415},
+		{name: "too large", contentType: "application/reports+json", body: "[" + strings.Repeat(" ", 70<<10) + "]", wantStatus: 413},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []reporting.Report
+			router := gin.New()
+			router.POST("/reports", reporting.Handler(reporting.Config{
+				MaxReports: tt.maxReports,
+				Sink: reporting.SinkFunc(func(ctx context.Context, reports []reporting.Report) error {
+					got = reports
+					return tt.sinkErr
+				}),
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/reports", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("User-Agent", "Mozilla/5.0")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body)
+			}
+			if len(got) != len(tt.wantTypes) {
+				t.Fatalf("expected %d reports, got %+v", len(tt.wantTypes), got)
+			}
+			for i, r := range got {
+				if r.Type != tt.wantTypes[i] {
+					t.Errorf("expected report %d of type %s, got %s", i, tt.wantTypes[i], r.Type)
+				}
+			}
+			if w.Code == 413 {
+				if e, err := response.ParseError(w.Body.Bytes()); err != nil || e.Error.Code != reporting.ErrorCodeTooLarge {
+					t.Errorf("expected code %s, got %s", reporting.ErrorCodeTooLarge, w.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestReportBodies(t *testing.T) {
+	var got []reporting.Report
+	router := gin.New()
+	router.POST("/reports", reporting.Handler(reporting.Config{
+		Sink: reporting.SinkFunc(func(ctx context.Context, reports []reporting.Report) error {
+			got = append(got, reports...)
+			return nil
+		}),
+	}))
+	for _, r := range []struct{ contentType, body string }{
+		{"application/csp-report", cspReport},
+		{"application/reports+json", reportsBody},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/reports", strings.NewReader(r.body))
+		req.Header.Set("Content-Type", r.contentType)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 reports, got %d", len(got))
+	}
+
+	v, ok := got[0].CSPViolation()
+	if !ok || v.EffectiveDirective != "script-src" || v.BlockedURL != "https://evil.example/x.js" || v.Disposition != "enforce" {
+		t.Errorf("unexpected legacy violation %+v", v)
+	}
+	if got[0].URL != "https://example.com/g/1" {
+		t.Errorf("expected the document URL, got %s", got[0].URL)
+	}
+	if v, ok := got[1].CSPViolation(); !ok || v.EffectiveDirective != "img-src" || v.Disposition != "report" {
+		t.Errorf("unexpected violation %+v", v)
+	}
+	ne, ok := got[2].NetworkError()
+	if !ok || ne.Phase != "connection" || ne.Type != "tcp.timed_out" || ne.ElapsedTime != 30000 {
+		t.Errorf("unexpected network error %+v", ne)
+	}
+	if _, ok := got[2].CSPViolation(); ok {
+		t.Errorf("expected a network error not to decode as a CSP violation")
+	}
+}
@@ -0,0 +1,166 @@
+// Package requestctx gathers everything ginapi stores in a request's
+// context into one snapshot, so service and repository layers can read
+// the language, request ID, client IP, trace, principal, tenant, and client
+// details without importing the middleware, auth, and response packages:
+//
+//	router.Use(requestctx.Middleware())
+//
+//	func (s *Service) ListGalleries(ctx context.Context) ([]Gallery, error) {
+//	    rc := requestctx.From(ctx)
+//	    s.log.Info("listing galleries", "request_id", rc.RequestID, "lang", rc.Language)
+//	    ...
+//	}
+package requestctx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+	"github.com/doujins-org/ginapi/tenant"
+)
+
+// Values is a snapshot of the request-scoped values ginapi middleware
+// stores in context. Fields whose middleware didn't run are zero.
+type Values struct {
+	// Language detected by middleware.Language
+	Language string
+	// Currency detected by middleware.Currency
+	Currency string
+	// RequestID is the X-Request-ID, captured by Middleware
+	RequestID string
+	// ClientIP is the client address, captured by Middleware
+	ClientIP string
+	// TraceID is the W3C traceparent trace ID, captured by Middleware
+	TraceID string
+	// Client details from middleware.ClientInfo; HasClient reports whether it ran
+	Client    middleware.Client
+	HasClient bool
+	// Priority assigned by middleware.PriorityClassifier (PriorityNormal if it didn't run)
+	Priority middleware.Priority
+	// Audiences granted with response.SetAudiences or response.WithAudiences
+	Audiences []string
+	// Principal set by auth.SetPrincipal; HasPrincipal is false for anonymous requests
+	Principal    auth.Principal
+	HasPrincipal bool
+	// Tenant resolved by tenant.Middleware; HasTenant is false without one
+	Tenant    tenant.Tenant
+	HasTenant bool
+}
+
+// From returns the values stored in ctx, which may be a request context or
+// a *gin.Context.
+func From(ctx context.Context) Values {
+	var v Values
+	if ctx == nil {
+		return v
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		v.Audiences = response.Audiences(c)
+		if c.Request == nil {
+			return v
+		}
+		ctx = c.Request.Context()
+	} else {
+		v.Audiences = response.AudiencesFromContext(ctx)
+	}
+
+	v.Language = middleware.LanguageFromContext(ctx)
+	v.Currency = middleware.CurrencyFromContext(ctx)
+	v.Client, v.HasClient = middleware.ClientFromContext(ctx)
+	v.Principal, v.HasPrincipal = auth.PrincipalFromContext(ctx)
+	v.Tenant, v.HasTenant = tenant.FromContext(ctx)
+	v.Priority = middleware.PriorityNormal
+	if p, ok := middleware.PriorityFromContext(ctx); ok {
+		v.Priority = p
+	}
+	if m, ok := ctx.Value(metaContextKey{}).(meta); ok {
+		v.RequestID, v.ClientIP, v.TraceID = m.requestID, m.clientIP, m.traceID
+	}
+	return v
+}
+
+// meta holds the values captured by Middleware and Handler.
+type meta struct {
+	requestID string
+	clientIP  string
+	traceID   string
+}
+
+// metaContextKey is the request context key for meta.
+type metaContextKey struct{}
+
+// WithRequestID returns a copy of ctx whose Values carry requestID, keeping
+// the client IP and trace ID it has. Use it to restore the request ID in
+// work that runs outside the request, such as a queued task.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	m, _ := ctx.Value(metaContextKey{}).(meta)
+	m.requestID = requestID
+	return context.WithValue(ctx, metaContextKey{}, m)
+}
+
+// Middleware returns middleware that captures the request ID, client IP
+// (c.ClientIP, which honors gin's trusted proxies), and trace ID into the
+// request context for From. Install it after your request ID middleware.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m := meta{
+			requestID: requestID(c.Writer, c.Request),
+			clientIP:  c.ClientIP(),
+			traceID:   traceID(c.Request.Header.Get("traceparent")),
+		}
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), metaContextKey{}, m))
+		c.Next()
+	}
+}
+
+// Handler is the net/http equivalent of Middleware. The client IP is the
+// host of r.RemoteAddr; put a trusted proxy middleware in front to rewrite it.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := meta{
+			requestID: requestID(w, r),
+			clientIP:  remoteIP(r.RemoteAddr),
+			traceID:   traceID(r.Header.Get("traceparent")),
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), metaContextKey{}, m)))
+	})
+}
+
+// requestID returns the X-Request-ID set on the response or, failing that,
+// sent with the request.
+func requestID(w http.ResponseWriter, r *http.Request) string {
+	if id := w.Header().Get("X-Request-ID"); id != "" {
+		return id
+	}
+	return r.Header.Get("X-Request-ID")
+}
+
+// remoteIP strips the port from a RemoteAddr.
+func remoteIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// traceID returns the trace ID of a W3C traceparent header
+// ("00-<trace-id>-<parent-id>-<flags>"), or "" if it is malformed.
+func traceID(traceparent string) string {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || parts[1] == strings.Repeat("0", 32) {
+		return ""
+	}
+	for _, r := range parts[1] {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f') {
+			return ""
+		}
+	}
+	return parts[1]
+}
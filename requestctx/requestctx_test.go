@@ -0,0 +1,132 @@
+package requestctx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/requestctx"
+	"github.com/doujins-org/ginapi/response"
+	"github.com/doujins-org/ginapi/tenant"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestFromGin(t *testing.T) {
+	var fromGin, fromRequest requestctx.Values
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Header("X-Request-ID", "req_123")
+		c.Next()
+	})
+	router.Use(requestctx.Middleware())
+	router.Use(middleware.Language(middleware.LanguageConfig{Supported: []string{"en", "ja"}}))
+	router.Use(middleware.Currency(middleware.CurrencyConfig{Supported: []string{"USD", "JPY"}}))
+	router.Use(middleware.ClientInfo(middleware.ClientInfoConfig{}))
+	router.Use(middleware.PriorityClassifier(middleware.PriorityConfig{
+		Classify: func(*gin.Context) (middleware.Priority, bool) { return middleware.PriorityHigh, true },
+	}))
+	router.GET("/test", func(c *gin.Context) {
+		response.SetAudiences(c, "owner")
+		auth.SetPrincipal(c, auth.Principal{ID: "usr_1", Type: auth.TypeUser})
+		tenant.Set(c, tenant.Tenant{ID: "acme"})
+		fromGin = requestctx.From(c)
+		fromRequest = requestctx.From(c.Request.Context())
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test?lang=ja", nil)
+	req.RemoteAddr = "203.0.113.7:5123"
+	req.Header.Set("Accept-Language", "ja-JP")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148 Safari/604.1")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(w, req)
+
+	for name, v := range map[string]requestctx.Values{"gin": fromGin, "request": fromRequest} {
+		if v.Language != "ja" {
+			t.Errorf("%s: expected language 'ja', got %q", name, v.Language)
+		}
+		if v.Currency != "JPY" {
+			t.Errorf("%s: expected currency 'JPY', got %q", name, v.Currency)
+		}
+		if v.RequestID != "req_123" {
+			t.Errorf("%s: expected request ID 'req_123', got %q", name, v.RequestID)
+		}
+		if v.ClientIP != "203.0.113.7" {
+			t.Errorf("%s: expected client IP '203.0.113.7', got %q", name, v.ClientIP)
+		}
+		if v.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("%s: expected trace ID, got %q", name, v.TraceID)
+		}
+		if !v.HasClient || v.Client.Device != middleware.DeviceMobile {
+			t.Errorf("%s: expected mobile client, got %+v", name, v.Client)
+		}
+		if !v.HasPrincipal || v.Principal.ID != "usr_1" {
+			t.Errorf("%s: expected principal usr_1, got %+v", name, v.Principal)
+		}
+		if !v.HasTenant || v.Tenant.ID != "acme" {
+			t.Errorf("%s: expected tenant acme, got %+v", name, v.Tenant)
+		}
+		if v.Priority != middleware.PriorityHigh {
+			t.Errorf("%s: expected priority high, got %s", name, v.Priority)
+		}
+	}
+	if len(fromGin.Audiences) != 1 || fromGin.Audiences[0] != "owner" {
+		t.Errorf("expected audiences [owner], got %v", fromGin.Audiences)
+	}
+}
+
+func TestFromHTTP(t *testing.T) {
+	var got requestctx.Values
+	h := requestctx.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = requestctx.From(r.Context())
+	}))
+
+	tests := []struct {
+		traceparent string
+		want        string
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", ""},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", ""},
+		{"garbage", ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "198.51.100.2:443"
+		req.Header.Set("X-Request-ID", "req_456")
+		req.Header.Set("traceparent", tt.traceparent)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		if got.TraceID != tt.want {
+			t.Errorf("traceparent %q: expected trace ID %q, got %q", tt.traceparent, tt.want, got.TraceID)
+		}
+		if got.RequestID != "req_456" || got.ClientIP != "198.51.100.2" {
+			t.Errorf("expected request ID and client IP, got %+v", got)
+		}
+		if got.HasClient || got.HasPrincipal || got.HasTenant || got.Language != "" || got.Priority != middleware.PriorityNormal {
+			t.Errorf("expected zero values for middleware that didn't run, got %+v", got)
+		}
+	}
+}
+
+func TestFromEmpty(t *testing.T) {
+	if v := requestctx.From(context.Background()); v.RequestID != "" || v.Language != "" || v.HasClient {
+		t.Errorf("expected zero values, got %+v", v)
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	if v := requestctx.From(requestctx.WithRequestID(context.Background(), "req-1")); v.RequestID != "req-1" {
+		t.Errorf("expected request ID req-1, got %+v", v)
+	}
+}
@@ -0,0 +1,109 @@
+package response
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AbsentObject is the response for a singular resource that doesn't
+// exist, when that is an expected state rather than an error.
+type AbsentObject struct {
+	Object string `json:"object"`
+	Exists bool   `json:"exists"` // Always false
+}
+
+// ObjectOrNotFound sends obj if found, and otherwise 200 OK with the
+// absent object of its type, for endpoints where "not found" is routine,
+// such as the current user's subscription:
+//
+//	sub, found, err := repo.CurrentSubscription(ctx, userID)
+//	if err != nil {
+//	    response.InternalError(c, "failed to load the subscription")
+//	    return
+//	}
+//	response.ObjectOrNotFound(c, sub, found)
+//	// {"object": "subscription", "exists": false}
+//
+// Clients check exists instead of handling a 404, so error tracking only
+// sees real failures. The object type is obj's "object" field, or its Go
+// type name in snake_case when that is empty (Subscription is
+// "subscription"), so obj may be a zero value or a typed nil pointer.
+func ObjectOrNotFound(c *gin.Context, obj any, found bool) {
+	objectOrNotFound(ginOutput(c), obj, found)
+}
+
+// objectOrNotFound is ObjectOrNotFound for o.
+func objectOrNotFound(o output, obj any, found bool) {
+	if found {
+		renderObject(o, http.StatusOK, obj)
+		return
+	}
+	renderObject(o, http.StatusOK, AbsentObject{Object: objectTypeOf(obj)})
+}
+
+// objectTypeOf returns the "object" field of obj, or its type name in
+// snake_case.
+func objectTypeOf(obj any) string {
+	t := reflect.TypeOf(obj)
+	if t == nil {
+		return "object"
+	}
+	v := reflect.ValueOf(obj)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		if !v.IsNil() {
+			v = v.Elem()
+		}
+	}
+	if t.Kind() == reflect.Struct && v.Kind() == reflect.Struct {
+		if objectType, _ := jsonObjectField(v); objectType != "" {
+			return objectType
+		}
+	}
+	if t.Name() == "" {
+		return "object"
+	}
+	return snakeCaseName(t.Name())
+}
+
+// jsonObjectField returns the string field of struct v sent as "object".
+func jsonObjectField(v reflect.Value) (string, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			if objectType, ok := jsonObjectField(v.Field(i)); ok {
+				return objectType, true
+			}
+			continue
+		}
+		if name == "object" && f.IsExported() && f.Type.Kind() == reflect.String {
+			return v.Field(i).String(), true
+		}
+	}
+	return "", false
+}
+
+// snakeCaseName converts a Go type name to snake_case, keeping initialisms
+// together: "GallerySubscription" is "gallery_subscription" and "APIKey"
+// is "api_key".
+func snakeCaseName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
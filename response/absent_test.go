@@ -0,0 +1,74 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+type Subscription struct {
+	Object string `json:"object"`
+	ID     string `json:"id"`
+}
+
+type APIKey struct {
+	ID string `json:"id"`
+}
+
+type objectBase struct {
+	Object string `json:"object"`
+}
+
+type embeddedObject struct {
+	objectBase
+	ID string `json:"id"`
+}
+
+func TestObjectOrNotFound(t *testing.T) {
+	tests := []struct {
+		name     string
+		obj      any
+		found    bool
+		wantBody string
+	}{
+		{"found", Subscription{Object: "subscription", ID: "sub_1"}, true, `{"object":"subscription","id":"sub_1"}`},
+		{"object field", Subscription{Object: "plan_subscription"}, false, `{"object":"plan_subscription","exists":false}`},
+		{"zero value", Subscription{}, false, `{"object":"subscription","exists":false}`},
+		{"typed nil pointer", (*Subscription)(nil), false, `{"object":"subscription","exists":false}`},
+		{"initialism", APIKey{}, false, `{"object":"api_key","exists":false}`},
+		{"embedded object field", embeddedObject{objectBase: objectBase{Object: "gallery"}}, false, `{"object":"gallery","exists":false}`},
+		{"nil", nil, false, `{"object":"object","exists":false}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/me/subscription", nil)
+
+			response.ObjectOrNotFound(c, tt.obj, tt.found)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status 200, got %d", w.Code)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("expected body %s, got %s", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestWriteObjectOrNotFound(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/me/subscription", nil)
+
+	response.WriteObjectOrNotFound(w, r, Subscription{}, false)
+
+	if want := `{"object":"subscription","exists":false}`; w.Body.String() != want {
+		t.Errorf("expected body %s, got %s", want, w.Body.String())
+	}
+}
@@ -0,0 +1,57 @@
+package response
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+var revealForbidden atomic.Bool
+
+// SetRevealForbidden makes DenyOrHide respond 403 to callers who may not
+// access a resource that exists, instead of the default 404. Only use it
+// for APIs whose resource IDs are public anyway; call it once at startup.
+func SetRevealForbidden(reveal bool) {
+	revealForbidden.Store(reveal)
+}
+
+// DenyOrHide applies our policy for resources a caller may not be able to
+// see, and reports whether the handler may go on. When the resource exists
+// and the caller is authorized it sends nothing and returns true;
+// otherwise it sends the error and returns false:
+//
+//   - Missing resource: 404 resource_not_found
+//   - Existing resource the caller may not access: the same 404, so IDs
+//     can't be probed (e.g. private galleries), or 403
+//     insufficient_permission with SetRevealForbidden
+//
+// The hidden 404 is byte-for-byte the missing one, so keep the lookup's
+// other side effects (headers, cache policy) the same for both too.
+// Authenticate first: DenyOrHide never sends a 401.
+//
+//	gallery, err := store.Gallery(ctx, id) // nil if missing
+//	...
+//	exists := gallery != nil
+//	if !response.DenyOrHide(c, exists && gallery.VisibleTo(userID), exists) {
+//	    return
+//	}
+func DenyOrHide(c *gin.Context, authorized, exists bool) bool {
+	switch {
+	case exists && authorized:
+		return true
+	case exists && revealForbidden.Load():
+		ginOutput(c).error(http.StatusForbidden, ErrorInfo{
+			Type:    ErrorTypeForbidden,
+			Code:    ErrorCodeInsufficientPermission,
+			Message: "you do not have access to this resource",
+		})
+	default:
+		ginOutput(c).error(http.StatusNotFound, ErrorInfo{
+			Type:    ErrorTypeNotFound,
+			Code:    ErrorCodeResourceNotFound,
+			Message: "resource not found",
+		})
+	}
+	return false
+}
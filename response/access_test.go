@@ -0,0 +1,71 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestDenyOrHide(t *testing.T) {
+	tests := []struct {
+		name       string
+		authorized bool
+		exists     bool
+		reveal     bool
+		wantOK     bool
+		wantStatus int
+		wantCode   string
+	}{
+		{"authorized", true, true, false, true, http.StatusOK, ""},
+		{"missing", false, false, false, false, http.StatusNotFound, response.ErrorCodeResourceNotFound},
+		{"missing but authorized", true, false, false, false, http.StatusNotFound, response.ErrorCodeResourceNotFound},
+		{"hidden", false, true, false, false, http.StatusNotFound, response.ErrorCodeResourceNotFound},
+		{"revealed", false, true, true, false, http.StatusForbidden, response.ErrorCodeInsufficientPermission},
+		{"missing with reveal", false, false, true, false, http.StatusNotFound, response.ErrorCodeResourceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response.SetRevealForbidden(tt.reveal)
+			defer response.SetRevealForbidden(false)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/galleries/1", nil)
+			ok := response.DenyOrHide(c, tt.authorized, tt.exists)
+			if ok != tt.wantOK {
+				t.Fatalf("expected %v, got %v", tt.wantOK, ok)
+			}
+			if ok {
+				if c.Writer.Written() {
+					t.Error("expected nothing written")
+				}
+				return
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			e, err := response.ParseError(w.Body.Bytes())
+			if err != nil || e.Error.Code != tt.wantCode {
+				t.Errorf("expected code %s, got %s", tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDenyOrHideIndistinguishable(t *testing.T) {
+	send := func(exists bool) string {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/galleries/1", nil)
+		response.DenyOrHide(c, false, exists)
+		return w.Body.String()
+	}
+	if hidden, missing := send(true), send(false); hidden != missing {
+		t.Errorf("expected identical bodies, got %s and %s", hidden, missing)
+	}
+}
@@ -0,0 +1,112 @@
+package response
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CachePolicy describes a Cache-Control header. Build one per route (or use
+// the helpers below) instead of hand-writing header strings.
+type CachePolicy struct {
+	// NoStore forbids caching entirely; other fields are ignored.
+	NoStore bool
+	// NoCache allows storing but requires revalidation before each use.
+	NoCache bool
+	// Public allows shared caches (CDNs) to store personalized-looking
+	// responses, e.g. ones requested with Authorization.
+	Public bool
+	// Private restricts caching to the browser.
+	Private bool
+	// MaxAge is how long the response is fresh.
+	MaxAge time.Duration
+	// SharedMaxAge overrides MaxAge for shared caches (s-maxage).
+	SharedMaxAge time.Duration
+	// StaleWhileRevalidate lets caches serve a stale response while fetching a fresh one.
+	StaleWhileRevalidate time.Duration
+	// StaleIfError lets caches serve a stale response when the origin fails.
+	StaleIfError time.Duration
+	// MustRevalidate forbids serving stale responses after MaxAge.
+	MustRevalidate bool
+	// Immutable promises the response never changes (fingerprinted assets).
+	Immutable bool
+}
+
+// String returns the Cache-Control header value.
+func (p CachePolicy) String() string {
+	if p.NoStore {
+		return "no-store"
+	}
+
+	var parts []string
+	switch {
+	case p.Public:
+		parts = append(parts, "public")
+	case p.Private:
+		parts = append(parts, "private")
+	}
+	if p.NoCache {
+		parts = append(parts, "no-cache")
+	}
+	seconds := func(directive string, d time.Duration) {
+		if d > 0 {
+			parts = append(parts, directive+"="+strconv.FormatInt(int64(d/time.Second), 10))
+		}
+	}
+	if p.MaxAge > 0 || (!p.NoCache && len(parts) > 0) {
+		parts = append(parts, "max-age="+strconv.FormatInt(int64(p.MaxAge/time.Second), 10))
+	}
+	seconds("s-maxage", p.SharedMaxAge)
+	seconds("stale-while-revalidate", p.StaleWhileRevalidate)
+	seconds("stale-if-error", p.StaleIfError)
+	if p.MustRevalidate {
+		parts = append(parts, "must-revalidate")
+	}
+	if p.Immutable {
+		parts = append(parts, "immutable")
+	}
+	if len(parts) == 0 {
+		return "no-cache"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Apply sets the Cache-Control header. Use it with net/http handlers.
+func (p CachePolicy) Apply(h http.Header) {
+	h.Set("Cache-Control", p.String())
+}
+
+// SetCacheControl sets the response's Cache-Control header from policy,
+// replacing any route-level policy set by middleware.CacheControl.
+func SetCacheControl(c *gin.Context, policy CachePolicy) {
+	policy.Apply(c.Writer.Header())
+}
+
+// NoStore marks the response as not cacheable anywhere (personal or
+// sensitive data).
+func NoStore(c *gin.Context) {
+	SetCacheControl(c, CachePolicy{NoStore: true})
+}
+
+// NoCache lets caches store the response but requires revalidation
+// (e.g. via ETag) before every use.
+func NoCache(c *gin.Context) {
+	SetCacheControl(c, CachePolicy{NoCache: true})
+}
+
+// PrivateCache lets only the browser cache the response for ttl.
+func PrivateCache(c *gin.Context, ttl time.Duration) {
+	SetCacheControl(c, CachePolicy{Private: true, MaxAge: ttl})
+}
+
+// PublicCache lets browsers and CDNs cache the response for ttl, then serve
+// it stale for up to swr while revalidating in the background.
+//
+//	response.PublicCache(c, 5*time.Minute, time.Hour)
+//	response.Object(c, gallery)
+func PublicCache(c *gin.Context, ttl, swr time.Duration) {
+	SetCacheControl(c, CachePolicy{Public: true, MaxAge: ttl, StaleWhileRevalidate: swr})
+}
@@ -0,0 +1,77 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestCachePolicyString(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy response.CachePolicy
+		want   string
+	}{
+		{name: "zero", policy: response.CachePolicy{}, want: "no-cache"},
+		{name: "no-store wins", policy: response.CachePolicy{NoStore: true, Public: true, MaxAge: time.Hour}, want: "no-store"},
+		{name: "public", policy: response.CachePolicy{Public: true, MaxAge: 5 * time.Minute, StaleWhileRevalidate: time.Hour}, want: "public, max-age=300, stale-while-revalidate=3600"},
+		{name: "private zero max-age", policy: response.CachePolicy{Private: true}, want: "private, max-age=0"},
+		{name: "private no-cache", policy: response.CachePolicy{Private: true, NoCache: true}, want: "private, no-cache"},
+		{name: "cdn only", policy: response.CachePolicy{Public: true, SharedMaxAge: time.Minute, StaleIfError: time.Hour}, want: "public, max-age=0, s-maxage=60, stale-if-error=3600"},
+		{name: "immutable", policy: response.CachePolicy{Public: true, MaxAge: 365 * 24 * time.Hour, Immutable: true}, want: "public, max-age=31536000, immutable"},
+		{name: "must revalidate", policy: response.CachePolicy{MaxAge: time.Minute, MustRevalidate: true}, want: "max-age=60, must-revalidate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.String(); got != tt.want {
+				t.Errorf("expected '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCacheHelpers(t *testing.T) {
+	tests := []struct {
+		name  string
+		apply func(c *gin.Context)
+		want  string
+	}{
+		{name: "NoStore", apply: response.NoStore, want: "no-store"},
+		{name: "NoCache", apply: response.NoCache, want: "no-cache"},
+		{name: "PrivateCache", apply: func(c *gin.Context) { response.PrivateCache(c, time.Minute) }, want: "private, max-age=60"},
+		{name: "PublicCache", apply: func(c *gin.Context) { response.PublicCache(c, time.Minute, 10*time.Minute) }, want: "public, max-age=60, stale-while-revalidate=600"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			tt.apply(c)
+			response.Success(c, "ok")
+			if got := w.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("expected '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestServerErrorIsNotCached(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	response.PublicCache(c, time.Hour, 0)
+
+	response.InternalError(c, "boom")
+
+	if got := w.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("expected no-store on 500, got '%s'", got)
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+}
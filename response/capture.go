@@ -0,0 +1,213 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactedValue replaces redacted header and field values.
+const redactedValue = "[REDACTED]"
+
+// RedactRules lists the request headers and body fields to mask before
+// request data leaves the process, in error reports or request logs. Share
+// one value between CaptureRequests and your logging middleware so both
+// hide the same things.
+type RedactRules struct {
+	// Headers are header names, matched case-insensitively
+	Headers []string
+	// Fields are JSON object keys and form field names, matched
+	// case-insensitively at any depth
+	Fields []string
+}
+
+// DefaultRedactRules returns rules covering credentials and common secret
+// fields. Append to them for app-specific fields:
+//
+//	rules := response.DefaultRedactRules()
+//	rules.Fields = append(rules.Fields, "date_of_birth")
+func DefaultRedactRules() RedactRules {
+	return RedactRules{
+		Headers: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Captcha-Token"},
+		Fields:  []string{"password", "password_confirmation", "current_password", "token", "access_token", "refresh_token", "secret", "api_key", "card_number", "cvc"},
+	}
+}
+
+// Header returns a copy of h with the listed headers masked.
+func (rr RedactRules) Header(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range rr.Headers {
+		key := http.CanonicalHeaderKey(name)
+		if vals, ok := out[key]; ok {
+			masked := make([]string, len(vals))
+			for i := range masked {
+				masked[i] = redactedValue
+			}
+			out[key] = masked
+		}
+	}
+	return out
+}
+
+// Body returns body with the listed fields masked, for JSON and form
+// bodies. It returns false for other media types and malformed bodies,
+// which can't be redacted and should be dropped.
+func (rr RedactRules) Body(contentType string, body []byte) ([]byte, bool) {
+	if len(body) == 0 {
+		return body, true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, false
+		}
+		out, err := json.Marshal(rr.redactJSON(v))
+		return out, err == nil
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, false
+		}
+		for key, vals := range form {
+			if rr.isField(key) {
+				for i := range vals {
+					vals[i] = redactedValue
+				}
+			}
+		}
+		return []byte(form.Encode()), true
+	}
+	return nil, false
+}
+
+// redactJSON masks listed fields in a decoded JSON value, in place.
+func (rr RedactRules) redactJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if rr.isField(k) {
+				v[k] = redactedValue
+			} else {
+				v[k] = rr.redactJSON(val)
+			}
+		}
+	case []any:
+		for i, val := range v {
+			v[i] = rr.redactJSON(val)
+		}
+	}
+	return v
+}
+
+// isField reports whether name is a listed field.
+func (rr RedactRules) isField(name string) bool {
+	for _, f := range rr.Fields {
+		if strings.EqualFold(f, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// CaptureConfig configures request capture for error reports.
+type CaptureConfig struct {
+	// MaxBytes is the most body captured (defaults to 64 KiB). Longer
+	// bodies are left out of reports, as they can't be redacted reliably.
+	MaxBytes int
+	// Redact masks headers and body fields (defaults to DefaultRedactRules).
+	// It replaces the defaults; start from DefaultRedactRules to extend them.
+	Redact *RedactRules
+}
+
+// requestCapture is the body and headers kept for a request.
+type requestCapture struct {
+	cfg       CaptureConfig
+	body      []byte
+	truncated bool
+}
+
+// requestCaptureContextKey is the request context key for the capture.
+type requestCaptureContextKey struct{}
+
+// CaptureRequests returns middleware that keeps the request body (up to
+// MaxBytes) and headers, so reports of server errors made while handling
+// the request (see ReportError) carry them, redacted, in RequestHeader and
+// RequestBody. That makes production 500s reproducible. Redaction only
+// runs when a report is made.
+//
+//	router.Use(middleware.Recovery(), response.CaptureRequests(response.CaptureConfig{}))
+func CaptureRequests(cfg CaptureConfig) gin.HandlerFunc {
+	cfg = captureDefaults(cfg)
+	return func(c *gin.Context) {
+		c.Request = captureRequest(c.Request, cfg)
+		c.Next()
+	}
+}
+
+// CaptureRequestsHandler is the net/http equivalent of CaptureRequests.
+func CaptureRequestsHandler(cfg CaptureConfig) func(http.Handler) http.Handler {
+	cfg = captureDefaults(cfg)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, captureRequest(r, cfg))
+		})
+	}
+}
+
+// captureDefaults applies CaptureConfig defaults.
+func captureDefaults(cfg CaptureConfig) CaptureConfig {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 64 << 10
+	}
+	if cfg.Redact == nil {
+		rules := DefaultRedactRules()
+		cfg.Redact = &rules
+	}
+	return cfg
+}
+
+// captureRequest reads up to MaxBytes of the body, puts it back in front
+// of the rest, and records it on the request context.
+func captureRequest(r *http.Request, cfg CaptureConfig) *http.Request {
+	capture := &requestCapture{cfg: cfg}
+	if r.Body != nil && r.Body != http.NoBody {
+		buf, _ := io.ReadAll(io.LimitReader(r.Body, int64(cfg.MaxBytes)+1))
+		if len(buf) > cfg.MaxBytes {
+			capture.truncated = true
+		} else {
+			capture.body = buf
+		}
+		r.Body = readCloser{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+	}
+	return r.WithContext(context.WithValue(r.Context(), requestCaptureContextKey{}, capture))
+}
+
+// readCloser pairs a reader with the original body's Close.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// attachCapture fills the request fields of report from the capture on r.
+func attachCapture(report *Report, r *http.Request) {
+	capture, _ := r.Context().Value(requestCaptureContextKey{}).(*requestCapture)
+	if capture == nil {
+		return
+	}
+	report.RequestHeader = capture.cfg.Redact.Header(r.Header)
+	report.RequestBodyTruncated = capture.truncated
+	if body, ok := capture.cfg.Redact.Body(r.Header.Get("Content-Type"), capture.body); ok {
+		report.RequestBody = body
+	}
+}
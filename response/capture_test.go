@@ -0,0 +1,119 @@
+package response_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestCaptureRequests(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           response.CaptureConfig
+		contentType   string
+		body          string
+		wantBody      string
+		wantTruncated bool
+	}{
+		{
+			name:        "json fields redacted at any depth",
+			contentType: "application/json",
+			body:        `{"email":"a@b.c","password":"hunter2","card":{"card_number":"4242","exp":"12/30"},"ids":[1]}`,
+			wantBody:    `{"card":{"card_number":"[REDACTED]","exp":"12/30"},"email":"a@b.c","ids":[1],"password":"[REDACTED]"}`,
+		},
+		{
+			name:        "form fields redacted",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "user=ann&Password=hunter2",
+			wantBody:    "Password=%5BREDACTED%5D&user=ann",
+		},
+		{
+			name:        "custom rules",
+			cfg:         response.CaptureConfig{Redact: &response.RedactRules{Headers: []string{"authorization"}, Fields: []string{"dob"}}},
+			contentType: "application/json",
+			body:        `{"dob":"2000-01-01","password":"x"}`,
+			wantBody:    `{"dob":"[REDACTED]","password":"x"}`,
+		},
+		{
+			name:        "unredactable types dropped",
+			contentType: "application/octet-stream",
+			body:        "password=hunter2",
+		},
+		{
+			name:        "malformed json dropped",
+			contentType: "application/json",
+			body:        `{"password":`,
+		},
+		{
+			name:          "oversized body dropped",
+			cfg:           response.CaptureConfig{MaxBytes: 8},
+			contentType:   "application/json",
+			body:          `{"name":"a long value"}`,
+			wantTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := captureReports(t)
+
+			router := gin.New()
+			router.Use(response.CaptureRequests(tt.cfg))
+			var handlerBody string
+			router.POST("/capture", func(c *gin.Context) {
+				b, _ := io.ReadAll(c.Request.Body)
+				handlerBody = string(b)
+				response.InternalError(c, "database unavailable")
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/capture", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Authorization", "Bearer secret")
+			req.Header.Set("X-Request-ID", "req_1")
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if handlerBody != tt.body {
+				t.Errorf("expected the handler to read the full body, got %q", handlerBody)
+			}
+			if len(*reports) != 1 {
+				t.Fatalf("expected 1 report, got %d", len(*reports))
+			}
+			r := (*reports)[0]
+			if string(r.RequestBody) != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, r.RequestBody)
+			}
+			if r.RequestBodyTruncated != tt.wantTruncated {
+				t.Errorf("expected truncated %v, got %v", tt.wantTruncated, r.RequestBodyTruncated)
+			}
+			if got := r.RequestHeader.Get("Authorization"); got != "[REDACTED]" {
+				t.Errorf("expected a redacted Authorization header, got %q", got)
+			}
+			if got := r.RequestHeader.Get("X-Request-ID"); got != "req_1" {
+				t.Errorf("expected other headers kept, got %q", got)
+			}
+			if req.Header.Get("Authorization") != "Bearer secret" {
+				t.Error("expected the request headers to be left untouched")
+			}
+		})
+	}
+}
+
+func TestCaptureRequestsWithoutMiddleware(t *testing.T) {
+	reports := captureReports(t)
+
+	router := gin.New()
+	router.POST("/nocapture", func(c *gin.Context) {
+		response.InternalError(c, "database unavailable")
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/nocapture", strings.NewReader(`{}`)))
+
+	if len(*reports) != 1 || (*reports)[0].RequestHeader != nil || (*reports)[0].RequestBody != nil {
+		t.Errorf("expected a report without request data, got %+v", *reports)
+	}
+}
@@ -0,0 +1,97 @@
+package response
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ErrorCodeDoc documents an error code, for the doc_url of error responses
+// and for error reference pages.
+type ErrorCodeDoc struct {
+	Code string
+	// Description explains when the code is sent and how clients should react
+	Description string
+	// DocURL links to the human documentation. Empty falls back to the base
+	// URL set with SetErrorDocsURL.
+	DocURL string
+}
+
+var (
+	errorCodesMu  sync.RWMutex
+	errorCodes    = map[string]ErrorCodeDoc{}
+	errorDocsBase string
+)
+
+// RegisterErrorCode registers an application error code, or documents a
+// built-in one. Register codes at startup; it panics on an empty or
+// already registered code.
+//
+//	response.RegisterErrorCode(response.ErrorCodeDoc{
+//	    Code:        "quota_exceeded",
+//	    Description: "The account used its monthly download quota.",
+//	    DocURL:      "https://docs.doujins.com/errors/quota_exceeded",
+//	})
+func RegisterErrorCode(doc ErrorCodeDoc) {
+	if doc.Code == "" {
+		panic("response: RegisterErrorCode requires a code")
+	}
+	errorCodesMu.Lock()
+	defer errorCodesMu.Unlock()
+	if _, ok := errorCodes[doc.Code]; ok {
+		panic("response: error code " + strconv.Quote(doc.Code) + " is registered more than once")
+	}
+	errorCodes[doc.Code] = doc
+}
+
+// SetErrorDocsURL sets the base URL error codes without their own DocURL
+// link to: the code is appended, so "https://docs.doujins.com/errors/"
+// links rate_limit_exceeded to https://docs.doujins.com/errors/rate_limit_exceeded.
+// It applies to built-in and unregistered codes too. Empty disables it.
+func SetErrorDocsURL(base string) {
+	errorCodesMu.Lock()
+	errorDocsBase = base
+	errorCodesMu.Unlock()
+}
+
+// ErrorDocURL returns the documentation URL for code, or "" if it has none.
+func ErrorDocURL(code string) string {
+	if code == "" {
+		return ""
+	}
+	errorCodesMu.RLock()
+	defer errorCodesMu.RUnlock()
+	if doc, ok := errorCodes[code]; ok && doc.DocURL != "" {
+		return doc.DocURL
+	}
+	if errorDocsBase == "" {
+		return ""
+	}
+	return errorDocsBase + code
+}
+
+// ErrorCodeDocs returns the registered codes sorted by code, e.g. to serve
+// an error reference page. DocURL is resolved as in error responses.
+func ErrorCodeDocs() []ErrorCodeDoc {
+	errorCodesMu.RLock()
+	docs := make([]ErrorCodeDoc, 0, len(errorCodes))
+	for _, doc := range errorCodes {
+		docs = append(docs, doc)
+	}
+	errorCodesMu.RUnlock()
+
+	for i := range docs {
+		docs[i].DocURL = ErrorDocURL(docs[i].Code)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Code < docs[j].Code })
+	return docs
+}
+
+// ResetErrorCodes removes all registered codes and the docs base URL.
+// Intended for tests.
+func ResetErrorCodes() {
+	errorCodesMu.Lock()
+	errorCodes = map[string]ErrorCodeDoc{}
+	errorDocsBase = ""
+	errorCodesMu.Unlock()
+}
@@ -0,0 +1,116 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestErrorDocURL(t *testing.T) {
+	defer response.ResetErrorCodes()
+	response.RegisterErrorCode(response.ErrorCodeDoc{
+		Code:   "quota_exceeded",
+		DocURL: "https://help.example/quota",
+	})
+	response.RegisterErrorCode(response.ErrorCodeDoc{Code: "gallery_locked", Description: "The gallery is being edited."})
+
+	tests := []struct {
+		name string
+		base string
+		code string
+		want string
+	}{
+		{"registered url", "", "quota_exceeded", "https://help.example/quota"},
+		{"registered without url", "", "gallery_locked", ""},
+		{"no code", "https://docs.example/errors/", "", ""},
+		{"base for registered", "https://docs.example/errors/", "gallery_locked", "https://docs.example/errors/gallery_locked"},
+		{"base for built-in", "https://docs.example/errors/", response.ErrorCodeRateLimitExceeded, "https://docs.example/errors/rate_limit_exceeded"},
+		{"own url wins", "https://docs.example/errors/", "quota_exceeded", "https://help.example/quota"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response.SetErrorDocsURL(tt.base)
+			if got := response.ErrorDocURL(tt.code); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	response.SetErrorDocsURL("https://docs.example/errors/")
+	docs := response.ErrorCodeDocs()
+	if len(docs) != 2 || docs[0].Code != "gallery_locked" || docs[0].DocURL != "https://docs.example/errors/gallery_locked" {
+		t.Errorf("expected sorted docs with resolved URLs, got %+v", docs)
+	}
+}
+
+func TestRegisterErrorCodePanics(t *testing.T) {
+	defer response.ResetErrorCodes()
+	response.RegisterErrorCode(response.ErrorCodeDoc{Code: "dup"})
+
+	for _, code := range []string{"", "dup"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic for %q", code)
+				}
+			}()
+			response.RegisterErrorCode(response.ErrorCodeDoc{Code: code})
+		}()
+	}
+}
+
+func TestErrorDocURLAndDetails(t *testing.T) {
+	defer response.ResetErrorCodes()
+	response.SetErrorDocsURL("https://docs.example/errors/")
+
+	tests := []struct {
+		name string
+		info response.ErrorInfo
+		want string
+	}{
+		{
+			name: "doc url from registry",
+			info: response.ErrorInfo{Type: "rate_limit", Code: "rate_limit_exceeded", Message: "slow down"},
+			want: `{"object":"error","error":{"type":"rate_limit","code":"rate_limit_exceeded","message":"slow down","doc_url":"https://docs.example/errors/rate_limit_exceeded"}}`,
+		},
+		{
+			name: "details",
+			info: response.ErrorInfo{Type: "rate_limit", Code: "rate_limit_exceeded", Message: "slow down", Details: map[string]any{"limit": 100, "rule": "ip"}},
+			want: `{"object":"error","error":{"type":"rate_limit","code":"rate_limit_exceeded","message":"slow down","doc_url":"https://docs.example/errors/rate_limit_exceeded","details":{"limit":100,"rule":"ip"}}}`,
+		},
+		{
+			name: "own doc url",
+			info: response.ErrorInfo{Type: "forbidden", Message: "forbidden", DocURL: "https://help.example/403"},
+			want: `{"object":"error","error":{"type":"forbidden","message":"forbidden","doc_url":"https://help.example/403"}}`,
+		},
+		{
+			name: "no code, no url",
+			info: response.ErrorInfo{Type: "forbidden", Message: "forbidden"},
+			want: `{"object":"error","error":{"type":"forbidden","message":"forbidden"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/err", func(c *gin.Context) { response.ErrorWithInfo(c, http.StatusTooManyRequests, tt.info) })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/err", nil))
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+
+			// The net/http path encodes the same envelope
+			w = httptest.NewRecorder()
+			response.WriteError(w, httptest.NewRequest(http.MethodGet, "/err", nil), http.StatusTooManyRequests, tt.info)
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("WriteError: expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentInfo describes the file sent by Content and ContentAt.
+type ContentInfo struct {
+	// Name is used to pick the Content-Type from its extension
+	Name string
+	// Type overrides the Content-Type (defaults to the type of Name's
+	// extension, else "application/octet-stream")
+	Type string
+	// ModTime sets Last-Modified and is checked against If-Range. Zero
+	// omits it.
+	ModTime time.Time
+	// ETag, quoted, sets ETag and is checked against If-None-Match and
+	// If-Range. Empty omits it.
+	ETag string
+}
+
+// Content sends a file with single-range support (RFC 7233), so image and
+// zip downloads can resume and video can seek:
+//
+//	f, err := os.Open(path)
+//	...
+//	defer f.Close()
+//	response.Content(c, f, response.ContentInfo{Name: "cover.webp", ModTime: stat.ModTime()})
+//
+// A satisfiable "Range: bytes=..." request gets a 206 with Content-Range;
+// one starting past the end gets a 416 with a range_not_satisfiable error
+// and "Content-Range: bytes */size". Requests for several ranges, invalid
+// Range headers, and ranges whose If-Range no longer matches get the whole
+// file. A matching If-None-Match gets a 304.
+func Content(c *gin.Context, content io.ReadSeeker, info ContentInfo) {
+	writeContent(ginOutput(c), seekerSource(content), info)
+}
+
+// ContentAt is Content for a source of known size read with ReadAt, such
+// as an object storage reader or a file inside a zip.
+func ContentAt(c *gin.Context, content io.ReaderAt, size int64, info ContentInfo) {
+	writeContent(ginOutput(c), readerAtSource(content, size), info)
+}
+
+// WriteContent is the net/http equivalent of Content.
+func WriteContent(w http.ResponseWriter, r *http.Request, content io.ReadSeeker, info ContentInfo) {
+	writeContent(httpOutput(w, r), seekerSource(content), info)
+}
+
+// WriteContentAt is the net/http equivalent of ContentAt.
+func WriteContentAt(w http.ResponseWriter, r *http.Request, content io.ReaderAt, size int64, info ContentInfo) {
+	writeContent(httpOutput(w, r), readerAtSource(content, size), info)
+}
+
+// contentSource returns the size of the content, and a reader of length
+// bytes from start.
+type contentSource func() (size int64, section func(start, length int64) (io.Reader, error), err error)
+
+func seekerSource(rs io.ReadSeeker) contentSource {
+	return func() (int64, func(int64, int64) (io.Reader, error), error) {
+		size, err := rs.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, nil, err
+		}
+		return size, func(start, length int64) (io.Reader, error) {
+			if _, err := rs.Seek(start, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return io.LimitReader(rs, length), nil
+		}, nil
+	}
+}
+
+func readerAtSource(ra io.ReaderAt, size int64) contentSource {
+	return func() (int64, func(int64, int64) (io.Reader, error), error) {
+		return size, func(start, length int64) (io.Reader, error) {
+			return io.NewSectionReader(ra, start, length), nil
+		}, nil
+	}
+}
+
+// rangeNotSatisfiable is the error of a 416 response.
+var rangeNotSatisfiable = ErrorInfo{
+	Type:    ErrorTypeInvalidRequest,
+	Code:    ErrorCodeRangeNotSatisfiable,
+	Message: "requested range is not satisfiable",
+	Param:   "Range",
+}
+
+// writeContent is the core behind Content and its variants.
+func writeContent(o output, source contentSource, info ContentInfo) {
+	size, section, err := source()
+	if err != nil {
+		ReportError(o.ctx, o.r, "", err)
+		o.error(http.StatusInternalServerError, ErrorInfo{Type: ErrorTypeAPI, Code: ErrorCodeInternal, Message: "internal error"})
+		return
+	}
+
+	h := o.w.Header()
+	h.Set("Accept-Ranges", "bytes")
+	if !info.ModTime.IsZero() {
+		h.Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if info.ETag != "" {
+		h.Set("ETag", info.ETag)
+		if etagMatches(o.r.Header.Get("If-None-Match"), info.ETag) {
+			o.w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	status, start, length := http.StatusOK, int64(0), size
+	if header := o.r.Header.Get("Range"); header != "" && o.rangeApplies(info) {
+		s, l, err := parseRange(header, size)
+		switch {
+		case errors.Is(err, errRangeNotSatisfiable):
+			h.Set("Content-Range", "bytes */"+strconv.FormatInt(size, 10))
+			notSatisfiable := rangeNotSatisfiable
+			notSatisfiable.Details = map[string]any{"size": size}
+			o.error(http.StatusRequestedRangeNotSatisfiable, notSatisfiable)
+			return
+		case err == nil:
+			status, start, length = http.StatusPartialContent, s, l
+			h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, size))
+		}
+	}
+
+	contentType := info.Type
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(info.Name))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Length", strconv.FormatInt(length, 10))
+
+	if o.r.Method == http.MethodHead {
+		o.w.WriteHeader(status)
+		return
+	}
+	body, err := section(start, length)
+	if err != nil {
+		h.Del("Content-Range")
+		h.Del("Content-Length")
+		ReportError(o.ctx, o.r, "", err)
+		o.error(http.StatusInternalServerError, ErrorInfo{Type: ErrorTypeAPI, Code: ErrorCodeInternal, Message: "internal error"})
+		return
+	}
+	o.w.WriteHeader(status)
+	if _, err := io.CopyN(o.w, body, length); err != nil && o.ctx.Err() == nil {
+		// The status is sent; the short body fails Content-Length
+		ReportError(o.ctx, o.r, "", err)
+	}
+}
+
+// rangeApplies reports whether the request's Range header should be
+// honored: only for GET and HEAD, and only while If-Range, if sent, still
+// matches the content.
+func (o output) rangeApplies(info ContentInfo) bool {
+	if o.r.Method != http.MethodGet && o.r.Method != http.MethodHead {
+		return false
+	}
+	ifRange := o.r.Header.Get("If-Range")
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) {
+		// If-Range requires the strong comparison
+		return info.ETag != "" && !strings.HasPrefix(info.ETag, "W/") && ifRange == info.ETag
+	}
+	t, err := http.ParseTime(ifRange)
+	return err == nil && !info.ModTime.IsZero() && info.ModTime.Truncate(time.Second).Equal(t)
+}
+
+// errRangeNotSatisfiable is returned by parseRange for ranges outside the
+// content; other errors mean the header should be ignored.
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// parseRange parses a single-range "bytes=" Range header against a size,
+// returning the start and length of the range.
+func parseRange(header string, size int64) (start, length int64, err error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, errors.New("unsupported range")
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, errors.New("invalid range")
+	}
+
+	if first == "" {
+		// bytes=-n is the last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("invalid range")
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, errRangeNotSatisfiable
+		}
+		n = min(n, size)
+		return size - n, n, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, errors.New("invalid range")
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, errors.New("invalid range")
+		}
+		end = min(end, size-1)
+	}
+	if start >= size {
+		return 0, 0, errRangeNotSatisfiable
+	}
+	return start, end - start + 1, nil
+}
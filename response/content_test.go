@@ -0,0 +1,97 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestContent(t *testing.T) {
+	modTime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	info := response.ContentInfo{Name: "chapter.pdf", ModTime: modTime, ETag: `"v1"`}
+
+	tests := []struct {
+		name       string
+		method     string
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+		wantRange  string
+		wantLength string
+	}{
+		{name: "whole file", wantStatus: http.StatusOK, wantBody: "0123456789", wantLength: "10"},
+		{name: "range", headers: map[string]string{"Range": "bytes=2-5"}, wantStatus: http.StatusPartialContent, wantBody: "2345", wantRange: "bytes 2-5/10", wantLength: "4"},
+		{name: "open range", headers: map[string]string{"Range": "bytes=7-"}, wantStatus: http.StatusPartialContent, wantBody: "789", wantRange: "bytes 7-9/10", wantLength: "3"},
+		{name: "suffix", headers: map[string]string{"Range": "bytes=-3"}, wantStatus: http.StatusPartialContent, wantBody: "789", wantRange: "bytes 7-9/10", wantLength: "3"},
+		{name: "end past size", headers: map[string]string{"Range": "bytes=8-100"}, wantStatus: http.StatusPartialContent, wantBody: "89", wantRange: "bytes 8-9/10", wantLength: "2"},
+		{name: "not satisfiable", headers: map[string]string{"Range": "bytes=10-"}, wantStatus: http.StatusRequestedRangeNotSatisfiable, wantBody: `"code":"range_not_satisfiable"`, wantRange: "bytes */10"},
+		{name: "several ranges", headers: map[string]string{"Range": "bytes=0-1,4-5"}, wantStatus: http.StatusOK, wantBody: "0123456789", wantLength: "10"},
+		{name: "invalid range", headers: map[string]string{"Range": "bytes=5-2"}, wantStatus: http.StatusOK, wantBody: "0123456789", wantLength: "10"},
+		{name: "if-range etag match", headers: map[string]string{"Range": "bytes=0-0", "If-Range": `"v1"`}, wantStatus: http.StatusPartialContent, wantBody: "0", wantRange: "bytes 0-0/10", wantLength: "1"},
+		{name: "if-range etag stale", headers: map[string]string{"Range": "bytes=0-0", "If-Range": `"v0"`}, wantStatus: http.StatusOK, wantBody: "0123456789", wantLength: "10"},
+		{name: "if-range date match", headers: map[string]string{"Range": "bytes=0-0", "If-Range": modTime.Format(http.TimeFormat)}, wantStatus: http.StatusPartialContent, wantBody: "0", wantRange: "bytes 0-0/10", wantLength: "1"},
+		{name: "if-none-match", headers: map[string]string{"If-None-Match": `"v1"`}, wantStatus: http.StatusNotModified},
+		{name: "head range", method: http.MethodHead, headers: map[string]string{"Range": "bytes=2-5"}, wantStatus: http.StatusPartialContent, wantRange: "bytes 2-5/10", wantLength: "4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Match([]string{http.MethodGet, http.MethodHead}, "/seeker", func(c *gin.Context) {
+				response.Content(c, strings.NewReader("0123456789"), info)
+			})
+			router.Match([]string{http.MethodGet, http.MethodHead}, "/reader-at", func(c *gin.Context) {
+				response.ContentAt(c, strings.NewReader("0123456789"), 10, info)
+			})
+
+			for _, target := range []string{"/seeker", "/reader-at"} {
+				method := tt.method
+				if method == "" {
+					method = http.MethodGet
+				}
+				req := httptest.NewRequest(method, target, nil)
+				for k, v := range tt.headers {
+					req.Header.Set(k, v)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				if w.Code != tt.wantStatus {
+					t.Fatalf("%s: expected status %d, got %d", target, tt.wantStatus, w.Code)
+				}
+				if !strings.Contains(w.Body.String(), tt.wantBody) || (tt.wantStatus < 300 && w.Body.String() != tt.wantBody) {
+					t.Errorf("%s: expected body %s, got %s", target, tt.wantBody, w.Body.String())
+				}
+				if got := w.Header().Get("Content-Range"); got != tt.wantRange {
+					t.Errorf("%s: expected Content-Range %q, got %q", target, tt.wantRange, got)
+				}
+				if got := w.Header().Get("Content-Length"); tt.wantLength != "" && got != tt.wantLength {
+					t.Errorf("%s: expected Content-Length %q, got %q", target, tt.wantLength, got)
+				}
+				if tt.wantStatus < 300 && w.Header().Get("Content-Type") != "application/pdf" {
+					t.Errorf("%s: expected application/pdf, got %q", target, w.Header().Get("Content-Type"))
+				}
+			}
+		})
+	}
+}
+
+func TestWriteContent(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=1-2")
+	w := httptest.NewRecorder()
+	response.WriteContent(w, req, strings.NewReader("abcd"), response.ContentInfo{Type: "image/webp"})
+
+	if w.Code != http.StatusPartialContent || w.Body.String() != "bc" {
+		t.Errorf("expected 206 bc, got %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Accept-Ranges") != "bytes" || w.Header().Get("Content-Type") != "image/webp" {
+		t.Errorf("unexpected headers %v", w.Header())
+	}
+}
@@ -0,0 +1,146 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// debugKey is the gin context key for the DebugParams state.
+const debugKey = "ginapi.debug"
+
+// DebugParamsConfig configures DebugParams.
+type DebugParamsConfig struct {
+	// AllowDebug reports whether the request may use ?debug=1. It runs when
+	// the response is rendered, so auth middleware registered after
+	// DebugParams has already identified the principal. Nil disables ?debug.
+	AllowDebug func(c *gin.Context) bool
+}
+
+var prettyJSON atomic.Bool
+
+// SetPrettyJSON indents every JSON response, as if ?pretty=1 were sent, for
+// development. Call it once at startup.
+func SetPrettyJSON(pretty bool) {
+	prettyJSON.Store(pretty)
+}
+
+// debugState is stored on the gin context by DebugParams.
+type debugState struct {
+	cfg   DebugParamsConfig
+	start time.Time
+}
+
+// debugOptions are the output options resolved for one response.
+type debugOptions struct {
+	pretty bool
+	debug  bool
+	meta   map[string]any
+}
+
+// DebugParams returns middleware enabling two query params for integration
+// development:
+//
+//   - ?pretty=1 indents JSON output
+//   - ?debug=1 adds a "_debug" section with timing and trace metadata to
+//     object responses, if AllowDebug permits the request
+//
+// Usage:
+//
+//	router.Use(response.DebugParams(response.DebugParamsConfig{
+//	    AllowDebug: func(c *gin.Context) bool { return auth.IsStaff(c) },
+//	}))
+func DebugParams(cfg DebugParamsConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(debugKey, &debugState{cfg: cfg, start: time.Now()})
+		c.Next()
+	}
+}
+
+// resolveDebug returns the debug options for c, or nil if none apply.
+func resolveDebug(c *gin.Context) *debugOptions {
+	v, ok := c.Get(debugKey)
+	if !ok || c.Request == nil {
+		return nil
+	}
+	state, ok := v.(*debugState)
+	if !ok {
+		return nil
+	}
+
+	q := c.Request.URL.Query()
+	opts := &debugOptions{pretty: truthy(q.Get("pretty"))}
+	if truthy(q.Get("debug")) && state.cfg.AllowDebug != nil && state.cfg.AllowDebug(c) {
+		opts.debug = true
+		opts.meta = debugMeta(c, state.start)
+	}
+	if !opts.pretty && !opts.debug {
+		return nil
+	}
+	return opts
+}
+
+// debugMeta builds the "_debug" section.
+func debugMeta(c *gin.Context, start time.Time) map[string]any {
+	meta := map[string]any{
+		"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+		"handler":     c.HandlerName(),
+		"method":      c.Request.Method,
+		"route":       c.FullPath(),
+	}
+	requestID := c.Writer.Header().Get("X-Request-ID")
+	if requestID == "" {
+		requestID = c.Request.Header.Get("X-Request-ID")
+	}
+	if requestID != "" {
+		meta["request_id"] = requestID
+	}
+	// W3C traceparent: version-traceid-parentid-flags
+	if parts := strings.Split(c.Request.Header.Get("traceparent"), "-"); len(parts) == 4 {
+		meta["trace_id"] = parts[1]
+	}
+	return meta
+}
+
+// apply adds the "_debug" section (to JSON objects only) and indentation.
+func (d *debugOptions) apply(body []byte) []byte {
+	if d.debug && len(body) >= 2 && body[0] == '{' {
+		if meta, err := json.Marshal(d.meta); err == nil {
+			var buf bytes.Buffer
+			buf.Write(body[:len(body)-1])
+			if len(body) > 2 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(`"_debug":`)
+			buf.Write(meta)
+			buf.WriteByte('}')
+			body = buf.Bytes()
+		}
+	}
+	if d.pretty {
+		body = indentJSON(body)
+	}
+	return body
+}
+
+// indentJSON indents body, leaving it as-is if it isn't valid JSON.
+func indentJSON(body []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, body, "", "  "); err != nil {
+		return body
+	}
+	return buf.Bytes()
+}
+
+// truthy reports whether a query param value enables a flag.
+func truthy(v string) bool {
+	switch strings.ToLower(v) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
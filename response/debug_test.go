@@ -0,0 +1,126 @@
+package response_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func newDebugRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(response.DebugParams(response.DebugParamsConfig{
+		AllowDebug: func(c *gin.Context) bool { return c.GetHeader("X-Staff") == "1" },
+	}))
+	router.GET("/galleries/:id", func(c *gin.Context) {
+		response.Object(c, map[string]any{"object": "gallery", "id": c.Param("id")})
+	})
+	return router
+}
+
+func TestDebugParamsPretty(t *testing.T) {
+	w := httptest.NewRecorder()
+	newDebugRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/galleries/gal_1?pretty=1", nil))
+
+	want := "{\n  \"id\": \"gal_1\",\n  \"object\": \"gallery\"\n}"
+	if w.Body.String() != want {
+		t.Errorf("expected %q, got %q", want, w.Body.String())
+	}
+}
+
+func TestSetPrettyJSON(t *testing.T) {
+	response.SetPrettyJSON(true)
+	t.Cleanup(func() { response.SetPrettyJSON(false) })
+
+	for _, path := range []string{"/galleries/gal_1", "/galleries/gal_1?pretty=1"} {
+		w := httptest.NewRecorder()
+		newDebugRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		want := "{\n  \"id\": \"gal_1\",\n  \"object\": \"gallery\"\n}"
+		if w.Body.String() != want {
+			t.Errorf("%s: expected %q, got %q", path, want, w.Body.String())
+		}
+	}
+}
+
+func TestSetPrettyJSONErrors(t *testing.T) {
+	response.SetPrettyJSON(true)
+	t.Cleanup(func() { response.SetPrettyJSON(false) })
+
+	router := gin.New()
+	router.GET("/unauthorized", func(c *gin.Context) { response.Unauthorized(c) })
+	router.GET("/missing", func(c *gin.Context) { response.NotFound(c, "gallery") })
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/unauthorized", "{\n  \"object\": \"error\",\n  \"error\": {\n    \"type\": \"authentication\",\n    \"message\": \"unauthorized\"\n  }\n}"},
+		{"/missing", "{\n  \"object\": \"error\",\n  \"error\": {\n    \"type\": \"not_found\",\n    \"message\": \"gallery not found\"\n  }\n}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDebugParamsDebug(t *testing.T) {
+	tests := []struct {
+		name      string
+		staff     bool
+		wantDebug bool
+	}{
+		{name: "allowed", staff: true, wantDebug: true},
+		{name: "not allowed", staff: false, wantDebug: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/galleries/gal_1?debug=1", nil)
+			req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			if tt.staff {
+				req.Header.Set("X-Staff", "1")
+			}
+			w := httptest.NewRecorder()
+			newDebugRouter().ServeHTTP(w, req)
+
+			var body map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid JSON %s: %v", w.Body.String(), err)
+			}
+			debug, ok := body["_debug"].(map[string]any)
+			if ok != tt.wantDebug {
+				t.Fatalf("expected _debug present=%v, got %s", tt.wantDebug, w.Body.String())
+			}
+			if !ok {
+				return
+			}
+			if debug["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" || debug["route"] != "/galleries/:id" {
+				t.Errorf("unexpected debug section %v", debug)
+			}
+			if _, ok := debug["duration_ms"].(float64); !ok {
+				t.Errorf("expected duration_ms, got %v", debug)
+			}
+		})
+	}
+}
+
+func TestDebugParamsNotRequested(t *testing.T) {
+	w := httptest.NewRecorder()
+	newDebugRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/galleries/gal_1", nil))
+
+	if strings.Contains(w.Body.String(), "\n") || strings.Contains(w.Body.String(), "_debug") {
+		t.Errorf("expected plain output, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,145 @@
+package response
+
+import (
+	"sync"
+	"unicode/utf8"
+)
+
+// The error path is hot during token expiry storms, when most responses
+// are 401s, so plain JSON error envelopes are encoded by hand rather than
+// through encoding/json: fixed envelopes are encoded once at startup and
+// the rest are appended into pooled buffers. The output is byte-for-byte
+// what json.Marshal produces for Error.
+
+// staticErrorKey identifies an envelope with only a type and a message.
+type staticErrorKey struct {
+	errType, message string
+}
+
+// staticErrors are the pre-encoded envelopes of the helpers with fixed messages.
+var staticErrors = map[staticErrorKey][]byte{}
+
+func init() {
+	for _, info := range []ErrorInfo{
+		{Type: ErrorTypeAuthentication, Message: "unauthorized"},
+		{Type: ErrorTypeForbidden, Message: "forbidden"},
+	} {
+		staticErrors[staticErrorKey{info.Type, info.Message}] = appendErrorEnvelope(nil, info)
+	}
+}
+
+// errorBufPool holds buffers for encoding dynamic error envelopes.
+var errorBufPool = sync.Pool{New: func() any { return new([]byte) }}
+
+// maxPooledErrorBuf is the largest buffer returned to the pool, so one huge
+// message doesn't pin memory.
+const maxPooledErrorBuf = 4 << 10
+
+// plainJSON reports whether o writes bodies as plain JSON, with no
+// interceptors, JSON:API conversion, debug output, key case conversion,
+// indentation (SetPrettyJSON), or MessagePack.
+func (o output) plainJSON() bool {
+	if len(o.interceptors) > 0 || o.jsonAPI || o.debug != nil || (o.keyCase != "" && o.keyCase != SnakeCase) || prettyJSON.Load() {
+		return false
+	}
+	_, msgpack := o.wantsMsgpack()
+	return !msgpack
+}
+
+// writeErrorEnvelope writes the Error envelope for info, as o.json would
+// for a plain JSON response. info must have no Details.
+func (o output) writeErrorEnvelope(status int, info ErrorInfo) {
+	const contentType = "application/json; charset=utf-8"
+	if info.Code == "" && info.Param == "" && info.Pointer == "" && info.DocURL == "" {
+		if body, ok := staticErrors[staticErrorKey{info.Type, info.Message}]; ok {
+			o.write(status, contentType, body)
+			return
+		}
+	}
+
+	buf := errorBufPool.Get().(*[]byte)
+	*buf = appendErrorEnvelope((*buf)[:0], info)
+	o.write(status, contentType, *buf)
+	if cap(*buf) <= maxPooledErrorBuf {
+		errorBufPool.Put(buf)
+	}
+}
+
+// appendErrorEnvelope appends the JSON encoding of Error{Object: "error", Error: info},
+// for info without Details.
+func appendErrorEnvelope(dst []byte, info ErrorInfo) []byte {
+	dst = append(dst, `{"object":"error","error":{"type":`...)
+	dst = appendJSONString(dst, info.Type)
+	if info.Code != "" {
+		dst = append(dst, `,"code":`...)
+		dst = appendJSONString(dst, info.Code)
+	}
+	dst = append(dst, `,"message":`...)
+	dst = appendJSONString(dst, info.Message)
+	if info.Param != "" {
+		dst = append(dst, `,"param":`...)
+		dst = appendJSONString(dst, info.Param)
+	}
+	if info.Pointer != "" {
+		dst = append(dst, `,"pointer":`...)
+		dst = appendJSONString(dst, info.Pointer)
+	}
+	if info.DocURL != "" {
+		dst = append(dst, `,"doc_url":`...)
+		dst = appendJSONString(dst, info.DocURL)
+	}
+	return append(dst, "}}"...)
+}
+
+// appendJSONString appends s as a JSON string, escaping it exactly as
+// encoding/json does (including HTML characters and U+2028/U+2029, and
+// replacing invalid UTF-8).
+func appendJSONString(dst []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+		case r == '\u2028' || r == '\u2029':
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hex[r&0xF])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
@@ -1,8 +1,9 @@
 package response
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,10 +16,13 @@ type Error struct {
 
 // ErrorInfo contains error details.
 type ErrorInfo struct {
-	Type    string `json:"type"`            // error type category (see ErrorType* constants)
-	Code    string `json:"code,omitempty"`  // machine-readable error code (see ErrorCode* constants)
-	Message string `json:"message"`         // human-readable message
-	Param   string `json:"param,omitempty"` // parameter that caused the error
+	Type    string         `json:"type"`              // error type category (see ErrorType* constants)
+	Code    string         `json:"code,omitempty"`    // machine-readable error code (see ErrorCode* constants)
+	Message string         `json:"message"`           // human-readable message
+	Param   string         `json:"param,omitempty"`   // parameter that caused the error, e.g. "items[2].price.currency"
+	Pointer string         `json:"pointer,omitempty"` // JSON Pointer to the body field that caused the error, e.g. "/items/2/price/currency"
+	DocURL  string         `json:"doc_url,omitempty"` // documentation for Code, filled from the error code registry
+	Details map[string]any `json:"details,omitempty"` // machine-readable context, e.g. the limit that was hit
 }
 
 // Error types - high-level categories for client-side error handling
@@ -35,13 +39,24 @@ const (
 // Error codes - specific machine-readable codes for programmatic handling
 const (
 	// Validation codes (used with ErrorTypeInvalidRequest)
-	ErrorCodeInvalidParam  = "invalid_param"
-	ErrorCodeMissingParam  = "missing_param"
-	ErrorCodeInvalidFormat = "invalid_format"
+	ErrorCodeInvalidParam      = "invalid_param"
+	ErrorCodeMissingParam      = "missing_param"
+	ErrorCodeInvalidFormat     = "invalid_format"
+	ErrorCodeOffsetTooDeep     = "offset_too_deep"
+	ErrorCodeDigestMismatch    = "digest_mismatch"
+	ErrorCodeInvalidIDChecksum = "invalid_id_checksum" // the ID's check character doesn't match: a typo, not an unknown ID
+
+	// Request routing codes (used with ErrorTypeInvalidRequest)
+	ErrorCodeHostNotAllowed      = "host_not_allowed"
+	ErrorCodeNotAcceptable       = "not_acceptable"
+	ErrorCodeRangeNotSatisfiable = "range_not_satisfiable"
 
 	// Resource codes (used with ErrorTypeNotFound, ErrorTypeConflict)
-	ErrorCodeResourceNotFound = "resource_not_found"
-	ErrorCodeAlreadyExists    = "already_exists"
+	ErrorCodeResourceNotFound     = "resource_not_found"
+	ErrorCodeAlreadyExists        = "already_exists"
+	ErrorCodeDuplicateRequest     = "duplicate_request"
+	ErrorCodePreconditionFailed   = "precondition_failed"   // 412 - If-Match doesn't match the current version
+	ErrorCodePreconditionRequired = "precondition_required" // 428 - the write requires If-Match
 
 	// Auth codes (used with ErrorTypeAuthentication, ErrorTypeForbidden)
 	ErrorCodeAuthRequired           = "auth_required"
@@ -50,26 +65,77 @@ const (
 	ErrorCodeInsufficientPermission = "insufficient_permission"
 
 	// Rate limit codes
-	ErrorCodeRateLimitExceeded = "rate_limit_exceeded"
+	ErrorCodeRateLimitExceeded        = "rate_limit_exceeded"
+	ErrorCodeChallengeRequired        = "challenge_required"
+	ErrorCodeConcurrencyLimitExceeded = "concurrency_limit_exceeded" // too many requests in flight at once
 
 	// Server error codes (used with ErrorTypeAPI)
 	ErrorCodeInternal           = "internal"
 	ErrorCodeServiceUnavailable = "service_unavailable"
+	ErrorCodeResponseTooLarge   = "response_too_large"
+	ErrorCodeTimeout            = "timeout" // 504 - the request ran past its time budget
 )
 
+// ErrorTypeForStatus returns the error type matching an HTTP status code,
+// for translating errors that only carry a status (framework or upstream errors).
+func ErrorTypeForStatus(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return ErrorTypeAuthentication
+	case http.StatusForbidden:
+		return ErrorTypeForbidden
+	case http.StatusNotFound:
+		return ErrorTypeNotFound
+	case http.StatusConflict:
+		return ErrorTypeConflict
+	case http.StatusTooManyRequests:
+		return ErrorTypeRateLimit
+	}
+	if status >= 500 {
+		return ErrorTypeAPI
+	}
+	return ErrorTypeInvalidRequest
+}
+
 // sendError sends an error response with the given status and error info.
 func sendError(c *gin.Context, status int, errType, code, message, param string) {
-	c.JSON(status, Error{
-		Object: "error",
-		Error: ErrorInfo{
-			Type:    errType,
-			Code:    code,
-			Message: message,
-			Param:   param,
-		},
+	ginOutput(c).error(status, ErrorInfo{
+		Type:    errType,
+		Code:    code,
+		Message: message,
+		Param:   param,
 	})
 }
 
+// error writes an error envelope. It is the core behind sendError and WriteError.
+// Server errors are marked no-store, overriding any route cache policy.
+// DocURL is filled from the error code registry (see RegisterErrorCode).
+// Plain JSON envelopes without details skip encoding/json (see
+// writeErrorEnvelope). Response hooks run after the envelope is written.
+func (o output) error(status int, info ErrorInfo) {
+	if status >= 500 {
+		o.w.Header().Set("Cache-Control", "no-store")
+	}
+	if info.DocURL == "" {
+		info.DocURL = ErrorDocURL(info.Code)
+	}
+	if info.Details == nil && o.plainJSON() {
+		o.writeErrorEnvelope(status, info)
+	} else {
+		o.json(status, Error{
+			Object: "error",
+			Error:  info,
+		})
+	}
+	o.notify(status, "error", "")
+}
+
+// ErrorWithInfo sends an error response with the given status and error info.
+// Use when a specific code or param is needed that the helpers below don't cover.
+func ErrorWithInfo(c *gin.Context, status int, info ErrorInfo) {
+	ginOutput(c).error(status, info)
+}
+
 // BadRequest sends a 400 Bad Request error.
 func BadRequest(c *gin.Context, message string) {
 	sendError(c, http.StatusBadRequest, ErrorTypeInvalidRequest, "", message, "")
@@ -107,7 +173,7 @@ func ForbiddenWithMessage(c *gin.Context, message string) {
 
 // NotFound sends a 404 Not Found error for an entity.
 func NotFound(c *gin.Context, entity string) {
-	sendError(c, http.StatusNotFound, ErrorTypeNotFound, "", fmt.Sprintf("%s not found", entity), "")
+	sendError(c, http.StatusNotFound, ErrorTypeNotFound, "", entity+" not found", "")
 }
 
 // NotFoundWithMessage sends a 404 Not Found error with a custom message.
@@ -125,19 +191,28 @@ func TooManyRequests(c *gin.Context, message string) {
 	sendError(c, http.StatusTooManyRequests, ErrorTypeRateLimit, "", message, "")
 }
 
-// InternalError sends a 500 Internal Server Error.
+// InternalError sends a 500 Internal Server Error and reports the message
+// to the Reporter set with SetReporter. See HideServerErrorMessages.
 func InternalError(c *gin.Context, message string) {
+	public, id := publicServerMessage(c, http.StatusInternalServerError, message)
+	reportError(c, c.Request, c.FullPath(), errors.New(message), id)
+	if id != "" {
+		sendHiddenServerError(c, http.StatusInternalServerError, public, id)
+		return
+	}
 	sendError(c, http.StatusInternalServerError, ErrorTypeAPI, "", message, "")
 }
 
-// ServiceUnavailable sends a 503 Service Unavailable error.
+// ServiceUnavailable sends a 503 Service Unavailable error. See
+// HideServerErrorMessages.
 func ServiceUnavailable(c *gin.Context, message string) {
-	sendError(c, http.StatusServiceUnavailable, ErrorTypeAPI, "", message, "")
+	sendServerError(c, http.StatusServiceUnavailable, message)
 }
 
-// NotImplemented sends a 501 Not Implemented error.
+// NotImplemented sends a 501 Not Implemented error. See
+// HideServerErrorMessages.
 func NotImplemented(c *gin.Context, message string) {
-	sendError(c, http.StatusNotImplemented, ErrorTypeAPI, "", message, "")
+	sendServerError(c, http.StatusNotImplemented, message)
 }
 
 // UnprocessableEntity sends a 422 Unprocessable Entity error.
@@ -146,13 +221,27 @@ func UnprocessableEntity(c *gin.Context, message string) {
 	sendError(c, http.StatusUnprocessableEntity, ErrorTypeInvalidRequest, "", message, "")
 }
 
+// NotAcceptable sends a 406 Not Acceptable error listing the media types
+// the route can produce. See Acceptable.
+func NotAcceptable(c *gin.Context, supported ...string) {
+	AddVary(c.Writer.Header(), "Accept")
+	sendError(c, http.StatusNotAcceptable, ErrorTypeInvalidRequest, ErrorCodeNotAcceptable,
+		"none of the requested media types are available; supported: "+strings.Join(supported, ", "), "")
+}
+
 // UnsupportedMediaType sends a 415 Unsupported Media Type error.
 func UnsupportedMediaType(c *gin.Context, message string) {
 	sendError(c, http.StatusUnsupportedMediaType, ErrorTypeInvalidRequest, "", message, "")
 }
 
 // BadGateway sends a 502 Bad Gateway error.
-// Use when an upstream service fails.
+// Use when an upstream service fails. See HideServerErrorMessages.
 func BadGateway(c *gin.Context, message string) {
-	sendError(c, http.StatusBadGateway, ErrorTypeAPI, "", message, "")
+	sendServerError(c, http.StatusBadGateway, message)
+}
+
+// MisdirectedRequest sends a 421 Misdirected Request error.
+// Use when the request was routed to a server that won't serve the requested host.
+func MisdirectedRequest(c *gin.Context, message string) {
+	sendError(c, http.StatusMisdirectedRequest, ErrorTypeInvalidRequest, ErrorCodeHostNotAllowed, message, "")
 }
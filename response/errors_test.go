@@ -158,3 +158,137 @@ func TestServiceUnavailable(t *testing.T) {
 		t.Errorf("expected status 503, got %d", w.Code)
 	}
 }
+
+func TestErrorWithInfo(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	response.ErrorWithInfo(c, http.StatusBadRequest, response.ErrorInfo{
+		Type:    response.ErrorTypeInvalidRequest,
+		Code:    response.ErrorCodeInvalidFormat,
+		Message: "bad date",
+		Param:   "since",
+	})
+
+	var result response.Error
+	json.Unmarshal(w.Body.Bytes(), &result)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+	if result.Error.Code != response.ErrorCodeInvalidFormat || result.Error.Param != "since" {
+		t.Errorf("unexpected error info: %+v", result.Error)
+	}
+}
+
+func TestMisdirectedRequest(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	response.MisdirectedRequest(c, "host not allowed")
+
+	if w.Code != http.StatusMisdirectedRequest {
+		t.Errorf("expected status 421, got %d", w.Code)
+	}
+}
+
+func TestErrorTypeForStatus(t *testing.T) {
+	tests := map[int]string{
+		http.StatusBadRequest:          response.ErrorTypeInvalidRequest,
+		http.StatusUnprocessableEntity: response.ErrorTypeInvalidRequest,
+		http.StatusUnauthorized:        response.ErrorTypeAuthentication,
+		http.StatusForbidden:           response.ErrorTypeForbidden,
+		http.StatusNotFound:            response.ErrorTypeNotFound,
+		http.StatusConflict:            response.ErrorTypeConflict,
+		http.StatusTooManyRequests:     response.ErrorTypeRateLimit,
+		http.StatusBadGateway:          response.ErrorTypeAPI,
+	}
+	for status, want := range tests {
+		if got := response.ErrorTypeForStatus(status); got != want {
+			t.Errorf("ErrorTypeForStatus(%d) = %s, want %s", status, got, want)
+		}
+	}
+}
+
+func TestErrorEnvelopeMatchesEncodingJSON(t *testing.T) {
+	tests := []response.ErrorInfo{
+		{Type: response.ErrorTypeAuthentication, Message: "unauthorized"},
+		{Type: response.ErrorTypeInvalidRequest, Code: response.ErrorCodeInvalidParam, Message: "bad", Param: "limit"},
+		{Type: response.ErrorTypeInvalidRequest, Message: "bad", Param: "items[2].price", Pointer: "/items/2/price", DocURL: "https://docs.example/e"},
+		{Type: "api", Message: `quote " backslash \\ <b>&amp;</b>`},
+		{Type: "api", Message: "control \b\f\n\r\t\x00\x1f"},
+		{Type: "api", Message: "unicode ギャラリー \u2028\u2029 invalid \xff\xfe"},
+		{Type: "api", Message: ""},
+	}
+
+	for _, info := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+
+		response.ErrorWithInfo(c, http.StatusBadRequest, info)
+
+		want, _ := json.Marshal(response.Error{Object: "error", Error: info})
+		if w.Body.String() != string(want) {
+			t.Errorf("expected %s, got %s", want, w.Body.String())
+		}
+	}
+}
+
+// discardWriter is a ResponseWriter that drops the body, so benchmarks and
+// allocation tests measure only the helper.
+type discardWriter struct{ header http.Header }
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+// errorHelperBudgets are the allocations allowed per request for each error
+// helper, including gin's handling of the request. They guard against
+// per-request regressions; raise one only with a reason in the commit.
+var errorHelperBudgets = []struct {
+	name    string
+	handler gin.HandlerFunc
+	allocs  float64
+}{
+	{"BadRequest", func(c *gin.Context) { response.BadRequest(c, "invalid input") }, 3},
+	{"BadRequestParam", func(c *gin.Context) { response.BadRequestParam(c, "limit", "must be positive") }, 3},
+	{"Unauthorized", func(c *gin.Context) { response.Unauthorized(c) }, 3},
+	{"NotFound", func(c *gin.Context) { response.NotFound(c, "gallery") }, 4},
+	{"InternalError", func(c *gin.Context) { response.InternalError(c, "database unavailable") }, 5},
+}
+
+// serveDiscard returns a function serving one request to handler.
+func serveDiscard(handler gin.HandlerFunc) func() {
+	router := gin.New()
+	router.GET("/", handler)
+	w := &discardWriter{header: http.Header{}}
+	req, _ := http.NewRequest("GET", "/", nil)
+	return func() { router.ServeHTTP(w, req) }
+}
+
+func TestErrorHelperAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector adds allocations")
+	}
+	for _, tt := range errorHelperBudgets {
+		t.Run(tt.name, func(t *testing.T) {
+			allocs := testing.AllocsPerRun(100, serveDiscard(tt.handler))
+			if allocs > tt.allocs {
+				t.Errorf("expected at most %v allocations, got %v", tt.allocs, allocs)
+			}
+		})
+	}
+}
+
+func BenchmarkErrorHelpers(b *testing.B) {
+	for _, tt := range errorHelperBudgets {
+		b.Run(tt.name, func(b *testing.B) {
+			serve := serveDiscard(tt.handler)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				serve()
+			}
+		})
+	}
+}
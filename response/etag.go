@@ -0,0 +1,82 @@
+package response
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ObjectCached sends obj like Object, with a strong ETag hashed from the
+// serialized body. If the request's If-None-Match matches, it sends 304 Not
+// Modified with no body instead. Nothing is stored, so it works for
+// personalized responses; the saving is bandwidth, not the handler's work.
+//
+//	gallery, err := repo.Get(ctx, id)
+//	...
+//	response.ObjectCached(c, gallery)
+func ObjectCached(c *gin.Context, obj any) {
+	ginOutput(c).cached(http.StatusOK, obj, false)
+}
+
+// ObjectCachedWeak is ObjectCached with a weak ETag (W/"..."), for
+// responses that are semantically but not byte-for-byte stable, or that a
+// proxy may re-encode (e.g. compress).
+func ObjectCachedWeak(c *gin.Context, obj any) {
+	ginOutput(c).cached(http.StatusOK, obj, true)
+}
+
+// WriteObjectCached is the net/http equivalent of ObjectCached.
+func WriteObjectCached(w http.ResponseWriter, r *http.Request, obj any) {
+	httpOutput(w, r).cached(http.StatusOK, obj, false)
+}
+
+// cached writes v with a content-hash ETag, or 304 if the client has it,
+// then runs the response hooks with the status sent.
+func (o output) cached(status int, v any, weak bool) {
+	checkShape(v)
+	status = o.writeCached(status, v, weak)
+	o.notifyObject(status, v)
+}
+
+func (o output) writeCached(status int, v any, weak bool) int {
+	status, contentType, body := o.encode(status, v)
+	if status != http.StatusOK {
+		o.write(status, contentType, body)
+		return status
+	}
+
+	etag := bodyETag(body)
+	if weak {
+		etag = "W/" + etag
+	}
+	o.w.Header().Set("ETag", etag)
+
+	if o.r != nil && etagMatches(o.r.Header.Get("If-None-Match"), etag) {
+		o.w.WriteHeader(http.StatusNotModified)
+		if f, ok := o.w.(interface{ WriteHeaderNow() }); ok {
+			f.WriteHeaderNow()
+		}
+		return http.StatusNotModified
+	}
+	o.write(status, contentType, body)
+	return status
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using the
+// weak comparison RFC 9110 requires for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
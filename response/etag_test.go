@@ -0,0 +1,64 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestObjectCached(t *testing.T) {
+	router := gin.New()
+	router.GET("/strong", func(c *gin.Context) { response.ObjectCached(c, map[string]any{"object": "gallery", "id": "gal_1"}) })
+	router.GET("/weak", func(c *gin.Context) { response.ObjectCachedWeak(c, map[string]any{"object": "gallery", "id": "gal_1"}) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/strong", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" || strings.HasPrefix(etag, "W/") {
+		t.Fatalf("expected 200 with strong ETag, got %d '%s'", w.Code, etag)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/weak", nil))
+	weak := w.Header().Get("ETag")
+	if weak != "W/"+etag {
+		t.Errorf("expected weak ETag W/%s, got '%s'", etag, weak)
+	}
+
+	tests := []struct {
+		name        string
+		target      string
+		ifNoneMatch string
+		want        int
+	}{
+		{name: "match", target: "/strong", ifNoneMatch: etag, want: http.StatusNotModified},
+		{name: "match in list", target: "/strong", ifNoneMatch: `"other", ` + etag, want: http.StatusNotModified},
+		{name: "weak comparison", target: "/weak", ifNoneMatch: etag, want: http.StatusNotModified},
+		{name: "star", target: "/strong", ifNoneMatch: "*", want: http.StatusNotModified},
+		{name: "stale", target: "/strong", ifNoneMatch: `"stale"`, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, w.Code)
+			}
+			if tt.want == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("expected empty body on 304, got %s", w.Body.String())
+			}
+			if w.Header().Get("ETag") == "" {
+				t.Error("expected ETag on every response")
+			}
+		})
+	}
+}
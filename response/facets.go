@@ -0,0 +1,57 @@
+package response
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+// Facet types
+const (
+	FacetTypeTerms = "terms" // value counts, e.g. tags or languages
+	FacetTypeRange = "range" // min/max bounds, e.g. page count or date
+)
+
+// Facets maps a filter name (e.g. "tags", "pages") to its facet.
+type Facets map[string]Facet
+
+// Facet holds aggregated filter information for a list.
+type Facet struct {
+	Type    string        `json:"type"`              // see FacetType* constants
+	Buckets []FacetBucket `json:"buckets,omitempty"` // for terms facets, ordered by the caller
+	Min     *float64      `json:"min,omitempty"`     // for range facets
+	Max     *float64      `json:"max,omitempty"`     // for range facets
+}
+
+// FacetBucket is a single term and its matching document count.
+type FacetBucket struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// TermsFacet creates a terms facet from buckets.
+func TermsFacet(buckets ...FacetBucket) Facet {
+	if buckets == nil {
+		buckets = []FacetBucket{}
+	}
+	return Facet{Type: FacetTypeTerms, Buckets: buckets}
+}
+
+// RangeFacet creates a range facet with the given bounds.
+func RangeFacet(min, max float64) Facet {
+	return Facet{Type: FacetTypeRange, Min: &min, Max: &max}
+}
+
+// ListWithFacets sends a list response with a facets section for search
+// endpoints whose UI shows filter counts.
+// Facets are dropped in PaginationHeaders mode, which has no envelope.
+//
+//	response.ListWithFacets(c, galleries, total, params, response.Facets{
+//	    "tags":  response.TermsFacet(response.FacetBucket{Value: "color", Count: 120}),
+//	    "pages": response.RangeFacet(1, 480),
+//	})
+func ListWithFacets[T any](c *gin.Context, data []T, total int64, params pagination.Params, facets Facets) {
+	list := NewList(data, total, params.Limit, params.Offset)
+	list.Facets = facets
+	sendList(c, list)
+}
@@ -0,0 +1,39 @@
+package response_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/pagination"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestListWithFacets(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	response.ListWithFacets(c, []string{"a"}, 1, pagination.Params{Limit: 20}, response.Facets{
+		"tags":  response.TermsFacet(response.FacetBucket{Value: "color", Count: 12}),
+		"pages": response.RangeFacet(1, 480),
+	})
+
+	want := `{"object":"list","data":["a"],"total":1,"limit":20,"offset":0,"has_more":false,` +
+		`"facets":{"pages":{"type":"range","min":1,"max":480},"tags":{"type":"terms","buckets":[{"value":"color","count":12}]}}}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestListWithoutFacetsOmitsSection(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	response.ListResponse(c, []string{}, 0, 20, 0)
+
+	want := `{"object":"list","data":[],"total":0,"limit":20,"offset":0,"has_more":false}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
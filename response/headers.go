@@ -0,0 +1,115 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PaginationMode controls how ListResponse exposes pagination metadata.
+type PaginationMode int
+
+const (
+	// PaginationBody uses the standard list envelope (the default).
+	PaginationBody PaginationMode = iota
+	// PaginationHeaders sends a bare JSON array and pagination via headers,
+	// for header-based clients like React-Admin.
+	PaginationHeaders
+	// PaginationBoth sends the list envelope and the pagination headers.
+	PaginationBoth
+)
+
+// paginationModeKey is the gin context key for the pagination mode.
+const paginationModeKey = "ginapi.pagination_mode"
+
+// UsePaginationMode returns middleware that sets the pagination mode for
+// ListResponse calls in the routes below it.
+//
+//	admin := router.Group("/admin", response.UsePaginationMode(response.PaginationHeaders))
+func UsePaginationMode(mode PaginationMode) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(paginationModeKey, mode)
+		c.Next()
+	}
+}
+
+// paginationMode returns the mode set by UsePaginationMode, falling back to
+// WithPaginationMode on the request context.
+func paginationMode(c *gin.Context) PaginationMode {
+	if v, ok := c.Get(paginationModeKey); ok {
+		if mode, ok := v.(PaginationMode); ok {
+			return mode
+		}
+	}
+	if c.Request != nil {
+		return PaginationModeFromContext(c.Request.Context())
+	}
+	return PaginationBody
+}
+
+// paginationModeContextKey is the request context key for the pagination mode.
+type paginationModeContextKey struct{}
+
+// WithPaginationMode returns a copy of ctx with the given pagination mode.
+// This is the net/http equivalent of UsePaginationMode.
+func WithPaginationMode(ctx context.Context, mode PaginationMode) context.Context {
+	return context.WithValue(ctx, paginationModeContextKey{}, mode)
+}
+
+// PaginationModeFromContext returns the mode stored by WithPaginationMode.
+func PaginationModeFromContext(ctx context.Context) PaginationMode {
+	if ctx == nil {
+		return PaginationBody
+	}
+	mode, _ := ctx.Value(paginationModeContextKey{}).(PaginationMode)
+	return mode
+}
+
+// SetPaginationHeaders sets X-Total-Count, X-Limit, X-Offset, and an RFC 8288
+// Link header (first/prev/next/last) built from the current request URL.
+// ListResponse calls this automatically outside PaginationBody mode.
+func SetPaginationHeaders(c *gin.Context, total int64, limit, offset int) {
+	WritePaginationHeaders(c.Writer, c.Request, total, limit, offset)
+}
+
+// WritePaginationHeaders is the net/http equivalent of SetPaginationHeaders.
+func WritePaginationHeaders(w http.ResponseWriter, r *http.Request, total int64, limit, offset int) {
+	h := w.Header()
+	h.Set("X-Total-Count", strconv.FormatInt(total, 10))
+	h.Set("X-Limit", strconv.Itoa(limit))
+	h.Set("X-Offset", strconv.Itoa(offset))
+	AddExposeHeaders(h, "X-Total-Count", "X-Limit", "X-Offset", "Link")
+
+	if r == nil || limit <= 0 {
+		return
+	}
+
+	var links []string
+	link := func(rel string, off int) {
+		u := *r.URL
+		q := u.Query()
+		q.Set("limit", strconv.Itoa(limit))
+		q.Set("offset", strconv.Itoa(off))
+		u.RawQuery = q.Encode()
+		u.Scheme, u.Host = "", ""
+		links = append(links, fmt.Sprintf("<%s>; rel=\"%s\"", u.String(), rel))
+	}
+
+	link("first", 0)
+	if offset > 0 {
+		link("prev", max(offset-limit, 0))
+	}
+	if int64(offset+limit) < total {
+		link("next", offset+limit)
+	}
+	if total > 0 {
+		last := int((total - 1) / int64(limit) * int64(limit))
+		link("last", last)
+	}
+
+	h.Set("Link", strings.Join(links, ", "))
+}
@@ -0,0 +1,101 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestPaginationHeadersMode(t *testing.T) {
+	router := gin.New()
+	router.Use(response.UsePaginationMode(response.PaginationHeaders))
+	router.GET("/items", func(c *gin.Context) {
+		response.ListResponse(c, []int{3, 4}, 7, 2, 2)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items?limit=2&offset=2&q=cat", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Body.String() != "[3,4]" {
+		t.Errorf("expected bare array, got %s", w.Body.String())
+	}
+	if got := w.Header().Get("X-Total-Count"); got != "7" {
+		t.Errorf("expected X-Total-Count 7, got '%s'", got)
+	}
+	if got := w.Header().Get("X-Offset"); got != "2" {
+		t.Errorf("expected X-Offset 2, got '%s'", got)
+	}
+
+	wantLink := `</items?limit=2&offset=0&q=cat>; rel="first", ` +
+		`</items?limit=2&offset=0&q=cat>; rel="prev", ` +
+		`</items?limit=2&offset=4&q=cat>; rel="next", ` +
+		`</items?limit=2&offset=6&q=cat>; rel="last"`
+	if got := w.Header().Get("Link"); got != wantLink {
+		t.Errorf("unexpected Link header:\n got: %s\nwant: %s", got, wantLink)
+	}
+}
+
+func TestPaginationBothMode(t *testing.T) {
+	router := gin.New()
+	router.Use(response.UsePaginationMode(response.PaginationBoth))
+	router.GET("/items", func(c *gin.Context) {
+		response.ListResponse(c, []int{1}, 1, 20, 0)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items", nil)
+	router.ServeHTTP(w, req)
+
+	want := `{"object":"list","data":[1],"total":1,"limit":20,"offset":0,"has_more":false}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+	if got := w.Header().Get("X-Total-Count"); got != "1" {
+		t.Errorf("expected X-Total-Count 1, got '%s'", got)
+	}
+	if got := w.Header().Get("Link"); got != `</items?limit=20&offset=0>; rel="first", </items?limit=20&offset=0>; rel="last"` {
+		t.Errorf("unexpected Link header: %s", got)
+	}
+}
+
+func TestPaginationBodyModeHasNoHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	response.ListResponse(c, []int{1}, 1, 20, 0)
+
+	if w.Header().Get("X-Total-Count") != "" {
+		t.Error("expected no pagination headers in body mode")
+	}
+}
+
+func TestPaginationHeadersMergeExposeHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		want     string
+	}{
+		{"none", nil, "X-Total-Count, X-Limit, X-Offset, Link"},
+		{"kept", []string{"X-Request-ID"}, "X-Request-ID, X-Total-Count, X-Limit, X-Offset, Link"},
+		{"deduplicated", []string{"x-total-count, ETag", "Link"}, "x-total-count, ETag, Link, X-Limit, X-Offset"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			for _, v := range tt.existing {
+				w.Header().Add("Access-Control-Expose-Headers", v)
+			}
+			response.WritePaginationHeaders(w, httptest.NewRequest(http.MethodGet, "/galleries", nil), 100, 10, 0)
+
+			if got := w.Header().Values("Access-Control-Expose-Headers"); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
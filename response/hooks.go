@@ -0,0 +1,147 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/doujins-org/ginapi/cache"
+)
+
+// ResponseHook is called after Object, Created, Deleted, SoftDeleted, or an
+// error helper (or their Write* equivalents) has written a response.
+// objectType and id come from the object's "object" and "id" fields; errors
+// are reported with objectType "error" and an empty id.
+//
+// ctx is the *gin.Context in gin handlers and the request context otherwise.
+// Hooks run synchronously on the request goroutine, so anything slow should
+// be handed off.
+type ResponseHook func(ctx context.Context, status int, objectType, id string)
+
+var (
+	hooksMu     sync.RWMutex
+	hooks       []ResponseHook
+	invalidated []*cache.Cache // see InvalidateCache
+)
+
+// OnResponse registers a hook for cross-cutting concerns like cache
+// invalidation, analytics events, and audit records, without wrapping every
+// handler. Register hooks at startup; they run in registration order.
+//
+//	response.OnResponse(func(ctx context.Context, status int, objectType, id string) {
+//	    if objectType == "gallery" && status < 300 {
+//	        cache.Invalidate(ctx, "gallery:"+id)
+//	    }
+//	})
+func OnResponse(hook ResponseHook) {
+	hooksMu.Lock()
+	hooks = append(hooks, hook)
+	hooksMu.Unlock()
+}
+
+// InvalidateCache deletes the cache entry of the object carried by every
+// successful response to a write (any method but GET, HEAD, and OPTIONS)
+// from c, at cache.KeyFor(objectType, id). Objects cached under that key
+// stop being served stale after Created, Object from an update, or Deleted:
+//
+//	response.InvalidateCache(cache.Default())
+//
+//	gallery, err := cache.Do(ctx, cache.KeyFor("gallery", id), time.Hour, loadGallery)
+//
+// Entries are deleted before the hooks registered with OnResponse run;
+// failures are reported (see SetReporter). Entries under other keys, like
+// cached lists, still need deleting by hand.
+func InvalidateCache(c *cache.Cache) {
+	hooksMu.Lock()
+	invalidated = append(invalidated, c)
+	hooksMu.Unlock()
+}
+
+// ResetResponseHooks removes all hooks registered with OnResponse and
+// caches registered with InvalidateCache. Intended for tests.
+func ResetResponseHooks() {
+	hooksMu.Lock()
+	hooks = nil
+	invalidated = nil
+	hooksMu.Unlock()
+}
+
+// notifyObject runs the hooks for an object response, reading the type and
+// id from obj. The object is only inspected when hooks are registered.
+func (o output) notifyObject(status int, obj any) {
+	if !hasHooks() {
+		return
+	}
+	objectType, id := objectIdentity(obj)
+	o.notify(status, objectType, id)
+}
+
+// notify invalidates cached objects and runs the registered hooks.
+func (o output) notify(status int, objectType, id string) {
+	hooksMu.RLock()
+	registered, caches := hooks, invalidated
+	hooksMu.RUnlock()
+
+	ctx := o.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(caches) > 0 && status < 300 && objectType != "" && objectType != "error" && id != "" && isWrite(o.r) {
+		for _, c := range caches {
+			if err := c.Delete(ctx, cache.KeyFor(objectType, id)); err != nil {
+				ReportError(ctx, o.r, "", err)
+			}
+		}
+	}
+	for _, hook := range registered {
+		hook(ctx, status, objectType, id)
+	}
+}
+
+func hasHooks() bool {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	return len(hooks) > 0 || len(invalidated) > 0
+}
+
+// isWrite reports whether r may have changed the objects in its response.
+func isWrite(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// objectIdentity returns the "object" and "id" fields of obj's JSON form.
+// Numeric ids are returned in their JSON form.
+func objectIdentity(obj any) (objectType, id string) {
+	switch v := obj.(type) {
+	case DeletedObject:
+		return v.Object, v.ID
+	case SoftDeletedObject:
+		return v.Object, v.ID
+	case AbsentObject:
+		return v.Object, ""
+	}
+
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return "", ""
+	}
+	var fields struct {
+		Object string          `json:"object"`
+		ID     json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(b, &fields) != nil {
+		return "", ""
+	}
+	if err := json.Unmarshal(fields.ID, &id); err != nil && len(fields.ID) > 0 && fields.ID[0] != 'n' {
+		id = string(fields.ID)
+	}
+	return fields.Object, id
+}
@@ -0,0 +1,142 @@
+package response_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/cache"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestOnResponse(t *testing.T) {
+	var got []string
+	response.OnResponse(func(ctx context.Context, status int, objectType, id string) {
+		got = append(got, fmt.Sprintf("%d %s %s %v", status, objectType, id, ctx.Value("user")))
+	})
+	t.Cleanup(response.ResetResponseHooks)
+
+	type gallery struct {
+		Object string `json:"object"`
+		ID     int64  `json:"id"`
+	}
+
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		want    string
+	}{
+		{"object", func(c *gin.Context) { response.Object(c, gallery{Object: "gallery", ID: 42}) }, "200 gallery 42 usr_1"},
+		{"created", func(c *gin.Context) {
+			response.Created(c, map[string]any{"object": "tag", "id": "tag_1"})
+		}, "201 tag tag_1 usr_1"},
+		{"deleted", func(c *gin.Context) { response.Deleted(c, "gallery", "gal_1") }, "200 gallery gal_1 usr_1"},
+		{"soft deleted", func(c *gin.Context) { response.SoftDeleted(c, "gallery", "gal_2", time.Now()) }, "200 gallery gal_2 usr_1"},
+		{"error", func(c *gin.Context) { response.NotFound(c, "gallery not found") }, "404 error  usr_1"},
+		{"cached", func(c *gin.Context) { response.ObjectCached(c, gallery{Object: "gallery", ID: 7}) }, "200 gallery 7 usr_1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			router := gin.New()
+			router.GET("/test", func(c *gin.Context) {
+				c.Set("user", "usr_1")
+				tt.handler(c)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			router.ServeHTTP(w, req)
+
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("expected [%s], got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestOnResponseNetHTTP(t *testing.T) {
+	var got []string
+	response.OnResponse(func(ctx context.Context, status int, objectType, id string) {
+		got = append(got, fmt.Sprintf("%d %s %s", status, objectType, id))
+	})
+	t.Cleanup(response.ResetResponseHooks)
+
+	r, _ := http.NewRequest("DELETE", "/galleries/gal_1", nil)
+	response.WriteDeleted(httptest.NewRecorder(), r, "gallery", "gal_1")
+	response.WriteError(httptest.NewRecorder(), r, http.StatusConflict, response.ErrorInfo{Type: response.ErrorTypeConflict, Message: "busy"})
+
+	want := []string{"200 gallery gal_1", "409 error "}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestOnResponseNotCalledForLists(t *testing.T) {
+	calls := 0
+	response.OnResponse(func(context.Context, int, string, string) { calls++ })
+	t.Cleanup(response.ResetResponseHooks)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	response.ListResponse(c, []string{"a"}, 1, 10, 0)
+	response.Success(c, "ok")
+
+	if calls != 0 {
+		t.Errorf("expected no hook calls, got %d", calls)
+	}
+}
+
+func TestInvalidateCache(t *testing.T) {
+	store := cache.NewMemoryStore(0)
+	c := cache.New(cache.Config{Store: store})
+	response.InvalidateCache(c)
+	t.Cleanup(response.ResetResponseHooks)
+
+	tests := []struct {
+		name        string
+		method      string
+		handler     gin.HandlerFunc
+		wantDeleted bool
+	}{
+		{"created", "POST", func(c *gin.Context) {
+			response.Created(c, map[string]any{"object": "gallery", "id": "gal_1"})
+		}, true},
+		{"updated", "PATCH", func(c *gin.Context) {
+			response.Object(c, map[string]any{"object": "gallery", "id": "gal_1"})
+		}, true},
+		{"deleted", "DELETE", func(c *gin.Context) { response.Deleted(c, "gallery", "gal_1") }, true},
+		{"read", "GET", func(c *gin.Context) {
+			response.Object(c, map[string]any{"object": "gallery", "id": "gal_1"})
+		}, false},
+		{"other object", "PATCH", func(c *gin.Context) {
+			response.Object(c, map[string]any{"object": "gallery", "id": "gal_2"})
+		}, false},
+		{"error", "DELETE", func(c *gin.Context) { response.NotFound(c, "gallery not found") }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := cache.KeyFor("gallery", "gal_1")
+			if err := store.Set(context.Background(), key, []byte(`{}`), time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			router := gin.New()
+			router.Handle(tt.method, "/galleries", tt.handler)
+			req, _ := http.NewRequest(tt.method, "/galleries", nil)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			_, found, _ := store.Get(context.Background(), key)
+			if found == tt.wantDeleted {
+				t.Errorf("expected deleted %v, got %v", tt.wantDeleted, !found)
+			}
+		})
+	}
+}
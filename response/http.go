@@ -0,0 +1,88 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+// The Write* functions are the net/http equivalents of the gin helpers, for
+// services on the standard library mux or chi. They produce byte-identical
+// output. Settings that gin middleware stores on the gin context (audiences,
+// pagination mode) are read from the request context instead; see
+// WithAudiences and WithPaginationMode.
+
+// WriteJSON writes v as a JSON response with the given status.
+func WriteJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	httpOutput(w, r).json(status, v)
+}
+
+// WriteObject is the net/http equivalent of Object.
+func WriteObject(w http.ResponseWriter, r *http.Request, obj any) {
+	renderObject(httpOutput(w, r), http.StatusOK, obj)
+}
+
+// WriteCreated is the net/http equivalent of Created.
+func WriteCreated(w http.ResponseWriter, r *http.Request, obj any) {
+	renderObject(httpOutput(w, r), http.StatusCreated, obj)
+}
+
+// WriteAccepted is the net/http equivalent of Accepted.
+func WriteAccepted(w http.ResponseWriter, r *http.Request, obj any) {
+	renderObject(httpOutput(w, r), http.StatusAccepted, obj)
+}
+
+// WriteNoContent is the net/http equivalent of NoContent.
+func WriteNoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WriteObjectOrNotFound is the net/http equivalent of ObjectOrNotFound.
+func WriteObjectOrNotFound(w http.ResponseWriter, r *http.Request, obj any, found bool) {
+	objectOrNotFound(httpOutput(w, r), obj, found)
+}
+
+// WriteDeleted is the net/http equivalent of Deleted.
+func WriteDeleted(w http.ResponseWriter, r *http.Request, objectType string, id string) {
+	renderObject(httpOutput(w, r), http.StatusOK, DeletedObject{
+		Object:  objectType,
+		ID:      id,
+		Deleted: true,
+	})
+}
+
+// WriteList is the net/http equivalent of ListResponse.
+func WriteList[T any](w http.ResponseWriter, r *http.Request, data []T, total int64, limit, offset int) {
+	writeList(httpOutput(w, r), http.StatusOK, NewList(data, total, limit, offset))
+}
+
+// WriteListWithTotal is the net/http equivalent of ListWithTotal.
+func WriteListWithTotal[T any](w http.ResponseWriter, r *http.Request, data []T, total pagination.Total, params pagination.Params) {
+	writeList(httpOutput(w, r), http.StatusOK, newListWithTotal(data, total, params))
+}
+
+// WriteListWithCounter is the net/http equivalent of ListWithCounter.
+func WriteListWithCounter[T any](w http.ResponseWriter, r *http.Request, data []T, counter pagination.Counter, params pagination.Params) {
+	total, err := counter.Count(r.Context())
+	if err != nil {
+		ReportError(r.Context(), r, r.Pattern, err)
+		httpOutput(w, r).error(http.StatusInternalServerError, countFailed)
+		return
+	}
+	WriteListWithTotal(w, r, data, total, params)
+}
+
+// WriteNotModifiedList is the net/http equivalent of NotModifiedList.
+func WriteNotModifiedList(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// WriteError is the net/http equivalent of the error helpers.
+//
+//	response.WriteError(w, r, http.StatusNotFound, response.ErrorInfo{
+//	    Type:    response.ErrorTypeNotFound,
+//	    Message: "gallery not found",
+//	})
+func WriteError(w http.ResponseWriter, r *http.Request, status int, info ErrorInfo) {
+	httpOutput(w, r).error(status, info)
+}
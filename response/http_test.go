@@ -0,0 +1,82 @@
+package response_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestWriteListMatchesGin(t *testing.T) {
+	data := []map[string]string{{"object": "artist", "id": "art_1"}}
+
+	ginRec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(ginRec)
+	c.Request, _ = http.NewRequest("GET", "/artists", nil)
+	response.ListResponse(c, data, 5, 1, 0)
+
+	httpRec := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/artists", nil)
+	response.WriteList(httpRec, req, data, 5, 1, 0)
+
+	if httpRec.Body.String() != ginRec.Body.String() {
+		t.Errorf("bodies differ:\n gin: %s\nhttp: %s", ginRec.Body.String(), httpRec.Body.String())
+	}
+	if httpRec.Header().Get("Content-Type") != ginRec.Header().Get("Content-Type") {
+		t.Errorf("content types differ: %s vs %s", ginRec.Header().Get("Content-Type"), httpRec.Header().Get("Content-Type"))
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/galleries/gal_1", nil)
+
+	response.WriteError(w, req, http.StatusNotFound, response.ErrorInfo{
+		Type:    response.ErrorTypeNotFound,
+		Message: "gallery not found",
+	})
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	want := `{"object":"error","error":{"type":"not_found","message":"gallery not found"}}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestWriteObjectUsesContextSettings(t *testing.T) {
+	type gallery struct {
+		ID     string `json:"id"`
+		Source string `json:"source" audience:"admin"`
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/galleries/gal_1", nil)
+	req = req.WithContext(response.WithAudiences(context.Background(), "admin"))
+
+	response.WriteObject(w, req, gallery{ID: "gal_1", Source: "src"})
+
+	if w.Body.String() != `{"id":"gal_1","source":"src"}` {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+}
+
+func TestWriteListHeadersMode(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items", nil)
+	req = req.WithContext(response.WithPaginationMode(req.Context(), response.PaginationHeaders))
+
+	response.WriteList(w, req, []int{1, 2}, 2, 20, 0)
+
+	if w.Body.String() != "[1,2]" {
+		t.Errorf("expected bare array, got %s", w.Body.String())
+	}
+	if w.Header().Get("X-Total-Count") != "2" {
+		t.Errorf("expected X-Total-Count 2, got '%s'", w.Header().Get("X-Total-Count"))
+	}
+}
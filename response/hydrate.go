@@ -0,0 +1,161 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ItemError is the failure to hydrate one list item from a source.
+type ItemError struct {
+	Source string    `json:"source"`
+	Error  ErrorInfo `json:"error"`
+}
+
+// Hydration collects the failures of hydrating a page's items from
+// secondary sources, such as thumbnails from an image service, by item
+// index. The zero value is ready to use; it is safe for concurrent use, so
+// items can be hydrated in parallel.
+type Hydration struct {
+	mu     sync.Mutex
+	failed map[int][]ItemError
+}
+
+// Fail records that item i couldn't be hydrated from source. err is
+// converted like SectionFailed: errors from ginapi services keep their type
+// and code, anything else becomes a generic service_unavailable.
+func (h *Hydration) Fail(i int, source string, err error) {
+	f := SectionFailed(source, err)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.failed == nil {
+		h.failed = map[int][]ItemError{}
+	}
+	h.failed[i] = append(h.failed[i], ItemError{Source: f.Section, Error: f.Error})
+}
+
+// Failed returns the failures of item i.
+func (h *Hydration) Failed(i int) []ItemError {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.failed[i]
+}
+
+// HydrationStats describes the hydration of one page, for metrics.
+type HydrationStats struct {
+	// Items on the page
+	Items int
+	// Failed is the number of items with at least one failure
+	Failed int
+	// Sources counts the failures per source
+	Sources map[string]int
+}
+
+// HydrationHook is called for every list sent with HydratedList, degraded
+// or not, so hydration failure rates can be computed. route is the matched
+// route pattern.
+type HydrationHook func(ctx context.Context, route string, stats HydrationStats)
+
+var (
+	hydrationMu    sync.RWMutex
+	hydrationHooks []HydrationHook
+)
+
+// OnHydrated registers a hook run for every list sent with HydratedList.
+// Register hooks at startup:
+//
+//	response.OnHydrated(func(ctx context.Context, route string, s response.HydrationStats) {
+//	    hydratedItems.WithLabelValues(route).Add(float64(s.Items))
+//	    for source, n := range s.Sources {
+//	        failedItems.WithLabelValues(route, source).Add(float64(n))
+//	    }
+//	})
+func OnHydrated(hook HydrationHook) {
+	hydrationMu.Lock()
+	hydrationHooks = append(hydrationHooks, hook)
+	hydrationMu.Unlock()
+}
+
+// ResetHydrationHooks removes all hooks registered with OnHydrated.
+// Intended for tests.
+func ResetHydrationHooks() {
+	hydrationMu.Lock()
+	hydrationHooks = nil
+	hydrationMu.Unlock()
+}
+
+// HydratedList sends a list whose items were hydrated from secondary
+// sources, where some items may have failed, instead of failing the whole
+// page. Failed items carry an "_errors" member, and the list is marked
+// degraded and sent with 207 Multi-Status, which shared caches won't
+// store; a page without failures is a plain 200 list:
+//
+//	var h response.Hydration
+//	for i, g := range galleries {
+//	    thumb, err := thumbnails.Get(ctx, g.CoverID)
+//	    if err != nil {
+//	        h.Fail(i, "thumbnails", err)
+//	        continue
+//	    }
+//	    galleries[i].Thumbnail = thumb
+//	}
+//	response.HydratedList(c, galleries, &h, total, p.Limit, p.Offset)
+//	// {"object": "list", "data": [{"id": "gal_1", ...,
+//	//  "_errors": [{"source": "thumbnails", "error": {"type": "api", "code": "service_unavailable", ...}}]}, ...],
+//	//  ..., "degraded": true}
+//
+// Items must encode as JSON objects to carry their errors. The hooks
+// registered with OnHydrated are run with the page's stats.
+func HydratedList[T any](c *gin.Context, data []T, h *Hydration, total int64, limit, offset int) {
+	o := ginOutput(c)
+	items := make([]hydratedItem[T], len(data))
+	stats := HydrationStats{Items: len(data), Sources: map[string]int{}}
+	for i, item := range data {
+		failed := h.Failed(i)
+		items[i] = hydratedItem[T]{item: item, errors: failed, audiences: o.audiences}
+		if len(failed) > 0 {
+			stats.Failed++
+		}
+		for _, f := range failed {
+			stats.Sources[f.Source]++
+		}
+	}
+
+	list := NewList(items, total, limit, offset)
+	status := http.StatusOK
+	if stats.Failed > 0 {
+		list.Degraded = true
+		status = http.StatusMultiStatus
+	}
+	writeList(o, status, list)
+
+	hydrationMu.RLock()
+	registered := hydrationHooks
+	hydrationMu.RUnlock()
+	for _, hook := range registered {
+		hook(c, c.FullPath(), stats)
+	}
+}
+
+// hydratedItem is a list item with its hydration failures.
+type hydratedItem[T any] struct {
+	item      T
+	errors    []ItemError
+	audiences []string
+}
+
+// MarshalJSON encodes the item, redacted for the response's audiences,
+// with an "_errors" member if it failed.
+func (h hydratedItem[T]) MarshalJSON() ([]byte, error) {
+	body, err := json.Marshal(redact(h.item, h.audiences))
+	if err != nil || len(h.errors) == 0 {
+		return body, err
+	}
+	return appendMember(body, "_errors", h.errors), nil
+}
@@ -0,0 +1,114 @@
+package response_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+type hydratedGallery struct {
+	ID        string `json:"id"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+	Uploader  string `json:"uploader,omitempty" audience:"staff"`
+}
+
+func TestHydratedList(t *testing.T) {
+	t.Cleanup(response.ResetHydrationHooks)
+	var stats []response.HydrationStats
+	response.OnHydrated(func(ctx context.Context, route string, s response.HydrationStats) {
+		if route != "/hydrated" {
+			t.Errorf("expected route /hydrated, got %s", route)
+		}
+		stats = append(stats, s)
+	})
+
+	tests := []struct {
+		name         string
+		fail         map[int]string // item -> source
+		wantStatus   int
+		wantDegraded bool
+		wantErrors   []int // number of errors per item
+		wantStats    response.HydrationStats
+	}{
+		{
+			name:       "all hydrated",
+			wantStatus: http.StatusOK,
+			wantErrors: []int{0, 0, 0},
+			wantStats:  response.HydrationStats{Items: 3, Sources: map[string]int{}},
+		},
+		{
+			name:         "one failed",
+			fail:         map[int]string{1: "thumbnails"},
+			wantStatus:   http.StatusMultiStatus,
+			wantDegraded: true,
+			wantErrors:   []int{0, 1, 0},
+			wantStats:    response.HydrationStats{Items: 3, Failed: 1, Sources: map[string]int{"thumbnails": 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats = nil
+			router := gin.New()
+			router.GET("/hydrated", func(c *gin.Context) {
+				galleries := []hydratedGallery{{ID: "gal_1"}, {ID: "gal_2"}, {ID: "gal_3"}}
+				var h response.Hydration
+				for i := range galleries {
+					if source, ok := tt.fail[i]; ok {
+						h.Fail(i, source, errors.New("dial tcp 10.0.0.7:443: i/o timeout"))
+						continue
+					}
+					galleries[i].Thumbnail = "https://img.example.com/" + galleries[i].ID + ".webp"
+					galleries[i].Uploader = "usr_1"
+				}
+				response.HydratedList(c, galleries, &h, 3, 20, 0)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hydrated", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			var body struct {
+				Object   string `json:"object"`
+				Degraded bool   `json:"degraded"`
+				Data     []struct {
+					ID       string               `json:"id"`
+					Uploader string               `json:"uploader"`
+					Errors   []response.ItemError `json:"_errors"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Object != "list" || body.Degraded != tt.wantDegraded || len(body.Data) != 3 {
+				t.Fatalf("unexpected body %s", w.Body.String())
+			}
+			for i, item := range body.Data {
+				if len(item.Errors) != tt.wantErrors[i] {
+					t.Errorf("expected %d errors on %s, got %+v", tt.wantErrors[i], item.ID, item.Errors)
+				}
+				if item.Uploader != "" {
+					t.Errorf("expected the staff field redacted, got %q", item.Uploader)
+				}
+				for _, e := range item.Errors {
+					if e.Source != "thumbnails" || e.Error.Code != response.ErrorCodeServiceUnavailable {
+						t.Errorf("unexpected item error %+v", e)
+					}
+				}
+			}
+			if len(stats) != 1 || !reflect.DeepEqual(stats[0], tt.wantStats) {
+				t.Errorf("expected stats %+v, got %+v", tt.wantStats, stats)
+			}
+		})
+	}
+}
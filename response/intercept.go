@@ -0,0 +1,132 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// interceptorsKey is the gin context key for response interceptors.
+const interceptorsKey = "ginapi.interceptors"
+
+// Interceptor mutates an object right before it is serialized. It sees the
+// object in its JSON form (after audience redaction), so one interceptor
+// works for every type: Object and Created pass the object, lists pass each
+// item. Errors are not intercepted. Because the object is re-encoded from a
+// map, its keys come out sorted.
+type Interceptor func(r *http.Request, obj map[string]any)
+
+// UseInterceptors returns middleware that registers interceptors for the
+// routes below it. Interceptors added by nested groups run after the outer ones.
+//
+//	api.Use(response.UseInterceptors(func(r *http.Request, obj map[string]any) {
+//	    if u, ok := obj["cover_url"].(string); ok {
+//	        obj["cover_url"] = cdn.Rewrite(u)
+//	    }
+//	}))
+func UseInterceptors(interceptors ...Interceptor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, _ := c.Get(interceptorsKey)
+		existing, _ := v.([]Interceptor)
+		c.Set(interceptorsKey, append(slices.Clip(existing), interceptors...))
+		c.Next()
+	}
+}
+
+// ginInterceptors returns the interceptors from UseInterceptors, falling back
+// to WithInterceptors on the request context.
+func ginInterceptors(c *gin.Context) []Interceptor {
+	if v, ok := c.Get(interceptorsKey); ok {
+		if interceptors, ok := v.([]Interceptor); ok {
+			return interceptors
+		}
+	}
+	if c.Request != nil {
+		return InterceptorsFromContext(c.Request.Context())
+	}
+	return nil
+}
+
+// interceptorsContextKey is the request context key for response interceptors.
+type interceptorsContextKey struct{}
+
+// WithInterceptors returns a copy of ctx with the interceptors appended.
+// This is the net/http equivalent of UseInterceptors.
+func WithInterceptors(ctx context.Context, interceptors ...Interceptor) context.Context {
+	existing := InterceptorsFromContext(ctx)
+	return context.WithValue(ctx, interceptorsContextKey{}, append(slices.Clip(existing), interceptors...))
+}
+
+// InterceptorsFromContext returns the interceptors stored by WithInterceptors.
+func InterceptorsFromContext(ctx context.Context) []Interceptor {
+	if ctx == nil {
+		return nil
+	}
+	interceptors, _ := ctx.Value(interceptorsContextKey{}).([]Interceptor)
+	return interceptors
+}
+
+// StripNulls is an Interceptor that removes null fields, recursively.
+func StripNulls(_ *http.Request, obj map[string]any) {
+	stripNulls(obj)
+}
+
+func stripNulls(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if val == nil {
+				delete(v, k)
+				continue
+			}
+			stripNulls(val)
+		}
+	case []any:
+		for _, val := range v {
+			stripNulls(val)
+		}
+	}
+}
+
+// intercept runs the interceptors over an encoded body. Errors pass through.
+func (o output) intercept(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	apply := func(item any) {
+		if obj, ok := item.(map[string]any); ok {
+			for _, interceptor := range o.interceptors {
+				interceptor(o.r, obj)
+			}
+		}
+	}
+
+	switch v := v.(type) {
+	case []any:
+		// Bare array from PaginationHeaders mode
+		for _, item := range v {
+			apply(item)
+		}
+	case map[string]any:
+		switch v["object"] {
+		case "error":
+			return body, nil
+		case "list":
+			items, _ := v["data"].([]any)
+			for _, item := range items {
+				apply(item)
+			}
+		default:
+			apply(v)
+		}
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,79 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+type interceptGallery struct {
+	Object   string  `json:"object"`
+	ID       string  `json:"id"`
+	CoverURL string  `json:"cover_url"`
+	Subtitle *string `json:"subtitle"`
+}
+
+func rewriteCDN(_ *http.Request, obj map[string]any) {
+	if u, ok := obj["cover_url"].(string); ok {
+		obj["cover_url"] = strings.Replace(u, "https://origin.example", "https://cdn.example", 1)
+	}
+}
+
+func newInterceptRouter() *gin.Engine {
+	router := gin.New()
+	api := router.Group("/api", response.UseInterceptors(rewriteCDN))
+	strict := api.Group("/v2", response.UseInterceptors(response.StripNulls))
+
+	gallery := interceptGallery{Object: "gallery", ID: "gal_1", CoverURL: "https://origin.example/c.webp"}
+	handlers := func(g *gin.RouterGroup) {
+		g.GET("/object", func(c *gin.Context) { response.Object(c, gallery) })
+		g.POST("/created", func(c *gin.Context) { response.Created(c, gallery) })
+		g.GET("/list", func(c *gin.Context) { response.ListResponse(c, []interceptGallery{gallery}, 1, 20, 0) })
+		g.GET("/error", func(c *gin.Context) { response.NotFound(c, "gallery") })
+	}
+	handlers(api)
+	handlers(strict)
+	return router
+}
+
+func TestInterceptors(t *testing.T) {
+	tests := []struct {
+		method string
+		target string
+		want   string
+	}{
+		{"GET", "/api/object", `{"cover_url":"https://cdn.example/c.webp","id":"gal_1","object":"gallery","subtitle":null}`},
+		{"POST", "/api/created", `{"cover_url":"https://cdn.example/c.webp","id":"gal_1","object":"gallery","subtitle":null}`},
+		{"GET", "/api/list", `{"data":[{"cover_url":"https://cdn.example/c.webp","id":"gal_1","object":"gallery","subtitle":null}],"has_more":false,"limit":20,"object":"list","offset":0,"total":1}`},
+		{"GET", "/api/error", `{"object":"error","error":{"type":"not_found","message":"gallery not found"}}`},
+		{"GET", "/api/v2/object", `{"cover_url":"https://cdn.example/c.webp","id":"gal_1","object":"gallery"}`},
+	}
+
+	router := newInterceptRouter()
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestWithInterceptors(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/galleries/gal_1", nil)
+	r = r.WithContext(response.WithInterceptors(r.Context(), rewriteCDN))
+	w := httptest.NewRecorder()
+
+	response.WriteObject(w, r, interceptGallery{Object: "gallery", ID: "gal_1", CoverURL: "https://origin.example/c.webp"})
+
+	if !strings.Contains(w.Body.String(), `"cover_url":"https://cdn.example/c.webp"`) {
+		t.Errorf("expected rewritten URL, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,271 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSONAPIMediaType is the media type of JSON:API documents.
+const JSONAPIMediaType = "application/vnd.api+json"
+
+// jsonAPIKey is the gin context key enabling JSON:API output.
+const jsonAPIKey = "ginapi.jsonapi"
+
+// UseJSONAPI returns middleware that makes the helpers in the routes below it
+// emit JSON:API documents instead of the standard envelope:
+//
+//   - Objects become resources: "object" is the type, "id" the id, and the
+//     remaining fields the attributes
+//   - Nested objects with "object" and "id" become relationships, with the
+//     full object added to "included"
+//   - Lists put their items in "data" and total/limit/offset/has_more in "meta"
+//   - Errors become a top-level "errors" array
+//
+// Usage:
+//
+//	partner := router.Group("/partner", response.UseJSONAPI())
+func UseJSONAPI() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(jsonAPIKey, true)
+		c.Next()
+	}
+}
+
+// jsonAPIEnabled reports whether UseJSONAPI or WithJSONAPI applies to c.
+func jsonAPIEnabled(c *gin.Context) bool {
+	if v, ok := c.Get(jsonAPIKey); ok {
+		if enabled, ok := v.(bool); ok {
+			return enabled
+		}
+	}
+	if c.Request != nil {
+		return JSONAPIFromContext(c.Request.Context())
+	}
+	return false
+}
+
+// jsonAPIContextKey is the request context key enabling JSON:API output.
+type jsonAPIContextKey struct{}
+
+// WithJSONAPI returns a copy of ctx with JSON:API output enabled.
+// This is the net/http equivalent of UseJSONAPI.
+func WithJSONAPI(ctx context.Context) context.Context {
+	return context.WithValue(ctx, jsonAPIContextKey{}, true)
+}
+
+// JSONAPIFromContext reports whether WithJSONAPI enabled JSON:API output.
+func JSONAPIFromContext(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	enabled, _ := ctx.Value(jsonAPIContextKey{}).(bool)
+	return enabled
+}
+
+// jsonAPIResource is a JSON:API resource object.
+type jsonAPIResource struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id"`
+	Attributes    map[string]any                 `json:"attributes,omitempty"`
+	Relationships map[string]jsonAPIRelationship `json:"relationships,omitempty"`
+}
+
+// jsonAPIRelationship holds a resource identifier or a list of them.
+type jsonAPIRelationship struct {
+	Data any `json:"data"`
+}
+
+// jsonAPIIdentifier is a JSON:API resource identifier object.
+type jsonAPIIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// jsonAPIError is a JSON:API error object.
+type jsonAPIError struct {
+	Status string            `json:"status"`
+	Code   string            `json:"code,omitempty"`
+	Title  string            `json:"title,omitempty"`
+	Detail string            `json:"detail"`
+	Source map[string]any    `json:"source,omitempty"`
+	Links  map[string]string `json:"links,omitempty"` // "about": the doc_url
+	Meta   map[string]any    `json:"meta,omitempty"`  // the details
+}
+
+// jsonAPIDocument is a top-level JSON:API document.
+type jsonAPIDocument struct {
+	Data     any               `json:"data,omitempty"`
+	Errors   []jsonAPIError    `json:"errors,omitempty"`
+	Meta     map[string]any    `json:"meta,omitempty"`
+	Included []jsonAPIResource `json:"included,omitempty"`
+}
+
+// toJSONAPI converts an already-encoded standard response body into a
+// JSON:API document.
+func toJSONAPI(status int, body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	b := jsonAPIBuilder{seen: map[jsonAPIIdentifier]bool{}}
+	var doc jsonAPIDocument
+
+	switch v := v.(type) {
+	case []any:
+		// Bare array from PaginationHeaders mode
+		doc.Data = b.resources(v)
+	case map[string]any:
+		switch {
+		case v["object"] == "error":
+			info, _ := v["error"].(map[string]any)
+			doc.Errors = []jsonAPIError{jsonAPIErrorFrom(status, info)}
+		case v["object"] == "list":
+			items, _ := v["data"].([]any)
+			doc.Data = b.resources(items)
+			doc.Meta = map[string]any{}
+			for k, val := range v {
+				if k != "object" && k != "data" {
+					doc.Meta[k] = val
+				}
+			}
+		case isResource(v):
+			doc.Data = b.resource(v)
+		default:
+			// Not a resource (e.g. a success message), so it can only be meta
+			delete(v, "object")
+			doc.Meta = v
+		}
+	default:
+		doc.Meta = map[string]any{"value": v}
+	}
+
+	doc.Included = b.included
+	return json.Marshal(doc)
+}
+
+// jsonAPIBuilder accumulates included resources while converting.
+type jsonAPIBuilder struct {
+	included []jsonAPIResource
+	seen     map[jsonAPIIdentifier]bool
+}
+
+// resources converts a list of objects. Items that aren't resources are skipped.
+func (b *jsonAPIBuilder) resources(items []any) []jsonAPIResource {
+	out := make([]jsonAPIResource, 0, len(items))
+	for _, item := range items {
+		if m, ok := item.(map[string]any); ok && isResource(m) {
+			out = append(out, b.resource(m))
+		}
+	}
+	return out
+}
+
+// resource converts one object, moving nested objects into relationships.
+func (b *jsonAPIBuilder) resource(m map[string]any) jsonAPIResource {
+	res := jsonAPIResource{Type: resourceType(m), ID: resourceID(m)}
+
+	for k, v := range m {
+		if k == "object" || k == "id" {
+			continue
+		}
+		if rel, ok := b.relationship(v); ok {
+			if res.Relationships == nil {
+				res.Relationships = map[string]jsonAPIRelationship{}
+			}
+			res.Relationships[k] = rel
+			continue
+		}
+		if res.Attributes == nil {
+			res.Attributes = map[string]any{}
+		}
+		res.Attributes[k] = v
+	}
+	return res
+}
+
+// relationship converts v into a relationship if it is a resource or a
+// non-empty list of resources, adding them to the included set.
+func (b *jsonAPIBuilder) relationship(v any) (jsonAPIRelationship, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		if !isResource(v) {
+			return jsonAPIRelationship{}, false
+		}
+		return jsonAPIRelationship{Data: b.include(v)}, true
+	case []any:
+		if len(v) == 0 {
+			return jsonAPIRelationship{}, false
+		}
+		for _, item := range v {
+			if m, ok := item.(map[string]any); !ok || !isResource(m) {
+				return jsonAPIRelationship{}, false
+			}
+		}
+		ids := make([]jsonAPIIdentifier, len(v))
+		for i, item := range v {
+			ids[i] = b.include(item.(map[string]any))
+		}
+		return jsonAPIRelationship{Data: ids}, true
+	}
+	return jsonAPIRelationship{}, false
+}
+
+// include adds m to the included resources (once) and returns its identifier.
+func (b *jsonAPIBuilder) include(m map[string]any) jsonAPIIdentifier {
+	id := jsonAPIIdentifier{Type: resourceType(m), ID: resourceID(m)}
+	if !b.seen[id] {
+		b.seen[id] = true
+		b.included = append(b.included, b.resource(m))
+	}
+	return id
+}
+
+// isResource reports whether m has the "object" and "id" of a resource.
+func isResource(m map[string]any) bool {
+	_, hasType := m["object"].(string)
+	_, hasID := m["id"]
+	return hasType && hasID
+}
+
+func resourceType(m map[string]any) string {
+	t, _ := m["object"].(string)
+	return t
+}
+
+// resourceID returns the id as a string, as JSON:API requires.
+func resourceID(m map[string]any) string {
+	switch id := m["id"].(type) {
+	case string:
+		return id
+	case json.Number:
+		return id.String()
+	}
+	return ""
+}
+
+// jsonAPIErrorFrom converts an envelope's error info into a JSON:API error.
+func jsonAPIErrorFrom(status int, info map[string]any) jsonAPIError {
+	e := jsonAPIError{Status: strconv.Itoa(status)}
+	e.Code, _ = info["code"].(string)
+	e.Detail, _ = info["message"].(string)
+	if t, ok := info["type"].(string); ok {
+		e.Title = t
+	}
+	if pointer, ok := info["pointer"].(string); ok && pointer != "" {
+		e.Source = map[string]any{"pointer": pointer}
+	} else if param, ok := info["param"].(string); ok && param != "" {
+		e.Source = map[string]any{"parameter": param}
+	}
+	if docURL, ok := info["doc_url"].(string); ok && docURL != "" {
+		e.Links = map[string]string{"about": docURL}
+	}
+	e.Meta, _ = info["details"].(map[string]any)
+	return e
+}
@@ -0,0 +1,94 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+type jsonAPIArtist struct {
+	Object string `json:"object"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+}
+
+type jsonAPIGallery struct {
+	Object  string          `json:"object"`
+	ID      int             `json:"id"`
+	Title   string          `json:"title"`
+	Artist  jsonAPIArtist   `json:"artist"`
+	Artists []jsonAPIArtist `json:"artists"`
+}
+
+func newJSONAPIContext() (*httptest.ResponseRecorder, *gin.Context) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/partner/galleries", nil)
+	c.Request = c.Request.WithContext(response.WithJSONAPI(c.Request.Context()))
+	return w, c
+}
+
+func TestJSONAPIObject(t *testing.T) {
+	w, c := newJSONAPIContext()
+
+	artist := jsonAPIArtist{Object: "artist", ID: "art_1", Name: "Ann"}
+	response.Object(c, jsonAPIGallery{Object: "gallery", ID: 7, Title: "Title", Artist: artist, Artists: []jsonAPIArtist{artist}})
+
+	if got := w.Header().Get("Content-Type"); got != response.JSONAPIMediaType {
+		t.Errorf("expected Content-Type %s, got %s", response.JSONAPIMediaType, got)
+	}
+	want := `{"data":{"type":"gallery","id":"7","attributes":{"title":"Title"},` +
+		`"relationships":{"artist":{"data":{"type":"artist","id":"art_1"}},"artists":{"data":[{"type":"artist","id":"art_1"}]}}},` +
+		`"included":[{"type":"artist","id":"art_1","attributes":{"name":"Ann"}}]}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestJSONAPIList(t *testing.T) {
+	w, c := newJSONAPIContext()
+
+	response.ListResponse(c, []jsonAPIArtist{{Object: "artist", ID: "art_1", Name: "Ann"}}, 1, 20, 0)
+
+	want := `{"data":[{"type":"artist","id":"art_1","attributes":{"name":"Ann"}}],"meta":{"has_more":false,"limit":20,"offset":0,"total":1}}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestJSONAPIError(t *testing.T) {
+	w, c := newJSONAPIContext()
+
+	response.BadRequestParam(c, "limit", "limit must be positive")
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+	want := `{"errors":[{"status":"400","title":"invalid_request","detail":"limit must be positive","source":{"parameter":"limit"}}]}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestUseJSONAPI(t *testing.T) {
+	router := gin.New()
+	router.GET("/plain", func(c *gin.Context) { response.Success(c, "ok") })
+	partner := router.Group("/partner", response.UseJSONAPI())
+	partner.GET("/ping", func(c *gin.Context) { response.Success(c, "ok") })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/partner/ping", nil))
+	if want := `{"meta":{"message":"ok"}}`; w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/plain", nil))
+	if got := w.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("expected plain JSON outside the group, got %s", got)
+	}
+}
@@ -0,0 +1,302 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KeyCase names the casing of the JSON keys responses are sent with.
+type KeyCase string
+
+const (
+	// SnakeCase sends keys as the json tags spell them, which is the
+	// default: "has_more"
+	SnakeCase KeyCase = "snake"
+	// CamelCase converts snake_case keys to camelCase: "hasMore"
+	CamelCase KeyCase = "camel"
+)
+
+var (
+	keyCasesMu sync.RWMutex
+	keyCases   = map[KeyCase]func(key string) string{
+		SnakeCase: nil,
+		CamelCase: camelCaseKey,
+	}
+	defaultKeyCase atomic.Value // KeyCase
+)
+
+// RegisterKeyCase adds a key case clients can negotiate, with the
+// function converting each snake_case key to it, e.g. PascalCase for an
+// SDK generator that needs it. Call it at startup. It panics if name is
+// empty or convert is nil.
+func RegisterKeyCase(name KeyCase, convert func(key string) string) {
+	if name == "" || convert == nil {
+		panic("response: RegisterKeyCase requires a name and a convert function")
+	}
+	keyCasesMu.Lock()
+	keyCases[name] = convert
+	keyCasesMu.Unlock()
+}
+
+// keyCaseConverter returns the conversion of a key case; nil for keys
+// sent as is. ok is false for unregistered cases.
+func keyCaseConverter(kc KeyCase) (convert func(string) string, ok bool) {
+	keyCasesMu.RLock()
+	defer keyCasesMu.RUnlock()
+	convert, ok = keyCases[kc]
+	return convert, ok
+}
+
+// SetKeyCase sets the key case of every response whose route doesn't
+// negotiate one, for a deployment that serves only clients needing it.
+// Call it once at startup. It panics if kc isn't registered.
+func SetKeyCase(kc KeyCase) {
+	if _, ok := keyCaseConverter(kc); !ok {
+		panic("response: unknown key case " + strconv.Quote(string(kc)))
+	}
+	defaultKeyCase.Store(kc)
+}
+
+// KeyCaseConfig configures NegotiateKeyCase.
+type KeyCaseConfig struct {
+	// Header carrying the client's key case, e.g. "camel" (defaults to
+	// "Key-Case")
+	Header string
+	// Client, if set, returns the key case of a client that sends no
+	// header, e.g. by the partner its API key belongs to. Empty means
+	// Default.
+	Client func(c *gin.Context) KeyCase
+	// Default is the key case of other requests (defaults to the one set
+	// with SetKeyCase, or SnakeCase)
+	Default KeyCase
+}
+
+// keyCaseKey is the gin context key for the request's key case.
+const keyCaseKey = "ginapi.key_case"
+
+// NegotiateKeyCase returns middleware choosing the JSON key case of the
+// responses below it per client, so a partner whose SDK generator can't
+// handle snake_case gets camelCase from the same handlers and DTOs:
+//
+//	api.Use(response.NegotiateKeyCase(response.KeyCaseConfig{
+//	    Client: func(c *gin.Context) response.KeyCase {
+//	        if p, _ := auth.GetPrincipal(c); p.Tier == "partner-acme" {
+//	            return response.CamelCase
+//	        }
+//	        return ""
+//	    },
+//	}))
+//
+// Every key is converted, including those of maps, and the key order is
+// kept. Request bodies are not converted. Unknown key cases get a 400
+// invalid_param. The chosen case is echoed in the header, which is added
+// to Vary.
+func NegotiateKeyCase(cfg KeyCaseConfig) gin.HandlerFunc {
+	header := keyCaseHeader(cfg)
+	return func(c *gin.Context) {
+		kc := KeyCase(strings.ToLower(strings.TrimSpace(c.GetHeader(header))))
+		if kc == "" && cfg.Client != nil {
+			kc = cfg.Client(c)
+		}
+		if kc == "" {
+			kc = cfg.Default
+		}
+		if kc == "" {
+			kc = globalKeyCase()
+		}
+		if _, ok := keyCaseConverter(kc); !ok {
+			ErrorWithInfo(c, http.StatusBadRequest, unknownKeyCase(kc, header))
+			c.Abort()
+			return
+		}
+
+		c.Set(keyCaseKey, kc)
+		c.Request = c.Request.WithContext(WithKeyCase(c.Request.Context(), kc))
+		c.Header(header, string(kc))
+		AddVary(c.Writer.Header(), header)
+		c.Next()
+	}
+}
+
+// NegotiateKeyCaseHandler is the net/http equivalent of NegotiateKeyCase.
+// Client is not consulted, as it takes a gin context.
+func NegotiateKeyCaseHandler(cfg KeyCaseConfig) func(http.Handler) http.Handler {
+	header := keyCaseHeader(cfg)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			kc := KeyCase(strings.ToLower(strings.TrimSpace(r.Header.Get(header))))
+			if kc == "" {
+				kc = cfg.Default
+			}
+			if kc == "" {
+				kc = globalKeyCase()
+			}
+			if _, ok := keyCaseConverter(kc); !ok {
+				WriteError(w, r, http.StatusBadRequest, unknownKeyCase(kc, header))
+				return
+			}
+			w.Header().Set(header, string(kc))
+			AddVary(w.Header(), header)
+			next.ServeHTTP(w, r.WithContext(WithKeyCase(r.Context(), kc)))
+		})
+	}
+}
+
+// UseKeyCase returns middleware sending the responses of the routes below
+// it with key case kc, e.g. for a partner route group. It panics if kc
+// isn't registered.
+func UseKeyCase(kc KeyCase) gin.HandlerFunc {
+	if _, ok := keyCaseConverter(kc); !ok {
+		panic("response: unknown key case " + strconv.Quote(string(kc)))
+	}
+	return func(c *gin.Context) {
+		c.Set(keyCaseKey, kc)
+		c.Next()
+	}
+}
+
+// keyCaseHeader returns the negotiation header of cfg.
+func keyCaseHeader(cfg KeyCaseConfig) string {
+	if cfg.Header == "" {
+		return "Key-Case"
+	}
+	return cfg.Header
+}
+
+// unknownKeyCase is the error for a key case that isn't registered.
+func unknownKeyCase(kc KeyCase, header string) ErrorInfo {
+	return ErrorInfo{
+		Type:    ErrorTypeInvalidRequest,
+		Code:    ErrorCodeInvalidParam,
+		Message: "unknown key case " + strconv.Quote(string(kc)),
+		Param:   header,
+	}
+}
+
+// keyCaseContextKey is the request context key for the key case.
+type keyCaseContextKey struct{}
+
+// WithKeyCase returns a copy of ctx whose responses are sent with key case
+// kc. This is the net/http equivalent of UseKeyCase.
+func WithKeyCase(ctx context.Context, kc KeyCase) context.Context {
+	return context.WithValue(ctx, keyCaseContextKey{}, kc)
+}
+
+// KeyCaseFromContext returns the key case responses for ctx are sent
+// with.
+func KeyCaseFromContext(ctx context.Context) KeyCase {
+	if ctx != nil {
+		if kc, ok := ctx.Value(keyCaseContextKey{}).(KeyCase); ok {
+			return kc
+		}
+	}
+	return globalKeyCase()
+}
+
+// ginKeyCase returns the key case set by NegotiateKeyCase or UseKeyCase,
+// falling back to the request context.
+func ginKeyCase(c *gin.Context) KeyCase {
+	if v, ok := c.Get(keyCaseKey); ok {
+		if kc, ok := v.(KeyCase); ok {
+			return kc
+		}
+	}
+	if c.Request != nil {
+		return KeyCaseFromContext(c.Request.Context())
+	}
+	return globalKeyCase()
+}
+
+// globalKeyCase returns the key case set with SetKeyCase.
+func globalKeyCase() KeyCase {
+	if kc, ok := defaultKeyCase.Load().(KeyCase); ok {
+		return kc
+	}
+	return SnakeCase
+}
+
+// convertKeys converts the keys of an encoded body to o's key case.
+func (o output) convertKeys(body []byte) []byte {
+	if o.keyCase == "" || o.keyCase == SnakeCase {
+		return body
+	}
+	if convert, _ := keyCaseConverter(o.keyCase); convert != nil {
+		return convertKeys(body, convert)
+	}
+	return body
+}
+
+// convertKeys rewrites the object keys of an encoded JSON body with
+// convert, keeping everything else, including the key order, as is.
+func convertKeys(body []byte, convert func(string) string) []byte {
+	out := make([]byte, 0, len(body))
+	for i := 0; i < len(body); i++ {
+		if body[i] != '"' {
+			out = append(out, body[i])
+			continue
+		}
+		end := i + 1
+		for end < len(body) && body[end] != '"' {
+			if body[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(body) {
+			return append(out, body[i:]...)
+		}
+		next := end + 1
+		for next < len(body) && isJSONSpace(body[next]) {
+			next++
+		}
+		if next < len(body) && body[next] == ':' && bytes.IndexByte(body[i+1:end], '\\') < 0 {
+			out = append(out, '"')
+			out = append(out, convert(string(body[i+1:end]))...)
+			out = append(out, '"')
+		} else {
+			out = append(out, body[i:end+1]...)
+		}
+		i = end
+	}
+	return out
+}
+
+// isJSONSpace reports whether b is JSON whitespace.
+func isJSONSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// camelCaseKey converts a snake_case key to camelCase: "has_more" is
+// "hasMore" and "address_line_1" is "addressLine1". Leading underscores,
+// as in "_errors", are kept.
+func camelCaseKey(key string) string {
+	if strings.IndexByte(strings.TrimLeft(key, "_"), '_') < 0 {
+		return key
+	}
+	var b strings.Builder
+	b.Grow(len(key))
+	upper, leading := false, true
+	for i := 0; i < len(key); i++ {
+		ch := key[i]
+		switch {
+		case ch == '_' && leading:
+			b.WriteByte(ch)
+			continue
+		case ch == '_' && i+1 < len(key) && key[i+1] != '_':
+			upper = true
+			continue
+		case upper && 'a' <= ch && ch <= 'z':
+			ch -= 'a' - 'A'
+		}
+		leading, upper = false, false
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
@@ -0,0 +1,151 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+type keyCaseGallery struct {
+	Object     string            `json:"object"`
+	ID         string            `json:"id"`
+	PageCount  int               `json:"page_count"`
+	CoverURL   string            `json:"cover_url"`
+	AddressL1  string            `json:"address_line_1"`
+	Labels     map[string]string `json:"labels"`
+	TitleValue string            `json:"title"`
+}
+
+func TestNegotiateKeyCase(t *testing.T) {
+	router := gin.New()
+	router.Use(response.NegotiateKeyCase(response.KeyCaseConfig{
+		Client: func(c *gin.Context) response.KeyCase {
+			if c.Query("partner") == "acme" {
+				return response.CamelCase
+			}
+			return ""
+		},
+	}))
+	router.GET("/galleries/1", func(c *gin.Context) {
+		response.Object(c, keyCaseGallery{
+			Object:     "gallery",
+			ID:         "gal_1",
+			PageCount:  12,
+			CoverURL:   "https://cdn.example.com/a_b.jpg",
+			AddressL1:  "x",
+			Labels:     map[string]string{"is_new": "page_count"},
+			TitleValue: `quoted "page_count": value`,
+		})
+	})
+	router.GET("/galleries", func(c *gin.Context) {
+		response.ListResponse(c, []keyCaseGallery{{Object: "gallery", ID: "gal_1"}}, 1, 20, 0)
+	})
+
+	camel := `{"object":"gallery","id":"gal_1","pageCount":12,"coverUrl":"https://cdn.example.com/a_b.jpg","addressLine1":"x","labels":{"isNew":"page_count"},"title":"quoted \"page_count\": value"}`
+	snake := `{"object":"gallery","id":"gal_1","page_count":12,"cover_url":"https://cdn.example.com/a_b.jpg","address_line_1":"x","labels":{"is_new":"page_count"},"title":"quoted \"page_count\": value"}`
+
+	tests := []struct {
+		name       string
+		target     string
+		header     string
+		wantStatus int
+		wantBody   string
+		wantCase   string
+	}{
+		{"default", "/galleries/1", "", http.StatusOK, snake, "snake"},
+		{"header", "/galleries/1", "camel", http.StatusOK, camel, "camel"},
+		{"header is case-insensitive", "/galleries/1", "Camel", http.StatusOK, camel, "camel"},
+		{"client", "/galleries/1?partner=acme", "", http.StatusOK, camel, "camel"},
+		{"header wins over client", "/galleries/1?partner=acme", "snake", http.StatusOK, snake, "snake"},
+		{"list envelope", "/galleries", "camel", http.StatusOK, `"hasMore":false`, "camel"},
+		{"unknown", "/galleries/1", "kebab", http.StatusBadRequest, `"code":"invalid_param"`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("Key-Case", tt.header)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("expected body containing %s, got %s", tt.wantBody, w.Body.String())
+			}
+			if got := w.Header().Get("Key-Case"); got != tt.wantCase {
+				t.Errorf("expected Key-Case %q, got %q", tt.wantCase, got)
+			}
+			if tt.wantStatus == http.StatusOK && !strings.Contains(w.Header().Get("Vary"), "Key-Case") {
+				t.Errorf("expected Vary to include Key-Case, got %q", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestUseKeyCase(t *testing.T) {
+	response.RegisterKeyCase("upper", strings.ToUpper)
+
+	router := gin.New()
+	router.GET("/partner", response.UseKeyCase("upper"), func(c *gin.Context) {
+		response.Object(c, keyCaseGallery{Object: "gallery"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/partner", nil)
+	router.ServeHTTP(w, req)
+
+	if !strings.HasPrefix(w.Body.String(), `{"OBJECT":"gallery","ID":""`) {
+		t.Errorf("expected upper case keys, got %s", w.Body.String())
+	}
+}
+
+func TestUseKeyCaseUnknown(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for an unknown key case")
+		}
+	}()
+	response.UseKeyCase("kebab")
+}
+
+func TestNegotiateKeyCaseHandler(t *testing.T) {
+	handler := response.NegotiateKeyCaseHandler(response.KeyCaseConfig{Default: response.CamelCase})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			response.WriteObject(w, r, keyCaseGallery{Object: "gallery", PageCount: 3})
+		}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !strings.Contains(w.Body.String(), `"pageCount":3`) {
+		t.Errorf("expected camelCase keys, got %s", w.Body.String())
+	}
+}
+
+func TestSetKeyCase(t *testing.T) {
+	response.SetKeyCase(response.CamelCase)
+	t.Cleanup(func() { response.SetKeyCase(response.SnakeCase) })
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	response.ErrorWithInfo(c, http.StatusBadRequest, response.ErrorInfo{
+		Type:    response.ErrorTypeInvalidRequest,
+		Code:    response.ErrorCodeInvalidParam,
+		Message: "bad",
+		DocURL:  "https://docs.example.com/errors",
+	})
+
+	if !strings.Contains(w.Body.String(), `"docUrl":`) {
+		t.Errorf("expected camelCase error keys, got %s", w.Body.String())
+	}
+}
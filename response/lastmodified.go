@@ -0,0 +1,91 @@
+package response
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotModified sends 304 Not Modified with no body.
+func NotModified(c *gin.Context) {
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+}
+
+// ObjectWithLastModified sends obj like Object, with a Last-Modified header
+// for t. If the request's If-Modified-Since is at or after t, it sends 304
+// Not Modified with no body instead. Use it for resources whose timestamp
+// is cheaper to get than an ETag hash; a zero t sends obj like Object.
+//
+//	gallery, err := repo.Get(ctx, id)
+//	...
+//	response.ObjectWithLastModified(c, gallery, gallery.UpdatedAt)
+//
+// To skip loading the object as well, check ModifiedSince first.
+func ObjectWithLastModified(c *gin.Context, obj any, t time.Time) {
+	ginOutput(c).lastModified(http.StatusOK, obj, t)
+}
+
+// ModifiedSince reports whether a resource last modified at t changed
+// since the copy the client has, per its If-Modified-Since header. It is
+// true when the request has no usable If-Modified-Since: not GET or HEAD,
+// with an If-None-Match (which takes precedence), or with an invalid date.
+//
+//	updatedAt, err := repo.GalleryUpdatedAt(ctx, id)
+//	...
+//	if !response.ModifiedSince(c, updatedAt) {
+//	    response.NotModified(c)
+//	    return
+//	}
+func ModifiedSince(c *gin.Context, t time.Time) bool {
+	return modifiedSince(c.Request, t)
+}
+
+// WriteNotModified is the net/http equivalent of NotModified.
+func WriteNotModified(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// WriteObjectWithLastModified is the net/http equivalent of
+// ObjectWithLastModified.
+func WriteObjectWithLastModified(w http.ResponseWriter, r *http.Request, obj any, t time.Time) {
+	httpOutput(w, r).lastModified(http.StatusOK, obj, t)
+}
+
+// lastModified writes v with a Last-Modified header, or 304 if the client
+// has it, then runs the response hooks with the status sent.
+func (o output) lastModified(status int, v any, t time.Time) {
+	if t.IsZero() {
+		renderObject(o, status, v)
+		return
+	}
+	o.w.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+	if o.r != nil && !modifiedSince(o.r, t) {
+		o.w.WriteHeader(http.StatusNotModified)
+		if f, ok := o.w.(interface{ WriteHeaderNow() }); ok {
+			f.WriteHeaderNow()
+		}
+		o.notifyObject(http.StatusNotModified, v)
+		return
+	}
+	renderObject(o, status, v)
+}
+
+// modifiedSince is ModifiedSince for r. HTTP dates have whole seconds, so
+// t is truncated to the second before comparing; otherwise a resource
+// modified at 12:00:00.5 would never match the "12:00:00" it was sent with.
+func modifiedSince(r *http.Request, t time.Time) bool {
+	if r == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) || r.Header.Get("If-None-Match") != "" {
+		return true
+	}
+	header := r.Header.Get("If-Modified-Since")
+	if header == "" {
+		return true
+	}
+	since, err := http.ParseTime(header)
+	if err != nil {
+		return true
+	}
+	return t.Truncate(time.Second).After(since)
+}
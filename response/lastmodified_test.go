@@ -0,0 +1,85 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestObjectWithLastModified(t *testing.T) {
+	updated := time.Date(2024, 3, 5, 12, 0, 0, 500_000_000, time.FixedZone("JST", 9*60*60))
+	router := gin.New()
+	router.GET("/galleries/1", func(c *gin.Context) {
+		response.ObjectWithLastModified(c, gin.H{"object": "gallery", "id": "1"}, updated)
+	})
+	router.POST("/galleries/1", func(c *gin.Context) {
+		response.ObjectWithLastModified(c, gin.H{"object": "gallery", "id": "1"}, updated)
+	})
+
+	lastModified := "Tue, 05 Mar 2024 03:00:00 GMT"
+	tests := []struct {
+		name        string
+		method      string
+		since       string
+		noneMatch   string
+		wantStatus  int
+		wantHasBody bool
+	}{
+		{"no header", http.MethodGet, "", "", http.StatusOK, true},
+		{"same second", http.MethodGet, lastModified, "", http.StatusNotModified, false},
+		{"later", http.MethodGet, "Tue, 05 Mar 2024 04:00:00 GMT", "", http.StatusNotModified, false},
+		{"earlier", http.MethodGet, "Tue, 05 Mar 2024 02:59:59 GMT", "", http.StatusOK, true},
+		{"invalid date", http.MethodGet, "yesterday", "", http.StatusOK, true},
+		{"If-None-Match takes precedence", http.MethodGet, lastModified, `"abc"`, http.StatusOK, true},
+		{"not GET", http.MethodPost, lastModified, "", http.StatusOK, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/galleries/1", nil)
+			if tt.since != "" {
+				req.Header.Set("If-Modified-Since", tt.since)
+			}
+			if tt.noneMatch != "" {
+				req.Header.Set("If-None-Match", tt.noneMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("Last-Modified"); got != lastModified {
+				t.Errorf("expected Last-Modified %s, got %s", lastModified, got)
+			}
+			if (w.Body.Len() > 0) != tt.wantHasBody {
+				t.Errorf("expected body %v, got %q", tt.wantHasBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestModifiedSince(t *testing.T) {
+	updated := time.Date(2024, 3, 5, 3, 0, 0, 0, time.UTC)
+	router := gin.New()
+	router.GET("/galleries/1", func(c *gin.Context) {
+		if !response.ModifiedSince(c, updated) {
+			response.NotModified(c)
+			return
+		}
+		c.String(http.StatusOK, "loaded")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/galleries/1", nil)
+	req.Header.Set("If-Modified-Since", updated.Format(http.TimeFormat))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected an empty 304, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,203 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LeakPattern detects one kind of sensitive value in a response field.
+type LeakPattern struct {
+	// Name identifies the pattern in reports, e.g. "email"
+	Name string
+	// Match reports whether the value of field key is sensitive. Strings
+	// and numbers (as json.Number) are passed; objects and arrays are
+	// walked instead.
+	Match func(key string, value any) bool
+}
+
+// Leak is a sensitive value found in a response.
+type Leak struct {
+	// Pattern is the Name of the matching LeakPattern
+	Pattern string
+	// Field is the path of the value within the object, e.g. "author.email"
+	// or "tags[2]"
+	Field string
+}
+
+// String formats a leak for messages.
+func (l Leak) String() string {
+	return fmt.Sprintf("%s in %s", l.Pattern, l.Field)
+}
+
+// LeakScanConfig configures ScanLeaks.
+type LeakScanConfig struct {
+	// Patterns to look for (defaults to DefaultLeakPatterns)
+	Patterns []LeakPattern
+	// Allow, if set, exempts expected values, e.g. the email on the
+	// caller's own profile
+	Allow func(r *http.Request, leak Leak) bool
+	// OnLeak is called for each leak (defaults to logging a warning with
+	// slog.Default). In tests, pass a function calling t.Errorf.
+	OnLeak func(r *http.Request, leak Leak)
+	// Fail panics after reporting the leaks of an object, failing the
+	// request (500 under Recovery) so leaks can't go unnoticed
+	Fail bool
+}
+
+// ScanLeaks returns an Interceptor that scans outgoing objects for values
+// that look sensitive: email addresses, credentials, card numbers, and
+// unprefixed internal IDs. It catches internal fields exposed by accident
+// (a struct gaining a field without a json:"-" tag) before they ship.
+// Scanning every response is slow; register it in development and tests
+// only:
+//
+//	if cfg.Env != "production" {
+//	    api.Use(response.UseInterceptors(response.ScanLeaks(response.LeakScanConfig{
+//	        Allow: func(r *http.Request, leak response.Leak) bool {
+//	            return r.URL.Path == "/api/me" && leak.Field == "email"
+//	        },
+//	        Fail: cfg.Env == "test",
+//	    })))
+//	}
+//
+// Like every Interceptor it sees objects after audience redaction, so
+// fields redacted for the caller are not reported.
+func ScanLeaks(cfg LeakScanConfig) Interceptor {
+	if cfg.Patterns == nil {
+		cfg.Patterns = DefaultLeakPatterns()
+	}
+	if cfg.OnLeak == nil {
+		cfg.OnLeak = func(r *http.Request, leak Leak) {
+			path := ""
+			if r != nil {
+				path = r.URL.Path
+			}
+			slog.Warn("response: possible sensitive data leak", "pattern", leak.Pattern, "field", leak.Field, "path", path)
+		}
+	}
+
+	return func(r *http.Request, obj map[string]any) {
+		var leaks []Leak
+		scanLeaks(cfg.Patterns, "", "", obj, &leaks)
+		var reported []string
+		for _, leak := range leaks {
+			if cfg.Allow != nil && cfg.Allow(r, leak) {
+				continue
+			}
+			cfg.OnLeak(r, leak)
+			reported = append(reported, leak.String())
+		}
+		if cfg.Fail && len(reported) > 0 {
+			panic("response: sensitive data in response: " + strings.Join(reported, ", "))
+		}
+	}
+}
+
+// scanLeaks walks v, the value of field key at path, collecting leaks.
+func scanLeaks(patterns []LeakPattern, path, key string, v any, leaks *[]Leak) {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // report in a stable order
+		for _, k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			scanLeaks(patterns, p, k, v[k], leaks)
+		}
+	case []any:
+		for i, val := range v {
+			scanLeaks(patterns, path+"["+strconv.Itoa(i)+"]", key, val, leaks)
+		}
+	case string, json.Number:
+		for _, pattern := range patterns {
+			if pattern.Match(key, v) {
+				*leaks = append(*leaks, Leak{Pattern: pattern.Name, Field: path})
+			}
+		}
+	}
+}
+
+// DefaultLeakPatterns returns the built-in patterns: email, jwt,
+// secret_key (sk_live_... style and AWS access keys), card_number
+// (Luhn-valid 13-19 digit numbers), and unprefixed_id (id and *_id fields
+// whose value isn't a prefixed ID like "gal_1").
+func DefaultLeakPatterns() []LeakPattern {
+	return []LeakPattern{
+		LeakRegexp("email", regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)),
+		LeakRegexp("jwt", regexp.MustCompile(`eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]+`)),
+		LeakRegexp("secret_key", regexp.MustCompile(`\b(?:(?:sk|rk)_(?:live|test)_[A-Za-z0-9]{16,}|AKIA[0-9A-Z]{16})\b`)),
+		{Name: "card_number", Match: matchCardNumber},
+		{Name: "unprefixed_id", Match: matchUnprefixedID},
+	}
+}
+
+// LeakRegexp returns a pattern matching string values containing re.
+func LeakRegexp(name string, re *regexp.Regexp) LeakPattern {
+	return LeakPattern{Name: name, Match: func(_ string, value any) bool {
+		s, ok := value.(string)
+		return ok && re.MatchString(s)
+	}}
+}
+
+// cardCandidate finds digit runs that may be card numbers, allowing the
+// usual space and dash separators.
+var cardCandidate = regexp.MustCompile(`\d(?:[ -]?\d){12,18}`)
+
+// matchCardNumber reports Luhn-valid 13-19 digit numbers in strings.
+func matchCardNumber(_ string, value any) bool {
+	s, ok := value.(string)
+	if !ok {
+		return false
+	}
+	for _, m := range cardCandidate.FindAllString(s, -1) {
+		if luhn(strings.NewReplacer(" ", "", "-", "").Replace(m)) {
+			return true
+		}
+	}
+	return false
+}
+
+// luhn reports whether digits passes the Luhn checksum.
+func luhn(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// prefixedID matches the public ID format, e.g. "gal_1" or "usr_2fK9".
+var prefixedID = regexp.MustCompile(`^[a-z][a-z0-9]*_[A-Za-z0-9]+$`)
+
+// matchUnprefixedID reports id and *_id fields holding raw database IDs.
+func matchUnprefixedID(key string, value any) bool {
+	if key != "id" && !strings.HasSuffix(key, "_id") {
+		return false
+	}
+	switch v := value.(type) {
+	case json.Number:
+		return true
+	case string:
+		return v != "" && !prefixedID.MatchString(v)
+	}
+	return false
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/pagination"
 )
 
 // List is a Stripe-style list response with offset/limit pagination.
@@ -14,6 +16,11 @@ type List[T any] struct {
 	Limit   int    `json:"limit"`    // Max items requested
 	Offset  int    `json:"offset"`   // Items skipped
 	HasMore bool   `json:"has_more"` // More items available
+
+	Facets          Facets `json:"facets,omitempty"`            // Filter counts for search UIs (see ListWithFacets)
+	SyncToken       string `json:"sync_token,omitempty"`        // Pass as ?updated_since= to fetch only later changes (see SyncListResponse)
+	TotalIsEstimate bool   `json:"total_is_estimate,omitempty"` // Total is an estimate, not an exact count (see ListWithTotal)
+	Degraded        bool   `json:"degraded,omitempty"`          // Some items failed to hydrate (see HydratedList)
 }
 
 // NewList creates a List response with has_more calculated automatically.
@@ -36,6 +43,104 @@ func NewList[T any](data []T, total int64, limit, offset int) List[T] {
 }
 
 // ListResponse sends a Stripe-style list response.
+// See UsePaginationMode for header-based pagination.
 func ListResponse[T any](c *gin.Context, data []T, total int64, limit, offset int) {
-	c.JSON(http.StatusOK, NewList(data, total, limit, offset))
+	sendList(c, NewList(data, total, limit, offset))
+}
+
+// ListWithTotal sends a list response whose total came from a
+// pagination.Counter. Estimated totals set total_is_estimate (and the
+// X-Total-Is-Estimate header), and has_more is then based on whether the
+// page is full, as the estimate may be off either way.
+func ListWithTotal[T any](c *gin.Context, data []T, total pagination.Total, params pagination.Params) {
+	sendList(c, newListWithTotal(data, total, params))
+}
+
+// ListWithCounter counts the list with counter and sends it like
+// ListWithTotal, so an endpoint can move between exact, cached, and
+// estimated totals without changing its response code. Count errors are
+// reported (see SetReporter) and answered with a 500.
+//
+//	response.ListWithCounter(c, galleries, pagination.CachedCount(nil, "galleries:count", time.Minute,
+//	    pagination.CountFunc(repo.CountGalleries)), p)
+func ListWithCounter[T any](c *gin.Context, data []T, counter pagination.Counter, params pagination.Params) {
+	total, err := counter.Count(c.Request.Context())
+	if err != nil {
+		ReportError(c, c.Request, c.FullPath(), err)
+		ginOutput(c).error(http.StatusInternalServerError, countFailed)
+		return
+	}
+	ListWithTotal(c, data, total, params)
+}
+
+// countFailed is the error sent when a list can't be counted.
+var countFailed = ErrorInfo{Type: ErrorTypeAPI, Code: ErrorCodeInternal, Message: "internal error"}
+
+// newListWithTotal builds the list for ListWithTotal and WriteListWithTotal.
+func newListWithTotal[T any](data []T, total pagination.Total, params pagination.Params) List[T] {
+	list := NewList(data, total.Value, params.Limit, params.Offset)
+	if total.Estimate {
+		list.TotalIsEstimate = true
+		list.HasMore = params.Limit > 0 && len(data) >= params.Limit
+	}
+	return list
+}
+
+// SyncListResponse sends a list response for an incremental sync request
+// (see pagination.BindUpdatedSince), with the token the client passes as
+// ?updated_since= next time. When nothing changed, use NotModifiedList.
+//
+//	since, err := pagination.BindUpdatedSince(c)
+//	...
+//	changed, total, syncToken, err := repo.ChangedSince(ctx, since, p)
+//	if total == 0 && !since.IsZero() {
+//	    response.NotModifiedList(c)
+//	    return
+//	}
+//	response.SyncListResponse(c, changed, total, p.Limit, p.Offset, syncToken)
+func SyncListResponse[T any](c *gin.Context, data []T, total int64, limit, offset int, syncToken string) {
+	list := NewList(data, total, limit, offset)
+	list.SyncToken = syncToken
+	sendList(c, list)
+}
+
+// NotModifiedList sends 304 Not Modified with no body, telling a syncing
+// client nothing changed since its updated_since; it keeps its current token.
+func NotModifiedList(c *gin.Context) {
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+}
+
+// sendList writes list according to the request's pagination mode.
+func sendList[T any](c *gin.Context, list List[T]) {
+	writeList(ginOutput(c), http.StatusOK, list)
+}
+
+// writeList is the core behind sendList and WriteList.
+func writeList[T any](o output, status int, list List[T]) {
+	checkShape(list)
+	if o.sizeLimit.maxBytes > 0 && o.sizeLimit.policy == SizeTruncate {
+		list = truncateList(o, list)
+	}
+	if o.mode == PaginationHeaders || o.mode == PaginationBoth {
+		WritePaginationHeaders(o.w, o.r, list.Total, list.Limit, list.Offset)
+	}
+	if list.TotalIsEstimate {
+		o.w.Header().Set("X-Total-Is-Estimate", "true")
+	}
+	o.json(status, listPayload(o.mode, list))
+}
+
+// listPayload returns what a list is encoded as in the pagination mode:
+// the bare items in PaginationHeaders mode, otherwise the envelope.
+func listPayload[T any](mode PaginationMode, list List[T]) any {
+	if mode == PaginationHeaders {
+		return list.Data
+	}
+	return list
+}
+
+// totalCount implements totaler.
+func (l List[T]) totalCount() int64 {
+	return l.Total
 }
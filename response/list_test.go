@@ -1,13 +1,17 @@
 package response_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/doujins-org/ginapi/pagination"
 	"github.com/doujins-org/ginapi/response"
 )
 
@@ -141,3 +145,108 @@ func TestListResponse(t *testing.T) {
 		t.Error("expected has_more to be true")
 	}
 }
+
+func TestSyncListResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	response.SyncListResponse(c, []string{"gal_1"}, 1, 20, 0, "tok_2")
+
+	want := `{"object":"list","data":["gal_1"],"total":1,"limit":20,"offset":0,"has_more":false,"sync_token":"tok_2"}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestNotModifiedList(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	response.NotModifiedList(c)
+
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected empty 304, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestNewListAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector adds allocations")
+	}
+	data := []string{"a", "b", "c"}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = response.NewList(data, 10, 3, 0)
+	})
+	if allocs != 0 {
+		t.Errorf("expected 0 allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkNewList(b *testing.B) {
+	data := []string{"a", "b", "c"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = response.NewList(data, 10, 3, 0)
+	}
+}
+
+func TestListWithTotal(t *testing.T) {
+	tests := []struct {
+		name       string
+		total      pagination.Total
+		items      int
+		want       string
+		wantHeader string
+	}{
+		{"exact", pagination.Total{Value: 3}, 2, `"total":3,"limit":2,"offset":0,"has_more":true}`, ""},
+		{"exact last page", pagination.Total{Value: 2}, 2, `"total":2,"limit":2,"offset":0,"has_more":false}`, ""},
+		{"estimate under page", pagination.Total{Value: 1, Estimate: true}, 2, `"total":1,"limit":2,"offset":0,"has_more":true,"total_is_estimate":true}`, "true"},
+		{"estimate partial page", pagination.Total{Value: 90, Estimate: true}, 1, `"total":90,"limit":2,"offset":0,"has_more":false,"total_is_estimate":true}`, "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/galleries", func(c *gin.Context) {
+				response.ListWithTotal(c, make([]int, tt.items), tt.total, pagination.Params{Limit: 2})
+			})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/galleries", nil))
+
+			if !strings.HasSuffix(w.Body.String(), tt.want) {
+				t.Errorf("expected body ending in %s, got %s", tt.want, w.Body.String())
+			}
+			if got := w.Header().Get("X-Total-Is-Estimate"); got != tt.wantHeader {
+				t.Errorf("expected X-Total-Is-Estimate %q, got %q", tt.wantHeader, got)
+			}
+		})
+	}
+}
+
+func TestListWithCounter(t *testing.T) {
+	tests := []struct {
+		name       string
+		counter    pagination.Counter
+		wantStatus int
+		want       string
+	}{
+		{"exact", pagination.CountFunc(func(context.Context) (int64, error) { return 5, nil }), http.StatusOK, `"total":5,`},
+		{"estimate", pagination.EstimateFunc(func(context.Context) (int64, error) { return 5, nil }), http.StatusOK, `"total_is_estimate":true`},
+		{"error", pagination.CountFunc(func(context.Context) (int64, error) { return 0, errors.New("db down") }), http.StatusInternalServerError, `"message":"internal error"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/galleries", func(c *gin.Context) {
+				response.ListWithCounter(c, []int{1}, tt.counter, pagination.Params{Limit: 2})
+			})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/galleries", nil))
+
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("expected %d containing %s, got %d %s", tt.wantStatus, tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
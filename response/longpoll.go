@@ -0,0 +1,111 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LongPollInterval is how often LongPoll calls its check function.
+const LongPollInterval = 500 * time.Millisecond
+
+// PollFunc reports whether new data is available. Return ok false to keep
+// waiting; a non-nil error ends the poll with a 500.
+type PollFunc func(ctx context.Context) (data any, ok bool, err error)
+
+// LongPoll calls check immediately and then every LongPollInterval until it
+// returns data, sending 200 with the data, or until timeout, sending 204 No
+// Content. If the client disconnects first, nothing is written. Errors from
+// check are reported (see SetReporter) and sent as a generic 500.
+//
+//	response.LongPoll(c, 30*time.Second, func(ctx context.Context) (any, bool, error) {
+//	    notes, err := repo.NotificationsSince(ctx, userID, since)
+//	    return notes, len(notes) > 0, err
+//	})
+func LongPoll(c *gin.Context, timeout time.Duration, check PollFunc) {
+	ginOutput(c).longPoll(c.Request.Context(), timeout, check)
+}
+
+// LongPollChan is LongPoll for data arriving on a channel, e.g. from a
+// pub/sub subscription. A closed channel ends the poll like a timeout.
+func LongPollChan[T any](c *gin.Context, timeout time.Duration, ch <-chan T) {
+	ginOutput(c).longPoll(c.Request.Context(), timeout, chanPoll(ch))
+}
+
+// WriteLongPoll is the net/http equivalent of LongPoll.
+func WriteLongPoll(w http.ResponseWriter, r *http.Request, timeout time.Duration, check PollFunc) {
+	httpOutput(w, r).longPoll(r.Context(), timeout, check)
+}
+
+// WriteLongPollChan is the net/http equivalent of LongPollChan.
+func WriteLongPollChan[T any](w http.ResponseWriter, r *http.Request, timeout time.Duration, ch <-chan T) {
+	httpOutput(w, r).longPoll(r.Context(), timeout, chanPoll(ch))
+}
+
+// chanClosed is the data chanPoll returns for a closed channel.
+type chanClosed struct{}
+
+// chanPoll adapts a channel to a PollFunc that waits for the next value.
+func chanPoll[T any](ch <-chan T) PollFunc {
+	return func(ctx context.Context) (any, bool, error) {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return chanClosed{}, true, nil
+			}
+			return v, true, nil
+		case <-ctx.Done():
+			return nil, false, nil
+		}
+	}
+}
+
+// longPoll runs the poll loop behind LongPoll and LongPollChan.
+func (o output) longPoll(ctx context.Context, timeout time.Duration, check PollFunc) {
+	pollCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(LongPollInterval)
+	defer ticker.Stop()
+
+	for {
+		data, ok, err := check(pollCtx)
+		if err != nil && ctx.Err() == nil && pollCtx.Err() == nil {
+			ReportError(o.ctx, o.r, "", err)
+			o.error(http.StatusInternalServerError, ErrorInfo{
+				Type:    ErrorTypeAPI,
+				Code:    ErrorCodeInternal,
+				Message: "internal error",
+			})
+			return
+		}
+		if ok && err == nil {
+			if _, closed := data.(chanClosed); closed {
+				o.noContent()
+				return
+			}
+			o.json(http.StatusOK, data)
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-pollCtx.Done():
+			if ctx.Err() != nil {
+				return // client went away
+			}
+			o.noContent()
+			return
+		}
+	}
+}
+
+// noContent sends 204 No Content.
+func (o output) noContent() {
+	o.w.WriteHeader(http.StatusNoContent)
+	if f, ok := o.w.(interface{ WriteHeaderNow() }); ok {
+		f.WriteHeaderNow()
+	}
+}
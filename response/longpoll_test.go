@@ -0,0 +1,118 @@
+package response_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestLongPoll(t *testing.T) {
+	tests := []struct {
+		name       string
+		check      func(calls int) (any, bool, error)
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "immediate data",
+			check:      func(int) (any, bool, error) { return gin.H{"object": "notification", "id": "n_1"}, true, nil },
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":"n_1","object":"notification"}`,
+		},
+		{
+			name: "data on second check",
+			check: func(calls int) (any, bool, error) {
+				return gin.H{"count": calls}, calls == 2, nil
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"count":2}`,
+		},
+		{
+			name:       "timeout",
+			check:      func(int) (any, bool, error) { return nil, false, nil },
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "error",
+			check:      func(int) (any, bool, error) { return nil, false, errors.New("db down") },
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"object":"error","error":{"type":"api","code":"internal","message":"internal error"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/poll", func(c *gin.Context) {
+				calls := 0
+				response.LongPoll(c, 700*time.Millisecond, func(ctx context.Context) (any, bool, error) {
+					calls++
+					return tt.check(calls)
+				})
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/poll", nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("expected %s, got %s", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestLongPollChan(t *testing.T) {
+	ch := make(chan string, 1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ch <- "ready"
+	}()
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/poll", nil)
+	response.WriteLongPollChan(w, r, time.Second, ch)
+
+	if w.Code != http.StatusOK || w.Body.String() != `"ready"` {
+		t.Errorf("expected 200 \"ready\", got %d %s", w.Code, w.Body.String())
+	}
+
+	closed := make(chan string)
+	close(closed)
+	w = httptest.NewRecorder()
+	response.WriteLongPollChan(w, r, time.Second, closed)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204 for a closed channel, got %d", w.Code)
+	}
+}
+
+func TestLongPollClientDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r, _ := http.NewRequestWithContext(ctx, "GET", "/poll", nil)
+	w := httptest.NewRecorder()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	response.WriteLongPollChan(w, r, time.Minute, make(chan int))
+
+	if time.Since(start) > time.Second {
+		t.Errorf("expected the poll to end when the client disconnected")
+	}
+	if w.Body.Len() != 0 || w.Code != http.StatusOK {
+		// httptest.ResponseRecorder reports 200 when nothing was written
+		t.Errorf("expected nothing written, got %d %s", w.Code, w.Body.String())
+	}
+}
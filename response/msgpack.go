@@ -0,0 +1,68 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/ugorji/go/codec"
+)
+
+// MessagePack media types. Clients may use either.
+const (
+	MsgpackMediaType       = "application/msgpack"
+	MsgpackMediaTypeLegacy = "application/x-msgpack"
+)
+
+// msgpackHandle encodes with the current spec (str8, bin types) and sorted
+// map keys so output is deterministic.
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{WriteExt: true}
+	h.Canonical = true
+	return h
+}()
+
+// wantsMsgpack reports whether the client negotiated MessagePack over JSON.
+func (o output) wantsMsgpack() (string, bool) {
+	mediaType := o.negotiate("application/json", MsgpackMediaType, MsgpackMediaTypeLegacy)
+	return mediaType, mediaType != "application/json"
+}
+
+// jsonToMsgpack re-encodes a JSON body as MessagePack. Going through the JSON
+// form keeps field names, omitempty, custom marshalers, and audience
+// redaction identical between the two encodings.
+func jsonToMsgpack(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, msgpackHandle).Encode(msgpackValue(v)); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// msgpackValue converts json.Numbers to int64 or float64 so integers are
+// encoded as msgpack integers.
+func msgpackValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, val := range v {
+			v[k] = msgpackValue(val)
+		}
+	case []any:
+		for i, val := range v {
+			v[i] = msgpackValue(val)
+		}
+	}
+	return v
+}
@@ -0,0 +1,56 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestMsgpackResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/galleries", nil)
+	c.Request.Header.Set("Accept", "application/msgpack, application/json;q=0.9")
+
+	items := []*redactGallery{{Object: "gallery", ID: "gal_1", SourceURL: "https://source"}}
+	response.ListResponse(c, items, 41, 20, 0)
+
+	if got := w.Header().Get("Content-Type"); got != response.MsgpackMediaType {
+		t.Fatalf("expected Content-Type %s, got %s", response.MsgpackMediaType, got)
+	}
+
+	var decoded map[string]any
+	h := &codec.MsgpackHandle{}
+	h.RawToString = true
+	if err := codec.NewDecoderBytes(w.Body.Bytes(), h).Decode(&decoded); err != nil {
+		t.Fatalf("expected msgpack body, got error %v", err)
+	}
+	if decoded["total"] != int64(41) || decoded["has_more"] != true {
+		t.Errorf("unexpected list metadata %v", decoded)
+	}
+	data, _ := decoded["data"].([]any)
+	if len(data) != 1 {
+		t.Fatalf("expected 1 item, got %v", decoded["data"])
+	}
+	if _, leaked := data[0].(map[any]any)["source_url"]; leaked {
+		t.Error("expected audience-restricted field to be redacted")
+	}
+}
+
+func TestMsgpackNotNegotiated(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/galleries/gal_1", nil)
+	c.Request.Header.Set("Accept", "application/json, application/msgpack;q=0.5")
+
+	response.Object(c, map[string]any{"object": "gallery", "id": "gal_1"})
+
+	if got := w.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("expected JSON, got %s", got)
+	}
+}
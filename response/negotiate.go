@@ -0,0 +1,89 @@
+package response
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// negotiate picks the response media type from offers and declares Vary:
+// Accept, since the choice depends on it.
+func (o output) negotiate(offers ...string) string {
+	AddVary(o.w.Header(), "Accept")
+	return negotiate(o.r, offers...)
+}
+
+// negotiate returns the offered media type the client prefers per its Accept
+// header, honoring q-values and wildcards. Without an Accept header, a request
+// Content-Type matching an offer wins (RPC clients expect replies in the
+// encoding they sent). Otherwise the first offer is the default.
+func negotiate(r *http.Request, offers ...string) string {
+	best, _ := Acceptable(r, offers...)
+	return best
+}
+
+// Acceptable returns the offered media type the client prefers, like the
+// response helpers' own negotiation. ok is false if the request's Accept
+// header rules out every offer, in which case the first offer is returned;
+// respond with NotAcceptable to reject such requests instead.
+func Acceptable(r *http.Request, offers ...string) (mediaType string, ok bool) {
+	if r == nil {
+		return offers[0], true
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		if ct, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil {
+			for _, offer := range offers {
+				if ct == offer {
+					return offer, true
+				}
+			}
+		}
+		return offers[0], true
+	}
+
+	best, bestQ, bestSpecificity := offers[0], -1.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		for _, offer := range offers {
+			specificity := mediaTypeMatch(mediaType, offer)
+			if specificity < 0 {
+				continue
+			}
+			if q > bestQ || (q == bestQ && specificity > bestSpecificity) {
+				best, bestQ, bestSpecificity = offer, q, specificity
+			}
+			break
+		}
+	}
+	return best, bestQ > 0
+}
+
+// mediaTypeMatch reports how specifically pattern (which may contain
+// wildcards) matches mediaType: 2 exact, 1 "type/*", 0 "*/*", -1 no match.
+func mediaTypeMatch(pattern, mediaType string) int {
+	switch {
+	case pattern == mediaType:
+		return 2
+	case pattern == "*/*":
+		return 0
+	case strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")):
+		return 1
+	}
+	return -1
+}
@@ -0,0 +1,113 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Error returns the code (or type) and message, and the param if set.
+func (e ErrorInfo) Error() string {
+	s := e.Code
+	if s == "" {
+		s = e.Type
+	}
+	if e.Message != "" {
+		s += ": " + e.Message
+	}
+	if e.Param != "" {
+		s += " (param " + e.Param + ")"
+	}
+	return s
+}
+
+// Is reports whether target is an ErrorInfo whose non-empty Type and Code
+// match e, so errors.Is(err, ErrorInfo{Code: ErrorCodeTokenExpired}) checks
+// for a code regardless of message.
+func (e ErrorInfo) Is(target error) bool {
+	t, ok := target.(ErrorInfo)
+	if !ok || (t.Type == "" && t.Code == "") {
+		return false
+	}
+	return (t.Type == "" || t.Type == e.Type) && (t.Code == "" || t.Code == e.Code)
+}
+
+// HTTPError is an error response received from a ginapi service. Error
+// cannot implement the error interface itself, as its envelope field is
+// named Error; ErrorInfo does, and HTTPError adds the status, so Go clients
+// handle failures with the same types and codes the server sends:
+//
+//	resp, err := http.Get(galleriesURL)
+//	...
+//	defer resp.Body.Close()
+//	if err := response.FromHTTPResponse(resp); err != nil {
+//	    if errors.Is(err, response.ErrorInfo{Code: response.ErrorCodeResourceNotFound}) {
+//	        ...
+//	    }
+//	    return err
+//	}
+type HTTPError struct {
+	StatusCode int
+	ErrorInfo
+}
+
+// Unwrap returns the ErrorInfo, for errors.As.
+func (e *HTTPError) Unwrap() error {
+	return e.ErrorInfo
+}
+
+// ErrNotErrorEnvelope is returned by ParseError for a body that is not an
+// error envelope.
+var ErrNotErrorEnvelope = errors.New("response: not an error envelope")
+
+// ParseError decodes an error response body: the standard envelope, or a
+// JSON:API errors document (whose first error is used).
+func ParseError(data []byte) (Error, error) {
+	var v struct {
+		Object string          `json:"object"`
+		Error  *ErrorInfo      `json:"error"`
+		Errors []jsonAPIError  `json:"errors"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return Error{}, err
+	}
+
+	switch {
+	case v.Object == "error" && v.Error != nil:
+		return Error{Object: "error", Error: *v.Error}, nil
+	case len(v.Errors) > 0:
+		e := v.Errors[0]
+		info := ErrorInfo{Type: e.Title, Code: e.Code, Message: e.Detail, DocURL: e.Links["about"], Details: e.Meta}
+		info.Param, _ = e.Source["parameter"].(string)
+		info.Pointer, _ = e.Source["pointer"].(string)
+		if info.Type == "" {
+			status, _ := strconv.Atoi(e.Status)
+			info.Type = ErrorTypeForStatus(status)
+		}
+		return Error{Object: "error", Error: info}, nil
+	}
+	return Error{}, ErrNotErrorEnvelope
+}
+
+// FromHTTPResponse returns nil for a response with a status below 400, and
+// otherwise reads the body and returns an *HTTPError. A body that is not an
+// error envelope (e.g. a proxy's HTML error page) yields the type for the
+// status and the status text as the message. The caller still closes the body.
+func FromHTTPResponse(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	env, err := ParseError(data)
+	if err != nil {
+		env.Error = ErrorInfo{
+			Type:    ErrorTypeForStatus(resp.StatusCode),
+			Message: http.StatusText(resp.StatusCode),
+		}
+	}
+	return &HTTPError{StatusCode: resp.StatusCode, ErrorInfo: env.Error}
+}
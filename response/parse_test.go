@@ -0,0 +1,127 @@
+package response_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestErrorInfoError(t *testing.T) {
+	tests := []struct {
+		info response.ErrorInfo
+		want string
+	}{
+		{response.ErrorInfo{Type: "not_found", Code: "resource_not_found", Message: "gallery not found"}, "resource_not_found: gallery not found"},
+		{response.ErrorInfo{Type: "invalid_request", Message: "bad limit", Param: "limit"}, "invalid_request: bad limit (param limit)"},
+	}
+	for _, tt := range tests {
+		if got := tt.info.Error(); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestErrorInfoIs(t *testing.T) {
+	err := error(&response.HTTPError{StatusCode: 401, ErrorInfo: response.ErrorInfo{
+		Type: response.ErrorTypeAuthentication, Code: response.ErrorCodeTokenExpired, Message: "token expired",
+	}})
+
+	tests := []struct {
+		name   string
+		target response.ErrorInfo
+		want   bool
+	}{
+		{"code", response.ErrorInfo{Code: response.ErrorCodeTokenExpired}, true},
+		{"type", response.ErrorInfo{Type: response.ErrorTypeAuthentication}, true},
+		{"other code", response.ErrorInfo{Code: response.ErrorCodeInvalidToken}, false},
+		{"empty", response.ErrorInfo{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(err, tt.target); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	var info response.ErrorInfo
+	if !errors.As(err, &info) || info.Code != response.ErrorCodeTokenExpired {
+		t.Errorf("expected errors.As to find the ErrorInfo, got %#v", info)
+	}
+}
+
+func TestFromHTTPResponseRoundTrip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/plain", func(c *gin.Context) {
+		response.BadRequestParam(c, "limit", "limit must be positive")
+	})
+	router.GET("/jsonapi", response.UseJSONAPI(), func(c *gin.Context) {
+		response.BadRequestParam(c, "limit", "limit must be positive")
+	})
+	quota := response.ErrorInfo{
+		Type: "rate_limit", Code: "quota_exceeded", Message: "quota exceeded",
+		DocURL: "https://help.example/quota", Details: map[string]any{"limit": float64(100)},
+	}
+	router.GET("/details", func(c *gin.Context) {
+		response.ErrorWithInfo(c, http.StatusTooManyRequests, quota)
+	})
+	router.GET("/jsonapi-details", response.UseJSONAPI(), func(c *gin.Context) {
+		response.ErrorWithInfo(c, http.StatusTooManyRequests, quota)
+	})
+	router.GET("/html", func(c *gin.Context) {
+		c.Data(http.StatusBadGateway, "text/html", []byte("<h1>Bad Gateway</h1>"))
+	})
+	router.GET("/ok", func(c *gin.Context) {
+		response.Object(c, gin.H{"id": 1})
+	})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		want       response.ErrorInfo
+	}{
+		{"/plain", 400, response.ErrorInfo{Type: "invalid_request", Message: "limit must be positive", Param: "limit"}},
+		{"/jsonapi", 400, response.ErrorInfo{Type: "invalid_request", Message: "limit must be positive", Param: "limit"}},
+		{"/details", 429, quota},
+		{"/jsonapi-details", 429, quota},
+		{"/html", 502, response.ErrorInfo{Type: "api", Message: "Bad Gateway"}},
+		{"/ok", 200, response.ErrorInfo{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			err := response.FromHTTPResponse(w.Result())
+			if tt.wantStatus < 400 {
+				if err != nil {
+					t.Errorf("expected nil, got %v", err)
+				}
+				return
+			}
+			var httpErr *response.HTTPError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("expected *HTTPError, got %T", err)
+			}
+			if httpErr.StatusCode != tt.wantStatus || !reflect.DeepEqual(httpErr.ErrorInfo, tt.want) {
+				t.Errorf("expected %d %#v, got %d %#v", tt.wantStatus, tt.want, httpErr.StatusCode, httpErr.ErrorInfo)
+			}
+		})
+	}
+}
+
+func TestParseErrorRejectsNonError(t *testing.T) {
+	if _, err := response.ParseError([]byte(`{"object":"list","data":[]}`)); !errors.Is(err, response.ErrNotErrorEnvelope) {
+		t.Errorf("expected ErrNotErrorEnvelope, got %v", err)
+	}
+	if _, err := response.ParseError([]byte(`not json`)); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,109 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PartialResponse is the envelope of an endpoint aggregating several
+// backends, where some sections may have failed. The shape is the same
+// whether or not anything failed, so clients always read data and errors.
+type PartialResponse struct {
+	Object   string         `json:"object"`   // Always "partial"
+	Data     any            `json:"data"`     // The sections that loaded
+	Degraded bool           `json:"degraded"` // Some sections failed
+	Errors   []SectionError `json:"errors"`   // One per failed section
+}
+
+// SectionError is the failure of one section of a partial response.
+type SectionError struct {
+	Section string    `json:"section"`
+	Error   ErrorInfo `json:"error"`
+}
+
+// SectionFailed builds the SectionError for err. Errors from ginapi
+// services (*HTTPError, ErrorInfo) keep their type and code; anything else
+// becomes a generic service_unavailable so internal details don't leak.
+func SectionFailed(section string, err error) SectionError {
+	var info ErrorInfo
+	if !errors.As(err, &info) {
+		info = ErrorInfo{
+			Type:    ErrorTypeAPI,
+			Code:    ErrorCodeServiceUnavailable,
+			Message: section + " is temporarily unavailable",
+		}
+	}
+	return SectionError{Section: section, Error: info}
+}
+
+// DegradedHook is called when a partial response is sent with failed
+// sections, for degradation metrics. route is the matched route pattern.
+type DegradedHook func(ctx context.Context, route string, failed []SectionError)
+
+var (
+	degradedMu    sync.RWMutex
+	degradedHooks []DegradedHook
+)
+
+// OnDegraded registers a hook run for every partial response with failed
+// sections. Register hooks at startup:
+//
+//	response.OnDegraded(func(ctx context.Context, route string, failed []response.SectionError) {
+//	    for _, f := range failed {
+//	        degradedSections.WithLabelValues(route, f.Section).Inc()
+//	    }
+//	})
+func OnDegraded(hook DegradedHook) {
+	degradedMu.Lock()
+	degradedHooks = append(degradedHooks, hook)
+	degradedMu.Unlock()
+}
+
+// ResetDegradedHooks removes all hooks registered with OnDegraded.
+// Intended for tests.
+func ResetDegradedHooks() {
+	degradedMu.Lock()
+	degradedHooks = nil
+	degradedMu.Unlock()
+}
+
+// Partial sends a 200 with the sections that loaded and an error per
+// section that failed, for fan-out endpoints that should degrade instead
+// of failing as a whole:
+//
+//	feed := map[string]any{}
+//	var failed []response.SectionError
+//	if recs, err := recsClient.ForUser(ctx, uid); err != nil {
+//	    failed = append(failed, response.SectionFailed("recommendations", err))
+//	} else {
+//	    feed["recommendations"] = recs
+//	}
+//	...
+//	response.Partial(c, feed, failed)
+//	// {"object": "partial", "data": {...}, "degraded": true,
+//	//  "errors": [{"section": "recommendations", "error": {"type": "api", ...}}]}
+func Partial(c *gin.Context, data any, failed []SectionError) {
+	if failed == nil {
+		failed = []SectionError{}
+	}
+	renderObject(ginOutput(c), http.StatusOK, PartialResponse{
+		Object:   "partial",
+		Data:     data,
+		Degraded: len(failed) > 0,
+		Errors:   failed,
+	})
+	if len(failed) == 0 {
+		return
+	}
+
+	degradedMu.RLock()
+	registered := degradedHooks
+	degradedMu.RUnlock()
+	for _, hook := range registered {
+		hook(c, c.FullPath(), failed)
+	}
+}
@@ -0,0 +1,100 @@
+package response_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestSectionFailed(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want response.ErrorInfo
+	}{
+		{
+			name: "upstream error keeps its code",
+			err:  &response.HTTPError{StatusCode: 429, ErrorInfo: response.ErrorInfo{Type: "rate_limit", Code: "rate_limit_exceeded", Message: "slow down"}},
+			want: response.ErrorInfo{Type: "rate_limit", Code: "rate_limit_exceeded", Message: "slow down"},
+		},
+		{
+			name: "unknown error is hidden",
+			err:  errors.New("dial tcp 10.0.0.3:443: connection refused"),
+			want: response.ErrorInfo{Type: "api", Code: "service_unavailable", Message: "recommendations is temporarily unavailable"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := response.SectionFailed("recommendations", tt.err)
+			if got.Section != "recommendations" || !reflect.DeepEqual(got.Error, tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, got.Error)
+			}
+		})
+	}
+}
+
+func TestPartial(t *testing.T) {
+	t.Cleanup(response.ResetDegradedHooks)
+	var degraded []string
+	response.OnDegraded(func(ctx context.Context, route string, failed []response.SectionError) {
+		for _, f := range failed {
+			degraded = append(degraded, route+" "+f.Section)
+		}
+	})
+
+	router := gin.New()
+	router.GET("/partial/feed", func(c *gin.Context) {
+		response.Partial(c, gin.H{"popular": []string{"gal_1"}}, []response.SectionError{
+			response.SectionFailed("recommendations", errors.New("timeout")),
+		})
+	})
+	router.GET("/partial/full", func(c *gin.Context) {
+		response.Partial(c, gin.H{"popular": []string{"gal_1"}}, nil)
+	})
+
+	tests := []struct {
+		path         string
+		wantDegraded bool
+		wantErrors   int
+	}{
+		{"/partial/feed", true, 1},
+		{"/partial/full", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d", w.Code)
+			}
+			var body struct {
+				Object   string            `json:"object"`
+				Data     map[string]any    `json:"data"`
+				Degraded bool              `json:"degraded"`
+				Errors   []json.RawMessage `json:"errors"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if body.Object != "partial" || body.Data["popular"] == nil {
+				t.Errorf("unexpected body %s", w.Body.String())
+			}
+			if body.Degraded != tt.wantDegraded || body.Errors == nil || len(body.Errors) != tt.wantErrors {
+				t.Errorf("expected degraded %v with %d errors, got %s", tt.wantDegraded, tt.wantErrors, w.Body.String())
+			}
+		})
+	}
+
+	if len(degraded) != 1 || degraded[0] != "/partial/feed recommendations" {
+		t.Errorf("expected one degradation for the feed, got %v", degraded)
+	}
+}
@@ -0,0 +1,61 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Protobuf media types. ProtobufMediaType is the one Twirp uses;
+// ProtobufMediaTypeLegacy is what gin and most older clients send.
+const (
+	ProtobufMediaType       = "application/protobuf"
+	ProtobufMediaTypeLegacy = "application/x-protobuf"
+)
+
+// protoJSON matches the snake_case field names of the rest of the API.
+var protoJSON = protojson.MarshalOptions{UseProtoNames: true}
+
+// Proto sends a protobuf message, as binary protobuf when the client asks for
+// it (Accept, or Content-Type when there's no Accept, as Twirp clients do) and
+// as JSON otherwise, so one handler serves both internal and public consumers.
+//
+//	router.GET("/internal/galleries/:id", func(c *gin.Context) {
+//	    response.Proto(c, galleryPB)
+//	})
+func Proto(c *gin.Context, msg proto.Message) {
+	ginOutput(c).proto(http.StatusOK, msg)
+}
+
+// ProtoWithStatus sends a protobuf message with the given status.
+func ProtoWithStatus(c *gin.Context, status int, msg proto.Message) {
+	ginOutput(c).proto(status, msg)
+}
+
+// WriteProto is the net/http equivalent of Proto.
+func WriteProto(w http.ResponseWriter, r *http.Request, msg proto.Message) {
+	httpOutput(w, r).proto(http.StatusOK, msg)
+}
+
+// proto writes msg in the negotiated encoding.
+func (o output) proto(status int, msg proto.Message) {
+	switch mediaType := o.negotiate("application/json", ProtobufMediaType, ProtobufMediaTypeLegacy); mediaType {
+	case ProtobufMediaType, ProtobufMediaTypeLegacy:
+		body, err := proto.Marshal(msg)
+		if err != nil {
+			o.error(http.StatusInternalServerError, ErrorInfo{Type: ErrorTypeAPI, Code: ErrorCodeInternal, Message: "failed to encode response"})
+			return
+		}
+		o.write(status, mediaType, body)
+	default:
+		body, err := protoJSON.Marshal(msg)
+		if err != nil {
+			o.error(http.StatusInternalServerError, ErrorInfo{Type: ErrorTypeAPI, Code: ErrorCodeInternal, Message: "failed to encode response"})
+			return
+		}
+		o.json(status, json.RawMessage(body))
+	}
+}
@@ -0,0 +1,64 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestProtoNegotiation(t *testing.T) {
+	msg, _ := structpb.NewStruct(map[string]any{"object": "gallery", "id": "gal_1"})
+
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+		want        string
+	}{
+		{name: "default json", want: "application/json; charset=utf-8"},
+		{name: "accept protobuf", accept: "application/protobuf", want: response.ProtobufMediaType},
+		{name: "accept legacy protobuf", accept: "application/x-protobuf", want: response.ProtobufMediaTypeLegacy},
+		{name: "json preferred by q", accept: "application/x-protobuf;q=0.5, application/json", want: "application/json; charset=utf-8"},
+		{name: "wildcard", accept: "*/*", want: "application/json; charset=utf-8"},
+		{name: "twirp content type", contentType: "application/protobuf", want: response.ProtobufMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/rpc", nil)
+			if tt.accept != "" {
+				c.Request.Header.Set("Accept", tt.accept)
+			}
+			if tt.contentType != "" {
+				c.Request.Header.Set("Content-Type", tt.contentType)
+			}
+
+			response.Proto(c, msg)
+
+			if got := w.Header().Get("Content-Type"); got != tt.want {
+				t.Fatalf("expected Content-Type %s, got %s", tt.want, got)
+			}
+			if tt.want == "application/json; charset=utf-8" {
+				if want := `{"id":"gal_1","object":"gallery"}`; w.Body.String() != want {
+					t.Errorf("expected %s, got %s", want, w.Body.String())
+				}
+				return
+			}
+			var decoded structpb.Struct
+			if err := proto.Unmarshal(w.Body.Bytes(), &decoded); err != nil {
+				t.Fatalf("expected protobuf body, got error %v", err)
+			}
+			if !proto.Equal(&decoded, msg) {
+				t.Errorf("expected %v, got %v", msg, &decoded)
+			}
+		})
+	}
+}
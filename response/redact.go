@@ -0,0 +1,448 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// audiencesKey is the gin context key for the request's audiences.
+const audiencesKey = "ginapi.audiences"
+
+// SetAudiences grants the current request the given audiences (e.g. "admin").
+// Struct fields tagged `audience:"admin"` are stripped from Object, Created,
+// and List output unless the request has one of the listed audiences.
+//
+// Auth middleware should call this after authenticating the principal:
+//
+//	type Gallery struct {
+//	    ID        string `json:"id"`
+//	    Title     string `json:"title"`
+//	    SourceURL string `json:"source_url" audience:"admin,moderator"`
+//	}
+func SetAudiences(c *gin.Context, audiences ...string) {
+	c.Set(audiencesKey, audiences)
+}
+
+// Audiences returns the audiences granted to the current request, from
+// SetAudiences or, failing that, the request context (see
+// AudiencesFromContext).
+func Audiences(c *gin.Context) []string {
+	if c == nil {
+		return nil
+	}
+	if v, ok := c.Get(audiencesKey); ok {
+		if audiences, ok := v.([]string); ok {
+			return audiences
+		}
+	}
+	if c.Request != nil {
+		return AudiencesFromContext(c.Request.Context())
+	}
+	return nil
+}
+
+// audiencesContextKey is the request context key for audiences.
+type audiencesContextKey struct{}
+
+// WithAudiences returns a copy of ctx granting the given audiences.
+// This is the net/http equivalent of SetAudiences.
+func WithAudiences(ctx context.Context, audiences ...string) context.Context {
+	return context.WithValue(ctx, audiencesContextKey{}, audiences)
+}
+
+// AudiencesFromContext returns the audiences stored by WithAudiences or,
+// failing that, the roles and scopes of the request's principal (see
+// WithPrincipalAudiences).
+func AudiencesFromContext(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	if audiences, ok := ctx.Value(audiencesContextKey{}).([]string); ok {
+		return audiences
+	}
+	audiences, _ := ctx.Value(principalAudiencesContextKey{}).([]string)
+	return audiences
+}
+
+// principalAudiencesContextKey is the request context key for the
+// audiences of the principal.
+type principalAudiencesContextKey struct{}
+
+// WithPrincipalAudiences returns a copy of ctx carrying the roles and
+// scopes of the request's principal, the audiences of requests that
+// neither SetAudiences nor WithAudiences granted any. auth.SetPrincipal
+// calls it, so fields tagged `audience:"moderator"` are shown to
+// principals with the moderator role without further setup. Reserved
+// audiences (see ReserveAudience) are dropped.
+func WithPrincipalAudiences(ctx context.Context, audiences ...string) context.Context {
+	reservedAudiencesMu.RLock()
+	if len(reservedAudiences) > 0 {
+		audiences = slices.DeleteFunc(slices.Clone(audiences), func(a string) bool {
+			_, ok := reservedAudiences[a]
+			return ok
+		})
+	}
+	reservedAudiencesMu.RUnlock()
+	return context.WithValue(ctx, principalAudiencesContextKey{}, audiences)
+}
+
+var (
+	reservedAudiencesMu sync.RWMutex
+	reservedAudiences   = map[string]struct{}{}
+)
+
+// ReserveAudience marks an audience that only SetAudiences or
+// WithAudiences grant, never a principal's roles or scopes, for audiences
+// tied to routes rather than to principals: package admin reserves
+// "admin", so an admin browsing the public routes sees the public fields.
+// Call it at init.
+func ReserveAudience(audience string) {
+	if audience == "" {
+		panic("response: ReserveAudience requires an audience")
+	}
+	reservedAudiencesMu.Lock()
+	reservedAudiences[audience] = struct{}{}
+	reservedAudiencesMu.Unlock()
+}
+
+// redact wraps v so audience-restricted fields not in audiences are omitted
+// when serialized. Returns v unchanged if no value reachable from it, including
+// the dynamic values of interfaces such as those of gin.H or []any, has
+// audience tags.
+func redact(v any, audiences []string) any {
+	if v == nil || !needsRedaction(reflect.ValueOf(v)) {
+		return v
+	}
+
+	allowed := make(map[string]struct{}, len(audiences))
+	for _, a := range audiences {
+		allowed[a] = struct{}{}
+	}
+	return redacted{value: v, allowed: allowed}
+}
+
+// redacted marshals value, omitting fields whose audience isn't allowed.
+type redacted struct {
+	value   any
+	allowed map[string]struct{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r redacted) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeRedacted(&buf, reflect.ValueOf(r.value), r.allowed); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var (
+	audienceTypes     sync.Map // reflect.Type -> audienceFlags
+	marshalerType     = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// audienceFlags describes what redaction a type may need.
+type audienceFlags uint8
+
+const (
+	// audienceTagged: the type, or one reachable from it, has a field with
+	// an audience tag
+	audienceTagged audienceFlags = 1 << iota
+	// audienceDynamic: an interface is reachable from the type, so whether
+	// its values need redacting depends on what they hold
+	audienceDynamic
+)
+
+// audienceFlagsOf returns the audience flags of t.
+func audienceFlagsOf(t reflect.Type) audienceFlags {
+	return audienceFlagsVisit(t, map[reflect.Type]bool{})
+}
+
+func audienceFlagsVisit(t reflect.Type, visiting map[reflect.Type]bool) audienceFlags {
+	if v, ok := audienceTypes.Load(t); ok {
+		return v.(audienceFlags)
+	}
+	if visiting[t] {
+		return 0
+	}
+	visiting[t] = true
+
+	var flags audienceFlags
+	if !t.Implements(marshalerType) {
+		switch t.Kind() {
+		case reflect.Interface:
+			flags = audienceDynamic
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			flags = audienceFlagsVisit(t.Elem(), visiting)
+		case reflect.Struct:
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				if _, ok := f.Tag.Lookup("audience"); ok {
+					flags |= audienceTagged
+				}
+				flags |= audienceFlagsVisit(f.Type, visiting)
+			}
+		}
+	}
+
+	audienceTypes.Store(t, flags)
+	return flags
+}
+
+// needsRedaction reports whether v has a value with audience tags,
+// looking at the dynamic value of every interface it reaches.
+func needsRedaction(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+	flags := audienceFlagsOf(v.Type())
+	if flags&audienceTagged != 0 {
+		return true
+	}
+	if flags&audienceDynamic == 0 {
+		return false
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return !v.IsNil() && needsRedaction(v.Elem())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if needsRedaction(v.Index(i)) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if needsRedaction(iter.Value()) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if f := t.Field(i); (f.IsExported() || f.Anonymous) && needsRedaction(v.Field(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encodeRedacted writes v as JSON, following encoding/json field rules for
+// names, omitempty, "-", and embedded structs, while dropping disallowed fields.
+func encodeRedacted(buf *bytes.Buffer, v reflect.Value, allowed map[string]struct{}) error {
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+	if audienceFlagsOf(v.Type()) == 0 {
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return encodeRedacted(buf, v.Elem(), allowed)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeRedacted(buf, v.Index(i), allowed); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		keys, err := mapKeys(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			name, _ := json.Marshal(k.name)
+			buf.Write(name)
+			buf.WriteByte(':')
+			if err := encodeRedacted(buf, v.MapIndex(k.key), allowed); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case reflect.Struct:
+		buf.WriteByte('{')
+		first := true
+		if err := encodeStructFields(buf, v, allowed, &first); err != nil {
+			return err
+		}
+		buf.WriteByte('}')
+		return nil
+	}
+
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+// mapKey is a map key with its JSON object name.
+type mapKey struct {
+	key  reflect.Value
+	name string
+}
+
+// mapKeys returns the keys of map v sorted by name, naming them the way
+// encoding/json does: strings as is, then encoding.TextMarshaler, then
+// integers in decimal.
+func mapKeys(v reflect.Value) ([]mapKey, error) {
+	keys := make([]mapKey, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		var name string
+		switch {
+		case k.Kind() == reflect.String:
+			name = k.String()
+		case k.Type().Implements(textMarshalerType):
+			if k.Kind() == reflect.Pointer && k.IsNil() {
+				break
+			}
+			text, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			name = string(text)
+		case k.CanInt():
+			name = strconv.FormatInt(k.Int(), 10)
+		case k.CanUint():
+			name = strconv.FormatUint(k.Uint(), 10)
+		default:
+			return nil, &json.UnsupportedTypeError{Type: v.Type()}
+		}
+		keys = append(keys, mapKey{key: k, name: name})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].name < keys[j].name })
+	return keys, nil
+}
+
+// encodeStructFields writes the fields of struct v (without braces),
+// inlining embedded structs the way encoding/json does.
+func encodeStructFields(buf *bytes.Buffer, v reflect.Value, allowed map[string]struct{}, first *bool) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+
+		if audience, ok := f.Tag.Lookup("audience"); ok && !audienceAllowed(audience, allowed) {
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// Embedded struct without an explicit name - inline its fields
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				ft, fv = ft.Elem(), fv.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := encodeStructFields(buf, fv, allowed, first); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(fv) {
+			continue
+		}
+
+		if !*first {
+			buf.WriteByte(',')
+		}
+		*first = false
+
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		if err := encodeRedacted(buf, fv, allowed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// audienceAllowed reports whether any audience in the comma-separated tag is allowed.
+func audienceAllowed(tag string, allowed map[string]struct{}) bool {
+	for _, a := range strings.Split(tag, ",") {
+		if _, ok := allowed[strings.TrimSpace(a)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmptyValue mirrors encoding/json's omitempty semantics.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/response"
+)
+
+type redactBase struct {
+	CreatedBy string `json:"created_by" audience:"admin"`
+}
+
+type redactGallery struct {
+	redactBase
+	Object    string `json:"object"`
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	SourceURL string `json:"source_url" audience:"admin,moderator"`
+	Notes     string `json:"notes,omitempty" audience:"admin"`
+	Internal  string `json:"-"`
+}
+
+func TestObjectRedactsByAudience(t *testing.T) {
+	obj := redactGallery{
+		redactBase: redactBase{CreatedBy: "usr_1"},
+		Object:     "gallery",
+		ID:         "gal_1",
+		Title:      "Title",
+		SourceURL:  "https://source",
+		Internal:   "secret",
+	}
+
+	tests := []struct {
+		name      string
+		audiences []string
+		want      string
+	}{
+		{
+			name: "public",
+			want: `{"object":"gallery","id":"gal_1","title":"Title"}`,
+		},
+		{
+			name:      "moderator",
+			audiences: []string{"moderator"},
+			want:      `{"object":"gallery","id":"gal_1","title":"Title","source_url":"https://source"}`,
+		},
+		{
+			name:      "admin omitempty still applies",
+			audiences: []string{"admin"},
+			want:      `{"created_by":"usr_1","object":"gallery","id":"gal_1","title":"Title","source_url":"https://source"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			if tt.audiences != nil {
+				response.SetAudiences(c, tt.audiences...)
+			}
+
+			response.Object(c, obj)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAudiencesFromPrincipal(t *testing.T) {
+	response.ReserveAudience("admin")
+	obj := redactGallery{Object: "gallery", ID: "gal_1", Title: "Title", SourceURL: "https://source"}
+	public := `{"object":"gallery","id":"gal_1","title":"Title"}`
+	moderator := `{"object":"gallery","id":"gal_1","title":"Title","source_url":"https://source"}`
+
+	tests := []struct {
+		name  string
+		setup func(c *gin.Context)
+		want  string
+	}{
+		{"role", func(c *gin.Context) {
+			auth.SetPrincipal(c, auth.Principal{ID: "usr_1", Roles: []string{"moderator"}})
+		}, moderator},
+		{"scope", func(c *gin.Context) {
+			auth.SetPrincipal(c, auth.Principal{ID: "usr_1", Scopes: []string{"moderator"}})
+		}, moderator},
+		{"SetAudiences wins", func(c *gin.Context) {
+			auth.SetPrincipal(c, auth.Principal{ID: "usr_1", Roles: []string{"moderator"}})
+			response.SetAudiences(c)
+		}, public},
+		{"WithAudiences wins", func(c *gin.Context) {
+			c.Request = c.Request.WithContext(response.WithAudiences(c.Request.Context()))
+			auth.SetPrincipal(c, auth.Principal{ID: "usr_1", Roles: []string{"moderator"}})
+		}, public},
+		{"reserved audience", func(c *gin.Context) {
+			auth.SetPrincipal(c, auth.Principal{ID: "usr_1", Roles: []string{"admin"}})
+		}, public},
+		{"anonymous", func(c *gin.Context) {}, public},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/galleries/gal_1", nil)
+			tt.setup(c)
+
+			response.Object(c, obj)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestListRedactsItems(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	items := []*redactGallery{{Object: "gallery", ID: "gal_1", SourceURL: "https://source"}, nil}
+	response.ListResponse(c, items, 2, 20, 0)
+
+	want := `{"object":"list","data":[{"object":"gallery","id":"gal_1","title":""},null],"total":2,"limit":20,"offset":0,"has_more":false}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestObjectWithoutAudienceTagsUnchanged(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	response.Object(c, map[string]any{"object": "thing", "id": "thg_1"})
+
+	if w.Body.String() != `{"id":"thg_1","object":"thing"}` {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+}
+
+func TestRedactsBehindInterfaces(t *testing.T) {
+	g := redactGallery{Object: "gallery", ID: "gal_1", SourceURL: "https://source"}
+	item := `{"object":"gallery","id":"gal_1","title":""}`
+
+	tests := []struct {
+		name   string
+		render func(c *gin.Context)
+		want   string
+	}{
+		{
+			name:   "gin.H",
+			render: func(c *gin.Context) { response.Object(c, gin.H{"gallery": g, "count": 1}) },
+			want:   `{"count":1,"gallery":` + item + `}`,
+		},
+		{
+			name:   "map with integer keys",
+			render: func(c *gin.Context) { response.Object(c, map[int]any{2: &g}) },
+			want:   `{"2":` + item + `}`,
+		},
+		{
+			name:   "[]any",
+			render: func(c *gin.Context) { response.Object(c, []any{g, "x"}) },
+			want:   `[` + item + `,"x"]`,
+		},
+		{
+			name:   "struct field",
+			render: func(c *gin.Context) { response.Object(c, struct{ Data any }{Data: g}) },
+			want:   `{"Data":` + item + `}`,
+		},
+		{
+			name:   "ListResponse[any]",
+			render: func(c *gin.Context) { response.ListResponse(c, []any{g}, 1, 20, 0) },
+			want:   `{"object":"list","data":[` + item + `],"total":1,"limit":20,"offset":0,"has_more":false}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			tt.render(c)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, w.Body.String())
+			}
+		})
+	}
+}
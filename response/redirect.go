@@ -0,0 +1,58 @@
+package response
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedirectObject is the body of a redirect sent to an API client.
+type RedirectObject struct {
+	Object string `json:"object"` // Always "redirect"
+	URL    string `json:"url"`
+	// Permanent is true for 301 and 308, which clients may cache
+	Permanent bool `json:"permanent"`
+}
+
+// Redirect sends a redirect to url with status, which must be 301, 302,
+// 303, 307, or 308. Browsers asking for HTML get the usual redirect; other
+// clients also get a RedirectObject body, so SPA and API clients that
+// can't follow the redirect, e.g. a cross-origin one under fetch's
+// redirect: "manual", still learn the target:
+//
+//	response.Redirect(c, http.StatusMovedPermanently, "/galleries/"+slug.Join(g.Slug, g.ID))
+//	// Location: /galleries/summer-gal_3f9a2c
+//	// {"object": "redirect", "url": "/galleries/summer-gal_3f9a2c", "permanent": true}
+//
+// It panics on other statuses.
+func Redirect(c *gin.Context, status int, url string) {
+	ginOutput(c).redirect(status, url)
+	c.Abort()
+}
+
+// WriteRedirect is the net/http equivalent of Redirect.
+func WriteRedirect(w http.ResponseWriter, r *http.Request, status int, url string) {
+	httpOutput(w, r).redirect(status, url)
+}
+
+// redirect writes the redirect, as HTML for clients that prefer it.
+func (o output) redirect(status int, url string) {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		panic("response: Redirect status must be a redirect, got " + strconv.Itoa(status))
+	}
+
+	if o.r != nil && o.negotiate("application/json", "text/html") == "text/html" {
+		http.Redirect(o.w, o.r, url, status)
+		return
+	}
+	o.w.Header().Set("Location", url)
+	renderObject(o, status, RedirectObject{
+		Object:    "redirect",
+		URL:       url,
+		Permanent: status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect,
+	})
+}
@@ -0,0 +1,93 @@
+package response_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestRedirect(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		accept        string
+		wantJSON      bool
+		wantPermanent bool
+	}{
+		{"no Accept", http.StatusMovedPermanently, "", true, true},
+		{"JSON", http.StatusFound, "application/json", true, false},
+		{"permanent redirect", http.StatusPermanentRedirect, "application/json", true, true},
+		{"temporary redirect", http.StatusTemporaryRedirect, "*/*", true, false},
+		{"browser", http.StatusMovedPermanently, "text/html,application/xhtml+xml,*/*;q=0.8", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/g/:id", func(c *gin.Context) {
+				response.Redirect(c, tt.status, "/galleries/summer-gal_3f9a2c")
+			})
+			req := httptest.NewRequest(http.MethodGet, "/g/3f9a2c", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if got := w.Header().Get("Location"); got != "/galleries/summer-gal_3f9a2c" {
+				t.Errorf("expected Location /galleries/summer-gal_3f9a2c, got %q", got)
+			}
+			if got := w.Header().Get("Vary"); !strings.Contains(got, "Accept") {
+				t.Errorf("expected Vary: Accept, got %q", got)
+			}
+
+			isJSON := strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+			if isJSON != tt.wantJSON {
+				t.Fatalf("expected JSON %v, got Content-Type %q", tt.wantJSON, w.Header().Get("Content-Type"))
+			}
+			if !isJSON {
+				return
+			}
+			var body response.RedirectObject
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			want := response.RedirectObject{Object: "redirect", URL: "/galleries/summer-gal_3f9a2c", Permanent: tt.wantPermanent}
+			if body != want {
+				t.Errorf("expected %+v, got %+v", want, body)
+			}
+		})
+	}
+}
+
+func TestWriteRedirect(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/g/3f9a2c", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	response.WriteRedirect(w, req, http.StatusSeeOther, "/galleries/summer-gal_3f9a2c")
+
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/galleries/summer-gal_3f9a2c" {
+		t.Errorf("expected 303 to /galleries/summer-gal_3f9a2c, got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	if !strings.Contains(w.Body.String(), `"object":"redirect"`) {
+		t.Errorf("expected a redirect object, got %s", w.Body.String())
+	}
+}
+
+func TestRedirectInvalidStatus(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic for a non-redirect status")
+		}
+	}()
+	response.WriteRedirect(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, "/")
+}
@@ -0,0 +1,176 @@
+package response
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// output is the framework-independent core behind every helper: the gin
+// helpers and the net/http Write* functions both resolve the per-request
+// settings into an output and write through it.
+type output struct {
+	w            http.ResponseWriter
+	r            *http.Request   // may be nil in tests
+	ctx          context.Context // passed to response hooks
+	audiences    []string
+	mode         PaginationMode
+	jsonAPI      bool
+	interceptors []Interceptor
+	debug        *debugOptions // set by DebugParams, gin only
+	sizeLimit    sizeLimit
+	warnings     []Warning    // set by Warn, gin only
+	version      *versionPlan // set by Versioned
+	keyCase      KeyCase      // set by NegotiateKeyCase, UseKeyCase, or SetKeyCase
+}
+
+// ginOutput builds an output from a gin context.
+func ginOutput(c *gin.Context) output {
+	return output{
+		w:            c.Writer,
+		r:            c.Request,
+		ctx:          c,
+		audiences:    Audiences(c),
+		mode:         paginationMode(c),
+		jsonAPI:      jsonAPIEnabled(c),
+		interceptors: ginInterceptors(c),
+		debug:        resolveDebug(c),
+		sizeLimit:    ginSizeLimit(c),
+		warnings:     Warnings(c),
+		version:      ginVersionPlan(c),
+		keyCase:      ginKeyCase(c),
+	}
+}
+
+// httpOutput builds an output from a plain net/http request.
+func httpOutput(w http.ResponseWriter, r *http.Request) output {
+	o := output{w: w, r: r, keyCase: globalKeyCase()}
+	if r != nil {
+		o.ctx = r.Context()
+		o.audiences = AudiencesFromContext(r.Context())
+		o.mode = PaginationModeFromContext(r.Context())
+		o.jsonAPI = JSONAPIFromContext(r.Context())
+		o.interceptors = InterceptorsFromContext(r.Context())
+		o.sizeLimit = sizeLimitFromContext(r.Context())
+		o.version = versionPlanFromContext(r.Context())
+		o.keyCase = KeyCaseFromContext(r.Context())
+	}
+	return o
+}
+
+// render writes v as the JSON response body for a gin handler.
+func render(c *gin.Context, status int, v any) {
+	ginOutput(c).json(status, v)
+}
+
+// json writes v as the JSON response body, applying audience redaction.
+// Clients that negotiate MessagePack get the same document as msgpack.
+// HEAD requests get the headers the equivalent GET would produce
+// (Content-Type, Content-Length, ETag, X-Total-Count) and no body.
+func (o output) json(status int, v any) {
+	head := o.r != nil && o.r.Method == http.MethodHead
+	if l, ok := v.(totaler); ok && head {
+		o.w.Header().Set("X-Total-Count", strconv.FormatInt(l.totalCount(), 10))
+	}
+
+	status, contentType, body := o.encode(status, v)
+	o.write(status, contentType, body)
+}
+
+// encode serializes v the way json sends it, returning the (possibly
+// changed) status, the content type, and the body. Bodies over the size
+// limit are replaced by a response_too_large error.
+func (o output) encode(status int, v any) (int, string, []byte) {
+	contentType := "application/json; charset=utf-8"
+	body, err := json.Marshal(redact(v, o.audiences))
+	if err == nil && len(o.interceptors) > 0 {
+		body, err = o.intercept(body)
+	}
+	if err == nil && o.version != nil && len(o.version.changes) > 0 {
+		body, err = o.version.downgrade(body)
+	}
+	if err == nil && len(o.warnings) > 0 && !o.jsonAPI && status < 400 {
+		body = appendWarnings(body, o.warnings)
+	}
+	if err == nil && o.jsonAPI {
+		contentType = JSONAPIMediaType
+		body, err = toJSONAPI(status, body)
+	}
+	if err == nil {
+		body = o.convertKeys(body)
+	}
+	if err == nil && o.debug != nil {
+		body = o.debug.apply(body)
+	}
+	if err == nil && prettyJSON.Load() && (o.debug == nil || !o.debug.pretty) {
+		body = indentJSON(body)
+	}
+	if mediaType, ok := o.wantsMsgpack(); err == nil && !o.jsonAPI && ok {
+		contentType = mediaType
+		body, err = jsonToMsgpack(body)
+	}
+	if err != nil {
+		status = http.StatusInternalServerError
+		contentType = "application/json; charset=utf-8"
+		body, _ = json.Marshal(Error{
+			Object: "error",
+			Error:  ErrorInfo{Type: ErrorTypeAPI, Code: ErrorCodeInternal, Message: "failed to encode response"},
+		})
+	}
+	if o.sizeLimit.maxBytes > 0 && len(body) > o.sizeLimit.maxBytes {
+		return o.tooLarge(len(body))
+	}
+
+	return status, contentType, body
+}
+
+// write sends an encoded body. HEAD requests get Content-Length and an ETag
+// instead of the body.
+func (o output) write(status int, contentType string, body []byte) {
+	head := o.r != nil && o.r.Method == http.MethodHead
+
+	h := o.w.Header()
+	h.Set("Content-Type", contentType)
+	if head {
+		h.Set("Content-Length", strconv.Itoa(len(body)))
+		if h.Get("ETag") == "" {
+			h.Set("ETag", bodyETag(body))
+		}
+	}
+
+	o.w.WriteHeader(status)
+	if head || !bodyAllowedForStatus(status) {
+		if f, ok := o.w.(interface{ WriteHeaderNow() }); ok {
+			f.WriteHeaderNow()
+		}
+		return
+	}
+	_, _ = o.w.Write(body)
+}
+
+// totaler is implemented by list responses so HEAD can expose the total count.
+type totaler interface {
+	totalCount() int64
+}
+
+// bodyAllowedForStatus reports whether a response with status may have a body.
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// bodyETag returns a strong ETag derived from the serialized body.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
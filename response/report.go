@@ -0,0 +1,164 @@
+package response
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync"
+)
+
+// Report describes a server-side failure for error tracking.
+type Report struct {
+	// Err is the failure: the recovered panic value, the unmapped error,
+	// or the InternalError message
+	Err error
+	// Stack is the goroutine stack where the failure was reported
+	Stack []byte
+	// Request is the request being handled, or nil outside a request
+	Request *http.Request
+	// Route is the matched route pattern, when known
+	Route string
+	// RequestID is the X-Request-ID of the request, when present
+	RequestID string
+	// ErrorID is the ID the client was given in place of the error's
+	// message (see HideServerErrorMessages), when it was hidden
+	ErrorID string
+	// Principal is the authenticated principal, as returned by the function
+	// passed to SetPrincipalResolver
+	Principal any
+	// RequestHeader and RequestBody are the redacted request headers and
+	// body, when CaptureRequests is installed. RequestBody is nil for bodies
+	// that can't be redacted (not JSON or a form) or exceed the capture limit.
+	RequestHeader http.Header
+	RequestBody   []byte
+	// RequestBodyTruncated reports that the body exceeded the capture limit
+	RequestBodyTruncated bool
+}
+
+// Reporter sends failures to an error tracker such as Sentry. Reports are
+// sent synchronously from the request goroutine, so implementations should
+// hand off to a background client rather than block.
+//
+// The recovery middleware, rpcerr's mapping of unknown errors, and
+// InternalError all report through the reporter set with SetReporter:
+//
+//	type sentryReporter struct{}
+//
+//	func (sentryReporter) Report(ctx context.Context, r response.Report) {
+//	    hub := sentry.CurrentHub().Clone()
+//	    hub.Scope().SetTag("route", r.Route)
+//	    hub.Scope().SetTag("request_id", r.RequestID)
+//	    if r.Request != nil {
+//	        hub.Scope().SetRequest(r.Request)
+//	    }
+//	    if p, ok := r.Principal.(auth.User); ok {
+//	        hub.Scope().SetUser(sentry.User{ID: p.ID})
+//	    }
+//	    hub.CaptureException(r.Err)
+//	}
+//
+//	response.SetReporter(sentryReporter{})
+type Reporter interface {
+	Report(ctx context.Context, r Report)
+}
+
+// ReporterFunc adapts a function to the Reporter interface.
+type ReporterFunc func(ctx context.Context, r Report)
+
+// Report calls f(ctx, r).
+func (f ReporterFunc) Report(ctx context.Context, r Report) {
+	f(ctx, r)
+}
+
+// nopReporter is the default Reporter; it discards reports.
+type nopReporter struct{}
+
+func (nopReporter) Report(context.Context, Report) {}
+
+// SlogReporter returns a Reporter that logs each report at error level.
+// Useful in development, or as a template for a real adapter.
+func SlogReporter(logger *slog.Logger) Reporter {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return ReporterFunc(func(ctx context.Context, r Report) {
+		attrs := []slog.Attr{slog.String("error", r.Err.Error())}
+		if r.Request != nil {
+			attrs = append(attrs, slog.String("method", r.Request.Method), slog.String("path", r.Request.URL.Path))
+		}
+		if r.Route != "" {
+			attrs = append(attrs, slog.String("route", r.Route))
+		}
+		if r.RequestID != "" {
+			attrs = append(attrs, slog.String("request_id", r.RequestID))
+		}
+		if r.ErrorID != "" && r.ErrorID != r.RequestID {
+			attrs = append(attrs, slog.String("error_id", r.ErrorID))
+		}
+		if r.Principal != nil {
+			attrs = append(attrs, slog.Any("principal", r.Principal))
+		}
+		attrs = append(attrs, slog.String("stack", string(r.Stack)))
+		logger.LogAttrs(ctx, slog.LevelError, "request failed", attrs...)
+	})
+}
+
+var (
+	reportMu          sync.RWMutex
+	reporter          Reporter = nopReporter{}
+	principalResolver func(ctx context.Context) any
+)
+
+// SetReporter sets the Reporter failures are sent to. Call it once at
+// startup; nil restores the no-op default.
+func SetReporter(r Reporter) {
+	if r == nil {
+		r = nopReporter{}
+	}
+	reportMu.Lock()
+	reporter = r
+	reportMu.Unlock()
+}
+
+// SetPrincipalResolver sets the function that fills Report.Principal from
+// the request context (or *gin.Context), e.g. the user your auth middleware
+// authenticated. Call it once at startup.
+func SetPrincipalResolver(fn func(ctx context.Context) any) {
+	reportMu.Lock()
+	principalResolver = fn
+	reportMu.Unlock()
+}
+
+// ReportError sends err to the Reporter with the current stack and the
+// metadata of r (which may be nil). ctx is passed to the principal resolver
+// and the Reporter; pass the *gin.Context in gin handlers. Called from a
+// deferred recover, the stack includes the panicking frames.
+func ReportError(ctx context.Context, r *http.Request, route string, err error) {
+	reportError(ctx, r, route, err, "")
+}
+
+// reportError is ReportError for an error whose message the client got as
+// errorID.
+func reportError(ctx context.Context, r *http.Request, route string, err error, errorID string) {
+	reportMu.RLock()
+	rep, resolve := reporter, principalResolver
+	reportMu.RUnlock()
+
+	if _, nop := rep.(nopReporter); nop {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	report := Report{Err: err, Stack: debug.Stack(), Request: r, Route: route, ErrorID: errorID}
+	if r != nil {
+		report.RequestID = r.Header.Get("X-Request-ID")
+		attachCapture(&report, r)
+	}
+	if resolve != nil {
+		report.Principal = resolve(ctx)
+	}
+	rep.Report(ctx, report)
+}
@@ -0,0 +1,110 @@
+package response_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// captureReports installs a Reporter collecting reports for the test.
+func captureReports(t *testing.T) *[]response.Report {
+	t.Helper()
+	var reports []response.Report
+	response.SetReporter(response.ReporterFunc(func(ctx context.Context, r response.Report) {
+		reports = append(reports, r)
+	}))
+	t.Cleanup(func() {
+		response.SetReporter(nil)
+		response.SetPrincipalResolver(nil)
+	})
+	return &reports
+}
+
+func TestInternalErrorReports(t *testing.T) {
+	reports := captureReports(t)
+	response.SetPrincipalResolver(func(ctx context.Context) any {
+		return ctx.Value("user")
+	})
+
+	router := gin.New()
+	router.GET("/galleries/:id", func(c *gin.Context) {
+		c.Set("user", "usr_1")
+		response.InternalError(c, "database unavailable")
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/galleries/42", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+	if len(*reports) != 1 {
+		t.Fatalf("expected 1 report, got %d", len(*reports))
+	}
+	r := (*reports)[0]
+	if r.Err.Error() != "database unavailable" {
+		t.Errorf("expected error 'database unavailable', got '%v'", r.Err)
+	}
+	if r.Route != "/galleries/:id" {
+		t.Errorf("expected route '/galleries/:id', got '%s'", r.Route)
+	}
+	if r.RequestID != "req-123" {
+		t.Errorf("expected request ID 'req-123', got '%s'", r.RequestID)
+	}
+	if r.Principal != "usr_1" {
+		t.Errorf("expected principal 'usr_1', got '%v'", r.Principal)
+	}
+	if r.Request == nil || r.Request.URL.Path != "/galleries/42" {
+		t.Errorf("expected request for /galleries/42, got %v", r.Request)
+	}
+	if !strings.Contains(string(r.Stack), "TestInternalErrorReports") {
+		t.Errorf("expected stack to include the handler, got %s", r.Stack)
+	}
+}
+
+func TestOtherErrorsDoNotReport(t *testing.T) {
+	reports := captureReports(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	response.ServiceUnavailable(c, "busy")
+	response.NotFound(c, "gallery not found")
+
+	if len(*reports) != 0 {
+		t.Errorf("expected no reports, got %d", len(*reports))
+	}
+}
+
+func TestSlogReporter(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := response.SlogReporter(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	req, _ := http.NewRequest("POST", "/galleries", nil)
+	reporter.Report(context.Background(), response.Report{
+		Err:       errTest("boom"),
+		Request:   req,
+		Route:     "/galleries",
+		RequestID: "req-1",
+	})
+
+	for _, want := range []string{"level=ERROR", "error=boom", "method=POST", "route=/galleries", "request_id=req-1"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected log to contain %q, got %s", want, buf.String())
+		}
+	}
+}
+
+type errTest string
+
+func (e errTest) Error() string { return string(e) }
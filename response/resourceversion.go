@@ -0,0 +1,158 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VersionedResource is implemented by objects that carry their own
+// version, such as an updated_at or version column. Object, Created, and
+// their net/http equivalents send it as the ETag, answer GET requests whose
+// If-None-Match has it with 304 Not Modified, and IfMatch checks writes
+// against it, so handlers don't wire either up themselves:
+//
+//	func (g Gallery) ResourceVersion() string {
+//	    return strconv.FormatInt(g.UpdatedAt.UnixMicro(), 10)
+//	}
+//
+// An empty version sends the object like any other. (The name Versioned is
+// taken by the API version middleware.)
+type VersionedResource interface {
+	ResourceVersion() string
+}
+
+// ResourceETag returns the strong ETag Object sends for v on this request:
+// the quoted version, or a hash of it when it isn't a valid ETag or the
+// representation varies by API version, audience, or key case. It is ""
+// when v has no version.
+func ResourceETag(c *gin.Context, v VersionedResource) string {
+	return ginOutput(c).resourceETag(v)
+}
+
+// IfMatch checks a write's If-Match header against the current version of
+// the resource and reports whether the handler may go on. Without the
+// header, or when it lists the current ETag or "*", it sends nothing and
+// returns true; otherwise it sends 412 precondition_failed with the
+// current ETag, and returns false, so a client never overwrites changes it
+// hasn't seen:
+//
+//	gallery, err := repo.Get(ctx, id)
+//	...
+//	if !response.IfMatch(c, gallery) {
+//	    return
+//	}
+//	... // apply the update
+//	response.Object(c, updated) // with the new ETag
+//
+// ETags are compared strongly, so weak ones never match.
+func IfMatch(c *gin.Context, current VersionedResource) bool {
+	return ginOutput(c).ifMatch(current, false)
+}
+
+// RequireIfMatch is IfMatch for writes that must be conditional: requests
+// without If-Match get 428 precondition_required.
+func RequireIfMatch(c *gin.Context, current VersionedResource) bool {
+	return ginOutput(c).ifMatch(current, true)
+}
+
+// ifMatch is IfMatch for o's request.
+func (o output) ifMatch(current VersionedResource, required bool) bool {
+	header := o.r.Header.Get("If-Match")
+	if header == "" {
+		if !required {
+			return true
+		}
+		o.error(http.StatusPreconditionRequired, ErrorInfo{
+			Type:    ErrorTypeInvalidRequest,
+			Code:    ErrorCodePreconditionRequired,
+			Message: "this request requires an If-Match header with the resource's ETag",
+		})
+		return false
+	}
+
+	etag := o.resourceETag(current)
+	if etag != "" && strongETagMatches(header, etag) {
+		return true
+	}
+	if etag != "" {
+		o.w.Header().Set("ETag", etag)
+	}
+	o.error(http.StatusPreconditionFailed, ErrorInfo{
+		Type:    ErrorTypeConflict,
+		Code:    ErrorCodePreconditionFailed,
+		Message: "the resource changed since it was fetched; fetch it again and retry",
+	})
+	return false
+}
+
+// resourceETag is ResourceETag for o's request.
+func (o output) resourceETag(v VersionedResource) string {
+	if v == nil {
+		return ""
+	}
+	version := v.ResourceVersion()
+	if version == "" {
+		return ""
+	}
+	keyCase := o.keyCase != "" && o.keyCase != SnakeCase
+	varies := (o.version != nil && o.version.version != "") || len(o.audiences) > 0 || keyCase
+	if !varies && validETag(version) {
+		return `"` + version + `"`
+	}
+
+	key := version
+	if o.version != nil {
+		key += "\x00" + o.version.version
+	}
+	key += "\x00" + strings.Join(o.audiences, ",")
+	if keyCase {
+		key += "\x00" + string(o.keyCase)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// validETag reports whether version can be quoted as an ETag as is.
+func validETag(version string) bool {
+	for i := 0; i < len(version); i++ {
+		if b := version[i]; b <= 0x20 || b == '"' || b >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// strongETagMatches reports whether an If-Match header matches etag, using
+// the strong comparison RFC 9110 requires for If-Match.
+func strongETagMatches(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimSpace(candidate) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// versionedObject sets the ETag of a successful obj that is a
+// VersionedResource, and reports whether the client already has it.
+func (o output) versionedObject(status int, obj any) bool {
+	v, ok := obj.(VersionedResource)
+	if !ok || (status != http.StatusOK && status != http.StatusCreated) {
+		return false
+	}
+	etag := o.resourceETag(v)
+	if etag == "" {
+		return false
+	}
+	o.w.Header().Set("ETag", etag)
+	return status == http.StatusOK && o.r != nil &&
+		(o.r.Method == http.MethodGet || o.r.Method == http.MethodHead) &&
+		etagMatches(o.r.Header.Get("If-None-Match"), etag)
+}
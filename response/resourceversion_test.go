@@ -0,0 +1,157 @@
+package response_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+type versionedGallery struct {
+	Object  string `json:"object"`
+	ID      string `json:"id"`
+	Version string `json:"-"`
+}
+
+func (g versionedGallery) ResourceVersion() string { return g.Version }
+
+func TestObjectVersionedResource(t *testing.T) {
+	router := gin.New()
+	router.GET("/galleries/:id", func(c *gin.Context) {
+		response.Object(c, versionedGallery{Object: "gallery", ID: c.Param("id"), Version: c.Query("v")})
+	})
+	router.POST("/galleries", func(c *gin.Context) {
+		response.Created(c, versionedGallery{Object: "gallery", ID: "2", Version: "1"})
+	})
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		noneMatch   string
+		wantStatus  int
+		wantETag    string
+		wantHasBody bool
+	}{
+		{"version as ETag", http.MethodGet, "/galleries/1?v=1710000000", "", http.StatusOK, `"1710000000"`, true},
+		{"client has it", http.MethodGet, "/galleries/1?v=1710000000", `W/"1710000000"`, http.StatusNotModified, `"1710000000"`, false},
+		{"client has an older version", http.MethodGet, "/galleries/1?v=1710000001", `"1710000000"`, http.StatusOK, `"1710000001"`, true},
+		{"version hashed when not a valid ETag", http.MethodGet, "/galleries/1?v=a+b", "", http.StatusOK, `"dfa5830757fffdb100436d8dad645f6a"`, true},
+		{"no version", http.MethodGet, "/galleries/1", "", http.StatusOK, "", true},
+		{"created", http.MethodPost, "/galleries", "", http.StatusCreated, `"1"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.noneMatch != "" {
+				req.Header.Set("If-None-Match", tt.noneMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("ETag"); tt.wantETag != "" && got != tt.wantETag {
+				t.Errorf("expected ETag %s, got %s", tt.wantETag, got)
+			}
+			if got := w.Header().Get("ETag"); tt.wantETag == "" && got != "" {
+				t.Errorf("expected no ETag, got %s", got)
+			}
+			if (w.Body.Len() > 0) != tt.wantHasBody {
+				t.Errorf("expected body %v, got %q", tt.wantHasBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestResourceETagVariesByAudience(t *testing.T) {
+	gallery := versionedGallery{Object: "gallery", ID: "1", Version: "7"}
+	var plain, admin string
+	router := gin.New()
+	router.GET("/plain", func(c *gin.Context) {
+		plain = response.ResourceETag(c, gallery)
+	})
+	router.GET("/admin", func(c *gin.Context) {
+		response.SetAudiences(c, "admin")
+		admin = response.ResourceETag(c, gallery)
+	})
+	for _, path := range []string{"/plain", "/admin"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if plain != `"7"` {
+		t.Errorf(`expected "7", got %s`, plain)
+	}
+	if admin == plain || admin == "" {
+		t.Errorf("expected a distinct ETag for the admin audience, got %s", admin)
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	current := versionedGallery{Object: "gallery", ID: "1", Version: "7"}
+	router := gin.New()
+	router.PATCH("/galleries/1", func(c *gin.Context) {
+		if !response.IfMatch(c, current) {
+			return
+		}
+		response.Object(c, versionedGallery{Object: "gallery", ID: "1", Version: "8"})
+	})
+	router.PUT("/galleries/1", func(c *gin.Context) {
+		if !response.RequireIfMatch(c, current) {
+			return
+		}
+		response.Object(c, versionedGallery{Object: "gallery", ID: "1", Version: "8"})
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		ifMatch    string
+		wantStatus int
+		wantCode   string
+		wantETag   string
+	}{
+		{"no header", http.MethodPatch, "", http.StatusOK, "", `"8"`},
+		{"current version", http.MethodPatch, `"7"`, http.StatusOK, "", `"8"`},
+		{"one of several", http.MethodPatch, `"6", "7"`, http.StatusOK, "", `"8"`},
+		{"any version", http.MethodPatch, "*", http.StatusOK, "", `"8"`},
+		{"stale version", http.MethodPatch, `"6"`, http.StatusPreconditionFailed, response.ErrorCodePreconditionFailed, `"7"`},
+		{"weak never matches", http.MethodPatch, `W/"7"`, http.StatusPreconditionFailed, response.ErrorCodePreconditionFailed, `"7"`},
+		{"required and missing", http.MethodPut, "", http.StatusPreconditionRequired, response.ErrorCodePreconditionRequired, ""},
+		{"required and current", http.MethodPut, `"7"`, http.StatusOK, "", `"8"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/galleries/1", nil)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("expected ETag %q, got %q", tt.wantETag, got)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var result response.Error
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Error.Code != tt.wantCode {
+				t.Errorf("expected code %s, got %s", tt.wantCode, result.Error.Code)
+			}
+		})
+	}
+}
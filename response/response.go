@@ -3,6 +3,7 @@ package response
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,12 +11,18 @@ import (
 // Object sends a single object response.
 // The object should have an "object" field identifying its type.
 func Object(c *gin.Context, obj any) {
-	c.JSON(http.StatusOK, obj)
+	renderObject(ginOutput(c), http.StatusOK, obj)
 }
 
 // Created sends a 201 Created response with the created object.
 func Created(c *gin.Context, obj any) {
-	c.JSON(http.StatusCreated, obj)
+	renderObject(ginOutput(c), http.StatusCreated, obj)
+}
+
+// Accepted sends a 202 Accepted response with obj, for work that
+// continues in the background, usually a job object the client polls.
+func Accepted(c *gin.Context, obj any) {
+	renderObject(ginOutput(c), http.StatusAccepted, obj)
 }
 
 // NoContent sends a 204 No Content response.
@@ -25,13 +32,29 @@ func NoContent(c *gin.Context) {
 
 // Deleted sends a Stripe-style deletion confirmation.
 func Deleted(c *gin.Context, objectType string, id string) {
-	c.JSON(http.StatusOK, DeletedObject{
+	renderObject(ginOutput(c), http.StatusOK, DeletedObject{
 		Object:  objectType,
 		ID:      id,
 		Deleted: true,
 	})
 }
 
+// renderObject writes obj and runs the response hooks. A
+// VersionedResource gets its ETag, or 304 if the client has it.
+func renderObject(o output, status int, obj any) {
+	checkShape(obj)
+	if o.versionedObject(status, obj) {
+		o.w.WriteHeader(http.StatusNotModified)
+		if f, ok := o.w.(interface{ WriteHeaderNow() }); ok {
+			f.WriteHeaderNow()
+		}
+		o.notifyObject(http.StatusNotModified, obj)
+		return
+	}
+	o.json(status, obj)
+	o.notifyObject(status, obj)
+}
+
 // DeletedObject represents a Stripe-style deletion response.
 type DeletedObject struct {
 	Object  string `json:"object"`
@@ -47,8 +70,28 @@ type Message struct {
 
 // Success sends a 200 OK response with a success message.
 func Success(c *gin.Context, message string) {
-	c.JSON(http.StatusOK, Message{
+	render(c, http.StatusOK, Message{
 		Object:  "message",
 		Message: message,
 	})
 }
+
+// SoftDeleted sends a deletion confirmation for a soft-deleted resource.
+// The shape extends DeletedObject with the deletion timestamp, so clients
+// handling hard deletes keep working.
+func SoftDeleted(c *gin.Context, objectType string, id string, deletedAt time.Time) {
+	renderObject(ginOutput(c), http.StatusOK, SoftDeletedObject{
+		DeletedObject: DeletedObject{
+			Object:  objectType,
+			ID:      id,
+			Deleted: true,
+		},
+		DeletedAt: deletedAt.UTC(),
+	})
+}
+
+// SoftDeletedObject represents a soft-deletion response.
+type SoftDeletedObject struct {
+	DeletedObject
+	DeletedAt time.Time `json:"deleted_at"`
+}
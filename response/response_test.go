@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -57,6 +58,17 @@ func TestCreated(t *testing.T) {
 	}
 }
 
+func TestAccepted(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	response.Accepted(c, map[string]string{"object": "export", "id": "exp_1", "status": "pending"})
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected status 202, got %d", w.Code)
+	}
+}
+
 func TestNoContent(t *testing.T) {
 	router := gin.New()
 	router.GET("/test", func(c *gin.Context) {
@@ -97,3 +109,37 @@ func TestDeleted(t *testing.T) {
 		t.Error("expected deleted to be true")
 	}
 }
+
+func TestSoftDeleted(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	deletedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	response.SoftDeleted(c, "gallery", "gal_1", deletedAt)
+
+	want := `{"object":"gallery","id":"gal_1","deleted":true,"deleted_at":"2026-01-02T03:04:05Z"}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestObjectHead(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("HEAD", "/artists/art_1", nil)
+
+	response.Object(c, map[string]string{"object": "artist", "id": "art_1"})
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", w.Body.String())
+	}
+	if got := w.Header().Get("Content-Length"); got != "32" {
+		t.Errorf("expected Content-Length 32, got '%s'", got)
+	}
+	if w.Header().Get("X-Total-Count") != "" {
+		t.Error("expected no X-Total-Count for objects")
+	}
+}
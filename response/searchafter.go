@@ -0,0 +1,136 @@
+package response
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+// SearchAfterList is a list response paginated with search_after tokens
+// instead of offsets.
+type SearchAfterList[T any] struct {
+	Object          string `json:"object"`                      // Always "list"
+	Data            []T    `json:"data"`                        // The items
+	Limit           int    `json:"limit"`                       // Max items requested
+	HasMore         bool   `json:"has_more"`                    // More items available
+	NextSearchAfter string `json:"next_search_after,omitempty"` // Token for the next page
+}
+
+// SearchAfterResponse sends a search_after list response. lastSort holds the
+// sort values of the last hit; it is encoded as the next_search_after token
+// when hasMore is true.
+func SearchAfterResponse[T any](c *gin.Context, data []T, limit int, hasMore bool, lastSort []any) {
+	if data == nil {
+		data = []T{}
+	}
+
+	list := SearchAfterList[T]{
+		Object:  "list",
+		Data:    data,
+		Limit:   limit,
+		HasMore: hasMore,
+	}
+
+	if hasMore && len(lastSort) > 0 {
+		token, err := pagination.EncodeCursor(lastSort)
+		if err != nil {
+			InternalError(c, "failed to encode search_after token")
+			return
+		}
+		list.NextSearchAfter = token
+	}
+
+	render(c, http.StatusOK, list)
+}
+
+// KeysetList is a list response paginated with keyset cursors (see
+// pagination.BindKeyset).
+type KeysetList[T any] struct {
+	Object     string `json:"object"`                // Always "list"
+	Data       []T    `json:"data"`                  // The items
+	Limit      int    `json:"limit"`                 // Max items requested
+	HasMore    bool   `json:"has_more"`              // More items available
+	NextCursor string `json:"next_cursor,omitempty"` // Pass as ?after= for the next page
+}
+
+// KeysetResponse sends a keyset list response. lastSortKey and lastID are
+// the sort column value and ID of the last item; they are encoded as the
+// next_cursor token when hasMore is true. Fetch p.Limit+1 rows to know
+// whether there are more.
+//
+//	rows, err := repo.List(ctx, p) // p.Where and p.OrderBy, LIMIT p.Limit+1
+//	...
+//	hasMore := len(rows) > p.Limit
+//	if hasMore {
+//	    rows = rows[:p.Limit]
+//	}
+//	var lastKey, lastID any
+//	if n := len(rows); n > 0 {
+//	    lastKey, lastID = rows[n-1].CreatedAt, rows[n-1].ID
+//	}
+//	response.KeysetResponse(c, rows, p, hasMore, lastKey, lastID)
+func KeysetResponse[T any](c *gin.Context, data []T, p pagination.KeysetParams, hasMore bool, lastSortKey, lastID any) {
+	if data == nil {
+		data = []T{}
+	}
+
+	list := KeysetList[T]{
+		Object:  "list",
+		Data:    data,
+		Limit:   p.Limit,
+		HasMore: hasMore,
+	}
+
+	if hasMore && len(data) > 0 {
+		token, err := pagination.EncodeKeysetCursor(p.Sort, lastSortKey, lastID)
+		if err != nil {
+			InternalError(c, "failed to encode cursor")
+			return
+		}
+		list.NextCursor = token
+	}
+
+	render(c, http.StatusOK, list)
+}
+
+// offsetTooDeepError is the envelope of OffsetTooDeep, which carries a
+// cursor alongside the standard error fields.
+type offsetTooDeepError struct {
+	Object string `json:"object"`
+	Error  struct {
+		ErrorInfo
+		Cursor string `json:"cursor,omitempty"`
+	} `json:"error"`
+}
+
+// OffsetTooDeep sends a 400 with code offset_too_deep for an offset past
+// maxOffset. cursor, if not empty, is a keyset cursor for the same position
+// (see pagination.EncodeKeysetCursor), which the client passes as ?after=
+// to continue with cursor pagination:
+//
+//	{"object": "error", "error": {"type": "invalid_request", "code": "offset_too_deep",
+//	    "message": "offset must be at most 10000; continue with ?after=<cursor>",
+//	    "param": "offset", "cursor": "eyJrIjoi..."}}
+func OffsetTooDeep(c *gin.Context, maxOffset int, cursor string) {
+	message := "offset must be at most " + strconv.Itoa(maxOffset) + "; use cursor pagination with ?after="
+	if cursor != "" {
+		message = "offset must be at most " + strconv.Itoa(maxOffset) + "; continue with ?after=<cursor>"
+	}
+
+	var env offsetTooDeepError
+	env.Object = "error"
+	env.Error.ErrorInfo = ErrorInfo{
+		Type:    ErrorTypeInvalidRequest,
+		Code:    ErrorCodeOffsetTooDeep,
+		Message: message,
+		Param:   "offset",
+	}
+	env.Error.Cursor = cursor
+
+	o := ginOutput(c)
+	o.json(http.StatusBadRequest, env)
+	o.notify(http.StatusBadRequest, "error", "")
+}
@@ -0,0 +1,83 @@
+package response_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/pagination"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestSearchAfterResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	response.SearchAfterResponse(c, []string{"a", "b"}, 2, true, []any{99, "gal_2"})
+
+	var result response.SearchAfterList[string]
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if result.Object != "list" || !result.HasMore || len(result.Data) != 2 {
+		t.Errorf("unexpected list: %+v", result)
+	}
+
+	var values []any
+	if err := pagination.DecodeCursor(result.NextSearchAfter, &values); err != nil {
+		t.Fatalf("next_search_after not decodable: %v", err)
+	}
+	if len(values) != 2 || values[1] != "gal_2" {
+		t.Errorf("unexpected sort values: %#v", values)
+	}
+}
+
+func TestSearchAfterResponseLastPage(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	response.SearchAfterResponse[string](c, nil, 20, false, []any{1})
+
+	want := `{"object":"list","data":[],"limit":20,"has_more":false}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestKeysetResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	p := pagination.KeysetParams{Limit: 2, Sort: "-created_at"}
+	response.KeysetResponse(c, []string{"a", "b"}, p, true, int64(1700000000), "gal_2")
+
+	var result response.KeysetList[string]
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if result.Object != "list" || !result.HasMore || result.Limit != 2 || len(result.Data) != 2 {
+		t.Errorf("unexpected list: %+v", result)
+	}
+
+	cursor, err := pagination.DecodeKeysetCursor(result.NextCursor)
+	if err != nil {
+		t.Fatalf("next_cursor not decodable: %v", err)
+	}
+	if cursor.Sort != "-created_at" || cursor.SortKey != int64(1700000000) || cursor.ID != "gal_2" {
+		t.Errorf("unexpected cursor: %#v", cursor)
+	}
+}
+
+func TestKeysetResponseLastPage(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	response.KeysetResponse[string](c, nil, pagination.KeysetParams{Limit: 20}, false, nil, nil)
+
+	want := `{"object":"list","data":[],"limit":20,"has_more":false}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
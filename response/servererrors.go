@@ -0,0 +1,108 @@
+package response
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// publicServerErrors holds the messages HideServerErrorMessages allows;
+// nil when server error messages are shown as-is.
+var publicServerErrors atomic.Pointer[map[string]bool]
+
+// HideServerErrorMessages stops InternalError, BadGateway,
+// ServiceUnavailable, and NotImplemented from echoing their message to
+// clients unless it is one of allowed, so a careless
+// InternalError(c, err.Error()) can't leak a SQL error:
+//
+//	response.HideServerErrorMessages("search is temporarily disabled")
+//
+// Other messages are replaced with the status text and an error ID, which
+// is also in the error's details and in the Report sent to the Reporter,
+// along with the original message:
+//
+//	{"object":"error","error":{"type":"api","message":"internal server error (error ID req_123)","details":{"error_id":"req_123"}}}
+//
+// The error ID is the request's X-Request-ID when it has one. Hidden
+// messages of BadGateway, ServiceUnavailable, and NotImplemented are
+// reported too, since they would be lost otherwise. Errors sent with
+// ErrorWithInfo are left alone. Call it once at startup; calling it again
+// replaces the allowed messages.
+func HideServerErrorMessages(allowed ...string) {
+	m := make(map[string]bool, len(allowed))
+	for _, msg := range allowed {
+		m[msg] = true
+	}
+	publicServerErrors.Store(&m)
+}
+
+// ShowServerErrorMessages undoes HideServerErrorMessages.
+func ShowServerErrorMessages() {
+	publicServerErrors.Store(nil)
+}
+
+// publicServerMessage returns the message to send for a server error, and
+// the error ID it was replaced with, or "" if it can be sent as-is.
+func publicServerMessage(c *gin.Context, status int, message string) (string, string) {
+	allowed := publicServerErrors.Load()
+	if allowed == nil || (*allowed)[message] {
+		return message, ""
+	}
+	id := errorID(c)
+	return strings.ToLower(http.StatusText(status)) + " (error ID " + id + ")", id
+}
+
+// sendServerError sends a 5xx error with message, hiding and reporting it
+// unless it may be shown.
+func sendServerError(c *gin.Context, status int, message string) {
+	public, id := publicServerMessage(c, status, message)
+	if id == "" {
+		sendError(c, status, ErrorTypeAPI, "", message, "")
+		return
+	}
+	reportError(c, c.Request, c.FullPath(), errors.New(message), id)
+	sendHiddenServerError(c, status, public, id)
+}
+
+// sendHiddenServerError sends a 5xx error whose message was replaced.
+func sendHiddenServerError(c *gin.Context, status int, public, id string) {
+	ginOutput(c).error(status, ErrorInfo{
+		Type:    ErrorTypeAPI,
+		Message: public,
+		Details: map[string]any{"error_id": id},
+	})
+}
+
+var exposeStacks atomic.Bool
+
+// SetExposeStacks makes middleware.Recovery include the panic and its stack
+// in the details of its 500s, for development. Never enable it in
+// production: stacks reveal source paths and dependencies. Call it once at
+// startup.
+func SetExposeStacks(expose bool) {
+	exposeStacks.Store(expose)
+}
+
+// StacksExposed reports whether SetExposeStacks enabled stacks in error
+// responses.
+func StacksExposed() bool {
+	return exposeStacks.Load()
+}
+
+// errorID returns the request's X-Request-ID, or a new random ID.
+func errorID(c *gin.Context) string {
+	if id := c.Writer.Header().Get("X-Request-ID"); id != "" {
+		return id
+	}
+	if id := c.GetHeader("X-Request-ID"); id != "" {
+		return id
+	}
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "err_" + hex.EncodeToString(b)
+}
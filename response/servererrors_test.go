@@ -0,0 +1,94 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestHideServerErrorMessages(t *testing.T) {
+	reports := captureReports(t)
+	response.HideServerErrorMessages("search is temporarily disabled")
+	defer response.ShowServerErrorMessages()
+
+	router := gin.New()
+	router.GET("/internal", func(c *gin.Context) {
+		response.InternalError(c, `pq: relation "galleries" does not exist`)
+	})
+	router.GET("/gateway", func(c *gin.Context) {
+		response.BadGateway(c, "dial tcp 10.0.3.7:5432: connection refused")
+	})
+	router.GET("/allowed", func(c *gin.Context) {
+		response.ServiceUnavailable(c, "search is temporarily disabled")
+	})
+
+	tests := []struct {
+		name        string
+		path        string
+		requestID   string
+		wantStatus  int
+		wantMessage string
+		wantErrorID string
+		wantReports int
+	}{
+		{"internal error hidden", "/internal", "req-1", http.StatusInternalServerError, "internal server error (error ID req-1)", "req-1", 1},
+		{"bad gateway hidden and reported", "/gateway", "req-2", http.StatusBadGateway, "bad gateway (error ID req-2)", "req-2", 1},
+		{"generated error ID", "/internal", "", http.StatusInternalServerError, "internal server error (error ID err_", "err_", 1},
+		{"allowed message", "/allowed", "req-3", http.StatusServiceUnavailable, "search is temporarily disabled", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*reports = nil
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-ID", tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			e, err := response.ParseError(w.Body.Bytes())
+			if err != nil {
+				t.Fatalf("expected an error body, got %s", w.Body.String())
+			}
+			if !strings.HasPrefix(e.Error.Message, tt.wantMessage) {
+				t.Errorf("expected message %q, got %q", tt.wantMessage, e.Error.Message)
+			}
+			errorID, _ := e.Error.Details["error_id"].(string)
+			if !strings.HasPrefix(errorID, tt.wantErrorID) || (tt.wantErrorID == "") != (errorID == "") {
+				t.Errorf("expected error ID %q, got %q", tt.wantErrorID, errorID)
+			}
+			if len(*reports) != tt.wantReports {
+				t.Fatalf("expected %d reports, got %d", tt.wantReports, len(*reports))
+			}
+			if tt.wantReports > 0 {
+				r := (*reports)[0]
+				if r.ErrorID != errorID {
+					t.Errorf("expected report error ID %q, got %q", errorID, r.ErrorID)
+				}
+				if strings.Contains(w.Body.String(), r.Err.Error()) {
+					t.Errorf("expected the message %q hidden, got %s", r.Err, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestServerErrorMessagesShownByDefault(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	response.BadGateway(c, "upstream timed out")
+
+	if !strings.Contains(w.Body.String(), "upstream timed out") {
+		t.Errorf("expected the message, got %s", w.Body.String())
+	}
+}
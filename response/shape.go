@@ -0,0 +1,245 @@
+package response
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ShapeViolation is a response type breaking the API's JSON conventions.
+type ShapeViolation struct {
+	// Type is the Go type sent, e.g. "api.Gallery"
+	Type string
+	// Field is the JSON path of the offending field, e.g. "author.displayName"
+	// or "data[].coverURL"; empty for the type itself
+	Field string
+	// Problem explains the violation
+	Problem string
+}
+
+// String formats a violation for messages.
+func (v ShapeViolation) String() string {
+	if v.Field == "" {
+		return v.Type + ": " + v.Problem
+	}
+	return fmt.Sprintf("%s: %s %s", v.Type, v.Field, v.Problem)
+}
+
+var shapeCheck atomic.Bool
+
+// SetShapeCheck makes the object and list helpers check the types they
+// send against our JSON conventions, logging each offending type once at
+// warn level: JSON names are snake_case, every field has a json tag, and
+// objects (including list items) have an "object" field. The result is
+// cached per type, but it is still meant for development (see
+// ginapi.Development); call it once at startup. The shapecheck analyzer
+// finds the same problems at build time.
+func SetShapeCheck(check bool) {
+	shapeCheck.Store(check)
+}
+
+var (
+	// shapes caches the violations of each type checked
+	shapes sync.Map // reflect.Type -> []ShapeViolation
+	// reportedShapes are the types already logged
+	reportedShapes sync.Map // reflect.Type -> struct{}
+)
+
+// checkShape logs the violations of v's type once, if SetShapeCheck is on.
+func checkShape(v any) {
+	if !shapeCheck.Load() {
+		return
+	}
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return
+	}
+	if _, reported := reportedShapes.LoadOrStore(t, struct{}{}); reported {
+		return
+	}
+	for _, violation := range shapeViolations(t) {
+		slog.Warn("response: "+violation.String(), "type", violation.Type, "field", violation.Field)
+	}
+}
+
+// ShapeViolations returns the convention violations of v's type, as
+// SetShapeCheck reports them. Use it in tests to check response types
+// without sending them:
+//
+//	for _, v := range response.ShapeViolations(api.Gallery{}) {
+//	    t.Error(v)
+//	}
+//
+// Only static types are checked: the values of interface fields and maps
+// of interfaces, such as gin.H, are not. Types implementing json.Marshaler
+// are skipped.
+func ShapeViolations(v any) []ShapeViolation {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil
+	}
+	return shapeViolations(t)
+}
+
+// shapeViolations is ShapeViolations for t, cached.
+func shapeViolations(t reflect.Type) []ShapeViolation {
+	if cached, ok := shapes.Load(t); ok {
+		return cached.([]ShapeViolation)
+	}
+	w := shapeWalker{typ: t.String(), seen: map[reflect.Type]bool{}}
+	w.object(t, "")
+	shapes.Store(t, w.violations)
+	return w.violations
+}
+
+// snakeCase matches the JSON names we accept.
+var snakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+var (
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// shapeWalker collects the violations of one type.
+type shapeWalker struct {
+	typ        string
+	seen       map[reflect.Type]bool
+	violations []ShapeViolation
+}
+
+// object checks t, sent at path as an API object: a struct with an
+// "object" field, whose "data" items are objects too.
+func (w *shapeWalker) object(t reflect.Type, path string) {
+	t = indirectType(t)
+	if t.Kind() != reflect.Struct || customJSON(t) {
+		w.value(t, path)
+		return
+	}
+	fields := jsonFields(t)
+	if _, ok := fields["object"]; !ok {
+		w.add(path, `has no "object" field`)
+	}
+	if data, ok := fields["data"]; ok {
+		if d := indirectType(data); d.Kind() == reflect.Slice || d.Kind() == reflect.Array {
+			if item := indirectType(d.Elem()); item.Kind() == reflect.Struct && !customJSON(item) {
+				if _, ok := jsonFields(item)["object"]; !ok {
+					w.add(shapePath(path, "data[]"), `has no "object" field`)
+				}
+			}
+		}
+	}
+	w.value(t, path)
+}
+
+// value checks the JSON names within t, sent at path.
+func (w *shapeWalker) value(t reflect.Type, path string) {
+	t = indirectType(t)
+	if customJSON(t) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		w.value(t.Elem(), path+"[]")
+	case reflect.Map:
+		w.value(t.Elem(), shapePath(path, "*"))
+	case reflect.Struct:
+		if w.seen[t] {
+			return
+		}
+		w.seen[t] = true
+		w.fields(t, path)
+	}
+}
+
+// fields checks the fields of struct t, sent at path.
+func (w *shapeWalker) fields(t reflect.Type, path string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, hasTag := f.Tag.Lookup("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" && tag == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			// Embedded structs are inlined, like encoding/json does
+			if et := indirectType(f.Type); et.Kind() == reflect.Struct {
+				w.fields(et, path)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		switch {
+		case name == "":
+			name = f.Name
+			if !hasTag {
+				w.add(shapePath(path, name), "has no json tag")
+			} else {
+				w.add(shapePath(path, name), "has a json tag without a name")
+			}
+		case !snakeCase.MatchString(name):
+			w.add(shapePath(path, name), "isn't snake_case")
+		}
+		w.value(f.Type, shapePath(path, name))
+	}
+}
+
+// add records a violation at path.
+func (w *shapeWalker) add(path, problem string) {
+	w.violations = append(w.violations, ShapeViolation{Type: w.typ, Field: path, Problem: problem})
+}
+
+// jsonFields returns the fields of struct t by JSON name, including those
+// of embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" {
+			if et := indirectType(f.Type); et.Kind() == reflect.Struct {
+				for n, ft := range jsonFields(et) {
+					fields[n] = ft
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if f.IsExported() && name != "-" {
+			fields[name] = f.Type
+		}
+	}
+	return fields
+}
+
+// customJSON reports whether t encodes itself.
+func customJSON(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return t.Implements(jsonMarshaler) || pt.Implements(jsonMarshaler) ||
+		t.Implements(textMarshaler) || pt.Implements(textMarshaler)
+}
+
+// indirectType returns the type pointers to t point to.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// shapePath appends name to the JSON path.
+func shapePath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
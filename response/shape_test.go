@@ -0,0 +1,114 @@
+package response_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+type shapeAuthor struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type shapeGallery struct {
+	Object    string            `json:"object"`
+	ID        string            `json:"id"`
+	CoverURL  string            `json:"coverURL"`
+	PageCount int               // no tag
+	Author    *shapeAuthor      `json:"author"`
+	Tags      map[string]string `json:"tags"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	internal  string
+	Ignored   string `json:"-"`
+}
+
+type shapeBase struct {
+	Object string `json:"object"`
+	ID     string `json:"id"`
+}
+
+type shapeTag struct {
+	shapeBase
+	Name string `json:"name,omitempty"`
+}
+
+type shapeRow struct {
+	Name string `json:"name"`
+}
+
+func TestShapeViolations(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want []string
+	}{
+		{"conventional", shapeTag{}, nil},
+		{"pointer", &shapeTag{}, nil},
+		{"violations", shapeGallery{}, []string{
+			"response_test.shapeGallery: coverURL isn't snake_case",
+			"response_test.shapeGallery: PageCount has no json tag",
+			"response_test.shapeGallery: author.displayName isn't snake_case",
+		}},
+		{"no object field", shapeRow{}, []string{
+			`response_test.shapeRow: has no "object" field`,
+		}},
+		{"list items without an object field", response.NewList([]shapeRow{}, 0, 10, 0), []string{
+			`response.List[github.com/doujins-org/ginapi/response_test.shapeRow]: data[] has no "object" field`,
+		}},
+		{"list", response.NewList([]shapeTag{}, 0, 10, 0), nil},
+		{"maps aren't checked", gin.H{"Object": "x"}, nil},
+		{"deleted", response.DeletedObject{}, nil},
+		{"error", response.Error{}, nil},
+		{"partial", response.PartialResponse{}, nil},
+		{"redirect", response.RedirectObject{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range response.ShapeViolations(tt.v) {
+				got = append(got, v.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSetShapeCheck(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	response.SetShapeCheck(true)
+	defer response.SetShapeCheck(false)
+
+	type row struct {
+		Object   string `json:"object"`
+		ThumbURL string `json:"thumbURL"`
+	}
+	router := gin.New()
+	router.GET("/rows", func(c *gin.Context) {
+		response.ListResponse(c, []row{{Object: "row"}}, 1, 10, 0)
+	})
+	for range 2 {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rows", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+	}
+
+	if n := strings.Count(logs.String(), "data[].thumbURL isn't snake_case"); n != 1 {
+		t.Errorf("expected the violation logged once, got %d times: %s", n, logs.String())
+	}
+}
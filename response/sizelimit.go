@@ -0,0 +1,120 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SizePolicy is what happens to a response over the size limit.
+type SizePolicy int
+
+const (
+	// SizeReject replaces the response with a 500 response_too_large.
+	SizeReject SizePolicy = iota
+	// SizeTruncate cuts lists to the most full items that fit, with
+	// has_more set and a Warning header. Other responses are rejected.
+	SizeTruncate
+)
+
+// sizeLimit is the resolved size limit of a request; the zero value
+// means unlimited.
+type sizeLimit struct {
+	maxBytes int
+	policy   SizePolicy
+}
+
+// sizeLimitKey is the gin context key for the response size limit.
+const sizeLimitKey = "ginapi.size_limit"
+
+// LimitResponseSize returns middleware that caps the serialized size of
+// responses from the helpers in the routes below it, so a runaway limit
+// can't produce a response big enough to take down the load balancer:
+//
+//	api.Use(response.LimitResponseSize(8<<20, response.SizeTruncate))
+//
+// Oversized responses are reported (see SetReporter) either way. With
+// SizeTruncate, a truncated list also gets its limit lowered to the number
+// of items sent, so offset+limit (and the next Link) continues where the
+// page stopped. Truncation re-encodes the list to find the cut, so it costs
+// a few extra encodes on the rare oversized response.
+func LimitResponseSize(maxBytes int, policy SizePolicy) gin.HandlerFunc {
+	if maxBytes <= 0 {
+		panic("response: LimitResponseSize requires maxBytes > 0")
+	}
+	limit := sizeLimit{maxBytes: maxBytes, policy: policy}
+	return func(c *gin.Context) {
+		c.Set(sizeLimitKey, limit)
+		c.Next()
+	}
+}
+
+// ginSizeLimit returns the limit from LimitResponseSize, falling back to
+// WithResponseSizeLimit on the request context.
+func ginSizeLimit(c *gin.Context) sizeLimit {
+	if v, ok := c.Get(sizeLimitKey); ok {
+		if limit, ok := v.(sizeLimit); ok {
+			return limit
+		}
+	}
+	if c.Request != nil {
+		return sizeLimitFromContext(c.Request.Context())
+	}
+	return sizeLimit{}
+}
+
+// sizeLimitContextKey is the request context key for the response size limit.
+type sizeLimitContextKey struct{}
+
+// WithResponseSizeLimit returns a copy of ctx with a response size limit.
+// This is the net/http equivalent of LimitResponseSize.
+func WithResponseSizeLimit(ctx context.Context, maxBytes int, policy SizePolicy) context.Context {
+	return context.WithValue(ctx, sizeLimitContextKey{}, sizeLimit{maxBytes: maxBytes, policy: policy})
+}
+
+func sizeLimitFromContext(ctx context.Context) sizeLimit {
+	if ctx == nil {
+		return sizeLimit{}
+	}
+	limit, _ := ctx.Value(sizeLimitContextKey{}).(sizeLimit)
+	return limit
+}
+
+// tooLarge reports an oversized body and returns the error that replaces it.
+func (o output) tooLarge(size int) (int, string, []byte) {
+	ReportError(o.ctx, o.r, "", fmt.Errorf("response of %d bytes exceeds the %d byte limit", size, o.sizeLimit.maxBytes))
+	body, _ := json.Marshal(Error{
+		Object: "error",
+		Error:  ErrorInfo{Type: ErrorTypeAPI, Code: ErrorCodeResponseTooLarge, Message: "response is too large"},
+	})
+	return http.StatusInternalServerError, "application/json; charset=utf-8", body
+}
+
+// truncateList cuts list to the most items whose encoding fits the size limit.
+func truncateList[T any](o output, list List[T]) List[T] {
+	unlimited := o
+	unlimited.sizeLimit = sizeLimit{}
+	fits := func(n int) bool {
+		l := list
+		l.Data = list.Data[:n]
+		_, _, body := unlimited.encode(http.StatusOK, listPayload(o.mode, l))
+		return len(body) <= o.sizeLimit.maxBytes
+	}
+
+	if fits(len(list.Data)) {
+		return list
+	}
+	n := sort.Search(len(list.Data), func(n int) bool { return !fits(n) }) - 1
+	ReportError(o.ctx, o.r, "", fmt.Errorf("list truncated from %d to %d items to fit the %d byte response limit",
+		len(list.Data), max(n, 0), o.sizeLimit.maxBytes))
+
+	list.Data = list.Data[:max(n, 0)]
+	list.Limit = len(list.Data)
+	list.HasMore = true
+	o.w.Header().Set("Warning", fmt.Sprintf(`299 - "response truncated to %d items to fit the size limit"`, len(list.Data)))
+	return list
+}
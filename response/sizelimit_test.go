@@ -0,0 +1,107 @@
+package response_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+type sizedItem struct {
+	Object string `json:"object"`
+	ID     int    `json:"id"`
+	Body   string `json:"body"`
+}
+
+func sizedItems(n int) []sizedItem {
+	items := make([]sizedItem, n)
+	for i := range items {
+		items[i] = sizedItem{Object: "item", ID: i, Body: strings.Repeat("x", 100)}
+	}
+	return items
+}
+
+func TestLimitResponseSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     response.SizePolicy
+		mode       response.PaginationMode
+		items      int
+		object     bool
+		wantStatus int
+		wantItems  int
+	}{
+		{name: "under limit", policy: response.SizeReject, items: 3, wantStatus: http.StatusOK, wantItems: 3},
+		{name: "reject list", policy: response.SizeReject, items: 50, wantStatus: http.StatusInternalServerError},
+		{name: "reject object", policy: response.SizeTruncate, object: true, wantStatus: http.StatusInternalServerError},
+		{name: "truncate list", policy: response.SizeTruncate, items: 50, wantStatus: http.StatusOK, wantItems: 7},
+		{name: "truncate bare array", policy: response.SizeTruncate, mode: response.PaginationHeaders, items: 50, wantStatus: http.StatusOK, wantItems: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(response.UsePaginationMode(tt.mode), response.LimitResponseSize(1024, tt.policy))
+			router.GET("/test", func(c *gin.Context) {
+				if tt.object {
+					response.Object(c, sizedItem{Object: "item", Body: strings.Repeat("x", 2048)})
+					return
+				}
+				response.ListResponse(c, sizedItems(tt.items), 100, tt.items, 0)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if w.Body.Len() > 1024 {
+				t.Errorf("expected at most 1024 bytes, got %d", w.Body.Len())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if !strings.Contains(w.Body.String(), `"response_too_large"`) {
+					t.Errorf("expected response_too_large, got %s", w.Body.String())
+				}
+				return
+			}
+
+			truncated := tt.wantItems < tt.items
+			if got := w.Header().Get("Warning") != ""; got != truncated {
+				t.Errorf("expected Warning header %v, got %q", truncated, w.Header().Get("Warning"))
+			}
+
+			if tt.mode == response.PaginationHeaders {
+				var items []sizedItem
+				if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+					t.Fatalf("invalid JSON: %v", err)
+				}
+				if len(items) != tt.wantItems {
+					t.Errorf("expected %d items, got %d", tt.wantItems, len(items))
+				}
+				if got := w.Header().Get("X-Limit"); got != strconv.Itoa(tt.wantItems) {
+					t.Errorf("expected X-Limit %d, got %q", tt.wantItems, got)
+				}
+				return
+			}
+
+			var list response.List[sizedItem]
+			if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if len(list.Data) != tt.wantItems {
+				t.Errorf("expected %d items, got %d", tt.wantItems, len(list.Data))
+			}
+			if truncated && (!list.HasMore || list.Limit != tt.wantItems) {
+				t.Errorf("expected has_more and limit %d, got %v and %d", tt.wantItems, list.HasMore, list.Limit)
+			}
+		})
+	}
+}
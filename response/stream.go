@@ -0,0 +1,200 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+// streamChunkSize is how much encoded output StreamList buffers before
+// flushing it to the client.
+const streamChunkSize = 32 << 10
+
+// StreamList sends a standard list envelope, encoding and flushing items
+// as the iterator yields them, so huge result sets (exports, sync dumps)
+// reach clients that expect the List shape without being buffered:
+//
+//	rows := repo.IterGalleries(ctx, filter) // iter.Seq2[Gallery, error]
+//	response.StreamList(c, rows, pagination.Params{Limit: 50000}, nil)
+//
+// It reads up to params.Limit+1 items (0 for no limit); the extra item only
+// sets has_more. The envelope keeps its usual field order, with data
+// before total, so the total is computed at the end: by counter, or when
+// nil, as offset plus the items sent (an estimate when has_more is set).
+// Each item is redacted, intercepted, and version-downgraded like in
+// ListResponse.
+//
+// An error from the iterator before the first flush is reported and sent
+// as a 500. After that the status is already sent, so the error is
+// reported and the body left unterminated, which clients see as invalid
+// JSON rather than a silently short list. A response size limit ends the
+// list early with has_more set. JSON:API, MessagePack, header pagination,
+// debug output, and HEAD need the whole body, so those requests are
+// buffered and sent as ListWithTotal would.
+func StreamList[T any](c *gin.Context, items iter.Seq2[T, error], params pagination.Params, counter pagination.Counter) {
+	writeStreamList(ginOutput(c), items, params, counter)
+}
+
+// WriteStreamList is the net/http equivalent of StreamList.
+func WriteStreamList[T any](w http.ResponseWriter, r *http.Request, items iter.Seq2[T, error], params pagination.Params, counter pagination.Counter) {
+	writeStreamList(httpOutput(w, r), items, params, counter)
+}
+
+// streamTail is the part of the envelope sent after the items.
+type streamTail struct {
+	Total           int64 `json:"total"`
+	Limit           int   `json:"limit"`
+	Offset          int   `json:"offset"`
+	HasMore         bool  `json:"has_more"`
+	TotalIsEstimate bool  `json:"total_is_estimate,omitempty"`
+}
+
+// writeStreamList is the core behind StreamList and WriteStreamList.
+func writeStreamList[T any](o output, items iter.Seq2[T, error], params pagination.Params, counter pagination.Counter) {
+	if !o.canStream() {
+		bufferStreamList(o, items, params, counter)
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"object":"list","data":[`)
+	started, flushed := false, 0
+	flush := func() {
+		if !started {
+			o.w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			o.w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		n, _ := o.w.Write(buf.Bytes())
+		flushed += n
+		buf.Reset()
+		if f, ok := o.w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	fail := func(err error) {
+		ReportError(o.ctx, o.r, "", err)
+		if !started {
+			o.error(http.StatusInternalServerError, countFailed)
+		}
+	}
+
+	sent, hasMore := 0, false
+	for item, err := range items {
+		if err != nil {
+			fail(err)
+			return
+		}
+		if params.Limit > 0 && sent == params.Limit {
+			hasMore = true
+			break
+		}
+		b, err := o.encodeItem(item)
+		if err != nil {
+			fail(err)
+			return
+		}
+		if limit := o.sizeLimit.maxBytes; limit > 0 && flushed+buf.Len()+len(b)+len(streamTailReserve) > limit {
+			ReportError(o.ctx, o.r, "", fmt.Errorf("streamed list ended after %d items to fit the %d byte response limit", sent, limit))
+			hasMore = true
+			break
+		}
+		if sent > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(b)
+		sent++
+		if buf.Len() >= streamChunkSize {
+			flush()
+		}
+	}
+
+	total := streamTotal(o, counter, params, sent, hasMore)
+	tail, _ := json.Marshal(streamTail{
+		Total:           total.Value,
+		Limit:           params.Limit,
+		Offset:          params.Offset,
+		HasMore:         hasMore,
+		TotalIsEstimate: total.Estimate,
+	})
+	if len(o.warnings) > 0 {
+		tail = appendWarnings(tail, o.warnings)
+	}
+	tail = o.convertKeys(tail)
+	buf.WriteString("],")
+	buf.Write(tail[1:])
+	flush()
+}
+
+// streamTailReserve approximates the largest tail, so a size limit leaves
+// room to close the envelope.
+const streamTailReserve = `],"total":-9223372036854775808,"limit":-9223372036854775808,"offset":-9223372036854775808,"has_more":false,"total_is_estimate":true}`
+
+// canStream reports whether the output can be written incrementally.
+func (o output) canStream() bool {
+	if o.r != nil && o.r.Method == http.MethodHead {
+		return false
+	}
+	if _, msgpack := o.wantsMsgpack(); msgpack {
+		return false
+	}
+	return !o.jsonAPI && o.debug == nil && o.mode == PaginationBody
+}
+
+// encodeItem encodes one list item the way it is encoded inside a list.
+func (o output) encodeItem(item any) ([]byte, error) {
+	b, err := json.Marshal(redact(item, o.audiences))
+	if err == nil && len(o.interceptors) > 0 {
+		b, err = o.intercept(b)
+	}
+	if err == nil && o.version != nil && len(o.version.changes) > 0 {
+		b, err = o.version.downgrade(b)
+	}
+	if err == nil {
+		b = o.convertKeys(b)
+	}
+	return b, err
+}
+
+// streamTotal computes the total of a streamed list.
+func streamTotal(o output, counter pagination.Counter, params pagination.Params, sent int, hasMore bool) pagination.Total {
+	if counter != nil {
+		total, err := counter.Count(o.ctx)
+		if err == nil {
+			return total
+		}
+		ReportError(o.ctx, o.r, "", err)
+	}
+	n := int64(params.Offset + sent)
+	if hasMore {
+		return pagination.Total{Value: n + 1, Estimate: true}
+	}
+	return pagination.Total{Value: n, Estimate: counter != nil}
+}
+
+// bufferStreamList collects the items and sends them as ListWithTotal.
+func bufferStreamList[T any](o output, items iter.Seq2[T, error], params pagination.Params, counter pagination.Counter) {
+	data := []T{}
+	hasMore := false
+	for item, err := range items {
+		if err != nil {
+			ReportError(o.ctx, o.r, "", err)
+			o.error(http.StatusInternalServerError, countFailed)
+			return
+		}
+		if params.Limit > 0 && len(data) == params.Limit {
+			hasMore = true
+			break
+		}
+		data = append(data, item)
+	}
+	list := newListWithTotal(data, streamTotal(o, counter, params, len(data), hasMore), params)
+	list.HasMore = hasMore
+	writeList(o, http.StatusOK, list)
+}
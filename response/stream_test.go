@@ -0,0 +1,178 @@
+package response_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/pagination"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// countTo yields 0..n-1, then err if set.
+func countTo(n int, err error) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		for i := range n {
+			if !yield(i, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(0, err)
+		}
+	}
+}
+
+func TestStreamList(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   iter.Seq2[int, error]
+		params  pagination.Params
+		counter pagination.Counter
+		mode    response.PaginationMode
+		want    string
+	}{
+		{
+			name:   "last page",
+			items:  countTo(3, nil),
+			params: pagination.Params{Limit: 5},
+			want:   `{"object":"list","data":[0,1,2],"total":3,"limit":5,"offset":0,"has_more":false}`,
+		},
+		{
+			name:   "more items",
+			items:  countTo(10, nil),
+			params: pagination.Params{Limit: 2, Offset: 4},
+			want:   `{"object":"list","data":[0,1],"total":7,"limit":2,"offset":4,"has_more":true,"total_is_estimate":true}`,
+		},
+		{
+			name:   "no limit",
+			items:  countTo(4, nil),
+			params: pagination.Params{},
+			want:   `{"object":"list","data":[0,1,2,3],"total":4,"limit":0,"offset":0,"has_more":false}`,
+		},
+		{
+			name:   "empty",
+			items:  countTo(0, nil),
+			params: pagination.Params{Limit: 5},
+			want:   `{"object":"list","data":[],"total":0,"limit":5,"offset":0,"has_more":false}`,
+		},
+		{
+			name:    "counter",
+			items:   countTo(10, nil),
+			params:  pagination.Params{Limit: 2},
+			counter: pagination.CountFunc(func(context.Context) (int64, error) { return 10, nil }),
+			want:    `{"object":"list","data":[0,1],"total":10,"limit":2,"offset":0,"has_more":true}`,
+		},
+		{
+			name:   "buffered for header pagination",
+			items:  countTo(10, nil),
+			params: pagination.Params{Limit: 2},
+			mode:   response.PaginationHeaders,
+			want:   `[0,1]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(response.UsePaginationMode(tt.mode))
+			router.GET("/galleries", func(c *gin.Context) {
+				response.StreamList(c, tt.items, tt.params, tt.counter)
+			})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/galleries", nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStreamListMatchesList(t *testing.T) {
+	items := sizedItems(3)
+	seq := func(yield func(sizedItem, error) bool) {
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+
+	streamed := httptest.NewRecorder()
+	response.WriteStreamList(streamed, httptest.NewRequest(http.MethodGet, "/", nil), seq, pagination.Params{Limit: 3}, nil)
+	buffered := httptest.NewRecorder()
+	response.WriteList(buffered, httptest.NewRequest(http.MethodGet, "/", nil), items, 3, 3, 0)
+
+	if streamed.Body.String() != strings.TrimSpace(buffered.Body.String()) {
+		t.Errorf("expected %s, got %s", buffered.Body.String(), streamed.Body.String())
+	}
+}
+
+func TestStreamListErrors(t *testing.T) {
+	t.Run("before first flush", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		response.WriteStreamList(w, httptest.NewRequest(http.MethodGet, "/", nil), countTo(2, errors.New("db down")), pagination.Params{Limit: 5}, nil)
+
+		if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"message":"internal error"`) {
+			t.Errorf("expected 500 internal error, got %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("after first flush", func(t *testing.T) {
+		big := func(yield func(string, error) bool) {
+			for range 100 {
+				if !yield(strings.Repeat("x", 1024), nil) {
+					return
+				}
+			}
+			yield("", errors.New("db down"))
+		}
+		w := httptest.NewRecorder()
+		response.WriteStreamList(w, httptest.NewRequest(http.MethodGet, "/", nil), big, pagination.Params{}, nil)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+		if json.Valid(w.Body.Bytes()) {
+			t.Error("expected an unterminated body")
+		}
+	})
+}
+
+func TestStreamListSizeLimit(t *testing.T) {
+	router := gin.New()
+	router.Use(response.LimitResponseSize(1024, response.SizeTruncate))
+	router.GET("/test", func(c *gin.Context) {
+		response.StreamList(c, func(yield func(sizedItem, error) bool) {
+			for _, item := range sizedItems(50) {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}, pagination.Params{Limit: 50}, nil)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	if w.Body.Len() > 1024 {
+		t.Errorf("expected at most 1024 bytes, got %d", w.Body.Len())
+	}
+	var list response.List[sizedItem]
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	if !list.HasMore || len(list.Data) == 0 || len(list.Data) == 50 {
+		t.Errorf("expected a partial list with has_more, got %d items, has_more %v", len(list.Data), list.HasMore)
+	}
+}
@@ -0,0 +1,58 @@
+package response
+
+import (
+	"net/http"
+	"strings"
+)
+
+// AddVary adds header names to the Vary header, merging with the names
+// already there instead of overwriting them. Middleware that changes the
+// response based on a request header (Language on Accept-Language and Cookie,
+// compression on Accept-Encoding, negotiation on Accept) must declare it, or
+// caches will serve one variant to every client.
+func AddVary(h http.Header, names ...string) {
+	mergeHeaderNames(h, "Vary", names)
+}
+
+// AddExposeHeaders adds header names to Access-Control-Expose-Headers,
+// merging with the names already there, so browsers let scripts read them
+// on cross-origin responses.
+func AddExposeHeaders(h http.Header, names ...string) {
+	mergeHeaderNames(h, "Access-Control-Expose-Headers", names)
+}
+
+// mergeHeaderNames adds names to the comma-separated list of header names
+// in h[key], skipping those already there in any case.
+func mergeHeaderNames(h http.Header, key string, names []string) {
+	var merged []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return
+		}
+		canonical := http.CanonicalHeaderKey(name)
+		if seen[canonical] {
+			return
+		}
+		seen[canonical] = true
+		merged = append(merged, name)
+	}
+
+	for _, line := range h.Values(key) {
+		for _, name := range strings.Split(line, ",") {
+			add(name)
+		}
+	}
+	for _, name := range names {
+		add(name)
+	}
+
+	// "*" already means every header
+	if seen["*"] {
+		merged = []string{"*"}
+	}
+	if len(merged) > 0 {
+		h.Set(key, strings.Join(merged, ", "))
+	}
+}
@@ -0,0 +1,52 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestAddVary(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		add      []string
+		want     string
+	}{
+		{name: "empty", add: []string{"Accept"}, want: "Accept"},
+		{name: "appends", existing: []string{"Accept-Encoding"}, add: []string{"Accept-Language", "Cookie"}, want: "Accept-Encoding, Accept-Language, Cookie"},
+		{name: "dedupes case-insensitively", existing: []string{"accept-language, Cookie"}, add: []string{"Accept-Language"}, want: "accept-language, Cookie"},
+		{name: "merges multiple lines", existing: []string{"Accept", "Origin"}, add: []string{"Accept"}, want: "Accept, Origin"},
+		{name: "star wins", existing: []string{"*"}, add: []string{"Accept"}, want: "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for _, v := range tt.existing {
+				h.Add("Vary", v)
+			}
+			response.AddVary(h, tt.add...)
+			if got := h.Values("Vary"); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("expected Vary '%s', got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNegotiatedResponseVaries(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/galleries/gal_1", nil)
+	c.Writer.Header().Set("Vary", "Accept-Language")
+
+	response.Object(c, map[string]any{"object": "gallery", "id": "gal_1"})
+
+	if got := w.Header().Get("Vary"); got != "Accept-Language, Accept" {
+		t.Errorf("expected merged Vary, got '%s'", got)
+	}
+}
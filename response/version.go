@@ -0,0 +1,297 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultVersionHeader is the header clients pin their API version with.
+const DefaultVersionHeader = "API-Version"
+
+// VersionChange is one backwards-incompatible change to the response shape.
+// Handlers always produce the latest shape; Downgrade turns it back into
+// the shape before Version for clients pinned to an older version.
+type VersionChange struct {
+	// Version is the version that introduced the change
+	Version string
+	// Object limits the change to objects with this "object" type, wherever
+	// they appear (top level, list items, nested). Empty applies it to the
+	// top-level objects only, like an Interceptor.
+	Object string
+	// Description documents the change, e.g. for a changelog endpoint
+	Description string
+	// Downgrade rewrites an object in its JSON form, in place
+	Downgrade func(obj map[string]any)
+}
+
+// VersionConfig configures response versioning.
+type VersionConfig struct {
+	// Versions are the API versions, oldest to newest (required)
+	Versions []string
+	// Header carrying the client's version (defaults to "API-Version")
+	Header string
+	// Pinned, if set, returns the version a client is pinned to when it
+	// sends no header, e.g. from its account. Empty means Default.
+	Pinned func(c *gin.Context) string
+	// Default is the version of unpinned requests without the header
+	// (defaults to the latest)
+	Default string
+	// Changes are the response changes between versions
+	Changes []VersionChange
+}
+
+// versionKey is the gin context key for the negotiated version.
+const versionKey = "ginapi.version"
+
+// versionPlan is the negotiated version of a request and the changes
+// that downgrade responses to it, newest first.
+type versionPlan struct {
+	version string
+	changes []VersionChange
+}
+
+// versioning is a normalized VersionConfig.
+type versioning struct {
+	cfg   VersionConfig
+	index map[string]int
+}
+
+// newVersioning validates cfg and applies defaults. It panics on invalid
+// configs, which are programming errors.
+func newVersioning(cfg VersionConfig) *versioning {
+	if len(cfg.Versions) == 0 {
+		panic("response: VersionConfig.Versions is required")
+	}
+	if cfg.Header == "" {
+		cfg.Header = DefaultVersionHeader
+	}
+	if cfg.Default == "" {
+		cfg.Default = cfg.Versions[len(cfg.Versions)-1]
+	}
+	v := &versioning{cfg: cfg, index: make(map[string]int, len(cfg.Versions))}
+	for i, version := range cfg.Versions {
+		v.index[version] = i
+	}
+	if _, ok := v.index[cfg.Default]; !ok {
+		panic("response: VersionConfig.Default " + strconv.Quote(cfg.Default) + " is not in Versions")
+	}
+	for _, change := range cfg.Changes {
+		if _, ok := v.index[change.Version]; !ok {
+			panic("response: VersionChange version " + strconv.Quote(change.Version) + " is not in Versions")
+		}
+		if change.Downgrade == nil {
+			panic("response: VersionChange " + strconv.Quote(change.Version) + " has no Downgrade")
+		}
+	}
+	// Newest first, so each Downgrade sees the shape it was written against
+	v.cfg.Changes = slices.Clone(cfg.Changes)
+	slices.SortStableFunc(v.cfg.Changes, func(a, b VersionChange) int {
+		return v.index[b.Version] - v.index[a.Version]
+	})
+	return v
+}
+
+// plan returns the plan for a requested version, or false if the version
+// is unknown.
+func (v *versioning) plan(version string) (*versionPlan, bool) {
+	i, ok := v.index[version]
+	if !ok {
+		return nil, false
+	}
+	p := &versionPlan{version: version}
+	for _, change := range v.cfg.Changes {
+		if v.index[change.Version] > i {
+			p.changes = append(p.changes, change)
+		}
+	}
+	return p, true
+}
+
+// unknown is the error for a version that isn't in Versions.
+func (v *versioning) unknown(version string) ErrorInfo {
+	return ErrorInfo{
+		Type:    ErrorTypeInvalidRequest,
+		Code:    ErrorCodeInvalidParam,
+		Message: "unknown API version " + strconv.Quote(version),
+		Param:   v.cfg.Header,
+	}
+}
+
+// Versioned returns middleware that negotiates the API version of the
+// routes below it and downgrades their responses to it, Stripe-style:
+// handlers always produce the latest shape, and the changes introduced
+// after the client's version are undone right before serialization.
+//
+//	api.Use(response.Versioned(response.VersionConfig{
+//	    Versions: []string{"2024-01-01", "2025-06-01"},
+//	    Pinned:   func(c *gin.Context) string { return account(c).APIVersion },
+//	    Changes: []response.VersionChange{{
+//	        Version:     "2025-06-01",
+//	        Object:      "gallery",
+//	        Description: "title was renamed from name",
+//	        Downgrade: func(obj map[string]any) {
+//	            obj["name"] = obj["title"]
+//	            delete(obj, "title")
+//	        },
+//	    }},
+//	}))
+//
+// The version comes from the header, then Pinned, then Default; unknown
+// versions get a 400 invalid_param. The response echoes the version in
+// the header. Errors are not downgraded. It panics if cfg is invalid.
+func Versioned(cfg VersionConfig) gin.HandlerFunc {
+	v := newVersioning(cfg)
+	return func(c *gin.Context) {
+		version := c.GetHeader(v.cfg.Header)
+		if version == "" && v.cfg.Pinned != nil {
+			version = v.cfg.Pinned(c)
+		}
+		if version == "" {
+			version = v.cfg.Default
+		}
+		p, ok := v.plan(version)
+		if !ok {
+			ErrorWithInfo(c, http.StatusBadRequest, v.unknown(version))
+			c.Abort()
+			return
+		}
+
+		c.Set(versionKey, p)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), versionContextKey{}, p))
+		c.Header(v.cfg.Header, version)
+		AddVary(c.Writer.Header(), v.cfg.Header)
+		c.Next()
+	}
+}
+
+// VersionedHandler is the net/http equivalent of Versioned. Pinned is not
+// consulted, as it takes a gin context.
+func VersionedHandler(cfg VersionConfig) func(http.Handler) http.Handler {
+	v := newVersioning(cfg)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			version := r.Header.Get(v.cfg.Header)
+			if version == "" {
+				version = v.cfg.Default
+			}
+			p, ok := v.plan(version)
+			if !ok {
+				WriteError(w, r, http.StatusBadRequest, v.unknown(version))
+				return
+			}
+			w.Header().Set(v.cfg.Header, version)
+			AddVary(w.Header(), v.cfg.Header)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionContextKey{}, p)))
+		})
+	}
+}
+
+// versionContextKey is the request context key for the negotiated version.
+type versionContextKey struct{}
+
+// Version returns the API version negotiated by Versioned, or "" outside
+// versioned routes. Use it for behavior changes beyond the response shape.
+func Version(c *gin.Context) string {
+	return ginVersionPlan(c).versionOrEmpty()
+}
+
+// VersionFromContext is the net/http equivalent of Version.
+func VersionFromContext(ctx context.Context) string {
+	return versionPlanFromContext(ctx).versionOrEmpty()
+}
+
+// versionOrEmpty returns the plan's version; nil plans have none.
+func (p *versionPlan) versionOrEmpty() string {
+	if p == nil {
+		return ""
+	}
+	return p.version
+}
+
+// ginVersionPlan returns the plan set by Versioned, falling back to the
+// request context.
+func ginVersionPlan(c *gin.Context) *versionPlan {
+	if v, ok := c.Get(versionKey); ok {
+		if p, ok := v.(*versionPlan); ok {
+			return p
+		}
+	}
+	if c.Request != nil {
+		return versionPlanFromContext(c.Request.Context())
+	}
+	return nil
+}
+
+// versionPlanFromContext returns the plan stored by VersionedHandler.
+func versionPlanFromContext(ctx context.Context) *versionPlan {
+	if ctx == nil {
+		return nil
+	}
+	p, _ := ctx.Value(versionContextKey{}).(*versionPlan)
+	return p
+}
+
+// downgrade applies the plan's changes to an encoded body. Errors pass
+// through.
+func (p *versionPlan) downgrade(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if m, ok := v.(map[string]any); ok && m["object"] == "error" {
+		return body, nil
+	}
+
+	for _, change := range p.changes {
+		if change.Object != "" {
+			downgradeObjects(v, change)
+			continue
+		}
+		switch v := v.(type) {
+		case []any:
+			// Bare array from PaginationHeaders mode
+			for _, item := range v {
+				if obj, ok := item.(map[string]any); ok {
+					change.Downgrade(obj)
+				}
+			}
+		case map[string]any:
+			if v["object"] != "list" {
+				change.Downgrade(v)
+				continue
+			}
+			items, _ := v["data"].([]any)
+			for _, item := range items {
+				if obj, ok := item.(map[string]any); ok {
+					change.Downgrade(obj)
+				}
+			}
+		}
+	}
+	return json.Marshal(v)
+}
+
+// downgradeObjects applies change to every object of its type within v.
+func downgradeObjects(v any, change VersionChange) {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, val := range v {
+			downgradeObjects(val, change)
+		}
+		if v["object"] == change.Object {
+			change.Downgrade(v)
+		}
+	case []any:
+		for _, val := range v {
+			downgradeObjects(val, change)
+		}
+	}
+}
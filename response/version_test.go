@@ -0,0 +1,203 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+type versionUser struct {
+	Object string `json:"object"`
+	ID     string `json:"id"`
+}
+
+type versionGallery struct {
+	Object string      `json:"object"`
+	ID     string      `json:"id"`
+	Title  string      `json:"title"`
+	Owner  versionUser `json:"owner"`
+}
+
+func newVersionConfig() response.VersionConfig {
+	return response.VersionConfig{
+		Versions: []string{"2024-01-01", "2024-06-01", "2025-01-01"},
+		Changes: []response.VersionChange{
+			{
+				Version: "2024-06-01",
+				Object:  "gallery",
+				Downgrade: func(obj map[string]any) {
+					obj["name"] = obj["title"]
+					delete(obj, "title")
+				},
+			},
+			{
+				Version: "2025-01-01",
+				Object:  "user",
+				Downgrade: func(obj map[string]any) {
+					obj["user_id"] = obj["id"]
+					delete(obj, "id")
+				},
+			},
+			{
+				// Runs first, so it still sees "title"
+				Version: "2025-01-01",
+				Downgrade: func(obj map[string]any) {
+					if title, ok := obj["title"].(string); ok {
+						obj["title"] = strings.ToUpper(title)
+					}
+				},
+			},
+		},
+	}
+}
+
+func TestVersioned(t *testing.T) {
+	cfg := newVersionConfig()
+	cfg.Pinned = func(c *gin.Context) string { return c.Query("pinned") }
+
+	gallery := versionGallery{Object: "gallery", ID: "gal_1", Title: "Summer", Owner: versionUser{Object: "user", ID: "usr_1"}}
+	router := gin.New()
+	router.Use(response.Versioned(cfg))
+	router.GET("/object", func(c *gin.Context) { response.Object(c, gallery) })
+	router.GET("/list", func(c *gin.Context) { response.ListResponse(c, []versionGallery{gallery}, 1, 20, 0) })
+	router.GET("/error", func(c *gin.Context) { response.NotFound(c, "gallery") })
+	router.GET("/version", func(c *gin.Context) { c.String(http.StatusOK, response.Version(c)) })
+
+	tests := []struct {
+		name       string
+		target     string
+		header     string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "latest by default",
+			target:     "/object",
+			wantStatus: http.StatusOK,
+			want:       `{"object":"gallery","id":"gal_1","title":"Summer","owner":{"object":"user","id":"usr_1"}}`,
+		},
+		{
+			name:       "one version back",
+			target:     "/object",
+			header:     "2024-06-01",
+			wantStatus: http.StatusOK,
+			want:       `{"id":"gal_1","object":"gallery","owner":{"object":"user","user_id":"usr_1"},"title":"SUMMER"}`,
+		},
+		{
+			name:       "oldest version",
+			target:     "/object",
+			header:     "2024-01-01",
+			wantStatus: http.StatusOK,
+			want:       `{"id":"gal_1","name":"SUMMER","object":"gallery","owner":{"object":"user","user_id":"usr_1"}}`,
+		},
+		{
+			name:       "pinned version",
+			target:     "/object?pinned=2024-01-01",
+			wantStatus: http.StatusOK,
+			want:       `{"id":"gal_1","name":"SUMMER","object":"gallery","owner":{"object":"user","user_id":"usr_1"}}`,
+		},
+		{
+			name:       "header overrides pin",
+			target:     "/object?pinned=2024-01-01",
+			header:     "2025-01-01",
+			wantStatus: http.StatusOK,
+			want:       `{"object":"gallery","id":"gal_1","title":"Summer","owner":{"object":"user","id":"usr_1"}}`,
+		},
+		{
+			name:       "list items",
+			target:     "/list",
+			header:     "2024-01-01",
+			wantStatus: http.StatusOK,
+			want:       `{"data":[{"id":"gal_1","name":"SUMMER","object":"gallery","owner":{"object":"user","user_id":"usr_1"}}],"has_more":false,"limit":20,"object":"list","offset":0,"total":1}`,
+		},
+		{
+			name:       "errors are not downgraded",
+			target:     "/error",
+			header:     "2024-01-01",
+			wantStatus: http.StatusNotFound,
+			want:       `{"object":"error","error":{"type":"not_found","message":"gallery not found"}}`,
+		},
+		{
+			name:       "version for handlers",
+			target:     "/version",
+			header:     "2024-06-01",
+			wantStatus: http.StatusOK,
+			want:       `2024-06-01`,
+		},
+		{
+			name:       "unknown version",
+			target:     "/object",
+			header:     "2023-01-01",
+			wantStatus: http.StatusBadRequest,
+			want:       `{"object":"error","error":{"type":"invalid_request","code":"invalid_param","message":"unknown API version \"2023-01-01\"","param":"API-Version"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("API-Version", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("expected body %s, got %s", tt.want, got)
+			}
+			if tt.wantStatus == http.StatusOK && w.Header().Get("API-Version") == "" {
+				t.Error("expected the version to be echoed")
+			}
+		})
+	}
+}
+
+func TestVersionedHandler(t *testing.T) {
+	handler := response.VersionedHandler(newVersionConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.WriteObject(w, r, versionGallery{Object: "gallery", ID: "gal_1", Title: "Summer", Owner: versionUser{Object: "user", ID: "usr_1"}})
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/object", nil)
+	req.Header.Set("API-Version", "2024-01-01")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	want := `{"id":"gal_1","name":"SUMMER","object":"gallery","owner":{"object":"user","user_id":"usr_1"}}`
+	if got := w.Body.String(); got != want {
+		t.Errorf("expected body %s, got %s", want, got)
+	}
+	if got := w.Header().Get("Vary"); !strings.Contains(got, "API-Version") {
+		t.Errorf("expected Vary to include API-Version, got %q", got)
+	}
+}
+
+func TestVersionedPanics(t *testing.T) {
+	noop := func(map[string]any) {}
+	tests := []struct {
+		name string
+		cfg  response.VersionConfig
+	}{
+		{"no versions", response.VersionConfig{}},
+		{"unknown default", response.VersionConfig{Versions: []string{"v1"}, Default: "v2"}},
+		{"unknown change version", response.VersionConfig{Versions: []string{"v1"}, Changes: []response.VersionChange{{Version: "v2", Downgrade: noop}}}},
+		{"missing downgrade", response.VersionConfig{Versions: []string{"v1", "v2"}, Changes: []response.VersionChange{{Version: "v2"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			response.Versioned(tt.cfg)
+		})
+	}
+}
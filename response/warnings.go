@@ -0,0 +1,89 @@
+package response
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Warning is a non-fatal issue with a request, such as a deprecated param
+// or partial data, reported alongside a successful response.
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// warningsKey is the gin context key for the request's warnings.
+const warningsKey = "ginapi.warnings"
+
+// Warn records a warning for the current request. Warnings are sent as
+// Warning headers and, for JSON object bodies, in a top-level "warnings"
+// array, so clients learn about soft issues without failing the request:
+//
+//	if c.Query("sort_by") != "" {
+//	    response.Warn(c, "deprecated_param", "sort_by is deprecated, use sort")
+//	}
+//	response.ListResponse(c, items, total, p.Limit, p.Offset)
+//	// {"object": "list", ..., "warnings": [{"code": "deprecated_param", "message": "..."}]}
+//
+// Call it before the response is written. Error envelopes and JSON:API
+// documents only get the header.
+func Warn(c *gin.Context, code, message string) {
+	v, _ := c.Get(warningsKey)
+	warnings, _ := v.([]Warning)
+	c.Set(warningsKey, append(warnings, Warning{Code: code, Message: message}))
+	c.Writer.Header().Add("Warning", `299 - "`+warningText(code, message)+`"`)
+}
+
+// Warnings returns the warnings recorded with Warn for the current request.
+func Warnings(c *gin.Context) []Warning {
+	v, _ := c.Get(warningsKey)
+	warnings, _ := v.([]Warning)
+	return warnings
+}
+
+// warningText formats a warning as Warning header text, escaping quotes
+// and dropping control characters.
+func warningText(code, message string) string {
+	text := message
+	if code != "" {
+		text = code + ": " + message
+	}
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text))
+}
+
+// appendWarnings adds a "warnings" member to a JSON object body. Other
+// bodies (bare arrays) are returned unchanged.
+func appendWarnings(body []byte, warnings []Warning) []byte {
+	return appendMember(body, "warnings", warnings)
+}
+
+// appendMember adds member name, encoding v, to a JSON object body. Other
+// bodies are returned unchanged.
+func appendMember(body []byte, name string, v any) []byte {
+	n := len(body)
+	if n < 2 || body[0] != '{' || body[n-1] != '}' {
+		return body
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+
+	out := make([]byte, 0, n+len(encoded)+len(name)+4)
+	out = append(out, body[:n-1]...)
+	if strings.TrimSpace(string(body[1:n-1])) != "" {
+		out = append(out, ',')
+	}
+	out = append(out, '"')
+	out = append(out, name...)
+	out = append(out, '"', ':')
+	out = append(out, encoded...)
+	return append(out, '}')
+}
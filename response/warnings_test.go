@@ -0,0 +1,103 @@
+package response_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestWarn(t *testing.T) {
+	router := gin.New()
+	router.GET("/object", func(c *gin.Context) {
+		response.Warn(c, "deprecated_param", "sort_by is deprecated, use sort")
+		response.Warn(c, "partial_data", `tag "x" unavailable`)
+		response.Object(c, gin.H{"object": "gallery", "id": "gal_1"})
+	})
+	router.GET("/empty", func(c *gin.Context) {
+		response.Warn(c, "partial_data", "nothing loaded")
+		response.Object(c, gin.H{})
+	})
+	router.GET("/list", func(c *gin.Context) {
+		response.Warn(c, "partial_data", "some shards timed out")
+		response.ListResponse(c, []string{"a"}, 1, 20, 0)
+	})
+	router.GET("/error", func(c *gin.Context) {
+		response.Warn(c, "deprecated_param", "sort_by is deprecated")
+		response.NotFound(c, "gallery not found")
+	})
+	router.GET("/none", func(c *gin.Context) {
+		response.Object(c, gin.H{"id": "gal_1"})
+	})
+
+	tests := []struct {
+		path         string
+		wantWarnings []response.Warning
+		wantHeaders  []string
+	}{
+		{
+			path: "/object",
+			wantWarnings: []response.Warning{
+				{Code: "deprecated_param", Message: "sort_by is deprecated, use sort"},
+				{Code: "partial_data", Message: `tag "x" unavailable`},
+			},
+			wantHeaders: []string{
+				`299 - "deprecated_param: sort_by is deprecated, use sort"`,
+				`299 - "partial_data: tag \"x\" unavailable"`,
+			},
+		},
+		{
+			path:         "/empty",
+			wantWarnings: []response.Warning{{Code: "partial_data", Message: "nothing loaded"}},
+			wantHeaders:  []string{`299 - "partial_data: nothing loaded"`},
+		},
+		{
+			path:         "/list",
+			wantWarnings: []response.Warning{{Code: "partial_data", Message: "some shards timed out"}},
+			wantHeaders:  []string{`299 - "partial_data: some shards timed out"`},
+		},
+		{
+			path:        "/error",
+			wantHeaders: []string{`299 - "deprecated_param: sort_by is deprecated"`},
+		},
+		{
+			path: "/none",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			var body struct {
+				Warnings []response.Warning `json:"warnings"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid JSON %s: %v", w.Body.String(), err)
+			}
+			if len(body.Warnings) != len(tt.wantWarnings) {
+				t.Fatalf("expected warnings %v, got %s", tt.wantWarnings, w.Body.String())
+			}
+			for i, want := range tt.wantWarnings {
+				if body.Warnings[i] != want {
+					t.Errorf("expected warning %v, got %v", want, body.Warnings[i])
+				}
+			}
+
+			headers := w.Header().Values("Warning")
+			if len(headers) != len(tt.wantHeaders) {
+				t.Fatalf("expected headers %q, got %q", tt.wantHeaders, headers)
+			}
+			for i, want := range tt.wantHeaders {
+				if headers[i] != want {
+					t.Errorf("expected header %q, got %q", want, headers[i])
+				}
+			}
+		})
+	}
+}
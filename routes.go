@@ -0,0 +1,16 @@
+package ginapi
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// GETAndHEAD registers handlers for both GET and HEAD on path.
+// Gin doesn't answer HEAD for GET routes on its own; the response package
+// helpers detect HEAD and send headers (Content-Length, ETag, X-Total-Count)
+// without a body.
+//
+//	ginapi.GETAndHEAD(api, "/galleries", listGalleries)
+func GETAndHEAD(r gin.IRoutes, path string, handlers ...gin.HandlerFunc) {
+	r.GET(path, handlers...)
+	r.HEAD(path, handlers...)
+}
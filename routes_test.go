@@ -0,0 +1,81 @@
+package ginapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/response"
+)
+
+func TestGETAndHEAD(t *testing.T) {
+	router := gin.New()
+	ginapi.GETAndHEAD(router, "/items", func(c *gin.Context) {
+		response.ListResponse(c, []string{"a", "b"}, 42, 2, 0)
+	})
+
+	get := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items", nil)
+	router.ServeHTTP(get, req)
+
+	head := httptest.NewRecorder()
+	req, _ = http.NewRequest("HEAD", "/items", nil)
+	router.ServeHTTP(head, req)
+
+	if head.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", head.Code)
+	}
+	if head.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", head.Body.String())
+	}
+	if got := head.Header().Get("Content-Length"); got != strconv.Itoa(get.Body.Len()) {
+		t.Errorf("expected Content-Length %d, got %s", get.Body.Len(), got)
+	}
+	if head.Header().Get("ETag") == "" {
+		t.Error("expected ETag header")
+	}
+	if got := head.Header().Get("X-Total-Count"); got != "42" {
+		t.Errorf("expected X-Total-Count 42, got '%s'", got)
+	}
+}
+
+func TestHandleRegistersHEAD(t *testing.T) {
+	router := gin.New()
+	list := func(c *gin.Context) { response.ListResponse(c, []string{"a"}, 1, 1, 0) }
+	ginapi.Handle(router, http.MethodGet, "/head/galleries", ginapi.RouteMeta{}, list)
+	ginapi.Handle(router, http.MethodGet, "/head/tags", ginapi.RouteMeta{NoHEAD: true}, list)
+	ginapi.Handle(router, http.MethodPost, "/head/galleries", ginapi.RouteMeta{}, list)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/head/galleries", http.StatusOK},
+		{"/head/tags", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("HEAD", tt.path, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusOK && w.Body.Len() != 0 {
+				t.Errorf("expected empty body, got %q", w.Body.String())
+			}
+		})
+	}
+
+	for _, route := range ginapi.Routes(router) {
+		if route.Method == http.MethodHead {
+			t.Errorf("expected HEAD routes added for GET routes to be left out, got %s %s", route.Method, route.Path)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Package rpcerr maps between gRPC/connect errors and the response error
+// envelope, so gateways fronting gRPC services return the same error types
+// and codes as native ginapi services.
+//
+// gRPC and connect share the same code numbering, so both directions work in
+// terms of codes.Code. The envelope's type, code, and param travel in a
+// google.rpc.ErrorInfo detail (Domain "ginapi") so a round trip through a
+// gRPC hop is lossless.
+package rpcerr
+
+import (
+	"errors"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Domain identifies the ErrorInfo detail carrying the envelope fields.
+const Domain = "ginapi"
+
+// statusClientClosedRequest is the non-standard status for a canceled request.
+const statusClientClosedRequest = 499
+
+// HTTPStatus returns the HTTP status for a gRPC code, following grpc-gateway.
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return statusClientClosedRequest
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// Code returns the gRPC code for an error envelope. A known info.Code wins
+// over the status, so already_exists maps to AlreadyExists rather than Aborted.
+func Code(httpStatus int, info response.ErrorInfo) codes.Code {
+	switch info.Code {
+	case response.ErrorCodeResourceNotFound:
+		return codes.NotFound
+	case response.ErrorCodeAlreadyExists:
+		return codes.AlreadyExists
+	case response.ErrorCodeAuthRequired, response.ErrorCodeInvalidToken, response.ErrorCodeTokenExpired:
+		return codes.Unauthenticated
+	case response.ErrorCodeInsufficientPermission:
+		return codes.PermissionDenied
+	case response.ErrorCodeRateLimitExceeded:
+		return codes.ResourceExhausted
+	case response.ErrorCodeServiceUnavailable:
+		return codes.Unavailable
+	case response.ErrorCodeInternal:
+		return codes.Internal
+	}
+
+	switch httpStatus {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case statusClientClosedRequest:
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	if httpStatus >= 500 {
+		return codes.Internal
+	}
+	return codes.InvalidArgument
+}
+
+// ErrorInfo builds an error envelope for a gRPC code and message.
+func ErrorInfo(code codes.Code, message string) response.ErrorInfo {
+	info := response.ErrorInfo{
+		Type:    response.ErrorTypeForStatus(HTTPStatus(code)),
+		Message: message,
+	}
+
+	switch code {
+	case codes.NotFound:
+		info.Code = response.ErrorCodeResourceNotFound
+	case codes.AlreadyExists:
+		info.Code = response.ErrorCodeAlreadyExists
+	case codes.Unauthenticated:
+		info.Code = response.ErrorCodeAuthRequired
+	case codes.PermissionDenied:
+		info.Code = response.ErrorCodeInsufficientPermission
+	case codes.ResourceExhausted:
+		info.Code = response.ErrorCodeRateLimitExceeded
+	case codes.Unavailable:
+		info.Code = response.ErrorCodeServiceUnavailable
+	case codes.Unknown, codes.Internal, codes.DataLoss:
+		info.Code = response.ErrorCodeInternal
+	}
+	return info
+}
+
+// FromError converts a gRPC status error or *connect.Error into an HTTP status
+// and error envelope. Any other error becomes a 500 with a generic message so
+// internal details don't leak to clients.
+func FromError(err error) (int, response.ErrorInfo) {
+	httpStatus, info, _ := fromError(err)
+	return httpStatus, info
+}
+
+// fromError is FromError, also reporting whether err was a gRPC or connect
+// error rather than an unknown one.
+func fromError(err error) (int, response.ErrorInfo, bool) {
+	var connectErr *connect.Error
+	if errors.As(err, &connectErr) {
+		code := codes.Code(connectErr.Code())
+		info := ErrorInfo(code, connectErr.Message())
+		for _, d := range connectErr.Details() {
+			if v, err := d.Value(); err == nil {
+				applyDetail(&info, v)
+			}
+		}
+		return HTTPStatus(code), info, true
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		st := grpcErr.GRPCStatus()
+		info := ErrorInfo(st.Code(), st.Message())
+		for _, d := range st.Details() {
+			applyDetail(&info, d)
+		}
+		return HTTPStatus(st.Code()), info, true
+	}
+
+	return http.StatusInternalServerError, response.ErrorInfo{
+		Type:    response.ErrorTypeAPI,
+		Code:    response.ErrorCodeInternal,
+		Message: "internal error",
+	}, false
+}
+
+// Status converts an error envelope into a gRPC status, attaching the
+// envelope fields as an ErrorInfo detail.
+func Status(httpStatus int, info response.ErrorInfo) *status.Status {
+	st := status.New(Code(httpStatus, info), info.Message)
+	if withDetails, err := st.WithDetails(detail(info)); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+// ConnectError converts an error envelope into a *connect.Error, attaching
+// the envelope fields as an ErrorInfo detail.
+func ConnectError(httpStatus int, info response.ErrorInfo) *connect.Error {
+	err := connect.NewError(connect.Code(Code(httpStatus, info)), errors.New(info.Message))
+	if d, detailErr := connect.NewErrorDetail(detail(info)); detailErr == nil {
+		err.AddDetail(d)
+	}
+	return err
+}
+
+// Error sends err as a structured error response. Errors that aren't gRPC or
+// connect errors are sent to response.SetReporter's Reporter, since their
+// details are hidden from the client.
+//
+//	gallery, err := client.GetGallery(ctx, req)
+//	if err != nil {
+//	    rpcerr.Error(c, err)
+//	    return
+//	}
+func Error(c *gin.Context, err error) {
+	httpStatus, info, known := fromError(err)
+	if !known {
+		response.ReportError(c, c.Request, c.FullPath(), err)
+	}
+	response.ErrorWithInfo(c, httpStatus, info)
+}
+
+// WriteError is the net/http equivalent of Error.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	httpStatus, info, known := fromError(err)
+	if !known {
+		response.ReportError(r.Context(), r, r.Pattern, err)
+	}
+	response.WriteError(w, r, httpStatus, info)
+}
+
+// detail builds the ErrorInfo detail for an envelope.
+func detail(info response.ErrorInfo) *errdetails.ErrorInfo {
+	metadata := map[string]string{"type": info.Type}
+	if info.Param != "" {
+		metadata["param"] = info.Param
+	}
+	return &errdetails.ErrorInfo{
+		Reason:   info.Code,
+		Domain:   Domain,
+		Metadata: metadata,
+	}
+}
+
+// applyDetail restores envelope fields from one of our ErrorInfo details.
+func applyDetail(info *response.ErrorInfo, d any) {
+	ei, ok := d.(*errdetails.ErrorInfo)
+	if !ok || ei.GetDomain() != Domain {
+		return
+	}
+	info.Code = ei.GetReason()
+	if t := ei.GetMetadata()["type"]; t != "" {
+		info.Type = t
+	}
+	info.Param = ei.GetMetadata()["param"]
+}
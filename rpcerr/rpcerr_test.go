@@ -0,0 +1,152 @@
+package rpcerr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/doujins-org/ginapi/response"
+	"github.com/doujins-org/ginapi/rpcerr"
+)
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantInfo   response.ErrorInfo
+	}{
+		{
+			name:       "grpc not found",
+			err:        status.Error(codes.NotFound, "gallery not found"),
+			wantStatus: http.StatusNotFound,
+			wantInfo:   response.ErrorInfo{Type: response.ErrorTypeNotFound, Code: response.ErrorCodeResourceNotFound, Message: "gallery not found"},
+		},
+		{
+			name:       "wrapped grpc invalid argument",
+			err:        fmt.Errorf("get gallery: %w", status.Error(codes.InvalidArgument, "bad id")),
+			wantStatus: http.StatusBadRequest,
+			wantInfo:   response.ErrorInfo{Type: response.ErrorTypeInvalidRequest, Message: "bad id"},
+		},
+		{
+			name:       "connect permission denied",
+			err:        connect.NewError(connect.CodePermissionDenied, errors.New("nope")),
+			wantStatus: http.StatusForbidden,
+			wantInfo:   response.ErrorInfo{Type: response.ErrorTypeForbidden, Code: response.ErrorCodeInsufficientPermission, Message: "nope"},
+		},
+		{
+			name:       "connect resource exhausted",
+			err:        connect.NewError(connect.CodeResourceExhausted, errors.New("slow down")),
+			wantStatus: http.StatusTooManyRequests,
+			wantInfo:   response.ErrorInfo{Type: response.ErrorTypeRateLimit, Code: response.ErrorCodeRateLimitExceeded, Message: "slow down"},
+		},
+		{
+			name:       "plain error is hidden",
+			err:        errors.New("dial tcp 10.0.0.1:5432: connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantInfo:   response.ErrorInfo{Type: response.ErrorTypeAPI, Code: response.ErrorCodeInternal, Message: "internal error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStatus, gotInfo := rpcerr.FromError(tt.err)
+			if gotStatus != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, gotStatus)
+			}
+			if !reflect.DeepEqual(gotInfo, tt.wantInfo) {
+				t.Errorf("expected %+v, got %+v", tt.wantInfo, gotInfo)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	info := response.ErrorInfo{
+		Type:    response.ErrorTypeInvalidRequest,
+		Code:    response.ErrorCodeInvalidParam,
+		Message: "limit must be positive",
+		Param:   "limit",
+	}
+
+	st := rpcerr.Status(http.StatusBadRequest, info)
+	if st.Code() != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %s", st.Code())
+	}
+	if gotStatus, gotInfo := rpcerr.FromError(st.Err()); gotStatus != http.StatusBadRequest || !reflect.DeepEqual(gotInfo, info) {
+		t.Errorf("grpc: expected 400 %+v, got %d %+v", info, gotStatus, gotInfo)
+	}
+
+	connectErr := rpcerr.ConnectError(http.StatusBadRequest, info)
+	if connectErr.Code() != connect.CodeInvalidArgument {
+		t.Errorf("expected invalid_argument, got %s", connectErr.Code())
+	}
+	if gotStatus, gotInfo := rpcerr.FromError(connectErr); gotStatus != http.StatusBadRequest || !reflect.DeepEqual(gotInfo, info) {
+		t.Errorf("connect: expected 400 %+v, got %d %+v", info, gotStatus, gotInfo)
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		status int
+		code   string
+		want   codes.Code
+	}{
+		{http.StatusConflict, response.ErrorCodeAlreadyExists, codes.AlreadyExists},
+		{http.StatusConflict, "", codes.Aborted},
+		{http.StatusUnauthorized, response.ErrorCodeTokenExpired, codes.Unauthenticated},
+		{http.StatusUnprocessableEntity, "", codes.InvalidArgument},
+		{http.StatusGatewayTimeout, "", codes.DeadlineExceeded},
+		{http.StatusBadGateway, "", codes.Internal},
+	}
+
+	for _, tt := range tests {
+		if got := rpcerr.Code(tt.status, response.ErrorInfo{Code: tt.code}); got != tt.want {
+			t.Errorf("Code(%d, %q): expected %s, got %s", tt.status, tt.code, tt.want, got)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	rpcerr.Error(c, status.Error(codes.Unavailable, "search is down"))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+	want := `{"object":"error","error":{"type":"api","code":"service_unavailable","message":"search is down"}}`
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestErrorReportsUnknownErrors(t *testing.T) {
+	var reported []error
+	response.SetReporter(response.ReporterFunc(func(ctx context.Context, r response.Report) {
+		reported = append(reported, r.Err)
+	}))
+	t.Cleanup(func() { response.SetReporter(nil) })
+
+	unknown := errors.New("dial tcp 10.0.0.1:5432: connection refused")
+	for _, err := range []error{unknown, status.Error(codes.NotFound, "gallery not found")} {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/galleries/1", nil)
+		rpcerr.WriteError(w, r, err)
+	}
+
+	if len(reported) != 1 || reported[0] != unknown {
+		t.Errorf("expected only the unknown error to be reported, got %v", reported)
+	}
+}
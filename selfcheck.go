@@ -0,0 +1,215 @@
+package ginapi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/pagination"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Check is a startup check run by SelfCheck.
+type Check func(engine *gin.Engine) error
+
+// SelfCheck verifies the router before it serves traffic: it runs
+// CheckOrder and then each check, returning every problem found rather
+// than the first. Call it after registering routes:
+//
+//	if err := ginapi.SelfCheck(router,
+//	    ginapi.RequireMiddleware(ginapi.FuncName(middleware.Recovery)),
+//	    ginapi.CheckLanguages(cfg.Language.Supported),
+//	    ginapi.CheckSkipPrefixes("NormalizePath", normalizeCfg.SkipPrefixes),
+//	    ginapi.CheckErrorCodes(apperr.Codes...),
+//	); err != nil {
+//	    log.Fatal(err)
+//	}
+func SelfCheck(engine *gin.Engine, checks ...Check) error {
+	errs := []error{CheckOrder(engine)}
+	for _, check := range checks {
+		errs = append(errs, check(engine))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("ginapi: self-check failed: %w", err)
+	}
+	return nil
+}
+
+// RequireMiddleware checks that every route runs middleware matching each
+// name (see OrderRule for how names match).
+func RequireMiddleware(names ...string) Check {
+	return func(engine *gin.Engine) error {
+		var errs []error
+		for _, name := range names {
+			var missing []string
+			for _, r := range Routes(engine) {
+				if firstMatch(r.Middleware, name) < 0 {
+					missing = append(missing, r.Method+" "+r.Path)
+				}
+			}
+			if len(missing) > 0 {
+				errs = append(errs, fmt.Errorf("required middleware %s is missing from %s", shortName(name), strings.Join(missing, ", ")))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// CheckLanguages checks that the supported language list is non-empty
+// and lowercase, as language detection compares lowercase tags.
+func CheckLanguages(supported []string) Check {
+	return func(*gin.Engine) error {
+		if len(supported) == 0 {
+			return errors.New("no supported languages configured")
+		}
+		var errs []error
+		for _, lang := range supported {
+			if lang != strings.ToLower(lang) {
+				errs = append(errs, fmt.Errorf("supported language %q must be lowercase", lang))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// CheckLanguageRoutes checks that no route starts with a static segment
+// that is a supported language, which language detection would take for a
+// language prefix: a GET /id/:id route with Indonesian supported serves
+// Indonesian. Segments listed in reserved (the LanguageConfig's Reserved)
+// are exempt, since detection skips them.
+func CheckLanguageRoutes(supported, reserved []string) Check {
+	return func(engine *gin.Engine) error {
+		langs := middleware.BuildSupportedMap(supported)
+		exempt := middleware.BuildSupportedMap(reserved)
+		collisions := map[string][]string{}
+		var order []string
+		for _, r := range Routes(engine) {
+			first, _, _ := strings.Cut(strings.TrimPrefix(r.Path, "/"), "/")
+			first = strings.ToLower(first)
+			if _, ok := langs[first]; !ok {
+				continue
+			}
+			if _, ok := exempt[first]; ok {
+				continue
+			}
+			if collisions[first] == nil {
+				order = append(order, first)
+			}
+			collisions[first] = append(collisions[first], r.Method+" "+r.Path)
+		}
+		var errs []error
+		for _, lang := range order {
+			errs = append(errs, fmt.Errorf("routes %s start with supported language %q; use a :lang parameter or reserve the segment", strings.Join(collisions[lang], ", "), lang))
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// CheckSkipPrefixes checks a middleware's skip list (e.g.
+// NormalizePathConfig.SkipPrefixes) against the registered routes. A
+// prefix is an error when it matches every route, or when it matches a
+// route partway through a path segment, e.g. "/api" skipping "/apidocs".
+// name identifies the middleware in errors.
+func CheckSkipPrefixes(name string, prefixes []string) Check {
+	return func(engine *gin.Engine) error {
+		routes := Routes(engine)
+		var errs []error
+		for _, prefix := range prefixes {
+			var matched int
+			var partial []string
+			for _, r := range routes {
+				if !strings.HasPrefix(r.Path, prefix) {
+					continue
+				}
+				matched++
+				if !strings.HasSuffix(prefix, "/") && len(r.Path) > len(prefix) && r.Path[len(prefix)] != '/' {
+					partial = append(partial, r.Path)
+				}
+			}
+			switch {
+			case len(routes) > 0 && matched == len(routes):
+				errs = append(errs, fmt.Errorf("%s skip prefix %q matches every route", name, prefix))
+			case len(partial) > 0:
+				errs = append(errs, fmt.Errorf("%s skip prefix %q shadows %s", name, prefix, strings.Join(partial, ", ")))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// builtinErrorCodes are the ErrorCode constants of the response package.
+var builtinErrorCodes = []string{
+	response.ErrorCodeInvalidParam,
+	response.ErrorCodeMissingParam,
+	response.ErrorCodeInvalidFormat,
+	response.ErrorCodeOffsetTooDeep,
+	response.ErrorCodeDigestMismatch,
+	response.ErrorCodeInvalidIDChecksum,
+	response.ErrorCodeHostNotAllowed,
+	response.ErrorCodeNotAcceptable,
+	response.ErrorCodeRangeNotSatisfiable,
+	response.ErrorCodeResourceNotFound,
+	response.ErrorCodeAlreadyExists,
+	response.ErrorCodeDuplicateRequest,
+	response.ErrorCodePreconditionFailed,
+	response.ErrorCodePreconditionRequired,
+	response.ErrorCodeAuthRequired,
+	response.ErrorCodeInvalidToken,
+	response.ErrorCodeTokenExpired,
+	response.ErrorCodeInsufficientPermission,
+	response.ErrorCodeRateLimitExceeded,
+	response.ErrorCodeChallengeRequired,
+	response.ErrorCodeConcurrencyLimitExceeded,
+	response.ErrorCodeInternal,
+	response.ErrorCodeServiceUnavailable,
+	response.ErrorCodeResponseTooLarge,
+	response.ErrorCodeTimeout,
+}
+
+// CheckErrorCodes checks the application's error codes for duplicates and
+// for collisions with the response package's built-in codes, which clients
+// already handle with their documented meaning.
+func CheckErrorCodes(codes ...string) Check {
+	return func(*gin.Engine) error {
+		builtin := map[string]bool{}
+		for _, code := range builtinErrorCodes {
+			builtin[code] = true
+		}
+		seen := map[string]bool{}
+		var errs []error
+		for _, code := range codes {
+			switch {
+			case builtin[code]:
+				errs = append(errs, fmt.Errorf("error code %q collides with a built-in code", code))
+			case seen[code]:
+				errs = append(errs, fmt.Errorf("error code %q is registered more than once", code))
+			}
+			seen[code] = true
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// CheckRouteLimits checks that every route given page size limits with
+// pagination.SetRouteLimits exists on the engine, so a typo'd pattern
+// doesn't silently leave a route uncapped.
+func CheckRouteLimits() Check {
+	return func(engine *gin.Engine) error {
+		registered := map[string]bool{}
+		for _, r := range engine.Routes() {
+			registered[r.Path] = true
+		}
+		var errs []error
+		for route := range pagination.AllRouteLimits() {
+			if !registered[route] {
+				errs = append(errs, fmt.Errorf("pagination limits are set for %s, which has no route", route))
+			}
+		}
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+		return errors.Join(errs...)
+	}
+}
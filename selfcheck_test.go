@@ -0,0 +1,102 @@
+package ginapi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/pagination"
+)
+
+func TestSelfCheck(t *testing.T) {
+	pagination.SetRouteLimits("/api/galleries", 20, 50)
+	pagination.SetRouteLimits("/api/galeries", 20, 50)
+
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.GET("/api/galleries", func(c *gin.Context) {})
+	router.GET("/apidocs", func(c *gin.Context) {})
+	router.GET("/galleries", func(c *gin.Context) {})
+	router.GET("/id/:id", func(c *gin.Context) {})
+
+	bare := gin.New()
+	bare.GET("/health", func(c *gin.Context) {})
+
+	tests := []struct {
+		name   string
+		engine *gin.Engine
+		checks []ginapi.Check
+		want   []string
+	}{
+		{
+			name:   "all pass",
+			engine: router,
+			checks: []ginapi.Check{
+				ginapi.RequireMiddleware(ginapi.FuncName(middleware.Recovery)),
+				ginapi.CheckLanguages([]string{"en", "ja"}),
+				ginapi.CheckLanguageRoutes([]string{"en", "ja", "id"}, []string{"id"}),
+				ginapi.CheckSkipPrefixes("NormalizePath", []string{"/api/"}),
+				ginapi.CheckErrorCodes("gallery_locked", "quota_exceeded"),
+			},
+		},
+		{
+			name:   "missing middleware",
+			engine: bare,
+			checks: []ginapi.Check{ginapi.RequireMiddleware(ginapi.FuncName(middleware.Recovery))},
+			want:   []string{"required middleware middleware.Recovery is missing from GET /health"},
+		},
+		{
+			name:   "languages",
+			engine: router,
+			checks: []ginapi.Check{ginapi.CheckLanguages(nil), ginapi.CheckLanguages([]string{"en", "zh-TW"})},
+			want:   []string{"no supported languages configured", `supported language "zh-TW" must be lowercase`},
+		},
+		{
+			name:   "language routes",
+			engine: router,
+			checks: []ginapi.Check{ginapi.CheckLanguageRoutes([]string{"en", "id"}, nil)},
+			want:   []string{`routes GET /id/:id start with supported language "id"`},
+		},
+		{
+			name:   "skip prefixes",
+			engine: router,
+			checks: []ginapi.Check{ginapi.CheckSkipPrefixes("NormalizePath", []string{"/api", "/"})},
+			want:   []string{`NormalizePath skip prefix "/api" shadows /apidocs`, `NormalizePath skip prefix "/" matches every route`},
+		},
+		{
+			name:   "error codes",
+			engine: router,
+			checks: []ginapi.Check{ginapi.CheckErrorCodes("gallery_locked", "internal", "gallery_locked")},
+			want:   []string{`error code "internal" collides with a built-in code`, `error code "gallery_locked" is registered more than once`},
+		},
+		{
+			name:   "route limits",
+			engine: router,
+			checks: []ginapi.Check{ginapi.CheckRouteLimits()},
+			want:   []string{"pagination limits are set for /api/galeries, which has no route"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ginapi.SelfCheck(tt.engine, tt.checks...)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error containing %q, got %q", want, err.Error())
+				}
+			}
+		})
+	}
+}
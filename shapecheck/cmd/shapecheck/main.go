@@ -0,0 +1,14 @@
+// The shapecheck command runs the shapecheck analyzer, standalone or as a
+// go vet tool:
+//
+//	shapecheck ./...
+//	go vet -vettool=$(which shapecheck) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/doujins-org/ginapi/shapecheck"
+)
+
+func main() { singlechecker.Main(shapecheck.Analyzer) }
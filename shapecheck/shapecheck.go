@@ -0,0 +1,265 @@
+// Package shapecheck is a go vet analyzer enforcing the JSON conventions of
+// ginapi responses on the types passed to the response helpers: JSON names
+// are snake_case, every field has a json tag, and objects (the obj of
+// response.Object and friends, and the items of lists) have an "object"
+// field. It finds at build time what response.SetShapeCheck logs at
+// runtime. Run it with go vet:
+//
+//	go install github.com/doujins-org/ginapi/shapecheck/cmd/shapecheck
+//	go vet -vettool=$(which shapecheck) ./...
+//
+// Only types declared in the package being checked are reported, at their
+// fields, so each problem is reported once where it can be fixed. Types
+// with their own MarshalJSON or MarshalText are skipped.
+package shapecheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// responsePath is the import path of the response helpers.
+const responsePath = "github.com/doujins-org/ginapi/response"
+
+// Analyzer reports response types breaking the JSON conventions.
+var Analyzer = &analysis.Analyzer{
+	Name:     "shapecheck",
+	Doc:      "check that response types have snake_case json tags and an object field",
+	URL:      "https://pkg.go.dev/github.com/doujins-org/ginapi/shapecheck",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// snakeCase matches the JSON names we accept.
+var snakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+func run(pass *analysis.Pass) (any, error) {
+	if pass.Pkg.Path() == responsePath {
+		return nil, nil
+	}
+	c := &checker{pass: pass, seen: map[types.Type]bool{}, reported: map[token.Pos]bool{}}
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	ins.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+		if !ok || fn.Pkg() == nil || fn.Pkg().Path() != responsePath {
+			return
+		}
+		sig := fn.Type().(*types.Signature)
+		for i, arg := range call.Args {
+			t := pass.TypesInfo.TypeOf(arg)
+			if t == nil {
+				continue
+			}
+			switch paramName(sig, i) {
+			case "obj":
+				c.object(t, "an object")
+			case "data":
+				if s, ok := t.Underlying().(*types.Slice); ok {
+					c.object(s.Elem(), "a list item")
+				} else {
+					c.value(t)
+				}
+			default:
+				c.value(t)
+			}
+		}
+	})
+	return nil, nil
+}
+
+// paramName returns the name of the parameter argument i is passed to.
+func paramName(sig *types.Signature, i int) string {
+	params := sig.Params()
+	if params.Len() == 0 {
+		return ""
+	}
+	if i >= params.Len() {
+		i = params.Len() - 1 // variadic
+	}
+	return params.At(i).Name()
+}
+
+// checker reports the violations of the types sent by one package.
+type checker struct {
+	pass     *analysis.Pass
+	seen     map[types.Type]bool
+	reported map[token.Pos]bool
+}
+
+// object checks t, sent as an API object (as what, in messages): a struct
+// with an "object" field, whose "data" items are objects too. Envelopes from
+// other packages, such as response.List, only have their items checked.
+func (c *checker) object(t types.Type, as string) {
+	if s, ok := deref(t).Underlying().(*types.Struct); ok && !customJSON(deref(t)) {
+		fields := jsonFields(s)
+		if _, name, pos, ok := c.local(t); ok {
+			if _, ok := fields["object"]; !ok {
+				c.report(pos, "%s is sent as %s but has no \"object\" field", name, as)
+			}
+		}
+		if data, ok := fields["data"]; ok {
+			if sl, ok := deref(data).Underlying().(*types.Slice); ok {
+				if item, itemName, itemPos, ok := c.local(sl.Elem()); ok {
+					if _, ok := jsonFields(item)["object"]; !ok {
+						c.report(itemPos, "%s is sent as a list item but has no \"object\" field", itemName)
+					}
+				}
+			}
+		}
+	}
+	c.value(t)
+}
+
+// value checks the JSON names within t, and within the types of its
+// fields when it is declared elsewhere.
+func (c *checker) value(t types.Type) {
+	t = deref(t)
+	if c.seen[t] || customJSON(t) {
+		return
+	}
+	c.seen[t] = true
+
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		c.value(u.Elem())
+	case *types.Array:
+		c.value(u.Elem())
+	case *types.Map:
+		c.value(u.Elem())
+	case *types.Struct:
+		if s, name, _, ok := c.local(t); ok {
+			c.fields(s, name)
+			return
+		}
+		for i := 0; i < u.NumFields(); i++ {
+			if f := u.Field(i); f.Exported() || f.Embedded() {
+				c.value(f.Type())
+			}
+		}
+	}
+}
+
+// fields checks the fields of struct s, named name in messages.
+func (c *checker) fields(s *types.Struct, name string) {
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		tag, hasTag := reflect.StructTag(s.Tag(i)).Lookup("json")
+		jsonName, _, _ := strings.Cut(tag, ",")
+		if tag == "-" {
+			continue
+		}
+		if f.Embedded() && jsonName == "" {
+			// Embedded structs are inlined, like encoding/json does
+			if es, _, _, ok := c.local(f.Type()); ok {
+				c.fields(es, name)
+				continue
+			}
+		}
+		if !f.Exported() {
+			continue
+		}
+		switch {
+		case !hasTag:
+			c.report(f.Pos(), "%s.%s has no json tag; it is sent as %q", name, f.Name(), f.Name())
+		case jsonName == "":
+			c.report(f.Pos(), "%s.%s has a json tag without a name; it is sent as %q", name, f.Name(), f.Name())
+		case !snakeCase.MatchString(jsonName):
+			c.report(f.Pos(), "JSON name %q of %s.%s isn't snake_case", jsonName, name, f.Name())
+		}
+		c.value(f.Type())
+	}
+}
+
+// local returns the struct t (or what it points to) is, if it is declared
+// in the package being checked and encoded by encoding/json, with its name
+// and position for messages.
+func (c *checker) local(t types.Type) (*types.Struct, string, token.Pos, bool) {
+	t = deref(t)
+	s, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil, "", token.NoPos, false
+	}
+	switch t := t.(type) {
+	case *types.Named:
+		obj := t.Obj()
+		if obj.Pkg() != c.pass.Pkg || customJSON(t) {
+			return nil, "", token.NoPos, false
+		}
+		return s, obj.Name(), obj.Pos(), true
+	default:
+		// An anonymous struct, declared here if its fields are
+		if s.NumFields() == 0 || s.Field(0).Pkg() != c.pass.Pkg {
+			return nil, "", token.NoPos, false
+		}
+		return s, "struct", s.Field(0).Pos(), true
+	}
+}
+
+// report reports a problem at pos, once.
+func (c *checker) report(pos token.Pos, format string, args ...any) {
+	if c.reported[pos] {
+		return
+	}
+	c.reported[pos] = true
+	c.pass.Report(analysis.Diagnostic{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// jsonFields returns the field types of struct s by JSON name, including
+// those of embedded structs.
+func jsonFields(s *types.Struct) map[string]types.Type {
+	fields := map[string]types.Type{}
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		name, _, _ := strings.Cut(reflect.StructTag(s.Tag(i)).Get("json"), ",")
+		if f.Embedded() && name == "" {
+			if es, ok := deref(f.Type()).Underlying().(*types.Struct); ok {
+				for n, ft := range jsonFields(es) {
+					fields[n] = ft
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name()
+		}
+		if f.Exported() && name != "-" {
+			fields[name] = f.Type()
+		}
+	}
+	return fields
+}
+
+// customJSON reports whether t encodes itself.
+func customJSON(t types.Type) bool {
+	ptr := types.NewPointer(t)
+	for _, method := range []string{"MarshalJSON", "MarshalText"} {
+		if obj, _, _ := types.LookupFieldOrMethod(ptr, true, nil, method); obj != nil {
+			if _, ok := obj.(*types.Func); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// deref returns the type pointers to t point to.
+func deref(t types.Type) types.Type {
+	for {
+		p, ok := t.Underlying().(*types.Pointer)
+		if !ok {
+			return t
+		}
+		t = p.Elem()
+	}
+}
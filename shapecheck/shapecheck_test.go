@@ -0,0 +1,13 @@
+package shapecheck_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/doujins-org/ginapi/shapecheck"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), shapecheck.Analyzer, "a")
+}
@@ -0,0 +1,68 @@
+package a
+
+import (
+	"time"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+type Author struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"` // want `JSON name "displayName" of Author.DisplayName isn't snake_case`
+}
+
+type Gallery struct {
+	Object    string            `json:"object"`
+	ID        string            `json:"id"`
+	CoverURL  string            `json:"coverURL"` // want `JSON name "coverURL" of Gallery.CoverURL isn't snake_case`
+	PageCount int               // want `Gallery.PageCount has no json tag; it is sent as "PageCount"`
+	Author    *Author           `json:"author"`
+	Tags      map[string]string `json:"tags"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Secret    string            `json:"-"`
+	internal  string
+}
+
+type Base struct {
+	Object string `json:"object"`
+	ID     string `json:"id"`
+}
+
+type Tag struct {
+	Base
+	Name string `json:"name,omitempty"`
+}
+
+type Row struct { // want `Row is sent as a list item but has no "object" field`
+	Name string `json:"name"`
+}
+
+type Summary struct { // want `Summary is sent as an object but has no "object" field`
+	Count int `json:"count"`
+}
+
+type Page struct { // want `Page is sent as a list item but has no "object" field`
+	PageNumber int `json:"pageNumber"` // want `JSON name "pageNumber" of Page.PageNumber isn't snake_case`
+}
+
+type Section struct {
+	TotalCount int `json:"totalCount"` // want `JSON name "totalCount" of Section.TotalCount isn't snake_case`
+}
+
+type Custom struct {
+	Whatever string
+}
+
+func (Custom) MarshalJSON() ([]byte, error) { return []byte(`{}`), nil }
+
+func handlers(c any) {
+	response.Object(c, &Gallery{})
+	response.Object(c, Tag{})
+	response.Object(c, Summary{})
+	response.Object(c, Custom{})
+	response.ListResponse(c, []Row{}, 0, 10, 0)
+	response.ListResponse(c, []Tag{}, 0, 10, 0)
+	response.Object(c, response.NewList([]Tag{}, 0, 10, 0))
+	response.Object(c, response.NewList([]Page{}, 0, 10, 0))
+	response.Partial(c, Section{}, nil)
+}
@@ -0,0 +1,17 @@
+// Package response is a stub of the response helpers for the analyzer tests.
+package response
+
+func Object(c any, obj any) {}
+
+func ListResponse[T any](c any, data []T, total int64, limit, offset int) {}
+
+func Partial(c any, data any, failed []string) {}
+
+type List[T any] struct {
+	Object string `json:"object"`
+	Data   []T    `json:"data"`
+}
+
+func NewList[T any](data []T, total int64, limit, offset int) List[T] {
+	return List[T]{Object: "list", Data: data}
+}
@@ -0,0 +1,100 @@
+package slug
+
+import "strings"
+
+// kana are the Hepburn romanizations of hiragana; katakana are mapped to
+// hiragana first.
+var kana = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n", 'ゔ': "vu",
+}
+
+// smallKana are the kana written small to modify the one before, like the
+// "ゃ" of "きゃ" (kya).
+var smallKana = map[rune]string{
+	'ゃ': "ya", 'ゅ': "yu", 'ょ': "yo",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+}
+
+// Romaji transliterates hiragana and katakana to Hepburn romaji, leaving
+// kanji and other text as-is. Runs of kana become separate words, since
+// Japanese doesn't separate them with spaces: "進撃の巨人" becomes
+// "進撃 no 巨人". Long vowel marks are dropped ("ラーメン" is "ramen").
+//
+// It is Make's transliteration for Language "ja". Kanji need a dictionary
+// to read; pass a Transliterator wrapping one as Config.Transliterate to
+// romanize them too.
+func Romaji(s string) string {
+	var b strings.Builder
+	var word []string // romanized kana of the current run
+	sokuon := false   // a small tsu doubles the next consonant
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		b.WriteByte(' ')
+		b.WriteString(strings.Join(word, ""))
+		b.WriteByte(' ')
+		word = word[:0]
+		sokuon = false
+	}
+
+	for _, r := range s {
+		if r >= 'ァ' && r <= 'ヶ' {
+			r -= 'ァ' - 'ぁ'
+		}
+		switch {
+		case r == 'っ':
+			sokuon = true
+		case r == 'ー':
+			// dropped, like the macron Hepburn would write
+		case smallKana[r] != "" && len(word) > 0:
+			word[len(word)-1] = combine(word[len(word)-1], smallKana[r])
+		case kana[r] != "" || smallKana[r] != "":
+			syllable := kana[r]
+			if syllable == "" {
+				syllable = smallKana[r]
+			}
+			if sokuon {
+				if strings.HasPrefix(syllable, "ch") {
+					syllable = "t" + syllable
+				} else if c := syllable[0]; !strings.ContainsRune("aeioun", rune(c)) {
+					syllable = string(c) + syllable
+				}
+				sokuon = false
+			}
+			word = append(word, syllable)
+		default:
+			flush()
+			b.WriteRune(r)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// combine returns the syllable of kana followed by a small kana: "ki" and
+// "ya" make "kya", "shi" and "ya" make "sha", and "fu" and "a" make "fa".
+func combine(syllable, small string) string {
+	base := strings.TrimRight(syllable, "aeiou")
+	if strings.HasPrefix(small, "y") && (strings.HasSuffix(base, "sh") || strings.HasSuffix(base, "ch") || strings.HasSuffix(base, "j")) {
+		return base + small[1:]
+	}
+	if base == "" {
+		base = "w" // "ウィ" is "wi"
+	}
+	return base + small
+}
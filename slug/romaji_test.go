@@ -0,0 +1,37 @@
+package slug_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/doujins-org/ginapi/slug"
+)
+
+func TestRomaji(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"さくら", "sakura"},
+		{"カタカナ", "katakana"},
+		{"きょうと", "kyouto"},
+		{"しゃしん", "shashin"},
+		{"ちゃ", "cha"},
+		{"じゅう", "juu"},
+		{"きって", "kitte"},
+		{"まっちゃ", "matcha"},
+		{"ラーメン", "ramen"},
+		{"ファイル", "fairu"},
+		{"ウィキ", "wiki"},
+		{"東京タワー", "東京 tawa"},
+		{"abc", "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := strings.Join(strings.Fields(slug.Romaji(tt.in)), " "); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
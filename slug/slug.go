@@ -0,0 +1,165 @@
+// Package slug generates URL slugs from titles and builds and parses path
+// segments combining a slug with a prefixed ID, like our gallery URLs:
+//
+//	seg := slug.Join(slug.Make(g.Title, slug.Config{Language: "ja"}), g.ID)
+//	// "/galleries/shingeki-no-kyojin-gal_3f9a2c"
+//
+//	_, id, ok := slug.Parse(c.Param("segment"), "gal_")
+//
+// The slug is for people and search engines; handlers look records up by
+// the ID only, so renamed titles and hand-edited URLs keep working.
+package slug
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultMaxLength is the longest slug Make returns by default, in runes.
+const DefaultMaxLength = 60
+
+// Transliterator rewrites text into Latin letters where it can, leaving
+// what it can't as-is, e.g. Romaji, or a wrapper around a pinyin
+// dictionary.
+type Transliterator func(s string) string
+
+// Config configures Make.
+type Config struct {
+	// Language of the text, selecting the transliteration: "ja" romanizes
+	// kana (see Romaji), "de" writes umlauts as "ae", "oe", and "ue"
+	Language string
+	// Transliterate replaces the Language's transliteration, e.g. for
+	// pinyin, which needs a dictionary this package doesn't ship
+	Transliterate Transliterator
+	// MaxLength in runes (defaults to DefaultMaxLength). Longer slugs are
+	// cut at a hyphen where possible.
+	MaxLength int
+}
+
+// transliterators are the built-in transliterations, by language.
+var transliterators = map[string]Transliterator{
+	"ja": Romaji,
+}
+
+// latinFolds are the Latin letters that don't decompose into a base letter
+// and accents.
+var latinFolds = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d",
+	'ł': "l", 'þ': "th", 'ı': "i", 'ŋ': "ng",
+}
+
+// germanFolds are the German spellings of umlauts.
+var germanFolds = map[rune]string{'ä': "ae", 'ö': "oe", 'ü': "ue"}
+
+// Make returns the slug of s: lowercase, with accents removed from Latin
+// letters, other scripts transliterated per cfg or kept as Unicode letters,
+// and everything else collapsed into single hyphens:
+//
+//	slug.Make("Crème Brûlée: A Story", slug.Config{})   // "creme-brulee-a-story"
+//	slug.Make("ねこのまち", slug.Config{Language: "ja"}) // "nekonomachi"
+//	slug.Make("進撃の巨人", slug.Config{Language: "ja"}) // "進撃-no-巨人"
+//
+// Unicode slugs are valid in paths; browsers show them as typed and send
+// them percent-encoded. Make returns "" for text with no letters or
+// digits; see Join.
+func Make(s string, cfg Config) string {
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = DefaultMaxLength
+	}
+	lang := strings.ToLower(cfg.Language)
+	if base, _, found := strings.Cut(lang, "-"); found {
+		lang = base
+	}
+
+	// NFKC first, so full-width letters and half-width kana are plain
+	s = norm.NFKC.String(s)
+	translit := cfg.Transliterate
+	if translit == nil {
+		translit = transliterators[lang]
+	}
+	if translit != nil {
+		s = translit(s)
+	}
+
+	var b strings.Builder
+	hyphen := false
+	write := func(s string) {
+		if hyphen && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		hyphen = false
+		b.WriteString(s)
+	}
+	for _, r := range s {
+		r = unicode.ToLower(r)
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			write(string(r))
+		case r == '\'' || r == '’':
+			// "don't" becomes "dont", not "don-t"
+		case lang == "de" && germanFolds[r] != "":
+			write(germanFolds[r])
+		case latinFolds[r] != "":
+			write(latinFolds[r])
+		case unicode.Is(unicode.Latin, r):
+			write(foldAccents(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.M, r):
+			write(string(r))
+		default:
+			hyphen = true
+		}
+	}
+	return truncate(b.String(), cfg.MaxLength)
+}
+
+// foldAccents returns r without its accents, e.g. "e" for 'é'.
+func foldAccents(r rune) string {
+	var b strings.Builder
+	for _, d := range norm.NFD.String(string(r)) {
+		if !unicode.Is(unicode.Mn, d) {
+			b.WriteRune(d)
+		}
+	}
+	return b.String()
+}
+
+// truncate cuts s to max runes, at the last hyphen if there is one.
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)[:max]
+	cut := string(runes)
+	if i := strings.LastIndexByte(cut, '-'); i > 0 {
+		return cut[:i]
+	}
+	return strings.TrimRight(cut, "-")
+}
+
+// Join returns the path segment for a slug and a prefixed ID, like
+// "my-title-gal_3f9a2c", or just the ID if the slug is empty.
+func Join(slug, id string) string {
+	if slug == "" {
+		return id
+	}
+	return slug + "-" + id
+}
+
+// Parse splits a segment built by Join into its slug and ID. prefix is the
+// ID's prefix, like "gal_"; IDs may contain anything but hyphens, which
+// slugs use to separate words, so the ID is whatever follows the last
+// one. Segments that are just the ID parse with an empty slug. ok is false
+// when the ID is missing or doesn't start with prefix.
+func Parse(segment, prefix string) (slug, id string, ok bool) {
+	id = segment
+	if i := strings.LastIndexByte(segment, '-'); i >= 0 {
+		slug, id = segment[:i], segment[i+1:]
+	}
+	if len(id) <= len(prefix) || !strings.HasPrefix(id, prefix) {
+		return "", "", false
+	}
+	return slug, id, true
+}
@@ -0,0 +1,74 @@
+package slug_test
+
+import (
+	"testing"
+
+	"github.com/doujins-org/ginapi/slug"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		cfg  slug.Config
+		want string
+	}{
+		{"ascii", "Hello, World!", slug.Config{}, "hello-world"},
+		{"accents", "Crème Brûlée: A Story", slug.Config{}, "creme-brulee-a-story"},
+		{"apostrophe", "Don't Stop", slug.Config{}, "dont-stop"},
+		{"german", "Über Größe", slug.Config{Language: "de"}, "ueber-groesse"},
+		{"german without language", "Über Größe", slug.Config{}, "uber-grosse"},
+		{"full-width", "ＡＢＣ１２３", slug.Config{}, "abc123"},
+		{"kana", "ねこのまち", slug.Config{Language: "ja"}, "nekonomachi"},
+		{"kanji kept", "進撃の巨人", slug.Config{Language: "ja-JP"}, "進撃-no-巨人"},
+		{"kana without language", "ねこ", slug.Config{}, "ねこ"},
+		{"chinese kept", "你好 世界", slug.Config{Language: "zh"}, "你好-世界"},
+		{"custom transliterator", "你好", slug.Config{
+			Language:      "zh",
+			Transliterate: func(s string) string { return map[string]string{"你好": "ni hao"}[s] },
+		}, "ni-hao"},
+		{"truncated at hyphen", "one two three four", slug.Config{MaxLength: 12}, "one-two"},
+		{"truncated word", "abcdefghij", slug.Config{MaxLength: 4}, "abcd"},
+		{"no letters", "!!!", slug.Config{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slug.Make(tt.in, tt.cfg); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJoinAndParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		segment  string
+		wantSlug string
+		wantID   string
+		wantOK   bool
+	}{
+		{"slug and id", "my-title-gal_3f9a2c", "my-title", "gal_3f9a2c", true},
+		{"id only", "gal_3f9a2c", "", "gal_3f9a2c", true},
+		{"unicode slug", "進撃-no-巨人-gal_3f9a2c", "進撃-no-巨人", "gal_3f9a2c", true},
+		{"slug only", "my-title", "", "", false},
+		{"other prefix", "my-title-usr_1", "", "", false},
+		{"bare prefix", "my-title-gal_", "", "", false},
+		{"empty", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, id, ok := slug.Parse(tt.segment, "gal_")
+			if s != tt.wantSlug || id != tt.wantID || ok != tt.wantOK {
+				t.Errorf("expected (%q, %q, %v), got (%q, %q, %v)", tt.wantSlug, tt.wantID, tt.wantOK, s, id, ok)
+			}
+			if ok {
+				if joined := slug.Join(s, id); joined != tt.segment {
+					t.Errorf("expected Join to rebuild %q, got %q", tt.segment, joined)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package ginapi
+
+import (
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+	"github.com/doujins-org/ginapi/static"
+)
+
+// SPAConfig configures the SPA fallback handler.
+type SPAConfig struct {
+	// Index file served for client-side routes (defaults to "index.html")
+	Index string
+	// APIPrefixes get a JSON 404 instead of index.html (defaults to "/api/")
+	APIPrefixes []string
+	// StaticPrefixes get a 404 when the file is missing instead of index.html
+	// (e.g. "/assets/"), so a stale chunk reference fails loudly
+	StaticPrefixes []string
+	// Language, if set, redirects paths without a language prefix
+	// (see middleware.HandleLanguageRedirect) before serving index.html
+	Language *middleware.LanguageRedirectConfig
+	// EarlyHints are sent for client-side routes, including those answered
+	// with the language redirect (see static.EarlyHints), e.g. the shell's
+	// entry script and stylesheet
+	EarlyHints []static.Hint
+}
+
+// SPAFallback returns a handler for router.NoRoute that serves a single-page app from fsys.
+//
+//   - API paths get a structured JSON 404
+//   - Files that exist in fsys are served via static.Server; hashed assets
+//     (e.g. "index-4f3a9c1b.js") get immutable cache headers, other files get no-cache
+//   - Missing files under StaticPrefixes get a 404
+//   - Everything else is a client-side route: after the early hints and
+//     the language redirect (if configured), index.html is served with
+//     no-cache so deploys take effect
+//
+// Usage:
+//
+//	r.NoRoute(ginapi.SPAFallback(distFS, ginapi.SPAConfig{
+//	    Language: &middleware.LanguageRedirectConfig{Supported: []string{"en", "ja"}},
+//	}))
+func SPAFallback(fsys fs.FS, cfg SPAConfig) gin.HandlerFunc {
+	index := cfg.Index
+	if index == "" {
+		index = "index.html"
+	}
+
+	apiPrefixes := cfg.APIPrefixes
+	if apiPrefixes == nil {
+		apiPrefixes = []string{"/api/"}
+	}
+
+	assets := static.New(fsys, static.Config{})
+
+	return func(c *gin.Context) {
+		urlPath := c.Request.URL.Path
+
+		if hasAnyPrefix(urlPath, apiPrefixes) {
+			response.NotFoundWithMessage(c, "route not found")
+			return
+		}
+
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			response.NotFoundWithMessage(c, "route not found")
+			return
+		}
+
+		if name := strings.TrimPrefix(urlPath, "/"); name != index && assets.Serve(c, name) {
+			return
+		}
+
+		if hasAnyPrefix(urlPath, cfg.StaticPrefixes) {
+			response.NotFoundWithMessage(c, "asset not found")
+			return
+		}
+
+		static.SendEarlyHints(c, cfg.EarlyHints...)
+		if cfg.Language != nil && middleware.HandleLanguageRedirect(c, *cfg.Language) {
+			return
+		}
+
+		if !assets.Serve(c, index) {
+			response.NotFoundWithMessage(c, "index not found")
+		}
+	}
+}
+
+// hasAnyPrefix reports whether s starts with any of the prefixes.
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
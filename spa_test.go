@@ -0,0 +1,83 @@
+package ginapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/static"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newSPARouter() *gin.Engine {
+	fsys := fstest.MapFS{
+		"index.html":                {Data: []byte("<html>app</html>")},
+		"favicon.ico":               {Data: []byte("icon")},
+		"assets/index-4f3a9c1b.js":  {Data: []byte("console.log(1)")},
+		"assets/vendor-BfX3k2aQ.js": {Data: []byte("console.log(2)")},
+	}
+
+	router := gin.New()
+	router.NoRoute(ginapi.SPAFallback(fsys, ginapi.SPAConfig{
+		StaticPrefixes: []string{"/assets/"},
+		Language: &middleware.LanguageRedirectConfig{
+			Supported: []string{"en", "ja"},
+			Default:   "en",
+		},
+		EarlyHints: []static.Hint{{URL: "/assets/index-4f3a9c1b.js"}},
+	}))
+	return router
+}
+
+func TestSPAFallback(t *testing.T) {
+	router := newSPARouter()
+
+	tests := []struct {
+		name         string
+		target       string
+		wantStatus   int
+		wantCache    string
+		wantLocation string
+		wantBody     string
+		wantLink     bool
+	}{
+		{"hashed asset", "/assets/index-4f3a9c1b.js", http.StatusOK, static.CacheImmutable, "", "console.log(1)", false},
+		{"unhashed file", "/favicon.ico", http.StatusOK, static.CacheNoCache, "", "icon", false},
+		{"missing asset", "/assets/old-12345678.js", http.StatusNotFound, "", "", "", false},
+		{"api route", "/api/v1/missing", http.StatusNotFound, "", "", "", false},
+		{"language prefixed route", "/ja/galleries", http.StatusOK, static.CacheNoCache, "", "<html>app</html>", true},
+		{"unprefixed route redirects", "/galleries?page=2", http.StatusFound, "", "/en/galleries?page=2", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tt.target, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantCache != "" && w.Header().Get("Cache-Control") != tt.wantCache {
+				t.Errorf("expected Cache-Control '%s', got '%s'", tt.wantCache, w.Header().Get("Cache-Control"))
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("expected Location '%s', got '%s'", tt.wantLocation, got)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("expected body '%s', got '%s'", tt.wantBody, w.Body.String())
+			}
+			if hasLink := w.Header().Get("Link") != ""; hasLink != tt.wantLink {
+				t.Errorf("expected Link header %v, got '%s'", tt.wantLink, w.Header().Get("Link"))
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package static
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Hint is a resource the browser should start loading before the page
+// that needs it arrives, sent as a Link header.
+type Hint struct {
+	// URL of the resource, e.g. "/assets/index-4f3a9c1b.js"
+	URL string
+	// Rel is the link relation (defaults to "preload"), e.g.
+	// "modulepreload" or "preconnect"
+	Rel string
+	// As is the preload destination (defaults to one inferred from the
+	// URL's extension: script, style, font, image, or fetch)
+	As string
+	// Type is the resource's media type, e.g. "font/woff2", letting
+	// browsers skip formats they don't support
+	Type string
+	// CrossOrigin marks the preload anonymous-CORS, which fonts need to be
+	// reused (set automatically for fonts)
+	CrossOrigin bool
+	// Push also pushes the resource with HTTP/2 server push where the
+	// connection supports it. Most browsers ignore pushes, so this is only
+	// worth it for clients known to accept them; the Link header is always
+	// sent.
+	Push bool
+}
+
+// preloadDestinations are the preload destinations of asset extensions.
+var preloadDestinations = map[string]string{
+	".js":    "script",
+	".mjs":   "script",
+	".css":   "style",
+	".woff":  "font",
+	".woff2": "font",
+	".ttf":   "font",
+	".otf":   "font",
+	".svg":   "image",
+	".png":   "image",
+	".jpg":   "image",
+	".jpeg":  "image",
+	".webp":  "image",
+	".avif":  "image",
+	".json":  "fetch",
+}
+
+// String formats h as a Link header value, e.g.
+// `</assets/app.js>; rel=preload; as=script`.
+func (h Hint) String() string {
+	rel := h.Rel
+	if rel == "" {
+		rel = "preload"
+	}
+	as := h.As
+	if as == "" && rel == "preload" {
+		as = preloadDestinations[strings.ToLower(path.Ext(h.URL))]
+	}
+
+	var b strings.Builder
+	b.WriteString("<" + h.URL + ">; rel=" + rel)
+	if as != "" {
+		b.WriteString("; as=" + as)
+	}
+	if h.Type != "" {
+		b.WriteString(`; type="` + h.Type + `"`)
+	}
+	if h.CrossOrigin || as == "font" {
+		b.WriteString("; crossorigin")
+	}
+	return b.String()
+}
+
+// Hints returns preload hints for the named files, served under prefix
+// through their fingerprinted paths (see Path):
+//
+//	assets.Hints("/assets", "css/app.css", "fonts/inter.woff2")
+//	// </assets/css/app.3f2a9c1b0d.css>; rel=preload; as=style, ...
+func (s *Server) Hints(prefix string, names ...string) []Hint {
+	prefix = strings.TrimSuffix(prefix, "/")
+	hints := make([]Hint, len(names))
+	for i, name := range names {
+		hints[i] = Hint{URL: prefix + "/" + s.Path(name)}
+		if strings.HasSuffix(name, ".woff2") {
+			hints[i].Type = "font/woff2"
+		}
+	}
+	return hints
+}
+
+// EarlyHints returns middleware that sends hints as a 103 Early Hints
+// response before running the handlers, so browsers fetch the SPA shell's
+// critical assets while the server is still working on the page, and adds
+// them as Link headers to the final response for browsers and CDNs that
+// ignore 103. Install it on the frontend route groups:
+//
+//	frontend := router.Group("/:lang", static.EarlyHints(
+//	    assets.Hints("/assets", "js/app.js", "css/app.css")...,
+//	))
+//
+// 103 is only sent to HTTP/1.1 and later clients, to GET requests, and when
+// net/http's server is behind gin's writer (not a wrapper installed by
+// earlier middleware, nor a test recorder). Requests preferring JSON to
+// HTML by their Accept header get no hints, so API calls sharing a
+// frontend group don't carry them.
+func EarlyHints(hints ...Hint) gin.HandlerFunc {
+	links := linkValues(hints)
+
+	return func(c *gin.Context) {
+		sendLinks(c, hints, links)
+		c.Next()
+	}
+}
+
+// SendEarlyHints sends hints like EarlyHints, from a handler, e.g. only
+// for the routes serving the SPA shell.
+func SendEarlyHints(c *gin.Context, hints ...Hint) {
+	sendLinks(c, hints, linkValues(hints))
+}
+
+// linkValues returns the Link header values of hints.
+func linkValues(hints []Hint) []string {
+	links := make([]string, len(hints))
+	for i, h := range hints {
+		links[i] = h.String()
+	}
+	return links
+}
+
+// sendLinks adds links, the values of hints, as Link headers, pushes the
+// hints marked Push, and sends the links as a 103 Early Hints response
+// where possible.
+func sendLinks(c *gin.Context, hints []Hint, links []string) {
+	if len(links) == 0 || !wantsHTML(c.Request) {
+		return
+	}
+	h := c.Writer.Header()
+	for _, link := range links {
+		h.Add("Link", link)
+	}
+	if c.Request.Method != http.MethodGet || !c.Request.ProtoAtLeast(1, 1) || c.Writer.Written() {
+		return
+	}
+	if pusher := c.Writer.Pusher(); pusher != nil {
+		for _, hint := range hints {
+			if hint.Push {
+				_ = pusher.Push(hint.URL, nil)
+			}
+		}
+	}
+	// Only net/http's server knows to treat 1xx as informational;
+	// httptest.ResponseRecorder would take it as the final status
+	if c.Request.Context().Value(http.ServerContextKey) == nil {
+		return
+	}
+	if u, ok := c.Writer.(interface{ Unwrap() http.ResponseWriter }); ok {
+		// gin's WriteHeader only records the status; write the 103 directly
+		u.Unwrap().WriteHeader(http.StatusEarlyHints)
+	}
+}
+
+// wantsHTML reports whether the client prefers HTML to JSON, as browsers
+// loading a page do.
+func wantsHTML(r *http.Request) bool {
+	mediaType, _ := response.Acceptable(r, "text/html", "application/json")
+	return mediaType == "text/html"
+}
@@ -0,0 +1,127 @@
+package static_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/static"
+)
+
+func TestHintString(t *testing.T) {
+	tests := []struct {
+		name string
+		hint static.Hint
+		want string
+	}{
+		{"script", static.Hint{URL: "/assets/app.js"}, "</assets/app.js>; rel=preload; as=script"},
+		{"style", static.Hint{URL: "/assets/app.css"}, "</assets/app.css>; rel=preload; as=style"},
+		{"font", static.Hint{URL: "/assets/inter.woff2", Type: "font/woff2"}, `</assets/inter.woff2>; rel=preload; as=font; type="font/woff2"; crossorigin`},
+		{"module preload", static.Hint{URL: "/assets/app.js", Rel: "modulepreload"}, "</assets/app.js>; rel=modulepreload"},
+		{"preconnect", static.Hint{URL: "https://cdn.example.com", Rel: "preconnect", CrossOrigin: true}, "<https://cdn.example.com>; rel=preconnect; crossorigin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hint.String(); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestServerHints(t *testing.T) {
+	_, assets := newStaticRouter()
+	hints := assets.Hints("/assets/", "css/app.css", "fonts/inter.woff2")
+	if len(hints) != 2 {
+		t.Fatalf("expected 2 hints, got %d", len(hints))
+	}
+	if want := "/assets/" + assets.Path("css/app.css"); hints[0].URL != want {
+		t.Errorf("expected %s, got %s", want, hints[0].URL)
+	}
+	if hints[1].Type != "font/woff2" {
+		t.Errorf("expected the woff2 type, got %+v", hints[1])
+	}
+}
+
+func TestEarlyHints(t *testing.T) {
+	router := gin.New()
+	frontend := router.Group("/:lang", static.EarlyHints(static.Hint{URL: "/assets/app.js"}))
+	frontend.GET("/galleries", func(c *gin.Context) { c.String(http.StatusOK, "<html>app</html>") })
+	frontend.POST("/galleries", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		method    string
+		wantEarly bool
+	}{
+		{"GET sends 103", http.MethodGet, true},
+		{"POST only sets Link", http.MethodPost, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var early []string
+			trace := &httptrace.ClientTrace{
+				Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+					if code == http.StatusEarlyHints {
+						early = append(early, header.Values("Link")...)
+					}
+					return nil
+				},
+			}
+			req, _ := http.NewRequest(tt.method, srv.URL+"/ja/galleries", nil)
+			req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			want := "</assets/app.js>; rel=preload; as=script"
+			if got := resp.Header.Get("Link"); got != want {
+				t.Errorf("expected Link %s on the final response, got %s", want, got)
+			}
+			if gotEarly := strings.Join(early, ", ") == want; gotEarly != tt.wantEarly {
+				t.Errorf("expected early hints %v, got %v", tt.wantEarly, early)
+			}
+		})
+	}
+}
+
+func TestEarlyHintsSkipJSON(t *testing.T) {
+	router := gin.New()
+	router.GET("/:lang/galleries", static.EarlyHints(static.Hint{URL: "/assets/app.js", Push: true}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		accept   string
+		wantLink bool
+	}{
+		{"text/html", true},
+		{"*/*", true},
+		{"application/json", false},
+		{"application/json, text/html;q=0.5", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ja/galleries", nil)
+			req.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Link") != ""; got != tt.wantLink {
+				t.Errorf("expected Link %v, got %q", tt.wantLink, w.Header().Get("Link"))
+			}
+		})
+	}
+}
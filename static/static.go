@@ -0,0 +1,242 @@
+// Package static serves embedded or on-disk assets with content-hash cache
+// busting, long-lived caching for fingerprinted files, and ETags.
+package static
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Cache-Control values used for assets.
+const (
+	CacheImmutable = "public, max-age=31536000, immutable"
+	CacheNoCache   = "no-cache"
+)
+
+// hashLength is the number of hex characters used in fingerprinted names.
+const hashLength = 10
+
+// contentTypes covers types that mime.TypeByExtension gets wrong or misses on
+// minimal container images.
+var contentTypes = map[string]string{
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".css":         "text/css; charset=utf-8",
+	".html":        "text/html; charset=utf-8",
+	".json":        "application/json",
+	".map":         "application/json",
+	".webmanifest": "application/manifest+json",
+	".svg":         "image/svg+xml",
+	".webp":        "image/webp",
+	".avif":        "image/avif",
+	".ico":         "image/x-icon",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".ttf":         "font/ttf",
+	".otf":         "font/otf",
+	".wasm":        "application/wasm",
+}
+
+// ContentType returns the Content-Type for a file name based on its extension.
+func ContentType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if ct, ok := contentTypes[ext]; ok {
+		return ct
+	}
+	if ct := mime.TypeByExtension(ext); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// Config configures an asset Server.
+type Config struct {
+	// CacheControl for files without a content hash in their name (defaults to "no-cache")
+	CacheControl string
+}
+
+// Server serves files from an fs.FS.
+//
+// Files whose names already carry a bundler hash (see IsHashedAsset) and
+// files requested through a fingerprinted path from Server.Path are served
+// with immutable caching. All files get a strong ETag from their content.
+type Server struct {
+	fsys         fs.FS
+	cacheControl string
+	assets       sync.Map // name -> *asset
+}
+
+// asset holds the content hash for a file, and the modification time and
+// size it was computed for.
+type asset struct {
+	hash    string
+	modTime time.Time
+	size    int64
+}
+
+// New returns a Server for fsys. Hashes are computed lazily on first access,
+// and recomputed when a file changes.
+func New(fsys fs.FS, cfg Config) *Server {
+	cacheControl := cfg.CacheControl
+	if cacheControl == "" {
+		cacheControl = CacheNoCache
+	}
+	return &Server{fsys: fsys, cacheControl: cacheControl}
+}
+
+// Path returns the fingerprinted path for name, e.g. "css/app.css" ->
+// "css/app.3f2a9c1b0d.css". Returns name unchanged if the file doesn't exist.
+func (s *Server) Path(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	a, ok := s.asset(name)
+	if !ok {
+		return name
+	}
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + a.hash + ext
+}
+
+// Register registers GET and HEAD routes serving the Server under prefix.
+//
+//	assets.Register(router, "/assets")
+func (s *Server) Register(r gin.IRoutes, prefix string) {
+	pattern := strings.TrimSuffix(prefix, "/") + "/*filepath"
+	r.GET(pattern, s.Handler())
+	r.HEAD(pattern, s.Handler())
+}
+
+// Handler returns a handler serving the file named by the "filepath" route param.
+// Missing files get a structured 404.
+func (s *Server) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.Serve(c, c.Param("filepath")) {
+			response.NotFoundWithMessage(c, "asset not found")
+		}
+	}
+}
+
+// Serve writes the named file to c with cache headers and ETag.
+// Returns false (writing nothing) if the file doesn't exist.
+func (s *Server) Serve(c *gin.Context, name string) bool {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return false
+	}
+
+	cacheControl := s.cacheControl
+	if IsHashedAsset(name) {
+		cacheControl = CacheImmutable
+	}
+
+	a, ok := s.asset(name)
+	if !ok {
+		// Fingerprinted path from Path: "app.<hash>.css" -> "app.css"
+		original, hash, found := splitFingerprint(name)
+		if !found {
+			return false
+		}
+		if a, ok = s.asset(original); !ok {
+			return false
+		}
+		name = original
+		if hash == a.hash {
+			cacheControl = CacheImmutable
+		} else {
+			// Stale fingerprint - serve current content, but don't pin it under the old URL
+			cacheControl = CacheNoCache
+		}
+	}
+
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		return false
+	}
+
+	c.Header("Cache-Control", cacheControl)
+	c.Header("Content-Type", ContentType(name))
+	c.Header("ETag", `"`+a.hash+`"`)
+	http.ServeContent(c.Writer, c.Request, path.Base(name), a.modTime, rs)
+	return true
+}
+
+// asset returns the cached hash for name, computing it on first access
+// and again when the file's modification time or size changes, so files
+// edited on disk don't keep their old ETag and fingerprint.
+func (s *Server) asset(name string) (*asset, bool) {
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil || info.IsDir() {
+		return nil, false
+	}
+	if v, ok := s.assets.Load(name); ok {
+		if a := v.(*asset); a.modTime.Equal(info.ModTime()) && a.size == info.Size() {
+			return a, true
+		}
+	}
+
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, false
+	}
+
+	a := &asset{
+		hash:    hex.EncodeToString(h.Sum(nil))[:hashLength],
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}
+	s.assets.Store(name, a)
+	return a, true
+}
+
+// splitFingerprint splits "dir/app.<hash>.css" into "dir/app.css" and the hash.
+func splitFingerprint(name string) (original, hash string, ok bool) {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	dot := strings.LastIndex(stem, ".")
+	if dot < 0 || len(stem)-dot-1 != hashLength {
+		return "", "", false
+	}
+	hash = stem[dot+1:]
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", "", false
+	}
+	return stem[:dot] + ext, hash, true
+}
+
+// hashedAssetPattern matches bundler output like "main.3f2a9c1b.js" or "index-BfX3k2aQ.js".
+var hashedAssetPattern = regexp.MustCompile(`[.-]([A-Za-z0-9_]{8,})\.[A-Za-z0-9]+$`)
+
+// IsHashedAsset reports whether a file name carries a bundler content hash
+// and can therefore be cached forever.
+func IsHashedAsset(name string) bool {
+	m := hashedAssetPattern.FindStringSubmatch(path.Base(name))
+	if m == nil {
+		return false
+	}
+	// Require a digit so plain words like "index-component.js" don't qualify
+	return strings.ContainsAny(m[1], "0123456789")
+}
@@ -0,0 +1,175 @@
+package static_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/static"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newStaticRouter() (*gin.Engine, *static.Server) {
+	fsys := fstest.MapFS{
+		"css/app.css":          {Data: []byte("body{}")},
+		"fonts/inter.woff2":    {Data: []byte("font")},
+		"img/cover.webp":       {Data: []byte("webp")},
+		"js/index-4f3a9c1b.js": {Data: []byte("console.log(1)")},
+	}
+
+	assets := static.New(fsys, static.Config{})
+	router := gin.New()
+	assets.Register(router, "/assets")
+	return router, assets
+}
+
+func serve(router *gin.Engine, target string, header ...string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestServeContentTypes(t *testing.T) {
+	router, _ := newStaticRouter()
+
+	tests := map[string]string{
+		"/assets/fonts/inter.woff2": "font/woff2",
+		"/assets/img/cover.webp":    "image/webp",
+		"/assets/css/app.css":       "text/css; charset=utf-8",
+	}
+	for target, want := range tests {
+		w := serve(router, target)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d", target, w.Code)
+		}
+		if got := w.Header().Get("Content-Type"); got != want {
+			t.Errorf("%s: expected Content-Type '%s', got '%s'", target, want, got)
+		}
+	}
+}
+
+func TestServeCacheHeaders(t *testing.T) {
+	router, _ := newStaticRouter()
+
+	if got := serve(router, "/assets/css/app.css").Header().Get("Cache-Control"); got != static.CacheNoCache {
+		t.Errorf("expected no-cache for unhashed file, got '%s'", got)
+	}
+	if got := serve(router, "/assets/js/index-4f3a9c1b.js").Header().Get("Cache-Control"); got != static.CacheImmutable {
+		t.Errorf("expected immutable for bundler-hashed file, got '%s'", got)
+	}
+}
+
+func TestFingerprintedPath(t *testing.T) {
+	router, assets := newStaticRouter()
+
+	fingerprinted := assets.Path("/css/app.css")
+	if fingerprinted == "css/app.css" || !strings.HasPrefix(fingerprinted, "css/app.") {
+		t.Fatalf("unexpected fingerprinted path '%s'", fingerprinted)
+	}
+
+	w := serve(router, "/assets/"+fingerprinted)
+	if w.Code != http.StatusOK || w.Body.String() != "body{}" {
+		t.Fatalf("expected file content, got %d '%s'", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Cache-Control"); got != static.CacheImmutable {
+		t.Errorf("expected immutable, got '%s'", got)
+	}
+
+	stale := serve(router, "/assets/css/app.0000000000.css")
+	if got := stale.Header().Get("Cache-Control"); stale.Code != http.StatusOK || got != static.CacheNoCache {
+		t.Errorf("expected stale fingerprint served with no-cache, got %d '%s'", stale.Code, got)
+	}
+
+	if got := assets.Path("missing.css"); got != "missing.css" {
+		t.Errorf("expected missing file unchanged, got '%s'", got)
+	}
+}
+
+func TestServeETag(t *testing.T) {
+	router, _ := newStaticRouter()
+
+	etag := serve(router, "/assets/css/app.css").Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag")
+	}
+
+	w := serve(router, "/assets/css/app.css", "If-None-Match", etag)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", w.Code)
+	}
+}
+
+func TestServeMissing(t *testing.T) {
+	router, _ := newStaticRouter()
+
+	if w := serve(router, "/assets/nope.js"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestIsHashedAsset(t *testing.T) {
+	tests := map[string]bool{
+		"main.3f2a9c1b.js":          true,
+		"assets/index-BfX3k2aQ.css": true,
+		"index-component.js":        false,
+		"index.html":                false,
+		"logo.png":                  false,
+	}
+	for name, want := range tests {
+		if got := static.IsHashedAsset(name); got != want {
+			t.Errorf("IsHashedAsset(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestServeChangedFile(t *testing.T) {
+	fsys := fstest.MapFS{"css/app.css": {Data: []byte("body{}"), ModTime: time.Unix(1, 0)}}
+	assets := static.New(fsys, static.Config{})
+	router := gin.New()
+	assets.Register(router, "/assets")
+
+	etag := serve(router, "/assets/css/app.css").Header().Get("ETag")
+	path := assets.Path("css/app.css")
+
+	tests := []struct {
+		name string
+		file *fstest.MapFile
+	}{
+		{"modified", &fstest.MapFile{Data: []byte("body{color:red}"), ModTime: time.Unix(2, 0)}},
+		{"same time, other size", &fstest.MapFile{Data: []byte("p{}"), ModTime: time.Unix(2, 0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys["css/app.css"] = tt.file
+
+			w := serve(router, "/assets/css/app.css")
+			if w.Body.String() != string(tt.file.Data) {
+				t.Errorf("expected %s, got %s", tt.file.Data, w.Body.String())
+			}
+			if got := w.Header().Get("ETag"); got == etag {
+				t.Errorf("expected a new ETag, got the old one %s", got)
+			}
+			if got := assets.Path("css/app.css"); got == path {
+				t.Errorf("expected a new fingerprinted path, got the old one %s", got)
+			}
+			etag, path = w.Header().Get("ETag"), assets.Path("css/app.css")
+		})
+	}
+
+	delete(fsys, "css/app.css")
+	if w := serve(router, "/assets/css/app.css"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a deleted file, got %d", w.Code)
+	}
+}
@@ -0,0 +1,153 @@
+// Package tasks is the seam between handlers and background work that must
+// happen after the response, such as sending emails or generating
+// thumbnails. Handlers enqueue named tasks on a Queue; the Worker in this
+// package runs them in process, and a durable queue (a Redis or SQL-backed
+// job system) can implement the same interface:
+//
+//	worker := tasks.NewWorker(tasks.WorkerConfig{})
+//	worker.Handle("email.welcome", func(ctx context.Context, t tasks.Task) error {
+//	    return mailer.SendWelcome(ctx, t.Payload.(User))
+//	})
+//	defer worker.Shutdown(context.Background())
+//
+//	// in a handler
+//	err := queue.Enqueue(c, "email.welcome", user, tasks.Options{})
+//
+// The request ID, language, and principal of the enqueuing request travel
+// with the task as Metadata and are restored in the context the task runs
+// with, so its logs and errors correlate with the request.
+package tasks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/requestctx"
+)
+
+var (
+	// ErrUnknownTask is returned by Enqueue for names without a handler.
+	ErrUnknownTask = errors.New("tasks: no handler for task")
+	// ErrQueueFull is returned by Enqueue when the queue can't take more
+	// tasks.
+	ErrQueueFull = errors.New("tasks: queue full")
+	// ErrClosed is returned by Enqueue after Shutdown.
+	ErrClosed = errors.New("tasks: queue closed")
+)
+
+// Queue accepts tasks for background processing.
+type Queue interface {
+	// Enqueue schedules the task name with payload. ctx is the context of
+	// the enqueuing request (a *gin.Context works); its values are carried
+	// to the task, but its cancellation is not.
+	Enqueue(ctx context.Context, name string, payload any, opts Options) error
+}
+
+// Options configures one enqueued task.
+type Options struct {
+	// Delay before the task first runs
+	Delay time.Duration
+	// MaxAttempts, including the first (defaults to 3)
+	MaxAttempts int
+	// Timeout for each attempt (defaults to the queue's)
+	Timeout time.Duration
+}
+
+// Task is a task being run.
+type Task struct {
+	// Name the task was enqueued under, e.g. "email.welcome"
+	Name string
+	// Payload passed to Enqueue
+	Payload any
+	// Attempt is 1 on the first run
+	Attempt int
+	// Metadata carries the enqueuing request's values; see Propagator
+	Metadata map[string]string
+}
+
+// Handler runs a task. Returning an error retries it, up to MaxAttempts.
+type Handler func(ctx context.Context, t Task) error
+
+// Propagator carries request-scoped values from the enqueuing request to
+// the task, through string metadata that durable queues can store.
+type Propagator interface {
+	// Inject adds the values of ctx to md.
+	Inject(ctx context.Context, md map[string]string)
+	// Extract returns ctx with the values in md restored.
+	Extract(ctx context.Context, md map[string]string) context.Context
+}
+
+// Metadata keys used by RequestValues.
+const (
+	MetadataRequestID     = "request_id"
+	MetadataLanguage      = "language"
+	MetadataPrincipalID   = "principal_id"
+	MetadataPrincipalType = "principal_type"
+)
+
+// RequestValues propagates the request ID (see requestctx), language, and
+// principal ID and type. The restored principal has no scopes or roles, so
+// tasks don't act with the permissions of a token that may have expired.
+var RequestValues Propagator = requestValues{}
+
+type requestValues struct{}
+
+func (requestValues) Inject(ctx context.Context, md map[string]string) {
+	v := requestctx.From(ctx)
+	if v.RequestID == "" {
+		if c, ok := ctx.(*gin.Context); ok && c.Request != nil {
+			v.RequestID = c.GetHeader("X-Request-ID")
+		}
+	}
+	set := func(key, value string) {
+		if value != "" {
+			md[key] = value
+		}
+	}
+	set(MetadataRequestID, v.RequestID)
+	set(MetadataLanguage, v.Language)
+	if v.HasPrincipal {
+		set(MetadataPrincipalID, v.Principal.ID)
+		set(MetadataPrincipalType, v.Principal.Type)
+	}
+}
+
+func (requestValues) Extract(ctx context.Context, md map[string]string) context.Context {
+	if id := md[MetadataRequestID]; id != "" {
+		ctx = requestctx.WithRequestID(ctx, id)
+	}
+	if lang := md[MetadataLanguage]; lang != "" {
+		ctx = middleware.WithLanguage(ctx, lang)
+	}
+	if id := md[MetadataPrincipalID]; id != "" {
+		ctx = auth.WithPrincipal(ctx, auth.Principal{ID: id, Type: md[MetadataPrincipalType]})
+	}
+	return ctx
+}
+
+// Inject returns the metadata propagators record for ctx, for Queue
+// implementations to store with the task.
+func Inject(ctx context.Context, propagators ...Propagator) map[string]string {
+	md := map[string]string{}
+	if ctx == nil {
+		return md
+	}
+	for _, p := range propagators {
+		p.Inject(ctx, md)
+	}
+	return md
+}
+
+// Extract returns ctx with the values in md restored by propagators, for
+// Queue implementations to run the task with.
+func Extract(ctx context.Context, md map[string]string, propagators ...Propagator) context.Context {
+	for _, p := range propagators {
+		ctx = p.Extract(ctx, md)
+	}
+	return ctx
+}
@@ -0,0 +1,62 @@
+package tasks_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/auth"
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/requestctx"
+	"github.com/doujins-org/ginapi/tasks"
+)
+
+func TestRequestValues(t *testing.T) {
+	ctx := requestctx.WithRequestID(context.Background(), "req-1")
+	ctx = middleware.WithLanguage(ctx, "ja")
+	ctx = auth.WithPrincipal(ctx, auth.Principal{ID: "usr_1", Type: "user", Scopes: []string{"admin"}})
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		wantRequestID string
+		wantLanguage  string
+		wantPrincipal string
+	}{
+		{name: "request values", ctx: ctx, wantRequestID: "req-1", wantLanguage: "ja", wantPrincipal: "usr_1"},
+		{name: "empty", ctx: context.Background()},
+		{name: "nil", ctx: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := tasks.Inject(tt.ctx, tasks.RequestValues)
+			v := requestctx.From(tasks.Extract(context.Background(), md, tasks.RequestValues))
+			if v.RequestID != tt.wantRequestID {
+				t.Errorf("expected request ID %q, got %q", tt.wantRequestID, v.RequestID)
+			}
+			if v.Language != tt.wantLanguage {
+				t.Errorf("expected language %q, got %q", tt.wantLanguage, v.Language)
+			}
+			if v.Principal.ID != tt.wantPrincipal {
+				t.Errorf("expected principal %q, got %q", tt.wantPrincipal, v.Principal.ID)
+			}
+			if len(v.Principal.Scopes) != 0 {
+				t.Errorf("expected no scopes, got %v", v.Principal.Scopes)
+			}
+		})
+	}
+}
+
+func TestRequestValuesGinContext(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/galleries", nil)
+	c.Request.Header.Set("X-Request-ID", "req-2")
+
+	md := tasks.Inject(c, tasks.RequestValues)
+	if md[tasks.MetadataRequestID] != "req-2" {
+		t.Errorf("expected request ID req-2, got %v", md)
+	}
+}
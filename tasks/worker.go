@@ -0,0 +1,242 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/doujins-org/ginapi/response"
+)
+
+// WorkerConfig configures an in-process Worker.
+type WorkerConfig struct {
+	// Concurrency is the number of tasks run at once (defaults to 4)
+	Concurrency int
+	// QueueSize is how many tasks may wait to run; Enqueue returns
+	// ErrQueueFull past it (defaults to 1000)
+	QueueSize int
+	// Timeout for each attempt, unless the task's Options set one
+	// (defaults to 1 minute)
+	Timeout time.Duration
+	// Backoff returns the delay before retrying after the given failed
+	// attempt (defaults to 1s, 2s, 4s, ..., capped at 1 minute)
+	Backoff func(attempt int) time.Duration
+	// Propagators carry request values to tasks (defaults to
+	// RequestValues)
+	Propagators []Propagator
+	// Logger for failed tasks (defaults to slog.Default())
+	Logger *slog.Logger
+}
+
+// Worker is an in-process Queue running tasks on a pool of goroutines.
+// Tasks are lost if the process exits before they run; use a durable
+// Queue for work that must survive restarts.
+type Worker struct {
+	cfg      WorkerConfig
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	jobs     chan *job
+	quit     chan struct{}
+	pending  sync.WaitGroup
+	closed   bool
+	drain    sync.Once
+	drained  chan struct{}
+}
+
+// job is a task waiting to run or be retried.
+type job struct {
+	task    Task
+	timeout time.Duration
+	max     int
+}
+
+// NewWorker returns a Worker with its goroutines started.
+func NewWorker(cfg WorkerConfig) *Worker {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Minute
+	}
+	if cfg.Backoff == nil {
+		cfg.Backoff = defaultBackoff
+	}
+	if cfg.Propagators == nil {
+		cfg.Propagators = []Propagator{RequestValues}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	w := &Worker{
+		cfg:      cfg,
+		handlers: map[string]Handler{},
+		jobs:     make(chan *job, cfg.QueueSize),
+		quit:     make(chan struct{}),
+		drained:  make(chan struct{}),
+	}
+	for range cfg.Concurrency {
+		go w.loop()
+	}
+	return w
+}
+
+// defaultBackoff doubles from one second up to a minute.
+func defaultBackoff(attempt int) time.Duration {
+	if attempt > 6 {
+		return time.Minute
+	}
+	return time.Second << (attempt - 1)
+}
+
+// Handle registers the handler for tasks named name, replacing any
+// previous one.
+func (w *Worker) Handle(name string, h Handler) {
+	w.mu.Lock()
+	w.handlers[name] = h
+	w.mu.Unlock()
+}
+
+// Enqueue schedules a task; see Queue. It returns ErrUnknownTask if name
+// has no handler, ErrQueueFull if the queue is full, and ErrClosed after
+// Shutdown.
+func (w *Worker) Enqueue(ctx context.Context, name string, payload any, opts Options) error {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = w.cfg.Timeout
+	}
+
+	j := &job{
+		task:    Task{Name: name, Payload: payload, Metadata: Inject(ctx, w.cfg.Propagators...)},
+		timeout: opts.Timeout,
+		max:     opts.MaxAttempts,
+	}
+
+	// Shutdown takes the write lock to close, so no task is added while
+	// it waits for the pending ones
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return ErrClosed
+	}
+	if w.handlers[name] == nil {
+		w.mu.RUnlock()
+		return fmt.Errorf("%w: %s", ErrUnknownTask, name)
+	}
+	w.pending.Add(1)
+	w.mu.RUnlock()
+	if opts.Delay > 0 {
+		w.later(j, opts.Delay)
+		return nil
+	}
+	if !w.push(j) {
+		w.pending.Done()
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// Shutdown stops accepting tasks and waits for the queued, delayed, and
+// running ones to finish, or for ctx to end.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	w.drain.Do(func() {
+		go func() {
+			w.pending.Wait()
+			close(w.quit)
+			close(w.drained)
+		}()
+	})
+	select {
+	case <-w.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Worker) handler(name string) Handler {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.handlers[name]
+}
+
+// push queues j, reporting false if the queue is full.
+func (w *Worker) push(j *job) bool {
+	select {
+	case w.jobs <- j:
+		return true
+	default:
+		return false
+	}
+}
+
+// later queues j after delay, dropping it if the queue is full by then.
+func (w *Worker) later(j *job, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		if !w.push(j) {
+			w.cfg.Logger.Error("task dropped: queue full", "task", j.task.Name, "attempt", j.task.Attempt)
+			w.pending.Done()
+		}
+	})
+}
+
+// loop runs queued jobs until Shutdown.
+func (w *Worker) loop() {
+	for {
+		select {
+		case j := <-w.jobs:
+			w.run(j)
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// run attempts j, retrying it later on failure while attempts remain.
+func (w *Worker) run(j *job) {
+	j.task.Attempt++
+	ctx := Extract(context.Background(), j.task.Metadata, w.cfg.Propagators...)
+	err := w.attempt(ctx, j)
+	if err == nil {
+		w.pending.Done()
+		return
+	}
+	if j.task.Attempt < j.max {
+		w.later(j, w.cfg.Backoff(j.task.Attempt))
+		return
+	}
+	w.cfg.Logger.ErrorContext(ctx, "task failed",
+		"task", j.task.Name, "attempts", j.task.Attempt, "error", err)
+	w.pending.Done()
+}
+
+// attempt runs j's handler once, turning a panic into an error reported
+// to the Reporter set with response.SetReporter.
+func (w *Worker) attempt(ctx context.Context, j *job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, j.timeout)
+	defer cancel()
+	defer func() {
+		if v := recover(); v != nil {
+			var ok bool
+			if err, ok = v.(error); !ok {
+				err = fmt.Errorf("panic: %v", v)
+			}
+			response.ReportError(ctx, nil, "task "+j.task.Name, err)
+		}
+	}()
+	h := w.handler(j.task.Name)
+	if h == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownTask, j.task.Name)
+	}
+	return h(ctx, j.task)
+}
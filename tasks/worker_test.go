@@ -0,0 +1,129 @@
+package tasks_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/requestctx"
+	"github.com/doujins-org/ginapi/tasks"
+)
+
+func newWorker(cfg tasks.WorkerConfig) *tasks.Worker {
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	if cfg.Backoff == nil {
+		cfg.Backoff = func(int) time.Duration { return time.Millisecond }
+	}
+	return tasks.NewWorker(cfg)
+}
+
+func TestWorker(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		panics       bool
+		opts         tasks.Options
+		wantAttempts int32
+	}{
+		{name: "success", wantAttempts: 1},
+		{name: "retried", failures: 2, wantAttempts: 3},
+		{name: "gives up", failures: 5, wantAttempts: 3},
+		{name: "max attempts", failures: 5, opts: tasks.Options{MaxAttempts: 1}, wantAttempts: 1},
+		{name: "panic retried", failures: 1, panics: true, wantAttempts: 2},
+		{name: "delayed", opts: tasks.Options{Delay: 10 * time.Millisecond}, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWorker(tasks.WorkerConfig{})
+			var attempts atomic.Int32
+			var payload any
+			w.Handle("thumbnail", func(ctx context.Context, task tasks.Task) error {
+				n := attempts.Add(1)
+				if int(n) != task.Attempt {
+					t.Errorf("expected attempt %d, got %d", n, task.Attempt)
+				}
+				payload = task.Payload
+				if int(n) <= tt.failures {
+					if tt.panics {
+						panic("boom")
+					}
+					return errors.New("failed")
+				}
+				return nil
+			})
+
+			if err := w.Enqueue(context.Background(), "thumbnail", "gal_1", tt.opts); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err := w.Shutdown(context.Background()); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, got)
+			}
+			if tt.wantAttempts > int32(tt.failures) && payload != "gal_1" {
+				t.Errorf("expected payload gal_1, got %v", payload)
+			}
+		})
+	}
+}
+
+func TestWorkerPropagation(t *testing.T) {
+	w := newWorker(tasks.WorkerConfig{})
+	var got string
+	w.Handle("email", func(ctx context.Context, task tasks.Task) error {
+		got = requestctx.From(ctx).RequestID
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(requestctx.WithRequestID(context.Background(), "req-1"))
+	if err := w.Enqueue(ctx, "email", nil, tasks.Options{Delay: 5 * time.Millisecond}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	cancel()
+	_ = w.Shutdown(context.Background())
+	if got != "req-1" {
+		t.Errorf("expected request ID req-1, got %q", got)
+	}
+}
+
+func TestWorkerEnqueueErrors(t *testing.T) {
+	w := newWorker(tasks.WorkerConfig{Concurrency: 1, QueueSize: 1})
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(1)
+	var once sync.Once
+	w.Handle("slow", func(ctx context.Context, task tasks.Task) error {
+		once.Do(started.Done)
+		<-release
+		return nil
+	})
+
+	if err := w.Enqueue(context.Background(), "missing", nil, tasks.Options{}); !errors.Is(err, tasks.ErrUnknownTask) {
+		t.Errorf("expected ErrUnknownTask, got %v", err)
+	}
+	_ = w.Enqueue(context.Background(), "slow", nil, tasks.Options{})
+	started.Wait()
+	if err := w.Enqueue(context.Background(), "slow", nil, tasks.Options{}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := w.Enqueue(context.Background(), "slow", nil, tasks.Options{}); !errors.Is(err, tasks.ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := w.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if err := w.Enqueue(context.Background(), "slow", nil, tasks.Options{}); !errors.Is(err, tasks.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	close(release)
+}
@@ -0,0 +1,137 @@
+// Package tenant identifies the tenant a request is for, so white-label
+// deployments can serve several brands from one build. Middleware resolves
+// the tenant once per request and stores it in the context, where the
+// policies that vary by tenant read it:
+//
+//	router.Use(tenant.Middleware(tenant.ByHost(map[string]tenant.Tenant{
+//	    "example.jp":  {ID: "jp", Name: "Example Japan"},
+//	    "example.com": {ID: "global", Name: "Example"},
+//	})))
+//	router.Use(middleware.Language(middleware.LanguageConfig{
+//	    Supported: []string{"en"},
+//	    Tenants:   languagesByTenant,
+//	}))
+//
+// middleware.LanguageConfig.Tenants and pagination.SetTenantLimits resolve
+// their settings per tenant. Install the middleware before them.
+package tenant
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tenant is a deployment served from the shared build.
+type Tenant struct {
+	// ID identifies the tenant, e.g. "acme"; per-tenant policies are
+	// cached by it
+	ID string
+	// Name is a display name, optional
+	Name string
+}
+
+// Resolver returns the tenant of a request; false if it has none, in which
+// case the default policies apply.
+type Resolver func(r *http.Request) (Tenant, bool)
+
+// ByHost returns a Resolver looking the request host (lowercased, without
+// its port) up in hosts.
+func ByHost(hosts map[string]Tenant) Resolver {
+	normalized := make(map[string]Tenant, len(hosts))
+	for host, t := range hosts {
+		normalized[strings.ToLower(strings.TrimSpace(host))] = t
+	}
+	return func(r *http.Request) (Tenant, bool) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		t, ok := normalized[strings.ToLower(host)]
+		return t, ok
+	}
+}
+
+// Middleware returns middleware storing the tenant resolve returns for each
+// request with Set. Requests without a tenant go on without one.
+func Middleware(resolve Resolver) gin.HandlerFunc {
+	if resolve == nil {
+		panic("tenant: Middleware requires a resolver")
+	}
+	return func(c *gin.Context) {
+		if t, ok := resolve(c.Request); ok {
+			Set(c, t)
+		}
+		c.Next()
+	}
+}
+
+// Handler is the net/http equivalent of Middleware.
+func Handler(resolve Resolver) func(http.Handler) http.Handler {
+	if resolve == nil {
+		panic("tenant: Handler requires a resolver")
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if t, ok := resolve(r); ok {
+				r = r.WithContext(WithTenant(r.Context(), t))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tenantKey is the gin context key for the tenant.
+const tenantKey = "ginapi.tenant"
+
+// Set stores t as the request's tenant, in the gin context and the request
+// context, for middleware resolving tenants its own way, e.g. from the
+// authenticated principal.
+func Set(c *gin.Context, t Tenant) {
+	c.Set(tenantKey, t)
+	if c.Request != nil {
+		c.Request = c.Request.WithContext(WithTenant(c.Request.Context(), t))
+	}
+}
+
+// Get returns the tenant stored by Set or Middleware.
+// The second return value is false if the request has none.
+func Get(c *gin.Context) (Tenant, bool) {
+	if c == nil {
+		return Tenant{}, false
+	}
+	if v, ok := c.Get(tenantKey); ok {
+		if t, ok := v.(Tenant); ok {
+			return t, true
+		}
+	}
+	if c.Request != nil {
+		return FromContext(c.Request.Context())
+	}
+	return Tenant{}, false
+}
+
+// tenantContextKey is the request context key for the tenant.
+type tenantContextKey struct{}
+
+// WithTenant returns a copy of ctx carrying t.
+// This is the net/http equivalent of Set.
+func WithTenant(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, t)
+}
+
+// FromContext returns the tenant stored in ctx, which may be a request
+// context or a *gin.Context.
+func FromContext(ctx context.Context) (Tenant, bool) {
+	if ctx == nil {
+		return Tenant{}, false
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		return Get(c)
+	}
+	t, ok := ctx.Value(tenantContextKey{}).(Tenant)
+	return t, ok
+}
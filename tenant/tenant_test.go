@@ -0,0 +1,93 @@
+package tenant_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/tenant"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(tenant.Middleware(tenant.ByHost(map[string]tenant.Tenant{
+		"Example.JP": {ID: "jp", Name: "Example Japan"},
+	})))
+	router.GET("/test", func(c *gin.Context) {
+		fromGin, _ := tenant.Get(c)
+		fromRequest, ok := tenant.FromContext(c.Request.Context())
+		if fromGin != fromRequest {
+			t.Errorf("expected the same tenant in both contexts, got %+v and %+v", fromGin, fromRequest)
+		}
+		if !ok {
+			c.String(http.StatusOK, "none")
+			return
+		}
+		c.String(http.StatusOK, fromGin.ID)
+	})
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"example.jp", "jp"},
+		{"example.jp:8080", "jp"},
+		{"EXAMPLE.jp", "jp"},
+		{"example.com", "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Host = tt.host
+			router.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var got tenant.Tenant
+	handler := tenant.Handler(func(r *http.Request) (tenant.Tenant, bool) {
+		return tenant.Tenant{ID: r.Header.Get("X-Tenant")}, true
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = tenant.FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Tenant", "acme")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got.ID != "acme" {
+		t.Errorf("expected tenant acme, got %q", got.ID)
+	}
+}
+
+func TestFromContextEmpty(t *testing.T) {
+	if _, ok := tenant.FromContext(context.Background()); ok {
+		t.Error("expected no tenant")
+	}
+	if _, ok := tenant.Get(nil); ok {
+		t.Error("expected no tenant for a nil context")
+	}
+}
+
+func TestMiddlewareNilResolver(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for a nil resolver")
+		}
+	}()
+	tenant.Middleware(nil)
+}
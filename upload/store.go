@@ -0,0 +1,296 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned by a Store for an unknown upload.
+	ErrNotFound = errors.New("upload: not found")
+	// ErrOffsetMismatch is returned by Store.Append when the offset isn't
+	// the upload's current offset, e.g. after a concurrent PATCH.
+	ErrOffsetMismatch = errors.New("upload: offset mismatch")
+)
+
+// Store holds uploads in progress. Implementations must be safe for
+// concurrent use, and must keep the bytes of an Append that fails partway,
+// since resuming after a dropped connection depends on them. Services with
+// several instances need a store all of them can reach (e.g. a shared
+// volume or object storage).
+type Store interface {
+	// Create stores a new, empty upload.
+	Create(ctx context.Context, info Info) error
+	// Get returns the upload's Info.
+	Get(ctx context.Context, id string) (Info, error)
+	// Append writes r at offset, which must be the upload's current offset,
+	// and returns the new offset.
+	Append(ctx context.Context, id string, offset int64, r io.Reader) (int64, error)
+	// Open returns a reader of the upload's data.
+	Open(ctx context.Context, id string) (io.ReadCloser, error)
+	// Delete removes the upload.
+	Delete(ctx context.Context, id string) error
+	// DeleteExpired removes uploads that expired before now and returns how
+	// many were removed.
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// MemoryStore is an in-process Store, for tests and small uploads.
+type MemoryStore struct {
+	mu      sync.Mutex
+	uploads map[string]*memoryUpload
+}
+
+type memoryUpload struct {
+	mu   sync.Mutex // held while appending
+	info Info
+	data []byte
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{uploads: map[string]*memoryUpload{}}
+}
+
+// Create implements Store.
+func (s *MemoryStore) Create(_ context.Context, info Info) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uploads[info.ID] = &memoryUpload{info: info}
+	return nil
+}
+
+// upload returns the upload id, locked.
+func (s *MemoryStore) upload(id string) (*memoryUpload, bool) {
+	s.mu.Lock()
+	u, ok := s.uploads[id]
+	s.mu.Unlock()
+	if ok {
+		u.mu.Lock()
+	}
+	return u, ok
+}
+
+// Get implements Store. It waits for appends to the upload in progress.
+func (s *MemoryStore) Get(_ context.Context, id string) (Info, error) {
+	u, ok := s.upload(id)
+	if !ok {
+		return Info{}, ErrNotFound
+	}
+	defer u.mu.Unlock()
+	return u.info, nil
+}
+
+// Append implements Store.
+func (s *MemoryStore) Append(_ context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	u, ok := s.upload(id)
+	if !ok {
+		return 0, ErrNotFound
+	}
+	defer u.mu.Unlock()
+	if offset != u.info.Offset {
+		return u.info.Offset, ErrOffsetMismatch
+	}
+	buf := bytes.NewBuffer(u.data)
+	_, err := io.Copy(buf, r)
+	u.data = buf.Bytes()
+	u.info.Offset = int64(len(u.data))
+	return u.info.Offset, err
+}
+
+// Open implements Store.
+func (s *MemoryStore) Open(_ context.Context, id string) (io.ReadCloser, error) {
+	u, ok := s.upload(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	defer u.mu.Unlock()
+	return io.NopCloser(bytes.NewReader(u.data)), nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.uploads, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// DeleteExpired implements Store.
+func (s *MemoryStore) DeleteExpired(_ context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, u := range s.uploads {
+		if !u.mu.TryLock() {
+			continue // being appended to
+		}
+		expired := u.info.expired(now)
+		u.mu.Unlock()
+		if expired {
+			delete(s.uploads, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// FileStore is a Store keeping each upload in a directory as two files:
+// <id> holds the data and <id>.json its Info. Appends to one upload are
+// serialized within the process only, so instances sharing the directory
+// must route an upload's requests to one instance.
+type FileStore struct {
+	dir   string
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewFileStore returns a FileStore in dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, locks: map[string]*sync.Mutex{}}, nil
+}
+
+// lock locks the upload id and returns its unlock function.
+func (s *FileStore) lock(id string) func() {
+	s.mu.Lock()
+	l, ok := s.locks[id]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[id] = l
+	}
+	s.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+func (s *FileStore) dataPath(id string) string { return filepath.Join(s.dir, id) }
+func (s *FileStore) infoPath(id string) string { return filepath.Join(s.dir, id+".json") }
+
+// Create implements Store.
+func (s *FileStore) Create(_ context.Context, info Info) error {
+	f, err := os.OpenFile(s.dataPath(info.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return s.writeInfo(info)
+}
+
+// Get implements Store.
+func (s *FileStore) Get(_ context.Context, id string) (Info, error) {
+	b, err := os.ReadFile(s.infoPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Info{}, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	var info Info
+	err = json.Unmarshal(b, &info)
+	return info, err
+}
+
+// Append implements Store.
+func (s *FileStore) Append(ctx context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	defer s.lock(id)()
+	info, err := s.Get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if offset != info.Offset {
+		return info.Offset, ErrOffsetMismatch
+	}
+
+	f, err := os.OpenFile(s.dataPath(id), os.O_WRONLY, 0)
+	if err != nil {
+		return info.Offset, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return info.Offset, err
+	}
+	n, copyErr := io.Copy(f, r)
+	if err := f.Close(); copyErr == nil {
+		copyErr = err
+	}
+	// Keep what was written before a failure, so the client resumes after it
+	info.Offset += n
+	if err := s.writeInfo(info); err != nil {
+		return offset, err
+	}
+	return info.Offset, copyErr
+}
+
+// Open implements Store.
+func (s *FileStore) Open(_ context.Context, id string) (io.ReadCloser, error) {
+	f, err := os.Open(s.dataPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	defer s.lock(id)()
+	err := errors.Join(removeFile(s.infoPath(id)), removeFile(s.dataPath(id)))
+	s.mu.Lock()
+	delete(s.locks, id)
+	s.mu.Unlock()
+	return err
+}
+
+// removeFile removes the named file, if it exists.
+func removeFile(name string) error {
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired implements Store.
+func (s *FileStore) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, p := range paths {
+		id := filepath.Base(p[:len(p)-len(".json")])
+		info, err := s.Get(ctx, id)
+		if err != nil || !info.expired(now) {
+			continue
+		}
+		if err := s.Delete(ctx, id); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// writeInfo replaces the upload's info file.
+func (s *FileStore) writeInfo(info Info) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	tmp := s.infoPath(info.ID) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.infoPath(info.ID))
+}
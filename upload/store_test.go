@@ -0,0 +1,109 @@
+package upload_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/upload"
+)
+
+// failingReader returns data, then an error, like a dropped connection.
+type failingReader struct {
+	data string
+	done bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, errors.New("connection reset")
+	}
+	r.done = true
+	return copy(p, r.data), nil
+}
+
+func TestStores(t *testing.T) {
+	fileStore, err := upload.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]upload.Store{
+		"memory": upload.NewMemoryStore(),
+		"file":   fileStore,
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			id := "upl_0123456789abcdef0123456789abcdef"
+			if err := s.Create(ctx, upload.Info{ID: id, Size: 10, Metadata: map[string]string{"filename": "a.zip"}, ExpiresAt: now.Add(time.Hour)}); err != nil {
+				t.Fatal(err)
+			}
+
+			// A failed append keeps the bytes that arrived
+			offset, err := s.Append(ctx, id, 0, &failingReader{data: "abcd"})
+			if err == nil || offset != 4 {
+				t.Errorf("expected offset 4 and an error, got %d %v", offset, err)
+			}
+			if _, err := s.Append(ctx, id, 2, strings.NewReader("x")); !errors.Is(err, upload.ErrOffsetMismatch) {
+				t.Errorf("expected ErrOffsetMismatch, got %v", err)
+			}
+			if offset, err := s.Append(ctx, id, 4, strings.NewReader("efghij")); err != nil || offset != 10 {
+				t.Errorf("expected offset 10, got %d %v", offset, err)
+			}
+
+			info, err := s.Get(ctx, id)
+			if err != nil || !info.Complete() || info.Metadata["filename"] != "a.zip" {
+				t.Errorf("expected complete upload with metadata, got %+v %v", info, err)
+			}
+			r, err := s.Open(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(r)
+			r.Close()
+			if string(data) != "abcdefghij" {
+				t.Errorf("expected abcdefghij, got %q", data)
+			}
+
+			if n, _ := s.DeleteExpired(ctx, now); n != 0 {
+				t.Errorf("expected nothing expired, got %d", n)
+			}
+			if n, _ := s.DeleteExpired(ctx, now.Add(2*time.Hour)); n != 1 {
+				t.Errorf("expected 1 expired upload, got %d", n)
+			}
+			if _, err := s.Get(ctx, id); !errors.Is(err, upload.ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFileStoreDelete(t *testing.T) {
+	dir := t.TempDir()
+	s, err := upload.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	id := "upl_0123456789abcdef0123456789abcdef"
+
+	if err := s.Delete(ctx, id); err != nil {
+		t.Errorf("expected deleting a missing upload to succeed, got %v", err)
+	}
+
+	// The info file is gone but the data can't be removed: the failure
+	// must not be hidden behind the missing info file
+	if err := os.MkdirAll(filepath.Join(dir, id, "child"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, id); err == nil {
+		t.Errorf("expected an error removing the data, got nil")
+	}
+}
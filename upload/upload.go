@@ -0,0 +1,413 @@
+// Package upload implements resumable uploads with the tus 1.0 protocol
+// (https://tus.io): the core protocol plus the creation, expiration, and
+// termination extensions. Large uploads from flaky mobile connections
+// resume at the last byte the server stored instead of starting over, and
+// every failure is a structured JSON error like the rest of the API.
+//
+// Standard tus clients (tus-js-client, TUSKit, tus-android-client) work
+// unchanged. Clients behind proxies that block PATCH can send POST with
+// X-HTTP-Method-Override (see middleware.MethodOverride).
+package upload
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/clock"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Version is the tus protocol version implemented.
+const Version = "1.0.0"
+
+// Error codes sent by the upload handlers.
+const (
+	ErrorCodeOffsetMismatch     = "upload_offset_mismatch"  // 409, details.offset has the current offset
+	ErrorCodeExpired            = "upload_expired"          // 410
+	ErrorCodeTooLarge           = "upload_too_large"        // 413, details.max_size has the limit
+	ErrorCodeUnsupportedVersion = "unsupported_tus_version" // 412
+)
+
+// Info describes an upload.
+type Info struct {
+	ID string `json:"id"`
+	// Size is the total length declared at creation
+	Size int64 `json:"size"`
+	// Offset is how many bytes are stored
+	Offset int64 `json:"offset"`
+	// Metadata sent by the client in Upload-Metadata, e.g. "filename"
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// Complete reports whether every byte of the upload is stored.
+func (i Info) Complete() bool {
+	return i.Offset == i.Size
+}
+
+// expired reports whether the upload expired before now.
+func (i Info) expired(now time.Time) bool {
+	return now.After(i.ExpiresAt)
+}
+
+// Config configures a Handler.
+type Config struct {
+	// Store holding uploads (required)
+	Store Store
+	// MaxSize is the largest upload accepted (defaults to 1GiB)
+	MaxSize int64
+	// Expiry is how long after creation an upload is kept (defaults to
+	// 24h). Expired uploads answer 410 and are removed by Purge.
+	Expiry time.Duration
+	// OnCreate runs before an upload is created, to authorize it and check
+	// its size and metadata. It may change info.Metadata. To reject the
+	// upload it sends an error and returns false.
+	OnCreate func(c *gin.Context, info *Info) bool
+	// OnAccess runs before HEAD, PATCH, and DELETE on an upload, to check
+	// the caller may use it, e.g. against an owner OnCreate stored in
+	// info.Metadata. To reject the request it sends an error (a 404 hides
+	// the upload) and returns false. Without it, anyone holding an upload's
+	// URL may resume or delete it.
+	OnAccess func(c *gin.Context, info Info) bool
+	// OnComplete runs when the last byte is stored, e.g. to enqueue
+	// processing. An error is reported and answered with a 500; the client
+	// retries the final PATCH, running OnComplete again.
+	OnComplete func(c *gin.Context, info Info) error
+	// Clock sets expiry times (defaults to clock.System)
+	Clock clock.Clock
+}
+
+// Handler serves the tus endpoints over a Store.
+type Handler struct {
+	cfg Config
+}
+
+// New returns a Handler for cfg. It panics if cfg.Store is nil.
+func New(cfg Config) *Handler {
+	if cfg.Store == nil {
+		panic("upload: Config.Store is required")
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 1 << 30
+	}
+	if cfg.Expiry <= 0 {
+		cfg.Expiry = 24 * time.Hour
+	}
+	cfg.Clock = clock.Or(cfg.Clock)
+	return &Handler{cfg: cfg}
+}
+
+// Register registers the tus routes under prefix: OPTIONS and POST on
+// prefix, and HEAD, PATCH, and DELETE on prefix/:id.
+//
+//	store, err := upload.NewFileStore("/var/lib/api/uploads")
+//	...
+//	uploads := upload.New(upload.Config{
+//	    Store:   store,
+//	    MaxSize: 2 << 30,
+//	    OnComplete: func(c *gin.Context, info upload.Info) error {
+//	        return jobs.Enqueue(c, "import_gallery", info.ID)
+//	    },
+//	})
+//	uploads.Register(api.Group("", auth.Require()), "/uploads")
+func (h *Handler) Register(r gin.IRoutes, prefix string) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	r.OPTIONS(prefix, h.options)
+	r.POST(prefix, h.create)
+	r.HEAD(prefix+"/:id", h.head)
+	r.PATCH(prefix+"/:id", h.patch)
+	r.DELETE(prefix+"/:id", h.terminate)
+}
+
+// Purge removes expired uploads and returns how many were removed. Run it
+// periodically.
+func (h *Handler) Purge(ctx context.Context) (int, error) {
+	return h.cfg.Store.DeleteExpired(ctx, h.cfg.Clock.Now())
+}
+
+// options answers with the protocol capabilities.
+func (h *Handler) options(c *gin.Context) {
+	c.Header("Tus-Resumable", Version)
+	c.Header("Tus-Version", Version)
+	c.Header("Tus-Extension", "creation,expiration,termination")
+	c.Header("Tus-Max-Size", strconv.FormatInt(h.cfg.MaxSize, 10))
+	response.NoContent(c)
+}
+
+// create creates an upload.
+func (h *Handler) create(c *gin.Context) {
+	if !checkVersion(c) {
+		return
+	}
+	length := c.GetHeader("Upload-Length")
+	if length == "" {
+		sendError(c, http.StatusBadRequest, response.ErrorCodeMissingParam, "Upload-Length is required", "Upload-Length")
+		return
+	}
+	size, err := strconv.ParseInt(length, 10, 64)
+	if err != nil || size < 0 {
+		sendError(c, http.StatusBadRequest, response.ErrorCodeInvalidParam, "Upload-Length must be a non-negative integer", "Upload-Length")
+		return
+	}
+	if size > h.cfg.MaxSize {
+		h.tooLarge(c)
+		return
+	}
+	metadata, err := parseMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		sendError(c, http.StatusBadRequest, response.ErrorCodeInvalidParam, err.Error(), "Upload-Metadata")
+		return
+	}
+
+	info := Info{
+		ID:        newID(),
+		Size:      size,
+		Metadata:  metadata,
+		ExpiresAt: h.cfg.Clock.Now().Add(h.cfg.Expiry),
+	}
+	if h.cfg.OnCreate != nil && !h.cfg.OnCreate(c, &info) {
+		return
+	}
+	if err := h.cfg.Store.Create(c, info); err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+info.ID)
+	c.Header("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// head answers with the upload's offset, so clients know where to resume.
+func (h *Handler) head(c *gin.Context) {
+	if !checkVersion(c) {
+		return
+	}
+	info, ok := h.get(c)
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(info.Size, 10))
+	c.Header("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusOK)
+}
+
+// patch appends the request body at Upload-Offset.
+func (h *Handler) patch(c *gin.Context) {
+	if !checkVersion(c) {
+		return
+	}
+	if c.ContentType() != "application/offset+octet-stream" {
+		response.UnsupportedMediaType(c, "Content-Type must be application/offset+octet-stream")
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		sendError(c, http.StatusBadRequest, response.ErrorCodeInvalidParam, "Upload-Offset must be a non-negative integer", "Upload-Offset")
+		return
+	}
+	info, ok := h.get(c)
+	if !ok {
+		return
+	}
+	if offset != info.Offset {
+		offsetMismatch(c, info.Offset)
+		return
+	}
+	remaining := info.Size - offset
+	if c.Request.ContentLength > remaining {
+		h.tooLarge(c)
+		return
+	}
+
+	body := &bodyReader{r: io.LimitReader(c.Request.Body, remaining)}
+	newOffset, err := h.cfg.Store.Append(c, info.ID, offset, body)
+	switch {
+	case errors.Is(err, ErrOffsetMismatch):
+		offsetMismatch(c, newOffset)
+		return
+	case body.err != nil:
+		// The client went away; what arrived is stored for it to resume
+		c.Abort()
+		return
+	case err != nil:
+		internalError(c, err)
+		return
+	}
+	info.Offset = newOffset
+
+	c.Header("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	c.Header("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	if info.Complete() && h.cfg.OnComplete != nil {
+		if err := h.cfg.OnComplete(c, info); err != nil {
+			internalError(c, err)
+			return
+		}
+	}
+	response.NoContent(c)
+}
+
+// terminate deletes the upload.
+func (h *Handler) terminate(c *gin.Context) {
+	if !checkVersion(c) {
+		return
+	}
+	info, ok := h.get(c)
+	if !ok {
+		return
+	}
+	if err := h.cfg.Store.Delete(c, info.ID); err != nil {
+		internalError(c, err)
+		return
+	}
+	response.NoContent(c)
+}
+
+// get loads the upload named by the id route param and runs OnAccess.
+// Unknown uploads get a 404 and expired ones a 410.
+func (h *Handler) get(c *gin.Context) (Info, bool) {
+	id := c.Param("id")
+	if !validID(id) {
+		response.NotFound(c, "upload")
+		return Info{}, false
+	}
+	info, err := h.cfg.Store.Get(c, id)
+	if errors.Is(err, ErrNotFound) {
+		response.NotFound(c, "upload")
+		return Info{}, false
+	}
+	if err != nil {
+		internalError(c, err)
+		return Info{}, false
+	}
+	if h.cfg.OnAccess != nil && !h.cfg.OnAccess(c, info) {
+		return Info{}, false
+	}
+	if info.expired(h.cfg.Clock.Now()) {
+		_ = h.cfg.Store.Delete(c, id)
+		response.ErrorWithInfo(c, http.StatusGone, response.ErrorInfo{
+			Type:    response.ErrorTypeNotFound,
+			Code:    ErrorCodeExpired,
+			Message: "upload expired",
+		})
+		return Info{}, false
+	}
+	return info, true
+}
+
+// checkVersion sets Tus-Resumable and answers 412 if the client speaks
+// another protocol version.
+func checkVersion(c *gin.Context) bool {
+	c.Header("Tus-Resumable", Version)
+	if v := c.GetHeader("Tus-Resumable"); v != Version {
+		c.Header("Tus-Version", Version)
+		sendError(c, http.StatusPreconditionFailed, ErrorCodeUnsupportedVersion,
+			fmt.Sprintf("Tus-Resumable %q is not supported; use %s", v, Version), "Tus-Resumable")
+		return false
+	}
+	return true
+}
+
+func (h *Handler) tooLarge(c *gin.Context) {
+	response.ErrorWithInfo(c, http.StatusRequestEntityTooLarge, response.ErrorInfo{
+		Type:    response.ErrorTypeInvalidRequest,
+		Code:    ErrorCodeTooLarge,
+		Message: "upload exceeds the maximum size",
+		Details: map[string]any{"max_size": h.cfg.MaxSize},
+	})
+}
+
+func offsetMismatch(c *gin.Context, current int64) {
+	c.Header("Upload-Offset", strconv.FormatInt(current, 10))
+	response.ErrorWithInfo(c, http.StatusConflict, response.ErrorInfo{
+		Type:    response.ErrorTypeConflict,
+		Code:    ErrorCodeOffsetMismatch,
+		Message: "Upload-Offset does not match the upload's offset",
+		Param:   "Upload-Offset",
+		Details: map[string]any{"offset": current},
+	})
+}
+
+func sendError(c *gin.Context, status int, code, message, param string) {
+	response.ErrorWithInfo(c, status, response.ErrorInfo{
+		Type:    response.ErrorTypeForStatus(status),
+		Code:    code,
+		Message: message,
+		Param:   param,
+	})
+}
+
+func internalError(c *gin.Context, err error) {
+	response.ReportError(c, c.Request, c.FullPath(), err)
+	response.ErrorWithInfo(c, http.StatusInternalServerError, response.ErrorInfo{
+		Type:    response.ErrorTypeAPI,
+		Code:    response.ErrorCodeInternal,
+		Message: "internal error",
+	})
+}
+
+// bodyReader records errors reading the request body, to tell a client
+// that went away from a failing Store.
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+// newID returns a random upload ID.
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "upl_" + hex.EncodeToString(b)
+}
+
+// validID reports whether id has the form newID returns, so stores never
+// see arbitrary path segments.
+func validID(id string) bool {
+	raw, ok := strings.CutPrefix(id, "upl_")
+	if !ok || len(raw) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(raw)
+	return err == nil
+}
+
+// parseMetadata parses Upload-Metadata: comma-separated pairs of a key and
+// a base64 value, which may be omitted.
+func parseMetadata(header string) (map[string]string, error) {
+	if strings.TrimSpace(header) == "" {
+		return nil, nil
+	}
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("Upload-Metadata has an empty key")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("Upload-Metadata value of %q is not base64", key)
+		}
+		metadata[key] = string(decoded)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,235 @@
+package upload_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/doujins-org/ginapi/apitest"
+	"github.com/doujins-org/ginapi/upload"
+)
+
+func newUploadRouter(cfg upload.Config) *gin.Engine {
+	router := gin.New()
+	upload.New(cfg).Register(router, "/uploads")
+	return router
+}
+
+func tusRequest(method, target string, body io.Reader, headers ...string) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Tus-Resumable", upload.Version)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	return req
+}
+
+func serve(router *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadResume(t *testing.T) {
+	store := upload.NewMemoryStore()
+	var completed upload.Info
+	router := newUploadRouter(upload.Config{
+		Store: store,
+		OnComplete: func(c *gin.Context, info upload.Info) error {
+			completed = info
+			return nil
+		},
+	})
+
+	// "cover.png" in base64
+	w := serve(router, tusRequest(http.MethodPost, "/uploads", nil, "Upload-Length", "11", "Upload-Metadata", "filename Y292ZXIucG5n,draft"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d %s", w.Code, w.Body.String())
+	}
+	location := w.Header().Get("Location")
+	if !strings.HasPrefix(location, "/uploads/upl_") || w.Header().Get("Upload-Expires") == "" {
+		t.Fatalf("unexpected creation headers %v", w.Header())
+	}
+
+	// The connection drops after the first chunk; the client asks where to resume
+	w = serve(router, tusRequest(http.MethodPatch, location, strings.NewReader("hello"), "Upload-Offset", "0", "Content-Type", "application/offset+octet-stream"))
+	if w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "5" {
+		t.Fatalf("expected 204 at offset 5, got %d %q", w.Code, w.Header().Get("Upload-Offset"))
+	}
+	w = serve(router, tusRequest(http.MethodHead, location, nil))
+	if w.Code != http.StatusOK || w.Header().Get("Upload-Offset") != "5" || w.Header().Get("Upload-Length") != "11" {
+		t.Fatalf("expected offset 5 of 11, got %d %v", w.Code, w.Header())
+	}
+
+	w = serve(router, tusRequest(http.MethodPatch, location, strings.NewReader(" world"), "Upload-Offset", "5", "Content-Type", "application/offset+octet-stream"))
+	if w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "11" {
+		t.Fatalf("expected 204 at offset 11, got %d %q", w.Code, w.Header().Get("Upload-Offset"))
+	}
+
+	if !completed.Complete() || completed.Metadata["filename"] != "cover.png" || completed.Metadata["draft"] != "" {
+		t.Errorf("expected OnComplete with metadata, got %+v", completed)
+	}
+	r, err := store.Open(context.Background(), completed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "hello world" {
+		t.Errorf("expected hello world, got %q", data)
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	clk := apitest.NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := upload.NewMemoryStore()
+	router := newUploadRouter(upload.Config{Store: store, MaxSize: 100, Expiry: time.Hour, Clock: clk})
+
+	create := func() string {
+		w := serve(router, tusRequest(http.MethodPost, "/uploads", nil, "Upload-Length", "10"))
+		return w.Header().Get("Location")
+	}
+	octet := "application/offset+octet-stream"
+
+	tests := []struct {
+		name       string
+		req        func() *http.Request
+		wantStatus int
+		wantCode   string
+	}{
+		{"missing version", func() *http.Request { return httptest.NewRequest(http.MethodPost, "/uploads", nil) }, http.StatusPreconditionFailed, upload.ErrorCodeUnsupportedVersion},
+		{"missing length", func() *http.Request { return tusRequest(http.MethodPost, "/uploads", nil) }, http.StatusBadRequest, "missing_param"},
+		{"too large", func() *http.Request { return tusRequest(http.MethodPost, "/uploads", nil, "Upload-Length", "101") }, http.StatusRequestEntityTooLarge, upload.ErrorCodeTooLarge},
+		{"bad metadata", func() *http.Request {
+			return tusRequest(http.MethodPost, "/uploads", nil, "Upload-Length", "1", "Upload-Metadata", "filename !!!")
+		}, http.StatusBadRequest, "invalid_param"},
+		{"unknown upload", func() *http.Request {
+			return tusRequest(http.MethodHead, "/uploads/upl_00000000000000000000000000000000", nil)
+		}, http.StatusNotFound, ""},
+		{"invalid id", func() *http.Request { return tusRequest(http.MethodHead, "/uploads/..", nil) }, http.StatusNotFound, ""},
+		{"wrong content type", func() *http.Request {
+			return tusRequest(http.MethodPatch, create(), strings.NewReader("x"), "Upload-Offset", "0")
+		}, http.StatusUnsupportedMediaType, ""},
+		{"offset mismatch", func() *http.Request {
+			return tusRequest(http.MethodPatch, create(), strings.NewReader("x"), "Upload-Offset", "3", "Content-Type", octet)
+		}, http.StatusConflict, upload.ErrorCodeOffsetMismatch},
+		{"chunk past length", func() *http.Request {
+			return tusRequest(http.MethodPatch, create(), strings.NewReader("0123456789x"), "Upload-Offset", "0", "Content-Type", octet)
+		}, http.StatusRequestEntityTooLarge, upload.ErrorCodeTooLarge},
+		{"expired", func() *http.Request {
+			location := create()
+			clk.Advance(2 * time.Hour)
+			return tusRequest(http.MethodPatch, location, strings.NewReader("x"), "Upload-Offset", "0", "Content-Type", octet)
+		}, http.StatusGone, upload.ErrorCodeExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, tt.req())
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantCode != "" && !strings.Contains(w.Body.String(), `"code":"`+tt.wantCode+`"`) {
+				t.Errorf("expected code %s, got %s", tt.wantCode, w.Body.String())
+			}
+			if w.Header().Get("Tus-Resumable") != upload.Version {
+				t.Errorf("expected Tus-Resumable %s, got %q", upload.Version, w.Header().Get("Tus-Resumable"))
+			}
+		})
+	}
+}
+
+func TestUploadHooks(t *testing.T) {
+	router := newUploadRouter(upload.Config{
+		Store: upload.NewMemoryStore(),
+		OnCreate: func(c *gin.Context, info *upload.Info) bool {
+			if info.Metadata["filename"] == "" {
+				c.AbortWithStatus(http.StatusUnprocessableEntity)
+				return false
+			}
+			return true
+		},
+		OnComplete: func(*gin.Context, upload.Info) error { return errors.New("queue down") },
+	})
+
+	w := serve(router, tusRequest(http.MethodPost, "/uploads", nil, "Upload-Length", "1"))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected OnCreate to reject with 422, got %d", w.Code)
+	}
+
+	w = serve(router, tusRequest(http.MethodPost, "/uploads", nil, "Upload-Length", "1", "Upload-Metadata", "filename YQ=="))
+	location := w.Header().Get("Location")
+	patch := func(offset string, body string) *httptest.ResponseRecorder {
+		return serve(router, tusRequest(http.MethodPatch, location, strings.NewReader(body), "Upload-Offset", offset, "Content-Type", "application/offset+octet-stream"))
+	}
+	if w := patch("0", "a"); w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 from OnComplete, got %d", w.Code)
+	}
+	// Retrying the final PATCH runs OnComplete again
+	if w := patch("1", ""); w.Code != http.StatusInternalServerError {
+		t.Errorf("expected OnComplete to run again, got %d", w.Code)
+	}
+}
+
+func TestUploadOnAccess(t *testing.T) {
+	router := newUploadRouter(upload.Config{
+		Store: upload.NewMemoryStore(),
+		OnCreate: func(c *gin.Context, info *upload.Info) bool {
+			info.Metadata = map[string]string{"owner": c.GetHeader("X-User")}
+			return true
+		},
+		OnAccess: func(c *gin.Context, info upload.Info) bool {
+			if info.Metadata["owner"] != c.GetHeader("X-User") {
+				c.AbortWithStatus(http.StatusNotFound)
+				return false
+			}
+			return true
+		},
+	})
+
+	w := serve(router, tusRequest(http.MethodPost, "/uploads", nil, "Upload-Length", "1", "X-User", "usr_1"))
+	location := w.Header().Get("Location")
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"HEAD by another user", tusRequest(http.MethodHead, location, nil, "X-User", "usr_2"), http.StatusNotFound},
+		{"PATCH by another user", tusRequest(http.MethodPatch, location, strings.NewReader("a"), "X-User", "usr_2", "Upload-Offset", "0", "Content-Type", "application/offset+octet-stream"), http.StatusNotFound},
+		{"DELETE by another user", tusRequest(http.MethodDelete, location, nil, "X-User", "usr_2"), http.StatusNotFound},
+		{"HEAD by the owner", tusRequest(http.MethodHead, location, nil, "X-User", "usr_1"), http.StatusOK},
+		{"DELETE by the owner", tusRequest(http.MethodDelete, location, nil, "X-User", "usr_1"), http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve(router, tt.req); w.Code != tt.status {
+				t.Errorf("expected %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestUploadOptionsAndTerminate(t *testing.T) {
+	router := newUploadRouter(upload.Config{Store: upload.NewMemoryStore()})
+
+	w := serve(router, httptest.NewRequest(http.MethodOptions, "/uploads", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Tus-Extension") != "creation,expiration,termination" || w.Header().Get("Tus-Max-Size") != "1073741824" {
+		t.Errorf("unexpected OPTIONS response %d %v", w.Code, w.Header())
+	}
+
+	w = serve(router, tusRequest(http.MethodPost, "/uploads", nil, "Upload-Length", "1"))
+	location := w.Header().Get("Location")
+	if w := serve(router, tusRequest(http.MethodDelete, location, nil)); w.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+	if w := serve(router, tusRequest(http.MethodHead, location, nil)); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after termination, got %d", w.Code)
+	}
+}
@@ -0,0 +1,138 @@
+// Package webhook defines the envelope of outgoing webhook events and how
+// deliveries are signed, so every service sends them the same way and
+// consumers verify them with one function:
+//
+//	e := webhook.NewEvent("gallery.updated", gallery)
+//	body, _ := json.Marshal(e)
+//	req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, body, time.Now()))
+//
+// The envelope matches the response package's objects:
+//
+//	{"object":"event","id":"evt_...","type":"gallery.updated","created":"2024-01-01T00:00:00Z","data":{...}}
+//
+// Consumers test their handlers against the format with
+// apitest.WebhookRequest.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doujins-org/ginapi/events"
+)
+
+// SignatureHeader carries the signature of a delivery, as
+// "t=<unix seconds>,v1=<hex HMAC-SHA256>". It may have several v1 values
+// while the secret is rotated.
+const SignatureHeader = "X-Webhook-Signature"
+
+// DefaultTolerance is how old a signature Verify accepts by default.
+const DefaultTolerance = 5 * time.Minute
+
+// ErrInvalidSignature is returned by Verify for missing, malformed, wrong,
+// and expired signatures.
+var ErrInvalidSignature = errors.New("webhook: invalid signature")
+
+// Event is the envelope of a webhook delivery.
+type Event struct {
+	Object  string    `json:"object"` // Always "event"
+	ID      string    `json:"id"`     // "evt_" and 32 hex digits
+	Type    string    `json:"type"`   // e.g. "gallery.updated"
+	Created time.Time `json:"created"`
+	Data    any       `json:"data"` // the object the event is about
+}
+
+// NewEvent returns an event of eventType about data, with a new ID,
+// created now.
+func NewEvent(eventType string, data any) Event {
+	return Event{
+		Object:  "event",
+		ID:      newID(),
+		Type:    eventType,
+		Created: time.Now().UTC().Truncate(time.Second),
+		Data:    data,
+	}
+}
+
+// FromEvent returns the webhook event for a domain event collected by the
+// events package, for publishers that deliver them as webhooks.
+func FromEvent(e events.Event) Event {
+	created := e.Time
+	if created.IsZero() {
+		created = time.Now()
+	}
+	return Event{
+		Object:  "event",
+		ID:      newID(),
+		Type:    e.Name,
+		Created: created.UTC().Truncate(time.Second),
+		Data:    e.Payload,
+	}
+}
+
+// newID returns a random event ID.
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "evt_" + hex.EncodeToString(b)
+}
+
+// Sign returns the SignatureHeader value for payload, the exact request
+// body, signed with secret at t.
+func Sign(secret, payload []byte, t time.Time) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + signature(secret, ts, payload)
+}
+
+// signature is the hex HMAC-SHA256 of "<timestamp>.<payload>".
+func signature(secret []byte, ts string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte{'.'})
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks header, the SignatureHeader of a delivery, against payload,
+// the raw request body. The signature must be made with one of secrets and
+// no more than tolerance old at now (DefaultTolerance if tolerance is 0),
+// which stops replays of captured deliveries. It returns
+// ErrInvalidSignature otherwise.
+func Verify(payload []byte, header string, now time.Time, tolerance time.Duration, secrets ...[]byte) error {
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrInvalidSignature
+	}
+	for _, secret := range secrets {
+		want := signature(secret, ts, payload)
+		for _, sig := range sigs {
+			if hmac.Equal([]byte(sig), []byte(want)) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
@@ -0,0 +1,87 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/doujins-org/ginapi/events"
+	"github.com/doujins-org/ginapi/webhook"
+)
+
+func TestNewEvent(t *testing.T) {
+	e := webhook.NewEvent("gallery.updated", map[string]any{"object": "gallery", "id": "gal_1"})
+	if !regexp.MustCompile(`^evt_[0-9a-f]{32}$`).MatchString(e.ID) {
+		t.Errorf("expected an evt_ ID, got %s", e.ID)
+	}
+	if other := webhook.NewEvent("gallery.updated", nil); other.ID == e.ID {
+		t.Errorf("expected unique IDs, got %s twice", e.ID)
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["object"] != "event" || got["type"] != "gallery.updated" {
+		t.Errorf("expected an event envelope, got %s", body)
+	}
+	if _, err := time.Parse(time.RFC3339, got["created"].(string)); err != nil {
+		t.Errorf("expected an RFC 3339 created time, got %v", got["created"])
+	}
+	if data, _ := got["data"].(map[string]any); data["id"] != "gal_1" {
+		t.Errorf("expected the data object, got %v", got["data"])
+	}
+}
+
+func TestFromEvent(t *testing.T) {
+	at := time.Date(2024, 1, 1, 9, 30, 0, 0, time.FixedZone("JST", 9*3600))
+	e := webhook.FromEvent(events.Event{Name: "tag.created", Payload: "tag_1", Time: at})
+	if e.Object != "event" || e.Type != "tag.created" || e.Data != "tag_1" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if !e.Created.Equal(at) || e.Created.Location() != time.UTC {
+		t.Errorf("expected created %v in UTC, got %v", at, e.Created)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret, old := []byte("secret"), []byte("old-secret")
+	payload := []byte(`{"object":"event"}`)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		header  string
+		payload []byte
+		secrets [][]byte
+		wantErr bool
+	}{
+		{"valid", webhook.Sign(secret, payload, now), payload, [][]byte{secret}, false},
+		{"previous secret", webhook.Sign(old, payload, now), payload, [][]byte{secret, old}, false},
+		{"rotating signer", webhook.Sign(secret, payload, now) + ",v1=" + "00", payload, [][]byte{secret}, false},
+		{"within tolerance", webhook.Sign(secret, payload, now.Add(-4*time.Minute)), payload, [][]byte{secret}, false},
+		{"expired", webhook.Sign(secret, payload, now.Add(-6*time.Minute)), payload, [][]byte{secret}, true},
+		{"tampered", webhook.Sign(secret, payload, now), []byte(`{"object":"evil"}`), [][]byte{secret}, true},
+		{"wrong secret", webhook.Sign(old, payload, now), payload, [][]byte{secret}, true},
+		{"missing", "", payload, [][]byte{secret}, true},
+		{"malformed", "t=abc,v1=00", payload, [][]byte{secret}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := webhook.Verify(tt.payload, tt.header, now, 0, tt.secrets...)
+			if tt.wantErr && !errors.Is(err, webhook.ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
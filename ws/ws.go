@@ -0,0 +1,230 @@
+// Package ws wraps the WebSocket upgrade handshake so failures before the
+// upgrade (bad origin, not a WebSocket request) get the same structured JSON
+// errors as the rest of the API, and the upgraded connection carries the
+// request's context values (language, request ID, gin keys such as the
+// authenticated principal).
+//
+// Auth and rate limiting stay in the usual middleware in front of the
+// handler; since they run before Upgrade, their errors are ordinary JSON
+// responses too.
+package ws
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+)
+
+// Config configures the upgrader.
+type Config struct {
+	// AllowedOrigins lists the origins (e.g. "https://doujins.com") allowed
+	// to connect; "*" allows any. Empty allows only same-host requests.
+	AllowedOrigins []string
+	// Subprotocols supported by the server, in order of preference
+	Subprotocols []string
+	// HandshakeTimeout bounds the upgrade (defaults to 10s)
+	HandshakeTimeout time.Duration
+	// ReadBufferSize and WriteBufferSize (default to 4096)
+	ReadBufferSize  int
+	WriteBufferSize int
+	// EnableCompression negotiates per-message deflate
+	EnableCompression bool
+}
+
+// Upgrader upgrades requests to WebSocket connections. Create one at startup
+// and share it between handlers.
+type Upgrader struct {
+	upgrader websocket.Upgrader
+}
+
+// New returns an Upgrader for cfg.
+func New(cfg Config) *Upgrader {
+	if cfg.HandshakeTimeout == 0 {
+		cfg.HandshakeTimeout = 10 * time.Second
+	}
+	if cfg.ReadBufferSize == 0 {
+		cfg.ReadBufferSize = 4096
+	}
+	if cfg.WriteBufferSize == 0 {
+		cfg.WriteBufferSize = 4096
+	}
+
+	u := &Upgrader{upgrader: websocket.Upgrader{
+		HandshakeTimeout:  cfg.HandshakeTimeout,
+		ReadBufferSize:    cfg.ReadBufferSize,
+		WriteBufferSize:   cfg.WriteBufferSize,
+		Subprotocols:      cfg.Subprotocols,
+		EnableCompression: cfg.EnableCompression,
+		Error:             writeHandshakeError,
+	}}
+	if len(cfg.AllowedOrigins) > 0 {
+		u.upgrader.CheckOrigin = originChecker(cfg.AllowedOrigins)
+	}
+	return u
+}
+
+// Upgrade upgrades the request. On failure it has already sent a structured
+// error and returns false.
+//
+//	var upgrader = ws.New(ws.Config{AllowedOrigins: []string{"https://doujins.com"}})
+//
+//	func notifications(c *gin.Context) {
+//	    conn, ok := upgrader.Upgrade(c)
+//	    if !ok {
+//	        return
+//	    }
+//	    defer conn.Close()
+//	    for note := range subscribe(conn.Context(), conn.Language()) {
+//	        if err := conn.WriteJSON(note); err != nil {
+//	            return
+//	        }
+//	    }
+//	}
+func (u *Upgrader) Upgrade(c *gin.Context) (*Conn, bool) {
+	conn, ok := u.upgrade(c.Writer, c.Request)
+	if !ok {
+		return nil, false
+	}
+	conn.language = middleware.GetLanguage(c)
+	conn.requestID = requestID(c.Writer, c.Request)
+	conn.keys = make(map[any]any, len(c.Keys))
+	for k, v := range c.Keys {
+		conn.keys[k] = v
+	}
+	return conn, true
+}
+
+// UpgradeHTTP is the net/http equivalent of Upgrade.
+func (u *Upgrader) UpgradeHTTP(w http.ResponseWriter, r *http.Request) (*Conn, bool) {
+	conn, ok := u.upgrade(w, r)
+	if !ok {
+		return nil, false
+	}
+	conn.language = middleware.LanguageFromContext(r.Context())
+	conn.requestID = requestID(w, r)
+	return conn, true
+}
+
+func (u *Upgrader) upgrade(w http.ResponseWriter, r *http.Request) (*Conn, bool) {
+	// Response headers already set by middleware (e.g. X-Request-ID) are
+	// sent with the 101 response
+	var header http.Header
+	if h := w.Header(); len(h) > 0 {
+		header = h.Clone()
+		header.Del("Content-Type")
+	}
+
+	wsConn, err := u.upgrader.Upgrade(w, r, header)
+	if err != nil {
+		return nil, false
+	}
+
+	// The request context is canceled when the handler returns, but the
+	// connection may outlive it (e.g. when served from a goroutine)
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	return &Conn{Conn: wsConn, ctx: ctx, cancel: cancel}, true
+}
+
+// Conn is an upgraded connection that remembers the request it came from.
+type Conn struct {
+	*websocket.Conn
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	language  string
+	requestID string
+	keys      map[any]any
+	writeMu   sync.Mutex
+}
+
+// Context returns the request context's values, canceled when the
+// connection is closed.
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// Language returns the language detected by the Language middleware.
+func (c *Conn) Language() string {
+	return c.language
+}
+
+// RequestID returns the request's X-Request-ID, if any.
+func (c *Conn) RequestID() string {
+	return c.requestID
+}
+
+// Get returns a value the gin middleware stored with c.Set before the
+// upgrade (e.g. the authenticated principal). Always false after UpgradeHTTP.
+func (c *Conn) Get(key string) (any, bool) {
+	v, ok := c.keys[key]
+	return v, ok
+}
+
+// WriteJSON sends v as a JSON text message. Unlike the embedded
+// websocket.Conn method, it is safe for concurrent use.
+func (c *Conn) WriteJSON(v any) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.WriteJSON(v)
+}
+
+// WriteError sends a structured error message, in the same envelope as
+// HTTP error responses.
+func (c *Conn) WriteError(info response.ErrorInfo) error {
+	return c.WriteJSON(response.Error{Object: "error", Error: info})
+}
+
+// Close cancels the connection context and closes the connection.
+func (c *Conn) Close() error {
+	c.cancel()
+	return c.Conn.Close()
+}
+
+// writeHandshakeError sends a failed handshake as a structured error.
+func writeHandshakeError(w http.ResponseWriter, r *http.Request, status int, reason error) {
+	info := response.ErrorInfo{
+		Type:    response.ErrorTypeForStatus(status),
+		Message: strings.TrimPrefix(reason.Error(), "websocket: "),
+	}
+	if status == http.StatusForbidden {
+		info.Message = "origin not allowed"
+	}
+	response.WriteError(w, r, status, info)
+}
+
+// originChecker allows requests from the given origins ("*" for any).
+// Requests without an Origin header (non-browser clients) are allowed.
+func originChecker(origins []string) func(r *http.Request) bool {
+	allowed := make(map[string]struct{}, len(origins))
+	for _, o := range origins {
+		if o == "*" {
+			return func(*http.Request) bool { return true }
+		}
+		allowed[strings.ToLower(strings.TrimSuffix(o, "/"))] = struct{}{}
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		_, ok := allowed[strings.ToLower(origin)]
+		return ok
+	}
+}
+
+// requestID returns the X-Request-ID set on the response or, failing that,
+// sent with the request.
+func requestID(w http.ResponseWriter, r *http.Request) string {
+	if id := w.Header().Get("X-Request-ID"); id != "" {
+		return id
+	}
+	return r.Header.Get("X-Request-ID")
+}
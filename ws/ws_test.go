@@ -0,0 +1,126 @@
+package ws_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/doujins-org/ginapi/middleware"
+	"github.com/doujins-org/ginapi/response"
+	"github.com/doujins-org/ginapi/ws"
+)
+
+func newServer(t *testing.T, cfg ws.Config) *httptest.Server {
+	t.Helper()
+	upgrader := ws.New(cfg)
+
+	router := gin.New()
+	router.Use(middleware.Language(middleware.LanguageConfig{Supported: []string{"en", "ja"}}))
+	router.GET("/ws", func(c *gin.Context) {
+		c.Set("principal", "usr_1")
+		conn, ok := upgrader.Upgrade(c)
+		if !ok {
+			return
+		}
+		defer conn.Close()
+
+		principal, _ := conn.Get("principal")
+		_ = conn.WriteJSON(map[string]any{
+			"language":   conn.Language(),
+			"request_id": conn.RequestID(),
+			"principal":  principal,
+		})
+		_ = conn.WriteError(response.ErrorInfo{Type: response.ErrorTypeInvalidRequest, Message: "bye"})
+	})
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestUpgrade(t *testing.T) {
+	srv := newServer(t, ws.Config{AllowedOrigins: []string{"https://doujins.com"}})
+
+	header := http.Header{}
+	header.Set("Origin", "https://doujins.com")
+	header.Set("Accept-Language", "ja")
+	header.Set("X-Request-ID", "req-1")
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", header)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("expected status 101, got %d", resp.StatusCode)
+	}
+
+	var hello map[string]any
+	if err := conn.ReadJSON(&hello); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	want := map[string]any{"language": "ja", "request_id": "req-1", "principal": "usr_1"}
+	for k, v := range want {
+		if hello[k] != v {
+			t.Errorf("expected %s %v, got %v", k, v, hello[k])
+		}
+	}
+
+	var errMsg response.Error
+	if err := conn.ReadJSON(&errMsg); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if errMsg.Object != "error" || errMsg.Error.Message != "bye" {
+		t.Errorf("expected error envelope, got %+v", errMsg)
+	}
+}
+
+func TestUpgradeFailures(t *testing.T) {
+	srv := newServer(t, ws.Config{AllowedOrigins: []string{"https://doujins.com"}})
+
+	tests := []struct {
+		name       string
+		websocket  bool
+		origin     string
+		wantStatus int
+		wantType   string
+	}{
+		{"bad origin", true, "https://evil.example", http.StatusForbidden, response.ErrorTypeForbidden},
+		{"not a websocket request", false, "", http.StatusBadRequest, response.ErrorTypeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", srv.URL+"/ws", nil)
+			if tt.websocket {
+				req.Header.Set("Connection", "Upgrade")
+				req.Header.Set("Upgrade", "websocket")
+				req.Header.Set("Sec-WebSocket-Version", "13")
+				req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			var result response.Error
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			if result.Error.Type != tt.wantType {
+				t.Errorf("expected type '%s', got '%s'", tt.wantType, result.Error.Type)
+			}
+		})
+	}
+}